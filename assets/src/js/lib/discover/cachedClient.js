@@ -0,0 +1,60 @@
+// Client for POST /discover/cached.
+//
+// Ranks releases already cached on the user's debrid accounts above the
+// rest. The server answers from its cache index only — the cache warmer and
+// earlier plays keep it filled — so this costs one cheap round-trip per
+// stream list and never a call to a debrid service.
+
+import { langPath } from './i18n.js';
+import { csrfHeaders } from './http.js';
+import { extractInfoHash } from './stream.js';
+
+// The lookup only reorders a list that is already complete, so it must not
+// hold the modal up: past this the list shows unranked.
+const FETCH_TIMEOUT = 3000;
+
+// fetchCached returns {infohash: [backend types]} for the cached streams,
+// or {} when the lookup fails — ranking is a nicety, never a reason to
+// break the list.
+export async function fetchCached(streams, { signal } = {}) {
+    const hashes = [...new Set((streams || []).map(extractInfoHash).filter(Boolean))];
+    if (!hashes.length) return {};
+    const controller = new AbortController();
+    const timeoutId = setTimeout(() => controller.abort(), FETCH_TIMEOUT);
+    if (signal) {
+        signal.addEventListener('abort', () => controller.abort(), { once: true });
+    }
+    try {
+        const res = await fetch(langPath('/discover/cached'), {
+            method: 'POST',
+            headers: csrfHeaders(),
+            body: JSON.stringify({ hashes }),
+            signal: controller.signal,
+        });
+        if (!res.ok) return {};
+        const data = await res.json();
+        return data.cached || {};
+    } catch (e) {
+        return {};
+    } finally {
+        clearTimeout(timeoutId);
+    }
+}
+
+// rankCachedFirst moves the cached streams to the top and tags each with
+// `cached` (the backend types that have it) for the row badge. The sort is
+// stable: within each half the interleaved source order is kept.
+export function rankCachedFirst(streams, cached) {
+    const hit = [];
+    const miss = [];
+    for (const s of streams || []) {
+        const hash = extractInfoHash(s);
+        const types = hash && cached?.[hash];
+        if (types && types.length) {
+            hit.push({ ...s, cached: types });
+        } else {
+            miss.push(s);
+        }
+    }
+    return hit.length ? [...hit, ...miss] : (streams || []);
+}
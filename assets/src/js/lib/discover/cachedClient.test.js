@@ -0,0 +1,44 @@
+import test from 'node:test';
+import assert from 'node:assert/strict';
+
+globalThis.__SUPPORTED_LOCALES__ = ['en', 'ru'];
+globalThis.document = { documentElement: { lang: 'en' } };
+globalThis.window = { _CSRF: 'csrf-token' };
+
+const { fetchCached, rankCachedFirst } = await import('./cachedClient.js');
+
+const A = 'a'.repeat(40);
+const B = 'b'.repeat(40);
+const C = 'c'.repeat(40);
+
+test('cached streams move up, tagged, in their original order', () => {
+    const streams = [
+        { name: 'one', infoHash: A },
+        { name: 'two', infoHash: B.toUpperCase() },
+        { name: 'three', url: 'magnet:?xt=urn:btih:' + C },
+    ];
+    const out = rankCachedFirst(streams, { [B]: ['torbox'], [C]: ['premiumize'] });
+    assert.deepEqual(out.map(s => s.name), ['two', 'three', 'one']);
+    assert.deepEqual(out[0].cached, ['torbox']);
+    assert.equal(out[2].cached, undefined);
+});
+
+test('nothing cached leaves the list as it was', () => {
+    const streams = [{ name: 'one', infoHash: A }, { name: 'no hash' }];
+    assert.equal(rankCachedFirst(streams, {}), streams);
+});
+
+test('the lookup posts each hash once and a failure reads as nothing cached', async () => {
+    const calls = [];
+    globalThis.fetch = async (url, opts) => {
+        calls.push({ url, ...opts });
+        return { ok: true, status: 200, json: async () => ({ cached: { [A]: ['torbox'] } }) };
+    };
+    const got = await fetchCached([{ infoHash: A }, { infoHash: A }, { name: 'no hash' }]);
+    assert.deepEqual(got, { [A]: ['torbox'] });
+    assert.equal(calls[0].url, '/discover/cached');
+    assert.deepEqual(JSON.parse(calls[0].body), { hashes: [A] });
+
+    globalThis.fetch = async () => { throw new Error('offline'); };
+    assert.deepEqual(await fetchCached([{ infoHash: A }]), {});
+});
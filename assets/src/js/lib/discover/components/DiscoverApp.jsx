@@ -26,6 +26,8 @@ import { AddonHealthChip } from './AddonHealthChip';
 import { AISection } from './ai/AISection';
 import { t, langPath } from '../i18n';
 import { fetchTorznabStreams, hasIndexers, indexerLabel } from '../torznabClient';
+import { hasUsenet } from '../usenetClient';
+import { fetchCached, rankCachedFirst } from '../cachedClient';
 
 // episodesModalFromBack rebuilds the episodes-view modal from the
 // backToEpisodes snapshot carried by an episode-streams modal. Shared by
@@ -384,7 +386,10 @@ export function DiscoverApp({ addonUrls, addonSeeds, hasCustomAddons }) {
         // external source can resolve — offers nothing, and the empty states
         // render without the CTA.
         const subTarget = subscriptionTargetFor(type, id, metaId, modalExtra?.backToEpisodes?.meta);
-        const itemMeta = { year, releaseInfo, imdbRating, description, tab: reviewsTab(), subTarget };
+        // Usenet releases are searched by the modal itself, under the stream
+        // list — see UsenetReleases. Same type and id the streams use.
+        const usenet = hasUsenet() ? { type, id } : null;
+        const itemMeta = { year, releaseInfo, imdbRating, description, tab: reviewsTab(), subTarget, usenet };
 
         // Addons we couldn't even probe for streams because their manifest
         // is unreachable or returned a 4xx. Without surfacing these, an
@@ -481,7 +486,13 @@ export function DiscoverApp({ addonUrls, addonSeeds, hasCustomAddons }) {
         // Dedup first (order decides which copy wins), then interleave so
         // every source is visible near the top rather than whichever one
         // returned the most results.
-        const allStreams = interleaveBySource(dedupeStreamsByHash(bySource.flat()));
+        const merged = interleaveBySource(dedupeStreamsByHash(bySource.flat()));
+        // Then put what the user's debrid accounts already hold on top: those
+        // start instantly. The server reads its cache index, which the cache
+        // warmer keeps filled, so this is one quick lookup per list.
+        const cached = await fetchCached(merged, { signal: streamSignal });
+        if (!current()) return;
+        const allStreams = rankCachedFirst(merged, cached);
         // Carry per-addon statuses into the streams view so the modal can
         // surface "Torrentio failed" instead of degrading silently to the
         // generic "no streams" empty-state. Combines fetch-time errors
@@ -11,6 +11,7 @@ import { SubscribeButton, OnAirDot } from './SubscribeButton';
 import { isSeasonUnfinished } from '../seasonStatus';
 import { getStreamPrefs, noneMatchPrefs } from '../streamPrefs';
 import { StarIcon } from './StarIcon';
+import { UsenetReleases } from './UsenetReleases';
 import { t, tf } from '../i18n';
 
 export function StreamModal({ modal, onClose, onEpisodeSelect, onStreamClick, onBackToEpisodes, onSeasonChange, hasCustomAddons, onSetupAddons, onRetryStreams, userStatuses, watchlistIds, onToggleWatched, onRate, onToggleWatchlist, onTabChange, subscriptionKeys, onToggleSubscription, hasSources }) {
@@ -731,7 +732,12 @@ function StreamContent({ modal, onStreamClick, hasCustomAddons, onSetupAddons, o
                 ) : null}
             />
 
-            {tab === 'reviews' ? <ReviewsTabContent reviews={reviews} /> : streamsContent}
+            {tab === 'reviews' ? <ReviewsTabContent reviews={reviews} /> : (
+                <>
+                    {streamsContent}
+                    <UsenetReleases query={modal.usenet} />
+                </>
+            )}
         </div>
     );
 }
@@ -844,6 +850,9 @@ function StreamRow({ stream, info, onStreamClick }) {
             <div class="min-w-0 flex-1">
                 <div class="flex items-center gap-1.5 flex-wrap">
                     <span class="text-sm font-medium">{info.source}</span>
+                    {stream.cached && (
+                        <span class="bg-w-pink/10 text-w-pinkL text-[10px] px-1.5 py-0.5 rounded font-medium" title={t('discover.cachedOnDebrid')}>⚡</span>
+                    )}
                     {info.labels.map(label => (
                         <span key={label} class="bg-w-cyan/10 text-w-cyan text-[10px] px-1.5 py-0.5 rounded font-medium">{label}</span>
                     ))}
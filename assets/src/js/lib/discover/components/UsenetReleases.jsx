@@ -0,0 +1,81 @@
+import { useEffect, useState, useCallback } from 'preact/hooks';
+import { parseStreamName } from '../stream';
+import { fetchUsenetReleases, sendUsenet } from '../usenetClient';
+import { t, langPath } from '../i18n';
+
+// UsenetReleases — the Usenet section under the stream list. Fetched on its
+// own, after the streams: an NZB cannot be played, only sent to a backend,
+// so the section must never hold up or reorder the list above it. Renders
+// nothing until there is at least one release.
+export function UsenetReleases({ query }) {
+    const [releases, setReleases] = useState([]);
+    // nzb token → 'sending' | 'sent'
+    const [sent, setSent] = useState({});
+
+    useEffect(() => {
+        setReleases([]);
+        setSent({});
+        if (!query) return;
+        const controller = new AbortController();
+        fetchUsenetReleases(query.type, query.id, { signal: controller.signal })
+            .then(r => { if (!controller.signal.aborted) setReleases(r); });
+        return () => controller.abort();
+    }, [query?.type, query?.id]);
+
+    const send = useCallback(async (nzb) => {
+        setSent(prev => ({ ...prev, [nzb]: 'sending' }));
+        window.umami?.track?.('usenet-send');
+        const result = await sendUsenet(nzb);
+        setSent(prev => {
+            const next = { ...prev };
+            if (result.ok) next[nzb] = 'sent';
+            else delete next[nzb];
+            return next;
+        });
+        if (!window.toast) return;
+        if (result.ok) window.toast.success(result.message || t('discover.usenet.sent'));
+        else window.toast.error(result.message || t('discover.networkError'));
+    }, []);
+
+    if (!releases.length) return null;
+
+    return (
+        <div class="mt-5 pt-4 border-t border-w-line/60">
+            <div class="flex items-center justify-between gap-2 mb-1">
+                <h3 class="text-sm font-semibold">{t('discover.usenet.title')}</h3>
+                <a href={langPath('/usenet')} class="text-xs text-w-pinkL link link-hover" data-umami-event="usenet-downloads-open">{t('discover.usenet.open')}</a>
+            </div>
+            <p class="text-xs text-w-muted mb-3">{t('discover.usenet.hint')}</p>
+            <div class="flex flex-col gap-2 max-h-[240px] overflow-y-auto">
+                {releases.map(r => {
+                    const info = parseStreamName(r.name);
+                    const state = sent[r.nzb];
+                    const titleLines = (r.title || '').split('\n').filter(Boolean);
+                    return (
+                        <div key={r.nzb} class="flex items-center gap-3 p-3 rounded-lg border border-w-line">
+                            <div class="min-w-0 flex-1">
+                                <div class="flex items-center gap-1.5 flex-wrap">
+                                    <span class="text-sm font-medium">{info.source}</span>
+                                    {info.labels.map(label => (
+                                        <span key={label} class="bg-w-cyan/10 text-w-cyan text-[10px] px-1.5 py-0.5 rounded font-medium">{label}</span>
+                                    ))}
+                                </div>
+                                {titleLines.map((line, i) => (
+                                    <div key={i} class="text-xs text-w-sub line-clamp-1">{line}</div>
+                                ))}
+                            </div>
+                            <button
+                                class="btn btn-soft btn-xs flex-shrink-0"
+                                onClick={() => send(r.nzb)}
+                                disabled={!!state}
+                            >
+                                {state === 'sending' && <span class="loading loading-spinner loading-xs"></span>}
+                                {state === 'sent' ? t('discover.usenet.sent') : t('discover.usenet.send')}
+                            </button>
+                        </div>
+                    );
+                })}
+            </div>
+        </div>
+    );
+}
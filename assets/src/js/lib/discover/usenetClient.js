@@ -0,0 +1,65 @@
+// Client for POST /discover/usenet/streams and POST /usenet/send.
+//
+// Usenet releases are not streams: nothing can play one until a backend
+// (TorBox, SABnzbd) has downloaded it. So they are fetched apart from the
+// stream list and rendered in their own section with a Send button. The
+// server never hands out the indexer's download link — it carries the
+// user's API key — only a sealed reference that /usenet/send accepts.
+
+// Explicit extensions: imported by the node --test suite as well as by
+// webpack, and Node's ESM resolver does not guess them.
+import { langPath } from './i18n.js';
+import { csrfHeaders } from './http.js';
+
+// Same outer bound as the Torznab search: the server queries the same kind
+// of indexers.
+const FETCH_TIMEOUT = 20000;
+
+// hasUsenet reports whether the user has any enabled Usenet indexer, so the
+// modal can skip the request entirely.
+export function hasUsenet() {
+    return window._usenet === true;
+}
+
+// fetchUsenetReleases returns [{name, title, size, nzb}], or [] when the
+// search fails — the section is an extra, never a reason to break the
+// modal.
+export async function fetchUsenetReleases(type, id, { signal } = {}) {
+    const controller = new AbortController();
+    const timeoutId = setTimeout(() => controller.abort(), FETCH_TIMEOUT);
+    if (signal) {
+        signal.addEventListener('abort', () => controller.abort(), { once: true });
+    }
+    try {
+        const res = await fetch(langPath('/discover/usenet/streams'), {
+            method: 'POST',
+            headers: csrfHeaders(),
+            body: JSON.stringify({ type, id }),
+            signal: controller.signal,
+        });
+        if (!res.ok) return [];
+        const data = await res.json();
+        return data.releases || [];
+    } catch (e) {
+        return [];
+    } finally {
+        clearTimeout(timeoutId);
+    }
+}
+
+// sendUsenet returns { ok, message }. The message comes from the server so
+// toasts stay in the current locale; it is empty on a network failure.
+export async function sendUsenet(nzb) {
+    try {
+        const res = await fetch(langPath('/usenet/send'), {
+            method: 'POST',
+            headers: csrfHeaders(),
+            body: JSON.stringify({ nzb }),
+        });
+        let body = {};
+        try { body = await res.json(); } catch (_) { /* ignore */ }
+        return { ok: res.ok, message: body.message || '' };
+    } catch (e) {
+        return { ok: false, message: '' };
+    }
+}
@@ -0,0 +1,55 @@
+import test from 'node:test';
+import assert from 'node:assert/strict';
+
+globalThis.__SUPPORTED_LOCALES__ = ['en', 'ru'];
+globalThis.document = { documentElement: { lang: 'en' } };
+globalThis.window = { _CSRF: 'csrf-token' };
+
+const { hasUsenet, fetchUsenetReleases, sendUsenet } = await import('./usenetClient.js');
+
+function stubFetch(response) {
+    const calls = [];
+    globalThis.fetch = async (url, opts = {}) => {
+        calls.push({ url, ...opts });
+        if (response instanceof Error) throw response;
+        return {
+            ok: response.status ? response.status < 400 : true,
+            status: response.status || 200,
+            json: async () => response.body,
+        };
+    };
+    return calls;
+}
+
+test('usenet is off unless the page says so', () => {
+    assert.equal(hasUsenet(), false);
+    window._usenet = true;
+    assert.equal(hasUsenet(), true);
+    delete window._usenet;
+});
+
+test('releases are searched by type and id, a failure reads as none', async () => {
+    const release = { name: 'NZBGeek\n1080p', title: 'Movie.2024.1080p', size: 42, nzb: 'sealed' };
+    const calls = stubFetch({ body: { releases: [release] } });
+    assert.deepEqual(await fetchUsenetReleases('movie', 'tt1'), [release]);
+    assert.equal(calls[0].url, '/discover/usenet/streams');
+    assert.deepEqual(JSON.parse(calls[0].body), { type: 'movie', id: 'tt1' });
+
+    stubFetch({ status: 500, body: {} });
+    assert.deepEqual(await fetchUsenetReleases('movie', 'tt1'), []);
+    stubFetch(new Error('offline'));
+    assert.deepEqual(await fetchUsenetReleases('movie', 'tt1'), []);
+});
+
+test('send posts the sealed release and passes the server message on', async () => {
+    const calls = stubFetch({ body: { status: 'success', message: 'Sent' } });
+    assert.deepEqual(await sendUsenet('sealed'), { ok: true, message: 'Sent' });
+    assert.equal(calls[0].url, '/usenet/send');
+    assert.equal(calls[0].headers['X-CSRF-TOKEN'], 'csrf-token');
+    assert.deepEqual(JSON.parse(calls[0].body), { nzb: 'sealed' });
+
+    stubFetch({ status: 422, body: { status: 'error', message: 'No backend' } });
+    assert.deepEqual(await sendUsenet('sealed'), { ok: false, message: 'No backend' });
+    stubFetch(new Error('offline'));
+    assert.deepEqual(await sendUsenet('sealed'), { ok: false, message: '' });
+});
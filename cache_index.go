@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/urfave/cli"
 	services "github.com/webtor-io/common-services"
 	ci "github.com/webtor-io/web-ui/services/cache_index"
+	cw "github.com/webtor-io/web-ui/services/cache_warmer"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -30,7 +33,16 @@ func configureCacheIndex(c *cli.Command) {
 			return cacheIndexCleanup(c)
 		},
 	}
-	c.Subcommands = []cli.Command{cleanupCmd}
+	warmCmd := cli.Command{
+		Name:    "warm",
+		Usage:   "Checks trending, subscribed and watchlisted torrents against debrid accounts in bulk",
+		Aliases: []string{"w"},
+		Action: func(c *cli.Context) error {
+			return cacheIndexWarm(c)
+		},
+	}
+	warmCmd.Flags = cw.RegisterFlags(warmCmd.Flags)
+	c.Subcommands = []cli.Command{cleanupCmd, warmCmd}
 	for k, _ := range c.Subcommands {
 		configureSubCacheIndex(&c.Subcommands[k])
 	}
@@ -59,3 +71,31 @@ func cacheIndexCleanup(c *cli.Context) error {
 
 	return nil
 }
+
+func cacheIndexWarm(c *cli.Context) error {
+	// Setting DB
+	pg := services.NewPG(c)
+	defer pg.Close()
+
+	// Setting CacheIndex
+	cacheIndex := ci.New(c, pg)
+
+	// Only the debrid backends are asked anything here, so the resolver
+	// needs no Webtor API.
+	resolver := lr.New(http.DefaultClient, pg, nil, cacheIndex)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	log.Info("running cache index warm-up")
+	st, err := cw.New(c, cw.NewStore(pg), resolver).Run(ctx)
+	if err != nil {
+		return err
+	}
+	log.WithField("backends", st.Backends).
+		WithField("hashes", st.Hashes).
+		WithField("cached", st.Cached).
+		Info("cache index warm-up completed")
+
+	return nil
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/urfave/cli"
 	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/services/anilist"
 	"github.com/webtor-io/web-ui/services/api"
 	enr "github.com/webtor-io/web-ui/services/enrich"
 	ku "github.com/webtor-io/web-ui/services/kinopoisk_unofficial"
+	"github.com/webtor-io/web-ui/services/musicbrainz"
 	"github.com/webtor-io/web-ui/services/omdb"
 	rec "github.com/webtor-io/web-ui/services/recommendations"
 	"github.com/webtor-io/web-ui/services/tmdb"
@@ -18,6 +20,8 @@ func configureEnricher(f []cli.Flag) []cli.Flag {
 	f = tmdb.RegisterFlags(f)
 	f = omdb.RegisterFlags(f)
 	f = ku.RegisterFlags(f)
+	f = anilist.RegisterFlags(f)
+	f = musicbrainz.RegisterFlags(f)
 	f = enr.RegisterFlags(f)
 	return f
 }
@@ -43,6 +47,20 @@ func makeEnricher(c *cli.Context, cl *http.Client, pg *cs.PG, sapi *api.Api, ant
 		if tmdbEp != nil {
 			epMappers = append(epMappers, tmdbEp)
 		}
+
+		// Setting AniList Mapper — anime by the romaji names fansub
+		// releases use, resolved to TMDB entries
+		al := enr.NewAniList(pg, anilist.New(c, cl), tmdbMapper)
+		if al != nil {
+			mdMappers = append(mdMappers, al)
+
+			// Setting AniList Episode Mapper — places absolute episode
+			// numbers in TMDB seasons
+			alEp := enr.NewAniListEpisodes(al, tmdbMapper)
+			if alEp != nil {
+				epMappers = append(epMappers, alEp)
+			}
+		}
 	}
 
 	// Setting OMDB API
@@ -63,6 +81,13 @@ func makeEnricher(c *cli.Context, cl *http.Client, pg *cs.PG, sapi *api.Api, ant
 		mdMappers = append(mdMappers, kpu)
 	}
 
+	// Setting MusicBrainz Mapper — albums, for torrents with no video
+	var musicMappers []enr.MusicMapper
+	mb := enr.NewMusicBrainz(pg, musicbrainz.New(c, cl))
+	if mb != nil {
+		musicMappers = append(musicMappers, mb)
+	}
+
 	// Setting AI Resolver — last-resort identifier when every title-search
 	// provider misses. Returns nil when the feature flag is off or the
 	// shared anthropic client is missing; the Enricher then skips the AI
@@ -70,5 +95,5 @@ func makeEnricher(c *cli.Context, cl *http.Client, pg *cs.PG, sapi *api.Api, ant
 	aiResolver := enr.New(c, anthropicCl, pg)
 
 	// Setting Enricher
-	return enr.NewEnricher(pg, sapi, mdMappers, epMappers, aiResolver)
+	return enr.NewEnricher(pg, sapi, mdMappers, epMappers, musicMappers, aiResolver)
 }
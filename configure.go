@@ -12,5 +12,8 @@ func configure(app *cli.App) {
 	vaultCMD := makeVaultCMD()
 	notificationCMD := makeNotificationCMD()
 	subscriptionCMD := makeSubscriptionCMD()
-	app.Commands = []cli.Command{serveCMD, migrationCMD, enrichCMD, cacheIndexCMD, vaultCMD, notificationCMD, subscriptionCMD}
+	dlnaCMD := makeDLNACMD()
+	streamingBackendCMD := makeStreamingBackendCMD()
+	usenetCMD := makeUsenetCMD()
+	app.Commands = []cli.Command{serveCMD, migrationCMD, enrichCMD, cacheIndexCMD, vaultCMD, notificationCMD, subscriptionCMD, dlnaCMD, streamingBackendCMD, usenetCMD}
 }
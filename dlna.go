@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"github.com/webtor-io/web-ui/services/dlna"
+	"github.com/webtor-io/web-ui/services/libapi"
+)
+
+func makeDLNACMD() cli.Command {
+	dlnaCmd := cli.Command{
+		Name:  "dlna",
+		Usage: "Serves your library to TVs on the local network (UPnP/DLNA)",
+	}
+	configureDLNA(&dlnaCmd)
+	return dlnaCmd
+}
+
+func configureDLNA(c *cli.Command) {
+	loginCmd := cli.Command{
+		Name:  "login",
+		Usage: "Connects the media server to your account",
+		Action: func(c *cli.Context) error {
+			return dlnaLogin(c)
+		},
+	}
+	serveCmd := cli.Command{
+		Name:    "serve",
+		Usage:   "Runs the media server",
+		Aliases: []string{"s"},
+		Action: func(c *cli.Context) error {
+			return dlnaServe(c)
+		},
+	}
+	c.Subcommands = []cli.Command{loginCmd, serveCmd}
+	for k := range c.Subcommands {
+		c.Subcommands[k].Flags = dlna.RegisterFlags(c.Subcommands[k].Flags)
+	}
+}
+
+// dlnaLogin runs the device flow (docs/api.md) and stores the key.
+func dlnaLogin(c *cli.Context) error {
+	cl, err := dlna.NewClientFromFlags(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	name := "webtor-dlna"
+	if h, err := os.Hostname(); err == nil {
+		name += " @ " + h
+	}
+	code, err := cl.DeviceCode(ctx, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to start device authorization")
+	}
+	fmt.Printf("Open %s and confirm the code %s\n", code.VerificationURIComplete, code.UserCode)
+
+	interval := time.Duration(code.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		res, err := cl.DeviceToken(ctx, code.DeviceCode)
+		var ae *libapi.Error
+		switch {
+		case err == nil:
+			if err := dlna.SaveKey(c, res.Key); err != nil {
+				return err
+			}
+			fmt.Println("Connected. Start the server with `web-ui dlna serve`.")
+			return nil
+		case errors.As(err, &ae) && ae.Code == libapi.CodeAuthorizationPending:
+		case errors.As(err, &ae) && ae.Code == libapi.CodeSlowDown:
+			interval += 5 * time.Second
+		default:
+			return errors.Wrap(err, "device authorization failed")
+		}
+	}
+	return errors.New("the code expired before it was confirmed, run login again")
+}
+
+func dlnaServe(c *cli.Context) error {
+	key, err := dlna.Key(c)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("no device key, run `web-ui dlna login` first")
+	}
+	cl, err := dlna.NewClientFromFlags(c)
+	if err != nil {
+		return err
+	}
+	srv, err := dlna.New(c, dlna.NewLibrary(cl), key)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(); err != nil {
+		log.WithError(err).Error("dlna stopped")
+		return err
+	}
+	return nil
+}
@@ -113,7 +113,7 @@ const docTemplatelibraryapi = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "The torrents saved to the account, newest first by default.\n\n` + "`" + `type` + "`" + ` filters the same way the library tabs do: ` + "`" + `movies` + "`" + ` and ` + "`" + `series` + "`" + ` mean a torrent with at least\none recognized film or show, which is decided by enrichment — a torrent added a moment ago may not\nbe classified yet and shows up only under ` + "`" + `all` + "`" + `.",
+                "description": "The torrents saved to the account, newest first by default.\n\n` + "`" + `type` + "`" + ` filters the same way the library tabs do: ` + "`" + `movies` + "`" + ` and ` + "`" + `series` + "`" + ` mean a torrent with at least\none recognized film or show, which is decided by enrichment — a torrent added a moment ago may not\nbe classified yet and shows up only under ` + "`" + `all` + "`" + `.\n\nListings of ` + "`" + `movies` + "`" + ` and ` + "`" + `series` + "`" + ` describe each entry under ` + "`" + `media` + "`" + `: title, year, plot, rating and\nposter, as recognized by enrichment.",
                 "produces": [
                     "application/json"
                 ],
@@ -1312,6 +1312,14 @@ const docTemplatelibraryapi = `{
                     "type": "integer",
                     "example": 3
                 },
+                "media": {
+                    "description": "Media is the film or show the torrent was recognized as. Only listings\nwith type=movies or type=series carry it.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/libapi.LibraryMedia"
+                        }
+                    ]
+                },
                 "name": {
                     "description": "Name is the library name, which the user may have renamed; it starts as\nthe torrent name.",
                     "type": "string",
@@ -1365,6 +1373,45 @@ const docTemplatelibraryapi = `{
                 }
             }
         },
+        "libapi.LibraryMedia": {
+            "description": "LibraryMedia is what enrichment made of a library entry. Title and year fall\nback to what was parsed from the torrent name while the entry is unmatched,\nin which case VideoID is empty.",
+            "type": "object",
+            "properties": {
+                "plot": {
+                    "type": "string",
+                    "example": "A lonely young woman, Sintel, helps and befriends a dragon."
+                },
+                "poster_url": {
+                    "description": "PosterURL is the same poster the library UI shows. It answers 404 when\nthere is none.",
+                    "type": "string",
+                    "example": "https://webtor.io/lib/poster/08ada5a7a6183aae1e09d831df6748d566095a10/240.jpg"
+                },
+                "rating": {
+                    "type": "number",
+                    "example": 7.4
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Sintel"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "movie",
+                        "series"
+                    ],
+                    "example": "movie"
+                },
+                "video_id": {
+                    "type": "string",
+                    "example": "tt1727587"
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2010
+                }
+            }
+        },
         "libapi.LibraryRenameRequest": {
             "type": "object",
             "required": [
@@ -94,7 +94,7 @@ func configureEnrich(c *cli.Command) {
 
 	cleanupCmd := cli.Command{
 		Name:   "cleanup-matches",
-		Usage:  "Detach pre-guard fuzzy-false-positive metadata matches (e.g. \"01\" → \"0187 UFO\"). Dry-run by default; pass --apply to write.",
+		Usage:  "Detach pre-guard fuzzy-false-positive metadata matches (e.g. \"01\" → \"0187 UFO\") and matches that contradict a global match override. Dry-run by default; pass --apply to write.",
 		Action: enrichCleanupMatches,
 	}
 	cleanupCmd.Flags = cs.RegisterPGFlags(cleanupCmd.Flags)
@@ -120,6 +120,11 @@ func configureEnrich(c *cli.Command) {
 // metadata rows are left intact; only the per-resource FK is nulled, so
 // a later re-enrich can resolve the resource correctly.
 //
+// User corrections take part too: resources users corrected are reported
+// and never touched, and a match that contradicts a global match override
+// (the title several users agreed on for that parsed name) is detached
+// like a false positive — the next enrichment applies the override.
+//
 // Dry-run by default — it reports the count and a sample and writes
 // nothing unless --apply is passed.
 func enrichCleanupMatches(c *cli.Context) error {
@@ -134,25 +139,58 @@ func enrichCleanupMatches(c *cli.Context) error {
 	}
 	ctx := context.Background()
 
+	corrected, err := models.GetOverriddenResourceIDs(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "listing match overrides")
+	}
+	globals, err := models.ListGlobalMatchOverrides(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "listing global match overrides")
+	}
+	globalByKey := make(map[string]*models.GlobalMatchOverride, len(globals))
+	for _, g := range globals {
+		globalByKey[globalOverrideKey(g.ContentType, g.QueryTitle, g.QueryYear)] = g
+	}
+
 	kinds := []struct {
 		name   string
+		ct     models.ContentType
 		list   func(context.Context, *gopg.DB) ([]models.MetadataMatchRow, error)
 		detach func(context.Context, *gopg.DB, []uuid.UUID) (int, error)
 	}{
-		{"movie", models.ListMovieMetadataMatches, models.DetachMovieMetadata},
-		{"series", models.ListSeriesMetadataMatches, models.DetachSeriesMetadata},
+		{"movie", models.ContentTypeMovie, models.ListMovieMetadataMatches, models.DetachMovieMetadata},
+		{"series", models.ContentTypeSeries, models.ListSeriesMetadataMatches, models.DetachSeriesMetadata},
 	}
 
-	var grandTotal, grandReject int
+	var grandTotal, grandReject, grandCorrected int
 	for _, k := range kinds {
 		rows, err := k.list(ctx, db)
 		if err != nil {
 			return errors.Wrapf(err, "listing %s matches", k.name)
 		}
 		var rejectIDs []uuid.UUID
-		var shown int
+		var shown, correctedN int
 		for _, r := range rows {
-			if !enr.IsRejectableMatch(r.QueryTitle, r.ResultTitle) {
+			if corrected[r.ResourceID] {
+				// A user picked this match (or the one that decides it);
+				// the guards have no say over it.
+				correctedN++
+				continue
+			}
+			var reason string
+			var year int16
+			if r.QueryYear != nil {
+				year = *r.QueryYear
+			}
+			g := globalByKey[globalOverrideKey(k.ct, enr.MatchQueryKey(r.QueryTitle), year)]
+			switch {
+			case g != nil && (g.NotMedia() || *g.VideoID != r.VideoID):
+				reason = "contradicts global match override"
+			case g != nil:
+				continue
+			case enr.IsRejectableMatch(r.QueryTitle, r.ResultTitle):
+				reason = "fuzzy false positive"
+			default:
 				continue
 			}
 			rejectIDs = append(rejectIDs, r.ID)
@@ -164,16 +202,18 @@ func enrichCleanupMatches(c *cli.Context) error {
 					"year":     yearStr(r.QueryYear),
 					"matched":  r.ResultTitle,
 					"video_id": r.VideoID,
-				}).Info("fuzzy false positive")
+				}).Info(reason)
 				shown++
 			}
 		}
 		grandTotal += len(rows)
 		grandReject += len(rejectIDs)
+		grandCorrected += correctedN
 		log.WithFields(log.Fields{
-			"kind":     k.name,
-			"scanned":  len(rows),
-			"rejected": len(rejectIDs),
+			"kind":           k.name,
+			"scanned":        len(rows),
+			"rejected":       len(rejectIDs),
+			"user_corrected": correctedN,
 		}).Info("cleanup-matches: scan complete")
 
 		if apply && len(rejectIDs) > 0 {
@@ -202,13 +242,19 @@ func enrichCleanupMatches(c *cli.Context) error {
 		mode = "APPLIED"
 	}
 	log.WithFields(log.Fields{
-		"scanned":  grandTotal,
-		"rejected": grandReject,
-		"mode":     mode,
+		"scanned":        grandTotal,
+		"rejected":       grandReject,
+		"user_corrected": grandCorrected,
+		"global":         len(globals),
+		"mode":           mode,
 	}).Info("cleanup-matches: done")
 	return nil
 }
 
+func globalOverrideKey(ct models.ContentType, queryTitle string, queryYear int16) string {
+	return string(ct) + "|" + queryTitle + "|" + strconv.Itoa(int(queryYear))
+}
+
 func yearStr(y *int16) string {
 	if y == nil {
 		return ""
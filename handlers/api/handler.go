@@ -33,6 +33,7 @@ import (
 	"github.com/webtor-io/web-ui/services/claims"
 	co "github.com/webtor-io/web-ui/services/common"
 	"github.com/webtor-io/web-ui/services/libapi"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	usettings "github.com/webtor-io/web-ui/services/user_settings"
 	"github.com/webtor-io/web-ui/services/vault"
 	"github.com/webtor-io/web-ui/services/web"
@@ -52,6 +53,7 @@ type Handler struct {
 	jobs         *j.Jobs
 	vault        *vault.Vault
 	userSettings *usettings.Service
+	events       *le.Bus
 	limiter      *libapi.RateLimiter
 	// domain is the site's public base URL; device verification URIs and the
 	// prefill key URL are built from it.
@@ -66,7 +68,7 @@ type Handler struct {
 	keyOrigins map[string]bool
 }
 
-func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessToken, sapi *restapi.Api, jobs *j.Jobs, v *vault.Vault, us *usettings.Service) {
+func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessToken, sapi *restapi.Api, jobs *j.Jobs, v *vault.Vault, us *usettings.Service, events *le.Bus) {
 	if c.Bool(co.DisableAPIFlag) {
 		return
 	}
@@ -77,6 +79,7 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessTok
 		jobs:         jobs,
 		vault:        v,
 		userSettings: us,
+		events:       events,
 		limiter:      libapi.NewRateLimiter(c),
 		domain:       strings.TrimSuffix(c.String(co.DomainFlag), "/"),
 		// A person confirms within minutes; three codes per minute per
@@ -15,6 +15,7 @@ import (
 	restapi "github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/libapi"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
@@ -26,6 +27,9 @@ import (
 //	@Description	`type` filters the same way the library tabs do: `movies` and `series` mean a torrent with at least
 //	@Description	one recognized film or show, which is decided by enrichment — a torrent added a moment ago may not
 //	@Description	be classified yet and shows up only under `all`.
+//	@Description
+//	@Description	Listings of `movies` and `series` describe each entry under `media`: title, year, plot, rating and
+//	@Description	poster, as recognized by enrichment.
 //	@Tags			library
 //	@Produce		json
 //	@Security		BearerAuth
@@ -117,9 +121,57 @@ func (s *Handler) listLibrary(c *gin.Context) {
 	for i := res.Offset; i < len(rows) && len(res.Items) < res.Limit; i++ {
 		res.Items = append(res.Items, libapi.NewLibraryItem(rows[i]))
 	}
+	if err := s.attachMedia(c.Request.Context(), db, typ, res.Items); err != nil {
+		s.abort(c, libapi.NewError(http.StatusInternalServerError, libapi.CodeInternal, "failed to load the library", err))
+		return
+	}
 	c.JSON(http.StatusOK, res)
 }
 
+// attachMedia describes a page of the movies or series section with what
+// enrichment recognized, in one query for the page. A pack holding several
+// films is described by the first one matched.
+func (s *Handler) attachMedia(ctx context.Context, db *pg.DB, typ string, items []libapi.LibraryItem) error {
+	if typ != libapi.LibraryTypeMovies && typ != libapi.LibraryTypeSeries || len(items) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
+		ids = append(ids, it.ResourceID)
+	}
+	var vcs []models.VideoContentWithMetadata
+	if typ == libapi.LibraryTypeMovies {
+		ms, err := models.GetMoviesWithMetadataByResourceIDs(ctx, db, ids)
+		if err != nil {
+			return err
+		}
+		for _, m := range ms {
+			vcs = append(vcs, m)
+		}
+	} else {
+		ss, err := models.GetSeriesWithMetadataByResourceIDs(ctx, db, ids)
+		if err != nil {
+			return err
+		}
+		for _, sr := range ss {
+			vcs = append(vcs, sr)
+		}
+	}
+	byID := map[string]models.VideoContentWithMetadata{}
+	for _, vc := range vcs {
+		id := vc.GetContent().ResourceID
+		if prev, ok := byID[id]; !ok || prev.GetMetadata() == nil && vc.GetMetadata() != nil {
+			byID[id] = vc
+		}
+	}
+	for i := range items {
+		if vc, ok := byID[items[i].ResourceID]; ok {
+			items[i].Media = libapi.NewLibraryMedia(vc, s.domain+web.PosterURL(items[i].ResourceID, 240, false, nil))
+		}
+	}
+	return nil
+}
+
 // getLibraryItem godoc
 //
 //	@Summary		Get one library entry
@@ -225,6 +277,7 @@ func (s *Handler) addLibrary(c *gin.Context) {
 		s.abort(c, libapi.NewError(http.StatusInternalServerError, libapi.CodeInternal, "failed to add to the library", derr))
 		return
 	}
+	s.events.Publish(le.Event{Kind: le.Added, UserID: u.ID.String(), ResourceID: rID})
 	if s.jobs != nil {
 		_, _ = s.jobs.Enrich(web.NewContext(c), rID)
 	}
@@ -285,6 +338,7 @@ func (s *Handler) renameLibrary(c *gin.Context) {
 		s.abort(c, libapi.NewError(http.StatusInternalServerError, libapi.CodeInternal, "failed to rename", derr))
 		return
 	}
+	s.events.Publish(le.Event{Kind: le.Renamed, UserID: l.UserID.String(), ResourceID: l.ResourceID})
 	c.JSON(http.StatusOK, libapi.NewLibraryItem(l))
 }
 
@@ -316,10 +370,12 @@ func (s *Handler) deleteLibrary(c *gin.Context) {
 		return
 	}
 	u := auth.GetUserFromContext(c)
-	if derr := models.RemoveFromLibrary(c.Request.Context(), db, u.ID, normalizeResourceID(c.Param("resource_id"))); derr != nil {
+	rID := normalizeResourceID(c.Param("resource_id"))
+	if derr := models.RemoveFromLibrary(c.Request.Context(), db, u.ID, rID); derr != nil {
 		s.abort(c, libapi.NewError(http.StatusInternalServerError, libapi.CodeInternal, "failed to remove from the library", derr))
 		return
 	}
+	s.events.Publish(le.Event{Kind: le.Removed, UserID: u.ID.String(), ResourceID: rID})
 	c.Status(http.StatusNoContent)
 }
 
@@ -0,0 +1,93 @@
+package browse
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	cs "github.com/webtor-io/common-services"
+	j "github.com/webtor-io/web-ui/jobs"
+	at "github.com/webtor-io/web-ui/services/access_token"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/autoindex"
+	"github.com/webtor-io/web-ui/services/claims"
+	co "github.com/webtor-io/web-ui/services/common"
+	"github.com/webtor-io/web-ui/services/i18n"
+	"github.com/webtor-io/web-ui/services/libfs"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+type Handler struct {
+	at     *at.AccessToken
+	index  *autoindex.Index
+	domain string
+}
+
+func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessToken, sapi *api.Api, jobs *j.Jobs, subs *us.Service, events *le.Bus, resolver *lr.LinkResolver) {
+	if c.Bool(co.DisableBrowseFlag) {
+		return
+	}
+	// Same tree as WebDAV and S3; the index only ever reads it.
+	h := &Handler{
+		at:     ats,
+		index:  autoindex.New(libfs.New(pg, sapi, jobs, subs, events, resolver)),
+		domain: strings.TrimSuffix(c.String(co.DomainFlag), "/"),
+	}
+
+	gr := r.Group("/browse")
+	gr.Use(auth.HasAuth)
+	gr.Use(claims.IsPaid)
+	gr.POST("/url/generate", h.generateUrl)
+	gr.POST("/url/regenerate", h.regenerateUrl)
+	// Reached only through the token URL, /token/<token>/browse/fs/…, so a
+	// browser with no session — a library or office machine — can open it.
+	grapi := gr.Group("")
+	grapi.Use(ats.HasScope(autoindex.Scope))
+	grapi.GET("/fs/*rest", h.browse)
+	grapi.HEAD("/fs/*rest", h.browse)
+}
+
+func (s *Handler) generateUrl(c *gin.Context) {
+	_, err := s.at.Generate(c, autoindex.TokenName, []string{autoindex.Scope})
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to generate browse url"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.browseUrlGenerated")
+}
+
+// regenerateUrl rotates the index token. Bookmarks and playlists made with
+// the previous URL stop working — the UI gates it behind a confirm.
+func (s *Handler) regenerateUrl(c *gin.Context) {
+	_, err := s.at.Regenerate(c, autoindex.TokenName, []string{autoindex.Scope})
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to regenerate browse url"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.browseUrlRegenerated")
+}
+
+// browse serves the index. Playlists carry absolute URLs, and those are built
+// on the token URL rather than the alias the page may have been opened by:
+// the alias prefix never reaches us.
+func (s *Handler) browse(c *gin.Context) {
+	ctx := context.WithValue(c.Request.Context(), web.Context{}, web.NewContext(c))
+	c.Request = c.Request.WithContext(ctx)
+	token := c.Query(co.AccessTokenParamName)
+	base := s.domain + "/" + co.AccessTokenParamName + "/" + token + "/browse/fs"
+	// The token route moved the token into the query; it is in the path the
+	// browser sees already and must not leak into redirects built from it.
+	q := c.Request.URL.Query()
+	q.Del(co.AccessTokenParamName)
+	c.Request.URL.RawQuery = q.Encode()
+	s.index.Serve(c.Writer, c.Request, c.Param("rest"), base, func(key string) string {
+		return i18n.T(c, key)
+	})
+}
@@ -0,0 +1,97 @@
+package calendar
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	at "github.com/webtor-io/web-ui/services/access_token"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/calendar"
+	"github.com/webtor-io/web-ui/services/common"
+	"github.com/webtor-io/web-ui/services/i18n"
+	usettings "github.com/webtor-io/web-ui/services/user_settings"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+type Handler struct {
+	at   *at.AccessToken
+	cal  *calendar.Service
+	us   *usettings.Service
+	i18n *i18n.Helper
+	host string
+}
+
+func RegisterHandler(c *cli.Context, r *gin.Engine, at *at.AccessToken, cal *calendar.Service, us *usettings.Service, th *i18n.Helper) error {
+	// The feed's UIDs are qualified by our host, so events stay unique
+	// across every calendar a user subscribes to.
+	d, err := url.Parse(c.String(common.DomainFlag))
+	if err != nil {
+		return err
+	}
+	h := &Handler{
+		at:   at,
+		cal:  cal,
+		us:   us,
+		i18n: th,
+		host: d.Hostname(),
+	}
+
+	gr := r.Group("/calendar")
+	gr.Use(auth.HasAuth)
+	gr.POST("/url/generate", h.generateUrl)
+	gr.POST("/url/regenerate", h.regenerateUrl)
+	// Calendar apps fetch the feed with no cookies, so it is reached only
+	// through the token URL: /token/<token>/calendar/episodes.ics.
+	grapi := gr.Group("")
+	grapi.Use(at.HasScope(calendar.Scope))
+	grapi.GET("/episodes.ics", h.episodes)
+	return nil
+}
+
+func (s *Handler) generateUrl(c *gin.Context) {
+	_, err := s.at.Generate(c, calendar.TokenName, []string{calendar.Scope})
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to generate calendar url"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.calendarUrlGenerated")
+}
+
+// regenerateUrl rotates the calendar token. Calendars already subscribed to
+// the previous URL stop updating — the UI gates it behind a confirm.
+func (s *Handler) regenerateUrl(c *gin.Context) {
+	_, err := s.at.Regenerate(c, calendar.TokenName, []string{calendar.Scope})
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to regenerate calendar url"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.calendarUrlRegenerated")
+}
+
+// episodes serves the feed. The calendar is named in the account's own
+// language: the request comes from Google's or Apple's servers, and its
+// Accept-Language says nothing about the user.
+func (s *Handler) episodes(c *gin.Context) {
+	u := auth.GetUserFromContext(c)
+	eps, err := s.cal.Upcoming(c.Request.Context(), u.ID)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to build calendar feed"))
+		return
+	}
+	lang := "en"
+	if us, err := s.us.Get(c.Request.Context(), u.ID); err == nil && us.GetLang() != "" {
+		lang = us.GetLang()
+	}
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="episodes.ics"`)
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Status(http.StatusOK)
+	if err := calendar.WriteICS(c.Writer, s.i18n.T(lang, "calendar.feedName"), s.host, eps, time.Now()); err != nil {
+		log.WithError(err).Warn("failed to write calendar feed")
+	}
+}
@@ -5,4 +5,5 @@ var AnyMethods = []string{
 	"OPTIONS", "HEAD", "PATCH", "TRACE",
 	"CONNECT", "PROPFIND", "PROPPATCH",
 	"MKCOL", "COPY", "MOVE",
+	"LOCK", "UNLOCK",
 }
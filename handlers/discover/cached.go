@@ -0,0 +1,92 @@
+package discover
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+)
+
+// maxCachedHashes bounds one lookup. A stream modal rarely shows more than a
+// couple of hundred releases after dedup; anything past this is left unranked.
+const maxCachedHashes = 500
+
+// cacheLookup is the slice of LinkResolver the cached endpoint needs. Kept
+// as an interface so the Level 2 worker is testable without Postgres.
+type cacheLookup interface {
+	CachedTorrents(ctx context.Context, userID uuid.UUID, hashes []string) (map[string][]models.StreamingBackendType, error)
+}
+
+type cachedRequest struct {
+	Hashes []string `json:"hashes"`
+}
+
+type cachedResponse struct {
+	// Cached maps each cached infohash to the user's backends that have it
+	Cached map[string][]models.StreamingBackendType `json:"cached"`
+}
+
+// cached is the Level 1 handler for POST /discover/cached. The stream modal
+// posts the infohashes of a merged stream list and gets back the ones the
+// cache index knows to be instant on the user's debrid accounts, so it can
+// rank them first. Only the index is read — the cache warmer and earlier
+// plays keep it filled — so the answer costs no call to a debrid service.
+func (h *Handler) cached(c *gin.Context) {
+	if h.cl == nil {
+		c.JSON(http.StatusOK, cachedResponse{Cached: map[string][]models.StreamingBackendType{}})
+		return
+	}
+	var req cachedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad payload"})
+		return
+	}
+	u := auth.GetUserFromContext(c)
+	cached, err := cachedForHashes(c.Request.Context(), h.cl, u.ID, req.Hashes)
+	if err != nil {
+		log.WithError(err).Warn("failed to look up cached torrents")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "cache lookup failed"})
+		return
+	}
+	c.JSON(http.StatusOK, cachedResponse{Cached: cached})
+}
+
+// cachedForHashes is the Level 2 worker: keeps the well-formed infohashes,
+// lowercased and capped at maxCachedHashes, and asks the cache index.
+func cachedForHashes(ctx context.Context, cl cacheLookup, userID uuid.UUID, hashes []string) (map[string][]models.StreamingBackendType, error) {
+	seen := map[string]bool{}
+	var valid []string
+	for _, hash := range hashes {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if !isInfoHash(hash) || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		valid = append(valid, hash)
+		if len(valid) == maxCachedHashes {
+			break
+		}
+	}
+	if len(valid) == 0 {
+		return map[string][]models.StreamingBackendType{}, nil
+	}
+	return cl.CachedTorrents(ctx, userID, valid)
+}
+
+func isInfoHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package discover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+type mockCacheLookup struct {
+	asked []string
+}
+
+func (m *mockCacheLookup) CachedTorrents(_ context.Context, _ uuid.UUID, hashes []string) (map[string][]models.StreamingBackendType, error) {
+	m.asked = hashes
+	return map[string][]models.StreamingBackendType{hashes[0]: {models.StreamingBackendTypeTorbox}}, nil
+}
+
+func TestCachedForHashes_NormalizesInput(t *testing.T) {
+	cl := &mockCacheLookup{}
+	a := strings.Repeat("a", 40)
+	got, err := cachedForHashes(context.Background(), cl, uuid.NewV4(), []string{
+		" " + strings.ToUpper(a) + " ", a, "not-a-hash", strings.Repeat("z", 40),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cl.asked) != 1 || cl.asked[0] != a {
+		t.Errorf("asked the index about %v, want only %s", cl.asked, a)
+	}
+	if len(got[a]) != 1 || got[a][0] != models.StreamingBackendTypeTorbox {
+		t.Errorf("unexpected answer %v", got)
+	}
+}
+
+func TestCachedForHashes_NothingValidSkipsLookup(t *testing.T) {
+	cl := &mockCacheLookup{}
+	got, err := cachedForHashes(context.Background(), cl, uuid.NewV4(), []string{"", "abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cl.asked != nil {
+		t.Error("the index was asked about nothing")
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("expected an empty non-nil map, got %#v", got)
+	}
+}
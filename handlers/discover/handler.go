@@ -14,8 +14,10 @@ import (
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/enrich"
 	"github.com/webtor-io/web-ui/services/i18n"
+	"github.com/webtor-io/web-ui/services/link_resolver"
 	"github.com/webtor-io/web-ui/services/stremio"
 	"github.com/webtor-io/web-ui/services/template"
+	"github.com/webtor-io/web-ui/services/usenet"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
@@ -54,6 +56,8 @@ type indexData struct {
 	// stream and lets the chips do the filtering, so without them it cannot
 	// tell an unwanted result from a missing one.
 	Prefs streamPrefsView
+	// HasUsenet turns on the Usenet section of the stream modal
+	HasUsenet bool
 }
 
 type streamPrefsView struct {
@@ -78,19 +82,32 @@ type Handler struct {
 	// sb builds the Torznab half of the stream pipeline — see torznab.go
 	// for why that half cannot run in the browser.
 	sb *stremio.Builder
+	// cl answers which releases are cached on the user's debrid accounts —
+	// see cached.go.
+	cl cacheLookup
+	// un seals Usenet releases for the page — see usenet.go.
+	un releaseSealer
 }
 
-func RegisterHandler(r *gin.Engine, tm *template.Manager[*web.Context], pg *cs.PG, en *enrich.Enricher, sb *stremio.Builder) {
+func RegisterHandler(r *gin.Engine, tm *template.Manager[*web.Context], pg *cs.PG, en *enrich.Enricher, sb *stremio.Builder, lr *link_resolver.LinkResolver, un *usenet.Usenet) {
 	h := &Handler{
 		tb: tm.MustRegisterViews("discover/*").WithLayout("main"),
 		pg: pg,
 		en: en,
 		sb: sb,
 	}
+	if lr != nil {
+		h.cl = lr
+	}
+	if un != nil {
+		h.un = un
+	}
 	r.GET("/discover", h.index)
 	r.POST("/discover/localize", auth.HasAuth, h.localize)
 	r.POST("/discover/reviews", auth.HasAuth, h.reviews)
 	r.POST("/discover/torznab/streams", auth.HasAuth, h.torznabStreams)
+	r.POST("/discover/cached", auth.HasAuth, h.cached)
+	r.POST("/discover/usenet/streams", auth.HasAuth, h.usenetStreams)
 }
 
 func (h *Handler) index(c *gin.Context) {
@@ -141,9 +158,10 @@ func (h *Handler) index(c *gin.Context) {
 	}
 
 	h.tb.Build("discover/index").HTML(http.StatusOK, web.NewContext(c).WithData(&indexData{
-		Addons:   views,
-		Indexers: indexerViews(c.Request.Context(), db, u),
-		Prefs:    streamPrefs(c.Request.Context(), db, u),
+		Addons:    views,
+		Indexers:  indexerViews(c.Request.Context(), db, u),
+		Prefs:     streamPrefs(c.Request.Context(), db, u),
+		HasUsenet: h.un != nil && hasUsenetIndexers(c.Request.Context(), db, u),
 	}))
 }
 
@@ -0,0 +1,121 @@
+package discover
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-pg/pg/v10"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/stremio"
+)
+
+// releaseSealer is the slice of usenet.Usenet the Usenet endpoint needs.
+// Kept as an interface so the Level 2 worker is testable without a secret.
+type releaseSealer interface {
+	Seal(userID uuid.UUID, r *stremio.NZBRelease) (string, error)
+}
+
+type usenetRelease struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Size  int64  `json:"size"`
+	// NZB is the sealed release the page posts to /usenet/send. The
+	// indexer's link carries the user's API key, so it never reaches the
+	// browser.
+	NZB string `json:"nzb"`
+}
+
+type usenetStreamsResponse struct {
+	Releases []usenetRelease `json:"releases"`
+}
+
+// hasUsenetIndexers reports whether the user has an enabled Usenet indexer.
+// A failure reads as none: the section is an extra.
+func hasUsenetIndexers(ctx context.Context, db *pg.DB, u *auth.User) bool {
+	indexers, err := models.GetUserUsenetIndexers(ctx, db, u.ID)
+	if err != nil {
+		log.WithError(err).Warn("failed to list usenet indexers for the client bootstrap")
+		return false
+	}
+	return len(indexers) > 0
+}
+
+// usenetStreams is the Level 1 handler for POST /discover/usenet/streams.
+// Usenet releases are not streams: nothing plays them until a backend has
+// downloaded them, so they are listed apart from the stream list and can
+// only be sent to a backend.
+func (h *Handler) usenetStreams(c *gin.Context) {
+	if h.sb == nil || h.un == nil {
+		c.JSON(http.StatusOK, usenetStreamsResponse{Releases: []usenetRelease{}})
+		return
+	}
+	var req torznabStreamsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad payload"})
+		return
+	}
+	contentType := strings.TrimSpace(req.Type)
+	contentID := strings.TrimSpace(req.ID)
+	// Same whitelist as the Torznab endpoint, for the same reasons.
+	if contentType != "movie" && contentType != "series" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be movie or series"})
+		return
+	}
+	if contentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	ctx := c.Request.Context()
+	u := auth.GetUserFromContext(c)
+	svc, err := h.sb.BuildUsenetStreamsService(ctx, u)
+	if err != nil {
+		log.WithError(err).Error("failed to build usenet streams service")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch releases"})
+		return
+	}
+	resp, err := usenetReleasesFor(ctx, svc, h.un, u.ID, contentType, contentID)
+	if err != nil {
+		log.WithError(err).Error("failed to fetch usenet releases")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch releases"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// usenetReleasesFor is the Level 2 half: searches the user's Usenet
+// indexers and seals every release for the user.
+func usenetReleasesFor(ctx context.Context, svc stremio.StreamsService, sealer releaseSealer, userID uuid.UUID, contentType, contentID string) (*usenetStreamsResponse, error) {
+	out := &usenetStreamsResponse{Releases: []usenetRelease{}}
+	if svc == nil {
+		return out, nil
+	}
+	resp, err := svc.GetStreams(ctx, contentType, contentID)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return out, nil
+	}
+	for _, s := range resp.Streams {
+		if s.NZB == nil {
+			continue
+		}
+		token, err := sealer.Seal(userID, s.NZB)
+		if err != nil {
+			return nil, err
+		}
+		out.Releases = append(out.Releases, usenetRelease{
+			Name:  s.Name,
+			Title: s.Title,
+			Size:  s.NZB.Size,
+			NZB:   token,
+		})
+	}
+	return out, nil
+}
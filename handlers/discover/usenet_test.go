@@ -0,0 +1,55 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/services/stremio"
+)
+
+type mockStreams struct {
+	streams []stremio.StreamItem
+}
+
+func (m *mockStreams) GetName() string { return "usenet" }
+
+func (m *mockStreams) GetStreams(context.Context, string, string) (*stremio.StreamsResponse, error) {
+	return &stremio.StreamsResponse{Streams: m.streams}, nil
+}
+
+type mockSealer struct{}
+
+func (mockSealer) Seal(userID uuid.UUID, r *stremio.NZBRelease) (string, error) {
+	return userID.String() + "|" + r.Name, nil
+}
+
+func TestUsenetReleasesFor_SealsReleases(t *testing.T) {
+	userID := uuid.NewV4()
+	svc := &mockStreams{streams: []stremio.StreamItem{
+		{Name: "NZBGeek\n1080p", Title: "Movie.2024.1080p", NZB: &stremio.NZBRelease{Link: "https://geek/getnzb?apikey=k", Name: "Movie.2024.1080p", Size: 42}},
+		{Name: "torrent", InfoHash: "aaaa"},
+	}}
+	got, err := usenetReleasesFor(context.Background(), svc, mockSealer{}, userID, "movie", "tt1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Releases) != 1 {
+		t.Fatalf("got %d releases, want 1", len(got.Releases))
+	}
+	r := got.Releases[0]
+	if r.NZB != userID.String()+"|Movie.2024.1080p" || r.Size != 42 || r.Name != "NZBGeek\n1080p" {
+		t.Errorf("unexpected release %+v", r)
+	}
+}
+
+func TestUsenetReleasesFor_NoIndexers(t *testing.T) {
+	got, err := usenetReleasesFor(context.Background(), nil, mockSealer{}, uuid.NewV4(), "movie", "tt1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Releases == nil || len(got.Releases) != 0 {
+		t.Errorf("expected an empty non-nil list, got %#v", got.Releases)
+	}
+}
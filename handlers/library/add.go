@@ -11,6 +11,7 @@ import (
 	"github.com/webtor-io/web-ui/models"
 	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/auth"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
@@ -57,6 +58,7 @@ func (s *Handler) addTorrentToLibrary(c *gin.Context, u *auth.User) (err error,
 	if err != nil {
 		return
 	}
+	s.events.Publish(le.Event{Kind: le.Added, UserID: u.ID.String(), ResourceID: rID})
 
 	return nil, rID
 }
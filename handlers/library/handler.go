@@ -11,6 +11,7 @@ import (
 	"github.com/webtor-io/web-ui/jobs"
 	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/enrich"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/poster_resolver"
 	"github.com/webtor-io/web-ui/services/template"
 	"github.com/webtor-io/web-ui/services/thumbnail"
@@ -42,9 +43,10 @@ type Handler struct {
 	thumbnail           *thumbnail.Service
 	posterResolver      *poster_resolver.Service
 	posterCacheS3Bucket string
+	events              *le.Bus
 }
 
-func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Context], api *api.Api, pg *cs.PG, jobs *j.Jobs, cl *http.Client, s3Cl *cs.S3Client, en *enrich.Enricher, thumb *thumbnail.Service) {
+func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Context], api *api.Api, pg *cs.PG, jobs *j.Jobs, cl *http.Client, s3Cl *cs.S3Client, en *enrich.Enricher, thumb *thumbnail.Service, events *le.Bus) {
 	bucket := c.String(awsPosterCacheBucket)
 	h := &Handler{
 		tb: tm.MustRegisterViews("library/*").
@@ -52,6 +54,7 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Co
 			WithHelper(helpers.NewMenuHelper()).
 			WithHelper(helpers.NewSortHelper()).
 			WithHelper(helpers.NewVideoContentHelper()).
+			WithHelper(helpers.NewAlbumHelper()).
 			WithLayout("main"),
 		api:                 api,
 		pg:                  pg,
@@ -62,6 +65,7 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Co
 		thumbnail:           thumb,
 		posterResolver:      poster_resolver.New(s3Cl, pg, cl, thumb, bucket),
 		posterCacheS3Bucket: bucket,
+		events:              events,
 	}
 	lg := r.Group("/lib")
 	lg.GET("/", h.index)
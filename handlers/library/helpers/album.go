@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+type AlbumHelper struct{}
+
+func NewAlbumHelper() *AlbumHelper {
+	return &AlbumHelper{}
+}
+
+// GetAlbumCover240 returns the album's cover at 240px: the Cover Art
+// Archive front through the poster proxy when the album was identified,
+// the resource poster — embedded art from the tracks — otherwise.
+func (s *AlbumHelper) GetAlbumCover240(a *models.Album, ctx *web.Context) string {
+	if md := a.AlbumMetadata; md != nil && md.MBID != "" && md.CoverURL != "" {
+		return fmt.Sprintf("/lib/album/poster/%s/240.jpg", md.MBID)
+	}
+	return web.PosterURL(a.ResourceID, 240, false, ctx)
+}
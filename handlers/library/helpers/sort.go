@@ -42,6 +42,7 @@ var sorts = map[shared.SectionType]Sort{
 	shared.SectionTypeTorrents: NewSort(models.SortTypeRecentlyAdded, models.SortTypeName),
 	shared.SectionTypeMovies:   videoSort,
 	shared.SectionTypeSeries:   videoSort,
+	shared.SectionTypeMusic:    NewSort(models.SortTypeRecentlyAdded, models.SortTypeYear, models.SortTypeName),
 }
 
 func (s *SortHelper) MakeSort(args *shared.IndexArgs) *Sort {
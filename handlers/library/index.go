@@ -31,6 +31,7 @@ type IndexData struct {
 	TorrentCount  int
 	MovieCount    int
 	SeriesCount   int
+	MusicCount    int
 	RateForm      *RateFormData
 }
 
@@ -116,12 +117,19 @@ func (s *Handler) index(c *gin.Context) {
 		return
 	}
 
+	ac, err := models.GetLibraryAlbumCount(ctx, db, u.ID)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get library counts"))
+		return
+	}
+
 	indexData := &IndexData{
 		Args:         args,
 		Items:        ls,
 		TorrentCount: tc,
 		MovieCount:   mc,
 		SeriesCount:  sc,
+		MusicCount:   ac,
 	}
 
 	// Detect rate-form prompt (from mark-watched redirect or explicit rate badge click).
@@ -163,6 +171,8 @@ func (s *Handler) getLibraryList(ctx context.Context, u *auth.User, args *shared
 		return s.getMovieList(ctx, u.ID, db, args.Sort, args.Watched)
 	case shared.SectionTypeSeries:
 		return s.getSeriesList(ctx, u.ID, db, args.Sort, args.Watched)
+	case shared.SectionTypeMusic:
+		return s.getMusicList(ctx, u.ID, db, args.Sort)
 	}
 	return
 }
@@ -203,6 +213,18 @@ func (s *Handler) getSeriesList(ctx context.Context, id uuid.UUID, db *pg.DB, so
 	return
 }
 
+func (s *Handler) getMusicList(ctx context.Context, id uuid.UUID, db *pg.DB, sort models.SortType) (items []any, err error) {
+	ls, err := models.GetLibraryAlbumList(ctx, db, id, sort)
+	if err != nil {
+		return
+	}
+	items = make([]any, len(ls))
+	for i, v := range ls {
+		items[i] = v
+	}
+	return
+}
+
 // annotateWatched sets the transient UserWatched flag on each Movie/Series in
 // items, using a single bulk query per kind against movie_status /
 // series_status. Errors are swallowed: a missing watched badge is a
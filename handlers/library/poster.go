@@ -41,6 +41,10 @@ const (
 // posters don't pollute the 5xx error budget.
 var errPosterNotFound = errors.New("poster not found")
 
+// posterTypeAlbum serves album covers through the same resize and cache
+// path, keyed by MusicBrainz release group id instead of a video id.
+const posterTypeAlbum models.ContentType = "album"
+
 type PosterArgs struct {
 	t      models.ContentType
 	imdbID string
@@ -50,7 +54,7 @@ type PosterArgs struct {
 
 func (s *Handler) bindPosterArgs(c *gin.Context) (*PosterArgs, error) {
 	t := models.ContentType(c.Param("type"))
-	if t != models.ContentTypeSeries && t != models.ContentTypeMovie {
+	if t != models.ContentTypeSeries && t != models.ContentTypeMovie && t != posterTypeAlbum {
 		return nil, errors.Errorf("wrong video type %v", t)
 	}
 	file := c.Param("file")
@@ -129,15 +133,15 @@ func (s *Handler) generateETag(data []byte) string {
 }
 
 func (s *Handler) getResizedPoster(ctx context.Context, db *pg.DB, args *PosterArgs) (*image.NRGBA, error) {
-	md, err := s.getPosterMetadata(ctx, db, args.t, args.imdbID)
+	posterURL, err := s.getPosterURL(ctx, db, args.t, args.imdbID)
 	if err != nil {
 		return nil, err
 	}
-	if md == nil || md.PosterURL == "" {
+	if posterURL == "" {
 		return nil, errors.Wrapf(errPosterNotFound, "%s %s", args.t, args.imdbID)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", md.PosterURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", posterURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +201,21 @@ func (s *Handler) getResizedJPEGPoster(ctx context.Context, db *pg.DB, args *Pos
 	return &buf, nil
 }
 
+func (s *Handler) getPosterURL(ctx context.Context, db *pg.DB, t models.ContentType, id string) (string, error) {
+	if t == posterTypeAlbum {
+		am, err := models.GetAlbumMetadataByMBID(ctx, db, id)
+		if err != nil || am == nil {
+			return "", err
+		}
+		return am.CoverURL, nil
+	}
+	md, err := s.getPosterMetadata(ctx, db, t, id)
+	if err != nil || md == nil {
+		return "", err
+	}
+	return md.PosterURL, nil
+}
+
 func (s *Handler) getPosterMetadata(ctx context.Context, db *pg.DB, t models.ContentType, videoID string) (md *models.VideoMetadata, err error) {
 	// First, try the persisted enrichment record. If a torrent has been
 	// enriched, the poster URL is already in series_metadata/movie_metadata
@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/webtor-io/web-ui/models"
 	"github.com/webtor-io/web-ui/services/auth"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
@@ -32,5 +33,9 @@ func (s *Handler) removeFromLibrary(ctx context.Context, c *gin.Context, u *auth
 	if db == nil {
 		return errors.New("no db")
 	}
-	return models.RemoveFromLibrary(ctx, db, u.ID, rID)
+	if err = models.RemoveFromLibrary(ctx, db, u.ID, rID); err != nil {
+		return
+	}
+	s.events.Publish(le.Event{Kind: le.Removed, UserID: u.ID.String(), ResourceID: rID})
+	return nil
 }
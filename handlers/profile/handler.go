@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,14 +19,18 @@ import (
 	"github.com/webtor-io/web-ui/models"
 	at "github.com/webtor-io/web-ui/services/access_token"
 	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/autoindex"
+	"github.com/webtor-io/web-ui/services/calendar"
 	"github.com/webtor-io/web-ui/services/claims"
 	"github.com/webtor-io/web-ui/services/common"
 	"github.com/webtor-io/web-ui/services/data_export"
 	"github.com/webtor-io/web-ui/services/i18n"
 	"github.com/webtor-io/web-ui/services/libapi"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
 	pay "github.com/webtor-io/web-ui/services/payments"
 	rss "github.com/webtor-io/web-ui/services/release_subscription"
 	"github.com/webtor-io/web-ui/services/s3"
+	"github.com/webtor-io/web-ui/services/sftp"
 	"github.com/webtor-io/web-ui/services/stremio"
 	ua "github.com/webtor-io/web-ui/services/url_alias"
 	usettings "github.com/webtor-io/web-ui/services/user_settings"
@@ -39,6 +45,12 @@ import (
 type BackendTypeInfo struct {
 	Type        string
 	DisplayName string
+	// ManagesTorrents marks types whose account torrent list can be
+	// browsed from Webtor
+	ManagesTorrents bool
+	// ServesUsenet marks types that download Usenet releases sent from
+	// Discover
+	ServesUsenet bool
 }
 
 // S3Credentials is what a user pastes into rclone, the aws CLI or Cyberduck.
@@ -49,6 +61,15 @@ type S3Credentials struct {
 	AccessKey string
 	SecretKey string
 	Region    string
+	// Proxy is the key's proxy mode (models.AccessToken.Proxy).
+	Proxy bool
+}
+
+// SFTPCredentials is what a user types into an SFTP client. Any user name
+// works: the password alone identifies the account.
+type SFTPCredentials struct {
+	Address  string
+	Password string
 }
 
 // APICredentials is what a script sends as `Authorization: Bearer <key>`.
@@ -62,7 +83,15 @@ type APICredentials struct {
 type Data struct {
 	StremioAddonURL       string
 	WebDAVURL             string
+	WebDAVProxy           bool
+	BrowseURL             string
+	CalendarURL           string
+	CalendarWebcalURL     htmltemplate.URL
 	S3                    *S3Credentials
+	SFTP                  *SFTPCredentials
+	SFTPAddress           string
+	SSHKeys               []models.UserSSHKey
+	SSHKeyLimit           int
 	API                   *APICredentials
 	APIDocsURL            string
 	Devices               []DeviceItem
@@ -81,6 +110,8 @@ type Data struct {
 	ErrKey                string
 	DisableWebDAV         bool
 	DisableS3             bool
+	DisableBrowse         bool
+	DisableSFTP           bool
 	DisableAPI            bool
 	DisableEmbed          bool
 	// HasPayments toggles the "my payments" link: shown only when the user
@@ -98,17 +129,21 @@ type Handler struct {
 	userSettings  *usettings.Service
 	payments      *pay.Client
 	releaseSubs   *rss.Service
+	lr            *lr.LinkResolver
 	disableWebDAV bool
 	disableS3     bool
+	disableBrowse bool
+	disableSFTP   bool
 	disableAPI    bool
 	disableEmbed  bool
 	s3Secret      string
 	s3Endpoint    string
+	sftpAddress   string
 	apiEndpoint   string
 	domain        string
 }
 
-func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Context], at *at.AccessToken, ual *ua.UrlAlias, pg *cs.PG, cl *claims.Claims, v *vault.Vault, us *usettings.Service, payments *pay.Client, releaseSubs *rss.Service) {
+func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Context], at *at.AccessToken, ual *ua.UrlAlias, pg *cs.PG, cl *claims.Claims, v *vault.Vault, us *usettings.Service, payments *pay.Client, releaseSubs *rss.Service, resolver *lr.LinkResolver) {
 	h := &Handler{
 		tb:            tm.MustRegisterViews("profile/*").WithLayout("main"),
 		at:            at,
@@ -119,12 +154,16 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Co
 		userSettings:  us,
 		payments:      payments,
 		releaseSubs:   releaseSubs,
+		lr:            resolver,
 		disableWebDAV: c.Bool(common.DisableWebDAVFlag),
 		disableS3:     c.Bool(common.DisableS3Flag),
+		disableBrowse: c.Bool(common.DisableBrowseFlag),
+		disableSFTP:   !sftp.Enabled(c),
 		disableAPI:    c.Bool(common.DisableAPIFlag),
 		disableEmbed:  c.Bool(common.DisableEmbedFlag),
 		s3Secret:      s3.SigningSecret(c),
 		s3Endpoint:    s3.PublicEndpoint(c),
+		sftpAddress:   sftp.PublicAddress(c),
 		apiEndpoint:   libapi.PublicEndpoint(c),
 		domain:        c.String(common.DomainFlag),
 	}
@@ -134,14 +173,33 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Co
 	gr.POST("/delete", h.delete)
 	gr.GET("/export", h.export)
 	gr.POST("/settings", h.updateSettings)
+	gr.POST("/subscription-mail", h.updateSubscriptionMail)
 }
 
 // getAvailableBackendTypes returns the list of available streaming backend types
-func getAvailableBackendTypes() []BackendTypeInfo {
-	return []BackendTypeInfo{
-		{Type: string(models.StreamingBackendTypeRealDebrid), DisplayName: "Real-Debrid"},
-		{Type: string(models.StreamingBackendTypeTorbox), DisplayName: "Torbox"},
+func (s *Handler) getAvailableBackendTypes() []BackendTypeInfo {
+	var types []BackendTypeInfo
+	for _, t := range []models.StreamingBackendType{
+		models.StreamingBackendTypeRealDebrid,
+		models.StreamingBackendTypeTorbox,
+		models.StreamingBackendTypeAllDebrid,
+		models.StreamingBackendTypePremiumize,
+		models.StreamingBackendTypeSABnzbd,
+		models.StreamingBackendTypeNZBGet,
+	} {
+		usenet := s.lr != nil && s.lr.ServesUsenet(t)
+		// Self-hosted backends are only offered where they are registered
+		if t.SelfHosted() && !usenet {
+			continue
+		}
+		types = append(types, BackendTypeInfo{
+			Type:            string(t),
+			DisplayName:     t.DisplayName(),
+			ManagesTorrents: s.lr != nil && s.lr.ManagesTorrents(t),
+			ServesUsenet:    usenet,
+		})
 	}
+	return types
 }
 
 func (s *Handler) getStremioAddonURL(c *gin.Context) (string, error) {
@@ -159,6 +217,35 @@ func (s *Handler) getStremioAddonURL(c *gin.Context) (string, error) {
 
 }
 
+// getCalendarURL returns the upcoming-episodes feed path, or "" when the
+// user has not issued one yet.
+func (s *Handler) getCalendarURL(c *gin.Context) (string, error) {
+	at, err := s.at.GetTokenByName(c, calendar.TokenName)
+	if at == nil {
+		return "", err
+	}
+	url := fmt.Sprintf("/%s/%s/calendar/", common.AccessTokenParamName, at.Token)
+
+	al, err := s.ual.Get(c.Request.Context(), url, false)
+	if err != nil {
+		return "", err
+	}
+	return al + "/episodes.ics", nil
+}
+
+// webcalURL rewrites an absolute feed URL to the webcal scheme, which
+// calendar apps open as a subscription rather than a one-off import. Typed
+// as trusted: html/template would otherwise blank an href in a scheme it
+// does not know.
+func webcalURL(abs string) htmltemplate.URL {
+	u, err := url.Parse(abs)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	u.Scheme = "webcal"
+	return htmltemplate.URL(u.String())
+}
+
 // getS3Credentials returns the endpoint/key/secret triple, or nil when the user
 // has not issued S3 credentials yet (the profile then shows the generate
 // button, same as WebDAV).
@@ -173,6 +260,24 @@ func (s *Handler) getS3Credentials(c *gin.Context) (*S3Credentials, error) {
 		AccessKey: key,
 		SecretKey: s3.DeriveSecretKey(s.s3Secret, key),
 		Region:    s3.DefaultRegion,
+		Proxy:     at.Proxy,
+	}, nil
+}
+
+// getSFTPCredentials returns the address/password pair, or nil when the user
+// has not issued a password yet (the profile then shows the generate button,
+// same as S3).
+func (s *Handler) getSFTPCredentials(c *gin.Context) (*SFTPCredentials, error) {
+	if s.disableSFTP {
+		return nil, nil
+	}
+	at, err := s.at.GetTokenByName(c, sftp.TokenName)
+	if at == nil {
+		return nil, err
+	}
+	return &SFTPCredentials{
+		Address:  s.sftpAddress,
+		Password: at.Token.String(),
 	}, nil
 }
 
@@ -225,18 +330,40 @@ func (s *Handler) getAPICredentials(c *gin.Context) (*APICredentials, error) {
 	}, nil
 }
 
-func (s *Handler) getWebDAVURL(c *gin.Context) (string, error) {
+// getWebDAVURL returns the mount URL and the token's proxy mode, or "" when
+// the user has not generated one yet.
+func (s *Handler) getWebDAVURL(c *gin.Context) (string, bool, error) {
 	at, err := s.at.GetTokenByName(c, "webdav")
 	if at == nil {
-		return "", err
+		return "", false, err
 	}
 	url := fmt.Sprintf("/%s/%s/webdav/fs/", common.AccessTokenParamName, at.Token)
 
+	al, err := s.ual.Get(c.Request.Context(), url, true)
+	if err != nil {
+		return "", false, err
+	}
+	return al + "/webdav/", at.Proxy, nil
+}
+
+// getBrowseURL returns the browser index URL, or "" when the user has not
+// generated one yet. Proxied alias, as for WebDAV: the index links are
+// relative and must keep resolving under the short URL.
+func (s *Handler) getBrowseURL(c *gin.Context) (string, error) {
+	if s.disableBrowse {
+		return "", nil
+	}
+	at, err := s.at.GetTokenByName(c, autoindex.TokenName)
+	if at == nil {
+		return "", err
+	}
+	url := fmt.Sprintf("/%s/%s/browse/fs/", common.AccessTokenParamName, at.Token)
+
 	al, err := s.ual.Get(c.Request.Context(), url, true)
 	if err != nil {
 		return "", err
 	}
-	return al + "/webdav/", nil
+	return al + "/", nil
 }
 
 func deleteUser(ctx context.Context, db *pg.DB, userID uuid.UUID) error {
@@ -312,16 +439,35 @@ func (s *Handler) get(c *gin.Context) {
 		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get stremio addon url"))
 		return
 	}
-	webdavURL, err := s.getWebDAVURL(c)
+	webdavURL, webdavProxy, err := s.getWebDAVURL(c)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get webdav url"))
 		return
 	}
+	browseURL, err := s.getBrowseURL(c)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get browse url"))
+		return
+	}
+	calendarURL, err := s.getCalendarURL(c)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get calendar url"))
+		return
+	}
+	var calendarWebcalURL htmltemplate.URL
+	if calendarURL != "" {
+		calendarWebcalURL = webcalURL(s.domain + calendarURL)
+	}
 	s3Creds, err := s.getS3Credentials(c)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get s3 credentials"))
 		return
 	}
+	sftpCreds, err := s.getSFTPCredentials(c)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get sftp credentials"))
+		return
+	}
 	apiCreds, err := s.getAPICredentials(c)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get api credentials"))
@@ -342,6 +488,15 @@ func (s *Handler) get(c *gin.Context) {
 		return
 	}
 
+	var sshKeys []models.UserSSHKey
+	if !s.disableSFTP {
+		sshKeys, err = models.ListUserSSHKeys(c.Request.Context(), db, u.ID)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to get ssh keys"))
+			return
+		}
+	}
+
 	// Get user addon URLs
 	addonUrls, err := models.GetAllUserStremioAddonUrls(c.Request.Context(), db, u.ID)
 	if err != nil {
@@ -422,7 +577,15 @@ func (s *Handler) get(c *gin.Context) {
 	s.tb.Build("profile/get").HTML(http.StatusOK, web.NewContext(c).WithData(&Data{
 		StremioAddonURL:       stremioURL,
 		WebDAVURL:             webdavURL,
+		WebDAVProxy:           webdavProxy,
+		BrowseURL:             browseURL,
+		CalendarURL:           calendarURL,
+		CalendarWebcalURL:     calendarWebcalURL,
 		S3:                    s3Creds,
+		SFTP:                  sftpCreds,
+		SFTPAddress:           s.sftpAddress,
+		SSHKeys:               sshKeys,
+		SSHKeyLimit:           models.MaxUserSSHKeys,
 		API:                   apiCreds,
 		APIDocsURL:            s.apiEndpoint + "/docs/index.html",
 		Devices:               devices,
@@ -433,13 +596,15 @@ func (s *Handler) get(c *gin.Context) {
 		SubscriptionLimit:     rss.FreeTierLimit,
 		StremioSettings:       ss,
 		StreamingBackends:     streamingBackends,
-		AvailableBackendTypes: getAvailableBackendTypes(),
+		AvailableBackendTypes: s.getAvailableBackendTypes(),
 		VaultStats:            vaultStats,
 		UserSettings:          userSettings,
 		ErrKey:                c.Query("err"),
 		HasPayments:           hasPayments,
 		DisableWebDAV:         s.disableWebDAV,
 		DisableS3:             s.disableS3,
+		DisableBrowse:         s.disableBrowse,
+		DisableSFTP:           s.disableSFTP,
 		DisableAPI:            s.disableAPI,
 		DisableEmbed:          s.disableEmbed,
 	}))
@@ -462,3 +627,62 @@ func (s *Handler) updateSettings(c *gin.Context) {
 	}
 	web.RedirectWithSuccess(c)
 }
+
+// updateSubscriptionMail stores how release-subscription mail is delivered:
+// immediate or as a daily / weekly digest, quiet hours, and the time zone
+// the hours are in. Async like updateSettings.
+func (s *Handler) updateSubscriptionMail(c *gin.Context) {
+	u := auth.GetUserFromContext(c)
+	digest, start, end, tz, err := parseSubscriptionMail(c)
+	if err != nil {
+		web.RedirectWithError(c, web.NewUserError("error.subscriptionMailInvalid", err))
+		return
+	}
+	if err := s.userSettings.SetMail(c.Request.Context(), u.ID, digest, start, end, tz); err != nil {
+		web.RedirectWithError(c, err)
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.settingsSaved")
+}
+
+// parseSubscriptionMail validates the form. Quiet hours need both edges or
+// neither; the time zone must be one the server can load, or the poller
+// would read every window in UTC without telling anyone.
+func parseSubscriptionMail(c *gin.Context) (string, *int16, *int16, string, error) {
+	digest := c.PostForm("digest")
+	switch digest {
+	case models.SubscriptionDigestImmediate, models.SubscriptionDigestDaily, models.SubscriptionDigestWeekly:
+	default:
+		return "", nil, nil, "", errors.Errorf("unknown digest mode %q", digest)
+	}
+	start, err := parseHour(c.PostForm("quiet_start"))
+	if err != nil {
+		return "", nil, nil, "", err
+	}
+	end, err := parseHour(c.PostForm("quiet_end"))
+	if err != nil {
+		return "", nil, nil, "", err
+	}
+	if (start == nil) != (end == nil) {
+		return "", nil, nil, "", errors.New("quiet hours need both a start and an end")
+	}
+	tz := strings.TrimSpace(c.PostForm("time_zone"))
+	if tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return "", nil, nil, "", errors.Wrapf(err, "unknown time zone %q", tz)
+		}
+	}
+	return digest, start, end, tz, nil
+}
+
+func parseHour(v string) (*int16, error) {
+	if v == "" {
+		return nil, nil
+	}
+	h, err := strconv.Atoi(v)
+	if err != nil || h < 0 || h > 23 {
+		return nil, errors.Errorf("invalid hour %q", v)
+	}
+	out := int16(h)
+	return &out, nil
+}
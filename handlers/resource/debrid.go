@@ -0,0 +1,29 @@
+package resource
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+)
+
+// getDebridBackends returns the user's enabled backends whose torrent list
+// Webtor can manage, in the user's priority order.
+func (s *Handler) getDebridBackends(ctx context.Context, db *pg.DB, u *auth.User) ([]*models.StreamingBackend, error) {
+	if s.lr == nil {
+		return nil, nil
+	}
+	backends, err := models.GetUserStreamingBackends(ctx, db, u.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get streaming backends")
+	}
+	var res []*models.StreamingBackend
+	for _, b := range backends {
+		if b.Enabled && s.lr.ManagesTorrents(b.Type) {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
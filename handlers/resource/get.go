@@ -105,6 +105,9 @@ type GetData struct {
 	PathActions           map[string]*PathAction
 	RateForm              *RateForm
 	ReleaseSubBanner      *ReleaseSubscribeBanner
+	// DebridBackends are the user's enabled accounts the torrent can be
+	// sent to from this page
+	DebridBackends []*models.StreamingBackend
 	// ResourceMetadata carries per-torrent classification (is_adult /
 	// is_sport) and the parsed-name snapshot. Nil when classification
 	// hasn't run for this resource yet — templates treat nil as "no
@@ -192,6 +195,7 @@ func (s *Handler) prepareGetData(ctx context.Context, args *GetArgs) (*GetData,
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to check if resource is in-library")
 			}
+			d.DebridBackends, _ = s.getDebridBackends(ctx, db, args.User)
 		}
 		// Load enrichment data
 		d.Movie, _ = models.GetMovieWithMetadataByResourceID(ctx, db, args.ID)
@@ -17,6 +17,8 @@ import (
 	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/common"
 	"github.com/webtor-io/web-ui/services/enrich"
+	"github.com/webtor-io/web-ui/services/libapi"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
 	"github.com/webtor-io/web-ui/services/template"
 	"github.com/webtor-io/web-ui/services/vault"
 	"github.com/webtor-io/web-ui/services/web"
@@ -29,10 +31,13 @@ type Handler struct {
 	pg             *cs.PG
 	vault          *vault.Vault
 	enricher       *enrich.Enricher
+	lr             *lr.LinkResolver
 	useDirectLinks bool
+	// matchLimiter bounds match corrections per user
+	matchLimiter *libapi.RateLimiter
 }
 
-func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Context], api *api.Api, jobs *j.Jobs, pg *cs.PG, v *vault.Vault, en *enrich.Enricher) {
+func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Context], api *api.Api, jobs *j.Jobs, pg *cs.PG, v *vault.Vault, en *enrich.Enricher, resolver *lr.LinkResolver) {
 	helper := NewHelper()
 	h := &Handler{
 		api:            api,
@@ -41,11 +46,15 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, tm *template.Manager[*web.Co
 		pg:             pg,
 		vault:          v,
 		enricher:       en,
+		lr:             resolver,
 		useDirectLinks: c.BoolT(common.UseDirectLinks),
+		matchLimiter:   libapi.NewRateLimiterWith(0.1, 10),
 	}
 	r.POST("/", h.post)
 	r.GET("/share", h.share)
 	r.GET("/:resource_id/status", h.status)
+	r.GET("/:resource_id/match", h.match)
+	r.POST("/:resource_id/match", h.saveMatch)
 	r.GET("/:resource_id", func(c *gin.Context) {
 		rid := c.Param("resource_id")
 		if strings.HasPrefix(rid, "magnet") {
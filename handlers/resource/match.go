@@ -0,0 +1,264 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/claims"
+	sv "github.com/webtor-io/web-ui/services/common"
+	"github.com/webtor-io/web-ui/services/enrich"
+	"github.com/webtor-io/web-ui/services/i18n"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+// MatchData backs the "wrong match?" page: the title the resource was
+// parsed into, what it is matched to now, and the search for the right one
+type MatchData struct {
+	ResourceID  string
+	ContentType models.ContentType
+	ParsedTitle string
+	Current     *models.VideoMetadata
+	// Override is the user's own correction, nil if they made none
+	Override *models.MatchOverride
+	// Pending is set when the user's correction is not what decides the
+	// match: more library owners picked another title
+	Pending bool
+	// CanPick is set when the resource is in the user's library, and Paid
+	// when they are on a paid plan: only paid corrections count towards
+	// the match of every torrent with the same name
+	CanPick    bool
+	Paid       bool
+	Query      string
+	Year       string
+	Searched   bool
+	Candidates []*models.VideoMetadata
+	// Message is the toast key of the action that led to this render
+	Message string
+	ErrKey  string
+}
+
+func (s *Handler) match(c *gin.Context) {
+	if !auth.GetUserFromContext(c).HasAuth() {
+		lang := i18n.GetLang(c)
+		v := url.Values{
+			"return-url": []string{i18n.LangPath(lang, c.Request.URL.Path)},
+		}
+		c.Redirect(http.StatusFound, i18n.LangPath(lang, "/login")+"?"+v.Encode())
+		return
+	}
+	s.renderMatch(c, "", nil)
+}
+
+// matchLibrary answers "is the resource in the user's library", as an
+// interface so the access rule can be tested without a database.
+type matchLibrary interface {
+	IsInLibrary(ctx context.Context, uID uuid.UUID, resourceID string) (bool, error)
+}
+
+// pgMatchLibrary is the production lookup.
+type pgMatchLibrary struct{ db *pg.DB }
+
+func (s pgMatchLibrary) IsInLibrary(ctx context.Context, uID uuid.UUID, resourceID string) (bool, error) {
+	if s.db == nil {
+		return false, errors.New("no database connection available")
+	}
+	return models.IsInLibrary(ctx, s.db, uID, resourceID)
+}
+
+// matchAccess returns the status a correction of the resource is refused
+// with, or 0 when the user may make it. Only users who have the torrent
+// in their library correct its match: the movie and series rows are
+// shared, and a pick from someone who never added the torrent says
+// nothing about it.
+func matchAccess(ctx context.Context, lib matchLibrary, user *auth.User, resourceID string) (int, error) {
+	if user == nil || !user.HasAuth() {
+		return http.StatusForbidden, nil
+	}
+	ok, err := lib.IsInLibrary(ctx, user.ID, resourceID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !ok {
+		return http.StatusForbidden, nil
+	}
+	return 0, nil
+}
+
+func (s *Handler) saveMatch(c *gin.Context) {
+	user := auth.GetUserFromContext(c)
+	if !user.HasAuth() {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	// Every pick may send a lookup to each mapper and rewrite shared rows.
+	if s.matchLimiter != nil {
+		if _, ok := s.matchLimiter.Take(user.ID.String()); !ok {
+			c.Status(http.StatusTooManyRequests)
+			return
+		}
+	}
+	status, err := matchAccess(c.Request.Context(), pgMatchLibrary{db: s.pg.Get()}, user, c.Param("resource_id"))
+	if err != nil {
+		_ = c.AbortWithError(status, errors.Wrap(err, "failed to check library membership"))
+		return
+	}
+	if status != 0 {
+		c.Status(status)
+		return
+	}
+	msg, err := s.saveMatchOverride(c)
+	if err != nil {
+		log.WithError(err).WithField("resource_id", c.Param("resource_id")).Warn("failed to save match override")
+	}
+	s.renderMatch(c, msg, err)
+}
+
+// saveMatchOverride stores the user's pick — a title, "not a movie or
+// show", or going back to the automatic match — and returns the toast key
+func (s *Handler) saveMatchOverride(c *gin.Context) (string, error) {
+	id, err := matchResourceID(c)
+	if err != nil {
+		return "", err
+	}
+	if s.enricher == nil {
+		return "", errors.New("enrichment is not configured")
+	}
+	ctx := c.Request.Context()
+	user := auth.GetUserFromContext(c)
+	if c.PostForm("reset") != "" {
+		return "toast.matchReset", matchError(s.enricher.ResetMatchOverride(ctx, user.ID, id))
+	}
+	var videoID *string
+	if c.PostForm("not_media") == "" {
+		v := strings.TrimSpace(c.PostForm("video_id"))
+		if v == "" {
+			return "", web.NewUserError("error.matchUnknownTitle", errors.New("no title picked"))
+		}
+		videoID = &v
+	}
+	paid := isPaid(claims.GetFromContext(c))
+	return "toast.matchSaved", matchError(s.enricher.SaveMatchOverride(ctx, user.ID, id, videoID, paid))
+}
+
+// renderMatch shows the page after the action, if any. msg is shown only
+// when the action went through.
+func (s *Handler) renderMatch(c *gin.Context, msg string, actionErr error) {
+	tpl := s.tb.Build("resource/match")
+	d := &MatchData{
+		ResourceID: c.Param("resource_id"),
+		Query:      strings.TrimSpace(c.Query("q")),
+		Year:       strings.TrimSpace(c.Query("year")),
+	}
+	if actionErr != nil {
+		d.ErrKey = web.ClassifyError(actionErr)
+	} else {
+		d.Message = msg
+	}
+	if err := s.prepareMatchData(c, d); err != nil {
+		log.WithError(err).WithField("resource_id", d.ResourceID).Warn("failed to prepare match page")
+		if d.ErrKey == "" {
+			d.ErrKey = web.ClassifyError(err)
+		}
+	}
+	if d.Message == "toast.matchSaved" && d.Pending {
+		d.Message = "toast.matchPending"
+	}
+	tpl.HTML(http.StatusOK, web.NewContext(c).WithData(d))
+}
+
+func (s *Handler) prepareMatchData(c *gin.Context, d *MatchData) error {
+	id, err := matchResourceID(c)
+	if err != nil {
+		return err
+	}
+	d.ResourceID = id
+	db := s.pg.Get()
+	if db == nil || s.enricher == nil {
+		return errors.New("no database connection available")
+	}
+	ctx := c.Request.Context()
+	target, err := s.enricher.GetMatchTarget(ctx, id)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return matchError(enrich.ErrNoMatchTarget)
+	}
+	content := target.GetContent()
+	d.ContentType = target.GetContentType()
+	d.ParsedTitle = content.Title
+	d.Current = target.GetMetadata()
+	user := auth.GetUserFromContext(c)
+	d.Override, err = models.GetUserMatchOverride(ctx, db, user.ID, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to get match override")
+	}
+	d.Pending = d.Override != nil && !isCurrentMatch(d.Override, d.Current)
+	d.CanPick, err = models.IsInLibrary(ctx, db, user.ID, id)
+	if err != nil {
+		return err
+	}
+	d.Paid = isPaid(claims.GetFromContext(c))
+	if d.Query == "" {
+		d.Query = content.Title
+		if content.Year != nil {
+			d.Year = strconv.Itoa(int(*content.Year))
+		}
+		return nil
+	}
+	d.Searched = true
+	var year *int16
+	if y, err := strconv.ParseInt(d.Year, 10, 16); err == nil && y > 0 {
+		yy := int16(y)
+		year = &yy
+	}
+	d.Candidates, err = s.enricher.SearchCandidates(ctx, d.Query, year, d.ContentType)
+	if err != nil {
+		return web.NewUserError("error.matchSearchFailed", err)
+	}
+	return nil
+}
+
+// isCurrentMatch reports whether the correction is what the resource is
+// matched to now
+func isCurrentMatch(o *models.MatchOverride, current *models.VideoMetadata) bool {
+	if o.NotMedia() {
+		return current == nil
+	}
+	return current != nil && current.VideoID == *o.VideoID
+}
+
+func isPaid(cl *claims.Data) bool {
+	return cl != nil && cl.Context != nil && cl.Context.Tier != nil && cl.Context.Tier.Id != 0
+}
+
+func matchResourceID(c *gin.Context) (string, error) {
+	id := c.Param("resource_id")
+	if sv.SHA1R.FindString(id) != id {
+		return "", web.NewUserError("error.not_found", errors.Errorf("wrong resource provided resource_id=%v", id))
+	}
+	return id, nil
+}
+
+// matchError keys a failed correction for the user
+func matchError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, enrich.ErrNoMatchTarget):
+		return web.NewUserError("error.matchNoTarget", err)
+	case errors.Is(err, enrich.ErrUnknownTitle):
+		return web.NewUserError("error.matchUnknownTitle", err)
+	}
+	return err
+}
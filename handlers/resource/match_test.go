@@ -0,0 +1,63 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+)
+
+type fakeMatchLibrary struct {
+	owned map[uuid.UUID]string
+	err   error
+}
+
+func (f fakeMatchLibrary) IsInLibrary(_ context.Context, uID uuid.UUID, resourceID string) (bool, error) {
+	return f.owned[uID] == resourceID, f.err
+}
+
+func TestMatchAccess(t *testing.T) {
+	const rid = "08ada5a7a6183aae1e09d831df6748d566095a10"
+	owner := &auth.User{ID: uuid.NewV4()}
+	stranger := &auth.User{ID: uuid.NewV4()}
+	lib := fakeMatchLibrary{owned: map[uuid.UUID]string{owner.ID: rid}}
+
+	for _, tt := range []struct {
+		name string
+		lib  fakeMatchLibrary
+		user *auth.User
+		want int
+	}{
+		{"owner", lib, owner, 0},
+		{"not in library", lib, stranger, http.StatusForbidden},
+		{"anonymous", lib, &auth.User{}, http.StatusForbidden},
+		{"lookup fails", fakeMatchLibrary{err: errors.New("boom")}, owner, http.StatusInternalServerError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := matchAccess(context.Background(), tt.lib, tt.user, rid)
+			if got != tt.want {
+				t.Errorf("matchAccess = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCurrentMatch(t *testing.T) {
+	id := "tt0133093"
+	pick := &models.MatchOverride{VideoID: &id}
+	notMedia := &models.MatchOverride{}
+	current := &models.VideoMetadata{VideoID: id}
+	other := &models.VideoMetadata{VideoID: "tt0000001"}
+
+	if !isCurrentMatch(pick, current) || isCurrentMatch(pick, other) || isCurrentMatch(pick, nil) {
+		t.Error("a title pick is current only when the resource is matched to it")
+	}
+	if !isCurrentMatch(notMedia, nil) || isCurrentMatch(notMedia, current) {
+		t.Error("a not-media pick is current only when the resource has no match")
+	}
+}
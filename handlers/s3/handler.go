@@ -16,8 +16,12 @@ import (
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/claims"
 	co "github.com/webtor-io/web-ui/services/common"
+	cp "github.com/webtor-io/web-ui/services/content_proxy"
 	"github.com/webtor-io/web-ui/services/libfs"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
 	s3 "github.com/webtor-io/web-ui/services/s3"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
@@ -29,19 +33,32 @@ const CredentialsPath = "/s3-credentials"
 var scopes = []string{s3.ScopeRead, s3.ScopeWrite}
 
 type Handler struct {
-	at *at.AccessToken
-	sh *s3.Handler
+	at    *at.AccessToken
+	sh    *s3.Handler
+	proxy *cp.Proxy
 }
 
-func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessToken, sapi *api.Api, jobs *j.Jobs) {
+func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessToken, sapi *api.Api, jobs *j.Jobs, subs *us.Service, events *le.Bus, resolver *lr.LinkResolver, proxy *cp.Proxy) {
 	if c.Bool(co.DisableS3Flag) {
 		return
 	}
 	// Same tree as WebDAV (services/libfs): the folders a user sees over S3 and
 	// over WebDAV are the same objects, by construction.
+	sh := s3.New(libfs.New(pg, sapi, jobs, subs, events, resolver), s3.SigningSecret(c), s3.MountPath)
+	sh.VolatileBuckets = map[string]bool{
+		libfs.RootUnwatched:  true,
+		libfs.RootInProgress: true,
+		libfs.RootVault:      true,
+		// Downloads finish on the backend, with no library event.
+		libfs.RootUsenet: true,
+	}
+	events.Subscribe(func(e le.Event) {
+		sh.Invalidate(e.UserID)
+	})
 	h := &Handler{
-		at: ats,
-		sh: s3.New(libfs.New(pg, sapi, jobs), s3.SigningSecret(c), s3.MountPath),
+		at:    ats,
+		sh:    sh,
+		proxy: proxy,
 	}
 
 	cr := r.Group(CredentialsPath)
@@ -49,6 +66,7 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessTok
 	cr.Use(claims.IsPaid)
 	cr.POST("/generate", h.generateCredentials)
 	cr.POST("/regenerate", h.regenerateCredentials)
+	cr.POST("/proxy", h.setProxy)
 
 	// The protocol itself authenticates with SigV4, so it gets its own
 	// authorization middleware instead of at.HasScope/claims.IsPaid — clients
@@ -119,6 +137,7 @@ func (s *Handler) handle(c *gin.Context) {
 	u := auth.GetUserFromContext(c)
 	ctx := context.WithValue(c.Request.Context(), web.Context{}, web.NewContext(c))
 	ctx = s3.WithUser(ctx, u.ID.String())
+	ctx = cp.WithStream(ctx, s.proxy.For(c.Request, at.ProxyFromContext(ctx), u.ID.String(), tierRate(c)))
 	c.Request = c.Request.WithContext(ctx)
 	// Hand the protocol layer the URI as the client sent it: middleware in front
 	// of us rewrote both the path and the query, and the signature covers both.
@@ -145,6 +164,16 @@ func (s *Handler) regenerateCredentials(c *gin.Context) {
 	web.RedirectWithSuccessAndMessage(c, "toast.s3CredentialsRegenerated")
 }
 
+// setProxy switches proxy mode for the S3 access key: GetObject then streams
+// content instead of redirecting, for clients that do not follow the redirect.
+func (s *Handler) setProxy(c *gin.Context) {
+	if err := s.at.SetProxy(c, s3.TokenName, c.PostForm("proxy") == "true"); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to set s3 proxy mode"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.proxyModeUpdated")
+}
+
 func (s *Handler) abort(c *gin.Context, e *s3.Error) {
 	s3.WriteError(c.Writer, c.Request, e)
 	c.Abort()
@@ -166,3 +195,10 @@ func hasScope(scope []string, want string) bool {
 	}
 	return false
 }
+
+func tierRate(c *gin.Context) string {
+	if cl := api.GetClaimsFromContext(c); cl != nil {
+		return cl.Rate
+	}
+	return ""
+}
@@ -0,0 +1,316 @@
+package sftp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+	cs "github.com/webtor-io/common-services"
+	j "github.com/webtor-io/web-ui/jobs"
+	"github.com/webtor-io/web-ui/models"
+	at "github.com/webtor-io/web-ui/services/access_token"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/claims"
+	"github.com/webtor-io/web-ui/services/i18n"
+	"github.com/webtor-io/web-ui/services/libfs"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	sftp "github.com/webtor-io/web-ui/services/sftp"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
+	"github.com/webtor-io/web-ui/services/web"
+	"golang.org/x/crypto/ssh"
+)
+
+// CredentialsPath and KeysPath hold the profile-side forms.
+const (
+	CredentialsPath = "/sftp-credentials"
+	KeysPath        = "/ssh-keys"
+)
+
+var scopes = []string{sftp.ScopeRead, sftp.ScopeWrite}
+
+type Handler struct {
+	pg     *cs.PG
+	at     *at.AccessToken
+	sapi   *api.Api
+	claims *claims.Claims
+}
+
+// RegisterHandler wires the profile forms and returns the SFTP server, or nil
+// when SFTP is disabled.
+func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, ats *at.AccessToken, sapi *api.Api, jobs *j.Jobs, subs *us.Service, cl *claims.Claims, events *le.Bus, resolver *lr.LinkResolver) (*sftp.Server, error) {
+	if !sftp.Enabled(c) {
+		return nil, nil
+	}
+	h := &Handler{
+		pg:     pg,
+		at:     ats,
+		sapi:   sapi,
+		claims: cl,
+	}
+
+	cr := r.Group(CredentialsPath)
+	cr.Use(auth.HasAuth)
+	cr.Use(claims.IsPaid)
+	cr.POST("/generate", h.generateCredentials)
+	cr.POST("/regenerate", h.regenerateCredentials)
+
+	kr := r.Group(KeysPath)
+	kr.Use(auth.HasAuth)
+	kr.POST("/add", claims.IsPaid, h.addKey)
+	// Deleting stays open to lapsed subscribers: nobody should have to pay
+	// to revoke a key.
+	kr.POST("/delete/:id", h.deleteKey)
+
+	// Same tree as WebDAV and S3 (services/libfs).
+	return sftp.New(c, libfs.New(pg, sapi, jobs, subs, events, resolver), h, h.fetch)
+}
+
+func (s *Handler) generateCredentials(c *gin.Context) {
+	if _, err := s.at.Generate(c, sftp.TokenName, scopes); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to generate sftp credentials"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.sftpCredentialsGenerated")
+}
+
+// regenerateCredentials rotates the password. Sessions already open stay
+// open; new logins need the new one.
+func (s *Handler) regenerateCredentials(c *gin.Context) {
+	if _, err := s.at.Regenerate(c, sftp.TokenName, scopes); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to regenerate sftp credentials"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.sftpCredentialsRegenerated")
+}
+
+func (s *Handler) addKey(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	if err := s.storeKey(ctx, auth.GetUserFromContext(c), c.PostForm("key")); err != nil {
+		web.RedirectWithError(c, err)
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.sshKeyAdded")
+}
+
+func (s *Handler) deleteKey(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		web.RedirectWithError(c, err)
+		return
+	}
+	db := s.pg.Get()
+	if db == nil {
+		web.RedirectWithError(c, errors.New("no db"))
+		return
+	}
+	if err := models.DeleteUserSSHKey(c.Request.Context(), db, auth.GetUserFromContext(c).ID, id); err != nil {
+		web.RedirectWithError(c, err)
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.sshKeyDeleted")
+}
+
+// parseKey reads one authorized_keys line. The comment, usually user@host,
+// names the key; the line is stored normalised, without any options.
+func parseKey(line string) (key ssh.PublicKey, name string, err error) {
+	key, comment, _, rest, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(line)))
+	if err != nil {
+		return nil, "", web.NewUserError("error.invalidSSHKey", err)
+	}
+	if len(strings.TrimSpace(string(rest))) > 0 {
+		return nil, "", web.NewUserError("error.invalidSSHKey", errors.New("more than one key provided"))
+	}
+	name = strings.TrimSpace(comment)
+	if name == "" {
+		name = key.Type()
+	}
+	return key, name, nil
+}
+
+func (s *Handler) storeKey(ctx context.Context, u *auth.User, line string) error {
+	key, name, err := parseKey(line)
+	if err != nil {
+		return err
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("no db")
+	}
+	n, err := models.CountUserSSHKeys(ctx, db, u.ID)
+	if err != nil {
+		return err
+	}
+	if n >= models.MaxUserSSHKeys {
+		return errors.Errorf("maximum %d keys allowed", models.MaxUserSSHKeys)
+	}
+	err = models.CreateUserSSHKey(ctx, db, &models.UserSSHKey{
+		UserID:      u.ID,
+		Name:        name,
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))) + " " + name,
+		Fingerprint: ssh.FingerprintSHA256(key),
+	})
+	if errors.Is(err, models.ErrSSHKeyTaken) {
+		return errors.New("ssh key already exists")
+	}
+	return err
+}
+
+// Password logs in with the SFTP token. The user name is not checked: the
+// token alone identifies the account, so any name a client insists on works.
+func (s *Handler) Password(ctx context.Context, _ string, password string) (*sftp.Identity, error) {
+	token, err := uuid.FromString(strings.TrimSpace(password))
+	if err != nil {
+		return nil, errors.New("access denied")
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	t, err := models.GetUserByAccessTokenWithUser(ctx, db, token)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.User == nil || !hasScope(t.Scope, sftp.ScopeRead) {
+		return nil, errors.New("access denied")
+	}
+	if err := s.checkPaid(t.User); err != nil {
+		return nil, err
+	}
+	return &sftp.Identity{
+		UserID:   t.User.UserID.String(),
+		ReadOnly: !hasScope(t.Scope, sftp.ScopeWrite),
+	}, nil
+}
+
+// PublicKey logs in with an uploaded key. Keys are not scoped: whoever holds
+// the private half is the account owner. Nothing is checked or written here
+// beyond the lookup — anyone can offer a key, proof comes later — so the
+// plan check and the last-used stamp wait for Context.
+func (s *Handler) PublicKey(ctx context.Context, _ string, key ssh.PublicKey) (*sftp.Identity, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	k, err := models.GetUserSSHKeyByFingerprint(ctx, db, ssh.FingerprintSHA256(key))
+	if err != nil {
+		return nil, err
+	}
+	if k == nil || k.User == nil {
+		return nil, errors.New("access denied")
+	}
+	return &sftp.Identity{
+		UserID: k.User.UserID.String(),
+		KeyID:  k.UserSSHKeyID.String(),
+	}, nil
+}
+
+// checkPaid keeps SFTP to paid plans, like WebDAV and S3. Password logins run
+// it at login: a refused login is the only error every SFTP client shows the
+// user. Key logins can only run it after the handshake, in Context.
+func (s *Handler) checkPaid(u *models.User) error {
+	cl, err := s.claims.Get(&claims.Request{Email: u.Email, PatreonUserID: u.PatreonUserID})
+	if err != nil {
+		return errors.Wrap(err, "failed to get claims")
+	}
+	return paid(cl)
+}
+
+func paid(cl *claims.Data) error {
+	if cl == nil || cl.Context.Tier.Id == 0 {
+		return errors.New("sftp is available on paid plans only")
+	}
+	return nil
+}
+
+// Context builds the web context the library filesystem expects, the same one
+// an HTTP request from this user would get.
+func (s *Handler) Context(ctx context.Context, id *sftp.Identity, remote net.Addr) (context.Context, error) {
+	uID, err := uuid.FromString(id.UserID)
+	if err != nil {
+		return nil, err
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	mu, err := models.GetUserByID(ctx, db, uID)
+	if err != nil {
+		return nil, err
+	}
+	if mu == nil {
+		return nil, errors.New("user not found")
+	}
+	u := &auth.User{
+		ID:            mu.UserID,
+		Email:         mu.Email,
+		PatreonUserID: mu.PatreonUserID,
+		Tier:          mu.Tier,
+		CreatedAt:     mu.CreatedAt,
+	}
+	cl, err := s.claims.Get(&claims.Request{Email: u.Email, PatreonUserID: u.PatreonUserID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get claims")
+	}
+	if id.KeyID != "" {
+		if err := paid(cl); err != nil {
+			return nil, err
+		}
+		kID, err := uuid.FromString(id.KeyID)
+		if err != nil {
+			return nil, err
+		}
+		if err := models.TouchUserSSHKey(ctx, db, kID); err != nil {
+			return nil, err
+		}
+	}
+	host := remote.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ac, err := s.sapi.MakeClaims(cl, api.GenerateSessionIDFromUser(u), host, "sftp")
+	if err != nil {
+		return nil, err
+	}
+	settings, err := models.GetUserSettings(ctx, db, uID)
+	if err != nil {
+		return nil, err
+	}
+	lang := settings.GetLang()
+	if lang == "" {
+		lang = i18n.DefaultLang
+	}
+	return context.WithValue(ctx, web.Context{}, &web.Context{
+		User:         u,
+		Claims:       cl,
+		ApiClaims:    ac,
+		UserSettings: settings,
+		Lang:         lang,
+	}), nil
+}
+
+// fetch proxies torrent content from the streaming chain.
+func (s *Handler) fetch(ctx context.Context, u *url.URL, offset int64) (io.ReadCloser, error) {
+	return s.sapi.DownloadWithRange(ctx, u.String(), int(offset), -1)
+}
+
+func hasScope(scope []string, want string) bool {
+	for _, s := range scope {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+var _ sftp.Auth = (*Handler)(nil)
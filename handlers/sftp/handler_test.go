@@ -0,0 +1,43 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sk)))
+
+	key, name, err := parseKey("  " + line + " me@laptop\n")
+	if err != nil {
+		t.Fatalf("parseKey: %v", err)
+	}
+	if name != "me@laptop" {
+		t.Errorf("name = %q, want the comment", name)
+	}
+	if ssh.FingerprintSHA256(key) != ssh.FingerprintSHA256(sk) {
+		t.Error("fingerprint mismatch")
+	}
+
+	if _, name, err = parseKey(line); err != nil || name != "ssh-ed25519" {
+		t.Errorf("without comment: name = %q, err = %v", name, err)
+	}
+	if _, _, err = parseKey("not a key"); err == nil {
+		t.Error("garbage accepted")
+	}
+	if _, _, err = parseKey(line + "\n" + line); err == nil {
+		t.Error("two keys accepted")
+	}
+}
@@ -2,6 +2,7 @@ package backends
 
 import (
 	"context"
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -9,17 +10,27 @@ import (
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	cs "github.com/webtor-io/common-services"
+	j "github.com/webtor-io/web-ui/jobs"
 	"github.com/webtor-io/web-ui/models"
 	at "github.com/webtor-io/web-ui/services/access_token"
+	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/auth"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	"github.com/webtor-io/web-ui/services/template"
+	"github.com/webtor-io/web-ui/services/usenet"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
 type Handler struct {
-	at *at.AccessToken
-	pg *cs.PG
-	lr *lr.LinkResolver
+	at     *at.AccessToken
+	pg     *cs.PG
+	lr     *lr.LinkResolver
+	tb     template.Builder[*web.Context]
+	api    *api.Api
+	jobs   *j.Jobs
+	events *le.Bus
+	un     *usenet.Usenet
 }
 
 func NewHandler(at *at.AccessToken, pg *cs.PG, resolver *lr.LinkResolver) *Handler {
@@ -30,12 +41,27 @@ func NewHandler(at *at.AccessToken, pg *cs.PG, resolver *lr.LinkResolver) *Handl
 	}
 }
 
-func RegisterHandler(r *gin.Engine, at *at.AccessToken, pg *cs.PG, resolver *lr.LinkResolver) {
+func RegisterHandler(r *gin.Engine, tm *template.Manager[*web.Context], at *at.AccessToken, pg *cs.PG, resolver *lr.LinkResolver, api *api.Api, jobs *j.Jobs, events *le.Bus, un *usenet.Usenet) {
 	h := NewHandler(at, pg, resolver)
+	h.tb = tm.MustRegisterViews("streaming/*").WithLayout("main")
+	h.api = api
+	h.jobs = jobs
+	h.events = events
+	h.un = un
 	gr := r.Group("/streaming/backends")
 	gr.Use(auth.HasAuth)
 	gr.POST("/create", h.createBackend)
 	gr.POST("/update", h.updateBackends)
+	gr.POST("/send", h.send)
+	gr.GET("/:id/torrents", h.torrents)
+	gr.POST("/:id/torrents/delete", h.deleteTorrent)
+	gr.POST("/:id/torrents/import", h.importTorrent)
+	ur := r.Group("/usenet")
+	ur.Use(auth.HasAuth)
+	ur.GET("", h.usenetDownloads)
+	ur.POST("/send", h.sendUsenet)
+	ur.POST("/:id/delete", h.deleteUsenet)
+	ur.GET("/:id/play", h.playUsenet)
 }
 
 func (s *Handler) createBackend(c *gin.Context) {
@@ -44,8 +70,18 @@ func (s *Handler) createBackend(c *gin.Context) {
 	// Parse form data
 	backendType := models.StreamingBackendType(c.PostForm("type"))
 	accessToken := strings.TrimSpace(c.PostForm("access_token"))
+	config := make(models.StreamingBackendConfig)
+	if backendType.SelfHosted() {
+		filesURL, err := parseFilesURL(c.PostForm("files_url"))
+		if err != nil {
+			log.WithError(err).Warn("failed to add streaming backend")
+			web.RedirectWithError(c, err)
+			return
+		}
+		config[models.StreamingBackendConfigFilesURL] = filesURL
+	}
 
-	err := s.addStreamingBackend(c.Request.Context(), backendType, accessToken, true, user)
+	err := s.addStreamingBackend(c.Request.Context(), backendType, accessToken, config, true, user)
 	if err != nil {
 		log.WithError(err).Error("failed to add streaming backend")
 		web.RedirectWithError(c, err)
@@ -54,7 +90,7 @@ func (s *Handler) createBackend(c *gin.Context) {
 	web.RedirectWithSuccessAndMessage(c, "toast.backendAdded")
 }
 
-func (s *Handler) addStreamingBackend(ctx context.Context, backendType models.StreamingBackendType, accessToken string, enabled bool, user *auth.User) error {
+func (s *Handler) addStreamingBackend(ctx context.Context, backendType models.StreamingBackendType, accessToken string, config models.StreamingBackendConfig, enabled bool, user *auth.User) error {
 	// Get database connection
 	db := s.pg.Get()
 	if db == nil {
@@ -80,7 +116,7 @@ func (s *Handler) addStreamingBackend(ctx context.Context, backendType models.St
 		Type:        backendType,
 		Priority:    int16(priority),
 		Enabled:     enabled,
-		Config:      make(models.StreamingBackendConfig),
+		Config:      config,
 		AccessToken: accessToken,
 	}
 
@@ -102,6 +138,18 @@ func (s *Handler) addStreamingBackend(ctx context.Context, backendType models.St
 	return nil
 }
 
+// parseFilesURL checks the address a self-hosted Usenet backend serves its
+// finished downloads from. Webtor only redirects the player there, so it
+// has to be reachable from the user's browser, not from the server.
+func parseFilesURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", web.NewUserError("error.badFilesURL", errors.Errorf("bad files url %q", raw))
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
 func (s *Handler) updateBackends(c *gin.Context) {
 	user := auth.GetUserFromContext(c)
 
@@ -0,0 +1,243 @@
+package backends
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/common"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+// magnetizeTimeout bounds fetching the metadata of a torrent Webtor has not
+// seen yet, when it is imported from a debrid account into the library.
+const magnetizeTimeout = 60 * time.Second
+
+type torrentsData struct {
+	Backend  *models.StreamingBackend
+	Torrents []torrentItem
+	// Message is the toast key of the action that led to this render
+	Message string
+	ErrKey  string
+}
+
+type torrentItem struct {
+	co.Torrent
+	InLibrary bool
+}
+
+func (s *Handler) torrents(c *gin.Context) {
+	s.renderTorrents(c, "", nil)
+}
+
+func (s *Handler) deleteTorrent(c *gin.Context) {
+	err := s.withOwnBackend(c, func(ctx context.Context, b *models.StreamingBackend) error {
+		return debridError(s.lr.DeleteTorrent(ctx, b, c.PostForm("torrent_id")))
+	})
+	if err != nil {
+		log.WithError(err).Warn("failed to delete debrid torrent")
+	}
+	s.renderTorrents(c, "toast.torrentDeleted", err)
+}
+
+func (s *Handler) importTorrent(c *gin.Context) {
+	var rID string
+	err := s.withOwnBackend(c, func(ctx context.Context, b *models.StreamingBackend) (err error) {
+		rID, err = s.addToLibrary(c, c.PostForm("hash"))
+		return
+	})
+	if err != nil {
+		log.WithError(err).Warn("failed to import debrid torrent")
+	} else {
+		_, _ = s.jobs.Enrich(web.NewContext(c), rID)
+	}
+	s.renderTorrents(c, "toast.addedToLibrary", err)
+}
+
+// send adds the torrent from the resource page to one of the user's debrid
+// accounts, with the picked files selected.
+func (s *Handler) send(c *gin.Context) {
+	var fileIdx []int
+	for _, v := range c.PostFormArray("file_idx") {
+		idx, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		fileIdx = append(fileIdx, idx)
+	}
+	hash := c.PostForm("resource_id")
+	err := s.withBackend(c, c.PostForm("backend_id"), func(ctx context.Context, b *models.StreamingBackend) error {
+		if len(hash) != 40 {
+			return errors.New("wrong resource provided")
+		}
+		_, err := s.lr.AddTorrent(ctx, b, hash, fileIdx)
+		return debridError(err)
+	})
+	if err != nil {
+		log.WithError(err).Warn("failed to send torrent to debrid")
+		web.RedirectWithError(c, err)
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.sentToDebrid")
+}
+
+func (s *Handler) withOwnBackend(c *gin.Context, fn func(ctx context.Context, b *models.StreamingBackend) error) error {
+	return s.withBackend(c, c.Param("id"), fn)
+}
+
+// withBackend loads the backend and runs fn if it belongs to the user and
+// has a torrent list to manage.
+func (s *Handler) withBackend(c *gin.Context, idStr string, fn func(ctx context.Context, b *models.StreamingBackend) error) error {
+	b, err := s.getOwnBackend(c.Request.Context(), idStr, auth.GetUserFromContext(c))
+	if err != nil {
+		return err
+	}
+	return fn(c.Request.Context(), b)
+}
+
+func (s *Handler) getOwnBackend(ctx context.Context, idStr string, user *auth.User) (*models.StreamingBackend, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no database connection available")
+	}
+	id, err := uuid.FromString(idStr)
+	if err != nil {
+		return nil, web.NewUserError("error.not_found", errors.Wrap(err, "invalid backend id"))
+	}
+	backend, err := models.GetStreamingBackendByID(ctx, db, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get streaming backend")
+	}
+	if backend == nil {
+		return nil, web.NewUserError("error.not_found", errors.New("streaming backend not found"))
+	}
+	if backend.UserID != user.ID {
+		return nil, web.NewUserError("error.access_denied", errors.New("access denied"))
+	}
+	if !s.lr.ManagesTorrents(backend.Type) {
+		return nil, web.NewUserError("error.not_found", errors.Errorf("backend %s has no torrent list", backend.Type))
+	}
+	return backend, nil
+}
+
+// renderTorrents lists the account after the action, if any, so the page
+// always shows what is in the account now. msg is shown only when the action
+// went through.
+func (s *Handler) renderTorrents(c *gin.Context, msg string, actionErr error) {
+	d := &torrentsData{}
+	if actionErr != nil {
+		d.ErrKey = web.ClassifyError(actionErr)
+	} else {
+		d.Message = msg
+	}
+	err := s.listTorrents(c, d)
+	if err != nil {
+		log.WithError(err).Warn("failed to list debrid torrents")
+		if d.ErrKey == "" {
+			d.ErrKey = web.ClassifyError(err)
+		}
+	}
+	s.tb.Build("streaming/torrents").HTML(http.StatusOK, web.NewContext(c).WithData(d))
+}
+
+func (s *Handler) listTorrents(c *gin.Context, d *torrentsData) error {
+	ctx := c.Request.Context()
+	user := auth.GetUserFromContext(c)
+	b, err := s.getOwnBackend(ctx, c.Param("id"), user)
+	if err != nil {
+		return err
+	}
+	d.Backend = b
+	list, err := s.lr.ListTorrents(ctx, b)
+	if err != nil {
+		return debridError(err)
+	}
+	hashes := make([]string, 0, len(list))
+	for _, t := range list {
+		hashes = append(hashes, t.Hash)
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("no database connection available")
+	}
+	inLib, err := models.InLibrary(ctx, db, user.ID, hashes)
+	if err != nil {
+		return err
+	}
+	d.Torrents = make([]torrentItem, 0, len(list))
+	for _, t := range list {
+		d.Torrents = append(d.Torrents, torrentItem{Torrent: t, InLibrary: inLib[t.Hash]})
+	}
+	return nil
+}
+
+// debridError keys a failed call to the debrid service for the user. The
+// service's own message is logged, not shown.
+func debridError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return web.NewUserError("error.debridFailed", err)
+}
+
+// addToLibrary adds the torrent with the infohash to the user's library.
+// Torrents that came into the debrid account from elsewhere may be new to
+// Webtor, so the magnet is stored first when the resource is unknown.
+func (s *Handler) addToLibrary(c *gin.Context, hash string) (string, error) {
+	ctx := c.Request.Context()
+	user := auth.GetUserFromContext(c)
+	clms := api.GetClaimsFromContext(c)
+	if len(hash) != 40 {
+		return "", errors.New("wrong resource provided")
+	}
+	hash, magnet, err := common.ResolveQueryHash(hash)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.api.GetResource(ctx, clms, hash)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load resource")
+	}
+	if res == nil {
+		mCtx, cancel := context.WithTimeout(ctx, magnetizeTimeout)
+		defer cancel()
+		_, err = s.api.StoreResource(mCtx, clms, []byte(magnet))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to load resource")
+		}
+	}
+	t, err := s.api.GetTorrentCached(ctx, clms, hash)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load resource")
+	}
+	mi, err := metainfo.Load(bytes.NewReader(t))
+	if err != nil {
+		return "", err
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return "", err
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return "", errors.New("no database connection available")
+	}
+	_, err = models.AddTorrentToLibrary(ctx, db, user.ID, hash, &info, "", int64(len(t)))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to add torrent to library")
+	}
+	s.events.Publish(le.Event{Kind: le.Added, UserID: user.ID.String(), ResourceID: hash})
+	return hash, nil
+}
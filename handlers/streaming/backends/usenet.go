@@ -0,0 +1,187 @@
+package backends
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/i18n"
+	"github.com/webtor-io/web-ui/services/usenet"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+type usenetData struct {
+	Downloads []*models.UsenetDownload
+	// Message is the toast key of the action that led to this render
+	Message string
+	ErrKey  string
+}
+
+type usenetSendRequest struct {
+	NZB string `json:"nzb"`
+}
+
+func (s *Handler) usenetDownloads(c *gin.Context) {
+	s.renderUsenet(c, "", nil)
+}
+
+// sendUsenet hands a release sealed by Discover to the user's Usenet
+// backends. Called from the stream modal, so it answers JSON, with the
+// message already in the user's language for the toast.
+func (s *Handler) sendUsenet(c *gin.Context) {
+	if s.un == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": i18n.T(c, "error.usenetFailed")})
+		return
+	}
+	var req usenetSendRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.NZB == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": i18n.T(c, "error.usenetExpired")})
+		return
+	}
+	user := auth.GetUserFromContext(c)
+	d, err := s.un.Send(c.Request.Context(), user.ID, req.NZB)
+	if err != nil {
+		err = usenetError(err)
+		log.WithError(err).Warn("failed to send usenet release")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"status": "error", "message": i18n.T(c, web.ClassifyError(err))})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": d.ID.String(), "message": i18n.T(c, "toast.sentToUsenet")})
+}
+
+func (s *Handler) deleteUsenet(c *gin.Context) {
+	err := s.deleteUsenetDownload(c)
+	if err != nil {
+		log.WithError(err).Warn("failed to delete usenet download")
+	}
+	s.renderUsenet(c, "toast.usenetDeleted", err)
+}
+
+// deleteUsenetDownload forgets the download. The job is removed from the
+// backend first, but a backend that fails to answer does not keep the row:
+// the user asked for it to go, and a leftover job is theirs to clean up.
+func (s *Handler) deleteUsenetDownload(c *gin.Context) error {
+	ctx := c.Request.Context()
+	user := auth.GetUserFromContext(c)
+	d, err := s.getOwnUsenetDownload(c)
+	if err != nil {
+		return err
+	}
+	if d.StreamingBackend != nil {
+		if err := s.lr.DeleteNZB(ctx, d.StreamingBackend, d.RemoteID); err != nil {
+			log.WithError(err).
+				WithField("usenet_download_id", d.ID).
+				Warn("failed to delete usenet job on backend")
+		}
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("no database connection available")
+	}
+	return models.DeleteUserUsenetDownload(ctx, db, user.ID, d.ID)
+}
+
+// playUsenet redirects to a direct link to a file of a finished download.
+// Links are asked for on every play because the backends' links expire.
+func (s *Handler) playUsenet(c *gin.Context) {
+	link, err := s.usenetLink(c)
+	if err != nil {
+		log.WithError(err).Warn("failed to get usenet file link")
+		s.renderUsenet(c, "", err)
+		return
+	}
+	c.Redirect(http.StatusFound, link)
+}
+
+func (s *Handler) usenetLink(c *gin.Context) (string, error) {
+	d, err := s.getOwnUsenetDownload(c)
+	if err != nil {
+		return "", err
+	}
+	if !d.Ready() || d.StreamingBackend == nil {
+		return "", web.NewUserError("error.usenetNotReady", errors.New("usenet download is not ready"))
+	}
+	fileID := c.Query("file")
+	found := false
+	for _, f := range d.Files {
+		if f.ID == fileID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", web.NewUserError("error.not_found", errors.Errorf("usenet download has no file %q", fileID))
+	}
+	link, err := s.lr.NZBLink(c.Request.Context(), d.StreamingBackend, d.RemoteID, fileID)
+	if err != nil {
+		return "", usenetError(err)
+	}
+	return link, nil
+}
+
+func (s *Handler) getOwnUsenetDownload(c *gin.Context) (*models.UsenetDownload, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no database connection available")
+	}
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, web.NewUserError("error.not_found", errors.Wrap(err, "invalid usenet download id"))
+	}
+	d, err := models.GetUserUsenetDownload(c.Request.Context(), db, auth.GetUserFromContext(c).ID, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get usenet download")
+	}
+	if d == nil {
+		return nil, web.NewUserError("error.not_found", errors.New("usenet download not found"))
+	}
+	return d, nil
+}
+
+// renderUsenet lists the user's downloads after the action, if any. msg is
+// shown only when the action went through.
+func (s *Handler) renderUsenet(c *gin.Context, msg string, actionErr error) {
+	d := &usenetData{}
+	if actionErr != nil {
+		d.ErrKey = web.ClassifyError(actionErr)
+	} else {
+		d.Message = msg
+	}
+	db := s.pg.Get()
+	if db == nil {
+		d.ErrKey = web.ClassifyError(errors.New("no database connection available"))
+	} else {
+		downloads, err := models.GetUserUsenetDownloads(c.Request.Context(), db, auth.GetUserFromContext(c).ID)
+		if err != nil {
+			log.WithError(err).Warn("failed to list usenet downloads")
+			if d.ErrKey == "" {
+				d.ErrKey = web.ClassifyError(err)
+			}
+		}
+		d.Downloads = downloads
+	}
+	s.tb.Build("streaming/usenet").HTML(http.StatusOK, web.NewContext(c).WithData(d))
+}
+
+// usenetError keys a failed Usenet action for the user. Anything that is
+// not already keyed is the backend's or the indexer's fault; their own
+// messages are logged, not shown.
+func usenetError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, usenet.ErrNoBackend):
+		return web.NewUserError("error.usenetNoBackend", err)
+	case errors.Is(err, usenet.ErrBadToken):
+		return web.NewUserError("error.usenetExpired", err)
+	}
+	var ue *web.UserError
+	if errors.As(err, &ue) {
+		return err
+	}
+	return web.NewUserError("error.usenetFailed", err)
+}
@@ -12,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/models"
 	at "github.com/webtor-io/web-ui/services/access_token"
 	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/auth"
@@ -28,6 +29,7 @@ type Handler struct {
 	pg     *cs.PG
 	lr     *lr.LinkResolver
 	secret string
+	domain string
 }
 
 func RegisterHandler(c *cli.Context, r *gin.Engine, at *at.AccessToken, b *stremio.Builder, pg *cs.PG, lr *lr.LinkResolver) {
@@ -37,6 +39,7 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, at *at.AccessToken, b *strem
 		pg:     pg,
 		lr:     lr,
 		secret: c.String(sv.SessionSecretFlag),
+		domain: c.String(sv.DomainFlag),
 	}
 
 	gr := r.Group("/stremio")
@@ -62,6 +65,9 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, at *at.AccessToken, b *strem
 	// this the probe 404s, Stremio treats the next episode's stream as dead
 	// and falls back to the source-selection screen instead of binge-playing.
 	grapi.Match([]string{http.MethodGet, http.MethodHead}, "/resolve/*data", h.resolve)
+	// /resolve redirects here rather than to the debrid link itself, so a
+	// player that reconnects mid-stream lands on a backend that still works.
+	grapi.Match([]string{http.MethodGet, http.MethodHead}, "/play/*data", h.play)
 }
 
 func (s *Handler) generateUrl(c *gin.Context) {
@@ -182,15 +188,15 @@ func episodeClaims(claims jwt.MapClaims) (int, int, bool) {
 	return int(sf), int(ef), true
 }
 
-func (s *Handler) resolve(c *gin.Context) {
-	// Step 1: Extract JWT token from URL path
+// parseToken reads the JWT from the route's data param. It aborts the
+// request and returns ok=false when there is none or it does not verify.
+func (s *Handler) parseToken(c *gin.Context) (jwt.MapClaims, bool) {
 	data := strings.TrimPrefix(c.Param("data"), "/")
 	if data == "" {
 		c.AbortWithStatus(http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
-	// Step 2: Parse and validate JWT token
 	token, err := jwt.Parse(data, func(token *jwt.Token) (interface{}, error) {
 		// Validate signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -202,16 +208,25 @@ func (s *Handler) resolve(c *gin.Context) {
 	if err != nil {
 		log.WithError(err).Warn("failed to parse JWT token")
 		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
-	// Step 3: Extract claims
 	jwtClaims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
 		log.Warn("invalid JWT token claims")
 		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+	return jwtClaims, true
+}
+
+func (s *Handler) resolve(c *gin.Context) {
+	// Steps 1-3: Extract, verify and read the JWT from the URL path
+	jwtClaims, ok := s.parseToken(c)
+	if !ok {
 		return
 	}
+	var err error
 
 	// Step 4: Extract claims. JWT shape: {hash, idx, exp}. Path resolution
 	// (when needed by user backends) and resource registration are handled
@@ -277,6 +292,12 @@ func (s *Handler) resolve(c *gin.Context) {
 		return
 	}
 
-	// Step 8: Redirect to destination URL
-	c.Redirect(http.StatusFound, linkResult.URL)
+	// Step 8: Redirect to destination URL. A debrid link goes through a
+	// play URL that can fail over; Webtor is the last resort already, so
+	// there is nothing to fail over to.
+	if linkResult.ServiceType == models.StreamingBackendTypeWebtor {
+		c.Redirect(http.StatusFound, linkResult.URL)
+		return
+	}
+	c.Redirect(http.StatusFound, s.playURL(c, hash, fileIdx, linkResult.ServiceType))
 }
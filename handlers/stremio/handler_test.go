@@ -4,8 +4,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // TestResolveRouteAcceptsHEAD guards the binge-watching fix: Stremio validates
@@ -38,3 +40,41 @@ func TestResolveRouteAcceptsHEAD(t *testing.T) {
 		}
 	}
 }
+
+// TestPlayRouteRejectsBadTokens covers the failover indirection /resolve
+// redirects debrid streams to. It is probed with HEAD the same way, and a
+// token without the backend it was minted for is refused before
+// LinkResolver is touched.
+func TestPlayRouteRejectsBadTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{secret: "test-secret"}
+	r := gin.New()
+	r.Match([]string{http.MethodGet, http.MethodHead}, "/stremio/play/*data", h.play)
+
+	noBackend := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"hash": "08ada5a7a6183aae1e09d831df6748d566095a10",
+		"idx":  0,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	})
+	noBackendString, err := noBackend.SignedString([]byte(h.secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		for _, tt := range []struct {
+			token string
+			want  int
+		}{
+			{token: "not-a-jwt", want: http.StatusUnauthorized},
+			{token: noBackendString, want: http.StatusBadRequest},
+		} {
+			req := httptest.NewRequest(method, "/stremio/play/"+tt.token, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("%s /stremio/play = %d, want %d", method, w.Code, tt.want)
+			}
+		}
+	}
+}
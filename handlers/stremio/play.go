@@ -0,0 +1,69 @@
+package stremio
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/claims"
+	sv "github.com/webtor-io/web-ui/services/common"
+)
+
+// playTTL bounds a play URL. It only has to outlive one playback session:
+// Stremio keeps the /resolve URL and resolves it again next time, never the
+// play URL it was redirected to.
+const playTTL = 6 * time.Hour
+
+// playURL mints the play URL for a file /resolve found on a debrid backend.
+// The token names the backend, so the play endpoint re-checks that one
+// before moving on to the others.
+func (s *Handler) playURL(c *gin.Context, hash string, fileIdx int, backend models.StreamingBackendType) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"hash": hash,
+		"idx":  fileIdx,
+		"b":    string(backend),
+		"exp":  time.Now().Add(playTTL).Unix(),
+	})
+	tokenString, _ := token.SignedString([]byte(s.secret))
+	return fmt.Sprintf("%s/%s/%s/stremio/play/%s", s.domain, sv.AccessTokenParamName, c.Query(sv.AccessTokenParamName), tokenString)
+}
+
+// play redirects to a working link for the file. Players come back here
+// whenever they reconnect, so a debrid link that died or got throttled
+// mid-playback is replaced by the next backend's.
+func (s *Handler) play(c *gin.Context) {
+	jwtClaims, ok := s.parseToken(c)
+	if !ok {
+		return
+	}
+	hash, _ := jwtClaims["hash"].(string)
+	idx, iok := jwtClaims["idx"].(float64)
+	backend, _ := jwtClaims["b"].(string)
+	if hash == "" || !iok || backend == "" {
+		log.Warn("missing claims in play token")
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	fileIdx := int(idx)
+	user := auth.GetUserFromContext(c)
+	linkResult, err := s.lr.Failover(c.Request.Context(), user.ID, api.GetClaimsFromContext(c), claims.GetFromContext(c), hash, fileIdx, models.StreamingBackendType(backend), true)
+	if err != nil {
+		log.WithError(err).
+			WithField("hash", hash).
+			WithField("file_idx", fileIdx).
+			Error("failed to resolve link for playback")
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if linkResult == nil || linkResult.URL == "" {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	c.Redirect(http.StatusFound, linkResult.URL)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/claims"
 	"github.com/webtor-io/web-ui/services/common"
+	"github.com/webtor-io/web-ui/services/template"
 	"github.com/webtor-io/web-ui/services/torznab"
 	"github.com/webtor-io/web-ui/services/web"
 )
@@ -33,11 +34,13 @@ const validateTimeout = 15 * time.Second
 
 type Handler struct {
 	pg        *cs.PG
+	client    *torznab.Client
 	validator *torznab.Validator
+	tb        template.Builder[*web.Context]
 	domain    string
 }
 
-func RegisterHandler(c *cli.Context, v *torznab.Validator, r *gin.Engine, pg *cs.PG) error {
+func RegisterHandler(c *cli.Context, cl *torznab.Client, v *torznab.Validator, r *gin.Engine, tm *template.Manager[*web.Context], pg *cs.PG) error {
 	d := c.String(common.DomainFlag)
 	if d != "" {
 		u, err := url.Parse(d)
@@ -49,7 +52,9 @@ func RegisterHandler(c *cli.Context, v *torznab.Validator, r *gin.Engine, pg *cs
 
 	h := &Handler{
 		pg:        pg,
+		client:    cl,
 		validator: v,
+		tb:        tm.MustRegisterViews("torznab_indexer/*").WithLayout("main"),
 		domain:    d,
 	}
 
@@ -59,15 +64,20 @@ func RegisterHandler(c *cli.Context, v *torznab.Validator, r *gin.Engine, pg *cs
 	gr.POST("/delete/:id", h.delete)
 	gr.POST("/update", h.update)
 	gr.POST("/:id/refresh-caps", h.refreshCaps)
+	gr.GET("/import", h.importForm)
+	gr.POST("/import/list", h.importList)
+	gr.POST("/import/add", h.importAdd)
+	gr.POST("/sync", h.sync)
 	return nil
 }
 
 func (s *Handler) add(c *gin.Context) {
 	rawURL := strings.TrimSpace(c.PostForm("url"))
 	apiKey := strings.TrimSpace(c.PostForm("api_key"))
+	usenet := c.PostForm("usenet") == "on"
 	user := auth.GetUserFromContext(c)
 	cla := claims.GetFromContext(c)
-	if err := s.addIndexer(c.Request.Context(), rawURL, apiKey, user, cla); err != nil {
+	if err := s.addIndexer(c.Request.Context(), rawURL, apiKey, usenet, user, cla); err != nil {
 		log.WithError(err).Error("failed to add torznab indexer")
 		web.RedirectWithError(c, err)
 		return
@@ -75,18 +85,14 @@ func (s *Handler) add(c *gin.Context) {
 	web.RedirectWithSuccessAndMessage(c, "toast.indexerAdded")
 }
 
-func (s *Handler) addIndexer(ctx context.Context, rawURL, apiKey string, user *auth.User, cla *claims.Data) error {
+func (s *Handler) addIndexer(ctx context.Context, rawURL, apiKey string, usenet bool, user *auth.User, cla *claims.Data) error {
 	feedURL, key, err := torznab.ParseFeedURL(rawURL, apiKey)
 	if err != nil {
 		return err
 	}
 
-	parsed, err := url.Parse(feedURL)
-	if err != nil {
-		return errors.New("invalid URL format")
-	}
-	if s.domain != "" && parsed.Hostname() == s.domain {
-		return errors.New("cannot add Webtor's own URL as an indexer")
+	if err := s.checkOwnDomain(feedURL); err != nil {
+		return err
 	}
 
 	db := s.pg.Get()
@@ -136,7 +142,18 @@ func (s *Handler) addIndexer(ctx context.Context, rawURL, apiKey string, user *a
 		}
 	}
 
-	return models.CreateTorznabIndexer(ctx, db, user.ID, feedURL, key, name, caps)
+	return models.CreateTorznabIndexer(ctx, db, user.ID, feedURL, key, name, caps, usenet)
+}
+
+func (s *Handler) checkOwnDomain(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("invalid URL format")
+	}
+	if s.domain != "" && parsed.Hostname() == s.domain {
+		return errors.New("cannot add Webtor's own URL as an indexer")
+	}
+	return nil
 }
 
 func (s *Handler) checkFreeTierLimit(ctx context.Context, user *auth.User) error {
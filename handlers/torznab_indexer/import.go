@@ -0,0 +1,367 @@
+package torznab_indexer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/claims"
+	"github.com/webtor-io/web-ui/services/torznab"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+// probeConcurrency bounds the caps probes one import or sync runs at once.
+// They all hit the same Jackett or Prowlarr, which proxies each one to a
+// different tracker — a handful in flight is polite, thirty is not.
+const probeConcurrency = 4
+
+// listTimeout bounds the manager listing that runs while the user waits on
+// the import page.
+const listTimeout = 15 * time.Second
+
+// importData drives the three states of the import page: the address form,
+// the pick list, and the per-indexer outcome.
+type importData struct {
+	URL      string
+	APIKey   string
+	Kind     string
+	Indexers []importIndexer
+	Results  []importResult
+	ErrKey   string
+}
+
+type importIndexer struct {
+	torznab.ManagedIndexer
+	// Added marks an indexer whose feed the user already has, however it
+	// got there. It is listed but cannot be picked again.
+	Added bool
+}
+
+type importResult struct {
+	Name   string
+	ErrKey string
+}
+
+func (s *Handler) importForm(c *gin.Context) {
+	s.renderImport(c, &importData{})
+}
+
+func (s *Handler) importList(c *gin.Context) {
+	d := &importData{
+		URL:    strings.TrimSpace(c.PostForm("url")),
+		APIKey: strings.TrimSpace(c.PostForm("api_key")),
+	}
+	user := auth.GetUserFromContext(c)
+	if err := s.listManaged(c.Request.Context(), d, user); err != nil {
+		log.WithError(err).Warn("failed to list indexer manager")
+		d.ErrKey = web.ClassifyError(err)
+	}
+	s.renderImport(c, d)
+}
+
+func (s *Handler) importAdd(c *gin.Context) {
+	d := &importData{
+		URL:    strings.TrimSpace(c.PostForm("url")),
+		APIKey: strings.TrimSpace(c.PostForm("api_key")),
+		Kind:   c.PostForm("kind"),
+	}
+	user := auth.GetUserFromContext(c)
+	cla := claims.GetFromContext(c)
+	results, err := s.importIndexers(c.Request.Context(), d, c.PostFormArray("indexer"), user, cla)
+	if err != nil {
+		log.WithError(err).Warn("failed to import torznab indexers")
+		d.ErrKey = web.ClassifyError(err)
+	}
+	d.Results = results
+	s.renderImport(c, d)
+}
+
+func (s *Handler) renderImport(c *gin.Context, d *importData) {
+	s.tb.Build("torznab_indexer/import").HTML(http.StatusOK, web.NewContext(c).WithData(d))
+}
+
+// listManaged fills the pick list. The address is normalised first, so the
+// hidden fields the next step posts back carry the base URL, not whatever
+// page the user copied it from.
+func (s *Handler) listManaged(ctx context.Context, d *importData, user *auth.User) error {
+	base, key, err := torznab.ParseManagerURL(d.URL, d.APIKey)
+	if err != nil {
+		return err
+	}
+	d.URL, d.APIKey = base, key
+	if err := s.checkOwnDomain(base); err != nil {
+		return err
+	}
+	lCtx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	m, list, err := s.client.DetectManager(lCtx, base, key)
+	if err != nil {
+		return managerError(err)
+	}
+	d.Kind = m.Kind
+
+	added, err := s.userFeedURLs(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, ix := range list {
+		d.Indexers = append(d.Indexers, importIndexer{ManagedIndexer: ix, Added: added[ix.FeedURL]})
+	}
+	return nil
+}
+
+// importIndexers adds the picked indexers, each validated through the same
+// caps probe as the add form, and reports every one of them.
+//
+// The manager is listed again rather than trusting the posted ids to map to
+// the feed URLs the pick list showed: the form round-trips through the
+// browser, and the listing is what ties a row to its upstream id for sync.
+func (s *Handler) importIndexers(ctx context.Context, d *importData, ids []string, user *auth.User, cla *claims.Data) ([]importResult, error) {
+	if len(ids) == 0 {
+		return nil, web.NewUserError("error.indexerImportNoneSelected", errors.New("no indexers selected"))
+	}
+	if d.Kind != torznab.ManagerJackett && d.Kind != torznab.ManagerProwlarr {
+		return nil, errors.New("unknown indexer manager")
+	}
+	if err := s.checkOwnDomain(d.URL); err != nil {
+		return nil, err
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	m := torznab.Manager{Kind: d.Kind, URL: d.URL, APIKey: d.APIKey}
+	lCtx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	list, err := s.client.ListManaged(lCtx, m)
+	if err != nil {
+		return nil, managerError(err)
+	}
+
+	picked := map[string]bool{}
+	for _, id := range ids {
+		picked[id] = true
+	}
+	added, err := s.userFeedURLs(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	var todo []torznab.ManagedIndexer
+	for _, ix := range list {
+		if picked[ix.ID] && !added[ix.FeedURL] {
+			todo = append(todo, ix)
+		}
+	}
+	if len(todo) == 0 {
+		return nil, web.NewUserError("error.indexerImportNoneSelected", errors.New("nothing new selected"))
+	}
+
+	type probe struct {
+		name string
+		caps *models.TorznabCaps
+		err  error
+	}
+	probes := make([]probe, len(todo))
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(probeConcurrency)
+	for i, ix := range todo {
+		g.Go(func() error {
+			pCtx, cancel := context.WithTimeout(gCtx, validateTimeout)
+			defer cancel()
+			name, caps, err := s.validator.ValidateAndFetch(pCtx, torznab.Endpoint{URL: ix.FeedURL, APIKey: m.APIKey})
+			probes[i] = probe{name: name, caps: caps, err: err}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// Inserts run in list order after every probe is back, so priorities
+	// follow the manager's order and the free-tier count is read fresh for
+	// each row.
+	freeTier := cla.Context.Tier.Id == 0
+	results := make([]importResult, 0, len(todo))
+	for i, ix := range todo {
+		err := probes[i].err
+		if err != nil && torznab.IsUnreachable(err) {
+			err = web.NewUserError("error.indexerUnreachable", err)
+		}
+		if err == nil && freeTier {
+			err = s.checkFreeTierLimit(ctx, user)
+		}
+		if err == nil {
+			indexer := &models.TorznabIndexer{
+				Url:    ix.FeedURL,
+				UserID: user.ID,
+			}
+			if m.APIKey != "" {
+				indexer.ApiKey = &m.APIKey
+			}
+			indexer.ApplyCaps(probes[i].name, probes[i].caps)
+			// The manager's own name for the tracker is exactly what a
+			// search would teach us later; knowing it now saves the row
+			// from showing "Jackett · rutracker" until then.
+			if ix.Name != "" {
+				name := ix.Name
+				indexer.TrackerName = &name
+			}
+			indexer.SetManager(m.Kind, m.URL, ix.ID)
+			err = models.InsertTorznabIndexer(ctx, db, indexer)
+		}
+		r := importResult{Name: ix.Name}
+		if err != nil {
+			log.WithError(err).
+				WithField("user_id", user.ID).
+				WithField("indexer", ix.ID).
+				Warn("failed to import torznab indexer")
+			r.ErrKey = web.ClassifyError(err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (s *Handler) sync(c *gin.Context) {
+	user := auth.GetUserFromContext(c)
+	if err := s.syncIndexers(c.Request.Context(), user); err != nil {
+		log.WithError(err).Error("failed to sync torznab indexers")
+		web.RedirectWithError(c, err)
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.indexersSynced")
+}
+
+// syncIndexers brings imported indexers back in line with their managers:
+// the name and caps of every tracker still listed are refreshed, and a
+// tracker removed upstream is switched off — its feed now answers with an
+// error on every search — but not deleted, since the row is still the
+// user's.
+//
+// Each manager is listed once, however many rows came from it. A manager
+// that cannot be listed leaves its rows untouched; the error is returned
+// only when no manager could be.
+func (s *Handler) syncIndexers(ctx context.Context, user *auth.User) error {
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("no db")
+	}
+	indexers, err := models.GetManagedTorznabIndexers(ctx, db, user.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get user indexers")
+	}
+	if len(indexers) == 0 {
+		return nil
+	}
+
+	groups := map[torznab.Manager][]*models.TorznabIndexer{}
+	for i := range indexers {
+		ix := &indexers[i]
+		m := torznab.Manager{Kind: *ix.Manager, URL: *ix.ManagerURL, APIKey: ix.GetApiKey()}
+		groups[m] = append(groups[m], ix)
+	}
+
+	var lastErr error
+	listed := 0
+	for m, rows := range groups {
+		lCtx, cancel := context.WithTimeout(ctx, listTimeout)
+		list, err := s.client.ListManaged(lCtx, m)
+		cancel()
+		if err != nil {
+			log.WithError(err).
+				WithField("user_id", user.ID).
+				WithField("manager", m.Kind).
+				Warn("failed to list indexer manager for sync")
+			lastErr = managerError(err)
+			continue
+		}
+		listed++
+		upstream := make(map[string]torznab.ManagedIndexer, len(list))
+		for _, ix := range list {
+			upstream[ix.ID] = ix
+		}
+		s.syncRows(ctx, user, rows, upstream)
+	}
+	if listed == 0 {
+		return lastErr
+	}
+	log.WithField("user_id", user.ID).Info("torznab indexers synced")
+	return nil
+}
+
+func (s *Handler) syncRows(ctx context.Context, user *auth.User, rows []*models.TorznabIndexer, upstream map[string]torznab.ManagedIndexer) {
+	db := s.pg.Get()
+	if db == nil {
+		return
+	}
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(probeConcurrency)
+	for _, row := range rows {
+		up, ok := upstream[*row.ManagerIndexerID]
+		if !ok {
+			if row.Enabled {
+				if err := models.DisableTorznabIndexer(ctx, db, row.ID, user.ID); err != nil {
+					log.WithError(err).WithField("indexer_id", row.ID).Warn("failed to disable indexer gone upstream")
+				}
+			}
+			continue
+		}
+		g.Go(func() error {
+			pCtx, cancel := context.WithTimeout(gCtx, validateTimeout)
+			defer cancel()
+			name, caps, err := s.validator.ValidateAndFetch(pCtx, torznab.Endpoint{URL: row.Url, APIKey: row.GetApiKey()})
+			if err != nil {
+				log.WithError(err).WithField("indexer_id", row.ID).Info("sync: indexer probe failed")
+				return nil
+			}
+			if err := models.UpdateTorznabIndexerCaps(ctx, db, row.ID, user.ID, name, caps); err != nil {
+				log.WithError(err).WithField("indexer_id", row.ID).Warn("sync: caps update failed")
+			}
+			if up.Name != "" && up.Name != row.GetTrackerName() {
+				if err := models.SetTorznabIndexerTrackerName(ctx, db, row.ID, up.Name); err != nil {
+					log.WithError(err).WithField("indexer_id", row.ID).Warn("sync: name update failed")
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// userFeedURLs is the set of feeds a user already has, for marking the
+// pick list and skipping duplicates.
+func (s *Handler) userFeedURLs(ctx context.Context, user *auth.User) (map[string]bool, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	indexers, err := models.GetAllUserTorznabIndexers(ctx, db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(indexers))
+	for _, ix := range indexers {
+		out[ix.Url] = true
+	}
+	return out, nil
+}
+
+// managerError gives the manager failures users can act on their own
+// message, the same split the add form makes for a single feed.
+func managerError(err error) error {
+	switch {
+	case torznab.IsUnreachable(err):
+		return web.NewUserError("error.indexerUnreachable", err)
+	case strings.Contains(err.Error(), "refused"):
+		return web.NewUserError("error.indexerManagerRefused", err)
+	case strings.Contains(err.Error(), "no Jackett or Prowlarr"):
+		return web.NewUserError("error.indexerManagerNotFound", err)
+	}
+	return err
+}
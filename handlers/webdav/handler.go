@@ -16,19 +16,25 @@ import (
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/claims"
 	co "github.com/webtor-io/web-ui/services/common"
+	cp "github.com/webtor-io/web-ui/services/content_proxy"
 	"github.com/webtor-io/web-ui/services/libfs"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
 	"github.com/webtor-io/web-ui/services/web"
 	webdav "github.com/webtor-io/web-ui/services/webdav"
 )
 
 type Handler struct {
-	pg   *cs.PG
-	at   *at.AccessToken
-	sapi *api.Api
-	wh   *webdav.Handler
+	pg    *cs.PG
+	at    *at.AccessToken
+	sapi  *api.Api
+	fs    webdav.FileSystem
+	locks *webdav.RedisLocks
+	proxy *cp.Proxy
 }
 
-func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, at *at.AccessToken, sapi *api.Api, jobs *j.Jobs) {
+func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, redis *cs.RedisClient, at *at.AccessToken, sapi *api.Api, jobs *j.Jobs, subs *us.Service, events *le.Bus, resolver *lr.LinkResolver, proxy *cp.Proxy) {
 	if c.Bool(co.DisableWebDAVFlag) {
 		return
 	}
@@ -37,14 +43,17 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, at *at.AccessToke
 	// the response has to be echoed back with that prefix intact.
 	fs := &PrefixDirectory{
 		Separator: "webdav",
-		Inner:     libfs.New(pg, sapi, jobs),
+		Inner:     libfs.New(pg, sapi, jobs, subs, events, resolver),
 	}
-	wh := &webdav.Handler{FileSystem: fs}
 	h := &Handler{
 		pg:   pg,
 		at:   at,
 		sapi: sapi,
-		wh:   wh,
+		fs:   fs,
+		// Locks live in Redis: Finder's LOCK and the PUT that follows it
+		// need not land on the same replica.
+		locks: webdav.NewRedisLocks(redis.Get()),
+		proxy: proxy,
 	}
 
 	gr := r.Group("/webdav")
@@ -52,6 +61,7 @@ func RegisterHandler(c *cli.Context, r *gin.Engine, pg *cs.PG, at *at.AccessToke
 	gr.Use(claims.IsPaid)
 	gr.POST("/url/generate", h.generateUrl)
 	gr.POST("/url/regenerate", h.regenerateUrl)
+	gr.POST("/proxy", h.setProxy)
 
 	// WebDAV protocol routes - these require token-based authentication
 	grapi := gr.Group("")
@@ -79,13 +89,37 @@ func (s *Handler) regenerateUrl(c *gin.Context) {
 	web.RedirectWithSuccessAndMessage(c, "toast.webdavUrlRegenerated")
 }
 
+// setProxy switches proxy mode for the WebDAV token, for clients that cannot
+// follow a redirect to the content and are not caught by User-Agent.
+func (s *Handler) setProxy(c *gin.Context) {
+	if err := s.at.SetProxy(c, "webdav", c.PostForm("proxy") == "true"); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.Wrap(err, "failed to set webdav proxy mode"))
+		return
+	}
+	web.RedirectWithSuccessAndMessage(c, "toast.proxyModeUpdated")
+}
+
 func (s *Handler) handleWebDAV(c *gin.Context) {
-	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), web.Context{}, web.NewContext(c)))
+	ctx := context.WithValue(c.Request.Context(), web.Context{}, web.NewContext(c))
+	ctx = cp.WithStream(ctx, s.proxy.For(c.Request, at.ProxyFromContext(ctx), auth.GetUserFromContext(c).ID.String(), tierRate(c)))
+	c.Request = c.Request.WithContext(ctx)
 	u, err := url.Parse(c.Request.RequestURI)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusBadRequest, errors.Wrap(err, "failed to parse webdav request uri"))
 		return
 	}
 	c.Request.URL.Path = u.Path
-	s.wh.ServeHTTP(c.Writer, c.Request)
+	user := auth.GetUserFromContext(c)
+	wh := &webdav.Handler{
+		FileSystem: s.fs,
+		LockSystem: s.locks.Scope(user.ID.String()),
+	}
+	wh.ServeHTTP(c.Writer, c.Request)
+}
+
+func tierRate(c *gin.Context) string {
+	if cl := api.GetClaimsFromContext(c); cl != nil {
+		return cl.Rate
+	}
+	return ""
 }
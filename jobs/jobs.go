@@ -8,6 +8,7 @@ import (
 	"github.com/webtor-io/web-ui/services/enrich"
 	"github.com/webtor-io/web-ui/services/i18n"
 	"github.com/webtor-io/web-ui/services/job"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/template"
 	"github.com/webtor-io/web-ui/services/thumbnail"
 	us "github.com/webtor-io/web-ui/services/user_subtitle"
@@ -79,6 +80,7 @@ type Jobs struct {
 	userSubtitles *us.Service
 	thumbnail     *thumbnail.Service
 	claims        *claims.Claims
+	events        *le.Bus
 	warmup        scripts.WarmupSettings
 	grace         scripts.GraceSettings
 }
@@ -98,7 +100,7 @@ func (s *Jobs) errorFormatter(c *web.Context) job.ErrorFormatter {
 	}
 }
 
-func New(c *cli.Context, q *job.Queues, tm *template.Manager[*web.Context], api *api.Api, enricher *enrich.Enricher, i18nSvc *i18n.Service, userSubtitles *us.Service, thumb *thumbnail.Service, uc *claims.Claims) *Jobs {
+func New(c *cli.Context, q *job.Queues, tm *template.Manager[*web.Context], api *api.Api, enricher *enrich.Enricher, i18nSvc *i18n.Service, userSubtitles *us.Service, thumb *thumbnail.Service, uc *claims.Claims, events *le.Bus) *Jobs {
 	return &Jobs{
 		q:             q,
 		tb:            tm,
@@ -108,6 +110,7 @@ func New(c *cli.Context, q *job.Queues, tm *template.Manager[*web.Context], api
 		userSubtitles: userSubtitles,
 		thumbnail:     thumb,
 		claims:        uc,
+		events:        events,
 		warmup: scripts.WarmupSettings{
 			TimeoutMin:            c.Int(warmupTimeoutMinFlag),
 			NoPeersTimeoutSec:     c.Int(warmupNoPeersTimeoutSecFlag),
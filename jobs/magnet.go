@@ -0,0 +1,41 @@
+package j
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/webtor-io/web-ui/jobs/scripts"
+	"github.com/webtor-io/web-ui/services/job"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+// MagnetResolved is told how a ResolveMagnet job ended: with the resource id
+// and the .torrent once metadata has arrived, or with the error that stopped
+// it.
+type MagnetResolved func(ctx context.Context, rID string, torrent []byte, err error) error
+
+// ResolveMagnet fetches the metadata of a magnet in the background — the same
+// way the load job does — and hands the result to done. id must be unique per
+// request: a job id already known to the queue replays that job's log instead
+// of running, and done would never be called.
+func (s *Jobs) ResolveMagnet(c *web.Context, id string, magnet string, done MagnetResolved) (j *job.Job, err error) {
+	ls, _, err := scripts.Load(s.api, s.i18n, c, &scripts.LoadArgs{Query: magnet})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	j = s.q.GetOrCreate("magnet").Enqueue(ctx, cancel, id, job.NewScript(func(j *job.Job) (err error) {
+		err = ls.Run(ctx, j)
+		if err != nil {
+			return done(ctx, "", nil, err)
+		}
+		rID := j.Context.Value("respID").(string)
+		t, err := s.api.GetTorrentCached(ctx, c.ApiClaims, rID)
+		if err == nil && len(t) == 0 {
+			err = errors.New("torrent not found")
+		}
+		return done(ctx, rID, t, err)
+	}), false, s.errorFormatter(c))
+	return
+}
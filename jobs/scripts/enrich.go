@@ -5,27 +5,36 @@ import (
 
 	"github.com/webtor-io/web-ui/services/enrich"
 	"github.com/webtor-io/web-ui/services/job"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/web"
 )
 
 type EnrichScript struct {
 	enricher *enrich.Enricher
+	events   *le.Bus
 	rID      string
 	c        *web.Context
 }
 
-func NewEnrichScript(enricher *enrich.Enricher, c *web.Context, rID string) *EnrichScript {
+func NewEnrichScript(enricher *enrich.Enricher, events *le.Bus, c *web.Context, rID string) *EnrichScript {
 	return &EnrichScript{
 		enricher: enricher,
+		events:   events,
 		rID:      rID,
 		c:        c,
 	}
 }
 
 func (s *EnrichScript) Run(ctx context.Context, j *job.Job) (err error) {
-	return s.enricher.Enrich(ctx, s.rID, s.c.ApiClaims, false, "")
+	if err = s.enricher.Enrich(ctx, s.rID, s.c.ApiClaims, false, ""); err != nil {
+		return
+	}
+	// No user: the job is shared by everyone who added the torrent meanwhile
+	// (it is keyed by infohash), and all of them now see it classified.
+	s.events.Publish(le.Event{Kind: le.Enriched, ResourceID: s.rID})
+	return
 }
 
-func Enrich(enricher *enrich.Enricher, c *web.Context, rID string) (job.Runnable, string) {
-	return NewEnrichScript(enricher, c, rID), rID
+func Enrich(enricher *enrich.Enricher, events *le.Bus, c *web.Context, rID string) (job.Runnable, string) {
+	return NewEnrichScript(enricher, events, c, rID), rID
 }
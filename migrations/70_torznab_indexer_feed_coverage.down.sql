@@ -0,0 +1,3 @@
+ALTER TABLE public.torznab_indexer
+	DROP COLUMN IF EXISTS feed_read_at,
+	DROP COLUMN IF EXISTS feed_covered_since;
@@ -0,0 +1,10 @@
+-- Feed-mode bookkeeping for the release-subscription poller. A
+-- latest-releases feed is a window, not a history: reading it covers the
+-- time since its oldest item and nothing before. feed_read_at is the last
+-- successful read; feed_covered_since is where the current unbroken run of
+-- reads begins. A subscription last checked before feed_covered_since may
+-- have missed releases that scrolled out of the window, and gets a targeted
+-- search instead of trusting the feed.
+ALTER TABLE public.torznab_indexer
+	ADD COLUMN feed_read_at timestamptz,
+	ADD COLUMN feed_covered_since timestamptz;
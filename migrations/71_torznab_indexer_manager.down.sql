@@ -0,0 +1,4 @@
+ALTER TABLE public.torznab_indexer
+	DROP COLUMN IF EXISTS manager,
+	DROP COLUMN IF EXISTS manager_url,
+	DROP COLUMN IF EXISTS manager_indexer_id;
@@ -0,0 +1,9 @@
+-- Where an imported indexer came from: the Jackett or Prowlarr instance
+-- (kind and base URL) and that instance's own id for the tracker. A sync
+-- lists the instance again and matches rows on the id, so a tracker renamed
+-- or reconfigured upstream is refreshed rather than re-imported. All three
+-- stay NULL for indexers added by pasting a feed URL.
+ALTER TABLE public.torznab_indexer
+	ADD COLUMN manager text,
+	ADD COLUMN manager_url text,
+	ADD COLUMN manager_indexer_id text;
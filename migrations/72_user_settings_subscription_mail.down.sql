@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS public.release_subscription_hit_pending_idx;
+ALTER TABLE public.user_settings
+	DROP COLUMN IF EXISTS subscription_digest,
+	DROP COLUMN IF EXISTS quiet_hours_start,
+	DROP COLUMN IF EXISTS quiet_hours_end,
+	DROP COLUMN IF EXISTS time_zone,
+	DROP COLUMN IF EXISTS digest_sent_at;
@@ -0,0 +1,28 @@
+-- How release-subscription mail reaches the account. A busy account with
+-- forty subscriptions would otherwise get up to forty letters a day, one
+-- per subscription, at whatever hour the poller happened to run.
+--
+-- subscription_digest: immediate (one letter per subscription, as before),
+-- daily or weekly (one combined letter across subscriptions). NULL reads
+-- as immediate, so existing accounts see no change.
+--
+-- quiet_hours_start / quiet_hours_end: hours of the day, 0-23, in the
+-- account's time_zone, during which nothing is sent. Both NULL means no
+-- quiet hours. A window may wrap midnight (22 → 8).
+--
+-- time_zone: IANA name. NULL reads as UTC.
+--
+-- digest_sent_at: when the last digest went out, which is what decides
+-- whether the next one is due.
+ALTER TABLE public.user_settings
+	ADD COLUMN subscription_digest text,
+	ADD COLUMN quiet_hours_start smallint CHECK (quiet_hours_start BETWEEN 0 AND 23),
+	ADD COLUMN quiet_hours_end smallint CHECK (quiet_hours_end BETWEEN 0 AND 23),
+	ADD COLUMN time_zone text,
+	ADD COLUMN digest_sent_at timestamptz;
+
+-- The delivery pass looks for subscriptions that still owe their owner
+-- something on every run. Pending rows are a sliver of the table.
+CREATE INDEX release_subscription_hit_pending_idx
+	ON public.release_subscription_hit (release_subscription_id)
+	WHERE notified_at IS NULL;
@@ -0,0 +1,28 @@
+-- Magnets dropped into the torrents/ folder of the library filesystem
+-- (WebDAV, S3) that are still waiting for metadata. A row lives from the
+-- drop until the torrent lands in public.library, and is shown meanwhile as
+-- a "(resolving)" placeholder. file_name and body are the file as dropped
+-- (a .magnet, or a .url/.txt holding a magnet URI), served back unchanged so
+-- a client verifying its upload sees what it wrote. error is set when
+-- resolving failed; the row then stays as a "(failed)" placeholder until the
+-- user deletes it.
+CREATE TABLE public.library_magnet (
+	library_magnet_id	uuid		NOT NULL DEFAULT uuid_generate_v4(),
+	user_id			uuid		NOT NULL,
+	resource_id		text		NOT NULL,
+	file_name		text		NOT NULL,
+	body			text		NOT NULL,
+	error			text,
+	created_at		timestamptz	NOT NULL DEFAULT now(),
+	updated_at		timestamptz	NOT NULL DEFAULT now(),
+
+	CONSTRAINT library_magnet_pk PRIMARY KEY (library_magnet_id),
+	CONSTRAINT library_magnet_unique UNIQUE (user_id, resource_id),
+	CONSTRAINT library_magnet_user_fk FOREIGN KEY (user_id)
+		REFERENCES public."user"(user_id)
+		ON DELETE CASCADE
+);
+
+CREATE TRIGGER update_library_magnet_updated_at
+	BEFORE UPDATE ON public.library_magnet
+	FOR EACH ROW EXECUTE FUNCTION update_updated_at();
@@ -0,0 +1,27 @@
+-- Public keys a user uploaded on the profile page to log in to the SFTP
+-- server without an access token. fingerprint is the SHA256 fingerprint as
+-- OpenSSH prints it; it is what a login is looked up by, so one key can
+-- belong to one account only. public_key is the authorized_keys line as
+-- uploaded, comment included, so the profile can show it back.
+CREATE TABLE public.user_ssh_key (
+	user_ssh_key_id		uuid		NOT NULL DEFAULT uuid_generate_v4(),
+	user_id			uuid		NOT NULL,
+	name			text		NOT NULL,
+	public_key		text		NOT NULL,
+	fingerprint		text		NOT NULL,
+	last_used_at		timestamptz,
+	created_at		timestamptz	NOT NULL DEFAULT now(),
+	updated_at		timestamptz	NOT NULL DEFAULT now(),
+
+	CONSTRAINT user_ssh_key_pk PRIMARY KEY (user_ssh_key_id),
+	CONSTRAINT user_ssh_key_fingerprint_unique UNIQUE (fingerprint),
+	CONSTRAINT user_ssh_key_user_fk FOREIGN KEY (user_id)
+		REFERENCES public."user"(user_id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX user_ssh_key_user_id_idx ON public.user_ssh_key (user_id);
+
+CREATE TRIGGER update_user_ssh_key_updated_at
+	BEFORE UPDATE ON public.user_ssh_key
+	FOR EACH ROW EXECUTE FUNCTION update_updated_at();
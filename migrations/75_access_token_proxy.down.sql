@@ -0,0 +1,2 @@
+ALTER TABLE public.access_token
+	DROP COLUMN IF EXISTS proxy;
@@ -0,0 +1,6 @@
+-- Whether file protocols (WebDAV, S3) stream content through web-ui for this
+-- token instead of redirecting to the content URL. Some clients do not follow
+-- a cross-host redirect — Windows Explorer, several smart-TV players, older
+-- s3fs — and the user switches this on for the token they mounted with.
+ALTER TABLE public.access_token
+	ADD COLUMN proxy boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS public.cache_index_expires_at_idx;
+
+ALTER TABLE public.cache_index
+	DROP COLUMN IF EXISTS expires_at;
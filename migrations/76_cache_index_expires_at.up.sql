@@ -0,0 +1,9 @@
+-- Rows written from a bulk instant-availability check (TorBox checkcached,
+-- Premiumize cache/check) are a service's word, not a link that resolved, and
+-- debrid caches churn — they carry their own, shorter expiry. NULL keeps the
+-- cache-index-expire window that resolved links get.
+ALTER TABLE public.cache_index
+	ADD COLUMN expires_at timestamptz NULL;
+
+CREATE INDEX cache_index_expires_at_idx
+	ON public.cache_index (expires_at);
@@ -0,0 +1,7 @@
+ALTER TABLE public.streaming_backend
+	DROP COLUMN IF EXISTS premium_until,
+	DROP COLUMN IF EXISTS points,
+	DROP COLUMN IF EXISTS usage,
+	DROP COLUMN IF EXISTS downloaded,
+	DROP COLUMN IF EXISTS invalid_notified_at,
+	DROP COLUMN IF EXISTS expiry_notified_for;
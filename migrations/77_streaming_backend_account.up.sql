@@ -0,0 +1,14 @@
+-- What the periodic account check (`streaming-backend check`) learns about
+-- the debrid account behind a token. Each service reports a different
+-- subset, so every column is nullable and NULL means "not reported".
+ALTER TABLE public.streaming_backend
+	ADD COLUMN premium_until timestamptz NULL,
+	ADD COLUMN points integer NULL,
+	ADD COLUMN usage double precision NULL,
+	ADD COLUMN downloaded bigint NULL,
+	-- Set when the user was told their token stopped working, cleared once it
+	-- works again: one letter per breakage rather than one per check.
+	ADD COLUMN invalid_notified_at timestamptz NULL,
+	-- The premium_until the expiry warning was sent for. A renewal moves
+	-- premium_until past it, which is what re-arms the warning.
+	ADD COLUMN expiry_notified_for timestamptz NULL;
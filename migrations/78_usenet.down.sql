@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.usenet_download;
+ALTER TABLE public.torznab_indexer
+	DROP COLUMN IF EXISTS usenet;
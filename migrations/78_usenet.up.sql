@@ -0,0 +1,42 @@
+-- Newznab feeds serve NZB releases, not torrents. The feed format cannot
+-- tell the two apart, so the user marks the indexer when adding it.
+ALTER TABLE public.torznab_indexer
+	ADD COLUMN usenet boolean DEFAULT false NOT NULL;
+
+-- A Usenet release sent to one of the user's Usenet-capable backends
+-- (TorBox, a self-hosted SABnzbd). The backend does the downloading; this
+-- row is what Webtor knows about the job, refreshed by `usenet poll` until
+-- the job finishes or fails. The NZB itself is not kept: its link carries
+-- the indexer's API key.
+CREATE TABLE public.usenet_download (
+	usenet_download_id uuid DEFAULT uuid_generate_v4() NOT NULL,
+	user_id uuid NOT NULL,
+	streaming_backend_id uuid NOT NULL,
+	remote_id text NOT NULL,
+	name text NOT NULL,
+	size int8 DEFAULT 0 NOT NULL,
+	status text NOT NULL,
+	progress double precision DEFAULT 0 NOT NULL,
+	files jsonb DEFAULT '[]' NOT NULL,
+	error text,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL,
+	finished_at timestamptz,
+	CONSTRAINT usenet_download_pk PRIMARY KEY (usenet_download_id),
+	CONSTRAINT usenet_download_user_fk FOREIGN KEY (user_id)
+		REFERENCES public."user" (user_id) ON DELETE CASCADE,
+	CONSTRAINT usenet_download_streaming_backend_fk FOREIGN KEY (streaming_backend_id)
+		REFERENCES public.streaming_backend (streaming_backend_id) ON DELETE CASCADE
+);
+
+CREATE INDEX usenet_download_user_idx ON public.usenet_download (user_id, created_at DESC);
+
+-- The poller's query: what is still in flight.
+CREATE INDEX usenet_download_pending_idx
+	ON public.usenet_download (created_at)
+	WHERE finished_at IS NULL;
+
+create trigger update_updated_at before
+update
+    on
+    public.usenet_download for each row execute function update_updated_at();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.global_match_override;
+DROP TABLE IF EXISTS public.match_override;
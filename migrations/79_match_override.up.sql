@@ -0,0 +1,52 @@
+-- A user's correction of the title a resource was matched to. The
+-- enricher's own pick is replaced by the user's on every later run,
+-- `enrich run --force` included. video_id is NULL when the user said the
+-- resource is not a movie or show at all. query_title / query_year are
+-- the parsed name the enricher searched with, folded the way the
+-- enricher folds titles, so corrections of the same name on different
+-- torrents can be counted together. query_year is 0 when the name carried
+-- no year.
+CREATE TABLE public.match_override (
+	match_override_id uuid DEFAULT uuid_generate_v4() NOT NULL,
+	user_id uuid NOT NULL,
+	resource_id text NOT NULL,
+	content_type text NOT NULL,
+	video_id text,
+	query_title text NOT NULL,
+	query_year int2 DEFAULT 0 NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT match_override_pk PRIMARY KEY (match_override_id),
+	CONSTRAINT match_override_user_resource_unique UNIQUE (user_id, resource_id),
+	CONSTRAINT match_override_user_fk FOREIGN KEY (user_id)
+		REFERENCES public."user" (user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX match_override_resource_idx ON public.match_override (resource_id);
+
+CREATE INDEX match_override_query_idx
+	ON public.match_override (content_type, query_title, query_year);
+
+create trigger update_updated_at before
+update
+    on
+    public.match_override for each row execute function update_updated_at();
+
+-- A correction enough users agreed on to apply to every torrent with the
+-- same parsed name, not only the ones they corrected. Filled from
+-- match_override; confirmations is the number of users behind it.
+CREATE TABLE public.global_match_override (
+	content_type text NOT NULL,
+	query_title text NOT NULL,
+	query_year int2 DEFAULT 0 NOT NULL,
+	video_id text,
+	confirmations int4 DEFAULT 0 NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT global_match_override_pk PRIMARY KEY (content_type, query_title, query_year)
+);
+
+create trigger update_updated_at before
+update
+    on
+    public.global_match_override for each row execute function update_updated_at();
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS anilist.mapping;
+
+DROP INDEX IF EXISTS anilist.uq_anilist_query_key;
+DROP TABLE IF EXISTS anilist.query;
+
+DROP TABLE IF EXISTS anilist.media;
+
+DROP SCHEMA IF EXISTS anilist CASCADE;
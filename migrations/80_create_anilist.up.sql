@@ -0,0 +1,63 @@
+-- Schema for AniList metadata
+CREATE SCHEMA IF NOT EXISTS anilist;
+
+-- AniList entries the enricher has looked at. title is the romaji one.
+CREATE TABLE anilist.media
+(
+	anilist_id INTEGER PRIMARY KEY,
+	title      TEXT        NOT NULL,
+	year       SMALLINT,
+	metadata   JSONB       NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+create trigger update_updated_at before
+update
+    on
+    anilist.media for each row execute function update_updated_at();
+
+-- Query table for caching search results
+CREATE TABLE anilist.query
+(
+	query_id   UUID PRIMARY KEY     DEFAULT uuid_generate_v4(),
+	title      TEXT        NOT NULL,
+	year       SMALLINT,
+	anilist_id INTEGER,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX uq_anilist_query_key
+	ON anilist.query (title, COALESCE(year, -1));
+
+create trigger update_updated_at before
+update
+    on
+    anilist.query for each row execute function update_updated_at();
+
+-- Where an AniList entry's episodes sit in the catalogue. Anime releases
+-- number episodes across the whole run ("Show - 137"); the enricher walks
+-- the catalogue's seasons from season, episode_offset episodes in, to find
+-- the season and episode a number falls on. A NULL season means the
+-- entry's numbers count from the show's first episode. Rows the enricher
+-- writes itself have a NULL season and no offset; curated rows are set by
+-- hand for entries whose numbering restarts mid-show, and the enricher
+-- never overwrites them.
+CREATE TABLE anilist.mapping
+(
+	anilist_id     INTEGER PRIMARY KEY,
+	video_id       TEXT        NOT NULL,
+	season         SMALLINT,
+	episode_offset SMALLINT    NOT NULL DEFAULT 0,
+	curated        BOOLEAN     NOT NULL DEFAULT false,
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX anilist_mapping_video_id_idx ON anilist.mapping (video_id);
+
+create trigger update_updated_at before
+update
+    on
+    anilist.mapping for each row execute function update_updated_at();
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS album;
+
+DROP TABLE IF EXISTS album_metadata;
+
+DROP INDEX IF EXISTS musicbrainz.uq_musicbrainz_query_key;
+DROP TABLE IF EXISTS musicbrainz.query;
+
+DROP TABLE IF EXISTS musicbrainz.release_group;
+
+DROP SCHEMA IF EXISTS musicbrainz CASCADE;
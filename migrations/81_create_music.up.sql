@@ -0,0 +1,82 @@
+-- Schema for MusicBrainz metadata
+CREATE SCHEMA IF NOT EXISTS musicbrainz;
+
+-- Release groups the enricher has looked at: an album as a work, every
+-- edition of it together.
+CREATE TABLE musicbrainz.release_group
+(
+	mbid       TEXT PRIMARY KEY,
+	title      TEXT        NOT NULL,
+	artist     TEXT        NOT NULL,
+	year       SMALLINT,
+	metadata   JSONB       NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+create trigger update_updated_at before
+update
+    on
+    musicbrainz.release_group for each row execute function update_updated_at();
+
+-- Query table for caching search results
+CREATE TABLE musicbrainz.query
+(
+	query_id   UUID PRIMARY KEY     DEFAULT uuid_generate_v4(),
+	artist     TEXT        NOT NULL,
+	album      TEXT        NOT NULL,
+	mbid       TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX uq_musicbrainz_query_key
+	ON musicbrainz.query (artist, album);
+
+create trigger update_updated_at before
+update
+    on
+    musicbrainz.query for each row execute function update_updated_at();
+
+-- Album metadata, one row per release group
+CREATE TABLE album_metadata
+(
+	album_metadata_id UUID PRIMARY KEY     DEFAULT uuid_generate_v4(),
+	mbid              TEXT UNIQUE,
+	artist            TEXT        NOT NULL,
+	title             TEXT        NOT NULL,
+	year              SMALLINT,
+	cover_url         TEXT,
+	created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+create trigger update_updated_at before
+update
+    on
+    album_metadata for each row execute function update_updated_at();
+
+-- Albums found in a torrent. path is the folder holding the album inside
+-- the torrent as rest-api lists it ("/Artist - Album (1973)"), "/" when
+-- the tracks sit at its root.
+CREATE TABLE album
+(
+	album_id          UUID PRIMARY KEY     DEFAULT uuid_generate_v4(),
+	resource_id       TEXT        NOT NULL REFERENCES media_info (resource_id) ON DELETE CASCADE,
+	album_metadata_id UUID        REFERENCES album_metadata (album_metadata_id) ON DELETE SET NULL,
+	artist            TEXT        NOT NULL,
+	title             TEXT        NOT NULL,
+	year              SMALLINT,
+	path              TEXT        NOT NULL,
+	track_count       INTEGER     NOT NULL DEFAULT 0,
+	metadata          JSONB,
+	created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_album_resource_id ON album (resource_id);
+
+create trigger update_updated_at before
+update
+    on
+    album for each row execute function update_updated_at();
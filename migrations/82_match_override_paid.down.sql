@@ -0,0 +1,2 @@
+ALTER TABLE public.match_override
+	DROP COLUMN IF EXISTS paid;
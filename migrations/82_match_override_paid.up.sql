@@ -0,0 +1,11 @@
+-- Whether the user was on a paid plan when they made the correction. Only
+-- paid corrections count towards a global one.
+ALTER TABLE public.match_override
+	ADD COLUMN paid boolean DEFAULT false NOT NULL;
+
+-- Earlier corrections take the plan their user is on now.
+UPDATE public.match_override AS mo
+SET paid = true
+FROM public."user" AS u
+WHERE u.user_id = mo.user_id
+	AND coalesce(u.tier, '') NOT IN ('', 'free', 'nobody');
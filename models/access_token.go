@@ -19,6 +19,8 @@ type AccessToken struct {
 	Scope     []string   `pg:"scope,array"`
 	ExpiresAt *time.Time `pg:"expires_at"`
 	CreatedAt time.Time  `pg:"created_at,notnull"`
+	// Proxy makes WebDAV and S3 stream content instead of redirecting to it.
+	Proxy bool `pg:"proxy,use_zero"`
 
 	User *User `pg:"rel:has-one,fk:user_id"`
 }
@@ -133,6 +135,17 @@ func GetUserByAccessTokenWithUser(ctx context.Context, db *pg.DB, token uuid.UUI
 	return accessToken, nil
 }
 
+// SetAccessTokenProxy switches proxy mode for one token by its (user, name)
+// pair. Rotating the token keeps the setting.
+func SetAccessTokenProxy(ctx context.Context, db *pg.DB, userID uuid.UUID, name string, proxy bool) error {
+	_, err := db.Model((*AccessToken)(nil)).Context(ctx).
+		Set("proxy = ?", proxy).
+		Where("user_id = ?", userID).
+		Where("name = ?", name).
+		Update()
+	return err
+}
+
 // DeleteAccessToken removes one token by its (user, name) pair. Reports
 // whether anything was deleted — revoking an already-revoked device is a
 // no-op, not an error.
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Album is an album found in a torrent: the audio tracks of one folder,
+// disc subfolders included. Artist, Title and Year are what the folder
+// name and tags said; AlbumMetadata is what a music mapper made of them.
+type Album struct {
+	tableName struct{} `pg:"album"`
+
+	AlbumID         uuid.UUID      `pg:"album_id,pk,type:uuid,default:uuid_generate_v4()"`
+	ResourceID      string         `pg:"resource_id"`
+	AlbumMetadataID *uuid.UUID     `pg:"album_metadata_id"`
+	Artist          string         `pg:"artist"`
+	Title           string         `pg:"title"`
+	Year            *int16         `pg:"year"`
+	Path            string         `pg:"path,use_zero"`
+	TrackCount      int            `pg:"track_count,use_zero"`
+	Metadata        map[string]any `pg:"metadata,type:jsonb"`
+	CreatedAt       time.Time      `pg:"created_at,default:now()"`
+	UpdatedAt       time.Time      `pg:"updated_at,default:now()"`
+
+	AlbumMetadata *AlbumMetadata   `pg:"rel:has-one,fk:album_metadata_id"`
+	Torrent       *TorrentResource `pg:"rel:has-one,fk:resource_id"`
+}
+
+// GetArtist prefers the mapper's credit over the parsed one
+func (s *Album) GetArtist() string {
+	if s.AlbumMetadata != nil && s.AlbumMetadata.Artist != "" {
+		return s.AlbumMetadata.Artist
+	}
+	return s.Artist
+}
+
+func (s *Album) GetTitle() string {
+	if s.AlbumMetadata != nil && s.AlbumMetadata.Title != "" {
+		return s.AlbumMetadata.Title
+	}
+	return s.Title
+}
+
+func (s *Album) GetIntYear() int {
+	if s.AlbumMetadata != nil && s.AlbumMetadata.Year != nil {
+		return int(*s.AlbumMetadata.Year)
+	}
+	if s.Year == nil {
+		return 0
+	}
+	return int(*s.Year)
+}
+
+func ReplaceAlbumsForResource(ctx context.Context, db *pg.DB, resourceID string, albums []*Album) error {
+	tx, err := db.BeginContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Close()
+	}()
+
+	_, err = tx.Model((*Album)(nil)).
+		Where("resource_id = ?", resourceID).
+		Context(ctx).
+		Delete()
+	if err != nil {
+		return err
+	}
+
+	if len(albums) > 0 {
+		_, err = tx.Model(&albums).
+			Context(ctx).
+			Insert()
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func GetAlbumsByResourceID(ctx context.Context, db *pg.DB, resourceID string) ([]*Album, error) {
+	var albums []*Album
+
+	err := db.Model(&albums).
+		Where("album.resource_id = ?", resourceID).
+		Relation("AlbumMetadata").
+		Order("album.path ASC").
+		Context(ctx).
+		Select()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return albums, nil
+}
+
+func GetLibraryAlbumCount(ctx context.Context, db *pg.DB, uID uuid.UUID) (int, error) {
+	n, err := db.Model((*Album)(nil)).
+		Context(ctx).
+		Join("join library as l").
+		JoinOn("album.resource_id = l.resource_id").
+		Where("l.user_id = ?", uID).
+		Count()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count albums")
+	}
+	return n, nil
+}
+
+// GetLibraryAlbumList loads the albums in the user's library with their
+// metadata and torrent.
+func GetLibraryAlbumList(ctx context.Context, db *pg.DB, uID uuid.UUID, sort SortType) ([]*Album, error) {
+	var list []*Album
+
+	query := db.Model(&list).
+		Context(ctx).
+		Join("join library as l").
+		JoinOn("album.resource_id = l.resource_id").
+		Where("l.user_id = ?", uID).
+		Relation("AlbumMetadata").
+		Relation("Torrent")
+
+	switch sort {
+	case SortTypeName:
+		query.OrderExpr("COALESCE(album_metadata.artist, album.artist) ASC, COALESCE(album_metadata.title, album.title) ASC")
+	case SortTypeYear:
+		query.OrderExpr("COALESCE(album_metadata.year, album.year) DESC NULLS LAST")
+	case SortTypeRecentlyAdded:
+		fallthrough
+	default:
+		query.OrderExpr("l.created_at DESC, album.path ASC")
+	}
+
+	err := query.Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch album list")
+	}
+
+	return list, nil
+}
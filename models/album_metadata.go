@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	uuid "github.com/satori/go.uuid"
+)
+
+// AlbumMetadata is what a music mapper knows of an album. MBID is the
+// MusicBrainz release group id.
+type AlbumMetadata struct {
+	tableName struct{} `pg:"album_metadata"`
+
+	AlbumMetadataID uuid.UUID `pg:"album_metadata_id,pk,type:uuid,default:uuid_generate_v4()"`
+	MBID            string    `pg:"mbid"`
+	Artist          string    `pg:"artist"`
+	Title           string    `pg:"title"`
+	Year            *int16    `pg:"year"`
+	CoverURL        string    `pg:"cover_url"`
+	CreatedAt       time.Time `pg:"created_at,default:now()"`
+	UpdatedAt       time.Time `pg:"updated_at,default:now()"`
+}
+
+func LinkAlbumToMetadata(
+	ctx context.Context,
+	db *pg.DB,
+	albumID uuid.UUID,
+	metadataID uuid.UUID,
+) error {
+	_, err := db.Model(&Album{}).
+		Set("album_metadata_id = ?", metadataID).
+		Where("album_id = ?", albumID).
+		Context(ctx).
+		Update()
+	return err
+}
+
+func UpsertAlbumMetadata(
+	ctx context.Context,
+	db *pg.DB,
+	md *AlbumMetadata,
+) (uuid.UUID, error) {
+	_, err := db.Model(md).
+		Context(ctx).
+		OnConflict("(mbid) DO UPDATE").
+		Set(`
+			artist = EXCLUDED.artist,
+			title = EXCLUDED.title,
+			year = EXCLUDED.year,
+			cover_url = EXCLUDED.cover_url
+		`).
+		Returning("album_metadata_id").
+		Insert()
+	if err != nil {
+		return uuid.NewV4(), err
+	}
+
+	return md.AlbumMetadataID, nil
+}
+
+func GetAlbumMetadataByMBID(
+	ctx context.Context,
+	db *pg.DB,
+	mbid string,
+) (*AlbumMetadata, error) {
+	var meta AlbumMetadata
+
+	err := db.Model(&meta).
+		Context(ctx).
+		Where("mbid = ?", mbid).
+		Limit(1).
+		Select()
+
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
@@ -0,0 +1,68 @@
+package anilist
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
+
+// Mapping places an AniList entry in the catalogue: the series it belongs
+// to, and where its episode numbers start. A nil Season means the numbers
+// count from the show's first episode.
+type Mapping struct {
+	tableName struct{} `pg:"anilist.mapping"`
+
+	AnilistID     int       `pg:"anilist_id,pk"`
+	VideoID       string    `pg:"video_id,notnull"`
+	Season        *int16    `pg:"season"`
+	EpisodeOffset int16     `pg:"episode_offset,notnull,use_zero"`
+	Curated       bool      `pg:"curated,notnull,use_zero"`
+	CreatedAt     time.Time `pg:"created_at,default:now()"`
+	UpdatedAt     time.Time `pg:"updated_at,default:now()"`
+}
+
+// ListMappingsByVideoID returns the entries mapped to one series, curated
+// ones first
+func ListMappingsByVideoID(ctx context.Context, db *pg.DB, videoID string) ([]*Mapping, error) {
+	var out []*Mapping
+	err := db.Model(&out).
+		Context(ctx).
+		Where("video_id = ?", videoID).
+		Order("curated DESC", "anilist_id ASC").
+		Select()
+	return out, err
+}
+
+// GetMapping returns the mapping of one entry, nil when it has none
+func GetMapping(ctx context.Context, db *pg.DB, anilistID int) (*Mapping, error) {
+	var m Mapping
+	err := db.Model(&m).
+		Context(ctx).
+		Where("anilist_id = ?", anilistID).
+		Limit(1).
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UpsertMapping records the series an entry was found to belong to. A
+// curated row is left as it is.
+func UpsertMapping(ctx context.Context, db *pg.DB, anilistID int, videoID string) error {
+	_, err := db.Model(&Mapping{
+		AnilistID: anilistID,
+		VideoID:   videoID,
+	}).
+		Context(ctx).
+		OnConflict("(anilist_id) DO UPDATE").
+		Set("video_id = EXCLUDED.video_id").
+		Where("mapping.curated = false").
+		Insert()
+	return err
+}
@@ -0,0 +1,69 @@
+package anilist
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
+
+type Media struct {
+	tableName struct{} `pg:"anilist.media"`
+
+	AnilistID int            `pg:"anilist_id,pk"`
+	Title     string         `pg:"title,notnull"`
+	Year      *int16         `pg:"year"`
+	Metadata  map[string]any `pg:"metadata,type:jsonb"`
+	CreatedAt time.Time      `pg:"created_at,default:now()"`
+	UpdatedAt time.Time      `pg:"updated_at,default:now()"`
+}
+
+func GetMediaByID(ctx context.Context, db *pg.DB, anilistID int) (*Media, error) {
+	var m Media
+
+	err := db.Model(&m).
+		Context(ctx).
+		Where("anilist_id = ?", anilistID).
+		Limit(1).
+		Select()
+
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ListMediaByID returns the entries it has of the given ids, in no order
+func ListMediaByID(ctx context.Context, db *pg.DB, anilistIDs []int) ([]*Media, error) {
+	var out []*Media
+	if len(anilistIDs) == 0 {
+		return out, nil
+	}
+	err := db.Model(&out).
+		Context(ctx).
+		WhereIn("anilist_id IN (?)", anilistIDs).
+		Select()
+	return out, err
+}
+
+func UpsertMedia(ctx context.Context, db *pg.DB, anilistID int, title string, year *int16, metadata map[string]any) (*Media, error) {
+	m := &Media{
+		AnilistID: anilistID,
+		Title:     title,
+		Year:      year,
+		Metadata:  metadata,
+	}
+
+	_, err := db.Model(m).
+		Context(ctx).
+		OnConflict("(anilist_id) DO UPDATE").
+		Set("metadata = EXCLUDED.metadata, title = EXCLUDED.title, year = EXCLUDED.year").
+		Insert()
+
+	return m, err
+}
@@ -0,0 +1,68 @@
+package anilist
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Query struct {
+	tableName struct{} `pg:"anilist.query"`
+
+	QueryID   uuid.UUID `pg:"query_id,pk,type:uuid,default:uuid_generate_v4()"`
+	Title     string    `pg:"title"`
+	Year      *int16    `pg:"year"`
+	AnilistID *int      `pg:"anilist_id"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
+	UpdatedAt time.Time `pg:"updated_at,default:now()"`
+}
+
+func GetQuery(ctx context.Context, db *pg.DB, title string, year *int16) (*Query, error) {
+	query := &Query{}
+
+	err := db.Model(query).
+		Context(ctx).
+		Where("title = ?", strings.ToLower(strings.TrimSpace(title))).
+		Apply(func(q *orm.Query) (*orm.Query, error) {
+			if year != nil {
+				q = q.Where("year = ?", *year)
+			} else {
+				q = q.Where("year IS NULL")
+			}
+			return q, nil
+		}).
+		Limit(1).
+		Select()
+
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// UpsertQuery records what a search for title and year found, nil for
+// nothing. A forced search replaces the earlier answer.
+func UpsertQuery(ctx context.Context, db *pg.DB, title string, year *int16, anilistID *int) (*Query, error) {
+	q := &Query{
+		Title:     strings.ToLower(strings.TrimSpace(title)),
+		Year:      year,
+		AnilistID: anilistID,
+	}
+
+	_, err := db.Model(q).
+		Context(ctx).
+		OnConflict("(title, COALESCE(year, -1)) DO UPDATE").
+		Set("anilist_id = EXCLUDED.anilist_id").
+		Insert()
+
+	return q, err
+}
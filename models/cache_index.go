@@ -15,17 +15,31 @@ type CacheIndex struct {
 	ResourceID  string               `pg:"resource_id,notnull"`
 	FileIdx     int                  `pg:"file_idx,notnull,use_zero"`
 	LastSeenAt  time.Time            `pg:"last_seen_at,default:now()"`
+	ExpiresAt   *time.Time           `pg:"expires_at"`
 	CreatedAt   time.Time            `pg:"created_at,default:now()"`
 	UpdatedAt   time.Time            `pg:"updated_at,default:now()"`
 }
 
+// CacheIndexTorrentFileIdx is the file index of an entry that vouches for a
+// whole torrent. The cache warmer checks hashes it has no file for; a debrid
+// service caches a torrent whole, so such an entry answers for every file.
+const CacheIndexTorrentFileIdx = -1
+
+// CacheIndexKey identifies a file in the cache index
+type CacheIndexKey struct {
+	ResourceID string
+	FileIdx    int
+}
+
 // CacheIndexResult represents a cache entry with backend type and last seen time
 type CacheIndexResult struct {
 	BackendType StreamingBackendType
 	LastSeenAt  time.Time
 }
 
-// MarkAsCached updates the last_seen_at for a cache entry, or creates it if it doesn't exist
+// MarkAsCached updates the last_seen_at for a cache entry, or creates it if it doesn't exist.
+// A link that resolved is the strongest evidence there is, so any shorter
+// expiry a bulk check put on the entry is cleared.
 func MarkAsCached(ctx context.Context, db *pg.DB, backendType StreamingBackendType, resourceID string, fileIdx int) error {
 	now := time.Now()
 	cache := &CacheIndex{
@@ -40,14 +54,60 @@ func MarkAsCached(ctx context.Context, db *pg.DB, backendType StreamingBackendTy
 		Column("backend_type", "resource_id", "file_idx", "last_seen_at").
 		OnConflict("(resource_id, file_idx, backend_type) DO UPDATE").
 		Set("last_seen_at = EXCLUDED.last_seen_at").
+		Set("expires_at = NULL").
+		Insert()
+
+	return err
+}
+
+// MarkAsCachedUntil records files a backend reported cached in a bulk check,
+// valid until expiresAt. An entry a resolved link already vouches for keeps
+// its longer window.
+func MarkAsCachedUntil(ctx context.Context, db *pg.DB, backendType StreamingBackendType, keys []CacheIndexKey, expiresAt time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	entries := make([]*CacheIndex, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, &CacheIndex{
+			BackendType: backendType,
+			ResourceID:  k.ResourceID,
+			FileIdx:     k.FileIdx,
+			LastSeenAt:  now,
+			ExpiresAt:   &expiresAt,
+		})
+	}
+
+	_, err := db.Model(&entries).
+		Context(ctx).
+		Column("backend_type", "resource_id", "file_idx", "last_seen_at", "expires_at").
+		OnConflict("(resource_id, file_idx, backend_type) DO UPDATE").
+		Set("last_seen_at = EXCLUDED.last_seen_at").
+		Set("expires_at = CASE WHEN cache_index.expires_at IS NULL THEN NULL ELSE EXCLUDED.expires_at END").
 		Insert()
 
 	return err
 }
 
+// DeleteCached removes every entry of the given resources on a backend —
+// used when a bulk check says the backend no longer has them.
+func DeleteCached(ctx context.Context, db *pg.DB, backendType StreamingBackendType, resourceIDs []string) error {
+	if len(resourceIDs) == 0 {
+		return nil
+	}
+	_, err := db.Model((*CacheIndex)(nil)).
+		Context(ctx).
+		Where("backend_type = ?", backendType).
+		Where("resource_id IN (?)", pg.In(resourceIDs)).
+		Delete()
+	return err
+}
+
 // IsCached returns a list of backend types and their last seen times for a
-// given resource and file index. Only entries seen within the expiration
-// window are returned.
+// given resource and file index, whole-torrent entries included. Only entries
+// seen within the expiration window, and not past their own expiry, are
+// returned.
 func IsCached(ctx context.Context, db *pg.DB, resourceID string, fileIdx int, expiration time.Duration) ([]CacheIndexResult, error) {
 	var results []CacheIndexResult
 	cutoffTime := time.Now().Add(-expiration)
@@ -56,8 +116,9 @@ func IsCached(ctx context.Context, db *pg.DB, resourceID string, fileIdx int, ex
 		Context(ctx).
 		Column("backend_type", "last_seen_at").
 		Where("resource_id = ?", resourceID).
-		Where("file_idx = ?", fileIdx).
+		Where("file_idx IN (?, ?)", fileIdx, CacheIndexTorrentFileIdx).
 		Where("last_seen_at >= ?", cutoffTime).
+		Where("expires_at IS NULL OR expires_at > now()").
 		Select(&results)
 
 	if err != nil {
@@ -67,13 +128,45 @@ func IsCached(ctx context.Context, db *pg.DB, resourceID string, fileIdx int, ex
 	return results, nil
 }
 
-// DeleteOldCacheEntries removes cache entries older than the specified expiration
+// CachedTorrents returns, for each of the resources that has any live entry
+// on one of the backend types, the types that have it. A file cached is
+// taken as the torrent cached: this answers "is there something instant
+// here", not which file.
+func CachedTorrents(ctx context.Context, db *pg.DB, resourceIDs []string, backendTypes []StreamingBackendType, expiration time.Duration) (map[string][]StreamingBackendType, error) {
+	res := map[string][]StreamingBackendType{}
+	if len(resourceIDs) == 0 || len(backendTypes) == 0 {
+		return res, nil
+	}
+	var rows []struct {
+		ResourceID  string
+		BackendType StreamingBackendType
+	}
+	err := db.Model((*CacheIndex)(nil)).
+		Context(ctx).
+		ColumnExpr("DISTINCT resource_id, backend_type").
+		Where("resource_id IN (?)", pg.In(resourceIDs)).
+		Where("backend_type IN (?)", pg.In(backendTypes)).
+		Where("last_seen_at >= ?", time.Now().Add(-expiration)).
+		Where("expires_at IS NULL OR expires_at > now()").
+		Select(&rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		res[r.ResourceID] = append(res[r.ResourceID], r.BackendType)
+	}
+	return res, nil
+}
+
+// DeleteOldCacheEntries removes cache entries older than the specified expiration,
+// and those past their own expiry
 func DeleteOldCacheEntries(ctx context.Context, db *pg.DB, expiration time.Duration) (int, error) {
 	cutoffTime := time.Now().Add(-expiration)
 
 	res, err := db.Model((*CacheIndex)(nil)).
 		Context(ctx).
 		Where("last_seen_at < ?", cutoffTime).
+		WhereOr("expires_at < now()").
 		Delete()
 
 	if err != nil {
@@ -56,3 +56,16 @@ func GetFirstEpisodePathForSeries(ctx context.Context, db *pg.DB, seriesID uuid.
 	}
 	return *ep.Path, nil
 }
+
+// RenumberEpisode moves an episode to its place in the catalogue's
+// seasons. Used for releases numbered across a whole run, which are
+// stored seasonless first.
+func RenumberEpisode(ctx context.Context, db *pg.DB, episodeID uuid.UUID, season int16, episode int16) error {
+	_, err := db.Model(&Episode{}).
+		Context(ctx).
+		Set("season = ?", season).
+		Set("episode = ?", episode).
+		Where("episode_id = ?", episodeID).
+		Update()
+	return err
+}
@@ -59,6 +59,45 @@ func IsInLibrary(ctx context.Context, db *pg.DB, uID uuid.UUID, resourceID strin
 	return exists, nil
 }
 
+// InLibrary returns which of the resource ids are in the user's library
+func InLibrary(ctx context.Context, db *pg.DB, uID uuid.UUID, resourceIDs []string) (map[string]bool, error) {
+	res := map[string]bool{}
+	if len(resourceIDs) == 0 {
+		return res, nil
+	}
+	var ids []string
+	err := db.Model((*Library)(nil)).
+		Context(ctx).
+		Column("resource_id").
+		Where("user_id = ? AND resource_id IN (?)", uID, pg.In(resourceIDs)).
+		Select(&ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check library membership")
+	}
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// ListTrendingResourceIDs returns the torrents added to the most libraries
+// since the given time, most added first.
+func ListTrendingResourceIDs(ctx context.Context, db *pg.DB, since time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := db.Model((*Library)(nil)).
+		Context(ctx).
+		Column("resource_id").
+		Where("created_at >= ?", since).
+		Group("resource_id").
+		OrderExpr("count(*) DESC, max(created_at) DESC").
+		Limit(limit).
+		Select(&ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list trending library resources")
+	}
+	return ids, nil
+}
+
 func GetLibraryByName(ctx context.Context, db *pg.DB, uID uuid.UUID, name string) (*Library, error) {
 	var lib Library
 	err := db.Model(&lib).
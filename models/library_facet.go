@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// LibraryFacet is what the browsable trees of the library filesystem group a
+// library entry by. Year is the enriched year, falling back to the one parsed
+// from the torrent name; Genres and Collection come from TMDB and are empty
+// until the entry is matched there.
+type LibraryFacet struct {
+	ResourceID string   `pg:"resource_id"`
+	Year       *int16   `pg:"year"`
+	Genres     []string `pg:"genres,array"`
+	Collection string   `pg:"collection"`
+}
+
+// GetLibraryFacets returns a facet per entry of the user's library, in one
+// query. An entry that is neither a movie nor a series still gets a row, with
+// everything empty.
+func GetLibraryFacets(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]*LibraryFacet, error) {
+	var list []*LibraryFacet
+	_, err := db.QueryContext(ctx, &list, `
+		SELECT DISTINCT ON (l.resource_id)
+			l.resource_id,
+			COALESCE(mmd.year, m.year, smd.year, s.year) AS year,
+			ARRAY(
+				SELECT g->>'name'
+				FROM jsonb_array_elements(COALESCE(ti.metadata->'genres', '[]'::jsonb)) AS g
+				WHERE g->>'name' <> ''
+			) AS genres,
+			COALESCE(ti.metadata->'belongs_to_collection'->>'name', '') AS collection
+		FROM library l
+		LEFT JOIN movie m ON m.resource_id = l.resource_id
+		LEFT JOIN movie_metadata mmd ON mmd.movie_metadata_id = m.movie_metadata_id
+		LEFT JOIN series s ON s.resource_id = l.resource_id
+		LEFT JOIN series_metadata smd ON smd.series_metadata_id = s.series_metadata_id
+		LEFT JOIN tmdb.info ti ON ti.imdb_id = COALESCE(mmd.video_id, smd.video_id)
+			AND ti.type = CASE WHEN mmd.video_id IS NOT NULL THEN 1 ELSE 2 END
+		WHERE l.user_id = ?
+		ORDER BY l.resource_id
+	`, uID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch library facets")
+	}
+	return list, nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// LibraryMagnet is a magnet dropped into the library filesystem's torrents/
+// folder that has no metadata yet. FileName and Body are the file as it was
+// dropped. The row goes away once the torrent is in the library; Error is set
+// when resolving it failed.
+type LibraryMagnet struct {
+	tableName struct{} `pg:"library_magnet"`
+
+	LibraryMagnetID uuid.UUID `pg:"library_magnet_id,pk"`
+	UserID          uuid.UUID `pg:"user_id"`
+	ResourceID      string    `pg:"resource_id"`
+	FileName        string    `pg:"file_name"`
+	Body            string    `pg:"body"`
+	Error           *string   `pg:"error"`
+	CreatedAt       time.Time `pg:"created_at"`
+	UpdatedAt       time.Time `pg:"updated_at"`
+}
+
+// UpsertLibraryMagnet records a drop. Dropping the same infohash again
+// replaces the earlier row — file, id and all — and clears its error,
+// so a job still resolving the old id no longer owns it.
+func UpsertLibraryMagnet(ctx context.Context, db *pg.DB, m *LibraryMagnet) error {
+	m.LibraryMagnetID = uuid.NewV4()
+	m.Error = nil
+	_, err := db.Model(m).
+		Context(ctx).
+		OnConflict("(user_id, resource_id) DO UPDATE").
+		Set("library_magnet_id = EXCLUDED.library_magnet_id").
+		Set("file_name = EXCLUDED.file_name").
+		Set("body = EXCLUDED.body").
+		Set("error = NULL").
+		Set("created_at = now()").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert library magnet")
+	}
+	return nil
+}
+
+// ListLibraryMagnets returns a user's pending magnets, oldest first.
+func ListLibraryMagnets(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]*LibraryMagnet, error) {
+	var list []*LibraryMagnet
+	err := db.Model(&list).
+		Context(ctx).
+		Where("user_id = ?", userID).
+		OrderExpr("created_at ASC").
+		Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list library magnets")
+	}
+	return list, nil
+}
+
+// LibraryMagnetExists tells whether a row is still there — not deleted by
+// its owner and not replaced by a newer drop of the same infohash.
+func LibraryMagnetExists(ctx context.Context, db *pg.DB, id uuid.UUID) (bool, error) {
+	exists, err := db.Model((*LibraryMagnet)(nil)).
+		Context(ctx).
+		Where("library_magnet_id = ?", id).
+		Exists()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check library magnet")
+	}
+	return exists, nil
+}
+
+// FailLibraryMagnet stores why resolving failed. A row that has been
+// replaced by a newer drop in the meantime is left alone.
+func FailLibraryMagnet(ctx context.Context, db *pg.DB, id uuid.UUID, reason string) error {
+	_, err := db.Model((*LibraryMagnet)(nil)).
+		Context(ctx).
+		Set("error = ?", reason).
+		Where("library_magnet_id = ?", id).
+		Update()
+	if err != nil {
+		return errors.Wrap(err, "failed to mark library magnet failed")
+	}
+	return nil
+}
+
+// DeleteLibraryMagnet removes a row by id, scoped to its owner. Deleting a
+// row that is already gone is not an error.
+func DeleteLibraryMagnet(ctx context.Context, db *pg.DB, userID, id uuid.UUID) error {
+	_, err := db.Model((*LibraryMagnet)(nil)).
+		Context(ctx).
+		Where("library_magnet_id = ? AND user_id = ?", id, userID).
+		Delete()
+	if err != nil {
+		return errors.Wrap(err, "failed to delete library magnet")
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// MatchOverridePromoteAt is how many users on a paid plan, each with a
+// torrent of that name in their library, have to correct the same parsed
+// name to the same title before the correction applies to every torrent
+// with that name. Anyone can sign up, but not for free.
+const MatchOverridePromoteAt = 2
+
+// MatchOverride is a user's correction of the title a resource was
+// matched to. A nil VideoID means the user said the resource is not a
+// movie or show at all.
+type MatchOverride struct {
+	tableName   struct{}    `pg:"match_override"`
+	ID          uuid.UUID   `pg:"match_override_id,pk,type:uuid,default:uuid_generate_v4()"`
+	UserID      uuid.UUID   `pg:"user_id,notnull"`
+	ResourceID  string      `pg:"resource_id,notnull"`
+	ContentType ContentType `pg:"content_type,notnull"`
+	VideoID     *string     `pg:"video_id"`
+	QueryTitle  string      `pg:"query_title,notnull"`
+	QueryYear   int16       `pg:"query_year,notnull,use_zero"`
+	// Paid is whether the user was on a paid plan when they made it
+	Paid      bool      `pg:"paid,notnull,use_zero"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
+	UpdatedAt time.Time `pg:"updated_at,default:now()"`
+}
+
+// NotMedia reports whether the user said the resource is not a movie or show
+func (o *MatchOverride) NotMedia() bool {
+	return o.VideoID == nil
+}
+
+// GlobalMatchOverride is a correction enough users agreed on to apply to
+// every torrent whose parsed name folds to QueryTitle and QueryYear
+type GlobalMatchOverride struct {
+	tableName     struct{}    `pg:"global_match_override"`
+	ContentType   ContentType `pg:"content_type,pk"`
+	QueryTitle    string      `pg:"query_title,pk"`
+	QueryYear     int16       `pg:"query_year,pk,use_zero"`
+	VideoID       *string     `pg:"video_id"`
+	Confirmations int         `pg:"confirmations,notnull,use_zero"`
+	CreatedAt     time.Time   `pg:"created_at,default:now()"`
+	UpdatedAt     time.Time   `pg:"updated_at,default:now()"`
+}
+
+// NotMedia reports whether the agreed correction is "not a movie or show"
+func (o *GlobalMatchOverride) NotMedia() bool {
+	return o.VideoID == nil
+}
+
+// ResourceMatchOverride is the correction that decides a resource's match:
+// the title most of the users with the resource in their library picked,
+// the most recent one on a tie
+type ResourceMatchOverride struct {
+	ContentType ContentType `pg:"content_type"`
+	VideoID     *string     `pg:"video_id"`
+	Users       int         `pg:"users"`
+}
+
+// NotMedia reports whether the resource is not a movie or show
+func (o *ResourceMatchOverride) NotMedia() bool {
+	return o.VideoID == nil
+}
+
+// UpsertMatchOverride stores the user's correction for a resource,
+// replacing the previous one
+func UpsertMatchOverride(ctx context.Context, db *pg.DB, o *MatchOverride) error {
+	_, err := db.Model(o).
+		Context(ctx).
+		OnConflict("(user_id, resource_id) DO UPDATE").
+		Set("content_type = EXCLUDED.content_type").
+		Set("video_id = EXCLUDED.video_id").
+		Set("query_title = EXCLUDED.query_title").
+		Set("query_year = EXCLUDED.query_year").
+		Set("paid = EXCLUDED.paid").
+		Returning("*").
+		Insert()
+	return err
+}
+
+// GetUserMatchOverride returns the user's correction for a resource, or
+// nil if they have not made one
+func GetUserMatchOverride(ctx context.Context, db *pg.DB, userID uuid.UUID, resourceID string) (*MatchOverride, error) {
+	o := new(MatchOverride)
+	err := db.Model(o).
+		Context(ctx).
+		Where("user_id = ?", userID).
+		Where("resource_id = ?", resourceID).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// DeleteUserMatchOverride withdraws the user's correction for a resource
+func DeleteUserMatchOverride(ctx context.Context, db *pg.DB, userID uuid.UUID, resourceID string) error {
+	_, err := db.Model((*MatchOverride)(nil)).
+		Context(ctx).
+		Where("user_id = ?", userID).
+		Where("resource_id = ?", resourceID).
+		Delete()
+	return err
+}
+
+// ownedMatchOverrides selects the corrections made by users who still
+// have the resource in their library
+func ownedMatchOverrides(ctx context.Context, db *pg.DB) *orm.Query {
+	return db.Model((*MatchOverride)(nil)).
+		Context(ctx).
+		Join("JOIN library AS l ON l.user_id = match_override.user_id AND l.resource_id = match_override.resource_id")
+}
+
+// GetResourceMatchOverride returns the correction that decides the
+// resource's match, or nil when nobody who has it in their library
+// corrected it
+func GetResourceMatchOverride(ctx context.Context, db *pg.DB, resourceID string) (*ResourceMatchOverride, error) {
+	var res []ResourceMatchOverride
+	err := ownedMatchOverrides(ctx, db).
+		ColumnExpr("match_override.content_type, match_override.video_id").
+		ColumnExpr("count(*) AS users").
+		Where("match_override.resource_id = ?", resourceID).
+		Group("match_override.content_type", "match_override.video_id").
+		OrderExpr("users DESC, max(match_override.updated_at) DESC").
+		Limit(1).
+		Select(&res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return &res[0], nil
+}
+
+// GetOverriddenResourceIDs returns the ids of every resource whose match a
+// correction decides
+func GetOverriddenResourceIDs(ctx context.Context, db *pg.DB) (map[string]bool, error) {
+	var ids []string
+	err := ownedMatchOverrides(ctx, db).
+		ColumnExpr("DISTINCT match_override.resource_id").
+		Select(&ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// PromoteMatchOverride counts the users on a paid plan who corrected the
+// parsed name to the same title on a torrent in their library and, once
+// there are MatchOverridePromoteAt of them, stores the correction as a
+// global one. A global correction is only
+// replaced by one with at least as many users behind it. Reports whether
+// the correction is now global.
+func PromoteMatchOverride(ctx context.Context, db *pg.DB, ct ContentType, queryTitle string, queryYear int16, videoID *string) (bool, error) {
+	if queryTitle == "" {
+		return false, nil
+	}
+	var n int
+	err := ownedMatchOverrides(ctx, db).
+		ColumnExpr("count(DISTINCT match_override.user_id)").
+		Where("match_override.paid").
+		Where("match_override.content_type = ?", ct).
+		Where("match_override.query_title = ?", queryTitle).
+		Where("match_override.query_year = ?", queryYear).
+		Where("match_override.video_id IS NOT DISTINCT FROM ?", videoID).
+		Select(&n)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to count match overrides")
+	}
+	if n < MatchOverridePromoteAt {
+		return false, nil
+	}
+	g := &GlobalMatchOverride{
+		ContentType:   ct,
+		QueryTitle:    queryTitle,
+		QueryYear:     queryYear,
+		VideoID:       videoID,
+		Confirmations: n,
+	}
+	res, err := db.Model(g).
+		Context(ctx).
+		OnConflict("(content_type, query_title, query_year) DO UPDATE").
+		Set("video_id = EXCLUDED.video_id").
+		Set("confirmations = EXCLUDED.confirmations").
+		Where("EXCLUDED.confirmations >= global_match_override.confirmations").
+		Insert()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to store global match override")
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+// GetGlobalMatchOverride returns the global correction for a parsed name,
+// or nil if there is none
+func GetGlobalMatchOverride(ctx context.Context, db *pg.DB, ct ContentType, queryTitle string, queryYear int16) (*GlobalMatchOverride, error) {
+	o := new(GlobalMatchOverride)
+	err := db.Model(o).
+		Context(ctx).
+		Where("content_type = ?", ct).
+		Where("query_title = ?", queryTitle).
+		Where("query_year = ?", queryYear).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// ListGlobalMatchOverrides returns every global correction
+func ListGlobalMatchOverrides(ctx context.Context, db *pg.DB) ([]*GlobalMatchOverride, error) {
+	var res []*GlobalMatchOverride
+	err := db.Model(&res).
+		Context(ctx).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
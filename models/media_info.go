@@ -23,6 +23,7 @@ type MediaInfo struct {
 	Movies     []*Movie   `pg:"rel:has-many,fk:resource_id"`
 	SeriesList []*Series  `pg:"rel:has-many,fk:resource_id"`
 	Episodes   []*Episode `pg:"rel:has-many,fk:resource_id"`
+	Albums     []*Album   `pg:"rel:has-many,fk:resource_id"`
 }
 
 type MediaInfoStatus int16
@@ -48,6 +49,9 @@ const (
 	// with its own title and year — the enricher splits them into
 	// separate movie rows so each can be matched against TMDB.
 	MediaInfoMediaTypeMovieMultiple
+	// MusicAlbum is a torrent of audio tracks: one album, or an
+	// artist's discography with a folder per album.
+	MediaInfoMediaTypeMusicAlbum
 )
 
 func (s MediaInfoMediaType) String() string {
@@ -64,6 +68,8 @@ func (s MediaInfoMediaType) String() string {
 		return "SeriesCompilation"
 	case MediaInfoMediaTypeMovieMultiple:
 		return "MovieMultiple"
+	case MediaInfoMediaTypeMusicAlbum:
+		return "MusicAlbum"
 	default:
 		return "Unknown"
 	}
@@ -127,6 +127,25 @@ func GetMoviesByResourceID(ctx context.Context, db *pg.DB, resourceID string) ([
 	return movies, nil
 }
 
+// GetMoviesWithMetadataByResourceIDs loads the movies of several resources
+// at once, metadata attached, for listings that would otherwise query per row.
+func GetMoviesWithMetadataByResourceIDs(ctx context.Context, db *pg.DB, resourceIDs []string) ([]*Movie, error) {
+	var movies []*Movie
+	if len(resourceIDs) == 0 {
+		return movies, nil
+	}
+	err := db.Model(&movies).
+		Context(ctx).
+		Where("movie.resource_id IN (?)", pg.In(resourceIDs)).
+		Relation("MovieMetadata").
+		Order("movie.created_at ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
 func GetMovieWithMetadataByResourceID(ctx context.Context, db *pg.DB, resourceID string) (*Movie, error) {
 	var m Movie
 
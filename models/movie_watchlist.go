@@ -151,3 +151,34 @@ func ListMovieWatchlistItems(ctx context.Context, db *pg.DB, userID uuid.UUID) (
 	}
 	return out, nil
 }
+
+// ListWatchlistResourceIDs returns the torrents known to carry a title on the
+// user's movie or series watchlist, newest bookmark first. Any user's library
+// counts: what matters is that the torrent has been matched to the title, not
+// who added it.
+func ListWatchlistResourceIDs(ctx context.Context, db *pg.DB, userID uuid.UUID, limit int) ([]string, error) {
+	var out []string
+	_, err := db.QueryContext(ctx, &out, `
+		SELECT resource_id FROM (
+			SELECT m.resource_id, max(mw.created_at) AS created_at
+			FROM movie_watchlist mw
+			JOIN movie_metadata mmd ON mmd.video_id = mw.video_id
+			JOIN movie m ON m.movie_metadata_id = mmd.movie_metadata_id
+			WHERE mw.user_id = ?0
+			GROUP BY m.resource_id
+			UNION ALL
+			SELECT s.resource_id, max(sw.created_at) AS created_at
+			FROM series_watchlist sw
+			JOIN series_metadata smd ON smd.video_id = sw.video_id
+			JOIN series s ON s.series_metadata_id = smd.series_metadata_id
+			WHERE sw.user_id = ?0
+			GROUP BY s.resource_id
+		) w
+		ORDER BY created_at DESC
+		LIMIT ?1
+	`, userID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list watchlist resources")
+	}
+	return out, nil
+}
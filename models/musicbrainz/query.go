@@ -0,0 +1,64 @@
+package musicbrainz
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Query struct {
+	tableName struct{} `pg:"musicbrainz.query"`
+
+	QueryID   uuid.UUID `pg:"query_id,pk,type:uuid,default:uuid_generate_v4()"`
+	Artist    string    `pg:"artist"`
+	Album     string    `pg:"album"`
+	MBID      *string   `pg:"mbid"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
+	UpdatedAt time.Time `pg:"updated_at,default:now()"`
+}
+
+func queryKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func GetQuery(ctx context.Context, db *pg.DB, artist, album string) (*Query, error) {
+	query := &Query{}
+
+	err := db.Model(query).
+		Context(ctx).
+		Where("artist = ?", queryKey(artist)).
+		Where("album = ?", queryKey(album)).
+		Limit(1).
+		Select()
+
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// UpsertQuery records what a search for artist and album found, nil for
+// nothing. A forced search replaces the earlier answer.
+func UpsertQuery(ctx context.Context, db *pg.DB, artist, album string, mbid *string) (*Query, error) {
+	q := &Query{
+		Artist: queryKey(artist),
+		Album:  queryKey(album),
+		MBID:   mbid,
+	}
+
+	_, err := db.Model(q).
+		Context(ctx).
+		OnConflict("(artist, album) DO UPDATE").
+		Set("mbid = EXCLUDED.mbid").
+		Insert()
+
+	return q, err
+}
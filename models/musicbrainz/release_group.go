@@ -0,0 +1,58 @@
+package musicbrainz
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
+
+type ReleaseGroup struct {
+	tableName struct{} `pg:"musicbrainz.release_group"`
+
+	MBID      string         `pg:"mbid,pk"`
+	Title     string         `pg:"title,notnull"`
+	Artist    string         `pg:"artist,notnull"`
+	Year      *int16         `pg:"year"`
+	Metadata  map[string]any `pg:"metadata,type:jsonb"`
+	CreatedAt time.Time      `pg:"created_at,default:now()"`
+	UpdatedAt time.Time      `pg:"updated_at,default:now()"`
+}
+
+func GetReleaseGroupByMBID(ctx context.Context, db *pg.DB, mbid string) (*ReleaseGroup, error) {
+	var rg ReleaseGroup
+
+	err := db.Model(&rg).
+		Context(ctx).
+		Where("mbid = ?", mbid).
+		Limit(1).
+		Select()
+
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rg, nil
+}
+
+func UpsertReleaseGroup(ctx context.Context, db *pg.DB, mbid, title, artist string, year *int16, metadata map[string]any) (*ReleaseGroup, error) {
+	rg := &ReleaseGroup{
+		MBID:     mbid,
+		Title:    title,
+		Artist:   artist,
+		Year:     year,
+		Metadata: metadata,
+	}
+
+	_, err := db.Model(rg).
+		Context(ctx).
+		OnConflict("(mbid) DO UPDATE").
+		Set("metadata = EXCLUDED.metadata, title = EXCLUDED.title, artist = EXCLUDED.artist, year = EXCLUDED.year").
+		Insert()
+
+	return rg, err
+}
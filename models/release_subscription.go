@@ -319,6 +319,46 @@ func ListDueReleaseSubscriptions(ctx context.Context, db *pg.DB, now time.Time,
 	return subs, nil
 }
 
+// ListActiveReleaseSubscriptions returns every enabled subscription past its
+// baseline, due or not, with its owner joined. Feed mode reads an indexer's
+// latest releases once per run and matches them against all of these: a
+// release is in the feed now, not when the subscription next comes due.
+func ListActiveReleaseSubscriptions(ctx context.Context, db *pg.DB) ([]ReleaseSubscription, error) {
+	var subs []ReleaseSubscription
+	err := db.Model(&subs).
+		Context(ctx).
+		Relation("User").
+		Where("release_subscription.enabled = ?", true).
+		Where("release_subscription.state = ?", ReleaseSubscriptionStateActive).
+		Order("release_subscription.user_id").
+		Select()
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "failed to list active release subscriptions")
+	}
+	return subs, nil
+}
+
+// ListReleaseSubscriptionsWithPendingHits returns every enabled
+// subscription that still owes its owner a letter, owner joined, grouped
+// by account. The mail delivery pass reads it: hits held back by a digest
+// or by quiet hours sit pending until their account's letter is due, and
+// that can be long after the subscription itself was last polled — or
+// after it completed.
+func ListReleaseSubscriptionsWithPendingHits(ctx context.Context, db *pg.DB) ([]ReleaseSubscription, error) {
+	var subs []ReleaseSubscription
+	err := db.Model(&subs).
+		Context(ctx).
+		Relation("User").
+		Where("release_subscription.enabled = ?", true).
+		Where("EXISTS (SELECT 1 FROM release_subscription_hit AS h WHERE h.release_subscription_id = release_subscription.release_subscription_id AND h.notified_at IS NULL)").
+		Order("release_subscription.user_id", "release_subscription.created_at").
+		Select()
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "failed to list release subscriptions with pending hits")
+	}
+	return subs, nil
+}
+
 // MarkReleaseSubscriptionChecked stamps a completed poll and schedules the
 // next one. State is written too because the baseline pass ends by promoting
 // the row to active.
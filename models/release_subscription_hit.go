@@ -143,3 +143,27 @@ func ListUserReleaseSubscriptionHits(ctx context.Context, db *pg.DB, userID uuid
 	}
 	return hits, nil
 }
+
+// ListActiveReleaseSubscriptionHitHashes returns the hashes the user's
+// enabled, active subscriptions found since the given time, newest first —
+// the releases a letter has pointed the user at, or is about to. Baseline
+// rows are the back catalogue nobody was told about, so they are left out.
+func ListActiveReleaseSubscriptionHitHashes(ctx context.Context, db *pg.DB, userID uuid.UUID, since time.Time, limit int) ([]string, error) {
+	var hashes []string
+	err := db.Model((*ReleaseSubscriptionHit)(nil)).
+		Context(ctx).
+		Column("release_subscription_hit.infohash").
+		Join("JOIN release_subscription AS s ON s.release_subscription_id = release_subscription_hit.release_subscription_id").
+		Where("s.user_id = ?", userID).
+		Where("s.enabled = ?", true).
+		Where("s.state = ?", ReleaseSubscriptionStateActive).
+		Where("release_subscription_hit.is_baseline = ?", false).
+		Where("release_subscription_hit.first_seen_at >= ?", since).
+		Order("release_subscription_hit.first_seen_at DESC").
+		Limit(limit).
+		Select(&hashes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list release subscription hit hashes")
+	}
+	return hashes, nil
+}
@@ -192,6 +192,25 @@ func GetSeriesByVideoID(ctx context.Context, db *pg.DB, uID uuid.UUID, videoID s
 	return list, nil
 }
 
+// GetSeriesWithMetadataByResourceIDs is GetMoviesWithMetadataByResourceIDs
+// for series. Episodes are not loaded.
+func GetSeriesWithMetadataByResourceIDs(ctx context.Context, db *pg.DB, resourceIDs []string) ([]*Series, error) {
+	var list []*Series
+	if len(resourceIDs) == 0 {
+		return list, nil
+	}
+	err := db.Model(&list).
+		Context(ctx).
+		Where("series.resource_id IN (?)", pg.In(resourceIDs)).
+		Relation("SeriesMetadata").
+		Order("series.created_at ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
 func GetSeriesWithMetadataByResourceID(ctx context.Context, db *pg.DB, resourceID string) (*Series, error) {
 	var s Series
 	err := db.Model(&s).
@@ -16,8 +16,41 @@ const (
 	StreamingBackendTypeWebtor     StreamingBackendType = "webtor"
 	StreamingBackendTypeRealDebrid StreamingBackendType = "real_debrid"
 	StreamingBackendTypeTorbox     StreamingBackendType = "torbox"
+	StreamingBackendTypeAllDebrid  StreamingBackendType = "all_debrid"
+	StreamingBackendTypePremiumize StreamingBackendType = "premiumize"
+	// StreamingBackendTypeSABnzbd is a self-hosted SABnzbd, which fetches
+	// Usenet releases only
+	StreamingBackendTypeSABnzbd StreamingBackendType = "sabnzbd"
+	// StreamingBackendTypeNZBGet is a self-hosted NZBGet, which fetches
+	// Usenet releases only
+	StreamingBackendTypeNZBGet StreamingBackendType = "nzbget"
 )
 
+// DisplayName returns the name the service goes by
+func (t StreamingBackendType) DisplayName() string {
+	switch t {
+	case StreamingBackendTypeRealDebrid:
+		return "Real-Debrid"
+	case StreamingBackendTypeTorbox:
+		return "Torbox"
+	case StreamingBackendTypeAllDebrid:
+		return "AllDebrid"
+	case StreamingBackendTypePremiumize:
+		return "Premiumize"
+	case StreamingBackendTypeSABnzbd:
+		return "SABnzbd"
+	case StreamingBackendTypeNZBGet:
+		return "NZBGet"
+	}
+	return string(t)
+}
+
+// SelfHosted reports whether the service runs on the user's own machine,
+// where its finished files are served from the files URL
+func (t StreamingBackendType) SelfHosted() bool {
+	return t == StreamingBackendTypeSABnzbd || t == StreamingBackendTypeNZBGet
+}
+
 // StreamingBackendStatus represents the last status of a streaming backend
 type StreamingBackendStatus string
 
@@ -26,11 +59,26 @@ const (
 	StreamingBackendStatusInvalidCredentials StreamingBackendStatus = "invalid_credentials"
 	StreamingBackendStatusRateLimited        StreamingBackendStatus = "rate_limited"
 	StreamingBackendStatusError              StreamingBackendStatus = "error"
+	// StreamingBackendStatusFailedOver marks a backend whose link stopped
+	// serving mid-playback, so the stream moved on to the next backend
+	StreamingBackendStatusFailedOver StreamingBackendStatus = "failed_over"
 )
 
 // StreamingBackendConfig represents the JSONB config field
 type StreamingBackendConfig map[string]interface{}
 
+// StreamingBackendConfigFilesURL is the address a self-hosted downloader's
+// finished files are served from over HTTP. SABnzbd and NZBGet write them
+// to disk and serve nothing themselves.
+const StreamingBackendConfigFilesURL = "files_url"
+
+// GetString returns the string stored under the key, or "" when there is
+// none
+func (c StreamingBackendConfig) GetString(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
 type StreamingBackend struct {
 	tableName     struct{}                `pg:"streaming_backend"`
 	ID            uuid.UUID               `pg:"streaming_backend_id,pk,type:uuid,default:uuid_generate_v4()"`
@@ -42,8 +90,16 @@ type StreamingBackend struct {
 	Enabled       bool                    `pg:"enabled,notnull,default:true,use_zero"`
 	LastStatus    *StreamingBackendStatus `pg:"last_status"`
 	LastCheckedAt *time.Time              `pg:"last_checked_at"`
-	CreatedAt     time.Time               `pg:"created_at,default:now()"`
-	UpdatedAt     time.Time               `pg:"updated_at,default:now()"`
+	// Account fields, filled by the periodic account check; nil when the
+	// service does not report them
+	PremiumUntil      *time.Time `pg:"premium_until"`
+	Points            *int       `pg:"points"`
+	Usage             *float64   `pg:"usage"`
+	Downloaded        *int64     `pg:"downloaded"`
+	InvalidNotifiedAt *time.Time `pg:"invalid_notified_at"`
+	ExpiryNotifiedFor *time.Time `pg:"expiry_notified_for"`
+	CreatedAt         time.Time  `pg:"created_at,default:now()"`
+	UpdatedAt         time.Time  `pg:"updated_at,default:now()"`
 
 	User *User `pg:"rel:has-one,fk:user_id"`
 }
@@ -93,6 +149,77 @@ func UpdateStreamingBackend(ctx context.Context, db *pg.DB, backend *StreamingBa
 	return err
 }
 
+// Status returns the last status as a plain string, empty when the backend
+// was never checked
+func (s *StreamingBackend) Status() string {
+	if s.LastStatus == nil {
+		return ""
+	}
+	return string(*s.LastStatus)
+}
+
+// StreamingBackendExpiryWarning is how long before the premium plan runs out
+// its owner is warned
+const StreamingBackendExpiryWarning = 7 * 24 * time.Hour
+
+// PremiumExpired reports whether the service reported a premium end that has
+// passed
+func (s *StreamingBackend) PremiumExpired() bool {
+	return s.PremiumUntil != nil && !s.PremiumUntil.After(time.Now())
+}
+
+// PremiumEndsSoon reports whether the premium plan runs out within
+// StreamingBackendExpiryWarning
+func (s *StreamingBackend) PremiumEndsSoon() bool {
+	return s.PremiumUntil != nil && !s.PremiumExpired() &&
+		time.Until(*s.PremiumUntil) <= StreamingBackendExpiryWarning
+}
+
+// UsagePercent returns the spent share of the fair-use allowance in percent
+func (s *StreamingBackend) UsagePercent() int {
+	if s.Usage == nil {
+		return 0
+	}
+	return int(*s.Usage*100 + 0.5)
+}
+
+// GetEnabledStreamingBackends returns every enabled streaming backend with
+// its user, for the periodic account check and the cache warmer
+func GetEnabledStreamingBackends(ctx context.Context, db *pg.DB) ([]*StreamingBackend, error) {
+	var backends []*StreamingBackend
+	err := db.Model(&backends).
+		Context(ctx).
+		Relation("User").
+		Where("streaming_backend.enabled = true").
+		Order("streaming_backend.created_at").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return backends, nil
+}
+
+// UpdateStreamingBackendAccount stores what the account check learned
+func UpdateStreamingBackendAccount(ctx context.Context, db *pg.DB, backend *StreamingBackend) error {
+	_, err := db.Model(backend).
+		Context(ctx).
+		Column("last_status", "last_checked_at", "premium_until", "points", "usage", "downloaded", "invalid_notified_at", "expiry_notified_for").
+		Where("streaming_backend_id = ?", backend.ID).
+		Update()
+	return err
+}
+
+// SetStreamingBackendStatus records the outcome of the latest call to a backend
+func SetStreamingBackendStatus(ctx context.Context, db *pg.DB, id uuid.UUID, status StreamingBackendStatus) error {
+	_, err := db.Model(&StreamingBackend{}).
+		Context(ctx).
+		Set("last_status = ?", status).
+		Set("last_checked_at = now()").
+		Where("streaming_backend_id = ?", id).
+		Update()
+	return err
+}
+
 // DeleteStreamingBackend deletes a streaming backend by ID
 func DeleteStreamingBackend(ctx context.Context, db *pg.DB, id uuid.UUID) error {
 	_, err := db.Model(&StreamingBackend{}).
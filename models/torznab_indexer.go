@@ -48,6 +48,27 @@ type TorznabIndexer struct {
 	// Nil until then, and for feeds that tag nothing.
 	TrackerName *string `pg:"tracker_name"`
 
+	// FeedReadAt and FeedCoveredSince are the release-subscription poller's
+	// feed-mode bookkeeping: the last successful read of the indexer's
+	// latest-releases feed, and the start of the unbroken run of reads that
+	// ends there. Nil until the poller has read the feed once.
+	FeedReadAt       *time.Time `pg:"feed_read_at"`
+	FeedCoveredSince *time.Time `pg:"feed_covered_since"`
+
+	// Manager, ManagerURL and ManagerIndexerID record the Jackett or
+	// Prowlarr instance an imported indexer came from, and that instance's
+	// id for it — what a sync matches on. Nil for indexers added by URL.
+	Manager          *string `pg:"manager"`
+	ManagerURL       *string `pg:"manager_url"`
+	ManagerIndexerID *string `pg:"manager_indexer_id"`
+
+	// Usenet marks a Newznab feed: its results are NZB releases rather
+	// than torrents. The feed format cannot tell — Prowlarr serves both
+	// kinds on the same path, and Jackett-style responses carry the same
+	// attributes — so the user says so when adding it. Usenet indexers stay
+	// out of every torrent search and feed only the Usenet half of Discover.
+	Usenet bool `pg:"usenet,notnull,default:false,use_zero"`
+
 	UserID uuid.UUID `pg:"user_id"`
 	User   *User     `pg:"rel:has-one,fk:user_id"`
 }
@@ -77,6 +98,17 @@ func (c *TorznabCaps) SupportsTVIMDB() bool {
 	return c != nil && containsParam(c.TVParams, "imdbid")
 }
 
+// SupportsTVTVDB reports whether t=tvsearch accepts a tvdbid parameter —
+// the id Sonarr-era indexers key series on.
+func (c *TorznabCaps) SupportsTVTVDB() bool {
+	return c != nil && containsParam(c.TVParams, "tvdbid")
+}
+
+// SupportsTVTMDB reports whether t=tvsearch accepts a tmdbid parameter.
+func (c *TorznabCaps) SupportsTVTMDB() bool {
+	return c != nil && containsParam(c.TVParams, "tmdbid")
+}
+
 // SupportsTVSearch reports whether t=tvsearch is available at all.
 func (c *TorznabCaps) SupportsTVSearch() bool {
 	return c != nil && len(c.TVParams) > 0
@@ -110,6 +142,19 @@ func (i *TorznabIndexer) ApplyCaps(name string, caps *TorznabCaps) {
 	i.CapsFetchedAt = &now
 }
 
+// SetManager records where an imported indexer came from.
+func (i *TorznabIndexer) SetManager(kind, managerURL, indexerID string) {
+	i.Manager = strPtr(kind)
+	i.ManagerURL = strPtr(managerURL)
+	i.ManagerIndexerID = strPtr(indexerID)
+}
+
+// IsManaged reports whether the indexer was imported from a manager and can
+// be synced with it.
+func (i *TorznabIndexer) IsManaged() bool {
+	return i.Manager != nil && i.ManagerURL != nil && i.ManagerIndexerID != nil
+}
+
 // GetApiKey returns the stored API key or "" when the indexer needs none.
 func (i *TorznabIndexer) GetApiKey() string {
 	if i.ApiKey == nil {
@@ -215,13 +260,24 @@ func hostOfURL(raw string) string {
 	return u.Hostname()
 }
 
-// GetUserTorznabIndexers returns enabled indexers for a user, highest
-// priority first — same ordering contract as GetUserStremioAddonUrls.
+// GetUserTorznabIndexers returns enabled torrent indexers for a user,
+// highest priority first — same ordering contract as
+// GetUserStremioAddonUrls.
 func GetUserTorznabIndexers(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]TorznabIndexer, error) {
+	return getUserEnabledIndexers(ctx, db, userID, false)
+}
+
+// GetUserUsenetIndexers returns enabled Usenet indexers for a user, highest
+// priority first.
+func GetUserUsenetIndexers(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]TorznabIndexer, error) {
+	return getUserEnabledIndexers(ctx, db, userID, true)
+}
+
+func getUserEnabledIndexers(ctx context.Context, db *pg.DB, userID uuid.UUID, usenet bool) ([]TorznabIndexer, error) {
 	var indexers []TorznabIndexer
 	err := db.Model(&indexers).
 		Context(ctx).
-		Where("user_id = ? AND enabled = ?", userID, true).
+		Where("user_id = ? AND enabled = ? AND usenet = ?", userID, true, usenet).
 		Order("priority DESC").
 		Select()
 	if err != nil {
@@ -244,6 +300,21 @@ func GetAllUserTorznabIndexers(ctx context.Context, db *pg.DB, userID uuid.UUID)
 	return indexers, nil
 }
 
+// GetManagedTorznabIndexers returns a user's indexers imported from a
+// Jackett or Prowlarr instance, enabled or not.
+func GetManagedTorznabIndexers(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]TorznabIndexer, error) {
+	var indexers []TorznabIndexer
+	err := db.Model(&indexers).
+		Context(ctx).
+		Where("user_id = ? AND manager_url IS NOT NULL AND manager_indexer_id IS NOT NULL", userID).
+		Order("priority DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return indexers, nil
+}
+
 // CountUserTorznabIndexers returns the number of indexers a user has.
 func CountUserTorznabIndexers(ctx context.Context, db *pg.DB, userID uuid.UUID) (int, error) {
 	return db.Model(&TorznabIndexer{}).
@@ -286,22 +357,29 @@ func GetTorznabIndexerByID(ctx context.Context, db *pg.DB, indexerID uuid.UUID)
 
 // CreateTorznabIndexer binds a new indexer to a user. New rows land at the
 // bottom of the priority order, matching CreateStremioAddonUrl.
-func CreateTorznabIndexer(ctx context.Context, db *pg.DB, userID uuid.UUID, url, apiKey, name string, caps *TorznabCaps) error {
-	count, err := CountUserTorznabIndexers(ctx, db, userID)
-	if err != nil {
-		return err
-	}
-
+func CreateTorznabIndexer(ctx context.Context, db *pg.DB, userID uuid.UUID, url, apiKey, name string, caps *TorznabCaps, usenet bool) error {
 	indexer := &TorznabIndexer{
-		Url:      url,
-		ApiKey:   strPtr(apiKey),
-		UserID:   userID,
-		Priority: int16(count + 1),
-		Enabled:  true,
+		Url:    url,
+		ApiKey: strPtr(apiKey),
+		UserID: userID,
+		Usenet: usenet,
 	}
 	if caps != nil || name != "" {
 		indexer.ApplyCaps(name, caps)
 	}
+	return InsertTorznabIndexer(ctx, db, indexer)
+}
+
+// InsertTorznabIndexer stores a prepared indexer — an import carries more
+// than CreateTorznabIndexer takes — at the bottom of its user's priority
+// order, enabled.
+func InsertTorznabIndexer(ctx context.Context, db *pg.DB, indexer *TorznabIndexer) error {
+	count, err := CountUserTorznabIndexers(ctx, db, indexer.UserID)
+	if err != nil {
+		return err
+	}
+	indexer.Priority = int16(count + 1)
+	indexer.Enabled = true
 
 	_, err = db.Model(indexer).
 		Context(ctx).
@@ -353,6 +431,30 @@ func SetTorznabIndexerTrackerName(ctx context.Context, db *pg.DB, indexerID uuid
 	return err
 }
 
+// DisableTorznabIndexer switches off an indexer owned by the given user
+// without touching the rest of the row.
+func DisableTorznabIndexer(ctx context.Context, db *pg.DB, indexerID, userID uuid.UUID) error {
+	_, err := db.Model(&TorznabIndexer{}).
+		Context(ctx).
+		Set("enabled = ?", false).
+		Where("torznab_indexer_id = ? AND user_id = ?", indexerID, userID).
+		Update()
+	return err
+}
+
+// MarkTorznabIndexerFeedRead records a successful read of an indexer's
+// latest-releases feed. coveredSince only moves forward when the read found
+// a gap — see release_subscription's feed mode for what it is compared to.
+func MarkTorznabIndexerFeedRead(ctx context.Context, db *pg.DB, indexerID uuid.UUID, readAt, coveredSince time.Time) error {
+	_, err := db.Model(&TorznabIndexer{}).
+		Context(ctx).
+		Set("feed_read_at = ?", readAt).
+		Set("feed_covered_since = ?", coveredSince).
+		Where("torznab_indexer_id = ?", indexerID).
+		Update()
+	return err
+}
+
 // DeleteUserTorznabIndexer deletes an indexer owned by a specific user.
 func DeleteUserTorznabIndexer(ctx context.Context, db *pg.DB, indexerID, userID uuid.UUID) error {
 	_, err := db.Model(&TorznabIndexer{}).
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// UsenetDownloadStatus is where a Usenet download stands
+type UsenetDownloadStatus string
+
+const (
+	UsenetDownloadStatusDownloading UsenetDownloadStatus = "downloading"
+	UsenetDownloadStatusReady       UsenetDownloadStatus = "ready"
+	UsenetDownloadStatusFailed      UsenetDownloadStatus = "failed"
+)
+
+// UsenetDownloadFile is a file of a finished Usenet download. ID is the
+// backend's own id for it. Self-hosted backends have no ids and list the
+// folder they saved the download to instead, with its Link under the files
+// URL.
+type UsenetDownloadFile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Link string `json:"link,omitempty"`
+}
+
+// UsenetDownload is a Usenet release the user sent to one of their
+// backends. The backend downloads it; the row follows the job until it is
+// ready or failed. Ready downloads are opened from /usenet and the library
+// filesystem (services/libfs).
+type UsenetDownload struct {
+	tableName          struct{}             `pg:"usenet_download"`
+	ID                 uuid.UUID            `pg:"usenet_download_id,pk,type:uuid,default:uuid_generate_v4()"`
+	UserID             uuid.UUID            `pg:"user_id,notnull"`
+	StreamingBackendID uuid.UUID            `pg:"streaming_backend_id,notnull"`
+	RemoteID           string               `pg:"remote_id,notnull"`
+	Name               string               `pg:"name,notnull"`
+	Size               int64                `pg:"size,notnull,use_zero"`
+	Status             UsenetDownloadStatus `pg:"status,notnull"`
+	Progress           float64              `pg:"progress,notnull,use_zero"`
+	Files              []UsenetDownloadFile `pg:"files,type:jsonb,notnull"`
+	Error              *string              `pg:"error"`
+	CreatedAt          time.Time            `pg:"created_at,default:now()"`
+	UpdatedAt          time.Time            `pg:"updated_at,default:now()"`
+	FinishedAt         *time.Time           `pg:"finished_at"`
+
+	StreamingBackend *StreamingBackend `pg:"rel:has-one,fk:streaming_backend_id"`
+}
+
+// Ready reports whether the files can be played
+func (d *UsenetDownload) Ready() bool {
+	return d.Status == UsenetDownloadStatusReady
+}
+
+// Failed reports whether the backend gave up on the download
+func (d *UsenetDownload) Failed() bool {
+	return d.Status == UsenetDownloadStatusFailed
+}
+
+// CreateUsenetDownload stores a download that was just sent to its backend
+func CreateUsenetDownload(ctx context.Context, db *pg.DB, d *UsenetDownload) error {
+	if d.Files == nil {
+		d.Files = []UsenetDownloadFile{}
+	}
+	_, err := db.Model(d).Context(ctx).Insert()
+	return err
+}
+
+// GetUserUsenetDownloads returns the user's Usenet downloads with their
+// backends, newest first
+func GetUserUsenetDownloads(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]*UsenetDownload, error) {
+	var downloads []*UsenetDownload
+	err := db.Model(&downloads).
+		Context(ctx).
+		Relation("StreamingBackend").
+		Where("usenet_download.user_id = ?", userID).
+		Order("usenet_download.created_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// GetUserReadyUsenetDownloads returns the user's finished Usenet
+// downloads with their backends, oldest first
+func GetUserReadyUsenetDownloads(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]*UsenetDownload, error) {
+	var downloads []*UsenetDownload
+	err := db.Model(&downloads).
+		Context(ctx).
+		Relation("StreamingBackend").
+		Where("usenet_download.user_id = ?", userID).
+		Where("usenet_download.status = ?", UsenetDownloadStatusReady).
+		Order("usenet_download.created_at ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// GetUserUsenetDownload returns one of the user's Usenet downloads with its
+// backend, or nil when the user has no such download
+func GetUserUsenetDownload(ctx context.Context, db *pg.DB, userID, id uuid.UUID) (*UsenetDownload, error) {
+	d := &UsenetDownload{}
+	err := db.Model(d).
+		Context(ctx).
+		Relation("StreamingBackend").
+		Where("usenet_download.usenet_download_id = ? AND usenet_download.user_id = ?", id, userID).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetPendingUsenetDownloads returns the downloads still in flight, oldest
+// first, with their backends
+func GetPendingUsenetDownloads(ctx context.Context, db *pg.DB, limit int) ([]*UsenetDownload, error) {
+	var downloads []*UsenetDownload
+	err := db.Model(&downloads).
+		Context(ctx).
+		Relation("StreamingBackend").
+		Where("usenet_download.finished_at IS NULL").
+		Order("usenet_download.created_at ASC").
+		Limit(limit).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// UpdateUsenetDownload stores what the poller learned about the download
+func UpdateUsenetDownload(ctx context.Context, db *pg.DB, d *UsenetDownload) error {
+	if d.Files == nil {
+		d.Files = []UsenetDownloadFile{}
+	}
+	_, err := db.Model(d).
+		Context(ctx).
+		Column("name", "size", "status", "progress", "files", "error", "finished_at").
+		WherePK().
+		Update()
+	return err
+}
+
+// DeleteUserUsenetDownload removes one of the user's Usenet downloads
+func DeleteUserUsenetDownload(ctx context.Context, db *pg.DB, userID, id uuid.UUID) error {
+	_, err := db.Model(&UsenetDownload{}).
+		Context(ctx).
+		Where("usenet_download_id = ? AND user_id = ?", id, userID).
+		Delete()
+	return err
+}
@@ -27,11 +27,33 @@ type UserSettings struct {
 	// URL prefix and the lang cookie that carry the language everywhere else
 	// are both out of reach. Nil means "never observed" — a different thing
 	// from "chose English", so senders fall back rather than assume.
-	Lang      *string   `pg:"lang"`
-	CreatedAt time.Time `pg:"created_at,notnull"`
-	UpdatedAt time.Time `pg:"updated_at,notnull"`
+	Lang *string `pg:"lang"`
+	// SubscriptionDigest decides how release-subscription mail is batched:
+	// one letter per subscription as finds come in, or one combined letter
+	// a day or a week. Nil is immediate — the behaviour every account had
+	// before the setting existed.
+	SubscriptionDigest *string `pg:"subscription_digest"`
+	// QuietHoursStart and QuietHoursEnd are hours of the day in TimeZone
+	// during which no subscription mail is sent; what comes in meanwhile
+	// waits for the window to close. Both nil means no quiet hours.
+	QuietHoursStart *int16 `pg:"quiet_hours_start"`
+	QuietHoursEnd   *int16 `pg:"quiet_hours_end"`
+	// TimeZone is an IANA name. Nil reads as UTC.
+	TimeZone *string `pg:"time_zone"`
+	// DigestSentAt is when the last digest went out; the next one is due
+	// once the account's local day (or week) has moved past it.
+	DigestSentAt *time.Time `pg:"digest_sent_at"`
+	CreatedAt    time.Time  `pg:"created_at,notnull"`
+	UpdatedAt    time.Time  `pg:"updated_at,notnull"`
 }
 
+// Subscription digest modes.
+const (
+	SubscriptionDigestImmediate = "immediate"
+	SubscriptionDigestDaily     = "daily"
+	SubscriptionDigestWeekly    = "weekly"
+)
+
 // GetUserSettings returns the row for a user, or nil when none exists.
 // Callers should treat nil as "user is on defaults" (ShowAdult=false,
 // future fields likewise at zero). Anonymous flows that have no User
@@ -107,3 +129,132 @@ func (s *UserSettings) GetLang() string {
 	}
 	return *s.Lang
 }
+
+// SetUserSettingsMail stores how release-subscription mail is delivered.
+// Column-scoped for the same reason as SetUserSettingsLang: the row carries
+// other preferences, and saving this form must not reset them. start and
+// end are written together — a window with one edge is no window — and
+// nil for both clears it.
+func SetUserSettingsMail(ctx context.Context, db *pg.DB, userID uuid.UUID, digest string, start, end *int16, timeZone string) error {
+	if userID == uuid.Nil {
+		return errors.New("user_settings: empty user_id")
+	}
+	now := time.Now()
+	us := &UserSettings{
+		UserID:             userID,
+		SubscriptionDigest: &digest,
+		QuietHoursStart:    start,
+		QuietHoursEnd:      end,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if timeZone != "" {
+		us.TimeZone = &timeZone
+	}
+	_, err := db.Model(us).
+		Context(ctx).
+		OnConflict("(user_id) DO UPDATE").
+		Set("subscription_digest = EXCLUDED.subscription_digest").
+		Set("quiet_hours_start = EXCLUDED.quiet_hours_start").
+		Set("quiet_hours_end = EXCLUDED.quiet_hours_end").
+		Set("time_zone = EXCLUDED.time_zone").
+		Set("updated_at = now()").
+		Insert()
+	return err
+}
+
+// MarkUserSettingsDigestSent stamps the digest that just went out. Called
+// only after the mailer reports success, like the per-subscription stamp.
+func MarkUserSettingsDigestSent(ctx context.Context, db *pg.DB, userID uuid.UUID, at time.Time) error {
+	_, err := db.Model((*UserSettings)(nil)).
+		Context(ctx).
+		Set("digest_sent_at = ?", at).
+		Where("user_id = ?", userID).
+		Update()
+	return err
+}
+
+// GetSubscriptionDigest returns the digest mode, immediate when unset or
+// unrecognised.
+func (s *UserSettings) GetSubscriptionDigest() string {
+	if s == nil || s.SubscriptionDigest == nil {
+		return SubscriptionDigestImmediate
+	}
+	switch *s.SubscriptionDigest {
+	case SubscriptionDigestDaily, SubscriptionDigestWeekly:
+		return *s.SubscriptionDigest
+	}
+	return SubscriptionDigestImmediate
+}
+
+// GetTimeZone returns the stored IANA name, or "" when none is set.
+func (s *UserSettings) GetTimeZone() string {
+	if s == nil || s.TimeZone == nil {
+		return ""
+	}
+	return *s.TimeZone
+}
+
+// Location resolves the account's time zone. A name the server's zone
+// database does not know reads as UTC rather than failing: quiet hours off
+// by a few hours beat a letter that can never be scheduled.
+func (s *UserSettings) Location() *time.Location {
+	if name := s.GetTimeZone(); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// HasQuietHours reports whether a quiet window is set. Equal edges are
+// treated as none: "from 8 to 8" is either nothing or the whole day, and
+// the whole day would mean never sending at all.
+func (s *UserSettings) HasQuietHours() bool {
+	return s != nil && s.QuietHoursStart != nil && s.QuietHoursEnd != nil &&
+		*s.QuietHoursStart != *s.QuietHoursEnd
+}
+
+// InQuietHours reports whether t falls inside the quiet window in the
+// account's time zone. The window is [start, end) and may wrap midnight.
+func (s *UserSettings) InQuietHours(t time.Time) bool {
+	if !s.HasQuietHours() {
+		return false
+	}
+	h := int16(t.In(s.Location()).Hour())
+	start, end := *s.QuietHoursStart, *s.QuietHoursEnd
+	if start < end {
+		return h >= start && h < end
+	}
+	return h >= start || h < end
+}
+
+// QuietUntil returns when the quiet window that t falls in closes: the next
+// occurrence of the end hour in the account's time zone.
+func (s *UserSettings) QuietUntil(t time.Time) time.Time {
+	if !s.InQuietHours(t) {
+		return t
+	}
+	local := t.In(s.Location())
+	end := time.Date(local.Year(), local.Month(), local.Day(), int(*s.QuietHoursEnd), 0, 0, 0, local.Location())
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// GetQuietHoursStart and GetQuietHoursEnd return the window's edges as
+// plain hours for the profile form, -1 when no window is set.
+func (s *UserSettings) GetQuietHoursStart() int {
+	if !s.HasQuietHours() {
+		return -1
+	}
+	return int(*s.QuietHoursStart)
+}
+
+func (s *UserSettings) GetQuietHoursEnd() int {
+	if !s.HasQuietHours() {
+		return -1
+	}
+	return int(*s.QuietHoursEnd)
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func hourPtrT(h int16) *int16 { return &h }
+
+// TestInQuietHours: the window is read in the account's own zone, and a
+// window that wraps midnight — the usual night-time one — covers both ends.
+func TestInQuietHours(t *testing.T) {
+	night := &UserSettings{
+		QuietHoursStart: hourPtrT(22),
+		QuietHoursEnd:   hourPtrT(8),
+		TimeZone:        strPtrT("Europe/Moscow"), // UTC+3, no DST
+	}
+	for _, tt := range []struct {
+		utc  string
+		want bool
+	}{
+		{"2026-03-01T18:59:00Z", false}, // 21:59 local
+		{"2026-03-01T19:00:00Z", true},  // 22:00 local
+		{"2026-03-01T23:30:00Z", true},  // 02:30 local
+		{"2026-03-02T04:59:00Z", true},  // 07:59 local
+		{"2026-03-02T05:00:00Z", false}, // 08:00 local
+	} {
+		at, _ := time.Parse(time.RFC3339, tt.utc)
+		if got := night.InQuietHours(at); got != tt.want {
+			t.Errorf("InQuietHours(%s) = %v, want %v", tt.utc, got, tt.want)
+		}
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2026-03-01T20:00:00Z") // 23:00 local
+	if got, want := night.QuietUntil(at), "2026-03-02T05:00:00Z"; got.UTC().Format(time.RFC3339) != want {
+		t.Errorf("QuietUntil(23:00 local) = %s, want %s", got.UTC().Format(time.RFC3339), want)
+	}
+
+	day := &UserSettings{QuietHoursStart: hourPtrT(9), QuietHoursEnd: hourPtrT(17)}
+	if !day.InQuietHours(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Error("noon is outside a 9-17 window with no time zone, want UTC")
+	}
+
+	// Equal edges are no window at all, not a window of the whole day.
+	same := &UserSettings{QuietHoursStart: hourPtrT(8), QuietHoursEnd: hourPtrT(8)}
+	if same.InQuietHours(time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Error("an 8-8 window held mail")
+	}
+
+	var none *UserSettings
+	if none.InQuietHours(time.Now()) || none.GetSubscriptionDigest() != SubscriptionDigestImmediate {
+		t.Error("an account with no settings row is not on the defaults")
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// MaxUserSSHKeys is how many public keys one account may upload.
+const MaxUserSSHKeys = 10
+
+// ErrSSHKeyTaken is returned when an uploaded key is already registered, to
+// this account or another one: a key logs in exactly one account.
+var ErrSSHKeyTaken = errors.New("ssh key is already registered")
+
+// UserSSHKey is a public key the user uploaded to log in to SFTP with.
+// PublicKey is the authorized_keys line as uploaded; Fingerprint is its
+// SHA256 fingerprint, which logins are looked up by.
+type UserSSHKey struct {
+	tableName struct{} `pg:"user_ssh_key"`
+
+	UserSSHKeyID uuid.UUID  `pg:"user_ssh_key_id,pk,type:uuid,default:uuid_generate_v4()"`
+	UserID       uuid.UUID  `pg:"user_id"`
+	Name         string     `pg:"name"`
+	PublicKey    string     `pg:"public_key"`
+	Fingerprint  string     `pg:"fingerprint"`
+	LastUsedAt   *time.Time `pg:"last_used_at"`
+	CreatedAt    time.Time  `pg:"created_at"`
+	UpdatedAt    time.Time  `pg:"updated_at"`
+
+	User *User `pg:"rel:has-one,fk:user_id"`
+}
+
+// ListUserSSHKeys returns a user's keys, newest first.
+func ListUserSSHKeys(ctx context.Context, db *pg.DB, userID uuid.UUID) ([]UserSSHKey, error) {
+	var keys []UserSSHKey
+	err := db.Model(&keys).
+		Context(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list ssh keys")
+	}
+	return keys, nil
+}
+
+// CountUserSSHKeys returns the number of keys a user has uploaded.
+func CountUserSSHKeys(ctx context.Context, db *pg.DB, userID uuid.UUID) (int, error) {
+	return db.Model((*UserSSHKey)(nil)).
+		Context(ctx).
+		Where("user_id = ?", userID).
+		Count()
+}
+
+// CreateUserSSHKey stores an uploaded key, or returns ErrSSHKeyTaken.
+func CreateUserSSHKey(ctx context.Context, db *pg.DB, k *UserSSHKey) error {
+	_, err := db.Model(k).
+		Context(ctx).
+		Returning("*").
+		Insert()
+	if pgErr, ok := err.(pg.Error); ok && pgErr.IntegrityViolation() {
+		return ErrSSHKeyTaken
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create ssh key")
+	}
+	return nil
+}
+
+// GetUserSSHKeyByFingerprint returns the key with its owner, or nil when no
+// account has uploaded it.
+func GetUserSSHKeyByFingerprint(ctx context.Context, db *pg.DB, fingerprint string) (*UserSSHKey, error) {
+	k := new(UserSSHKey)
+	err := db.Model(k).
+		Context(ctx).
+		Where("user_ssh_key.fingerprint = ?", fingerprint).
+		Relation("User").
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ssh key")
+	}
+	return k, nil
+}
+
+// TouchUserSSHKey records a login with the key, so the profile can show which
+// keys are still in use.
+func TouchUserSSHKey(ctx context.Context, db *pg.DB, id uuid.UUID) error {
+	_, err := db.Model((*UserSSHKey)(nil)).
+		Context(ctx).
+		Set("last_used_at = now()").
+		Where("user_ssh_key_id = ?", id).
+		Update()
+	if err != nil {
+		return errors.Wrap(err, "failed to touch ssh key")
+	}
+	return nil
+}
+
+// DeleteUserSSHKey removes a key owned by the user.
+func DeleteUserSSHKey(ctx context.Context, db *pg.DB, userID uuid.UUID, id uuid.UUID) error {
+	_, err := db.Model((*UserSSHKey)(nil)).
+		Context(ctx).
+		Where("user_ssh_key_id = ? AND user_id = ?", id, userID).
+		Delete()
+	if err != nil {
+		return errors.Wrap(err, "failed to delete ssh key")
+	}
+	return nil
+}
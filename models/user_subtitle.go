@@ -66,6 +66,22 @@ func ListUserSubtitlesForFile(ctx context.Context, db *pg.DB, userID uuid.UUID,
 	return list, nil
 }
 
+// ListUserSubtitlesForResource returns every subtitle a user has uploaded for
+// any file of one torrent, oldest first. The library filesystem lists a whole
+// directory's sidecars from it in one query instead of one per video.
+func ListUserSubtitlesForResource(ctx context.Context, db *pg.DB, userID uuid.UUID, resourceID string) ([]*UserSubtitle, error) {
+	var list []*UserSubtitle
+	err := db.Model(&list).
+		Context(ctx).
+		Where("user_id = ? AND resource_id = ?", userID, resourceID).
+		OrderExpr("created_at ASC").
+		Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list user subtitles for resource")
+	}
+	return list, nil
+}
+
 // GetUserSubtitle loads a single row by id scoped to a user (used by the
 // delete handler to both load the hash and enforce ownership).
 func GetUserSubtitle(ctx context.Context, db *pg.DB, userID, id uuid.UUID) (*UserSubtitle, error) {
@@ -126,11 +142,13 @@ func ListUserSubtitleHashesForResource(ctx context.Context, db *pg.DB, userID uu
 }
 
 // CountUserSubtitlesForFile returns how many subtitles a user has already
-// uploaded for one (resource_id, path). Enforces the per-file upload limit.
-func CountUserSubtitlesForFile(ctx context.Context, db *pg.DB, userID uuid.UUID, resourceID, path string) (int, error) {
+// uploaded for one (resource_id, path), leaving out except (uuid.Nil for
+// none): a subtitle about to be replaced. Enforces the per-file upload limit.
+func CountUserSubtitlesForFile(ctx context.Context, db *pg.DB, userID uuid.UUID, resourceID, path string, except uuid.UUID) (int, error) {
 	count, err := db.Model((*UserSubtitle)(nil)).
 		Context(ctx).
 		Where("user_id = ? AND resource_id = ? AND path = ?", userID, resourceID, path).
+		Where("user_subtitle_id <> ?", except).
 		Count()
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to count user subtitles")
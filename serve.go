@@ -7,6 +7,8 @@ import (
 	wa "github.com/webtor-io/web-ui/handlers/action"
 	japi "github.com/webtor-io/web-ui/handlers/api"
 	wau "github.com/webtor-io/web-ui/handlers/auth"
+	"github.com/webtor-io/web-ui/handlers/browse"
+	"github.com/webtor-io/web-ui/handlers/calendar"
 	wdev "github.com/webtor-io/web-ui/handlers/device"
 	"github.com/webtor-io/web-ui/handlers/discover"
 	"github.com/webtor-io/web-ui/handlers/discover_ai"
@@ -30,6 +32,7 @@ import (
 	wr "github.com/webtor-io/web-ui/handlers/resource"
 	s3 "github.com/webtor-io/web-ui/handlers/s3"
 	sess "github.com/webtor-io/web-ui/handlers/session"
+	sftph "github.com/webtor-io/web-ui/handlers/sftp"
 	"github.com/webtor-io/web-ui/handlers/sitemap"
 	"github.com/webtor-io/web-ui/handlers/speedtest"
 	sta "github.com/webtor-io/web-ui/handlers/static"
@@ -50,10 +53,13 @@ import (
 	at "github.com/webtor-io/web-ui/services/access_token"
 	ac "github.com/webtor-io/web-ui/services/anthropic_client"
 	ci "github.com/webtor-io/web-ui/services/cache_index"
+	scal "github.com/webtor-io/web-ui/services/calendar"
 	"github.com/webtor-io/web-ui/services/common"
+	cp "github.com/webtor-io/web-ui/services/content_proxy"
 	"github.com/webtor-io/web-ui/services/geoip"
 	si18n "github.com/webtor-io/web-ui/services/i18n"
 	"github.com/webtor-io/web-ui/services/libapi"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	lr "github.com/webtor-io/web-ui/services/link_resolver"
 	"github.com/webtor-io/web-ui/services/notification"
 	"github.com/webtor-io/web-ui/services/onboarding"
@@ -62,6 +68,7 @@ import (
 	rss "github.com/webtor-io/web-ui/services/release_subscription"
 	rum "github.com/webtor-io/web-ui/services/request_url_mapper"
 	s3svc "github.com/webtor-io/web-ui/services/s3"
+	"github.com/webtor-io/web-ui/services/sftp"
 	thumb "github.com/webtor-io/web-ui/services/thumbnail"
 	"github.com/webtor-io/web-ui/services/turnstile"
 	"github.com/webtor-io/web-ui/services/umami"
@@ -76,6 +83,7 @@ import (
 	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/embed"
+	enr "github.com/webtor-io/web-ui/services/enrich"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -87,6 +95,7 @@ import (
 
 	stremios "github.com/webtor-io/web-ui/services/stremio"
 	"github.com/webtor-io/web-ui/services/torznab"
+	"github.com/webtor-io/web-ui/services/usenet"
 )
 
 func makeServeCMD() cli.Command {
@@ -136,6 +145,8 @@ func configureServe(c *cli.Command) {
 	c.Flags = usv.RegisterFlags(c.Flags)
 	c.Flags = thumb.RegisterFlags(c.Flags)
 	c.Flags = donate.RegisterFlags(c.Flags)
+	c.Flags = sftp.RegisterFlags(c.Flags)
+	c.Flags = cp.RegisterFlags(c.Flags)
 }
 
 func serve(c *cli.Context) error {
@@ -272,6 +283,14 @@ func serve(c *cli.Context) error {
 		defer nats.Close()
 	}
 
+	// Setting Library Events (listing caches drop on every replica when a
+	// library changes; without NATS only on the one that changed it)
+	libEvents := le.New(nats)
+	if nats != nil {
+		servers = append(servers, libEvents)
+		defer libEvents.Close()
+	}
+
 	// Setting UserClaims
 	uc := claims.New(c, cpCl, pg)
 	if uc != nil {
@@ -356,7 +375,7 @@ func serve(c *cli.Context) error {
 	// Setting JobQueues
 	queues := job.NewQueues(job.NewStorage(redis, gin.Mode()))
 
-	jobs := jj.New(c, queues, tm, sapi, en, i18nSvc, userSubtitleSvc, thumbnailSvc, uc)
+	jobs := jj.New(c, queues, tm, sapi, en, i18nSvc, userSubtitleSvc, thumbnailSvc, uc, libEvents)
 
 	// Setting JobHandler
 	wj.RegisterHandler(r, queues)
@@ -400,8 +419,15 @@ func serve(c *cli.Context) error {
 		vh.RegisterHandler(r, v, tm, pg)
 	}
 
+	// Setting CacheIndex
+	cacheIndex := ci.New(c, pg)
+
+	// Setting LinkResolver (the resource page offers sending the torrent to
+	// the user's debrid accounts)
+	linkResolver := lr.New(cl, pg, sapi, cacheIndex)
+
 	// Setting ResourceHandler
-	wr.RegisterHandler(c, r, tm, sapi, jobs, pg, v, en)
+	wr.RegisterHandler(c, r, tm, sapi, jobs, pg, v, en, linkResolver)
 
 	// Setting IndexHandler
 	wi.RegisterHandler(r, tm, pg)
@@ -430,7 +456,7 @@ func serve(c *cli.Context) error {
 	release_subscription.RegisterHandler(r, tm, pg, releaseSubSvc)
 
 	// Setting ProfileHandler
-	p.RegisterHandler(c, r, tm, ats, ual, pg, uc, v, userSettingsSvc, payClient, releaseSubSvc)
+	p.RegisterHandler(c, r, tm, ats, ual, pg, uc, v, userSettingsSvc, payClient, releaseSubSvc, linkResolver)
 
 	// Setting device authorization confirmation page (the human half of the
 	// device flow; the API half lives in handlers/api)
@@ -466,12 +492,29 @@ func serve(c *cli.Context) error {
 	// Setting Torznab
 	torznabCl := torznab.New(c)
 	torznabTitles := torznab.NewCinemetaTitles(torznabCl.HTTP(), c.String(torznab.UserAgentFlag))
+	torznabResolver := torznab.TitleResolver(torznabTitles)
+	if aliases := enr.NewTorznabAliases(en, torznab.AliasLangs(c)); aliases != nil {
+		torznabResolver = torznab.WithAliases(torznabTitles, aliases)
+	}
 	torznabValidator := torznab.NewValidator(torznabCl)
 
-	sb := stremios.NewBuilder(c, pg, stremioAddonCl, sapi, requestURLMapper, torznabCl, torznabTitles)
+	sb := stremios.NewBuilder(c, pg, stremioAddonCl, sapi, requestURLMapper, torznabCl, torznabResolver)
+
+	// Setting self-hosted backends (SABnzbd) on the LinkResolver. They live
+	// at addresses users type in, so they go through the indexers' egress
+	// policy
+	linkResolver.RegisterSelfHosted(torznabCl.HTTP())
+
+	// Setting Usenet (releases from Newznab indexers sent to TorBox or
+	// SABnzbd)
+	usenetSealer, err := usenet.NewSealer(c.String(common.SessionSecretFlag))
+	if err != nil {
+		return err
+	}
+	usenetSvc := usenet.New(usenet.NewStore(pg), linkResolver, torznabCl, usenetSealer)
 
 	// Setting Discover
-	discover.RegisterHandler(r, tm, pg, en, sb)
+	discover.RegisterHandler(r, tm, pg, en, sb, linkResolver, usenetSvc)
 
 	// Setting AI Recommendations (Discover)
 	//
@@ -489,22 +532,16 @@ func serve(c *cli.Context) error {
 	discover_watchlist.RegisterHandler(r, pg, en)
 
 	// Setting Library
-	library.RegisterHandler(c, r, tm, sapi, pg, jobs, cl, s3Cl, en, thumbnailSvc)
+	library.RegisterHandler(c, r, tm, sapi, pg, jobs, cl, s3Cl, en, thumbnailSvc, libEvents)
 
 	// Setting UserSubtitle handler. When AWS_USER_SUBTITLE_BUCKET is not
 	// set the service is nil; RegisterHandler skips its routes and the UI
 	// hides the feature.
 	ush.RegisterHandler(r, tm, userSubtitleSvc, sapi)
 
-	// Setting CacheIndex
-	cacheIndex := ci.New(c, pg)
-
 	// Setting AddonValidator with custom client and cli context
 	av := stremios.NewAddonValidator(c, stremioAddonCl)
 
-	// Setting LinkResolver
-	linkResolver := lr.New(cl, pg, sapi, cacheIndex)
-
 	// Setting Stremio
 	stremio.RegisterHandler(c, r, ats, sb, pg, linkResolver)
 
@@ -515,7 +552,7 @@ func serve(c *cli.Context) error {
 	}
 
 	// Setting Torznab Indexers
-	err = torznab_indexer.RegisterHandler(c, torznabValidator, r, pg)
+	err = torznab_indexer.RegisterHandler(c, torznabCl, torznabValidator, r, tm, pg)
 	if err != nil {
 		return err
 	}
@@ -524,16 +561,41 @@ func serve(c *cli.Context) error {
 	settings.RegisterHandler(r, ats, pg)
 
 	// Setting Streaming Backends
-	backends.RegisterHandler(r, ats, pg, linkResolver)
+	backends.RegisterHandler(r, tm, ats, pg, linkResolver, sapi, jobs, libEvents, usenetSvc)
+
+	// Setting Calendar (iCal feed of upcoming episodes, token URL like
+	// Stremio's)
+	err = calendar.RegisterHandler(c, r, ats, scal.New(pg, en), userSettingsSvc, si18n.NewHelper(i18nSvc))
+	if err != nil {
+		return err
+	}
+
+	// Setting Content Proxy (WebDAV and S3 stream through it for clients
+	// that cannot follow a redirect to the content)
+	contentProxy := cp.New(c)
 
 	// Setting WebDAV
-	webdav.RegisterHandler(c, r, pg, ats, sapi, jobs)
+	webdav.RegisterHandler(c, r, pg, redis, ats, sapi, jobs, userSubtitleSvc, libEvents, linkResolver, contentProxy)
 
 	// Setting S3 (same library tree as WebDAV, different protocol)
-	s3.RegisterHandler(c, r, pg, ats, sapi, jobs)
+	s3.RegisterHandler(c, r, pg, ats, sapi, jobs, userSubtitleSvc, libEvents, linkResolver, contentProxy)
+
+	// Setting browser index (same tree again, read-only HTML/JSON at a token
+	// URL)
+	browse.RegisterHandler(c, r, pg, ats, sapi, jobs, userSubtitleSvc, libEvents, linkResolver)
+
+	// Setting SFTP (same tree again, on its own port)
+	sftpSrv, err := sftph.RegisterHandler(c, r, pg, ats, sapi, jobs, userSubtitleSvc, uc, libEvents, linkResolver)
+	if err != nil {
+		return err
+	}
+	if sftpSrv != nil {
+		servers = append(servers, sftpSrv)
+		defer sftpSrv.Close()
+	}
 
 	// Setting JSON API (same library tree again, plus vault and profile)
-	japi.RegisterHandler(c, r, pg, ats, sapi, jobs, v, userSettingsSvc)
+	japi.RegisterHandler(c, r, pg, ats, sapi, jobs, v, userSettingsSvc, libEvents)
 
 	// Setting Tests
 	tests.RegisterHandler(r, tm)
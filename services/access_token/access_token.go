@@ -52,6 +52,20 @@ func (s *AccessToken) Regenerate(c *gin.Context, name string, scope []string) (*
 	return models.RegenerateAccessToken(c.Request.Context(), db, u.ID, name, scope)
 }
 
+// SetProxy switches proxy mode (see models.AccessToken.Proxy) for the current
+// user's token with this name.
+func (s *AccessToken) SetProxy(c *gin.Context, name string, proxy bool) error {
+	u := auth.GetUserFromContext(c)
+	if !u.HasAuth() {
+		return fmt.Errorf("no auth")
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("database not initialized")
+	}
+	return models.SetAccessTokenProxy(c.Request.Context(), db, u.ID, name, proxy)
+}
+
 func (s *AccessToken) GetTokenByName(c *gin.Context, name string) (*models.AccessToken, error) {
 	db := s.pg.Get()
 	if db == nil {
@@ -66,6 +80,15 @@ func (s *AccessToken) GetTokenByName(c *gin.Context, name string) (*models.Acces
 
 type TokenScope struct{}
 
+// TokenProxy holds the request token's proxy setting.
+type TokenProxy struct{}
+
+// ProxyFromContext reports whether the request's token asked for proxy mode.
+func ProxyFromContext(ctx context.Context) bool {
+	p, _ := ctx.Value(TokenProxy{}).(bool)
+	return p
+}
+
 func (s *AccessToken) RegisterHandler(r *gin.Engine) {
 	prefix := fmt.Sprintf("/%s/", common2.AccessTokenParamName)
 	r.Match(common.AnyMethods, prefix+"*rest", func(c *gin.Context) {
@@ -99,6 +122,7 @@ func (s *AccessToken) RegisterHandler(r *gin.Engine) {
 		if at != nil {
 			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), auth.UserContext{}, at.User))
 			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), TokenScope{}, at.Scope))
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), TokenProxy{}, at.Proxy))
 		}
 		c.Next()
 	})
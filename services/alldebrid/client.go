@@ -0,0 +1,225 @@
+package alldebrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Agent identifies us to AllDebrid; every request has to carry one.
+const Agent = "webtor"
+
+// APIError is an error reported by the AllDebrid API
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (code %d): %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// InvalidCredentials reports whether the API key was rejected. All of
+// AllDebrid's key problems — missing, bad, blocked, banned — are AUTH_ codes.
+func (e *APIError) InvalidCredentials() bool {
+	return e.StatusCode == http.StatusUnauthorized ||
+		e.StatusCode == http.StatusForbidden ||
+		strings.HasPrefix(e.Code, "AUTH_")
+}
+
+// RateLimited reports whether the request was throttled
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// Client is an AllDebrid API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// New creates a new AllDebrid API client
+func New(httpClient *http.Client, baseURL, apiKey string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+	}
+}
+
+// GetUser retrieves the current user's information
+func (s *Client) GetUser(ctx context.Context) (*User, error) {
+	var data UserData
+	if err := s.get(ctx, "/v4/user", nil, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to get user")
+	}
+	return &data.User, nil
+}
+
+// UploadMagnet adds a magnet to the user's list. AllDebrid answers with
+// Ready set right away when the content is already cached.
+func (s *Client) UploadMagnet(ctx context.Context, magnet string) (*UploadedMagnet, error) {
+	params := url.Values{}
+	params.Add("magnets[]", magnet)
+	var data UploadData
+	if err := s.post(ctx, "/v4/magnet/upload", params, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to upload magnet")
+	}
+	if len(data.Magnets) == 0 {
+		return nil, errors.New("no magnet in upload response")
+	}
+	m := &data.Magnets[0]
+	if m.Error != nil {
+		return nil, &APIError{StatusCode: http.StatusOK, Code: m.Error.Code, Message: m.Error.Message}
+	}
+	return m, nil
+}
+
+// ListMagnets retrieves the user's magnets
+func (s *Client) ListMagnets(ctx context.Context) ([]Magnet, error) {
+	var data StatusData
+	if err := s.post(ctx, "/v4.1/magnet/status", url.Values{}, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to list magnets")
+	}
+	return data.Magnets, nil
+}
+
+// GetMagnetFiles retrieves the files of a ready magnet, flattened in tree
+// order.
+func (s *Client) GetMagnetFiles(ctx context.Context, id int64) ([]File, error) {
+	params := url.Values{}
+	params.Add("id[]", strconv.FormatInt(id, 10))
+	var data FilesData
+	if err := s.post(ctx, "/v4/magnet/files", params, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to get magnet files")
+	}
+	if len(data.Magnets) == 0 {
+		return nil, errors.New("no magnet in files response")
+	}
+	m := data.Magnets[0]
+	if m.Error != nil {
+		return nil, &APIError{StatusCode: http.StatusOK, Code: m.Error.Code, Message: m.Error.Message}
+	}
+	return Flatten(m.Files), nil
+}
+
+// UnlockLink turns a magnet file link into a direct download link
+func (s *Client) UnlockLink(ctx context.Context, link string) (*UnlockedLink, error) {
+	params := url.Values{}
+	params.Set("link", link)
+	var data UnlockedLink
+	if err := s.get(ctx, "/v4/link/unlock", params, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to unlock link")
+	}
+	return &data, nil
+}
+
+// DeleteMagnet removes a magnet from the user's list
+func (s *Client) DeleteMagnet(ctx context.Context, id int64) error {
+	params := url.Values{}
+	params.Set("id", strconv.FormatInt(id, 10))
+	if err := s.get(ctx, "/v4/magnet/delete", params, nil); err != nil {
+		return errors.Wrap(err, "failed to delete magnet")
+	}
+	return nil
+}
+
+// Flatten walks a magnet's file tree depth-first and returns its files with
+// their full paths, in the order the tree lists them.
+func Flatten(nodes []FileNode) []File {
+	var files []File
+	var walk func(prefix string, nodes []FileNode)
+	walk = func(prefix string, nodes []FileNode) {
+		for _, n := range nodes {
+			p := n.Name
+			if prefix != "" {
+				p = prefix + "/" + n.Name
+			}
+			if n.Entries != nil {
+				walk(p, n.Entries)
+				continue
+			}
+			files = append(files, File{Path: p, Size: n.Size, Link: n.Link})
+		}
+	}
+	walk("", nodes)
+	return files
+}
+
+// get performs a GET request
+func (s *Client) get(ctx context.Context, path string, params url.Values, data any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("agent", Agent)
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return s.doRequest(req, data)
+}
+
+// post performs a POST request with a form body
+func (s *Client) post(ctx context.Context, path string, params url.Values, data any) error {
+	q := url.Values{}
+	q.Set("agent", Agent)
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+path+"?"+q.Encode(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.doRequest(req, data)
+}
+
+// doRequest executes an HTTP request and decodes the data of the envelope
+// into data. AllDebrid reports errors in the envelope, with or without a
+// matching HTTP status, so both are checked.
+func (s *Client) doRequest(req *http.Request, data any) error {
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var r response[json.RawMessage]
+	if err := json.Unmarshal(body, &r); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		}
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if r.Status != "success" {
+		e := &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		if r.Error != nil {
+			e.Code, e.Message = r.Error.Code, r.Error.Message
+		}
+		return e
+	}
+	if data == nil || len(r.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Data, data); err != nil {
+		return fmt.Errorf("failed to parse response data: %w", err)
+	}
+	return nil
+}
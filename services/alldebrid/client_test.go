@@ -0,0 +1,100 @@
+package alldebrid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testKey = "good-key"
+
+func mockAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4/user", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","data":{"user":{"username":"alice","isPremium":true}}}`))
+	})
+	mux.HandleFunc("/v4/magnet/upload", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("magnets[]") == "" {
+			t.Error("upload without a magnet")
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"magnets":[{"hash":"abc","id":42,"ready":true}]}}`))
+	})
+	mux.HandleFunc("/v4/magnet/files", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("id[]") != "42" {
+			t.Errorf("files for id %q", r.PostFormValue("id[]"))
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"magnets":[{"id":"42","files":[
+			{"n":"Show","e":[
+				{"n":"S01E01.mkv","s":100,"l":"https://alldebrid.com/f/1"},
+				{"n":"Extras","e":[{"n":"sample.mkv","s":5,"l":"https://alldebrid.com/f/2"}]},
+				{"n":"S01E02.mkv","s":110,"l":"https://alldebrid.com/f/3"}
+			]}
+		]}]}}`))
+	})
+	mux.HandleFunc("/v4/link/unlock", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"status":"error","error":{"code":"TOO_MANY_REQUESTS","message":"slow down"}}`))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("agent") != Agent {
+			t.Errorf("%s without agent", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer "+testKey {
+			_, _ = w.Write([]byte(`{"status":"error","error":{"code":"AUTH_BAD_APIKEY","message":"The auth apikey is invalid"}}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetUser(t *testing.T) {
+	srv := mockAPI(t)
+	u, err := New(srv.Client(), srv.URL, testKey).GetUser(context.Background())
+	if err != nil || u.Username != "alice" || !u.IsPremium {
+		t.Fatalf("user %+v: %v", u, err)
+	}
+	_, err = New(srv.Client(), srv.URL, "bad").GetUser(context.Background())
+	var ae *APIError
+	if !errors.As(err, &ae) || !ae.InvalidCredentials() || ae.RateLimited() {
+		t.Errorf("bad key: %v", err)
+	}
+}
+
+func TestMagnetFiles(t *testing.T) {
+	srv := mockAPI(t)
+	cl := New(srv.Client(), srv.URL+"/", testKey)
+	m, err := cl.UploadMagnet(context.Background(), "magnet:?xt=urn:btih:abc")
+	if err != nil || !m.Ready || m.ID != 42 {
+		t.Fatalf("upload %+v: %v", m, err)
+	}
+	files, err := cl.GetMagnetFiles(context.Background(), m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Show/S01E01.mkv", "Show/Extras/sample.mkv", "Show/S01E02.mkv"}
+	if len(files) != len(want) {
+		t.Fatalf("files %+v", files)
+	}
+	for i, f := range files {
+		if f.Path != want[i] {
+			t.Errorf("file %d: %q, want %q", i, f.Path, want[i])
+		}
+	}
+	if files[2].Link != "https://alldebrid.com/f/3" || files[2].Size != 110 {
+		t.Errorf("last file %+v", files[2])
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	srv := mockAPI(t)
+	_, err := New(srv.Client(), srv.URL, testKey).UnlockLink(context.Background(), "https://alldebrid.com/f/1")
+	var ae *APIError
+	if !errors.As(err, &ae) || !ae.RateLimited() || ae.InvalidCredentials() {
+		t.Errorf("unlock: %v", err)
+	}
+}
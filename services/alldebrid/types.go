@@ -0,0 +1,107 @@
+package alldebrid
+
+// Error is the error object AllDebrid returns alongside status "error"
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// response is the envelope every AllDebrid v4 endpoint answers with
+type response[T any] struct {
+	Status string `json:"status"`
+	Data   T      `json:"data"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// User represents an AllDebrid account
+type User struct {
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	IsPremium    bool   `json:"isPremium"`
+	PremiumUntil int64  `json:"premiumUntil"`
+	// FidelityPoints is AllDebrid's loyalty balance
+	FidelityPoints int `json:"fidelityPoints"`
+}
+
+// UserData is the data of the user endpoint
+type UserData struct {
+	User User `json:"user"`
+}
+
+// UploadedMagnet represents a magnet as returned by the upload endpoint
+type UploadedMagnet struct {
+	Magnet string `json:"magnet"`
+	Hash   string `json:"hash"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Ready  bool   `json:"ready"`
+	ID     int64  `json:"id"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// UploadData is the data of the magnet upload endpoint
+type UploadData struct {
+	Magnets []UploadedMagnet `json:"magnets"`
+}
+
+// MagnetStatusReady is the status code of a magnet whose files are available
+const MagnetStatusReady = 4
+
+// Magnet represents a magnet in the user's AllDebrid list
+type Magnet struct {
+	ID         int64  `json:"id"`
+	Filename   string `json:"filename"`
+	Size       int64  `json:"size"`
+	Hash       string `json:"hash"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"statusCode"`
+}
+
+// Ready reports whether the magnet's files can be unlocked
+func (m *Magnet) Ready() bool {
+	return m.StatusCode == MagnetStatusReady
+}
+
+// StatusData is the data of the magnet status endpoint
+type StatusData struct {
+	Magnets []Magnet `json:"magnets"`
+}
+
+// FileNode is a node of the file tree AllDebrid returns for a magnet:
+// a file carries a link, a folder carries entries.
+type FileNode struct {
+	Name    string     `json:"n"`
+	Size    int64      `json:"s,omitempty"`
+	Link    string     `json:"l,omitempty"`
+	Entries []FileNode `json:"e,omitempty"`
+}
+
+// MagnetFiles is the file tree of a single magnet
+type MagnetFiles struct {
+	ID    string     `json:"id"`
+	Files []FileNode `json:"files"`
+	Error *Error     `json:"error,omitempty"`
+}
+
+// FilesData is the data of the magnet files endpoint
+type FilesData struct {
+	Magnets []MagnetFiles `json:"magnets"`
+}
+
+// File is a file of a magnet with its path flattened out of the tree
+type File struct {
+	Path string
+	Size int64
+	Link string
+}
+
+// UnlockedLink represents a link returned by the unlock endpoint
+type UnlockedLink struct {
+	Link     string `json:"link"`
+	Filename string `json:"filename"`
+	Filesize int64  `json:"filesize"`
+	ID       string `json:"id"`
+	// Delayed is set when the host needs time to generate the link; magnet
+	// links are served from AllDebrid's own storage and never are.
+	Delayed int64 `json:"delayed,omitempty"`
+}
@@ -0,0 +1,210 @@
+package anilist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	enabledFlag = "anilist-enabled"
+	urlFlag     = "anilist-api-url"
+)
+
+func RegisterFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.BoolFlag{
+			Name:   enabledFlag,
+			Usage:  "resolve anime titles through anilist",
+			EnvVar: "ANILIST_ENABLED",
+		},
+		cli.StringFlag{
+			Name:   urlFlag,
+			Usage:  "anilist graphql api url",
+			EnvVar: "ANILIST_API_URL",
+			Value:  "https://graphql.anilist.co",
+		},
+	)
+}
+
+// mediaFields is what a search asks for about each entry. Relations are
+// left out: the mapper only needs titles to search the catalogue with and
+// the episode count to place absolute numbers.
+const mediaFields = `
+	id
+	idMal
+	format
+	status
+	episodes
+	seasonYear
+	startDate { year }
+	title { romaji english native }
+	synonyms
+`
+
+const searchQuery = `query ($search: String, $year: Int) {
+	Page(perPage: 10) {
+		media(search: $search, seasonYear: $year, type: ANIME, sort: SEARCH_MATCH) {` + mediaFields + `}
+	}
+}`
+
+// Media is one AniList entry. AniList splits a show into an entry per
+// season or cour, so a long-running series is either one entry with
+// hundreds of episodes or a chain of short ones.
+type Media struct {
+	ID         int    `json:"id"`
+	IDMal      int    `json:"idMal"`
+	Format     string `json:"format"`
+	Status     string `json:"status"`
+	Episodes   int    `json:"episodes"`
+	SeasonYear int    `json:"seasonYear"`
+	StartDate  struct {
+		Year int `json:"year"`
+	} `json:"startDate"`
+	Title struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+		Native  string `json:"native"`
+	} `json:"title"`
+	Synonyms []string       `json:"synonyms"`
+	Raw      map[string]any `json:"-"`
+}
+
+// Year is the year the entry started airing, 0 when unknown
+func (m *Media) Year() int {
+	if m.StartDate.Year != 0 {
+		return m.StartDate.Year
+	}
+	return m.SeasonYear
+}
+
+// Titles lists every name the entry goes by, English first
+func (m *Media) Titles() []string {
+	var out []string
+	for _, t := range append([]string{m.Title.English, m.Title.Romaji, m.Title.Native}, m.Synonyms...) {
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// IsMovie reports whether the entry is a film rather than a series
+func (m *Media) IsMovie() bool {
+	return m.Format == "MOVIE"
+}
+
+type Api struct {
+	url string
+	cl  *http.Client
+}
+
+func New(c *cli.Context, cl *http.Client) *Api {
+	if !c.Bool(enabledFlag) {
+		return nil
+	}
+	u := c.String(urlFlag)
+	log.Infof("anilist api endpoint %v", u)
+	return newApi(cl, u)
+}
+
+func newApi(cl *http.Client, u string) *Api {
+	return &Api{
+		url: u,
+		cl:  cl,
+	}
+}
+
+// Search returns the anime entries matching title, best match first. A
+// nil year searches every year.
+func (api *Api) Search(ctx context.Context, title string, year *int16) ([]*Media, error) {
+	vars := map[string]any{"search": title}
+	if year != nil {
+		vars["year"] = int(*year)
+	}
+	var data struct {
+		Page struct {
+			Media []json.RawMessage `json:"media"`
+		} `json:"Page"`
+	}
+	if err := api.doRequest(ctx, searchQuery, vars, &data); err != nil {
+		return nil, errors.Wrap(err, "anilist search request")
+	}
+	out := make([]*Media, 0, len(data.Page.Media))
+	for _, raw := range data.Page.Media {
+		m, err := decodeMedia(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func decodeMedia(raw json.RawMessage) (*Media, error) {
+	var m Media
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, errors.Wrap(err, "unmarshal media")
+	}
+	if err := json.Unmarshal(raw, &m.Raw); err != nil {
+		return nil, errors.Wrap(err, "unmarshal media")
+	}
+	return &m, nil
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+func (api *Api) doRequest(ctx context.Context, query string, vars map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshal request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := api.cl.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return errors.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(b))
+	}
+	if len(res.Errors) > 0 {
+		return errors.Errorf("anilist error: %s (status %d)", res.Errors[0].Message, res.Errors[0].Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(b))
+	}
+	if err := json.Unmarshal(res.Data, out); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package anilist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// replay serves a recorded AniList response and hands back the variables
+// of the query it was asked.
+func replay(t *testing.T, status int, fixture string) (*Api, *map[string]any) {
+	t.Helper()
+	body, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+			t.Errorf("not a graphql request: %s", r.Method)
+		}
+		if !strings.Contains(req.Query, "type: ANIME") {
+			t.Errorf("query without the anime filter: %s", req.Query)
+		}
+		vars = req.Variables
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return newApi(srv.Client(), srv.URL), &vars
+}
+
+func TestSearch(t *testing.T) {
+	api, vars := replay(t, http.StatusOK, "testdata/search_shingeki_no_kyojin.json")
+	year := int16(2013)
+	res, err := api.Search(context.Background(), "Shingeki no Kyojin", &year)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*vars)["search"] != "Shingeki no Kyojin" || (*vars)["year"] != float64(2013) {
+		t.Errorf("variables %v", *vars)
+	}
+	if len(res) != 3 {
+		t.Fatalf("got %d entries", len(res))
+	}
+	m := res[0]
+	if m.ID != 16498 || m.Episodes != 25 || m.Year() != 2013 || m.IsMovie() {
+		t.Errorf("first entry %+v", m)
+	}
+	titles := m.Titles()
+	if len(titles) != 5 || titles[0] != "Attack on Titan" || titles[1] != "Shingeki no Kyojin" {
+		t.Errorf("titles %q", titles)
+	}
+	if m.Raw["idMal"] != float64(16498) {
+		t.Errorf("raw payload %v", m.Raw)
+	}
+}
+
+func TestSearchWithoutYear(t *testing.T) {
+	api, vars := replay(t, http.StatusOK, "testdata/search_shingeki_no_kyojin.json")
+	if _, err := api.Search(context.Background(), "Shingeki no Kyojin", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (*vars)["year"]; ok {
+		t.Errorf("year sent without one: %v", *vars)
+	}
+}
+
+func TestSearchRateLimited(t *testing.T) {
+	api, _ := replay(t, http.StatusTooManyRequests, "testdata/rate_limited.json")
+	_, err := api.Search(context.Background(), "One Piece", nil)
+	if err == nil || !strings.Contains(err.Error(), "Too Many Requests") {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -151,6 +151,14 @@ type MediaProbe struct {
 			MajorBrand       string    `json:"major_brand"`
 			MinorVersion     string    `json:"minor_version"`
 			Title            string    `json:"title"`
+			// Audio tags. ID3 names the album artist album_artist,
+			// Vorbis comments ALBUMARTIST; the key match is
+			// case-insensitive.
+			Artist            string `json:"artist"`
+			Album             string `json:"album"`
+			AlbumArtist       string `json:"album_artist"`
+			VorbisAlbumArtist string `json:"albumartist"`
+			Date              string `json:"date"`
 		} `json:"tags"`
 	} `json:"format"`
 	Streams []struct {
@@ -884,11 +892,21 @@ func getRemoteAddress(c *gin.Context) string {
 type ClaimsContext struct{}
 
 func (s *Api) MakeClaimsFromContext(c *gin.Context, domain string, uc *claims.Data, sessionID string) (*Claims, error) {
+	return s.makeClaims(domain, uc, sessionID, getRemoteAddress(c), c.Request.Header.Get("User-Agent"))
+}
+
+// MakeClaims builds claims for a caller that did not come in over HTTP, such
+// as an SFTP session, for the default domain.
+func (s *Api) MakeClaims(uc *claims.Data, sessionID string, remoteAddress string, agent string) (*Claims, error) {
+	return s.makeClaims(s.domain, uc, sessionID, remoteAddress, agent)
+}
+
+func (s *Api) makeClaims(domain string, uc *claims.Data, sessionID string, remoteAddress string, agent string) (*Claims, error) {
 	cl := &Claims{
 		SessionID:     sessionID,
 		Domain:        domain,
-		RemoteAddress: getRemoteAddress(c),
-		Agent:         c.Request.Header.Get("User-Agent"),
+		RemoteAddress: remoteAddress,
+		Agent:         agent,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.expire) * 24 * time.Hour)),
 		},
@@ -0,0 +1,486 @@
+// Package autoindex renders the library filesystem for a web browser: an HTML
+// listing in the spirit of nginx's autoindex, the same listing as JSON, a
+// player page per media file and an .m3u playlist per folder.
+//
+// It is a protocol layer like services/webdav and services/s3 — it reads the
+// same vfs.FileSystem and adds nothing to it — for people on a machine where
+// they can neither install rclone nor mount a drive. It is read-only.
+//
+// Every link it renders is relative, so the listing works whether it is
+// reached through the token URL or through its short alias. Playlists and the
+// JSON listing need absolute URLs and take them from the caller's base.
+package autoindex
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/services/libfs"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+const (
+	// TokenName is the access_token row the index URL is issued under.
+	TokenName = "browse"
+	// Scope is the only thing an index token may do: read. The URL is meant
+	// to be opened on machines the user does not own, so it must not carry
+	// the write access a WebDAV token has.
+	Scope = "browse:read"
+)
+
+// posterWidth is the card size the library pages use; the poster endpoint
+// has it cached already.
+const posterWidth = 240
+
+//go:embed index.html
+var templateFS embed.FS
+
+// Translator looks up a locale key in the reader's language.
+type Translator func(key string) string
+
+// Index serves one library tree.
+type Index struct {
+	fs  vfs.FileSystem
+	tpl *template.Template
+}
+
+func New(fs vfs.FileSystem) *Index {
+	return &Index{
+		fs: fs,
+		tpl: template.Must(template.New("").Funcs(template.FuncMap{
+			// Replaced per request (see renderPage); declared here so the
+			// templates parse.
+			"t":    func(key string) string { return key },
+			"size": formatSize,
+		}).ParseFS(templateFS, "index.html")),
+	}
+}
+
+// Entry is one row of a listing.
+type Entry struct {
+	Name     string    `json:"name"`
+	IsDir    bool      `json:"is_dir"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	MIMEType string    `json:"mime_type,omitempty"`
+	URL      string    `json:"url"`
+	// PosterURL and PlaylistURL are set on folders. The poster answers 404
+	// for folders enrichment knows nothing about.
+	PosterURL   string `json:"poster_url,omitempty"`
+	PlaylistURL string `json:"playlist_url,omitempty"`
+	// PlayURL is set on files a browser can play.
+	PlayURL string `json:"play_url,omitempty"`
+
+	href string
+}
+
+// Href is the entry's link relative to the listing. The "./" keeps a name
+// with a colon in it from reading as a URL scheme.
+func (e *Entry) Href() string {
+	return e.href
+}
+
+// Listing is a folder as the JSON view returns it.
+type Listing struct {
+	Path    string   `json:"path"`
+	Entries []*Entry `json:"entries"`
+}
+
+type listingPage struct {
+	*Listing
+	Title  string
+	Parent bool
+	Query  string
+	Sort   string
+	Order  string
+}
+
+// SortHref links a column header: by that column, ascending, or flipped when
+// the listing is already sorted by it. The search is kept.
+func (p *listingPage) SortHref(col string) string {
+	v := url.Values{"sort": {col}, "order": {"asc"}}
+	if p.Sort == col && p.Order == "asc" {
+		v.Set("order", "desc")
+	}
+	if p.Query != "" {
+		v.Set("q", p.Query)
+	}
+	return "?" + v.Encode()
+}
+
+// Arrow marks the column the listing is sorted by.
+func (p *listingPage) Arrow(col string) string {
+	switch {
+	case p.Sort != col:
+		return ""
+	case p.Order == "desc":
+		return "↓"
+	}
+	return "↑"
+}
+
+type playerPage struct {
+	Title string
+	Href  string
+	Video bool
+}
+
+// Serve answers r for name, a path in the tree. base is the absolute URL the
+// tree is mounted at, with no trailing slash; t translates the page chrome.
+func (s *Index) Serve(w http.ResponseWriter, r *http.Request, name string, base string, t Translator) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if name == "" {
+		name = "/"
+	}
+	ctx := r.Context()
+	fi, err := s.fs.Stat(ctx, name)
+	if err != nil {
+		s.error(w, name, err)
+		return
+	}
+	q := r.URL.Query()
+	if fi.IsDir && !strings.HasSuffix(name, "/") {
+		// Relative links only resolve inside a folder whose URL ends in a
+		// slash. The Location is relative too: the client may have come in
+		// through the alias, whose prefix we never see.
+		loc := url.PathEscape(path.Base(name)) + "/"
+		if r.URL.RawQuery != "" {
+			loc += "?" + r.URL.RawQuery
+		}
+		// Not http.Redirect: it would resolve the Location against the
+		// rewritten path.
+		w.Header().Set("Location", "./"+loc)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+	switch {
+	case fi.IsDir && q.Has("poster"):
+		s.poster(w, r, name)
+	case fi.IsDir && q.Get("format") == "m3u":
+		s.playlist(w, r, name, base)
+	case fi.IsDir:
+		s.listing(w, r, name, base, t)
+	case q.Has("play") && playable(fi) != "":
+		s.player(w, fi, t)
+	default:
+		s.file(w, r, name, fi)
+	}
+}
+
+func (s *Index) listing(w http.ResponseWriter, r *http.Request, name string, base string, t Translator) {
+	q := r.URL.Query()
+	fis, err := s.fs.ReadDir(r.Context(), name, false)
+	if err != nil {
+		s.error(w, name, err)
+		return
+	}
+	l := &Listing{Path: name, Entries: make([]*Entry, 0, len(fis))}
+	filter := strings.ToLower(strings.TrimSpace(q.Get("q")))
+	for i := range fis {
+		e := newEntry(&fis[i], name, base)
+		if e == nil || (filter != "" && !strings.Contains(strings.ToLower(e.Name), filter)) {
+			continue
+		}
+		l.Entries = append(l.Entries, e)
+	}
+	sortBy, order := sortEntries(l.Entries, q.Get("sort"), q.Get("order"))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := json.NewEncoder(w).Encode(l); err != nil {
+			log.WithError(err).Warn("failed to write index listing")
+		}
+		return
+	}
+	s.render(w, r, "listing", t, &listingPage{
+		Listing: l,
+		Title:   name,
+		Parent:  name != "/",
+		Query:   q.Get("q"),
+		Sort:    sortBy,
+		Order:   order,
+	})
+}
+
+// newEntry converts a child of dir, or returns nil for anything that is not
+// one (a tree may list the folder itself).
+func newEntry(fi *vfs.FileInfo, dir string, base string) *Entry {
+	n := path.Base(strings.TrimSuffix(fi.Path, "/"))
+	if n == "." || n == "/" || n == "" || strings.TrimSuffix(fi.Path, "/") == strings.TrimSuffix(dir, "/") {
+		return nil
+	}
+	href := url.PathEscape(n)
+	abs := base + escapePath(dir) + href
+	e := &Entry{
+		Name:     n,
+		IsDir:    fi.IsDir,
+		Size:     fi.Size,
+		ModTime:  fi.ModTime,
+		MIMEType: mimeType(fi),
+		href:     "./" + href,
+	}
+	if fi.IsDir {
+		e.href += "/"
+		e.URL = abs + "/"
+		e.PosterURL = e.URL + "?poster"
+		e.PlaylistURL = e.URL + "?format=m3u"
+		return e
+	}
+	e.URL = abs
+	if playable(fi) != "" {
+		e.PlayURL = abs + "?play"
+	}
+	return e
+}
+
+// sortEntries orders folders first, then by the requested column. It
+// returns the column and order actually applied.
+func sortEntries(es []*Entry, by string, order string) (string, string) {
+	less := func(a, b *Entry) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+	switch by {
+	case "size":
+		less = func(a, b *Entry) bool { return a.Size < b.Size }
+	case "modified":
+		less = func(a, b *Entry) bool { return a.ModTime.Before(b.ModTime) }
+	default:
+		by = "name"
+	}
+	if order != "desc" {
+		order = "asc"
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		a, b := es[i], es[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if order == "desc" {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return by, order
+}
+
+// poster sends the browser on to the poster of the torrent a folder belongs
+// to. Resolving it here, one folder at a time as the browser lazily asks,
+// keeps the listing itself at a single ReadDir.
+func (s *Index) poster(w http.ResponseWriter, r *http.Request, name string) {
+	md, err := vfs.ReadMetadata(r.Context(), s.fs, name)
+	if err != nil {
+		s.error(w, name, err)
+		return
+	}
+	ih := md[libfs.MetaInfohash]
+	if ih == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.Redirect(w, r, "/lib/poster/"+url.PathEscape(ih)+"/"+formatInt(posterWidth)+".jpg", http.StatusFound)
+}
+
+// playlist writes the folder's media files as an extended M3U. Only this
+// folder: a series gets one playlist per season folder, which is what a
+// player wants anyway.
+func (s *Index) playlist(w http.ResponseWriter, r *http.Request, name string, base string) {
+	fis, err := s.fs.ReadDir(r.Context(), name, false)
+	if err != nil {
+		s.error(w, name, err)
+		return
+	}
+	var es []*Entry
+	for i := range fis {
+		if e := newEntry(&fis[i], name, base); e != nil && e.PlayURL != "" {
+			es = append(es, e)
+		}
+	}
+	sortEntries(es, "name", "asc")
+	n := path.Base(strings.TrimSuffix(name, "/"))
+	if n == "/" || n == "." {
+		n = "library"
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": n + ".m3u"}))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := WriteM3U(w, es); err != nil {
+		log.WithError(err).Warn("failed to write index playlist")
+	}
+}
+
+// WriteM3U writes entries as an extended M3U playlist of their URLs.
+func WriteM3U(w io.Writer, es []*Entry) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, e := range es {
+		title := strings.TrimSuffix(e.Name, path.Ext(e.Name))
+		b.WriteString("#EXTINF:-1," + strings.NewReplacer("\n", " ", "\r", " ").Replace(title) + "\n")
+		b.WriteString(e.URL + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (s *Index) player(w http.ResponseWriter, fi *vfs.FileInfo, t Translator) {
+	n := path.Base(fi.Path)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	s.renderPage(w, "player", t, &playerPage{
+		Title: n,
+		Href:  "./" + url.PathEscape(n),
+		Video: playable(fi) == "video",
+	})
+}
+
+// file streams a small local body or redirects to the content, the same two
+// outcomes the tree gives WebDAV. HEAD is answered from the Stat alone:
+// opening would mint a content URL for nothing.
+func (s *Index) file(w http.ResponseWriter, r *http.Request, name string, fi *vfs.FileInfo) {
+	if r.Method == http.MethodHead {
+		setFileHeaders(w, fi)
+		return
+	}
+	rc, u, err := s.fs.Open(r.Context(), name)
+	if err != nil {
+		s.error(w, name, err)
+		return
+	}
+	if u != nil {
+		http.Redirect(w, r, u.String(), http.StatusTemporaryRedirect)
+		return
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	setFileHeaders(w, fi)
+	if r.URL.Query().Has("download") {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(fi.Path)}))
+	}
+	if _, err := io.Copy(w, rc); err != nil && r.Context().Err() == nil {
+		log.WithError(err).WithField("path", name).Warn("failed to write index file")
+	}
+}
+
+func setFileHeaders(w http.ResponseWriter, fi *vfs.FileInfo) {
+	w.Header().Set("Content-Type", mimeType(fi))
+	if fi.Size > 0 {
+		w.Header().Set("Content-Length", formatInt(fi.Size))
+	}
+	if !fi.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", fi.ModTime.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (s *Index) render(w http.ResponseWriter, r *http.Request, name string, t Translator, data any) {
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		return
+	}
+	s.renderPage(w, name, t, data)
+}
+
+func (s *Index) renderPage(w http.ResponseWriter, name string, t Translator, data any) {
+	tpl, err := s.tpl.Clone()
+	if err != nil {
+		s.error(w, name, errors.Wrap(err, "failed to clone index template"))
+		return
+	}
+	tpl.Funcs(template.FuncMap{"t": func(key string) string { return t(key) }})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tpl.ExecuteTemplate(w, name, data); err != nil {
+		log.WithError(err).Warn("failed to render index page")
+	}
+}
+
+func (s *Index) error(w http.ResponseWriter, name string, err error) {
+	code := vfs.StatusCode(err)
+	if code >= http.StatusInternalServerError {
+		log.WithError(err).WithField("path", name).Error("failed to serve index")
+	}
+	http.Error(w, http.StatusText(code), code)
+}
+
+// wantsJSON is true for ?format=json and for clients that ask for JSON and
+// not HTML — a browser's Accept lists text/html first.
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	a := r.Header.Get("Accept")
+	return strings.Contains(a, "application/json") && !strings.Contains(a, "text/html")
+}
+
+func mimeType(fi *vfs.FileInfo) string {
+	if fi.IsDir {
+		return ""
+	}
+	if fi.MIMEType != "" {
+		return fi.MIMEType
+	}
+	if t := mime.TypeByExtension(path.Ext(fi.Path)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// playable says which element a browser would play the file in — "video",
+// "audio" or "" for neither.
+func playable(fi *vfs.FileInfo) string {
+	t := mimeType(fi)
+	switch {
+	case strings.HasPrefix(t, "video/"):
+		return "video"
+	case strings.HasPrefix(t, "audio/"):
+		return "audio"
+	}
+	return ""
+}
+
+// escapePath escapes each segment of a tree path, keeping the slashes.
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		parts[i] = url.PathEscape(s)
+	}
+	return strings.Join(parts, "/")
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// formatSize prints a size the way nginx's human-readable autoindex does.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return formatInt(n) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + " " + string("KMGTPE"[exp]) + "iB"
+}
@@ -0,0 +1,211 @@
+package autoindex
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webtor-io/web-ui/services/libfs"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+const (
+	testBase = "https://webtor.io/token/abc/browse/fs"
+	movie    = "Sintel (2010)"
+)
+
+var testModTime = time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeFS struct {
+	libfs.BaseDirectory
+	dirs  map[string][]vfs.FileInfo
+	files map[string]vfs.FileInfo
+}
+
+func newFakeFS() *fakeFS {
+	mkv := vfs.FileInfo{Path: "/movies/" + movie + "/Sintel.mkv", Size: 3 << 20, ModTime: testModTime, MIMEType: "video/x-matroska"}
+	srt := vfs.FileInfo{Path: "/movies/" + movie + "/Sintel.en.srt", Size: 12, ModTime: testModTime}
+	return &fakeFS{
+		dirs: map[string][]vfs.FileInfo{
+			"/": {
+				{Path: "/movies/", IsDir: true},
+				{Path: "/torrents/", IsDir: true},
+			},
+			"/movies/": {
+				{Path: "/movies/" + movie + "/", IsDir: true, ModTime: testModTime},
+				{Path: "/movies/Big Buck Bunny/", IsDir: true, ModTime: testModTime.Add(time.Hour)},
+			},
+			"/movies/" + movie + "/":  {srt, mkv},
+			"/movies/Big Buck Bunny/": {},
+			"/torrents/":              {},
+		},
+		files: map[string]vfs.FileInfo{mkv.Path: mkv, srt.Path: srt},
+	}
+}
+
+func (f *fakeFS) Stat(_ context.Context, name string) (*vfs.FileInfo, error) {
+	if _, ok := f.dirs[strings.TrimSuffix(name, "/")+"/"]; ok || name == "/" {
+		return &vfs.FileInfo{Path: name, IsDir: true}, nil
+	}
+	if fi, ok := f.files[name]; ok {
+		return &fi, nil
+	}
+	return nil, vfs.NewHTTPError(http.StatusNotFound, nil)
+}
+
+func (f *fakeFS) ReadDir(_ context.Context, name string, _ bool) ([]vfs.FileInfo, error) {
+	fis, ok := f.dirs[name]
+	if !ok {
+		return nil, vfs.NewHTTPError(http.StatusNotFound, nil)
+	}
+	return fis, nil
+}
+
+func (f *fakeFS) Open(_ context.Context, name string) (io.ReadCloser, *url.URL, error) {
+	if strings.HasSuffix(name, ".srt") {
+		return io.NopCloser(strings.NewReader("1\n00:00:01,000")), nil, nil
+	}
+	u, _ := url.Parse("https://export.example/" + url.PathEscape(name))
+	return nil, u, nil
+}
+
+func (f *fakeFS) Metadata(_ context.Context, name string) (map[string]string, error) {
+	if strings.HasPrefix(name, "/movies/"+movie+"/") {
+		return map[string]string{libfs.MetaInfohash: "08ada5a7"}, nil
+	}
+	return nil, nil
+}
+
+func serve(t *testing.T, target string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	name, _ := url.PathUnescape(r.URL.Path)
+	w := httptest.NewRecorder()
+	New(newFakeFS()).Serve(w, r, name, testBase, func(key string) string { return key })
+	return w
+}
+
+func TestListingJSON(t *testing.T) {
+	w := serve(t, "/movies/"+url.PathEscape(movie)+"/?format=json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var l Listing
+	if err := json.Unmarshal(w.Body.Bytes(), &l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Entries) != 2 || l.Entries[0].Name != "Sintel.en.srt" {
+		t.Fatalf("entries %+v", l.Entries)
+	}
+	mkv := l.Entries[1]
+	want := testBase + "/movies/Sintel%20%282010%29/Sintel.mkv"
+	if mkv.URL != want || mkv.PlayURL != want+"?play" {
+		t.Errorf("urls %q %q", mkv.URL, mkv.PlayURL)
+	}
+	if l.Entries[0].PlayURL != "" {
+		t.Error("a subtitle is not playable")
+	}
+}
+
+func TestListingSortAndSearch(t *testing.T) {
+	var l Listing
+	w := serve(t, "/movies/?sort=modified&order=desc", "Accept", "application/json")
+	if err := json.Unmarshal(w.Body.Bytes(), &l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Entries) != 2 || l.Entries[0].Name != "Big Buck Bunny" {
+		t.Fatalf("entries %+v", l.Entries)
+	}
+	if l.Entries[0].PosterURL != testBase+"/movies/Big%20Buck%20Bunny/?poster" {
+		t.Errorf("poster %q", l.Entries[0].PosterURL)
+	}
+	l = Listing{}
+	w = serve(t, "/movies/?q=sin&format=json")
+	if err := json.Unmarshal(w.Body.Bytes(), &l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Entries) != 1 || l.Entries[0].Name != movie {
+		t.Errorf("search: %+v", l.Entries)
+	}
+}
+
+func TestListingHTML(t *testing.T) {
+	w := serve(t, "/movies/", "Accept", "text/html,application/json")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("status %d, type %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		`href="./Sintel%20%282010%29/"`,
+		`src="./Sintel%20%282010%29/?poster"`,
+		`href="../"`,
+		"browse.name",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing lacks %s", want)
+		}
+	}
+}
+
+func TestFolderRedirect(t *testing.T) {
+	w := serve(t, "/movies?sort=size")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "./movies/?sort=size" {
+		t.Errorf("got %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestPlaylist(t *testing.T) {
+	w := serve(t, "/movies/"+url.PathEscape(movie)+"/?format=m3u")
+	want := "#EXTM3U\n#EXTINF:-1,Sintel\n" + testBase + "/movies/Sintel%20%282010%29/Sintel.mkv\n"
+	if w.Body.String() != want {
+		t.Errorf("playlist %q", w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") || !strings.Contains(cd, ".m3u") {
+		t.Errorf("disposition %q", cd)
+	}
+}
+
+func TestPoster(t *testing.T) {
+	w := serve(t, "/movies/"+url.PathEscape(movie)+"/?poster")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/lib/poster/08ada5a7/240.jpg" {
+		t.Errorf("got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := serve(t, "/movies/?poster"); w.Code != http.StatusNotFound {
+		t.Errorf("folder without a torrent: %d", w.Code)
+	}
+}
+
+func TestFile(t *testing.T) {
+	w := serve(t, "/movies/"+url.PathEscape(movie)+"/Sintel.mkv")
+	if w.Code != http.StatusTemporaryRedirect || !strings.HasPrefix(w.Header().Get("Location"), "https://export.example/") {
+		t.Errorf("content: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	w = serve(t, "/movies/"+url.PathEscape(movie)+"/Sintel.en.srt?download")
+	if w.Code != http.StatusOK || w.Body.String() != "1\n00:00:01,000" || !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("local body: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = serve(t, "/movies/"+url.PathEscape(movie)+"/Sintel.mkv?play")
+	if !strings.Contains(w.Body.String(), `<video src="./Sintel.mkv"`) {
+		t.Errorf("player: %s", w.Body.String())
+	}
+	if w := serve(t, "/movies/nope.mkv"); w.Code != http.StatusNotFound {
+		t.Errorf("missing: %d", w.Code)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 3 << 30: "3.0 GiB"} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package backend_account
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	cs "github.com/webtor-io/common-services"
+
+	"github.com/webtor-io/web-ui/models"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+	"github.com/webtor-io/web-ui/services/notification"
+)
+
+// store is the database behind the check, an interface so the rules about
+// which letter goes out when can be tested without Postgres.
+type store interface {
+	EnabledBackends(ctx context.Context) ([]*models.StreamingBackend, error)
+	UpdateAccount(ctx context.Context, b *models.StreamingBackend) error
+	AccountLang(ctx context.Context, userID uuid.UUID) string
+}
+
+// fetcher asks a backend's service about its account. LinkResolver is the
+// production one.
+type fetcher interface {
+	ChecksAccount(t models.StreamingBackendType) bool
+	Account(ctx context.Context, b *models.StreamingBackend) (*co.Account, error)
+}
+
+type mailer interface {
+	SendBackendExpiring(to string, b notification.BackendView) error
+	SendBackendInvalid(to string, b notification.BackendView) error
+}
+
+// Checker refreshes what is known about the account behind every enabled
+// streaming backend, and mails the owner when the premium plan is about to
+// run out or the token stopped working.
+type Checker struct {
+	store   store
+	fetcher fetcher
+	mail    mailer
+	now     func() time.Time
+}
+
+func New(store store, fetcher fetcher, mail mailer) *Checker {
+	return &Checker{
+		store:   store,
+		fetcher: fetcher,
+		mail:    mail,
+		now:     time.Now,
+	}
+}
+
+// Run checks every enabled backend and returns how many were checked. One
+// backend failing does not stop the others. Backends with no account to
+// ask about, such as a self-hosted SABnzbd, are left alone: nothing about
+// them was checked, so nothing is recorded.
+func (s *Checker) Run(ctx context.Context) (int, error) {
+	backends, err := s.store.EnabledBackends(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list streaming backends")
+	}
+	n := 0
+	for _, b := range backends {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		if !s.fetcher.ChecksAccount(b.Type) {
+			continue
+		}
+		if err := s.check(ctx, b); err != nil {
+			log.WithError(err).
+				WithField("streaming_backend_id", b.ID).
+				Warn("failed to check streaming backend account")
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *Checker) check(ctx context.Context, b *models.StreamingBackend) error {
+	now := s.now()
+	acc, err := s.fetcher.Account(ctx, b)
+	status := co.StatusFromError(err)
+	b.LastStatus = &status
+	b.LastCheckedAt = &now
+	if err != nil {
+		log.WithError(err).
+			WithField("streaming_backend_id", b.ID).
+			WithField("status", status).
+			Info("streaming backend account check failed")
+	} else if acc != nil {
+		b.PremiumUntil = acc.PremiumUntil
+		b.Points = acc.Points
+		b.Usage = acc.Usage
+		b.Downloaded = acc.Downloaded
+	}
+	if status == models.StreamingBackendStatusOK {
+		b.InvalidNotifiedAt = nil
+	}
+
+	to := ""
+	if b.User != nil {
+		to = b.User.Email
+	}
+	// The row is written after the letters, with what was actually sent: a
+	// failed send leaves the flag unset, so the next run tries again.
+	if to != "" && status == models.StreamingBackendStatusInvalidCredentials && b.InvalidNotifiedAt == nil {
+		if err := s.mail.SendBackendInvalid(to, s.view(ctx, b)); err != nil {
+			log.WithError(err).WithField("streaming_backend_id", b.ID).Warn("failed to send invalid token notice")
+		} else {
+			b.InvalidNotifiedAt = &now
+		}
+	}
+	if to != "" && status == models.StreamingBackendStatusOK && s.expiresSoon(b, now) {
+		if err := s.mail.SendBackendExpiring(to, s.view(ctx, b)); err != nil {
+			log.WithError(err).WithField("streaming_backend_id", b.ID).Warn("failed to send expiry notice")
+		} else {
+			b.ExpiryNotifiedFor = b.PremiumUntil
+		}
+	}
+
+	return s.store.UpdateAccount(ctx, b)
+}
+
+func (s *Checker) view(ctx context.Context, b *models.StreamingBackend) notification.BackendView {
+	return notification.BackendView{
+		ID:           b.ID,
+		Name:         b.Type.DisplayName(),
+		Lang:         s.store.AccountLang(ctx, b.UserID),
+		PremiumUntil: b.PremiumUntil,
+	}
+}
+
+// expiresSoon reports whether the plan ends within
+// models.StreamingBackendExpiryWarning and this
+// expiry has not been announced yet.
+func (s *Checker) expiresSoon(b *models.StreamingBackend, now time.Time) bool {
+	if b.PremiumUntil == nil {
+		return false
+	}
+	until := *b.PremiumUntil
+	if !until.After(now) || until.Sub(now) > models.StreamingBackendExpiryWarning {
+		return false
+	}
+	return b.ExpiryNotifiedFor == nil || !b.ExpiryNotifiedFor.Equal(until)
+}
+
+// pgStore is the production store.
+type pgStore struct{ pg *cs.PG }
+
+func NewStore(pg *cs.PG) pgStore {
+	return pgStore{pg: pg}
+}
+
+func (s pgStore) db() (*pg.DB, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	return db, nil
+}
+
+func (s pgStore) EnabledBackends(ctx context.Context) ([]*models.StreamingBackend, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.GetEnabledStreamingBackends(ctx, db)
+}
+
+func (s pgStore) UpdateAccount(ctx context.Context, b *models.StreamingBackend) error {
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+	return models.UpdateStreamingBackendAccount(ctx, db, b)
+}
+
+// AccountLang returns the language the account browses in, or "" for the
+// default. A lookup failure must not stop a letter.
+func (s pgStore) AccountLang(ctx context.Context, userID uuid.UUID) string {
+	db, err := s.db()
+	if err != nil {
+		return ""
+	}
+	us, err := models.GetUserSettings(ctx, db, userID)
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Warn("failed to read account language")
+		return ""
+	}
+	return us.GetLang()
+}
@@ -0,0 +1,187 @@
+package backend_account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+	"github.com/webtor-io/web-ui/services/notification"
+)
+
+type fakeStore struct {
+	backends []*models.StreamingBackend
+	updated  int
+}
+
+func (s *fakeStore) EnabledBackends(context.Context) ([]*models.StreamingBackend, error) {
+	return s.backends, nil
+}
+
+func (s *fakeStore) UpdateAccount(context.Context, *models.StreamingBackend) error {
+	s.updated++
+	return nil
+}
+
+func (s *fakeStore) AccountLang(context.Context, uuid.UUID) string { return "de" }
+
+type fakeFetcher struct {
+	acc       *co.Account
+	err       error
+	unchecked map[models.StreamingBackendType]bool
+}
+
+func (f *fakeFetcher) ChecksAccount(t models.StreamingBackendType) bool {
+	return !f.unchecked[t]
+}
+
+func (f *fakeFetcher) Account(context.Context, *models.StreamingBackend) (*co.Account, error) {
+	return f.acc, f.err
+}
+
+type fakeMailer struct {
+	expiring, invalid []notification.BackendView
+}
+
+func (m *fakeMailer) SendBackendExpiring(_ string, b notification.BackendView) error {
+	m.expiring = append(m.expiring, b)
+	return nil
+}
+
+func (m *fakeMailer) SendBackendInvalid(_ string, b notification.BackendView) error {
+	m.invalid = append(m.invalid, b)
+	return nil
+}
+
+type invalidErr struct{}
+
+func (invalidErr) Error() string            { return "bad token" }
+func (invalidErr) InvalidCredentials() bool { return true }
+
+func newBackend() *models.StreamingBackend {
+	return &models.StreamingBackend{
+		ID:   uuid.NewV4(),
+		Type: models.StreamingBackendTypeRealDebrid,
+		User: &models.User{Email: "user@example.com"},
+	}
+}
+
+func TestCheckerWarnsOncePerExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	until := now.Add(5 * 24 * time.Hour)
+	points := 120
+	b := newBackend()
+	st := &fakeStore{backends: []*models.StreamingBackend{b}}
+	f := &fakeFetcher{acc: &co.Account{PremiumUntil: &until, Points: &points}}
+	m := &fakeMailer{}
+	c := New(st, f, m)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if n, err := c.Run(context.Background()); err != nil || n != 1 {
+			t.Fatalf("run %d: checked %d: %v", i, n, err)
+		}
+	}
+	if len(m.expiring) != 1 {
+		t.Fatalf("sent %d expiry notices, want 1", len(m.expiring))
+	}
+	if m.expiring[0].Name != "Real-Debrid" || m.expiring[0].Lang != "de" {
+		t.Errorf("notice for %+v", m.expiring[0])
+	}
+	if b.Points == nil || *b.Points != 120 || *b.LastStatus != models.StreamingBackendStatusOK {
+		t.Errorf("account not stored: %+v", b)
+	}
+
+	// A renewal moves the expiry; nearing the new one warns again.
+	renewed := until.Add(30 * 24 * time.Hour)
+	f.acc = &co.Account{PremiumUntil: &renewed}
+	c.now = func() time.Time { return renewed.Add(-24 * time.Hour) }
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.expiring) != 2 {
+		t.Errorf("sent %d expiry notices after renewal, want 2", len(m.expiring))
+	}
+}
+
+func TestCheckerSkipsDistantExpiry(t *testing.T) {
+	until := time.Now().Add(30 * 24 * time.Hour)
+	st := &fakeStore{backends: []*models.StreamingBackend{newBackend()}}
+	m := &fakeMailer{}
+	if _, err := New(st, &fakeFetcher{acc: &co.Account{PremiumUntil: &until}}, m).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.expiring) != 0 {
+		t.Error("warned about a plan a month away from ending")
+	}
+}
+
+func TestCheckerInvalidToken(t *testing.T) {
+	until := time.Now().Add(3 * 24 * time.Hour)
+	points := 5
+	b := newBackend()
+	b.PremiumUntil = &until
+	b.Points = &points
+	st := &fakeStore{backends: []*models.StreamingBackend{b}}
+	f := &fakeFetcher{err: errors.Wrap(invalidErr{}, "failed to get user")}
+	m := &fakeMailer{}
+	c := New(st, f, m)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m.invalid) != 1 {
+		t.Fatalf("sent %d invalid token notices, want 1", len(m.invalid))
+	}
+	if len(m.expiring) != 0 {
+		t.Error("warned about expiry on an account we cannot read")
+	}
+	if *b.LastStatus != models.StreamingBackendStatusInvalidCredentials || b.Points == nil {
+		t.Errorf("a failed check should keep the last known account: %+v", b)
+	}
+
+	// Once the token works again, a later breakage is reported again.
+	f.err = nil
+	f.acc = &co.Account{}
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b.InvalidNotifiedAt != nil {
+		t.Error("a working token kept the invalid notice flag")
+	}
+	f.err = invalidErr{}
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.invalid) != 2 {
+		t.Errorf("sent %d invalid token notices, want 2", len(m.invalid))
+	}
+	if st.updated != 4 {
+		t.Errorf("stored %d checks, want 4", st.updated)
+	}
+}
+
+// A backend with no account to ask about is not marked working: nothing
+// was checked.
+func TestCheckerSkipsUncheckable(t *testing.T) {
+	notified := time.Now()
+	b := newBackend()
+	b.Type = models.StreamingBackendTypeSABnzbd
+	b.InvalidNotifiedAt = &notified
+	st := &fakeStore{backends: []*models.StreamingBackend{b}}
+	f := &fakeFetcher{unchecked: map[models.StreamingBackendType]bool{models.StreamingBackendTypeSABnzbd: true}}
+	c := New(st, f, &fakeMailer{})
+
+	if n, err := c.Run(context.Background()); err != nil || n != 0 {
+		t.Fatalf("Run() = %d, %v; want 0 checked", n, err)
+	}
+	if st.updated != 0 || b.LastStatus != nil || b.InvalidNotifiedAt == nil {
+		t.Errorf("an unchecked backend was recorded: %+v", b)
+	}
+}
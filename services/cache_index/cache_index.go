@@ -16,6 +16,7 @@ import (
 
 const (
 	cacheExpireFlag = "cache-index-expire"
+	checkExpireFlag = "cache-index-check-expire"
 )
 
 func RegisterFlags(f []cli.Flag) []cli.Flag {
@@ -26,12 +27,19 @@ func RegisterFlags(f []cli.Flag) []cli.Flag {
 			Value:  12 * time.Hour,
 			EnvVar: "CACHE_INDEX_EXPIRE",
 		},
+		cli.DurationFlag{
+			Name:   checkExpireFlag,
+			Usage:  "cache index expiration time for bulk availability check results",
+			Value:  time.Hour,
+			EnvVar: "CACHE_INDEX_CHECK_EXPIRE",
+		},
 	)
 }
 
 type CacheIndex struct {
 	pg            *cs.PG
 	cacheExpire   time.Duration
+	checkExpire   time.Duration
 	markCachedMap *lazymap.LazyMap[bool]
 	isCachedMap   *lazymap.LazyMap[[]models.CacheIndexResult]
 }
@@ -40,6 +48,7 @@ func New(c *cli.Context, pg *cs.PG) *CacheIndex {
 	return &CacheIndex{
 		pg:          pg,
 		cacheExpire: c.Duration(cacheExpireFlag),
+		checkExpire: c.Duration(checkExpireFlag),
 		markCachedMap: lazymap.New[bool](&lazymap.Config{
 			Expire:      time.Minute,
 			ErrorExpire: 10 * time.Second,
@@ -73,6 +82,39 @@ func (s *CacheIndex) MarkAsCached(ctx context.Context, backendType models.Stream
 	return err
 }
 
+// MarkAsChecked records the outcome of a bulk availability check on a
+// backend: cached files are marked for the (shorter) check expiry, and the
+// backend's entries for the rest are dropped, so a file the service has
+// evicted stops showing as instant.
+func (s *CacheIndex) MarkAsChecked(ctx context.Context, backendType models.StreamingBackendType, cached, uncached []models.CacheIndexKey) error {
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("database connection not available")
+	}
+	err := models.MarkAsCachedUntil(ctx, db, backendType, cached, time.Now().Add(s.checkExpire))
+	if err != nil {
+		return errors.Wrap(err, "failed to mark as cached")
+	}
+	ids := make([]string, 0, len(uncached))
+	for _, k := range uncached {
+		ids = append(ids, k.ResourceID)
+	}
+	err = models.DeleteCached(ctx, db, backendType, ids)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete evicted entries")
+	}
+	for _, k := range cached {
+		s.isCachedMap.Drop(fmt.Sprintf("is:%s:%d", k.ResourceID, k.FileIdx))
+	}
+	// A MarkAsCached remembered in memory would skip the next write of an
+	// entry just deleted here.
+	for _, k := range uncached {
+		s.isCachedMap.Drop(fmt.Sprintf("is:%s:%d", k.ResourceID, k.FileIdx))
+		s.markCachedMap.Drop(fmt.Sprintf("mark:%s:%s:%d", backendType, k.ResourceID, k.FileIdx))
+	}
+	return nil
+}
+
 // IsCached returns a list of backend types and their last seen times for the
 // given resource + file index.
 func (s *CacheIndex) IsCached(ctx context.Context, resourceID string, fileIdx int) ([]models.CacheIndexResult, error) {
@@ -90,6 +132,20 @@ func (s *CacheIndex) IsCached(ctx context.Context, resourceID string, fileIdx in
 	})
 }
 
+// CachedTorrents returns, for each of the resources cached on any of the
+// backend types, the types that have it
+func (s *CacheIndex) CachedTorrents(ctx context.Context, resourceIDs []string, backendTypes []models.StreamingBackendType) (map[string][]models.StreamingBackendType, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("database connection not available")
+	}
+	res, err := models.CachedTorrents(ctx, db, resourceIDs, backendTypes, s.cacheExpire)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check cached torrents")
+	}
+	return res, nil
+}
+
 // RunCleanup removes old cache entries from the database
 func (s *CacheIndex) RunCleanup(ctx context.Context) {
 	db := s.pg.Get()
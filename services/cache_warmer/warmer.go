@@ -0,0 +1,238 @@
+package cache_warmer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	cs "github.com/webtor-io/common-services"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+const (
+	trendingWindowFlag = "cache-warm-trending-window"
+	trendingLimitFlag  = "cache-warm-trending-limit"
+	userWindowFlag     = "cache-warm-user-window"
+	userLimitFlag      = "cache-warm-user-limit"
+)
+
+func RegisterFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.DurationFlag{
+			Name:   trendingWindowFlag,
+			Usage:  "how far back library additions count towards trending",
+			Value:  7 * 24 * time.Hour,
+			EnvVar: "CACHE_WARM_TRENDING_WINDOW",
+		},
+		cli.IntFlag{
+			Name:   trendingLimitFlag,
+			Usage:  "how many trending torrents are checked per backend type",
+			Value:  200,
+			EnvVar: "CACHE_WARM_TRENDING_LIMIT",
+		},
+		cli.DurationFlag{
+			Name:   userWindowFlag,
+			Usage:  "how far back release subscription hits are checked",
+			Value:  30 * 24 * time.Hour,
+			EnvVar: "CACHE_WARM_USER_WINDOW",
+		},
+		cli.IntFlag{
+			Name:   userLimitFlag,
+			Usage:  "how many subscription hits, and how many watchlist torrents, are checked per account",
+			Value:  200,
+			EnvVar: "CACHE_WARM_USER_LIMIT",
+		},
+	)
+}
+
+// store is the database behind the warmer, an interface so what gets
+// checked where can be tested without Postgres.
+type store interface {
+	EnabledBackends(ctx context.Context) ([]*models.StreamingBackend, error)
+	TrendingHashes(ctx context.Context, since time.Time, limit int) ([]string, error)
+	SubscriptionHashes(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]string, error)
+	WatchlistHashes(ctx context.Context, userID uuid.UUID, limit int) ([]string, error)
+}
+
+// checker checks hashes against a backend in bulk and writes the answer to
+// the cache index. LinkResolver is the production one.
+type checker interface {
+	ChecksInBulk(t models.StreamingBackendType) bool
+	WarmCache(ctx context.Context, backend *models.StreamingBackend, hashes []string) (int, error)
+}
+
+// Stats is what one run did
+type Stats struct {
+	Backends int
+	Hashes   int
+	Cached   int
+}
+
+// Warmer fills the cache index ahead of demand. Left alone the index only
+// learns about a torrent when someone resolves or lists it; the warmer asks
+// every debrid account that can answer in bulk about the torrents its owner
+// is likely to want next — their release subscription hits and the torrents
+// matched to their watchlist — and about what is trending across libraries.
+type Warmer struct {
+	store          store
+	checker        checker
+	trendingWindow time.Duration
+	trendingLimit  int
+	userWindow     time.Duration
+	userLimit      int
+	now            func() time.Time
+}
+
+func New(c *cli.Context, store store, checker checker) *Warmer {
+	return &Warmer{
+		store:          store,
+		checker:        checker,
+		trendingWindow: c.Duration(trendingWindowFlag),
+		trendingLimit:  c.Int(trendingLimitFlag),
+		userWindow:     c.Duration(userWindowFlag),
+		userLimit:      c.Int(userLimitFlag),
+		now:            time.Now,
+	}
+}
+
+// Run checks every enabled backend that can answer in bulk. The cache index
+// is shared, so trending torrents are checked once per backend type, on the
+// first account of that type that answers; each account is asked about its
+// owner's own torrents. One backend failing does not stop the others.
+func (s *Warmer) Run(ctx context.Context) (*Stats, error) {
+	backends, err := s.store.EnabledBackends(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list streaming backends")
+	}
+	trending, err := s.store.TrendingHashes(ctx, s.now().Add(-s.trendingWindow), s.trendingLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list trending torrents")
+	}
+	trending = normalize(trending)
+	st := &Stats{}
+	warmed := map[models.StreamingBackendType]bool{}
+	userHashes := map[uuid.UUID][]string{}
+	for _, b := range backends {
+		if ctx.Err() != nil {
+			return st, ctx.Err()
+		}
+		if !s.checker.ChecksInBulk(b.Type) {
+			continue
+		}
+		// A rejected token stays rejected until its owner replaces it; the
+		// account check mails them about it.
+		if b.LastStatus != nil && *b.LastStatus == models.StreamingBackendStatusInvalidCredentials {
+			continue
+		}
+		own, ok := userHashes[b.UserID]
+		if !ok {
+			own = s.userHashes(ctx, b.UserID)
+			userHashes[b.UserID] = own
+		}
+		hashes := own
+		withTrending := !warmed[b.Type]
+		if withTrending {
+			hashes = normalize(append(append([]string{}, own...), trending...))
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		n, err := s.checker.WarmCache(ctx, b, hashes)
+		if err != nil {
+			log.WithError(err).
+				WithField("streaming_backend_id", b.ID).
+				Warn("failed to warm cache index")
+			continue
+		}
+		if withTrending {
+			warmed[b.Type] = true
+		}
+		st.Backends++
+		st.Hashes += len(hashes)
+		st.Cached += n
+	}
+	return st, nil
+}
+
+// userHashes returns the torrents the user is likely to play next. A lookup
+// failure leaves that part out rather than skipping the account.
+func (s *Warmer) userHashes(ctx context.Context, userID uuid.UUID) []string {
+	subs, err := s.store.SubscriptionHashes(ctx, userID, s.now().Add(-s.userWindow), s.userLimit)
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Warn("failed to list release subscription hits")
+	}
+	watchlist, err := s.store.WatchlistHashes(ctx, userID, s.userLimit)
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Warn("failed to list watchlist torrents")
+	}
+	return normalize(append(subs, watchlist...))
+}
+
+// normalize lowercases the hashes and drops duplicates and anything that is
+// not a v1 infohash, keeping the first occurrence's order.
+func normalize(hashes []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if len(h) != 40 || seen[h] {
+			continue
+		}
+		seen[h] = true
+		out = append(out, h)
+	}
+	return out
+}
+
+// pgStore is the production store.
+type pgStore struct{ pg *cs.PG }
+
+func NewStore(pg *cs.PG) pgStore {
+	return pgStore{pg: pg}
+}
+
+func (s pgStore) db() (*pg.DB, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	return db, nil
+}
+
+func (s pgStore) EnabledBackends(ctx context.Context) ([]*models.StreamingBackend, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.GetEnabledStreamingBackends(ctx, db)
+}
+
+func (s pgStore) TrendingHashes(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.ListTrendingResourceIDs(ctx, db, since, limit)
+}
+
+func (s pgStore) SubscriptionHashes(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]string, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.ListActiveReleaseSubscriptionHitHashes(ctx, db, userID, since, limit)
+}
+
+func (s pgStore) WatchlistHashes(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.ListWatchlistResourceIDs(ctx, db, userID, limit)
+}
@@ -0,0 +1,116 @@
+package cache_warmer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+type fakeStore struct {
+	backends  []*models.StreamingBackend
+	trending  []string
+	subs      map[uuid.UUID][]string
+	watchlist map[uuid.UUID][]string
+	userCalls int
+}
+
+func (s *fakeStore) EnabledBackends(context.Context) ([]*models.StreamingBackend, error) {
+	return s.backends, nil
+}
+
+func (s *fakeStore) TrendingHashes(context.Context, time.Time, int) ([]string, error) {
+	return s.trending, nil
+}
+
+func (s *fakeStore) SubscriptionHashes(_ context.Context, userID uuid.UUID, _ time.Time, _ int) ([]string, error) {
+	s.userCalls++
+	return s.subs[userID], nil
+}
+
+func (s *fakeStore) WatchlistHashes(_ context.Context, userID uuid.UUID, _ int) ([]string, error) {
+	return nil, errors.New("watchlist unavailable")
+}
+
+type fakeChecker struct {
+	bulk   map[models.StreamingBackendType]bool
+	fail   map[uuid.UUID]bool
+	warmed map[uuid.UUID][]string
+}
+
+func (c *fakeChecker) ChecksInBulk(t models.StreamingBackendType) bool {
+	return c.bulk[t]
+}
+
+func (c *fakeChecker) WarmCache(_ context.Context, b *models.StreamingBackend, hashes []string) (int, error) {
+	if c.fail[b.ID] {
+		return 0, errors.New("service down")
+	}
+	c.warmed[b.ID] = hashes
+	return 1, nil
+}
+
+func hash(c string) string {
+	return strings.Repeat(c, 40)
+}
+
+// TestWarmerChecksTrendingOncePerType checks that every account is asked
+// about its owner's torrents, trending ones go to the first account of a
+// type that answers, and backends without a bulk check or with a rejected
+// token are left alone.
+func TestWarmerChecksTrendingOncePerType(t *testing.T) {
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+	invalid := models.StreamingBackendStatusInvalidCredentials
+	down := &models.StreamingBackend{ID: uuid.NewV4(), UserID: alice, Type: models.StreamingBackendTypeTorbox}
+	aliceTB := &models.StreamingBackend{ID: uuid.NewV4(), UserID: alice, Type: models.StreamingBackendTypeTorbox}
+	aliceRD := &models.StreamingBackend{ID: uuid.NewV4(), UserID: alice, Type: models.StreamingBackendTypeRealDebrid}
+	bobTB := &models.StreamingBackend{ID: uuid.NewV4(), UserID: bob, Type: models.StreamingBackendTypeTorbox}
+	bobPM := &models.StreamingBackend{ID: uuid.NewV4(), UserID: bob, Type: models.StreamingBackendTypePremiumize, LastStatus: &invalid}
+
+	st := &fakeStore{
+		backends: []*models.StreamingBackend{down, aliceTB, aliceRD, bobTB, bobPM},
+		trending: []string{hash("A"), hash("b"), "not-a-hash"},
+		subs: map[uuid.UUID][]string{
+			alice: {hash("b"), hash("c")},
+			bob:   {hash("d")},
+		},
+	}
+	ch := &fakeChecker{
+		bulk: map[models.StreamingBackendType]bool{
+			models.StreamingBackendTypeTorbox:     true,
+			models.StreamingBackendTypePremiumize: true,
+		},
+		fail:   map[uuid.UUID]bool{down.ID: true},
+		warmed: map[uuid.UUID][]string{},
+	}
+	w := &Warmer{store: st, checker: ch, trendingLimit: 10, userLimit: 10, now: time.Now}
+
+	stats, err := w.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := map[uuid.UUID][]string{
+		aliceTB.ID: {hash("b"), hash("c"), hash("a")},
+		bobTB.ID:   {hash("d")},
+	}
+	if len(ch.warmed) != len(want) {
+		t.Fatalf("warmed %d backends, want %d: %v", len(ch.warmed), len(want), ch.warmed)
+	}
+	for id, hashes := range want {
+		if strings.Join(ch.warmed[id], ",") != strings.Join(hashes, ",") {
+			t.Errorf("backend %s checked %v, want %v", id, ch.warmed[id], hashes)
+		}
+	}
+	if st.userCalls != 2 {
+		t.Errorf("looked up user torrents %d times, want once per user", st.userCalls)
+	}
+	if stats.Backends != 2 || stats.Hashes != 4 || stats.Cached != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,103 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// refreshInterval is what the feed asks calendar apps to poll at. Google
+// ignores it and polls on its own schedule; Apple and Thunderbird honour it.
+const refreshInterval = "PT6H"
+
+// UID is the event's identity, stable across refreshes so a calendar app
+// moves an episode whose date changed instead of showing it twice.
+func (e Episode) UID() string {
+	return fmt.Sprintf("%s-s%02de%02d", e.VideoID, e.Season, e.Episode)
+}
+
+// Summary is the event title: "Series S01E02: Episode title".
+func (e Episode) Summary() string {
+	s := fmt.Sprintf("S%02dE%02d", e.Season, e.Episode)
+	if e.Series != "" {
+		s = e.Series + " " + s
+	}
+	if e.Title != "" {
+		s += ": " + e.Title
+	}
+	return s
+}
+
+// WriteICS renders the feed as an RFC 5545 calendar. Episodes are all-day
+// events: TMDB gives an air date, not a time, and a date is all a user
+// needs to know an episode is out. host qualifies the UIDs so they stay
+// unique across every calendar the user subscribes to.
+func WriteICS(w io.Writer, name, host string, eps []Episode, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		_, _ = bw.WriteString(fold(s))
+		_, _ = bw.WriteString("\r\n")
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//" + host + "//Upcoming episodes//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:" + refreshInterval)
+	line("X-PUBLISHED-TTL:" + refreshInterval)
+	for _, e := range eps {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID() + "@" + host)
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + e.AirDate.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.AirDate.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeText(e.Summary()))
+		if e.Plot != "" {
+			line("DESCRIPTION:" + escapeText(e.Plot))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT value (RFC 5545 §3.3.11).
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// fold splits a content line into 75-octet chunks joined by CRLF and a
+// space (RFC 5545 §3.1), without cutting a UTF-8 sequence in half.
+func fold(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			// The leading space counts towards the next line's length.
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
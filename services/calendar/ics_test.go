@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteICS(t *testing.T) {
+	eps := []Episode{{
+		VideoID: "tt1190634",
+		Series:  "The Boys",
+		Season:  4,
+		Episode: 5,
+		Title:   "Beware the Jabberwock, My Son",
+		Plot:    "Hughie; Annie\nand everyone else",
+		AirDate: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+	}}
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, "Upcoming episodes", "webtor.io", eps, testNow); err != nil {
+		t.Fatalf("WriteICS: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:tt1190634-s04e05@webtor.io\r\n",
+		"DTSTART;VALUE=DATE:20260305\r\n",
+		"DTEND;VALUE=DATE:20260306\r\n",
+		"SUMMARY:The Boys S04E05: Beware the Jabberwock\\, My Son\r\n",
+		"DESCRIPTION:Hughie\\; Annie\\nand everyone else\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("feed has a bare LF line ending")
+	}
+}
+
+// TestFold: long lines are folded at 75 octets, never inside a multi-byte
+// character, and unfold back to the original.
+func TestFold(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("Серия ", 30)
+	folded := fold(long)
+	for _, l := range strings.Split(folded, "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line of %d octets: %q", len(l), l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line cut inside a character: %q", l)
+		}
+	}
+	if got := strings.ReplaceAll(folded, "\r\n ", ""); got != long {
+		t.Errorf("unfolded %q, want %q", got, long)
+	}
+	if fold("SUMMARY:short") != "SUMMARY:short" {
+		t.Error("a short line was folded")
+	}
+}
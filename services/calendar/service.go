@@ -0,0 +1,222 @@
+// Package calendar builds the per-user iCalendar feed of upcoming episodes.
+//
+// The Discover calendar shows what airs soon, but only in the browser and
+// only from Cinemeta. This is the same idea for the series a user actually
+// follows — the ones they are subscribed to, have in their library or keep
+// on their watchlist — published as an .ics URL that Google Calendar, Apple
+// Calendar and the like poll on their own. The URL carries a per-user
+// access token (see handlers/calendar), so it is private without a login
+// and rotating the token revokes it.
+//
+// Air dates are episode_metadata rows as TMDB Episodes produces them, kept
+// fresh through the Enricher's UpcomingEpisodes; what episode_metadata
+// already holds is the fallback when TMDB cannot be reached.
+package calendar
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/lazymap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+const (
+	// TokenName is the access_token row the feed URL is issued under.
+	TokenName = "calendar"
+	// Scope is the only thing a calendar token may do: read the feed.
+	Scope = "calendar:read"
+)
+
+const (
+	// lookBack keeps an episode in the feed for a week after it aired, so
+	// it does not vanish from the calendar the morning after.
+	lookBack = 7 * 24 * time.Hour
+	// lookAhead is as far forward as the feed goes. TMDB rarely has dates
+	// further out than that, and those it has tend to move.
+	lookAhead = 90 * 24 * time.Hour
+	// maxSeries bounds the work one feed can cause: a season request per
+	// series per refresh on a cold cache.
+	maxSeries = 100
+	// fetchConcurrency bounds parallel episode lookups per feed.
+	fetchConcurrency = 4
+)
+
+// Series is one followed season. Season 0 means whichever season is
+// airing — library and watchlist entries follow the series, only a
+// season subscription names a season.
+type Series struct {
+	VideoID string
+	Season  int
+	Title   string
+}
+
+// Episode is one entry of the feed.
+type Episode struct {
+	VideoID string
+	Series  string
+	Season  int
+	Episode int
+	Title   string
+	Plot    string
+	AirDate time.Time
+}
+
+// episodeSource is the Enricher, as far as the feed needs it.
+type episodeSource interface {
+	UpcomingEpisodes(ctx context.Context, videoID string, season int) ([]*models.EpisodeMetadata, error)
+}
+
+// store is the database behind the feed. An interface so the rules —
+// which series, which window, what happens when TMDB fails — can be
+// tested without Postgres.
+type store interface {
+	// Followed lists what the user follows, subscriptions first, at most
+	// limit entries.
+	Followed(ctx context.Context, userID uuid.UUID, limit int) ([]Series, error)
+	// StoredEpisodes reads a season from episode_metadata.
+	StoredEpisodes(ctx context.Context, videoID string, season int16) ([]models.EpisodeMetadata, error)
+}
+
+type Service struct {
+	*lazymap.LazyMap[[]Episode]
+	store    store
+	episodes episodeSource
+	now      func() time.Time
+}
+
+// New returns the feed service. Feeds are cached for an hour: calendar
+// apps poll on their own schedule, some of them every few minutes, and
+// none of them needs fresher data than that. The cache holds a feed per
+// subscribed user, plots included, so it is capped rather than left to
+// grow with the user base.
+func New(pg *cs.PG, episodes episodeSource) *Service {
+	return newService(pgStore{pg: pg}, episodes)
+}
+
+func newService(st store, episodes episodeSource) *Service {
+	return &Service{
+		LazyMap: lazymap.New[[]Episode](&lazymap.Config{
+			Expire:      time.Hour,
+			ErrorExpire: 30 * time.Second,
+			Capacity:    1000,
+		}),
+		store:    st,
+		episodes: episodes,
+		now:      time.Now,
+	}
+}
+
+// Upcoming returns the user's feed, sorted by air date.
+func (s *Service) Upcoming(ctx context.Context, userID uuid.UUID) ([]Episode, error) {
+	return s.LazyMap.Get(userID.String(), func() ([]Episode, error) {
+		return s.build(ctx, userID)
+	})
+}
+
+func (s *Service) build(ctx context.Context, userID uuid.UUID) ([]Episode, error) {
+	followed, err := s.store.Followed(ctx, userID, maxSeries)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	from := now.Add(-lookBack)
+	to := now.Add(lookAhead)
+
+	results := make([][]Episode, len(followed))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(fetchConcurrency)
+	for i, f := range followed {
+		g.Go(func() error {
+			eps := s.season(gctx, f)
+			for _, ep := range eps {
+				if ep.AirDate == nil || ep.AirDate.Before(from) || ep.AirDate.After(to) {
+					continue
+				}
+				results[i] = append(results[i], toEpisode(f, ep))
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// A season subscription and a library entry of the same series name
+	// the same episodes; the first one wins.
+	seen := map[string]struct{}{}
+	out := make([]Episode, 0)
+	for _, eps := range results {
+		for _, ep := range eps {
+			if _, ok := seen[ep.UID()]; ok {
+				continue
+			}
+			seen[ep.UID()] = struct{}{}
+			out = append(out, ep)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].AirDate.Equal(out[j].AirDate) {
+			return out[i].AirDate.Before(out[j].AirDate)
+		}
+		if out[i].Series != out[j].Series {
+			return out[i].Series < out[j].Series
+		}
+		return out[i].Episode < out[j].Episode
+	})
+	return out, nil
+}
+
+// season reads one followed season. A failed lookup costs that series its
+// entries, not the whole feed: with a named season what episode_metadata
+// holds stands in; with season 0 there is nothing to say which season
+// that would be, and the series is left out until the next refresh.
+func (s *Service) season(ctx context.Context, f Series) []*models.EpisodeMetadata {
+	eps, err := s.episodes.UpcomingEpisodes(ctx, f.VideoID, f.Season)
+	if err == nil && eps != nil {
+		return eps
+	}
+	if err != nil {
+		log.WithError(err).
+			WithField("video_id", f.VideoID).
+			WithField("season", f.Season).
+			Warn("failed to get upcoming episodes")
+	}
+	if f.Season == 0 {
+		return nil
+	}
+	stored, err := s.store.StoredEpisodes(ctx, f.VideoID, int16(f.Season))
+	if err != nil {
+		log.WithError(err).
+			WithField("video_id", f.VideoID).
+			Warn("failed to read stored episodes")
+		return nil
+	}
+	out := make([]*models.EpisodeMetadata, len(stored))
+	for i := range stored {
+		out[i] = &stored[i]
+	}
+	return out
+}
+
+func toEpisode(f Series, ep *models.EpisodeMetadata) Episode {
+	e := Episode{
+		VideoID: f.VideoID,
+		Series:  f.Title,
+		Season:  int(ep.Season),
+		Episode: int(ep.Episode),
+		AirDate: *ep.AirDate,
+	}
+	if ep.Title != nil {
+		e.Title = *ep.Title
+	}
+	if ep.Plot != nil {
+		e.Plot = *ep.Plot
+	}
+	return e
+}
@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+type fakeStore struct {
+	followed []Series
+	stored   map[string][]models.EpisodeMetadata
+}
+
+func (f *fakeStore) Followed(_ context.Context, _ uuid.UUID, limit int) ([]Series, error) {
+	if len(f.followed) > limit {
+		return f.followed[:limit], nil
+	}
+	return f.followed, nil
+}
+
+func (f *fakeStore) StoredEpisodes(_ context.Context, videoID string, _ int16) ([]models.EpisodeMetadata, error) {
+	return f.stored[videoID], nil
+}
+
+type fakeEpisodes struct {
+	byID   map[string][]*models.EpisodeMetadata
+	failed map[string]bool
+}
+
+func (f *fakeEpisodes) UpcomingEpisodes(_ context.Context, videoID string, season int) ([]*models.EpisodeMetadata, error) {
+	if f.failed[videoID] {
+		return nil, errors.New("tmdb is down")
+	}
+	return f.byID[videoID], nil
+}
+
+var testNow = time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+
+func ep(videoID string, season, episode int, in time.Duration) *models.EpisodeMetadata {
+	at := testNow.Add(in).Truncate(24 * time.Hour)
+	return &models.EpisodeMetadata{VideoID: videoID, Season: int16(season), Episode: int16(episode), AirDate: &at}
+}
+
+func newTestService(st store, src episodeSource) *Service {
+	s := newService(st, src)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// TestUpcomingWindow: the feed keeps last week and the next three months,
+// in air-date order, and drops episodes without a date.
+func TestUpcomingWindow(t *testing.T) {
+	undated := &models.EpisodeMetadata{VideoID: "tt1", Season: 2, Episode: 9}
+	src := &fakeEpisodes{byID: map[string][]*models.EpisodeMetadata{
+		"tt1": {
+			ep("tt1", 2, 1, -30*24*time.Hour),
+			ep("tt1", 2, 2, -3*24*time.Hour),
+			ep("tt1", 2, 4, 14*24*time.Hour),
+			ep("tt1", 2, 3, 7*24*time.Hour),
+			ep("tt1", 2, 5, 120*24*time.Hour),
+			undated,
+		},
+	}}
+	s := newTestService(&fakeStore{followed: []Series{{VideoID: "tt1", Title: "Severance"}}}, src)
+
+	got, err := s.Upcoming(context.Background(), uuid.NewV4())
+	if err != nil {
+		t.Fatalf("Upcoming: %v", err)
+	}
+	var order []int
+	for _, e := range got {
+		order = append(order, e.Episode)
+	}
+	if len(order) != 3 || order[0] != 2 || order[1] != 3 || order[2] != 4 {
+		t.Errorf("episodes %v, want [2 3 4]", order)
+	}
+	if len(got) > 0 && got[0].Series != "Severance" {
+		t.Errorf("series title %q, want it carried from the followed entry", got[0].Series)
+	}
+}
+
+// TestUpcomingDedupes: a season subscription and a library entry of the
+// same series produce each episode once.
+func TestUpcomingDedupes(t *testing.T) {
+	src := &fakeEpisodes{byID: map[string][]*models.EpisodeMetadata{
+		"tt1": {ep("tt1", 4, 1, 24*time.Hour), ep("tt1", 4, 2, 8*24*time.Hour)},
+	}}
+	st := &fakeStore{followed: []Series{
+		{VideoID: "tt1", Season: 4, Title: "The Boys"},
+		{VideoID: "tt1", Title: "The Boys"},
+	}}
+	got, err := newTestService(st, src).Upcoming(context.Background(), uuid.NewV4())
+	if err != nil {
+		t.Fatalf("Upcoming: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d episodes, want 2", len(got))
+	}
+}
+
+// TestUpcomingFallsBackToStored: when TMDB fails, a named season is served
+// from episode_metadata and the rest of the feed is unaffected; a series
+// followed without a season has nothing to fall back on and is left out.
+func TestUpcomingFallsBackToStored(t *testing.T) {
+	stored := *ep("tt1", 3, 5, 2*24*time.Hour)
+	src := &fakeEpisodes{
+		byID:   map[string][]*models.EpisodeMetadata{"tt3": {ep("tt3", 1, 1, 24*time.Hour)}},
+		failed: map[string]bool{"tt1": true, "tt2": true},
+	}
+	st := &fakeStore{
+		followed: []Series{{VideoID: "tt1", Season: 3}, {VideoID: "tt2"}, {VideoID: "tt3"}},
+		stored: map[string][]models.EpisodeMetadata{
+			"tt1": {stored},
+			"tt2": {*ep("tt2", 1, 1, 24*time.Hour)},
+		},
+	}
+	got, err := newTestService(st, src).Upcoming(context.Background(), uuid.NewV4())
+	if err != nil {
+		t.Fatalf("Upcoming: %v", err)
+	}
+	ids := map[string]bool{}
+	for _, e := range got {
+		ids[e.VideoID] = true
+	}
+	if !ids["tt1"] || ids["tt2"] || !ids["tt3"] || len(got) != 2 {
+		t.Errorf("feed %+v, want tt1 from storage and tt3 from TMDB", got)
+	}
+}
@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	cs "github.com/webtor-io/common-services"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// pgStore is the production store.
+type pgStore struct{ pg *cs.PG }
+
+// Followed gathers the three sources in the order a user would rank them:
+// a season subscription is the most deliberate, a library entry says they
+// watch it, a watchlist entry that they mean to. A series in several of
+// them is listed once per distinct season.
+func (s pgStore) Followed(ctx context.Context, userID uuid.UUID, limit int) ([]Series, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	out := make([]Series, 0)
+	seen := map[string]struct{}{}
+	add := func(videoID string, season int, title string) {
+		if videoID == "" || len(out) >= limit {
+			return
+		}
+		key := fmt.Sprintf("%s:%d", videoID, season)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		out = append(out, Series{VideoID: videoID, Season: season, Title: title})
+	}
+
+	subs, err := models.GetUserReleaseSubscriptions(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		if sub.Kind != models.ReleaseSubscriptionKindSeason || sub.Season == nil || !sub.Enabled || sub.IsCompleted() {
+			continue
+		}
+		title := ""
+		if sub.Title != nil {
+			title = *sub.Title
+		}
+		add(sub.VideoID, int(*sub.Season), title)
+	}
+
+	lib, err := models.GetLibrarySeriesList(ctx, db, userID, models.SortTypeRecentlyAdded, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, ser := range lib {
+		if ser.SeriesMetadata == nil || ser.SeriesMetadata.VideoMetadata == nil {
+			continue
+		}
+		add(ser.SeriesMetadata.VideoID, 0, ser.SeriesMetadata.Title)
+	}
+
+	wl, err := models.ListSeriesWatchlistItems(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range wl {
+		add(it.VideoID, 0, it.Title)
+	}
+	return out, nil
+}
+
+func (s pgStore) StoredEpisodes(ctx context.Context, videoID string, season int16) ([]models.EpisodeMetadata, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	return models.ListEpisodeMetadataBySeason(ctx, db, videoID, season)
+}
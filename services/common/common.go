@@ -60,6 +60,7 @@ var (
 	SessionSecretFlag = "secret"
 	DisableWebDAVFlag = "disable-webdav"
 	DisableS3Flag     = "disable-s3"
+	DisableBrowseFlag = "disable-browse"
 	S3SecretFlag      = "s3-signing-secret"
 	S3DomainFlag      = "s3-domain"
 	DisableAPIFlag    = "disable-api"
@@ -139,6 +140,11 @@ func RegisterFlags(f []cli.Flag) []cli.Flag {
 			Usage:  "disable s3",
 			EnvVar: "DISABLE_S3",
 		},
+		cli.BoolFlag{
+			Name:   DisableBrowseFlag,
+			Usage:  "disable the browser index of the library",
+			EnvVar: "DISABLE_BROWSE",
+		},
 		cli.StringFlag{
 			Name: S3DomainFlag,
 			// Hostnames that serve the S3 API at their root, comma-separated.
@@ -0,0 +1,276 @@
+// Package content_proxy streams torrent content through web-ui for file
+// protocol clients that cannot follow a redirect to the content URL.
+//
+// WebDAV and S3 answer a GET with a 307 to the streaming chain, which keeps
+// the bytes off this process. Some clients fail on a cross-host redirect —
+// Windows Explorer, several smart-TV players, older s3fs — and for those the
+// only way to play is to fetch the content ourselves and pass it on. That
+// costs web-ui bandwidth, so it is opt-in (per token, or by User-Agent), runs
+// through a fixed buffer per stream and is held to the user's tier rate.
+package content_proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"github.com/webtor-io/lazymap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	userAgentsFlag = "content-proxy-user-agents"
+	// bufferSize is what one stream holds in memory at a time. It is also the
+	// limiter burst, so a throttled stream writes in chunks of at most this.
+	bufferSize = 64 << 10
+)
+
+// DefaultUserAgents are clients known not to follow a cross-host redirect.
+// Matched as case-insensitive substrings.
+var DefaultUserAgents = []string{
+	"Microsoft-WebDAV-MiniRedir",
+	"DavClnt",
+	"Tizen",
+	"Web0S",
+	"webOS",
+	"s3fs/1.8",
+}
+
+// forwardedRequestHeaders are what makes a proxied GET behave like the
+// original: ranges for seeking, dates for conditional requests. ETag
+// validators stay behind — the client has ours, which upstream has never
+// heard of — and so does upstream's ETag on the way back.
+var forwardedRequestHeaders = []string{
+	"Range",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+}
+
+var forwardedResponseHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Range",
+	"Accept-Ranges",
+	"Last-Modified",
+}
+
+func RegisterFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   userAgentsFlag,
+			Usage:  "user agents always served in proxy mode by webdav and s3 (comma-separated substrings)",
+			Value:  strings.Join(DefaultUserAgents, ","),
+			EnvVar: "CONTENT_PROXY_USER_AGENTS",
+		},
+	)
+}
+
+// Proxy holds what proxied streams share: the upstream client, the buffers
+// and one bandwidth bucket per user.
+type Proxy struct {
+	cl         *http.Client
+	userAgents []string
+	bufs       sync.Pool
+	buckets    *lazymap.LazyMap[*rate.Limiter]
+}
+
+func New(c *cli.Context) *Proxy {
+	var uas []string
+	for _, ua := range strings.Split(c.String(userAgentsFlag), ",") {
+		if ua = strings.TrimSpace(ua); ua != "" {
+			uas = append(uas, ua)
+		}
+	}
+	return NewWith(uas)
+}
+
+// NewWith builds a proxy from an explicit User-Agent list — the flag-free
+// path, used directly by tests.
+func NewWith(userAgents []string) *Proxy {
+	return &Proxy{
+		cl: &http.Client{
+			// No overall timeout: a stream lasts as long as the film. The
+			// header timeout catches an upstream that never answers.
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 30 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+				MaxIdleConnsPerHost:   16,
+			},
+		},
+		userAgents: userAgents,
+		bufs: sync.Pool{
+			New: func() any {
+				b := make([]byte, bufferSize)
+				return &b
+			},
+		},
+		buckets: lazymap.New[*rate.Limiter](&lazymap.Config{
+			// A bucket idle this long has long since refilled to full;
+			// recreating it fresh is indistinguishable from having kept it.
+			Expire: 10 * time.Minute,
+		}),
+	}
+}
+
+// MatchUserAgent reports whether a client is known to need proxy mode.
+func (s *Proxy) MatchUserAgent(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, m := range s.userAgents {
+		if strings.Contains(ua, strings.ToLower(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// For returns the stream settings for a request that has to be proxied —
+// its token opted in or its client is known to need it — and nil when the
+// client gets the usual redirect. tierRate is the rate from the api claims
+// ("10M", in megabits per second); empty means unlimited.
+func (s *Proxy) For(r *http.Request, optIn bool, userID string, tierRate string) *Stream {
+	if s == nil || (!optIn && !s.MatchUserAgent(r.UserAgent())) {
+		return nil
+	}
+	return &Stream{p: s, UserID: userID, BytesPerSec: parseRate(tierRate)}
+}
+
+// parseRate reads a claims rate ("10M" megabits per second) as bytes per
+// second; anything else means no limit.
+func parseRate(r string) int64 {
+	r = strings.TrimSpace(r)
+	if !strings.HasSuffix(r, "M") || len(r) < 2 {
+		return 0
+	}
+	n, err := strconv.ParseInt(r[:len(r)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n * 1_000_000 / 8
+}
+
+// Stream is one request's proxying decision.
+type Stream struct {
+	p           *Proxy
+	UserID      string
+	BytesPerSec int64
+}
+
+type streamContextKey struct{}
+
+// WithStream marks ctx for proxy mode; a nil st leaves it as it is.
+func WithStream(ctx context.Context, st *Stream) context.Context {
+	if st == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, streamContextKey{}, st)
+}
+
+// FromContext returns the request's stream, or nil when the protocol layer
+// should redirect as usual.
+func FromContext(ctx context.Context) *Stream {
+	st, _ := ctx.Value(streamContextKey{}).(*Stream)
+	return st
+}
+
+// bucket is shared by every proxied stream of a user, so the tier rate holds
+// for the user however many files they open at once. It is keyed by rate
+// too: a tier change takes effect on the next request.
+func (s *Stream) bucket() *rate.Limiter {
+	if s.BytesPerSec <= 0 {
+		return nil
+	}
+	key := s.UserID + "|" + strconv.FormatInt(s.BytesPerSec, 10)
+	b, _ := s.p.buckets.Get(key, func() (*rate.Limiter, error) {
+		return rate.NewLimiter(rate.Limit(s.BytesPerSec), bufferSize), nil
+	})
+	return b
+}
+
+// Serve answers r with the content at target. An error means nothing has
+// been written yet and the caller still owns the response; once upstream
+// has answered, failures are only logged.
+func (s *Stream) Serve(w http.ResponseWriter, r *http.Request, target *url.URL) error {
+	method := http.MethodGet
+	if r.Method == http.MethodHead {
+		method = http.MethodHead
+	}
+	req, err := http.NewRequestWithContext(r.Context(), method, target.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to make content request")
+	}
+	for _, h := range forwardedRequestHeaders {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	res, err := s.p.cl.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to request content")
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("content request failed with status %d", res.StatusCode)
+	}
+	for _, h := range forwardedResponseHeaders {
+		if v := res.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(res.StatusCode)
+	if method == http.MethodHead {
+		return nil
+	}
+
+	start := time.Now()
+	n, err := s.copy(r.Context(), w, res.Body)
+	l := log.WithField("user_id", s.UserID).
+		WithField("bytes", n).
+		WithField("duration", time.Since(start).Round(time.Millisecond)).
+		WithField("rate", s.BytesPerSec)
+	if err != nil && r.Context().Err() == nil {
+		l.WithError(err).Warn("proxied stream failed")
+		return nil
+	}
+	l.Info("proxied stream finished")
+	return nil
+}
+
+func (s *Stream) copy(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	bp := s.p.bufs.Get().(*[]byte)
+	defer s.p.bufs.Put(bp)
+	buf := *bp
+	bucket := s.bucket()
+	var total int64
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			if bucket != nil {
+				if err := bucket.WaitN(ctx, n); err != nil {
+					return total, err
+				}
+			}
+			wn, werr := w.Write(buf[:n])
+			total += int64(wn)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		if rerr == io.EOF {
+			return total, nil
+		}
+		if rerr != nil {
+			return total, rerr
+		}
+	}
+}
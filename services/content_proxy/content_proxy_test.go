@@ -0,0 +1,104 @@
+package content_proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func upstream(t *testing.T) *url.URL {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("our ETag validator leaked upstream")
+		}
+		w.Header().Set("ETag", `"upstream"`)
+		w.Header().Set("Content-Type", "video/x-matroska")
+		http.ServeContent(w, r, "video.mkv", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL + "/video.mkv")
+	return u
+}
+
+func TestFor(t *testing.T) {
+	p := NewWith(DefaultUserAgents)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "rclone/v1.66.0")
+	if st := p.For(r, false, "u", "10M"); st != nil {
+		t.Errorf("rclone follows redirects, got %+v", st)
+	}
+	st := p.For(r, true, "u", "10M")
+	if st == nil || st.BytesPerSec != 1_250_000 {
+		t.Errorf("opted-in token: got %+v", st)
+	}
+	r.Header.Set("User-Agent", "Microsoft-WebDAV-MiniRedir/10.0.19045")
+	if st := p.For(r, false, "u", ""); st == nil || st.BytesPerSec != 0 {
+		t.Errorf("windows explorer: got %+v", st)
+	}
+	var np *Proxy
+	if np.For(r, true, "u", "") != nil {
+		t.Error("nil proxy must never proxy")
+	}
+}
+
+func TestServeRange(t *testing.T) {
+	st := NewWith(nil).For(httptest.NewRequest(http.MethodGet, "/", nil), true, "u", "")
+	r := httptest.NewRequest(http.MethodGet, "/all/video.mkv", nil)
+	r.Header.Set("Range", "bytes=10-19")
+	r.Header.Set("If-None-Match", `"ours"`)
+	w := httptest.NewRecorder()
+	if err := st.Serve(w, r, upstream(t)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status %d", w.Code)
+	}
+	if got := w.Body.String(); got != content[10:20] {
+		t.Errorf("body %q", got)
+	}
+	if w.Header().Get("Content-Range") != "bytes 10-19/36" || w.Header().Get("Content-Type") != "video/x-matroska" {
+		t.Errorf("headers %v", w.Header())
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Errorf("upstream ETag passed through: %s", w.Header().Get("ETag"))
+	}
+}
+
+func TestServeIsThrottled(t *testing.T) {
+	// 1M is 125000 bytes per second, but the bucket starts full with a burst
+	// of bufferSize — so a body just over one burst has to wait.
+	st := NewWith(nil).For(httptest.NewRequest(http.MethodGet, "/", nil), true, "u", "1M")
+	body := strings.Repeat("x", bufferSize+12_500)
+	start := time.Now()
+	n, err := st.copy(context.Background(), io.Discard, strings.NewReader(body))
+	if err != nil || n != int64(len(body)) {
+		t.Fatalf("copied %d: %v", n, err)
+	}
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Errorf("copy took %s, the tier rate was not applied", d)
+	}
+}
+
+func TestServeUpstreamFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	st := NewWith(nil).For(httptest.NewRequest(http.MethodGet, "/", nil), true, "u", "")
+	w := httptest.NewRecorder()
+	if err := st.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil), u); err == nil {
+		t.Fatal("expected an error for a failing upstream")
+	}
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("response written on failure: %d %q", w.Code, w.Body.String())
+	}
+}
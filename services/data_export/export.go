@@ -50,6 +50,7 @@ type Export struct {
 	StreamingBackends       []StreamingBackend           `json:"streaming_backends"`
 	UserSubtitles           []UserSubtitleItem           `json:"user_subtitles"`
 	AccessTokens            []AccessTokenItem            `json:"access_tokens"`
+	SSHKeys                 []SSHKeyItem                 `json:"ssh_keys"`
 	// PendingDeviceAuth lists in-flight device authorizations. Rows live
 	// minutes (confirmed ones are deleted on the device's next poll), so the
 	// list is almost always empty — included because the table is user-keyed.
@@ -212,7 +213,7 @@ type EmbedDomainItem struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// StreamingBackend mirrors the user-provided RealDebrid/Torbox config.
+// StreamingBackend mirrors the user-provided debrid backend config.
 // AccessToken is included because the user supplied it and can see it on
 // the profile page; the export is delivered over an authenticated session.
 type StreamingBackend struct {
@@ -249,6 +250,16 @@ type AccessTokenItem struct {
 	CreatedAt time.Time  `json:"created_at"`
 }
 
+// SSHKeyItem is a public key uploaded for SFTP logins. Public halves only —
+// the private key never reaches us.
+type SSHKeyItem struct {
+	Name        string     `json:"name"`
+	PublicKey   string     `json:"public_key"`
+	Fingerprint string     `json:"fingerprint"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
 // DeviceAuthItem is one in-flight device authorization (a confirmed code the
 // device has not picked up yet).
 type DeviceAuthItem struct {
@@ -677,6 +688,21 @@ func (e *Export) fillSubtitlesAndTokens(ctx context.Context, db *pg.DB, uID uuid
 		})
 	}
 
+	keys, err := models.ListUserSSHKeys(ctx, db, uID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load ssh keys")
+	}
+	e.SSHKeys = make([]SSHKeyItem, 0, len(keys))
+	for _, k := range keys {
+		e.SSHKeys = append(e.SSHKeys, SSHKeyItem{
+			Name:        k.Name,
+			PublicKey:   k.PublicKey,
+			Fingerprint: k.Fingerprint,
+			LastUsedAt:  k.LastUsedAt,
+			CreatedAt:   k.CreatedAt,
+		})
+	}
+
 	das, err := models.ListUserDeviceAuth(ctx, db, uID)
 	if err != nil {
 		return errors.Wrap(err, "failed to load device authorizations")
@@ -0,0 +1,162 @@
+package dlna
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/services/libapi"
+)
+
+// Client talks to the public JSON API (docs/api.md) on behalf of one device
+// key. It is everything the media server knows about Webtor: no database, no
+// shared secret, nothing a user's own machine could not be trusted with.
+type Client struct {
+	base string
+	key  string
+	cl   *http.Client
+}
+
+func NewClient(base string, key string) *Client {
+	return &Client{
+		base: strings.TrimSuffix(base, "/"),
+		key:  key,
+		cl: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+func (s *Client) do(ctx context.Context, method string, path string, q url.Values, in any, out any) error {
+	u := s.base + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.key != "" {
+		req.Header.Set("Authorization", "Bearer "+s.key)
+	}
+	res, err := s.cl.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to %s %s", method, path)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode >= 300 {
+		var er libapi.ErrorResponse
+		if err := json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&er); err != nil || er.Error.Code == "" {
+			return &libapi.Error{Status: res.StatusCode, Code: http.StatusText(res.StatusCode), Message: method + " " + path}
+		}
+		er.Error.Status = res.StatusCode
+		return &er.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.Wrapf(json.NewDecoder(res.Body).Decode(out), "failed to decode %s", path)
+}
+
+// Library pages one library section (libapi.LibraryType*).
+func (s *Client) Library(ctx context.Context, typ string, limit int, offset int) (*libapi.LibraryListResponse, error) {
+	q := url.Values{}
+	q.Set("type", typ)
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	var res libapi.LibraryListResponse
+	if err := s.do(ctx, http.MethodGet, "/library", q, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// LibraryItem returns one library entry.
+func (s *Client) LibraryItem(ctx context.Context, resourceID string) (*libapi.LibraryItem, error) {
+	var res libapi.LibraryItem
+	if err := s.do(ctx, http.MethodGet, "/library/"+url.PathEscape(resourceID), nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// List lists one directory of a torrent.
+func (s *Client) List(ctx context.Context, resourceID string, path string, limit int, offset int) (*ra.ListResponse, error) {
+	q := url.Values{}
+	q.Set("output", "tree")
+	q.Set("path", path)
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	var res ra.ListResponse
+	if err := s.do(ctx, http.MethodGet, "/resource/"+url.PathEscape(resourceID)+"/list", q, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DownloadURL resolves the export chain's download URL of a file. The URL
+// carries its own authorization and is short-lived, so it is resolved per
+// playback, never cached.
+func (s *Client) DownloadURL(ctx context.Context, resourceID string, contentID string) (string, error) {
+	q := url.Values{}
+	q.Set("types", "download")
+	var res ra.ExportResponse
+	if err := s.do(ctx, http.MethodGet, "/resource/"+url.PathEscape(resourceID)+"/export/"+url.PathEscape(contentID), q, nil, &res); err != nil {
+		return "", err
+	}
+	e, ok := res.ExportItems["download"]
+	if !ok || e.URL == "" {
+		return "", errors.New("no download export for the file")
+	}
+	return e.URL, nil
+}
+
+// DeviceCode starts device authorization.
+func (s *Client) DeviceCode(ctx context.Context, name string) (*libapi.DeviceCodeResponse, error) {
+	var res libapi.DeviceCodeResponse
+	if err := s.do(ctx, http.MethodPost, "/device/code", nil, &libapi.DeviceCodeRequest{Name: name}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DeviceToken polls for the key. Pending, slow_down and expiry come back as a
+// *libapi.Error with the matching code.
+func (s *Client) DeviceToken(ctx context.Context, deviceCode string) (*libapi.DeviceTokenResponse, error) {
+	var res libapi.DeviceTokenResponse
+	if err := s.do(ctx, http.MethodPost, "/device/token", nil, &libapi.DeviceTokenRequest{DeviceCode: deviceCode}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// errorCode is the API error code behind err, or "".
+func errorCode(err error) string {
+	var e *libapi.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
@@ -0,0 +1,299 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// UPnP error codes a control request may answer with.
+const (
+	upnpInvalidAction = 401
+	upnpInvalidArgs   = 402
+	upnpActionFailed  = 501
+	upnpNoSuchObject  = 701
+)
+
+type upnpError struct {
+	code int
+	msg  string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("%d %s", e.code, e.msg)
+}
+
+// soapRequest is an action call, reduced to its name and flat arguments —
+// the only shape UPnP actions have.
+type soapRequest struct {
+	Body struct {
+		Action struct {
+			XMLName xml.Name
+			Args    []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+func parseSOAP(r *http.Request) (string, map[string]string, error) {
+	var req soapRequest
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		return "", nil, errors.Wrap(err, "failed to decode soap request")
+	}
+	args := map[string]string{}
+	for _, a := range req.Body.Action.Args {
+		args[a.XMLName.Local] = a.Value
+	}
+	return req.Body.Action.XMLName.Local, args, nil
+}
+
+// arg is one action argument; the values are ordered pairs.
+type arg struct {
+	name  string
+	value string
+}
+
+func writeSOAP(w http.ResponseWriter, service string, action string, args []arg) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&b, `<u:%sResponse xmlns:u="%s">`, action, service)
+	for _, a := range args {
+		b.WriteString("<" + a.name + ">")
+		_ = xml.EscapeText(&b, []byte(a.value))
+		b.WriteString("</" + a.name + ">")
+	}
+	fmt.Fprintf(&b, `</u:%sResponse>`, action)
+	b.WriteString(`</s:Body></s:Envelope>`)
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("Ext", "")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func writeSOAPFault(w http.ResponseWriter, err error) {
+	var ue *upnpError
+	if !errors.As(err, &ue) {
+		ue = &upnpError{code: upnpActionFailed, msg: "Action Failed"}
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`+
+		`<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, ue.code, ue.msg)
+}
+
+// soapAction is the action named by the SOAPACTION header,
+// "urn:…:service:X:1#Action" with the quotes most clients add.
+func soapAction(r *http.Request) string {
+	a := strings.Trim(r.Header.Get("SOAPACTION"), `"`)
+	if i := strings.LastIndex(a, "#"); i >= 0 {
+		return a[i+1:]
+	}
+	return a
+}
+
+// contentDirectory handles ContentDirectory:1 control requests.
+type contentDirectory struct {
+	src      Source
+	updateID string
+	// streamURL builds the res URL of an item, pointing back at this server.
+	streamURL func(r *http.Request, o *Object) string
+}
+
+func (s *contentDirectory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, args, err := parseSOAP(r)
+	if err != nil {
+		writeSOAPFault(w, &upnpError{code: upnpInvalidAction, msg: "Invalid Action"})
+		return
+	}
+	if h := soapAction(r); h != "" && h != name {
+		writeSOAPFault(w, &upnpError{code: upnpInvalidAction, msg: "Invalid Action"})
+		return
+	}
+	switch name {
+	case "Browse":
+		res, err := s.browse(r, args)
+		if err != nil {
+			if !errors.As(err, new(*upnpError)) {
+				log.WithError(err).WithField("object_id", args["ObjectID"]).Warn("dlna browse failed")
+			}
+			writeSOAPFault(w, err)
+			return
+		}
+		writeSOAP(w, contentDirectoryType, name, res)
+	case "GetSearchCapabilities":
+		writeSOAP(w, contentDirectoryType, name, []arg{{"SearchCaps", ""}})
+	case "GetSortCapabilities":
+		writeSOAP(w, contentDirectoryType, name, []arg{{"SortCaps", ""}})
+	case "GetSystemUpdateID":
+		writeSOAP(w, contentDirectoryType, name, []arg{{"Id", s.updateID}})
+	default:
+		writeSOAPFault(w, &upnpError{code: upnpInvalidAction, msg: "Invalid Action"})
+	}
+}
+
+func (s *contentDirectory) browse(r *http.Request, args map[string]string) ([]arg, error) {
+	id := args["ObjectID"]
+	start, err1 := strconv.Atoi(orZero(args["StartingIndex"]))
+	count, err2 := strconv.Atoi(orZero(args["RequestedCount"]))
+	if id == "" || err1 != nil || err2 != nil || start < 0 || count < 0 {
+		return nil, &upnpError{code: upnpInvalidArgs, msg: "Invalid Args"}
+	}
+	ctx := r.Context()
+	var objs []Object
+	var total int
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		o, err := s.src.Object(ctx, id)
+		if err != nil {
+			return nil, noSuchObject(err)
+		}
+		objs, total = []Object{*o}, 1
+	case "BrowseDirectChildren":
+		var err error
+		objs, total, err = s.src.Children(ctx, id, start, count)
+		if err != nil {
+			return nil, noSuchObject(err)
+		}
+	default:
+		return nil, &upnpError{code: upnpInvalidArgs, msg: "Invalid Args"}
+	}
+	didl, err := s.didl(r, objs)
+	if err != nil {
+		return nil, err
+	}
+	return []arg{
+		{"Result", didl},
+		{"NumberReturned", strconv.Itoa(len(objs))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", s.updateID},
+	}, nil
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+func noSuchObject(err error) error {
+	if errors.Is(err, ErrNoSuchObject) {
+		return &upnpError{code: upnpNoSuchObject, msg: "No such object"}
+	}
+	return err
+}
+
+// DIDL-Lite, the metadata document Browse returns. Prefixed names are written
+// literally: every client matches on the prefixes the spec examples use, so
+// the namespaces are declared once on the root under exactly those.
+type didlLite struct {
+	XMLName    xml.Name        `xml:"DIDL-Lite"`
+	XMLNS      string          `xml:"xmlns,attr"`
+	DC         string          `xml:"xmlns:dc,attr"`
+	UPnP       string          `xml:"xmlns:upnp,attr"`
+	DLNA       string          `xml:"xmlns:dlna,attr"`
+	Containers []didlContainer `xml:"container"`
+	Items      []didlItem      `xml:"item"`
+}
+
+type didlObject struct {
+	ID          string        `xml:"id,attr"`
+	ParentID    string        `xml:"parentID,attr"`
+	Restricted  string        `xml:"restricted,attr"`
+	Title       string        `xml:"dc:title"`
+	Class       string        `xml:"upnp:class"`
+	Date        string        `xml:"dc:date,omitempty"`
+	Description string        `xml:"dc:description,omitempty"`
+	AlbumArt    *didlAlbumArt `xml:"upnp:albumArtURI,omitempty"`
+}
+
+type didlAlbumArt struct {
+	ProfileID string `xml:"dlna:profileID,attr"`
+	URL       string `xml:",chardata"`
+}
+
+type didlContainer struct {
+	didlObject
+	ChildCount *int `xml:"childCount,attr,omitempty"`
+}
+
+type didlItem struct {
+	didlObject
+	Res didlRes `xml:"res"`
+}
+
+type didlRes struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr,omitempty"`
+	URL          string `xml:",chardata"`
+}
+
+// dlnaFlags advertise byte seeking (OP=01) and streaming transfer: the export
+// chain serves ranges, which is what lets a TV scrub.
+const dlnaFlags = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+func (s *contentDirectory) didl(r *http.Request, objs []Object) (string, error) {
+	d := didlLite{
+		XMLNS: "urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/",
+		DC:    "http://purl.org/dc/elements/1.1/",
+		UPnP:  "urn:schemas-upnp-org:metadata-1-0/upnp/",
+		DLNA:  "urn:schemas-dlna-org:metadata-1-0/",
+	}
+	for i := range objs {
+		o := &objs[i]
+		do := didlObject{
+			ID:          o.ID,
+			ParentID:    o.ParentID,
+			Restricted:  "1",
+			Title:       o.Title,
+			Class:       o.Class,
+			Description: o.Plot,
+		}
+		if o.Year != 0 {
+			do.Date = fmt.Sprintf("%04d-01-01", o.Year)
+		}
+		if o.PosterURL != "" {
+			do.AlbumArt = &didlAlbumArt{ProfileID: "JPEG_TN", URL: o.PosterURL}
+		}
+		if o.IsContainer() {
+			c := didlContainer{didlObject: do}
+			if o.ChildCount >= 0 {
+				n := o.ChildCount
+				c.ChildCount = &n
+			}
+			d.Containers = append(d.Containers, c)
+			continue
+		}
+		d.Items = append(d.Items, didlItem{
+			didlObject: do,
+			Res: didlRes{
+				ProtocolInfo: "http-get:*:" + o.MimeType + ":" + dlnaFlags,
+				Size:         o.Size,
+				URL:          s.streamURL(r, o),
+			},
+		})
+	}
+	b, err := xml.Marshal(&d)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal didl")
+	}
+	return string(b), nil
+}
+
+// streamPath is where an item's res URL points: this server, which resolves
+// a fresh export URL on every request.
+func streamPath(o *Object) string {
+	return "/stream/" + url.PathEscape(o.ResourceID) + "/" + url.PathEscape(o.ContentID)
+}
@@ -0,0 +1,131 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeSource struct {
+	objs map[string]Object
+}
+
+func (s *fakeSource) Object(_ context.Context, id string) (*Object, error) {
+	o, ok := s.objs[id]
+	if !ok {
+		return nil, ErrNoSuchObject
+	}
+	return &o, nil
+}
+
+func (s *fakeSource) Children(_ context.Context, id string, offset int, limit int) ([]Object, int, error) {
+	if _, ok := s.objs[id]; !ok {
+		return nil, 0, ErrNoSuchObject
+	}
+	var all []Object
+	for _, o := range s.objs {
+		if o.ParentID == id {
+			all = append(all, o)
+		}
+	}
+	return page(all, offset, limit), len(all), nil
+}
+
+func (s *fakeSource) StreamURL(_ context.Context, resourceID string, contentID string) (string, error) {
+	return "https://cdn.example/" + resourceID + "/" + contentID, nil
+}
+
+const browseEnvelope = `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">
+<ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag><Filter>*</Filter>
+<StartingIndex>0</StartingIndex><RequestedCount>0</RequestedCount><SortCriteria></SortCriteria>
+</u:Browse></s:Body></s:Envelope>`
+
+func browse(t *testing.T, cd *contentDirectory, id string, flag string) (*httptest.ResponseRecorder, map[string]string) {
+	t.Helper()
+	body := fmt.Sprintf(browseEnvelope, id, flag)
+	req := httptest.NewRequest(http.MethodPost, contentDirectoryCtlPath, strings.NewReader(body))
+	req.Header.Set("SOAPACTION", `"`+contentDirectoryType+`#Browse"`)
+	rec := httptest.NewRecorder()
+	cd.ServeHTTP(rec, req)
+	var env struct {
+		Body struct {
+			Inner struct {
+				Fields []struct {
+					XMLName xml.Name
+					Value   string `xml:",chardata"`
+				} `xml:",any"`
+			} `xml:",any"`
+		}
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("bad soap response: %v\n%s", err, rec.Body.String())
+	}
+	out := map[string]string{}
+	for _, f := range env.Body.Inner.Fields {
+		out[f.XMLName.Local] = f.Value
+	}
+	return rec, out
+}
+
+func TestBrowse(t *testing.T) {
+	src := &fakeSource{objs: map[string]Object{
+		RootID:         {ID: RootID, ParentID: "-1", Title: "Webtor", Class: classFolder, ChildCount: 1},
+		"movies":       {ID: "movies", ParentID: RootID, Title: "Movies", Class: classFolder, ChildCount: -1},
+		"movies/a":     {ID: "movies/a", ParentID: "movies", Title: "Sintel (2010)", Class: classFolder, ChildCount: -1, PosterURL: "https://webtor.io/p.jpg", Year: 2010},
+		"movies/a/x.m": {ID: "movies/a/x.m", ParentID: "movies/a", Title: "x.mkv", Class: classVideo, MimeType: "video/x-matroska", Size: 42, ResourceID: "a", ContentID: "c1"},
+	}}
+	cd := &contentDirectory{
+		src:       src,
+		updateID:  "7",
+		streamURL: func(_ *http.Request, o *Object) string { return "http://10.0.0.2:8200" + streamPath(o) },
+	}
+
+	rec, res := browse(t, cd, "movies/a", "BrowseDirectChildren")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if res["NumberReturned"] != "1" || res["TotalMatches"] != "1" || res["UpdateID"] != "7" {
+		t.Fatalf("unexpected response %v", res)
+	}
+	var d struct {
+		Items []struct {
+			ID    string `xml:"id,attr"`
+			Title string `xml:"title"`
+			Res   struct {
+				ProtocolInfo string `xml:"protocolInfo,attr"`
+				Size         int64  `xml:"size,attr"`
+				URL          string `xml:",chardata"`
+			} `xml:"res"`
+		} `xml:"item"`
+	}
+	if err := xml.Unmarshal([]byte(res["Result"]), &d); err != nil {
+		t.Fatalf("bad didl: %v\n%s", err, res["Result"])
+	}
+	if len(d.Items) != 1 {
+		t.Fatalf("got %d items", len(d.Items))
+	}
+	it := d.Items[0]
+	if it.Title != "x.mkv" || it.Res.Size != 42 || it.Res.URL != "http://10.0.0.2:8200/stream/a/c1" {
+		t.Errorf("unexpected item %+v", it)
+	}
+	if !strings.HasPrefix(it.Res.ProtocolInfo, "http-get:*:video/x-matroska:DLNA.ORG_OP=01") {
+		t.Errorf("protocolInfo %q", it.Res.ProtocolInfo)
+	}
+
+	_, res = browse(t, cd, "movies/a", "BrowseMetadata")
+	if !strings.Contains(res["Result"], "<upnp:albumArtURI dlna:profileID=\"JPEG_TN\">https://webtor.io/p.jpg</upnp:albumArtURI>") ||
+		!strings.Contains(res["Result"], "<dc:date>2010-01-01</dc:date>") {
+		t.Errorf("container metadata missing: %s", res["Result"])
+	}
+
+	rec, _ = browse(t, cd, "movies/missing", "BrowseMetadata")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "<errorCode>701</errorCode>") {
+		t.Errorf("missing object: %d %s", rec.Code, rec.Body.String())
+	}
+}
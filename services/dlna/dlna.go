@@ -0,0 +1,320 @@
+// Package dlna is a UPnP MediaServer (ContentDirectory:1) that puts a user's
+// library on smart TVs and consoles on their LAN.
+//
+// It does not run next to web-ui but on the user's own network, in the same
+// binary (`web-ui dlna`): multicast discovery does not cross the internet, so
+// the server has to sit where the TVs are. That is also why it knows nothing
+// of the database — it reaches the library through the public JSON API with a
+// device key from the device flow, like any other client, and hands the TV
+// export URLs to stream from directly.
+package dlna
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	apiURLFlag    = "dlna-api-url"
+	keyFlag       = "dlna-key"
+	keyFileFlag   = "dlna-key-file"
+	hostFlag      = "dlna-host"
+	portFlag      = "dlna-port"
+	nameFlag      = "dlna-name"
+	interfaceFlag = "dlna-interface"
+	proxyFlag     = "dlna-proxy"
+)
+
+func RegisterFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   apiURLFlag,
+			Usage:  "webtor api base url",
+			Value:  "https://webtor.io/api/v1",
+			EnvVar: "DLNA_API_URL",
+		},
+		cli.StringFlag{
+			Name:   keyFlag,
+			Usage:  "device key (takes precedence over the key file)",
+			EnvVar: "DLNA_KEY",
+		},
+		cli.StringFlag{
+			Name:   keyFileFlag,
+			Usage:  "file the device key is stored in by `dlna login`",
+			Value:  "webtor-dlna.key",
+			EnvVar: "DLNA_KEY_FILE",
+		},
+		cli.StringFlag{
+			Name:   hostFlag,
+			Usage:  "LAN address to listen on and advertise (detected when empty)",
+			EnvVar: "DLNA_HOST",
+		},
+		cli.IntFlag{
+			Name:   portFlag,
+			Usage:  "http port of the media server",
+			Value:  8200,
+			EnvVar: "DLNA_PORT",
+		},
+		cli.StringFlag{
+			Name:   nameFlag,
+			Usage:  "name the server shows up under on TVs",
+			Value:  "Webtor",
+			EnvVar: "DLNA_NAME",
+		},
+		cli.StringFlag{
+			Name:   interfaceFlag,
+			Usage:  "network interface for discovery (system default when empty)",
+			EnvVar: "DLNA_INTERFACE",
+		},
+		cli.BoolFlag{
+			Name: proxyFlag,
+			// Some renderers do not follow redirects on a media URL. Proxying
+			// works with all of them at the cost of the bytes passing through
+			// this machine.
+			Usage:  "relay video through the media server instead of redirecting the TV to it",
+			EnvVar: "DLNA_PROXY",
+		},
+	)
+}
+
+// NewClientFromFlags builds the API client, with the device key when there is
+// one.
+func NewClientFromFlags(c *cli.Context) (*Client, error) {
+	key, err := Key(c)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c.String(apiURLFlag), key), nil
+}
+
+// Key returns the configured device key: the flag, else the key file, else "".
+func Key(c *cli.Context) (string, error) {
+	if k := strings.TrimSpace(c.String(keyFlag)); k != "" {
+		return k, nil
+	}
+	b, err := os.ReadFile(c.String(keyFileFlag))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the device key")
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// SaveKey stores a device key where Key looks for it.
+func SaveKey(c *cli.Context, key string) error {
+	return errors.Wrap(os.WriteFile(c.String(keyFileFlag), []byte(key+"\n"), 0600), "failed to store the device key")
+}
+
+type Server struct {
+	name    string
+	uuid    string
+	host    string
+	port    int
+	iface   *net.Interface
+	proxy   bool
+	src     Source
+	cd      *contentDirectory
+	ssdp    *ssdp
+	srv     *http.Server
+	streams *http.Client
+}
+
+// New builds the server. deviceKey only seeds the device UUID, which has to
+// stay the same across restarts or TVs list the server twice.
+func New(c *cli.Context, src Source, deviceKey string) (*Server, error) {
+	s := &Server{
+		name:  c.String(nameFlag),
+		uuid:  uuid.NewV5(uuid.NamespaceOID, "webtor-dlna:"+deviceKey).String(),
+		host:  c.String(hostFlag),
+		port:  c.Int(portFlag),
+		proxy: c.Bool(proxyFlag),
+		src:   src,
+		// No timeout: a proxied stream lasts as long as the film.
+		streams: &http.Client{},
+	}
+	if name := c.String(interfaceFlag); name != "" {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find interface %s", name)
+		}
+		s.iface = ifi
+	}
+	if s.host == "" {
+		h, err := lanAddress(s.iface)
+		if err != nil {
+			return nil, err
+		}
+		s.host = h
+	}
+	s.cd = &contentDirectory{
+		src: src,
+		// The library changes behind our back; a per-run id at least tells
+		// a TV that a restarted server may have new content.
+		updateID:  strconv.FormatUint(uint64(uint32(time.Now().Unix())), 10),
+		streamURL: func(_ *http.Request, o *Object) string { return s.baseURL() + streamPath(o) },
+	}
+	s.ssdp = &ssdp{
+		uuid:     s.uuid,
+		location: func() string { return s.baseURL() + descriptionPath },
+		iface:    s.iface,
+		maxDelay: 3 * time.Second,
+	}
+	return s, nil
+}
+
+func (s *Server) baseURL() string {
+	return "http://" + net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+// lanAddress is the address other machines on the LAN reach this one at: the
+// one on ifi, or else the source address of the default route.
+func lanAddress(ifi *net.Interface) (string, error) {
+	if ifi != nil {
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read interface addresses")
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil && !n.IP.IsLoopback() {
+				return n.IP.String(), nil
+			}
+		}
+		return "", errors.Errorf("no ipv4 address on %s", ifi.Name)
+	}
+	// Dialing UDP sends nothing; it only picks the route.
+	conn, err := net.Dial("udp4", ssdpAddr.String())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to detect the lan address, set --"+hostFlag)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// Handler serves the description documents, the control endpoints and the
+// stream redirects.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+descriptionPath, s.serveDescription)
+	mux.HandleFunc("GET "+contentDirectorySCPDPath, serveSCPD(contentDirectorySCPD))
+	mux.HandleFunc("GET "+connectionManagerSCPDPath, serveSCPD(connectionManagerSCPD))
+	mux.Handle("POST "+contentDirectoryCtlPath, s.cd)
+	mux.HandleFunc("POST "+connectionManagerCtlPath, connectionManager)
+	mux.HandleFunc(contentDirectoryEvtPath, subscribe)
+	mux.HandleFunc(connectionManagerEvtPath, subscribe)
+	mux.HandleFunc("GET /stream/{resource}/{content}", s.stream)
+	return mux
+}
+
+// subscribe accepts GENA subscriptions without ever sending an event: nothing
+// here changes in a way the server could know about, but several TVs refuse a
+// server whose SUBSCRIBE fails.
+func subscribe(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			sid = "uuid:" + uuid.NewV4().String()
+		}
+		w.Header().Set("SID", sid)
+		w.Header().Set("TIMEOUT", "Second-1800")
+		w.Header().Set("Server", ssdpServer)
+	case "UNSUBSCRIBE":
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// stream resolves a fresh export URL for the item and sends the TV there, or,
+// with --dlna-proxy, relays the bytes.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	u, err := s.src.StreamURL(r.Context(), r.PathValue("resource"), r.PathValue("content"))
+	if err != nil {
+		log.WithError(err).WithField("resource_id", r.PathValue("resource")).Warn("failed to resolve dlna stream")
+		http.Error(w, "failed to resolve the stream", http.StatusBadGateway)
+		return
+	}
+	if !s.proxy {
+		http.Redirect(w, r, u, http.StatusTemporaryRedirect)
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, u, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rg := r.Header.Get("Range"); rg != "" {
+		req.Header.Set("Range", rg)
+	}
+	res, err := s.streams.Do(req)
+	if err != nil {
+		http.Error(w, "failed to open the stream", http.StatusBadGateway)
+		return
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	for _, h := range []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges"} {
+		if v := res.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	// Tells DLNA renderers the stream is seekable by byte range.
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", dlnaFlags)
+	w.WriteHeader(res.StatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, res.Body)
+	}
+}
+
+// Serve announces the server on the LAN and serves until Close. It listens
+// on the address SSDP advertises only, not on every interface.
+func (s *Server) Serve() error {
+	ln, err := net.Listen("tcp4", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return errors.Wrap(err, "failed to dlna listen to tcp connection")
+	}
+	if err := s.ssdp.listen(); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	go s.ssdp.serve()
+	s.srv = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Infof("serving dlna media server %q at %v", s.name, s.baseURL())
+	err = s.srv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return errors.Wrap(err, "failed to serve dlna")
+}
+
+func (s *Server) Close() {
+	log.Info("closing dlna")
+	defer func() {
+		log.Info("dlna closed")
+	}()
+	s.ssdp.close()
+	if s.srv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.srv.Shutdown(ctx)
+	}
+}
@@ -0,0 +1,360 @@
+package dlna
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/webtor-io/lazymap"
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/services/libapi"
+)
+
+// ErrNoSuchObject answers a Browse of an id that does not resolve.
+var ErrNoSuchObject = errors.New("no such object")
+
+// UPnP classes the tree uses.
+const (
+	classFolder = "object.container.storageFolder"
+	classVideo  = "object.item.videoItem"
+)
+
+// RootID is the ContentDirectory root, fixed by the spec.
+const RootID = "0"
+
+// Object is one entry of the ContentDirectory: a container or a playable item.
+type Object struct {
+	ID       string
+	ParentID string
+	Title    string
+	Class    string
+	// ChildCount is unknown (and omitted) when negative.
+	ChildCount int
+	PosterURL  string
+	Plot       string
+	Year       int
+	// Items only.
+	Size       int64
+	MimeType   string
+	ResourceID string
+	ContentID  string
+}
+
+func (s *Object) IsContainer() bool {
+	return strings.HasPrefix(s.Class, "object.container")
+}
+
+// Source is what the ContentDirectory serves.
+type Source interface {
+	// Object describes one id, for BrowseMetadata.
+	Object(ctx context.Context, id string) (*Object, error)
+	// Children pages a container, for BrowseDirectChildren, and returns the
+	// total count alongside.
+	Children(ctx context.Context, id string, offset int, limit int) ([]Object, int, error)
+	// StreamURL resolves where an item's bytes are served from.
+	StreamURL(ctx context.Context, resourceID string, contentID string) (string, error)
+}
+
+// sections are the top-level containers: the same movies and series folders
+// services/libfs serves over WebDAV, S3 and SFTP.
+var sections = []struct {
+	id    string
+	title string
+}{
+	{libapi.LibraryTypeMovies, "Movies"},
+	{libapi.LibraryTypeSeries, "Series"},
+}
+
+// maxDirEntries bounds how much of one torrent directory is read: a directory
+// is filtered down to videos before paging, so it is read whole.
+const maxDirEntries = 5000
+
+// libraryPage is how many library entries one API call asks for.
+const libraryPage = 200
+
+// Library serves the account's movies and series through the JSON API.
+//
+// Ids are paths: "<section>/<infohash>" for a torrent, followed by the path
+// inside it for its folders and files — so any id can be resolved on its own,
+// which BrowseMetadata needs, and a TV that bookmarks one keeps working.
+type Library struct {
+	cl    *Client
+	dirs  *lazymap.LazyMap[[]Object]
+	mux   sync.Mutex
+	known map[string]Object
+}
+
+func NewLibrary(cl *Client) *Library {
+	return &Library{
+		cl: cl,
+		// TVs re-browse the folder they return to, and ask for the metadata of
+		// what they just listed; a short cache keeps that off the API.
+		dirs: lazymap.New[[]Object](&lazymap.Config{
+			Expire:      1 * time.Minute,
+			ErrorExpire: 10 * time.Second,
+			Capacity:    256,
+		}),
+		known: map[string]Object{},
+	}
+}
+
+// splitID breaks an id into section, infohash and the path inside the torrent
+// ("/" for its root).
+func splitID(id string) (section string, resourceID string, p string) {
+	parts := strings.SplitN(id, "/", 3)
+	section = parts[0]
+	if len(parts) > 1 {
+		resourceID = parts[1]
+	}
+	p = "/"
+	if len(parts) > 2 {
+		p = "/" + parts[2]
+	}
+	return
+}
+
+func isSection(id string) bool {
+	for _, s := range sections {
+		if s.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func parentID(id string) string {
+	i := strings.LastIndex(id, "/")
+	if i < 0 {
+		return RootID
+	}
+	return id[:i]
+}
+
+func (s *Library) Object(ctx context.Context, id string) (*Object, error) {
+	if id == RootID {
+		return &Object{ID: RootID, ParentID: "-1", Title: "Webtor", Class: classFolder, ChildCount: len(sections)}, nil
+	}
+	for _, sc := range sections {
+		if sc.id == id {
+			return &Object{ID: id, ParentID: RootID, Title: sc.title, Class: classFolder, ChildCount: -1}, nil
+		}
+	}
+	section, rID, p := splitID(id)
+	if !isSection(section) || rID == "" {
+		return nil, ErrNoSuchObject
+	}
+	if p == "/" {
+		return s.torrent(ctx, section, rID)
+	}
+	siblings, err := s.dir(ctx, section, rID, path.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+	for i := range siblings {
+		if siblings[i].ID == id {
+			o := siblings[i]
+			return &o, nil
+		}
+	}
+	return nil, ErrNoSuchObject
+}
+
+func (s *Library) Children(ctx context.Context, id string, offset int, limit int) ([]Object, int, error) {
+	var all []Object
+	switch {
+	case id == RootID:
+		for _, sc := range sections {
+			all = append(all, Object{ID: sc.id, ParentID: RootID, Title: sc.title, Class: classFolder, ChildCount: -1})
+		}
+	case isSection(id):
+		return s.section(ctx, id, offset, limit)
+	default:
+		section, rID, p := splitID(id)
+		if !isSection(section) || rID == "" {
+			return nil, 0, ErrNoSuchObject
+		}
+		var err error
+		all, err = s.dir(ctx, section, rID, p)
+		if err != nil {
+			return nil, 0, err
+		}
+		if p == "/" {
+			all, err = s.unwrap(ctx, section, rID, all)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		for i := range all {
+			all[i].ParentID = id
+		}
+	}
+	return page(all, offset, limit), len(all), nil
+}
+
+// unwrap skips the lone top-level folder most torrents pack their files into,
+// so a movie opens onto its video rather than onto a folder of the same name.
+func (s *Library) unwrap(ctx context.Context, section string, rID string, root []Object) ([]Object, error) {
+	if len(root) != 1 || !root[0].IsContainer() {
+		return root, nil
+	}
+	_, _, p := splitID(root[0].ID)
+	return s.dir(ctx, section, rID, p)
+}
+
+func page(all []Object, offset int, limit int) []Object {
+	if offset >= len(all) {
+		return []Object{}
+	}
+	all = all[offset:]
+	if limit > 0 && limit < len(all) {
+		all = all[:limit]
+	}
+	return all
+}
+
+// section pages the library straight through to the API, which pages too.
+func (s *Library) section(ctx context.Context, section string, offset int, limit int) ([]Object, int, error) {
+	if limit <= 0 || limit > libraryPage {
+		limit = libraryPage
+	}
+	res, err := s.cl.Library(ctx, section, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]Object, 0, len(res.Items))
+	for _, it := range res.Items {
+		o := torrentObject(section, it)
+		s.remember(o)
+		out = append(out, o)
+	}
+	return out, res.Count, nil
+}
+
+func torrentObject(section string, it libapi.LibraryItem) Object {
+	o := Object{
+		ID:         section + "/" + it.ResourceID,
+		ParentID:   section,
+		Title:      it.Name,
+		Class:      classFolder,
+		ChildCount: -1,
+		ResourceID: it.ResourceID,
+	}
+	if m := it.Media; m != nil {
+		if m.Title != "" {
+			o.Title = m.Title
+			if m.Year != 0 {
+				o.Title = fmt.Sprintf("%s (%d)", m.Title, m.Year)
+			}
+		}
+		o.PosterURL = m.PosterURL
+		o.Plot = m.Plot
+		o.Year = m.Year
+	}
+	return o
+}
+
+// remember keeps the last listing's view of a torrent: GET /library/{id}
+// answers without media, and BrowseMetadata should not lose the title and
+// poster the listing just showed.
+func (s *Library) remember(o Object) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	// A library is thousands of entries at most; this never needs evicting
+	// within the life of a sidecar.
+	s.known[o.ID] = o
+}
+
+func (s *Library) torrent(ctx context.Context, section string, rID string) (*Object, error) {
+	s.mux.Lock()
+	o, ok := s.known[section+"/"+rID]
+	s.mux.Unlock()
+	if ok {
+		return &o, nil
+	}
+	it, err := s.cl.LibraryItem(ctx, rID)
+	if errorCode(err) == libapi.CodeNotFound {
+		return nil, ErrNoSuchObject
+	}
+	if err != nil {
+		return nil, err
+	}
+	o = torrentObject(section, *it)
+	return &o, nil
+}
+
+// dir lists one folder of a torrent: subfolders and videos, nothing else a TV
+// could play.
+func (s *Library) dir(ctx context.Context, section string, rID string, p string) ([]Object, error) {
+	return s.dirs.Get(section+"|"+rID+"|"+p, func() ([]Object, error) {
+		t, err := s.torrent(ctx, section, rID)
+		if err != nil {
+			return nil, err
+		}
+		var out []Object
+		for offset := 0; offset < maxDirEntries; {
+			res, err := s.cl.List(ctx, rID, p, libraryPage, offset)
+			if errorCode(err) == libapi.CodeNotFound {
+				return nil, ErrNoSuchObject
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, it := range res.Items {
+				if o, ok := fileObject(t, it); ok {
+					out = append(out, o)
+				}
+			}
+			offset += len(res.Items)
+			if len(res.Items) == 0 || offset >= res.Count {
+				break
+			}
+		}
+		return out, nil
+	})
+}
+
+func fileObject(t *Object, it ra.ListItem) (Object, bool) {
+	p := it.PathStr
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	o := Object{
+		ID:         strings.TrimSuffix(t.ID+p, "/"),
+		Title:      it.Name,
+		ChildCount: -1,
+		PosterURL:  t.PosterURL,
+		Year:       t.Year,
+		ResourceID: t.ResourceID,
+	}
+	o.ParentID = parentID(o.ID)
+	switch {
+	case it.Type == ra.ListTypeDirectory:
+		o.Class = classFolder
+	case it.MediaFormat == ra.Video:
+		o.Class = classVideo
+		o.Plot = t.Plot
+		o.Size = it.Size
+		o.ContentID = it.ID
+		o.MimeType = it.MimeType
+		if o.MimeType == "" {
+			o.MimeType = mime.TypeByExtension(path.Ext(it.Name))
+		}
+		if o.MimeType == "" {
+			o.MimeType = "video/mp4"
+		}
+	default:
+		return o, false
+	}
+	return o, true
+}
+
+func (s *Library) StreamURL(ctx context.Context, resourceID string, contentID string) (string, error) {
+	return s.cl.DownloadURL(ctx, resourceID, contentID)
+}
+
+var _ Source = (*Library)(nil)
@@ -0,0 +1,150 @@
+package dlna
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/services/libapi"
+)
+
+const testRID = "08ada5a7a6183aae1e09d831df6748d566095a10"
+
+// fakeAPI answers the few endpoints Library uses with one movie packed into
+// a folder, the usual torrent layout.
+func fakeAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	item := libapi.LibraryItem{
+		ResourceID: testRID,
+		Name:       "Sintel.2010.1080p",
+		Media: &libapi.LibraryMedia{
+			Type:      libapi.MediaTypeMovie,
+			Title:     "Sintel",
+			Year:      2010,
+			Plot:      "A lonely young woman searches for her dragon.",
+			PosterURL: "https://webtor.io/lib/poster/" + testRID + "/240.jpg",
+		},
+	}
+	tree := map[string][]ra.ListItem{
+		"/": {
+			{ID: "d1", Name: "Sintel", PathStr: "/Sintel", Type: ra.ListTypeDirectory},
+		},
+		"/Sintel": {
+			{ID: "f1", Name: "Sintel.mkv", PathStr: "/Sintel/Sintel.mkv", Size: 734003200, MediaFormat: ra.Video},
+			{ID: "f2", Name: "Sintel.nfo", PathStr: "/Sintel/Sintel.nfo", Size: 120},
+			{ID: "d2", Name: "Extras", PathStr: "/Sintel/Extras", Type: ra.ListTypeDirectory},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /library", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		res := libapi.LibraryListResponse{Count: 0, Items: []libapi.LibraryItem{}}
+		if r.URL.Query().Get("type") == libapi.LibraryTypeMovies {
+			res = libapi.LibraryListResponse{Count: 1, Items: []libapi.LibraryItem{item}}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	})
+	mux.HandleFunc("GET /library/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != testRID {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(libapi.ErrorResponse{Error: libapi.Error{Code: libapi.CodeNotFound, Message: "not found"}})
+			return
+		}
+		it := item
+		it.Media = nil
+		_ = json.NewEncoder(w).Encode(it)
+	})
+	mux.HandleFunc("GET /resource/{id}/list", func(w http.ResponseWriter, r *http.Request) {
+		items := tree[r.URL.Query().Get("path")]
+		_ = json.NewEncoder(w).Encode(ra.ListResponse{Items: items, Count: len(items)})
+	})
+	mux.HandleFunc("GET /resource/{id}/export/{content}", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ra.ExportResponse{ExportItems: map[string]ra.ExportItem{
+			"download": {URL: "https://cdn.example/" + r.PathValue("content")},
+		}})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLibraryBrowse(t *testing.T) {
+	l := NewLibrary(NewClient(fakeAPI(t).URL, "key"))
+	ctx := context.Background()
+
+	movies, total, err := l.Children(ctx, libapi.LibraryTypeMovies, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(movies) != 1 {
+		t.Fatalf("got %d of %d movies", len(movies), total)
+	}
+	m := movies[0]
+	if m.Title != "Sintel (2010)" || m.PosterURL == "" || m.Plot == "" {
+		t.Fatalf("media not applied: %+v", m)
+	}
+
+	// The lone top-level folder is skipped; the .nfo is not playable.
+	files, total, err := l.Children(ctx, m.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("got %d children, want 2: %+v", total, files)
+	}
+	var video *Object
+	for i := range files {
+		if files[i].ParentID != m.ID {
+			t.Errorf("%s: parent %q, want %q", files[i].ID, files[i].ParentID, m.ID)
+		}
+		if !files[i].IsContainer() {
+			video = &files[i]
+		}
+	}
+	if video == nil || video.ID != "movies/"+testRID+"/Sintel/Sintel.mkv" || video.ContentID != "f1" {
+		t.Fatalf("unexpected video: %+v", video)
+	}
+	if video.MimeType != "video/x-matroska" {
+		t.Errorf("mime type %q", video.MimeType)
+	}
+
+	// BrowseMetadata resolves ids on their own, keeping what the listing showed.
+	o, err := l.Object(ctx, m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Title != "Sintel (2010)" {
+		t.Errorf("torrent title %q", o.Title)
+	}
+	o, err = l.Object(ctx, video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ContentID != "f1" {
+		t.Errorf("video object %+v", o)
+	}
+
+	u, err := l.StreamURL(ctx, testRID, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://cdn.example/f1" {
+		t.Errorf("stream url %q", u)
+	}
+}
+
+func TestLibraryNoSuchObject(t *testing.T) {
+	l := NewLibrary(NewClient(fakeAPI(t).URL, "key"))
+	ctx := context.Background()
+	for _, id := range []string{"music", "movies/unknown", "movies/" + testRID + "/Missing.mkv"} {
+		if _, err := l.Object(ctx, id); err != ErrNoSuchObject {
+			t.Errorf("%s: got %v, want ErrNoSuchObject", id, err)
+		}
+	}
+}
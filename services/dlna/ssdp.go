@@ -0,0 +1,190 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// SSDP (UPnP Device Architecture 1.0, section 1) is how TVs find the server:
+// they multicast M-SEARCH and expect a unicast answer, and the server
+// multicasts NOTIFY on start, every so often while alive, and on shutdown.
+
+var ssdpAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+const (
+	// ssdpMaxAge is how long a control point may trust an announcement;
+	// ssdpInterval re-announces well within it, as the spec asks.
+	ssdpMaxAge   = 1800
+	ssdpInterval = ssdpMaxAge / 3 * time.Second
+	ssdpServer   = "Linux/1.0 UPnP/1.0 webtor-dlna/1.0"
+)
+
+type ssdp struct {
+	uuid     string
+	location func() string
+	iface    *net.Interface
+	conn     *net.UDPConn
+	closed   chan struct{}
+	once     sync.Once
+	// maxDelay caps the MX-requested random delay before answering a search.
+	maxDelay time.Duration
+}
+
+// targets are the notification types the server answers to: the root device,
+// its UDN, its device type and each service.
+func (s *ssdp) targets() []string {
+	return []string{
+		"upnp:rootdevice",
+		"uuid:" + s.uuid,
+		mediaServerType,
+		contentDirectoryType,
+		connectionManagerType,
+	}
+}
+
+func (s *ssdp) usn(nt string) string {
+	if nt == "uuid:"+s.uuid {
+		return nt
+	}
+	return "uuid:" + s.uuid + "::" + nt
+}
+
+func (s *ssdp) listen() error {
+	conn, err := net.ListenMulticastUDP("udp4", s.iface, ssdpAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to join the ssdp multicast group")
+	}
+	s.conn = conn
+	s.closed = make(chan struct{})
+	return nil
+}
+
+func (s *ssdp) serve() {
+	go s.announce()
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.closed:
+				return
+			default:
+			}
+			log.WithError(err).Warn("failed to read ssdp packet")
+			continue
+		}
+		targets, mx, ok := s.parseSearch(buf[:n])
+		if !ok {
+			continue
+		}
+		go s.reply(from, targets, mx)
+	}
+}
+
+// parseSearch reads an M-SEARCH and returns the targets it asks about.
+func (s *ssdp) parseSearch(b []byte) ([]string, int, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil || req.Method != "M-SEARCH" {
+		return nil, 0, false
+	}
+	if strings.Trim(req.Header.Get("MAN"), `"`) != "ssdp:discover" {
+		return nil, 0, false
+	}
+	mx, _ := strconv.Atoi(req.Header.Get("MX"))
+	st := req.Header.Get("ST")
+	if st == "ssdp:all" {
+		return s.targets(), mx, true
+	}
+	for _, t := range s.targets() {
+		if t == st {
+			return []string{st}, mx, true
+		}
+	}
+	return nil, 0, false
+}
+
+func (s *ssdp) reply(to *net.UDPAddr, targets []string, mx int) {
+	// MX asks for a random delay so that every device on the network does
+	// not answer at once; capped, since some TVs send absurd values.
+	if d := min(time.Duration(mx)*time.Second, s.maxDelay); d > 0 {
+		select {
+		case <-time.After(rand.N(d)):
+		case <-s.closed:
+			return
+		}
+	}
+	for _, st := range targets {
+		if _, err := s.conn.WriteToUDP(s.searchResponse(st), to); err != nil {
+			log.WithError(err).WithField("to", to.String()).Debug("failed to answer ssdp search")
+			return
+		}
+	}
+}
+
+func (s *ssdp) searchResponse(st string) []byte {
+	return fmt.Appendf(nil, "HTTP/1.1 200 OK\r\n"+
+		"CACHE-CONTROL: max-age=%d\r\n"+
+		"DATE: %s\r\n"+
+		"EXT:\r\n"+
+		"LOCATION: %s\r\n"+
+		"SERVER: %s\r\n"+
+		"ST: %s\r\n"+
+		"USN: %s\r\n\r\n",
+		ssdpMaxAge, time.Now().UTC().Format(http.TimeFormat), s.location(), ssdpServer, st, s.usn(st))
+}
+
+func (s *ssdp) notify(nts string) {
+	for _, nt := range s.targets() {
+		msg := fmt.Appendf(nil, "NOTIFY * HTTP/1.1\r\n"+
+			"HOST: %s\r\n"+
+			"CACHE-CONTROL: max-age=%d\r\n"+
+			"LOCATION: %s\r\n"+
+			"NT: %s\r\n"+
+			"NTS: %s\r\n"+
+			"SERVER: %s\r\n"+
+			"USN: %s\r\n\r\n",
+			ssdpAddr.String(), ssdpMaxAge, s.location(), nt, nts, ssdpServer, s.usn(nt))
+		if _, err := s.conn.WriteToUDP(msg, ssdpAddr); err != nil {
+			log.WithError(err).Debug("failed to send ssdp notify")
+			return
+		}
+	}
+}
+
+func (s *ssdp) announce() {
+	s.notify("ssdp:alive")
+	t := time.NewTicker(ssdpInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.notify("ssdp:alive")
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *ssdp) close() {
+	s.once.Do(func() {
+		if s.conn == nil {
+			return
+		}
+		// Tell TVs to drop the server now rather than show it until max-age
+		// runs out.
+		s.notify("ssdp:byebye")
+		close(s.closed)
+		_ = s.conn.Close()
+	})
+}
@@ -0,0 +1,52 @@
+package dlna
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSearch(t *testing.T) {
+	s := &ssdp{uuid: "u1", location: func() string { return "http://10.0.0.2:8200/description.xml" }}
+	search := func(st string, man string) string {
+		return "M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: " + man + "\r\nMX: 2\r\nST: " + st + "\r\n\r\n"
+	}
+	for _, tc := range []struct {
+		msg  string
+		want int
+	}{
+		{search("ssdp:all", `"ssdp:discover"`), 5},
+		{search(contentDirectoryType, `"ssdp:discover"`), 1},
+		{search("urn:schemas-upnp-org:device:MediaRenderer:1", `"ssdp:discover"`), 0},
+		{search("ssdp:all", `"ssdp:other"`), 0},
+		{"NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nNT: upnp:rootdevice\r\n\r\n", 0},
+	} {
+		targets, mx, ok := s.parseSearch([]byte(tc.msg))
+		if len(targets) != tc.want || ok != (tc.want > 0) {
+			t.Errorf("%q: got %v %v, want %d targets", tc.msg, targets, ok, tc.want)
+		}
+		if ok && mx != 2 {
+			t.Errorf("mx %d", mx)
+		}
+	}
+}
+
+func TestSearchResponse(t *testing.T) {
+	s := &ssdp{uuid: "u1", location: func() string { return "http://10.0.0.2:8200/description.xml" }}
+	res := string(s.searchResponse("upnp:rootdevice"))
+	for _, want := range []string{
+		"HTTP/1.1 200 OK\r\n",
+		"LOCATION: http://10.0.0.2:8200/description.xml\r\n",
+		"ST: upnp:rootdevice\r\n",
+		"USN: uuid:u1::upnp:rootdevice\r\n",
+	} {
+		if !strings.Contains(res, want) {
+			t.Errorf("missing %q in\n%s", want, res)
+		}
+	}
+	if !strings.HasSuffix(res, "\r\n\r\n") {
+		t.Error("response not terminated")
+	}
+	if got := s.usn("uuid:u1"); got != "uuid:u1" {
+		t.Errorf("usn of the udn %q", got)
+	}
+}
@@ -0,0 +1,412 @@
+package enrich
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/models"
+	am "github.com/webtor-io/web-ui/models/anilist"
+	tm "github.com/webtor-io/web-ui/models/tmdb"
+	"github.com/webtor-io/web-ui/services/anilist"
+)
+
+// animeCatalog is the mapper AniList hands the names it finds to. AniList
+// ids are not video ids, so the metadata itself comes from there — TMDB.
+type animeCatalog interface {
+	MetadataMapper
+	DirectMapper
+}
+
+// AniList resolves anime by the names release groups use. Fansub releases
+// carry the romaji title ("Shingeki no Kyojin"), which TMDB's search — and
+// the title guard on it — does not tie to "Attack on Titan". AniList knows
+// every name an anime goes by; the mapper finds the entry named exactly
+// like the parsed title and searches the catalogue with its other names.
+type AniList struct {
+	pg      *cs.PG
+	api     *anilist.Api
+	catalog animeCatalog
+}
+
+func (s *AniList) GetName() string {
+	return "AniList"
+}
+
+func NewAniList(pg *cs.PG, api *anilist.Api, catalog animeCatalog) *AniList {
+	if api == nil || catalog == nil {
+		return nil
+	}
+	return &AniList{
+		pg:      pg,
+		api:     api,
+		catalog: catalog,
+	}
+}
+
+func (s *AniList) Map(ctx context.Context, m *models.VideoContent, ct models.ContentType, force bool) (*models.VideoMetadata, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	mi, err := s.findMedia(ctx, db, m.Title, m.Year, ct, force)
+	if err != nil || mi == nil {
+		return nil, err
+	}
+	return s.resolve(ctx, db, mi, ct, force)
+}
+
+// findMedia returns the AniList entry named exactly like title, nil when
+// there is none. AniList's search is as fuzzy as TMDB's, so only a name
+// match is taken, never the top result as such.
+func (s *AniList) findMedia(ctx context.Context, db *pg.DB, title string, year *int16, ct models.ContentType, force bool) (*am.Media, error) {
+	if isWeakSearchTitle(title) {
+		return nil, nil
+	}
+	q, err := am.GetQuery(ctx, db, title, year)
+	if err != nil {
+		return nil, err
+	}
+	if q != nil && !force {
+		if q.AnilistID == nil {
+			return nil, nil
+		}
+		mi, err := am.GetMediaByID(ctx, db, *q.AnilistID)
+		if err != nil || mi == nil || !animeFormatFits(mediaFormat(mi), ct) {
+			return nil, err
+		}
+		return mi, nil
+	}
+
+	res, err := s.api.Search(ctx, title, year)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 && year != nil {
+		// Same reasoning as the TMDB retry: the year in a release name is
+		// often not the year the anime started airing.
+		res, err = s.api.Search(ctx, title, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	best := pickAnime(res, title, ct)
+	if best == nil {
+		log.Infof("no anilist entry named %v", title)
+		_, err = am.UpsertQuery(ctx, db, title, year, nil)
+		return nil, err
+	}
+	var y *int16
+	if best.Year() != 0 {
+		yy := int16(best.Year())
+		y = &yy
+	}
+	mi, err := am.UpsertMedia(ctx, db, best.ID, best.Title.Romaji, y, best.Raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = am.UpsertQuery(ctx, db, title, year, &best.ID); err != nil {
+		return nil, err
+	}
+	return mi, nil
+}
+
+// resolve finds the catalogue entry for an AniList entry: through its
+// mapping when it has one, otherwise by searching the catalogue with its
+// English and romaji names. A hit is remembered as the entry's mapping.
+func (s *AniList) resolve(ctx context.Context, db *pg.DB, mi *am.Media, ct models.ContentType, force bool) (*models.VideoMetadata, error) {
+	mp, err := am.GetMapping(ctx, db, mi.AnilistID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get anilist mapping")
+	}
+	if mp != nil && (!force || mp.Curated) {
+		md, err := s.catalog.MapByID(ctx, mp.VideoID, ct, force)
+		if err != nil || md != nil {
+			return md, err
+		}
+	}
+	for _, t := range mediaTitles(mi, false) {
+		md, err := s.catalog.Map(ctx, &models.VideoContent{Title: t, Year: mi.Year}, ct, force)
+		if err != nil {
+			return nil, err
+		}
+		if md == nil {
+			continue
+		}
+		if err := am.UpsertMapping(ctx, db, mi.AnilistID, md.VideoID); err != nil {
+			return nil, errors.Wrap(err, "failed to store anilist mapping")
+		}
+		return md, nil
+	}
+	return nil, nil
+}
+
+// pickAnime returns the first search result that goes by title and is
+// the right kind of entry
+func pickAnime(res []*anilist.Media, title string, ct models.ContentType) *anilist.Media {
+	key := MatchQueryKey(title)
+	if key == "" {
+		return nil
+	}
+	for _, m := range res {
+		if !animeFormatFits(m.Format, ct) {
+			continue
+		}
+		for _, t := range m.Titles() {
+			if MatchQueryKey(t) == key {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// animeFormatFits reports whether an AniList format is the content type:
+// films are MOVIE, everything else — TV, ONA, OVA, specials — is a series
+func animeFormatFits(format string, ct models.ContentType) bool {
+	return (format == "MOVIE") == (ct == models.ContentTypeMovie)
+}
+
+func mediaFormat(mi *am.Media) string {
+	f, _ := mi.Metadata["format"].(string)
+	return f
+}
+
+// mediaTitles reads an entry's names from its cached payload, English
+// first. Synonyms are mostly abbreviations ("SnK") and only asked for
+// when matching a parsed title, never as a catalogue search key.
+func mediaTitles(mi *am.Media, synonyms bool) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(t string) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if t, ok := mi.Metadata["title"].(map[string]any); ok {
+		for _, k := range []string{"english", "romaji", "native"} {
+			v, _ := t[k].(string)
+			add(v)
+		}
+	}
+	add(mi.Title)
+	if synonyms {
+		ss, _ := mi.Metadata["synonyms"].([]any)
+		for _, v := range ss {
+			sv, _ := v.(string)
+			add(sv)
+		}
+	}
+	return out
+}
+
+// EpisodeNumber is where an episode sits in the catalogue
+type EpisodeNumber struct {
+	Season  int
+	Episode int
+}
+
+// AniListEpisodes places episodes numbered across a whole anime run
+// ("[SubsPlease] One Piece - 1071") in the catalogue's seasons. It has no
+// episode metadata of its own — once placed, an episode's metadata comes
+// from TMDB Episodes like any other — so MapEpisodes never answers.
+type AniListEpisodes struct {
+	anilist *AniList
+	tmdb    *TMDB
+}
+
+func NewAniListEpisodes(al *AniList, tmdb *TMDB) *AniListEpisodes {
+	if al == nil || tmdb == nil {
+		return nil
+	}
+	return &AniListEpisodes{
+		anilist: al,
+		tmdb:    tmdb,
+	}
+}
+
+func (s *AniListEpisodes) GetName() string {
+	return "AniList Episodes"
+}
+
+func (s *AniListEpisodes) MapEpisodes(ctx context.Context, videoID string, season int, force bool) ([]*models.EpisodeMetadata, error) {
+	return nil, nil
+}
+
+// MapAbsoluteEpisodes implements AbsoluteEpisodeMapper. The series counts
+// as anime when one of its AniList entries is mapped to it, or, for a
+// series TMDB matched on its own, when AniList has an entry named like it.
+// The entry's mapping row says where its numbers start; TMDB's episode
+// counts per season do the rest.
+func (s *AniListEpisodes) MapAbsoluteEpisodes(ctx context.Context, videoID string, title string, episodes []int, force bool) (map[int]EpisodeNumber, error) {
+	db := s.anilist.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	mp, err := s.mapping(ctx, db, videoID, title, force)
+	if err != nil || mp == nil {
+		return nil, err
+	}
+	seasons, err := s.seasons(ctx, db, videoID)
+	if err != nil || len(seasons) == 0 {
+		return nil, err
+	}
+	out := map[int]EpisodeNumber{}
+	for _, abs := range episodes {
+		if n, ok := placeAbsoluteEpisode(seasons, mp.Season, mp.EpisodeOffset, abs); ok {
+			out[abs] = n
+		}
+	}
+	return out, nil
+}
+
+// mapping picks the AniList entry of the series the release is numbered
+// by: the one named like the parsed title, else the one counting from the
+// first episode, else any
+func (s *AniListEpisodes) mapping(ctx context.Context, db *pg.DB, videoID string, title string, force bool) (*am.Mapping, error) {
+	mps, err := am.ListMappingsByVideoID(ctx, db, videoID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list anilist mappings")
+	}
+	if len(mps) == 0 {
+		mi, err := s.anilist.findMedia(ctx, db, title, nil, models.ContentTypeSeries, force)
+		if err != nil || mi == nil {
+			return nil, err
+		}
+		mp, err := am.GetMapping(ctx, db, mi.AnilistID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get anilist mapping")
+		}
+		if mp != nil {
+			// The entry belongs to another series: the name is shared,
+			// the show is not
+			return nil, nil
+		}
+		if err := am.UpsertMapping(ctx, db, mi.AnilistID, videoID); err != nil {
+			return nil, errors.Wrap(err, "failed to store anilist mapping")
+		}
+		return &am.Mapping{AnilistID: mi.AnilistID, VideoID: videoID}, nil
+	}
+	if len(mps) == 1 {
+		return mps[0], nil
+	}
+	ids := make([]int, 0, len(mps))
+	for _, mp := range mps {
+		ids = append(ids, mp.AnilistID)
+	}
+	media, err := am.ListMediaByID(ctx, db, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list anilist media")
+	}
+	return pickMapping(mps, media, title), nil
+}
+
+func pickMapping(mps []*am.Mapping, media []*am.Media, title string) *am.Mapping {
+	key := MatchQueryKey(title)
+	named := map[int]bool{}
+	for _, mi := range media {
+		for _, t := range mediaTitles(mi, true) {
+			if MatchQueryKey(t) == key {
+				named[mi.AnilistID] = true
+			}
+		}
+	}
+	for _, mp := range mps {
+		if named[mp.AnilistID] {
+			return mp
+		}
+	}
+	for _, mp := range mps {
+		if mp.Season == nil {
+			return mp
+		}
+	}
+	return mps[0]
+}
+
+// seasonLength is one regular season of a series and its episode count
+type seasonLength struct {
+	Season   int
+	Episodes int
+}
+
+// seasons reads the series' season lengths off its cached TMDB info
+func (s *AniListEpisodes) seasons(ctx context.Context, db *pg.DB, videoID string) ([]seasonLength, error) {
+	tmdbID, _, err := s.tmdb.GetTmdbID(ctx, videoID, models.ContentTypeSeries)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve tmdb id")
+	}
+	if tmdbID == 0 {
+		return nil, nil
+	}
+	info, err := tm.GetInfoByID(ctx, db, tmdbID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cached series info")
+	}
+	if info == nil {
+		return nil, nil
+	}
+	return seasonLengths(info.Metadata), nil
+}
+
+// seasonLengths reads the regular seasons out of a TMDB series payload,
+// in order. Specials (season 0) sit outside the absolute count.
+func seasonLengths(raw map[string]any) []seasonLength {
+	ss, _ := raw["seasons"].([]any)
+	var out []seasonLength
+	for _, v := range ss {
+		sm, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		n, _ := sm["season_number"].(float64)
+		c, _ := sm["episode_count"].(float64)
+		if n < 1 || c < 1 {
+			continue
+		}
+		out = append(out, seasonLength{Season: int(n), Episodes: int(c)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Season < out[j].Season
+	})
+	return out
+}
+
+// placeAbsoluteEpisode walks the seasons from start (the first one when
+// nil), offset episodes in, to the one abs falls on. A number past the
+// last season's count is put in the last season: that is the one still
+// airing, whose count on TMDB lags the releases.
+func placeAbsoluteEpisode(seasons []seasonLength, start *int16, offset int16, abs int) (EpisodeNumber, bool) {
+	n := abs + int(offset)
+	if n < 1 {
+		return EpisodeNumber{}, false
+	}
+	first := 1
+	if start != nil {
+		first = int(*start)
+	}
+	var last *seasonLength
+	for i := range seasons {
+		sl := &seasons[i]
+		if sl.Season < first {
+			continue
+		}
+		if n <= sl.Episodes {
+			return EpisodeNumber{Season: sl.Season, Episode: n}, true
+		}
+		n -= sl.Episodes
+		last = sl
+	}
+	if last == nil {
+		return EpisodeNumber{}, false
+	}
+	return EpisodeNumber{Season: last.Season, Episode: last.Episodes + n}, true
+}
+
+var _ MetadataMapper = (*AniList)(nil)
+var _ EpisodeMapper = (*AniListEpisodes)(nil)
+var _ AbsoluteEpisodeMapper = (*AniListEpisodes)(nil)
@@ -0,0 +1,148 @@
+package enrich
+
+import (
+	"testing"
+
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+	am "github.com/webtor-io/web-ui/models/anilist"
+	"github.com/webtor-io/web-ui/services/anilist"
+	ptn "github.com/webtor-io/web-ui/services/parse_torrent_name"
+)
+
+func anime(id int, format, romaji, english string, synonyms ...string) *anilist.Media {
+	m := &anilist.Media{ID: id, Format: format, Synonyms: synonyms}
+	m.Title.Romaji = romaji
+	m.Title.English = english
+	return m
+}
+
+// Shaped like AniList's answer to "Shingeki no Kyojin": the sequels come
+// back too, and the first result is not always the one named exactly.
+func TestPickAnime(t *testing.T) {
+	res := []*anilist.Media{
+		anime(20958, "TV", "Shingeki no Kyojin Season 2", "Attack on Titan Season 2", "SnK 2"),
+		anime(16498, "TV", "Shingeki no Kyojin", "Attack on Titan", "AoT", "SnK"),
+		anime(18397, "MOVIE", "Shingeki no Kyojin Movie", "Attack on Titan: Crimson Bow and Arrow"),
+	}
+	for _, tt := range []struct {
+		title string
+		ct    models.ContentType
+		want  int
+	}{
+		{"Shingeki no Kyojin", models.ContentTypeSeries, 16498},
+		{"shingeki no kyojin season 2", models.ContentTypeSeries, 20958},
+		{"SnK", models.ContentTypeSeries, 16498},
+		{"Shingeki no Kyojin", models.ContentTypeMovie, 0},
+		{"Shingeki no Kyojin Movie", models.ContentTypeMovie, 18397},
+		{"Shingeki", models.ContentTypeSeries, 0},
+	} {
+		got := 0
+		if m := pickAnime(res, tt.title, tt.ct); m != nil {
+			got = m.ID
+		}
+		if got != tt.want {
+			t.Errorf("pickAnime(%q, %v) = %d, want %d", tt.title, tt.ct, got, tt.want)
+		}
+	}
+}
+
+func TestSeasonLengths(t *testing.T) {
+	raw := map[string]any{"seasons": []any{
+		map[string]any{"season_number": float64(0), "episode_count": float64(9)},
+		map[string]any{"season_number": float64(2), "episode_count": float64(12)},
+		map[string]any{"season_number": float64(1), "episode_count": float64(25)},
+		map[string]any{"season_number": float64(3), "episode_count": float64(0)},
+	}}
+	got := seasonLengths(raw)
+	want := []seasonLength{{1, 25}, {2, 12}}
+	if len(got) != len(want) {
+		t.Fatalf("seasonLengths = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("seasonLengths = %v, want %v", got, want)
+		}
+	}
+}
+
+// Attack on Titan on TMDB: 25, 12, 22 and 30 episodes.
+func TestPlaceAbsoluteEpisode(t *testing.T) {
+	seasons := []seasonLength{{1, 25}, {2, 12}, {3, 22}, {4, 30}}
+	s3 := int16(3)
+	for _, tt := range []struct {
+		name   string
+		start  *int16
+		offset int16
+		abs    int
+		want   EpisodeNumber
+		ok     bool
+	}{
+		{"first episode", nil, 0, 1, EpisodeNumber{1, 1}, true},
+		{"end of a season", nil, 0, 25, EpisodeNumber{1, 25}, true},
+		{"next season", nil, 0, 26, EpisodeNumber{2, 1}, true},
+		{"final season", nil, 0, 60, EpisodeNumber{4, 1}, true},
+		{"past the count lands in the airing season", nil, 0, 93, EpisodeNumber{4, 34}, true},
+		{"entry counting from season 3", &s3, 0, 13, EpisodeNumber{3, 13}, true},
+		{"second cour of season 3", &s3, 10, 3, EpisodeNumber{3, 13}, true},
+		{"offset below one", nil, -5, 3, EpisodeNumber{}, false},
+		{"start past the last season", func() *int16 { s := int16(5); return &s }(), 0, 1, EpisodeNumber{}, false},
+	} {
+		got, ok := placeAbsoluteEpisode(seasons, tt.start, tt.offset, tt.abs)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: placeAbsoluteEpisode = %v %v, want %v %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPickMapping(t *testing.T) {
+	s2 := int16(2)
+	mps := []*am.Mapping{
+		{AnilistID: 20958, VideoID: "tt2560140", Season: &s2},
+		{AnilistID: 16498, VideoID: "tt2560140"},
+	}
+	media := []*am.Media{
+		{AnilistID: 20958, Title: "Shingeki no Kyojin Season 2", Metadata: map[string]any{
+			"title":    map[string]any{"romaji": "Shingeki no Kyojin Season 2", "english": "Attack on Titan Season 2"},
+			"synonyms": []any{"SnK 2"},
+		}},
+		{AnilistID: 16498, Title: "Shingeki no Kyojin", Metadata: map[string]any{
+			"title": map[string]any{"romaji": "Shingeki no Kyojin", "english": "Attack on Titan"},
+		}},
+	}
+	if got := pickMapping(mps, media, "SnK 2"); got.AnilistID != 20958 {
+		t.Errorf("named by synonym: got %d, want 20958", got.AnilistID)
+	}
+	if got := pickMapping(mps, media, "Shingeki no Kyojin"); got.AnilistID != 16498 {
+		t.Errorf("named by romaji: got %d, want 16498", got.AnilistID)
+	}
+	if got := pickMapping(mps, media, "Attack on Titan Final Season"); got.AnilistID != 16498 {
+		t.Errorf("unnamed: got %d, want the entry counting from season 1", got.AnilistID)
+	}
+}
+
+// The parser files a fansub tag under Group, not Website. A release that
+// names nothing else must still get a series title.
+func TestMakeSeriesTitleFromFansubGroup(t *testing.T) {
+	var infos []*TorrentInfo
+	for i, name := range []string{
+		"[SubsPlease] - 01 (1080p) [6A8D4F1B].mkv",
+		"[SubsPlease] - 02 (1080p) [0B1C2D3E].mkv",
+	} {
+		pi, err := ptn.Parse(&ptn.TorrentInfo{}, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos = append(infos, &TorrentInfo{TorrentInfo: pi, ListItem: &ra.ListItem{PathStr: "/" + name}, FileIdx: i})
+	}
+	ser, err := (&Enricher{}).makeSeriesWithEpisodes(infos, "hash", models.MediaInfoMediaTypeSeriesSingleSeason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ser.Title != "SubsPlease" {
+		t.Errorf("title = %q, want the group", ser.Title)
+	}
+	if len(ser.Episodes) != 2 || *ser.Episodes[1].Episode != 2 {
+		t.Errorf("episodes = %+v", ser.Episodes)
+	}
+}
@@ -3,6 +3,7 @@ package enrich
 import (
 	"context"
 	"encoding/json"
+	"path"
 	"reflect"
 	"slices"
 	"strings"
@@ -24,6 +25,7 @@ type Enricher struct {
 	api            *api.Api
 	mappers        []MetadataMapper
 	episodeMappers []EpisodeMapper
+	musicMappers   []MusicMapper
 	aiResolver     *AIResolver
 }
 
@@ -58,6 +60,14 @@ type LocalizableMapper interface {
 	Localize(ctx context.Context, videoID string, lang string) (title string, plot string, err error)
 }
 
+// ExternalIDsMapper is an optional capability of a MetadataMapper. Mappers
+// that know a video's ids in other catalogues implement it: its TMDB id,
+// and for a series its TVDB id — the ids Torznab indexers built for
+// Sonarr and Radarr key on. Either may be 0. Today only TMDB.
+type ExternalIDsMapper interface {
+	ExternalIDs(ctx context.Context, videoID string, ct models.ContentType) (tmdbID int, tvdbID int, err error)
+}
+
 // Review is a single external user review surfaced through the
 // ReviewsProvider capability. Rating is the author's 0-10 score when
 // present; CreatedAt is an RFC3339 timestamp string straight from the
@@ -89,6 +99,23 @@ type AiringChecker interface {
 	IsAiring(ctx context.Context, videoID string) (bool, error)
 }
 
+// UpcomingProvider is an optional capability of an EpisodeMapper. Unlike
+// MapEpisodes, whose season cache is kept forever, it answers with air
+// dates fresh enough to put in a calendar, and with season 0 it picks the
+// season currently airing by itself. Today only TMDB Episodes.
+type UpcomingProvider interface {
+	UpcomingEpisodes(ctx context.Context, videoID string, season int) ([]*models.EpisodeMetadata, error)
+}
+
+// AbsoluteEpisodeMapper is an optional capability of an EpisodeMapper.
+// Anime releases number episodes across the whole run ("One Piece - 1071")
+// instead of per season; mappers that know where such numbers fall in the
+// catalogue's seasons implement it. Numbers it can't place are left out
+// of the result. Today only AniList Episodes.
+type AbsoluteEpisodeMapper interface {
+	MapAbsoluteEpisodes(ctx context.Context, videoID string, title string, episodes []int, force bool) (map[int]EpisodeNumber, error)
+}
+
 func (s *Enricher) HasMappers() bool {
 	return len(s.mappers) > 0
 }
@@ -210,6 +237,31 @@ func (s *Enricher) walkByID(ctx context.Context, videoID string, ct models.Conte
 	return lastErr
 }
 
+// ExternalIDs returns the TMDB and TVDB ids of a video from the first
+// mapper that knows it. Zeroes with a nil error mean no mapper does.
+func (s *Enricher) ExternalIDs(ctx context.Context, videoID string, ct models.ContentType) (int, int, error) {
+	var lastErr error
+	for _, m := range s.mappers {
+		em, ok := m.(ExternalIDsMapper)
+		if !ok {
+			continue
+		}
+		tmdbID, tvdbID, err := em.ExternalIDs(ctx, videoID, ct)
+		if err != nil {
+			log.WithError(err).
+				WithField("mapper", m.GetName()).
+				WithField("video_id", videoID).
+				Debug("external ids: mapper failed, trying next")
+			lastErr = err
+			continue
+		}
+		if tmdbID > 0 || tvdbID > 0 {
+			return tmdbID, tvdbID, nil
+		}
+	}
+	return 0, 0, lastErr
+}
+
 // LocalizeByID returns the localized title and plot for a bare video ID
 // (IMDB tt* / tmdb*) without a pre-built VideoMetadata — the Discover
 // catalog grid only has Stremio ids on hand. Walks the same mapper chain
@@ -352,12 +404,13 @@ func (s *Enricher) IsAiringSeriesChecked(ctx context.Context, videoID string) (b
 	return false, nil
 }
 
-func NewEnricher(pg *services.PG, api *api.Api, mappers []MetadataMapper, episodeMappers []EpisodeMapper, aiResolver *AIResolver) *Enricher {
+func NewEnricher(pg *services.PG, api *api.Api, mappers []MetadataMapper, episodeMappers []EpisodeMapper, musicMappers []MusicMapper, aiResolver *AIResolver) *Enricher {
 	return &Enricher{
 		pg:             pg,
 		api:            api,
 		mappers:        mappers,
 		episodeMappers: episodeMappers,
+		musicMappers:   musicMappers,
 		aiResolver:     aiResolver,
 	}
 }
@@ -759,6 +812,12 @@ func (s *Enricher) enrichMediaInfo(ctx context.Context, db *pg.DB, hash string,
 	}
 
 	if len(torrentInfos) == 0 {
+		// No video: a music torrent, perhaps. The albums get the same
+		// media_info lifecycle as movies and series.
+		mt, err := s.enrichMusic(ctx, db, hash, claims, items, force)
+		if err != nil || mt != nil {
+			return mt, err
+		}
 		log.Infof("no media info acquired for hash %s", hash)
 		return nil, nil
 	}
@@ -820,10 +879,24 @@ func (s *Enricher) enrichMediaInfo(ctx context.Context, db *pg.DB, hash string,
 		return nil, errors.Wrapf(err, "failed to replace series for hash %s", hash)
 	}
 
-	movies, err = models.GetMoviesByResourceID(ctx, db, hash)
+	err = models.ReplaceAlbumsForResource(ctx, db, hash, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to replace albums for hash %s", hash)
+	}
+
+	movies, seriesSlice, err = s.getResourceTitles(ctx, db, hash)
+	if err != nil {
+		return nil, err
+	}
 
+	// A user's correction beats the mappers, --force included — see
+	// match_override.go.
+	applied, err := s.applyMatchOverride(ctx, db, hash, movies, seriesSlice, force)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get movies for hash %s", hash)
+		return nil, err
+	}
+	if applied {
+		return &mt, nil
 	}
 
 	// One AI-fallback budget shared by every movie + the series block
@@ -855,11 +928,6 @@ func (s *Enricher) enrichMediaInfo(ctx context.Context, db *pg.DB, hash string,
 		}
 	}
 
-	seriesSlice, err = models.GetSeriesByResourceID(ctx, db, hash)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get series for hash %s", hash)
-	}
-
 	for _, ser := range seriesSlice {
 		var md *models.VideoMetadata
 		if mt != models.MediaInfoMediaTypeSeriesCompilation && mt != models.MediaInfoMediaTypeSeriesSplitScenes {
@@ -1137,6 +1205,8 @@ func (s *Enricher) enrichEpisodes(ctx context.Context, db *pg.DB, ser *models.Se
 		return errors.Wrap(err, "failed to get series with episodes")
 	}
 
+	s.placeAbsoluteEpisodes(ctx, db, serWithEps, videoID, force)
+
 	// Collect unique seasons
 	seasons := map[int]bool{}
 	for _, ep := range serWithEps.Episodes {
@@ -1190,6 +1260,65 @@ func (s *Enricher) enrichEpisodes(ctx context.Context, db *pg.DB, ser *models.Se
 	return nil
 }
 
+// placeAbsoluteEpisodes gives seasonless episodes a season through the
+// first AbsoluteEpisodeMapper that can place them, so that they link to
+// episode metadata like any other. Failures only leave the episodes
+// seasonless, as they were.
+func (s *Enricher) placeAbsoluteEpisodes(ctx context.Context, db *pg.DB, ser *models.Series, videoID string, force bool) {
+	var eps []*models.Episode
+	var nums []int
+	for _, ep := range ser.Episodes {
+		if ep.Season == nil && ep.Episode != nil {
+			eps = append(eps, ep)
+			nums = append(nums, int(*ep.Episode))
+		}
+	}
+	if len(eps) == 0 {
+		return
+	}
+	placed := s.mapAbsoluteEpisodes(ctx, videoID, ser.Title, nums, force)
+	for _, ep := range eps {
+		n, ok := placed[int(*ep.Episode)]
+		if !ok {
+			continue
+		}
+		sea, num := int16(n.Season), int16(n.Episode)
+		if err := models.RenumberEpisode(ctx, db, ep.EpisodeID, sea, num); err != nil {
+			log.WithError(err).Warnf("failed to renumber episode %d", *ep.Episode)
+			continue
+		}
+		ep.Season, ep.Episode = &sea, &num
+	}
+}
+
+// PlaceAbsoluteEpisode returns the season and episode an episode numbered
+// across a whole anime run falls on in the series videoID. title is the
+// name the release goes by. False when no mapper can place it.
+func (s *Enricher) PlaceAbsoluteEpisode(ctx context.Context, videoID string, title string, episode int) (EpisodeNumber, bool) {
+	n, ok := s.mapAbsoluteEpisodes(ctx, videoID, title, []int{episode}, false)[episode]
+	return n, ok
+}
+
+// mapAbsoluteEpisodes asks the AbsoluteEpisodeMappers in turn; the first
+// one that places anything answers for all the numbers.
+func (s *Enricher) mapAbsoluteEpisodes(ctx context.Context, videoID string, title string, episodes []int, force bool) map[int]EpisodeNumber {
+	for _, m := range s.episodeMappers {
+		abs, ok := m.(AbsoluteEpisodeMapper)
+		if !ok {
+			continue
+		}
+		placed, err := abs.MapAbsoluteEpisodes(ctx, videoID, title, episodes, force)
+		if err != nil {
+			log.WithError(err).Warnf("failed to place absolute episodes with %s", m.GetName())
+			continue
+		}
+		if len(placed) > 0 {
+			return placed
+		}
+	}
+	return nil
+}
+
 func (s *Enricher) mapEpisodeMetadata(ctx context.Context, videoID string, season int, force bool) ([]*models.EpisodeMetadata, error) {
 	for _, m := range s.episodeMappers {
 		eps, err := m.MapEpisodes(ctx, videoID, season, force)
@@ -1203,6 +1332,40 @@ func (s *Enricher) mapEpisodeMetadata(ctx context.Context, videoID string, seaso
 	return nil, nil
 }
 
+// UpcomingEpisodes returns the episodes of one season of a series as the
+// first UpcomingProvider episode mapper sees them; season 0 means the
+// season currently airing. (nil, nil) is "nothing to show" — an ended
+// series, or an id no mapper knows. An error means no mapper could answer,
+// and the caller may fall back to what episode_metadata already holds.
+func (s *Enricher) UpcomingEpisodes(ctx context.Context, videoID string, season int) ([]*models.EpisodeMetadata, error) {
+	if videoID == "" {
+		return nil, nil
+	}
+	var lastErr error
+	for _, m := range s.episodeMappers {
+		up, ok := m.(UpcomingProvider)
+		if !ok {
+			continue
+		}
+		eps, err := up.UpcomingEpisodes(ctx, videoID, season)
+		if err != nil {
+			log.WithError(err).
+				WithField("mapper", m.GetName()).
+				WithField("video_id", videoID).
+				Debug("upcoming episodes: mapper failed, trying next")
+			lastErr = err
+			continue
+		}
+		if eps != nil {
+			return eps, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, errors.Wrap(lastErr, "upcoming episodes: every mapper failed")
+	}
+	return nil, nil
+}
+
 // mapMetadata resolves metadata for a (title, year) pair through the
 // configured providers in priority order, with two fallback paths.
 //
@@ -1213,6 +1376,9 @@ func (s *Enricher) mapEpisodeMetadata(ctx context.Context, videoID string, seaso
 // visible metadata still comes from a real provider; Claude only
 // supplies search keys.
 //
+// A global match override for the parsed name (see match_override.go)
+// is consulted before any provider search and wins over it.
+//
 // budget is a per-resource cap on AI fallback misses; nil disables it.
 // See resourceAIBudget for the rationale.
 func (s *Enricher) mapMetadata(ctx context.Context, vc *models.VideoContent, t models.ContentType, f bool, hintVideoID string, pathHint string, budget *resourceAIBudget) (*models.VideoMetadata, error) {
@@ -1221,6 +1387,9 @@ func (s *Enricher) mapMetadata(ctx context.Context, vc *models.VideoContent, t m
 			return md, nil
 		}
 	}
+	if md, ok := s.globalMatch(ctx, vc, t, f); ok {
+		return md, nil
+	}
 	md, firstErr := s.searchAllMappers(ctx, vc, t, f)
 	if md != nil {
 		return md, nil
@@ -1568,6 +1737,7 @@ func (s *Enricher) retrieveTorrentItems(ctx context.Context, hash string, claims
 func (s *Enricher) getMediaType(infos []*TorrentInfo) models.MediaInfoMediaType {
 	var hasSeasones, hasDifferentSeasones, hasEpisodes, sameTitle, hasScenes bool
 	sameTitle = true
+	fansub := len(infos) > 0
 	var title string
 	var season int
 	for _, info := range infos {
@@ -1584,6 +1754,9 @@ func (s *Enricher) getMediaType(infos []*TorrentInfo) models.MediaInfoMediaType
 		if info.Scene != 0 {
 			hasScenes = true
 		}
+		if !isFansubEpisode(info) {
+			fansub = false
+		}
 		if title != "" && info.Title != title {
 			sameTitle = false
 		}
@@ -1594,8 +1767,9 @@ func (s *Enricher) getMediaType(infos []*TorrentInfo) models.MediaInfoMediaType
 	// trustworthy — single-digit "Movie - 1.mkv" pack titles, multi-CD
 	// movies, and odd codec tags can all leak an episode number out of a
 	// movie filename. With <3 files and a consistent title, treating it as
-	// a movie recovers far more cases than it breaks.
-	if len(infos) < 3 && sameTitle && !hasScenes && !hasSeasones {
+	// a movie recovers far more cases than it breaks. Fansub names are the
+	// exception: "[SubsPlease] Show - 07" is an episode even on its own.
+	if len(infos) < 3 && sameTitle && !hasScenes && !hasSeasones && !fansub {
 		return models.MediaInfoMediaTypeMovieSingle
 	}
 	if hasSeasones && hasEpisodes && hasDifferentSeasones {
@@ -1607,7 +1781,7 @@ func (s *Enricher) getMediaType(infos []*TorrentInfo) models.MediaInfoMediaType
 	// blocks Le-Hobbit-style multi-movie compilations (sameTitle=false
 	// because each movie has a different title) from being mislabelled as
 	// a series and falling through to KPU.
-	hasSequentialEpisodes := sameTitle && len(infos) >= 3 && hasEpisodes && !hasDifferentSeasones
+	hasSequentialEpisodes := sameTitle && (len(infos) >= 3 || fansub) && hasEpisodes && !hasDifferentSeasones
 	if (hasSeasones || hasSequentialEpisodes) && hasEpisodes && !hasDifferentSeasones {
 		return models.MediaInfoMediaTypeSeriesSingleSeason
 	}
@@ -1625,6 +1799,16 @@ func (s *Enricher) getMediaType(infos []*TorrentInfo) models.MediaInfoMediaType
 	return models.MediaInfoMediaTypeSeriesCompilation
 }
 
+// isFansubEpisode reports whether the file is named the way anime fansub
+// releases are, "[Group] Show - 07.mkv": the group tag leads the file name
+// and the episode number comes without a season.
+func isFansubEpisode(info *TorrentInfo) bool {
+	if info.Episode == 0 || info.Season != 0 || info.Group == "" || info.ListItem == nil {
+		return false
+	}
+	return strings.HasPrefix(path.Base(info.PathStr), "["+info.Group+"]")
+}
+
 func (s *Enricher) makeMovie(infos []*TorrentInfo, hash string) (*models.Movie, error) {
 	ti := infos[0]
 	movie := &models.Movie{
@@ -1690,10 +1874,15 @@ func (s *Enricher) makeSeriesWithEpisodes(infos []*TorrentInfo, hash string, mt
 		},
 		SeriesID: uuid.NewV4(),
 	}
+	// A release named by its tag alone, "[SubsPlease] - 01": the tag is a
+	// website or, for fansubs, the group.
 	title := ti.Title
-	if ti.Title == "" {
+	if title == "" {
 		title = ti.Website
 	}
+	if title == "" {
+		title = ti.Group
+	}
 	ser.Title = title
 	if ti.Year != 0 {
 		year := int16(ti.Year)
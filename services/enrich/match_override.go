@@ -0,0 +1,347 @@
+package enrich
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// Users correct wrong matches from the resource and library pages. A
+// correction is stored per user and resource (match_override) and applied
+// right away. The title most users with the resource in their library
+// picked decides the resource's match on every later enrichment, `enrich
+// run --force` included, and is never second-guessed by the mappers or the
+// AI fallback. Once MatchOverridePromoteAt of them on a paid plan correct
+// the same parsed name to the same title, the correction becomes global
+// (global_match_override) and mapMetadata applies it to every torrent with
+// that name.
+//
+// Only resources parsed into a single movie or a single series can be
+// corrected — a pack of movies has no one title to replace.
+
+// ErrNoMatchTarget means the resource holds no single title to correct:
+// it is a pack of movies or it was not enriched yet
+var ErrNoMatchTarget = errors.New("resource has no single title to correct")
+
+// ErrUnknownTitle means none of the mappers knows the picked title
+var ErrUnknownTitle = errors.New("unknown title")
+
+var videoIDReg = regexp.MustCompile(`^(tt\d+|kp\d+)$`)
+
+// MatchQueryKey folds a parsed title the way the title guards do, so
+// "The.Matrix" and "the matrix" count as the same name
+func MatchQueryKey(title string) string {
+	return strings.Join(titleTokens(title), " ")
+}
+
+func matchQueryYear(year *int16) int16 {
+	if year == nil {
+		return 0
+	}
+	return *year
+}
+
+// matchTarget returns the single movie or series the resource was parsed
+// into, or nil when there is none
+func matchTarget(movies []*models.Movie, series []*models.Series) models.VideoContentWithMetadata {
+	if len(movies) == 1 && len(series) == 0 {
+		return movies[0]
+	}
+	if len(movies) == 0 && len(series) == 1 {
+		return series[0]
+	}
+	return nil
+}
+
+// GetMatchTarget returns the title of the resource users can correct,
+// with its current metadata, or nil when there is none
+func (s *Enricher) GetMatchTarget(ctx context.Context, hash string) (models.VideoContentWithMetadata, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	movies, err := models.GetMoviesWithMetadataByResourceIDs(ctx, db, []string{hash})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get movies for hash %s", hash)
+	}
+	series, err := models.GetSeriesWithMetadataByResourceIDs(ctx, db, []string{hash})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get series for hash %s", hash)
+	}
+	return matchTarget(movies, series), nil
+}
+
+// SearchCandidates asks every mapper for the title and returns each
+// distinct answer, in mapper order. Unlike enrichment it does not stop at
+// the first hit: the user is the one choosing. A query that is a video id
+// (tt…, kp…) is looked up directly.
+func (s *Enricher) SearchCandidates(ctx context.Context, query string, year *int16, ct models.ContentType) ([]*models.VideoMetadata, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	if videoIDReg.MatchString(query) {
+		if md := s.lookupByHint(ctx, query, ct, false); md != nil {
+			return []*models.VideoMetadata{md}, nil
+		}
+		return nil, nil
+	}
+	vc := &models.VideoContent{
+		Title: query,
+		Year:  year,
+	}
+	var (
+		res      []*models.VideoMetadata
+		seen     = map[string]bool{}
+		firstErr error
+	)
+	for _, m := range s.mappers {
+		md, err := m.Map(ctx, vc, ct, false)
+		if err != nil {
+			log.WithError(err).WithField("mapper", m.GetName()).Warn("mapper failed during match search")
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "got \"%v\" mapper error", m.GetName())
+			}
+			continue
+		}
+		if md == nil || md.VideoID == "" || seen[md.VideoID] {
+			continue
+		}
+		seen[md.VideoID] = true
+		res = append(res, md)
+	}
+	if len(res) == 0 {
+		return nil, firstErr
+	}
+	return res, nil
+}
+
+// SaveMatchOverride stores the user's correction of the resource's match
+// and applies the correction that now decides it. A nil videoID means
+// "not a movie or show"; paid is whether the user is on a paid plan, the
+// caller having checked the resource is in their library.
+func (s *Enricher) SaveMatchOverride(ctx context.Context, userID uuid.UUID, hash string, videoID *string, paid bool) error {
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	movies, series, err := s.getResourceTitles(ctx, db, hash)
+	if err != nil {
+		return err
+	}
+	target := matchTarget(movies, series)
+	if target == nil {
+		return ErrNoMatchTarget
+	}
+	ct := target.GetContentType()
+	if videoID != nil && s.lookupByHint(ctx, *videoID, ct, false) == nil {
+		return ErrUnknownTitle
+	}
+	content := target.GetContent()
+	o := &models.MatchOverride{
+		UserID:      userID,
+		ResourceID:  hash,
+		ContentType: ct,
+		VideoID:     videoID,
+		QueryTitle:  MatchQueryKey(content.Title),
+		QueryYear:   matchQueryYear(content.Year),
+		Paid:        paid,
+	}
+	if err := models.UpsertMatchOverride(ctx, db, o); err != nil {
+		return errors.Wrap(err, "failed to store match override")
+	}
+	// A free pick decides its own resource but counts for no other one.
+	if paid {
+		promoted, err := models.PromoteMatchOverride(ctx, db, ct, o.QueryTitle, o.QueryYear, videoID)
+		if err != nil {
+			// The user's own correction is stored; promotion is retried by
+			// the next user who agrees.
+			log.WithError(err).WithField("hash", hash).Warn("failed to promote match override")
+		} else if promoted {
+			log.WithFields(log.Fields{
+				"query": o.QueryTitle,
+				"year":  o.QueryYear,
+				"type":  ct,
+			}).Info("match override promoted to global")
+		}
+	}
+	_, err = s.applyMatchOverride(ctx, db, hash, movies, series, false)
+	return err
+}
+
+// ResetMatchOverride withdraws the user's correction. When a correction
+// decided the resource's match, the resource goes back to what the other
+// users picked, or to the enricher's own match when that no longer holds.
+func (s *Enricher) ResetMatchOverride(ctx context.Context, userID uuid.UUID, hash string) error {
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	decided, err := models.GetResourceMatchOverride(ctx, db, hash)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get match override for hash %s", hash)
+	}
+	if err := models.DeleteUserMatchOverride(ctx, db, userID, hash); err != nil {
+		return errors.Wrap(err, "failed to delete match override")
+	}
+	if decided == nil {
+		// No library owner's pick decided the shared rows, so there is
+		// nothing to undo.
+		return nil
+	}
+	movies, series, err := s.getResourceTitles(ctx, db, hash)
+	if err != nil {
+		return err
+	}
+	applied, err := s.applyMatchOverride(ctx, db, hash, movies, series, false)
+	if err != nil || applied {
+		return err
+	}
+	target := matchTarget(movies, series)
+	if target == nil {
+		return nil
+	}
+	// No path hint: a reset is not worth an AI call, the next enrichment
+	// run can still make one.
+	md, err := s.mapMetadata(ctx, target.GetContent(), target.GetContentType(), false, "", "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to map metadata for hash %s", hash)
+	}
+	return s.linkMatchTarget(ctx, db, target, md, false)
+}
+
+func (s *Enricher) getResourceTitles(ctx context.Context, db *pg.DB, hash string) ([]*models.Movie, []*models.Series, error) {
+	movies, err := models.GetMoviesByResourceID(ctx, db, hash)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get movies for hash %s", hash)
+	}
+	series, err := models.GetSeriesByResourceID(ctx, db, hash)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get series for hash %s", hash)
+	}
+	return movies, series, nil
+}
+
+// applyMatchOverride links the resource to the title its users picked.
+// Reports false, touching nothing, when the resource has no correction
+// or its correction no longer fits it (the files were re-parsed as the
+// other content type). A picked title the mappers fail to resolve is an
+// error rather than a reason to search: searching is what got the match
+// wrong.
+func (s *Enricher) applyMatchOverride(ctx context.Context, db *pg.DB, hash string, movies []*models.Movie, series []*models.Series, force bool) (bool, error) {
+	target := matchTarget(movies, series)
+	if target == nil {
+		return false, nil
+	}
+	ov, err := models.GetResourceMatchOverride(ctx, db, hash)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get match override for hash %s", hash)
+	}
+	if ov == nil {
+		return false, nil
+	}
+	if ov.ContentType != target.GetContentType() {
+		log.WithFields(log.Fields{
+			"hash":     hash,
+			"override": ov.ContentType,
+			"parsed":   target.GetContentType(),
+		}).Warn("match override does not fit the resource any more, ignoring")
+		return false, nil
+	}
+	var md *models.VideoMetadata
+	if !ov.NotMedia() {
+		md = s.lookupByHint(ctx, *ov.VideoID, ov.ContentType, force)
+		if md == nil {
+			return false, errors.Errorf("failed to resolve match override %v for hash %s", *ov.VideoID, hash)
+		}
+	}
+	log.WithFields(log.Fields{
+		"hash":      hash,
+		"video_id":  ov.VideoID,
+		"users":     ov.Users,
+		"not_media": ov.NotMedia(),
+	}).Info("applying match override")
+	return true, s.linkMatchTarget(ctx, db, target, md, force)
+}
+
+// linkMatchTarget links the title to the metadata, or unlinks it when md
+// is nil
+func (s *Enricher) linkMatchTarget(ctx context.Context, db *pg.DB, target models.VideoContentWithMetadata, md *models.VideoMetadata, force bool) error {
+	switch t := target.(type) {
+	case *models.Movie:
+		if md == nil {
+			_, err := models.DetachMovieMetadata(ctx, db, []uuid.UUID{t.MovieID})
+			return errors.Wrapf(err, "failed to detach metadata from movie %v", t.MovieID)
+		}
+		metadataID, err := models.UpsertMovieMetadata(ctx, db, md)
+		if err != nil {
+			return errors.Wrapf(err, "failed to upsert metadata for movie %+v", md)
+		}
+		return errors.Wrapf(models.LinkMovieToMetadata(ctx, db, t.MovieID, metadataID),
+			"failed to link movie %v with metadata", t.MovieID)
+	case *models.Series:
+		if md == nil {
+			_, err := models.DetachSeriesMetadata(ctx, db, []uuid.UUID{t.SeriesID})
+			return errors.Wrapf(err, "failed to detach metadata from series %v", t.SeriesID)
+		}
+		metadataID, err := models.UpsertSeriesMetadata(ctx, db, md)
+		if err != nil {
+			return errors.Wrapf(err, "failed to upsert series metadata %+v", md)
+		}
+		if err := models.LinkSeriesToMetadata(ctx, db, t.SeriesID, metadataID); err != nil {
+			return errors.Wrapf(err, "failed to link series %v with metadata", t.SeriesID)
+		}
+		if len(s.episodeMappers) > 0 {
+			if err := s.enrichEpisodes(ctx, db, t, md.VideoID, force); err != nil {
+				log.WithError(err).Warnf("failed to enrich episodes for series %v", md.VideoID)
+			}
+		}
+	}
+	return nil
+}
+
+// globalMatch applies the global correction for the parsed name, if there
+// is one. Reports whether it decided the match; a nil result then means
+// "not a movie or show". A corrected title the mappers cannot resolve
+// right now falls through to the regular search.
+func (s *Enricher) globalMatch(ctx context.Context, vc *models.VideoContent, t models.ContentType, f bool) (*models.VideoMetadata, bool) {
+	if s.pg == nil {
+		return nil, false
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, false
+	}
+	key := MatchQueryKey(vc.Title)
+	if key == "" {
+		return nil, false
+	}
+	g, err := models.GetGlobalMatchOverride(ctx, db, t, key, matchQueryYear(vc.Year))
+	if err != nil {
+		log.WithError(err).WithField("title", vc.Title).Warn("failed to get global match override")
+		return nil, false
+	}
+	if g == nil {
+		return nil, false
+	}
+	if g.NotMedia() {
+		log.WithField("title", vc.Title).Info("global match override: not a movie or show")
+		return nil, true
+	}
+	md := s.lookupByHint(ctx, *g.VideoID, t, f)
+	if md == nil {
+		return nil, false
+	}
+	log.WithFields(log.Fields{
+		"title":    vc.Title,
+		"video_id": *g.VideoID,
+	}).Info("metadata resolved via global match override")
+	return md, true
+}
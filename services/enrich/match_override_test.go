@@ -0,0 +1,87 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+func TestMatchQueryKey(t *testing.T) {
+	cases := map[string]string{
+		"The.Matrix":      "the matrix",
+		"  the   MATRIX ": "the matrix",
+		"Amélie":          "amelie",
+		"...":             "",
+	}
+	for in, want := range cases {
+		if got := MatchQueryKey(in); got != want {
+			t.Errorf("MatchQueryKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchTarget(t *testing.T) {
+	movie := &models.Movie{}
+	series := &models.Series{}
+	if got := matchTarget([]*models.Movie{movie}, nil); got != movie {
+		t.Errorf("single movie: got %v", got)
+	}
+	if got := matchTarget(nil, []*models.Series{series}); got != series {
+		t.Errorf("single series: got %v", got)
+	}
+	if got := matchTarget([]*models.Movie{movie, {}}, nil); got != nil {
+		t.Errorf("movie pack: got %v, want nil", got)
+	}
+	if got := matchTarget(nil, nil); got != nil {
+		t.Errorf("nothing parsed: got %v, want nil", got)
+	}
+}
+
+// TestSearchCandidates locks in that the match search asks every mapper
+// rather than stopping at the first hit, and drops repeated answers.
+func TestSearchCandidates(t *testing.T) {
+	matrix := &models.VideoMetadata{VideoID: "tt0133093", Title: "The Matrix"}
+	t.Run("every mapper, deduplicated", func(t *testing.T) {
+		en := &Enricher{mappers: []MetadataMapper{
+			&fakeMapper{name: "TMDB", mapResult: matrix},
+			&fakeMapper{name: "OMDB", mapResult: &models.VideoMetadata{VideoID: "tt0133093", Title: "Matrix"}},
+			&fakeMapper{name: "Kinopoisk", mapResult: &models.VideoMetadata{VideoID: "kp301", Title: "Матрица"}},
+		}}
+		res, err := en.SearchCandidates(context.Background(), "matrix", nil, models.ContentTypeMovie)
+		if err != nil {
+			t.Fatalf("SearchCandidates: %v", err)
+		}
+		if len(res) != 2 || res[0] != matrix || res[1].VideoID != "kp301" {
+			t.Fatalf("got %+v", res)
+		}
+	})
+	t.Run("mapper error surfaces only without results", func(t *testing.T) {
+		en := &Enricher{mappers: []MetadataMapper{
+			&fakeMapper{name: "TMDB", mapErr: errors.New("rate limited")},
+			&fakeMapper{name: "OMDB", mapResult: matrix},
+		}}
+		res, err := en.SearchCandidates(context.Background(), "matrix", nil, models.ContentTypeMovie)
+		if err != nil || len(res) != 1 {
+			t.Fatalf("got %+v, %v", res, err)
+		}
+		en.mappers = en.mappers[:1]
+		if _, err := en.SearchCandidates(context.Background(), "matrix", nil, models.ContentTypeMovie); err == nil {
+			t.Fatal("expected the mapper error")
+		}
+	})
+	t.Run("video id is looked up directly", func(t *testing.T) {
+		en := &Enricher{mappers: []MetadataMapper{
+			&fakeMapper{
+				name:      "TMDB",
+				mapResult: &models.VideoMetadata{VideoID: "tt9999999"},
+				byID:      map[string]*models.VideoMetadata{"tt0133093": matrix},
+			},
+		}}
+		res, err := en.SearchCandidates(context.Background(), " tt0133093 ", nil, models.ContentTypeMovie)
+		if err != nil || len(res) != 1 || res[0] != matrix {
+			t.Fatalf("got %+v, %v", res, err)
+		}
+	})
+}
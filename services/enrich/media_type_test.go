@@ -26,6 +26,13 @@ func mkMovieInfo(title string, year int, path string) *TorrentInfo {
 	}
 }
 
+func mkFansubInfo(title, group string, episode int, path string) *TorrentInfo {
+	return &TorrentInfo{
+		TorrentInfo: &ptn.TorrentInfo{Title: title, Group: group, Episode: episode},
+		ListItem:    &ra.ListItem{PathStr: path},
+	}
+}
+
 // Each case mirrors a real-world torrent we hit in production. The
 // classifier was historically too eager to declare SeriesSingleSeason
 // whenever the parser saw any episode-like number — which then routed
@@ -107,6 +114,20 @@ func TestGetMediaType(t *testing.T) {
 			},
 			models.MediaInfoMediaTypeSeriesSingleSeason,
 		},
+		{
+			"single fansub episode, group tag leads the file name",
+			[]*TorrentInfo{
+				mkFansubInfo("One Piece", "SubsPlease", 1071, "/[SubsPlease] One Piece - 1071 (1080p) [6A8D4F1B].mkv"),
+			},
+			models.MediaInfoMediaTypeSeriesSingleSeason,
+		},
+		{
+			"single file with a group but not fansub-named stays a movie",
+			[]*TorrentInfo{
+				mkFansubInfo("Interestelar", "GRP", 1046, "/Interestelar - 1046-GRP.mkv"),
+			},
+			models.MediaInfoMediaTypeMovieSingle,
+		},
 		{
 			"split-scenes torrent",
 			[]*TorrentInfo{
@@ -0,0 +1,343 @@
+package enrich
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/api"
+)
+
+// MusicMapper identifies albums, as MetadataMapper identifies videos
+type MusicMapper interface {
+	MapAlbum(ctx context.Context, q *AlbumQuery, force bool) (*models.AlbumMetadata, error)
+	GetName() string
+}
+
+// AlbumQuery is an album as its folder and tags name it
+type AlbumQuery struct {
+	Artist string
+	Title  string
+	Year   *int16
+}
+
+// foundAlbum is one album folder of a torrent and the tracks under it
+type foundAlbum struct {
+	dir    string
+	tracks []*ra.ListItem
+}
+
+// discFolder matches the per-disc folders of a multi-disc album: "CD1",
+// "Disc 2", "Disk 3 - Bonus".
+var discFolder = regexp.MustCompile(`(?i)^(cd|disc|disk)\s*\d+\b`)
+
+// albumDir is the folder an audio file's album lives in: its own, or the
+// one above when it sits in a disc folder.
+func albumDir(p string) string {
+	dir := path.Dir(p)
+	if dir != "/" && discFolder.MatchString(path.Base(dir)) {
+		dir = path.Dir(dir)
+	}
+	return dir
+}
+
+// findAlbums groups a torrent's audio files by album folder, in path
+// order. A discography comes out as an album per folder.
+func findAlbums(items []ra.ListItem) []*foundAlbum {
+	byDir := map[string]*foundAlbum{}
+	var res []*foundAlbum
+	for i := range items {
+		item := &items[i]
+		if item.Type != ra.ListTypeFile || item.MediaFormat != ra.Audio {
+			continue
+		}
+		dir := albumDir(item.PathStr)
+		fa, ok := byDir[dir]
+		if !ok {
+			fa = &foundAlbum{dir: dir}
+			byDir[dir] = fa
+			res = append(res, fa)
+		}
+		fa.tracks = append(fa.tracks, item)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].dir < res[j].dir
+	})
+	for _, fa := range res {
+		sort.Slice(fa.tracks, func(i, j int) bool {
+			return fa.tracks[i].PathStr < fa.tracks[j].PathStr
+		})
+	}
+	return res
+}
+
+var (
+	// Release details in brackets: "[FLAC]", "{24-96}", "[2011 Remaster]".
+	albumBrackets = regexp.MustCompile(`\[[^\]]*\]|\{[^}]*\}`)
+	// Parenthesised ones, told from "(Live at Pompeii)" by their words.
+	albumFormatParens = regexp.MustCompile(`(?i)\((?:[^)]*\b(?:flac|mp3|aac|alac|ape|wv|ogg|opus|m4a|wav|dsd|sacd|lossless|cbr|vbr|v0|320|256|\d+\s*kbps|\d+\s*bit|\d+[-/]\d+|web|vinyl|cd|remaster(?:ed)?|deluxe)\b[^)]*)\)`)
+	albumYearParen    = regexp.MustCompile(`\(((?:19|20)\d{2})\)`)
+	albumYearToken    = regexp.MustCompile(`^(?:19|20)\d{2}$`)
+	albumLeadingYear  = regexp.MustCompile(`^\(?((?:19|20)\d{2})\)?\s*[-.]?\s+`)
+	// "Pink Floyd - Discography 1967-2014", "Queen (Complete Discography)".
+	artistCollection = regexp.MustCompile(`(?i)(?:^|[\s\-(]+)(?:the\s+)?(?:complete\s+)?(?:discography|дискография|collection|albums)(?:[\s)].*)?$`)
+	spaces           = regexp.MustCompile(`\s+`)
+)
+
+func cleanAlbumPart(s string) string {
+	s = strings.ReplaceAll(s, "_", " ")
+	s = spaces.ReplaceAllString(s, " ")
+	return strings.Trim(s, " -.")
+}
+
+// parseAlbumFolder reads an album out of its folder path. It knows the
+// usual layouts:
+//
+//	/Artist - Album (1973) [FLAC]
+//	/Artist - 1973 - Album
+//	/Artist/1973 - Album
+//	/Artist - Discography/(1973) Album
+//	/Artist/Album
+//
+// A part it cannot tell is left empty.
+func parseAlbumFolder(dir string) (artist string, title string, year *int16) {
+	if dir == "/" || dir == "." {
+		return "", "", nil
+	}
+	if artist, title, year, ok := parseSceneAlbum(path.Base(dir)); ok {
+		return artist, title, year
+	}
+	name := albumBrackets.ReplaceAllString(path.Base(dir), " ")
+	name = albumFormatParens.ReplaceAllString(name, " ")
+	var y string
+	if m := albumYearParen.FindStringSubmatch(name); m != nil {
+		y = m[1]
+		name = strings.Replace(name, m[0], " ", 1)
+	}
+	name = cleanAlbumPart(name)
+	if m := albumLeadingYear.FindStringSubmatch(name); m != nil && len(name) > len(m[0]) {
+		y = m[1]
+		name = name[len(m[0]):]
+	}
+
+	// A bare year between dashes is the year; alone after the artist it
+	// is a title, as in "Van Halen - 1984".
+	split := strings.Split(name, " - ")
+	var parts []string
+	for _, p := range split {
+		if p = cleanAlbumPart(p); p == "" {
+			continue
+		}
+		if len(split) > 2 && albumYearToken.MatchString(p) {
+			y = p
+			continue
+		}
+		parts = append(parts, p)
+	}
+	switch len(parts) {
+	case 0:
+	case 1:
+		title = parts[0]
+	default:
+		artist = parts[0]
+		title = strings.Join(parts[1:], " - ")
+	}
+	if artist == "" {
+		if parent := path.Dir(dir); parent != "/" && parent != "." {
+			pn := albumBrackets.ReplaceAllString(path.Base(parent), " ")
+			pn = artistCollection.ReplaceAllString(pn, "")
+			artist = cleanAlbumPart(pn)
+		}
+	}
+	if y != "" {
+		n, _ := strconv.Atoi(y)
+		yy := int16(n)
+		year = &yy
+	}
+	return
+}
+
+// parseSceneAlbum reads the scene form, "Daft_Punk-Discovery-(WEB)-2001-GRP":
+// no spaces, the artist and album first, the year after the source.
+func parseSceneAlbum(name string) (artist string, title string, year *int16, ok bool) {
+	if strings.Contains(name, " ") {
+		return "", "", nil, false
+	}
+	parts := strings.Split(name, "-")
+	for i := 2; i < len(parts); i++ {
+		if !albumYearToken.MatchString(parts[i]) {
+			continue
+		}
+		artist, title = cleanAlbumPart(parts[0]), cleanAlbumPart(parts[1])
+		if artist == "" || title == "" {
+			return "", "", nil, false
+		}
+		n, _ := strconv.Atoi(parts[i])
+		y := int16(n)
+		return artist, title, &y, true
+	}
+	return "", "", nil, false
+}
+
+// trackTags probes the first track of an album for its tags. Folder names
+// are tried first; this costs a round-trip through the content prober.
+func (s *Enricher) trackTags(ctx context.Context, hash string, claims *api.Claims, item *ra.ListItem) (*api.MediaProbe, error) {
+	er, err := s.api.ExportResourceContent(ctx, claims, hash, item.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	dl, ok := er.ExportItems["download"]
+	if !ok || dl.URL == "" {
+		return nil, errors.New("no download export item")
+	}
+	pctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return s.api.GetMediaProbe(pctx, contentProbeURL(dl.URL))
+}
+
+// contentProbeURL injects the ~cp suffix between path and query so the
+// request hits the content prober rather than the raw download.
+func contentProbeURL(dlURL string) string {
+	if i := strings.IndexByte(dlURL, '?'); i >= 0 {
+		return dlURL[:i] + "~cp" + dlURL[i:]
+	}
+	return dlURL + "~cp"
+}
+
+// fillFromTags completes an album with what the track tags say
+func fillFromTags(a *models.Album, mp *api.MediaProbe) {
+	if mp == nil {
+		return
+	}
+	tags := mp.Format.Tags
+	if a.Artist == "" {
+		for _, v := range []string{tags.AlbumArtist, tags.VorbisAlbumArtist, tags.Artist} {
+			if v = strings.TrimSpace(v); v != "" {
+				a.Artist = v
+				break
+			}
+		}
+	}
+	if a.Title == "" {
+		a.Title = strings.TrimSpace(tags.Album)
+	}
+	if a.Year == nil && len(tags.Date) >= 4 && albumYearToken.MatchString(tags.Date[:4]) {
+		n, _ := strconv.Atoi(tags.Date[:4])
+		y := int16(n)
+		a.Year = &y
+	}
+}
+
+func (s *Enricher) makeAlbums(ctx context.Context, hash string, claims *api.Claims, fas []*foundAlbum) []*models.Album {
+	var albums []*models.Album
+	for _, fa := range fas {
+		artist, title, year := parseAlbumFolder(fa.dir)
+		a := &models.Album{
+			ResourceID: hash,
+			Artist:     artist,
+			Title:      title,
+			Year:       year,
+			Path:       fa.dir,
+			TrackCount: len(fa.tracks),
+		}
+		if (a.Artist == "" || a.Title == "") && s.api != nil {
+			mp, err := s.trackTags(ctx, hash, claims, fa.tracks[0])
+			if err != nil {
+				log.WithError(err).WithField("hash", hash).WithField("path", fa.tracks[0].PathStr).
+					Warn("failed to probe track tags")
+			}
+			fillFromTags(a, mp)
+		}
+		if a.Title == "" {
+			log.WithField("hash", hash).WithField("path", fa.dir).Info("album without a name, skipped")
+			continue
+		}
+		a.Metadata = map[string]any{
+			"folder": path.Base(fa.dir),
+		}
+		albums = append(albums, a)
+	}
+	return albums
+}
+
+// enrichMusic stores a torrent's audio as albums and identifies them.
+// It runs when the torrent carries no video; nil means there was no
+// album to be found either.
+func (s *Enricher) enrichMusic(ctx context.Context, db *pg.DB, hash string, claims *api.Claims, items []ra.ListItem, force bool) (*models.MediaInfoMediaType, error) {
+	fas := findAlbums(items)
+	if len(fas) == 0 {
+		return nil, nil
+	}
+	albums := s.makeAlbums(ctx, hash, claims, fas)
+	if len(albums) == 0 {
+		return nil, nil
+	}
+	log.Infof("got %v albums for hash %v", len(albums), hash)
+
+	// A resource enriched before as video — a re-run after the files
+	// changed, say — keeps no stale movie or series rows.
+	if err := models.ReplaceMoviesForResource(ctx, db, hash, nil); err != nil {
+		return nil, errors.Wrapf(err, "failed to replace movie for hash %s", hash)
+	}
+	if err := models.ReplaceSeriesForResource(ctx, db, hash, nil); err != nil {
+		return nil, errors.Wrapf(err, "failed to replace series for hash %s", hash)
+	}
+	if err := models.ReplaceAlbumsForResource(ctx, db, hash, albums); err != nil {
+		return nil, errors.Wrapf(err, "failed to replace albums for hash %s", hash)
+	}
+
+	albums, err := models.GetAlbumsByResourceID(ctx, db, hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get albums for hash %s", hash)
+	}
+
+	mt := models.MediaInfoMediaTypeMusicAlbum
+	for _, a := range albums {
+		if a.Artist == "" {
+			continue
+		}
+		md := s.mapAlbum(ctx, a, force)
+		if md == nil {
+			log.Warnf("no metadata for album %v - %v", a.Artist, a.Title)
+			continue
+		}
+		metadataID, err := models.UpsertAlbumMetadata(ctx, db, md)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to upsert metadata for album %+v hash %s", md, hash)
+		}
+		if err := models.LinkAlbumToMetadata(ctx, db, a.AlbumID, metadataID); err != nil {
+			return nil, errors.Wrapf(err, "failed to link album %v with metadata for hash %s", a.Path, hash)
+		}
+	}
+	return &mt, nil
+}
+
+// mapAlbum asks the music mappers in turn. A failing one is skipped
+// rather than failing the resource: MusicBrainz turns clients away
+// above its rate limit, and the albums are stored either way.
+func (s *Enricher) mapAlbum(ctx context.Context, a *models.Album, force bool) *models.AlbumMetadata {
+	q := &AlbumQuery{Artist: a.Artist, Title: a.Title, Year: a.Year}
+	for _, m := range s.musicMappers {
+		md, err := m.MapAlbum(ctx, q, force)
+		if err != nil {
+			log.WithError(err).
+				WithField("mapper", m.GetName()).
+				WithField("album", a.Artist+" - "+a.Title).
+				Warn("failed to map album")
+			continue
+		}
+		if md != nil {
+			return md
+		}
+	}
+	return nil
+}
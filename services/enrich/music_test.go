@@ -0,0 +1,117 @@
+package enrich
+
+import (
+	"testing"
+
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/musicbrainz"
+)
+
+func TestParseAlbumFolder(t *testing.T) {
+	for _, tt := range []struct {
+		dir    string
+		artist string
+		title  string
+		year   int16
+	}{
+		{"/Pink Floyd - The Dark Side of the Moon (1973) [FLAC]", "Pink Floyd", "The Dark Side of the Moon", 1973},
+		{"/Pink Floyd - 1977 - Animals", "Pink Floyd", "Animals", 1977},
+		{"/Radiohead/2000 - Kid A", "Radiohead", "Kid A", 2000},
+		{"/Queen - Discography 1973-1995/(1975) A Night at the Opera", "Queen", "A Night at the Opera", 1975},
+		{"/Queen (Complete Discography)/1977 - News of the World", "Queen", "News of the World", 1977},
+		{"/Miles Davis/Kind of Blue", "Miles Davis", "Kind of Blue", 0},
+		{"/Daft_Punk-Discovery-(WEB)-2001-GRP", "Daft Punk", "Discovery", 2001},
+		{"/Van Halen - 1984", "Van Halen", "1984", 0},
+		{"/Led Zeppelin - Led Zeppelin IV (2014 Remaster) [24-96]", "Led Zeppelin", "Led Zeppelin IV", 0},
+		{"/Pink Floyd - Live at Pompeii (Live)", "Pink Floyd", "Live at Pompeii (Live)", 0},
+		{"/", "", "", 0},
+	} {
+		artist, title, year := parseAlbumFolder(tt.dir)
+		var y int16
+		if year != nil {
+			y = *year
+		}
+		if artist != tt.artist || title != tt.title || y != tt.year {
+			t.Errorf("parseAlbumFolder(%q) = %q, %q, %d; want %q, %q, %d", tt.dir, artist, title, y, tt.artist, tt.title, tt.year)
+		}
+	}
+}
+
+func track(p string) ra.ListItem {
+	return ra.ListItem{PathStr: p, Type: ra.ListTypeFile, MediaFormat: ra.Audio}
+}
+
+func TestFindAlbums(t *testing.T) {
+	items := []ra.ListItem{
+		track("/Pink Floyd - Discography/1979 - The Wall/CD2/01 Hey You.flac"),
+		track("/Pink Floyd - Discography/1979 - The Wall/CD1/01 In the Flesh.flac"),
+		track("/Pink Floyd - Discography/1979 - The Wall/CD1/02 The Thin Ice.flac"),
+		track("/Pink Floyd - Discography/1977 - Animals/01 Pigs on the Wing.flac"),
+		{PathStr: "/Pink Floyd - Discography/1977 - Animals/cover.jpg", Type: ra.ListTypeFile, MediaFormat: ra.Image},
+		{PathStr: "/Pink Floyd - Discography/1977 - Animals/animals.cue", Type: ra.ListTypeFile},
+	}
+	got := findAlbums(items)
+	if len(got) != 2 {
+		t.Fatalf("got %d albums, want 2", len(got))
+	}
+	if got[0].dir != "/Pink Floyd - Discography/1977 - Animals" || len(got[0].tracks) != 1 {
+		t.Errorf("first album %s with %d tracks", got[0].dir, len(got[0].tracks))
+	}
+	if got[1].dir != "/Pink Floyd - Discography/1979 - The Wall" || len(got[1].tracks) != 3 {
+		t.Errorf("second album %s with %d tracks", got[1].dir, len(got[1].tracks))
+	}
+	if got[1].tracks[0].PathStr != "/Pink Floyd - Discography/1979 - The Wall/CD1/01 In the Flesh.flac" {
+		t.Errorf("tracks out of order: first is %s", got[1].tracks[0].PathStr)
+	}
+}
+
+func TestFillFromTags(t *testing.T) {
+	mp := &api.MediaProbe{}
+	mp.Format.Tags.Artist = "Roger Waters"
+	mp.Format.Tags.VorbisAlbumArtist = "Pink Floyd"
+	mp.Format.Tags.Album = "The Final Cut"
+	mp.Format.Tags.Date = "1983-03-21"
+	a := &models.Album{Title: "Final Cut"}
+	fillFromTags(a, mp)
+	if a.Artist != "Pink Floyd" || a.Title != "Final Cut" || a.Year == nil || *a.Year != 1983 {
+		t.Errorf("album %+v", a)
+	}
+}
+
+func releaseGroup(id, title, artist, date, primary string, secondary ...string) *musicbrainz.ReleaseGroup {
+	g := &musicbrainz.ReleaseGroup{ID: id, Title: title, FirstReleaseDate: date, PrimaryType: primary, SecondaryTypes: secondary}
+	g.ArtistCredit = []musicbrainz.ArtistCredit{{Name: artist, Artist: musicbrainz.Artist{Name: artist}}}
+	return g
+}
+
+func TestPickReleaseGroup(t *testing.T) {
+	res := []*musicbrainz.ReleaseGroup{
+		releaseGroup("live", "Animals", "Pink Floyd", "1977-05-01", "Album", "Live"),
+		releaseGroup("other", "Animals", "Maroon 5", "2014", "Single"),
+		releaseGroup("album", "Animals", "Pink Floyd", "1977-01-23", "Album"),
+		releaseGroup("remix", "Animals (2018 Remix)", "Pink Floyd", "2022", "Album"),
+		releaseGroup("beatles", "Abbey Road", "The Beatles", "1969-09-26", "Album"),
+	}
+	y := int16(1977)
+	for _, tt := range []struct {
+		q    AlbumQuery
+		want string
+	}{
+		{AlbumQuery{Artist: "Pink Floyd", Title: "Animals", Year: &y}, "album"},
+		{AlbumQuery{Artist: "pink floyd", Title: "ANIMALS"}, "album"},
+		{AlbumQuery{Artist: "Maroon 5", Title: "Animals"}, "other"},
+		{AlbumQuery{Artist: "Beatles", Title: "Abbey Road"}, "beatles"},
+		{AlbumQuery{Artist: "Pink Floyd", Title: "Meddle"}, ""},
+		{AlbumQuery{Artist: "Roger Waters", Title: "Animals"}, ""},
+	} {
+		got := ""
+		if g := pickReleaseGroup(res, &tt.q); g != nil {
+			got = g.ID
+		}
+		if got != tt.want {
+			t.Errorf("pickReleaseGroup(%q, %q) = %q, want %q", tt.q.Artist, tt.q.Title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package enrich
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/models"
+	mm "github.com/webtor-io/web-ui/models/musicbrainz"
+	"github.com/webtor-io/web-ui/services/musicbrainz"
+)
+
+// MusicBrainz identifies albums as MusicBrainz release groups and takes
+// their front covers from the Cover Art Archive.
+type MusicBrainz struct {
+	pg  *cs.PG
+	api *musicbrainz.Api
+}
+
+func (s *MusicBrainz) GetName() string {
+	return "MusicBrainz"
+}
+
+func NewMusicBrainz(pg *cs.PG, api *musicbrainz.Api) *MusicBrainz {
+	if api == nil {
+		return nil
+	}
+	return &MusicBrainz{
+		pg:  pg,
+		api: api,
+	}
+}
+
+func (s *MusicBrainz) MapAlbum(ctx context.Context, q *AlbumQuery, force bool) (*models.AlbumMetadata, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if q.Artist == "" || q.Title == "" {
+		return nil, nil
+	}
+	cached, err := mm.GetQuery(ctx, db, q.Artist, q.Title)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && !force {
+		if cached.MBID == nil {
+			return nil, nil
+		}
+		md, err := models.GetAlbumMetadataByMBID(ctx, db, *cached.MBID)
+		if err != nil || md != nil {
+			return md, err
+		}
+		rg, err := mm.GetReleaseGroupByMBID(ctx, db, *cached.MBID)
+		if err != nil || rg == nil {
+			return nil, err
+		}
+		return s.metadata(ctx, rg)
+	}
+	res, err := s.api.SearchReleaseGroups(ctx, q.Artist, q.Title)
+	if err != nil {
+		return nil, err
+	}
+	g := pickReleaseGroup(res, q)
+	if g == nil {
+		_, err = mm.UpsertQuery(ctx, db, q.Artist, q.Title, nil)
+		return nil, err
+	}
+	var year *int16
+	if y := g.Year(); y != 0 {
+		yy := int16(y)
+		year = &yy
+	}
+	rg, err := mm.UpsertReleaseGroup(ctx, db, g.ID, g.Title, g.Artist(), year, g.Raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store release group")
+	}
+	if _, err = mm.UpsertQuery(ctx, db, q.Artist, q.Title, &g.ID); err != nil {
+		return nil, err
+	}
+	return s.metadata(ctx, rg)
+}
+
+func (s *MusicBrainz) metadata(ctx context.Context, rg *mm.ReleaseGroup) (*models.AlbumMetadata, error) {
+	cover, err := s.api.FrontCover(ctx, rg.MBID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AlbumMetadata{
+		MBID:     rg.MBID,
+		Artist:   rg.Artist,
+		Title:    rg.Title,
+		Year:     rg.Year,
+		CoverURL: cover,
+	}, nil
+}
+
+// pickReleaseGroup returns the release group titled and credited like
+// q, nil when none is. The search matches words, so "Animals" by Pink
+// Floyd also brings up every live bootleg with the word in it. Among
+// exact matches a plain album beats a live one or a compilation, then
+// the one released in q's year, then MusicBrainz's order.
+func pickReleaseGroup(res []*musicbrainz.ReleaseGroup, q *AlbumQuery) *musicbrainz.ReleaseGroup {
+	title := MatchQueryKey(q.Title)
+	artist := artistKey(q.Artist)
+	var best *musicbrainz.ReleaseGroup
+	bestScore := -1
+	for _, g := range res {
+		if MatchQueryKey(g.Title) != title || !creditedTo(g, artist) {
+			continue
+		}
+		score := 0
+		if g.PrimaryType == "Album" && len(g.SecondaryTypes) == 0 {
+			score += 2
+		}
+		if q.Year != nil && g.Year() == int(*q.Year) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = g, score
+		}
+	}
+	return best
+}
+
+// artistKey folds an artist name for comparison. Folders drop the
+// article as often as not: "Beatles" is "The Beatles".
+func artistKey(s string) string {
+	if k := strings.Join(significantTitleTokens(s), " "); k != "" {
+		return k
+	}
+	return MatchQueryKey(s)
+}
+
+// creditedTo reports whether artist, an artistKey, is the group's whole
+// credit or one of the artists in it
+func creditedTo(g *musicbrainz.ReleaseGroup, artist string) bool {
+	if artistKey(g.Artist()) == artist {
+		return true
+	}
+	for _, ac := range g.ArtistCredit {
+		if artistKey(ac.Name) == artist || artistKey(ac.Artist.Name) == artist {
+			return true
+		}
+	}
+	return false
+}
+
+var _ MusicMapper = (*MusicBrainz)(nil)
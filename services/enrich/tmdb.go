@@ -45,11 +45,18 @@ func reviewHasLink(content string) bool {
 	return reviewLinkRe.MatchString(content)
 }
 
+// externalIDs is what ExternalIDs caches per video.
+type externalIDs struct {
+	TMDBID int
+	TVDBID int
+}
+
 type TMDB struct {
 	api      *tmdb.Api
 	pg       *cs.PG
 	locCache *lazymap.LazyMap[*localizedText]
 	revCache *lazymap.LazyMap[[]Review]
+	idCache  *lazymap.LazyMap[*externalIDs]
 }
 
 func (s *TMDB) GetName() string {
@@ -76,6 +83,13 @@ func NewTMDB(pg *cs.PG, api *tmdb.Api) *TMDB {
 			// can't grow it unbounded.
 			Capacity: 1000,
 		}),
+		// A title's ids never change; the day-long expiry only bounds how
+		// long a TMDB-side correction takes to arrive.
+		idCache: lazymap.New[*externalIDs](&lazymap.Config{
+			Expire:      24 * time.Hour,
+			ErrorExpire: time.Minute,
+			Capacity:    10000,
+		}),
 	}
 }
 
@@ -296,6 +310,34 @@ func (s *TMDB) GetTmdbID(ctx context.Context, videoID string, ct models.ContentT
 	return 0, ct, nil
 }
 
+// ExternalIDs implements ExternalIDsMapper. The TVDB id costs one
+// external_ids call per series — TMDB's details payload for a show carries
+// neither it nor the IMDb id — so it is only fetched for series, and cached.
+func (s *TMDB) ExternalIDs(ctx context.Context, videoID string, ct models.ContentType) (int, int, error) {
+	ids, err := s.idCache.Get(fmt.Sprintf("%s:%s", ct, videoID), func() (*externalIDs, error) {
+		tmdbID, actual, err := s.GetTmdbID(ctx, videoID, ct)
+		if err != nil {
+			return nil, err
+		}
+		out := &externalIDs{TMDBID: tmdbID}
+		if tmdbID == 0 || actual != models.ContentTypeSeries {
+			return out, nil
+		}
+		ext, err := s.api.GetExternalIDs(ctx, tmdbID, tmdb.TmdbTypeTV)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := ext["tvdb_id"].(float64); ok && v > 0 {
+			out.TVDBID = int(v)
+		}
+		return out, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return ids.TMDBID, ids.TVDBID, nil
+}
+
 func (s *TMDB) MapByID(ctx context.Context, videoID string, ct models.ContentType, force bool) (*models.VideoMetadata, error) {
 	db := s.pg.Get()
 	if db == nil {
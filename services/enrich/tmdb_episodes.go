@@ -125,4 +125,83 @@ func (s *TMDBEpisodes) parseSeasonMetadata(raw map[string]any, videoID string) [
 	return result
 }
 
+// upcomingRefreshAge is how old a cached season may be before
+// UpcomingEpisodes asks TMDB again. Air dates of episodes that have not
+// aired yet move — a date is announced, then shifted — and a calendar
+// that is a week behind is worse than none. A day keeps it current at one
+// season request per followed series per day.
+const upcomingRefreshAge = 24 * time.Hour
+
+// UpcomingEpisodes is MapEpisodes for a calendar: the cached season is
+// re-fetched once it is older than upcomingRefreshAge, and served stale
+// only when that fetch fails. With season 0 the season comes from the
+// series' own cached info — the one next_episode_to_air points into, or
+// the last one while the series is still running. The info row is as old
+// as the last enrichment, so a season announced since may be missed until
+// the series is enriched again; an ended or cancelled series has nothing
+// upcoming and yields (nil, nil).
+func (s *TMDBEpisodes) UpcomingEpisodes(ctx context.Context, videoID string, season int) ([]*models.EpisodeMetadata, error) {
+	db := s.tmdb.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	tmdbID, _, err := s.tmdb.GetTmdbID(ctx, videoID, models.ContentTypeSeries)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve tmdb id")
+	}
+	if tmdbID == 0 {
+		return nil, nil
+	}
+
+	if season == 0 {
+		info, err := tm.GetInfoByID(ctx, db, tmdbID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get cached series info")
+		}
+		if info == nil {
+			return nil, nil
+		}
+		season = currentSeason(info.Metadata)
+		if season == 0 {
+			return nil, nil
+		}
+	}
+
+	cached, err := tm.GetSeasonInfo(ctx, db, tmdbID, int16(season))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cached season info")
+	}
+	if cached != nil && time.Since(cached.UpdatedAt) < upcomingRefreshAge {
+		return s.parseSeasonMetadata(cached.Metadata, videoID), nil
+	}
+	eps, err := s.MapEpisodes(ctx, videoID, season, true)
+	if err != nil && cached != nil {
+		log.WithError(err).
+			WithField("video_id", videoID).
+			WithField("season", season).
+			Warn("failed to refresh season info, serving cached")
+		return s.parseSeasonMetadata(cached.Metadata, videoID), nil
+	}
+	return eps, err
+}
+
+// currentSeason picks the season a calendar should show from a TMDB series
+// info payload, 0 when there is none.
+func currentSeason(raw map[string]any) int {
+	if next, ok := raw["next_episode_to_air"].(map[string]any); ok {
+		if n, ok := next["season_number"].(float64); ok && n > 0 {
+			return int(n)
+		}
+	}
+	if status, _ := raw["status"].(string); status == "Ended" || status == "Canceled" {
+		return 0
+	}
+	if n, ok := raw["number_of_seasons"].(float64); ok && n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
 var _ EpisodeMapper = (*TMDBEpisodes)(nil)
+var _ UpcomingProvider = (*TMDBEpisodes)(nil)
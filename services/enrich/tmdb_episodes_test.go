@@ -0,0 +1,24 @@
+package enrich
+
+import "testing"
+
+func TestCurrentSeason(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		raw  map[string]any
+		want int
+	}{
+		{"next episode announced", map[string]any{
+			"number_of_seasons":   float64(4),
+			"status":              "Returning Series",
+			"next_episode_to_air": map[string]any{"season_number": float64(5)},
+		}, 5},
+		{"between seasons", map[string]any{"number_of_seasons": float64(4), "status": "Returning Series"}, 4},
+		{"ended", map[string]any{"number_of_seasons": float64(4), "status": "Ended"}, 0},
+		{"nothing known", map[string]any{}, 0},
+	} {
+		if got := currentSeason(tt.raw); got != tt.want {
+			t.Errorf("%s: currentSeason = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
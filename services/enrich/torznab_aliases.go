@@ -0,0 +1,80 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/lazymap"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/torznab"
+)
+
+// TorznabAliases answers the Torznab query planner's alias lookups from the
+// metadata mappers: the localized titles a tracker may file a release
+// under, through LocalizeByID, and the series ids Sonarr-era indexers key
+// on, through ExternalIDs.
+//
+// Only reached when an indexer's id query came back empty or it has none,
+// so the lookups stay off the path of indexers that answer by IMDb id.
+type TorznabAliases struct {
+	en    *Enricher
+	langs []string
+	cache *lazymap.LazyMap[*torznab.Aliases]
+}
+
+// NewTorznabAliases returns nil for an enricher with no mappers to ask.
+func NewTorznabAliases(en *Enricher, langs []string) *TorznabAliases {
+	if en == nil || !en.HasMappers() {
+		return nil
+	}
+	return &TorznabAliases{
+		en:    en,
+		langs: langs,
+		cache: lazymap.New[*torznab.Aliases](&lazymap.Config{
+			Expire:      24 * time.Hour,
+			ErrorExpire: time.Minute,
+			Capacity:    10000,
+		}),
+	}
+}
+
+func (s *TorznabAliases) Aliases(ctx context.Context, contentType, imdbID string) (*torznab.Aliases, error) {
+	ct := models.ContentTypeMovie
+	if contentType == string(models.ContentTypeSeries) {
+		ct = models.ContentTypeSeries
+	}
+	return s.cache.Get(fmt.Sprintf("%s:%s", ct, imdbID), func() (*torznab.Aliases, error) {
+		return s.lookup(ctx, ct, imdbID), nil
+	})
+}
+
+// lookup swallows every failure: an alias is a second chance at a title,
+// and without it the planner still has the id and the canonical name.
+func (s *TorznabAliases) lookup(ctx context.Context, ct models.ContentType, imdbID string) *torznab.Aliases {
+	out := &torznab.Aliases{}
+	for _, lang := range s.langs {
+		title, _, err := s.en.LocalizeByID(ctx, imdbID, ct, lang)
+		if err != nil {
+			log.WithError(err).
+				WithField("video_id", imdbID).
+				WithField("lang", lang).
+				Debug("failed to localize title for torznab aliases")
+			continue
+		}
+		if title = strings.TrimSpace(title); title != "" {
+			out.Titles = append(out.Titles, title)
+		}
+	}
+	tmdbID, tvdbID, err := s.en.ExternalIDs(ctx, imdbID, ct)
+	if err != nil {
+		log.WithError(err).
+			WithField("video_id", imdbID).
+			Debug("failed to resolve external ids for torznab aliases")
+	}
+	out.TMDBID, out.TVDBID = tmdbID, tvdbID
+	return out
+}
@@ -37,6 +37,57 @@ type LibraryItem struct {
 	Size       int64     `json:"size" example:"734003200"`
 	FilesCount int       `json:"files_count" example:"3"`
 	AddedAt    time.Time `json:"added_at" example:"2026-01-02T15:04:05Z"`
+	// Media is the film or show the torrent was recognized as. Only listings
+	// with type=movies or type=series carry it.
+	Media *LibraryMedia `json:"media,omitempty"`
+}
+
+// Media types of a LibraryMedia.
+const (
+	MediaTypeMovie  = "movie"
+	MediaTypeSeries = "series"
+)
+
+// LibraryMedia is what enrichment made of a library entry. Title and year fall
+// back to what was parsed from the torrent name while the entry is unmatched,
+// in which case VideoID is empty.
+type LibraryMedia struct {
+	Type    string   `json:"type" example:"movie" enums:"movie,series"`
+	VideoID string   `json:"video_id,omitempty" example:"tt1727587"`
+	Title   string   `json:"title" example:"Sintel"`
+	Year    int      `json:"year,omitempty" example:"2010"`
+	Plot    string   `json:"plot,omitempty" example:"A lonely young woman, Sintel, helps and befriends a dragon."`
+	Rating  *float64 `json:"rating,omitempty" example:"7.4"`
+	// PosterURL is the same poster the library UI shows. It answers 404 when
+	// there is none.
+	PosterURL string `json:"poster_url" example:"https://webtor.io/lib/poster/08ada5a7a6183aae1e09d831df6748d566095a10/240.jpg"`
+}
+
+// NewLibraryMedia flattens a movie or series row. A torrent holding several
+// films is described by whichever the caller picked.
+func NewLibraryMedia(vc models.VideoContentWithMetadata, posterURL string) *LibraryMedia {
+	m := &LibraryMedia{
+		Type:      string(vc.GetContentType()),
+		PosterURL: posterURL,
+	}
+	if c := vc.GetContent(); c != nil {
+		m.Title = c.Title
+		if c.Year != nil {
+			m.Year = int(*c.Year)
+		}
+	}
+	if md := vc.GetMetadata(); md != nil {
+		m.VideoID = md.VideoID
+		if md.Title != "" {
+			m.Title = md.Title
+		}
+		if md.Year != nil {
+			m.Year = int(*md.Year)
+		}
+		m.Plot = md.Plot
+		m.Rating = md.Rating
+	}
+	return m
 }
 
 // LibraryListResponse pages the library. The field names follow rest-api's
@@ -17,6 +17,9 @@ type ContentDirectory struct {
 	*TorrentDirectory
 	Library
 	pg *cs.PG
+	// subtitles adds the user's uploaded subtitles as sidecar files next to
+	// their videos; nil when user subtitles are not configured.
+	subtitles subtitleStore
 }
 
 func (s *ContentDirectory) Open(ctx context.Context, path string) (io.ReadCloser, *url.URL, error) {
@@ -27,6 +30,14 @@ func (s *ContentDirectory) Open(ctx context.Context, path string) (io.ReadCloser
 	if lr == nil {
 		return nil, nil, vfs.NewHTTPError(404, errors.New("file not found"))
 	}
+	sc, err := s.sidecar(ctx, lr, lr.NewPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sc != nil {
+		rc, err := s.openSidecar(ctx, sc)
+		return rc, nil, err
+	}
 	return s.TorrentDirectory.Open(ctx, lr.Item.Torrent, lr.NewPath)
 }
 
@@ -45,6 +56,13 @@ func (s *ContentDirectory) Stat(ctx context.Context, path string) (*vfs.FileInfo
 			IsDir:   true,
 		}, nil
 	}
+	sc, err := s.sidecar(ctx, lr, lr.NewPath)
+	if err != nil {
+		return nil, err
+	}
+	if sc != nil {
+		return AddPrefix(sc.fileInfo(), lr.Root), nil
+	}
 	fi, err := s.TorrentDirectory.Stat(ctx, lr.Item.Torrent, lr.NewPath)
 	if err != nil {
 		return nil, err
@@ -75,9 +93,63 @@ func (s *ContentDirectory) ReadDir(ctx context.Context, path string, recursive b
 	if err != nil {
 		return nil, err
 	}
+	scs, err := s.sidecars(ctx, lr, lr.NewPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range scs {
+		fis = append(fis, *sc.fileInfo())
+	}
 	return AddPrefixes(fis, lr.Root), nil
 }
 
+// Create accepts subtitle files next to a video; the rest of the tree is
+// the torrent's and read-only.
+func (s *ContentDirectory) Create(ctx context.Context, path string, body io.ReadCloser, opts *vfs.CreateOptions) (*vfs.FileInfo, bool, error) {
+	if s.subtitles == nil || !isSubtitleName(path) {
+		return s.BaseDirectory.Create(ctx, path, body, opts)
+	}
+	lr, err := s.getContentItem(ctx, path)
+	if err != nil {
+		return nil, false, err
+	}
+	if lr == nil || isRoot(lr.NewPath) {
+		return nil, false, vfs.NewHTTPError(404, errors.New("file not found"))
+	}
+	fi, created, err := s.createSidecar(ctx, lr, lr.NewPath, body)
+	if err != nil {
+		return nil, false, err
+	}
+	return AddPrefix(fi, lr.Root), created, nil
+}
+
+// RemoveAll deletes a sidecar subtitle. Torrent content cannot be removed
+// from here; the torrent itself goes through torrents/.
+func (s *ContentDirectory) RemoveAll(ctx context.Context, path string, opts *vfs.RemoveAllOptions) error {
+	if s.subtitles == nil || !isSubtitleName(path) {
+		return s.BaseDirectory.RemoveAll(ctx, path, opts)
+	}
+	lr, err := s.getContentItem(ctx, path)
+	if err != nil {
+		return err
+	}
+	if lr == nil {
+		return vfs.NewHTTPError(404, errors.New("file not found"))
+	}
+	sc, err := s.sidecar(ctx, lr, lr.NewPath)
+	if err != nil {
+		return err
+	}
+	if sc == nil {
+		return s.BaseDirectory.RemoveAll(ctx, path, opts)
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return err
+	}
+	return s.subtitles.Delete(ctx, wcc.User.ID, sc.Sub.UserSubtitleID)
+}
+
 func (s *ContentDirectory) getContentWithContext(ctx context.Context) ([]*models.Library, error) {
 	db := s.pg.Get()
 	if db == nil {
@@ -11,6 +11,9 @@ import (
 	services "github.com/webtor-io/common-services"
 	j "github.com/webtor-io/web-ui/jobs"
 	"github.com/webtor-io/web-ui/services/api"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
 	"github.com/webtor-io/web-ui/services/vfs"
 )
 
@@ -21,39 +24,66 @@ const (
 	RootAll      = "all"
 	RootMovies   = "movies"
 	RootSeries   = "series"
+
+	// Generated browsing trees, read-only apart from subtitle sidecars.
+	RootGenres      = "genres"      // genres/<genre>/
+	RootYears       = "years"       // years/<decade>/<year>/
+	RootCollections = "collections" // collections/<TMDB collection>/
+	RootUnwatched   = "unwatched"
+	RootInProgress  = "in-progress"
+	RootVault       = "vault"
+	RootMusic       = "music"  // music/<artist>/<album>/
+	RootUsenet      = "usenet" // usenet/<download>/, finished Usenet downloads
 )
 
 // New builds the library tree. The caller owns any protocol-specific wrapping —
 // handlers/webdav puts a PrefixDirectory on top because its URLs carry an alias
 // prefix, while S3 addresses the tree directly as bucket + key.
-func New(pg *services.PG, sapi *api.Api, jobs *j.Jobs) vfs.FileSystem {
+//
+// subs may be nil (user subtitles not configured); the content folders then
+// carry no subtitle sidecars. resolver may be nil too; the usenet folder is
+// then left out.
+func New(pg *services.PG, sapi *api.Api, jobs *j.Jobs, subs *us.Service, events *le.Bus, resolver *lr.LinkResolver) vfs.FileSystem {
 	td := &TorrentDirectory{
 		api: sapi,
 	}
+	// A nil *Service in the interface field would not compare equal to nil.
+	var ss subtitleStore
+	if subs.Enabled() {
+		ss = subs
+	}
+	content := func(l Library) vfs.FileSystem {
+		return &ContentDirectory{
+			Library:          l,
+			TorrentDirectory: td,
+			pg:               pg,
+			subtitles:        ss,
+		}
+	}
+	children := map[string]vfs.FileSystem{
+		RootTorrents: &TorrentLibraryDirectory{
+			pg:     pg,
+			api:    sapi,
+			jobs:   jobs,
+			events: events,
+		},
+		RootAll:         content(&AllLibrary{}),
+		RootMovies:      content(&MovieLibrary{}),
+		RootSeries:      content(&SeriesLibrary{}),
+		RootGenres:      facetTree(pg, content, genreKey),
+		RootYears:       facetTree(pg, content, decadeKey, yearKey),
+		RootCollections: facetTree(pg, content, collectionKey),
+		RootUnwatched:   content(&UnwatchedLibrary{}),
+		RootInProgress:  content(&InProgressLibrary{}),
+		RootVault:       content(&VaultLibrary{}),
+		RootMusic:       musicTree(pg, td),
+	}
+	if resolver != nil {
+		children[RootUsenet] = usenetRoot(pg, resolver)
+	}
 	return &DebugDirectory{
 		Inner: &RootDirectory{
-			Children: map[string]vfs.FileSystem{
-				RootTorrents: &TorrentLibraryDirectory{
-					pg:   pg,
-					api:  sapi,
-					jobs: jobs,
-				},
-				RootAll: &ContentDirectory{
-					Library:          &AllLibrary{},
-					TorrentDirectory: td,
-					pg:               pg,
-				},
-				RootMovies: &ContentDirectory{
-					Library:          &MovieLibrary{},
-					TorrentDirectory: td,
-					pg:               pg,
-				},
-				RootSeries: &ContentDirectory{
-					Library:          &SeriesLibrary{},
-					TorrentDirectory: td,
-					pg:               pg,
-				},
-			},
+			Children: children,
 		},
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/go-pg/pg/v10"
 	uuid "github.com/satori/go.uuid"
 	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/models/vault"
 )
 
 type Library interface {
@@ -35,3 +36,65 @@ func (s *SeriesLibrary) GetContent(ctx context.Context, db *pg.DB, uID uuid.UUID
 }
 
 var _ Library = (*SeriesLibrary)(nil)
+
+// UnwatchedLibrary is what the web library shows with the "unwatched" filter:
+// movies and series not marked watched. Entries that are neither are left
+// out — there is nothing to have watched.
+type UnwatchedLibrary struct{}
+
+func (s *UnwatchedLibrary) GetContent(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]*models.Library, error) {
+	ms, err := models.GetLibraryMovieTorrentList(ctx, db, uID, models.SortTypeName, "unwatched")
+	if err != nil {
+		return nil, err
+	}
+	ss, err := models.GetLibrarySeriesTorrentList(ctx, db, uID, models.SortTypeName, "unwatched")
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(ms)+len(ss))
+	for _, l := range append(ms, ss...) {
+		ids[l.ResourceID] = struct{}{}
+	}
+	return getLibraryByIDs(ctx, db, uID, ids)
+}
+
+var _ Library = (*UnwatchedLibrary)(nil)
+
+// inProgressLimit caps the entries in-progress/ looks at; the web's
+// "continue watching" row shows far fewer.
+const inProgressLimit = 500
+
+// InProgressLibrary is the web's "continue watching": entries with a file
+// started but not finished, or a next episode after the last one watched.
+type InProgressLibrary struct{}
+
+func (s *InProgressLibrary) GetContent(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]*models.Library, error) {
+	whs, err := models.GetRecentlyWatched(ctx, db, uID, inProgressLimit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(whs))
+	for _, wh := range whs {
+		ids[wh.ResourceID] = struct{}{}
+	}
+	return getLibraryByIDs(ctx, db, uID, ids)
+}
+
+var _ Library = (*InProgressLibrary)(nil)
+
+// VaultLibrary is the entries the user has pledged to the vault.
+type VaultLibrary struct{}
+
+func (s *VaultLibrary) GetContent(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]*models.Library, error) {
+	ps, err := vault.GetUserPledges(ctx, db, uID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		ids[p.ResourceID] = struct{}{}
+	}
+	return getLibraryByIDs(ctx, db, uID, ids)
+}
+
+var _ Library = (*VaultLibrary)(nil)
@@ -0,0 +1,225 @@
+package libfs
+
+import (
+	"context"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/common"
+	le "github.com/webtor-io/web-ui/services/library_event"
+	"github.com/webtor-io/web-ui/services/vfs"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+// Files other than .torrent that torrents/ accepts: a bare magnet URI, or a
+// Windows internet shortcut / text file with one inside. Download automation
+// writing to a blackhole folder produces all three.
+var magnetExts = map[string]struct{}{
+	".magnet": {},
+	".url":    {},
+	".txt":    {},
+}
+
+// maxMagnetDropSize bounds what is read from a magnet drop. Real ones are a
+// few hundred bytes; the body is kept as dropped, so it must stay small.
+const maxMagnetDropSize = 64 << 10
+
+// magnetResolveTimeout is how long the resolve job may run (see
+// jobs.ResolveMagnet). A placeholder older than that lost its job — a
+// restarted replica — and shows as failed so the user can drop it again.
+const magnetResolveTimeout = 30 * time.Minute
+
+const (
+	magnetResolving = "resolving"
+	magnetFailed    = "failed"
+)
+
+var magnetURIRe = regexp.MustCompile(`magnet:\?[^\s"'<>]+`)
+
+func isMagnetName(name string) bool {
+	_, ok := magnetExts[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// parseMagnetDrop finds the magnet URI in a dropped file and the infohash it
+// points to.
+func parseMagnetDrop(body []byte) (hash string, magnet string, err error) {
+	magnet = magnetURIRe.FindString(string(body))
+	if magnet == "" {
+		return "", "", errors.New("no magnet uri found")
+	}
+	hash, _, err = common.ResolveQueryHash(magnet)
+	if err != nil {
+		return "", "", err
+	}
+	return hash, magnet, nil
+}
+
+// magnetStatus is what a placeholder shows in its name.
+func magnetStatus(m *models.LibraryMagnet, now time.Time) string {
+	if m.Error != nil || now.Sub(m.CreatedAt) > magnetResolveTimeout {
+		return magnetFailed
+	}
+	return magnetResolving
+}
+
+// placeholderName is how a pending magnet is listed: "Name.magnet" shows as
+// "Name (resolving).magnet" until its torrent replaces it.
+func placeholderName(m *models.LibraryMagnet, now time.Time) string {
+	ext := path.Ext(m.FileName)
+	return strings.TrimSuffix(m.FileName, ext) + " (" + magnetStatus(m, now) + ")" + ext
+}
+
+func magnetToFileInfo(m *models.LibraryMagnet, name string) vfs.FileInfo {
+	return vfs.FileInfo{
+		Path:     name,
+		ModTime:  m.CreatedAt,
+		MIMEType: "text/plain",
+		Size:     int64(len(m.Body)),
+	}
+}
+
+// findMagnet looks a pending magnet up by the name it was dropped under or
+// the placeholder name it is listed under; clients that verify an upload ask
+// for the former, everything that lists the folder sees the latter.
+func findMagnet(ms []*models.LibraryMagnet, name string, now time.Time) *models.LibraryMagnet {
+	for _, m := range ms {
+		if m.FileName == name || placeholderName(m, now) == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *TorrentLibraryDirectory) getMagnets(ctx context.Context) ([]*models.LibraryMagnet, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.ListLibraryMagnets(ctx, db, wcc.User.ID)
+}
+
+func (s *TorrentLibraryDirectory) getMagnet(ctx context.Context, name string) (*models.LibraryMagnet, error) {
+	ms, err := s.getMagnets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return findMagnet(ms, strings.TrimPrefix(name, "/"), time.Now()), nil
+}
+
+// createMagnet records a dropped magnet and queues resolving its metadata.
+// The torrent joins the library under the dropped file's name once that is
+// done; until then the drop is listed as a placeholder.
+func (s *TorrentLibraryDirectory) createMagnet(ctx context.Context, name string, body io.Reader) (*vfs.FileInfo, bool, error) {
+	b, err := io.ReadAll(io.LimitReader(body, maxMagnetDropSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	name = strings.TrimPrefix(name, "/")
+	// Finder and the Windows redirector create an empty file before they
+	// write the real one. Nothing to resolve yet, and failing would abort
+	// the copy.
+	if len(b) == 0 {
+		return &vfs.FileInfo{Path: name, MIMEType: "text/plain", ModTime: time.Now()}, true, nil
+	}
+	if len(b) > maxMagnetDropSize {
+		return nil, false, vfs.NewHTTPError(413, errors.New("magnet file is too large"))
+	}
+	hash, magnet, err := parseMagnetDrop(b)
+	if err != nil {
+		return nil, false, vfs.NewHTTPError(400, err)
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, false, errors.New("db is nil")
+	}
+	m := &models.LibraryMagnet{
+		UserID:     wcc.User.ID,
+		ResourceID: hash,
+		FileName:   name,
+		Body:       string(b),
+	}
+	if err := models.UpsertLibraryMagnet(ctx, db, m); err != nil {
+		return nil, false, err
+	}
+	s.publish(le.Added, m.UserID, m.ResourceID)
+	if _, err := s.jobs.ResolveMagnet(wcc, m.LibraryMagnetID.String(), magnet, s.magnetResolved(wcc, m)); err != nil {
+		return nil, false, err
+	}
+	fi := magnetToFileInfo(m, name)
+	return &fi, true, nil
+}
+
+// magnetResolved moves a resolved magnet into the library, or records why it
+// could not be resolved. It runs in the job, after the request that dropped
+// the magnet is gone, so it carries the request's web context along itself.
+func (s *TorrentLibraryDirectory) magnetResolved(wcc *web.Context, m *models.LibraryMagnet) func(ctx context.Context, rID string, t []byte, err error) error {
+	return func(ctx context.Context, rID string, t []byte, rerr error) error {
+		db := s.pg.Get()
+		if db == nil {
+			return errors.New("db is nil")
+		}
+		if rerr == nil {
+			// Deleted meanwhile: the user changed their mind. Replaced: the
+			// newer drop's own job adds the torrent.
+			ok, err := models.LibraryMagnetExists(ctx, db, m.LibraryMagnetID)
+			if err != nil || !ok {
+				return err
+			}
+			ctx := context.WithValue(ctx, web.Context{}, wcc)
+			info, err := loadTorrentInfo(t)
+			if err == nil {
+				name := strings.TrimSuffix(m.FileName, path.Ext(m.FileName))
+				_, err = s.storeToLibrary(ctx, rID, info, name, int64(len(t)))
+			}
+			if err == nil {
+				return models.DeleteLibraryMagnet(ctx, db, m.UserID, m.LibraryMagnetID)
+			}
+			rerr = err
+		}
+		log.WithError(rerr).
+			WithField("resource_id", m.ResourceID).
+			Warn("failed to resolve dropped magnet")
+		if err := models.FailLibraryMagnet(ctx, db, m.LibraryMagnetID, rerr.Error()); err != nil {
+			return err
+		}
+		// The placeholder is listed under its status.
+		s.publish(le.Renamed, m.UserID, m.ResourceID)
+		return rerr
+	}
+}
+
+// removeMagnet drops a pending magnet. A job still resolving it finds its row
+// gone and leaves the library alone.
+func (s *TorrentLibraryDirectory) removeMagnet(ctx context.Context, name string) error {
+	m, err := s.getMagnet(ctx, name)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return vfs.NewHTTPError(404, errors.New("file not found"))
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	if err := models.DeleteLibraryMagnet(ctx, db, m.UserID, m.LibraryMagnetID); err != nil {
+		return err
+	}
+	s.publish(le.Removed, m.UserID, m.ResourceID)
+	return nil
+}
@@ -0,0 +1,80 @@
+package libfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+const testHash = "08ada5a7a6183aae1e09d831df6748d566095a10"
+
+func TestParseMagnetDrop(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		body string
+	}{
+		{"bare magnet", "magnet:?xt=urn:btih:" + testHash + "&dn=Sintel\n"},
+		{"internet shortcut", "[InternetShortcut]\r\nURL=magnet:?xt=urn:btih:" + testHash + "&tr=udp%3A%2F%2Ftracker\r\n"},
+		{"text with noise", "grabbed by sonarr: <magnet:?xt=urn:btih:" + testHash + "> enjoy"},
+		{"uppercase hash", "magnet:?xt=urn:btih:08ADA5A7A6183AAE1E09D831DF6748D566095A10"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, magnet, err := parseMagnetDrop([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseMagnetDrop: %v", err)
+			}
+			if hash != testHash {
+				t.Fatalf("expected %s, got %s", testHash, hash)
+			}
+			if magnet[len(magnet)-1] == '\r' || magnet[len(magnet)-1] == '>' {
+				t.Fatalf("magnet carries trailing junk: %q", magnet)
+			}
+		})
+	}
+	for _, body := range []string{"", "just some notes", "magnet:?dn=no-hash"} {
+		if _, _, err := parseMagnetDrop([]byte(body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestPlaceholderName(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	reason := "no peers"
+	for _, tt := range []struct {
+		m    models.LibraryMagnet
+		want string
+	}{
+		{models.LibraryMagnet{FileName: "Show.S01E01.magnet", CreatedAt: now.Add(-time.Minute)}, "Show.S01E01 (resolving).magnet"},
+		{models.LibraryMagnet{FileName: "Movie.url", CreatedAt: now, Error: &reason}, "Movie (failed).url"},
+		// Outlived its job: a replica went away while resolving.
+		{models.LibraryMagnet{FileName: "Old.txt", CreatedAt: now.Add(-time.Hour)}, "Old (failed).txt"},
+	} {
+		if got := placeholderName(&tt.m, now); got != tt.want {
+			t.Errorf("placeholderName(%s) = %q, want %q", tt.m.FileName, got, tt.want)
+		}
+	}
+}
+
+func TestFindMagnet(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	ms := []*models.LibraryMagnet{
+		{FileName: "A.magnet", CreatedAt: now},
+		{FileName: "B.url", CreatedAt: now},
+	}
+	// Found both as dropped and as listed, so a client checking its upload
+	// and one browsing the folder reach the same entry.
+	for name, want := range map[string]string{
+		"A.magnet":             "A.magnet",
+		"A (resolving).magnet": "A.magnet",
+		"B (resolving).url":    "B.url",
+	} {
+		if m := findMagnet(ms, name, now); m == nil || m.FileName != want {
+			t.Errorf("findMagnet(%q) = %+v, want %s", name, m, want)
+		}
+	}
+	if m := findMagnet(ms, "A (failed).magnet", now); m != nil {
+		t.Errorf("a resolving magnet must not answer to its failed name, got %+v", m)
+	}
+}
@@ -0,0 +1,225 @@
+package libfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+// Metadata keys, shared by every protocol that publishes them.
+const (
+	MetaInfohash   = "infohash"
+	MetaIMDBID     = "imdb-id"
+	MetaTitle      = "title"
+	MetaYear       = "year"
+	MetaSeason     = "season"
+	MetaEpisode    = "episode"
+	MetaResolution = "resolution"
+	MetaCodec      = "codec"
+)
+
+func (s *BaseDirectory) Metadata(ctx context.Context, name string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (s *DebugDirectory) Metadata(ctx context.Context, path string) (map[string]string, error) {
+	md, err := vfs.ReadMetadata(ctx, s.Inner, path)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Error("metadata")
+		return nil, err
+	}
+	log.WithField("path", path).WithField("metadata", md).Info("metadata")
+	return md, nil
+}
+
+func (s *RootDirectory) Metadata(ctx context.Context, path string) (map[string]string, error) {
+	c := s.getChild(path)
+	if c == nil || c.Root == path {
+		return nil, nil
+	}
+	return vfs.ReadMetadata(ctx, c.Child, c.NewPath)
+}
+
+func (s *GroupDirectory) Metadata(ctx context.Context, path string) (map[string]string, error) {
+	group, rest := splitGroup(path)
+	if isRoot(path) || isRoot(rest) {
+		return nil, nil
+	}
+	return vfs.ReadMetadata(ctx, s.Child(group), rest)
+}
+
+// Metadata describes a file by the movie or episode enrichment matched it
+// to. Folders and files enrichment did not pick (samples, extras, sidecars)
+// carry the infohash alone.
+func (s *ContentDirectory) Metadata(ctx context.Context, path string) (map[string]string, error) {
+	lr, err := s.getContentItem(ctx, path)
+	if err != nil || lr == nil {
+		return nil, err
+	}
+	rID := lr.Item.Torrent.ResourceID
+	md := map[string]string{MetaInfohash: rID}
+	if isRoot(lr.NewPath) || isSubtitleName(lr.NewPath) {
+		return md, nil
+	}
+	prefix, err := s.getPrefix(ctx, rID)
+	if err != nil {
+		return nil, err
+	}
+	li, err := s.retrieveTorrentItem(ctx, rID, prefix+lr.NewPath)
+	if err != nil {
+		return nil, err
+	}
+	if li == nil || li.Type == ra.ListTypeDirectory {
+		return md, nil
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if err := addMediaMetadata(ctx, db, md, rID, li); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// Metadata of a .torrent file is its infohash and, for a single movie, what
+// the movie was identified as.
+func (s *TorrentLibraryDirectory) Metadata(ctx context.Context, name string) (map[string]string, error) {
+	if isMagnetName(name) {
+		m, err := s.getMagnet(ctx, name)
+		if err != nil || m == nil {
+			return nil, err
+		}
+		return map[string]string{MetaInfohash: m.ResourceID}, nil
+	}
+	l, err := s.getLibraryByName(ctx, torrentToName(name))
+	if err != nil || l == nil {
+		return nil, err
+	}
+	md := map[string]string{MetaInfohash: l.ResourceID}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if err := addMediaMetadata(ctx, db, md, l.ResourceID, nil); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// addMediaMetadata fills md from the movie or episode of resource rID stored
+// as file li. A nil li stands for the whole torrent, which only a movie
+// torrent holding a single movie describes.
+func addMediaMetadata(ctx context.Context, db *pg.DB, md map[string]string, rID string, li *ra.ListItem) error {
+	ms, err := models.GetMoviesWithMetadataByResourceIDs(ctx, db, []string{rID})
+	if err != nil {
+		return errors.Wrap(err, "failed to get movies")
+	}
+	if li == nil {
+		if len(ms) == 1 {
+			addMovieMetadata(md, ms[0])
+		}
+		return nil
+	}
+	for _, m := range ms {
+		if matchesFile(li, m.Path, m.FileIdx) {
+			addMovieMetadata(md, m)
+			return nil
+		}
+	}
+	sr, err := models.GetSeriesWithMetadataByResourceID(ctx, db, rID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get series")
+	}
+	if sr == nil {
+		return nil
+	}
+	for _, e := range sr.Episodes {
+		if matchesFile(li, e.Path, e.FileIdx) {
+			addEpisodeMetadata(md, sr, e)
+			return nil
+		}
+	}
+	return nil
+}
+
+// matchesFile compares by path first; the index only identifies a file when
+// no path was stored, as the rest-api numbers files by torrent order.
+func matchesFile(li *ra.ListItem, path *string, idx *int) bool {
+	if path != nil && *path != "" {
+		return strings.TrimPrefix(*path, "/") == strings.TrimPrefix(li.PathStr, "/")
+	}
+	return idx != nil && *idx == li.Index
+}
+
+func addMovieMetadata(md map[string]string, m *models.Movie) {
+	var vm *models.VideoMetadata
+	if m.MovieMetadata != nil {
+		vm = m.MovieMetadata.VideoMetadata
+	}
+	var parsed map[string]any
+	if m.VideoContent != nil {
+		parsed = m.VideoContent.Metadata
+	}
+	addVideoMetadata(md, m.VideoContent, vm, parsed)
+}
+
+func addEpisodeMetadata(md map[string]string, sr *models.Series, e *models.Episode) {
+	var vm *models.VideoMetadata
+	if sr.SeriesMetadata != nil {
+		vm = sr.SeriesMetadata.VideoMetadata
+	}
+	addVideoMetadata(md, sr.VideoContent, vm, e.Metadata)
+	if e.Season != nil {
+		md[MetaSeason] = strconv.Itoa(int(*e.Season))
+	}
+	if e.Episode != nil {
+		md[MetaEpisode] = strconv.Itoa(int(*e.Episode))
+	}
+}
+
+// addVideoMetadata prefers what the metadata providers say over the title
+// and year parsed from the torrent name; resolution and codec come from the
+// file name parser only.
+func addVideoMetadata(md map[string]string, vc *models.VideoContent, vm *models.VideoMetadata, parsed map[string]any) {
+	if vc != nil {
+		if vc.Title != "" {
+			md[MetaTitle] = vc.Title
+		}
+		if vc.Year != nil {
+			md[MetaYear] = strconv.Itoa(int(*vc.Year))
+		}
+	}
+	if vm != nil {
+		// VideoID is the IMDB id unless the title only matched elsewhere.
+		if strings.HasPrefix(vm.VideoID, "tt") {
+			md[MetaIMDBID] = vm.VideoID
+		}
+		if vm.Title != "" {
+			md[MetaTitle] = vm.Title
+		}
+		if vm.Year != nil {
+			md[MetaYear] = strconv.Itoa(int(*vm.Year))
+		}
+	}
+	for _, k := range []string{MetaResolution, MetaCodec} {
+		if v, ok := parsed[k].(string); ok && strings.TrimSpace(v) != "" {
+			md[k] = strings.TrimSpace(v)
+		}
+	}
+}
+
+var (
+	_ vfs.MetadataReader = (*RootDirectory)(nil)
+	_ vfs.MetadataReader = (*GroupDirectory)(nil)
+	_ vfs.MetadataReader = (*ContentDirectory)(nil)
+	_ vfs.MetadataReader = (*TorrentLibraryDirectory)(nil)
+	_ vfs.MetadataReader = (*DebugDirectory)(nil)
+)
@@ -0,0 +1,65 @@
+package libfs
+
+import (
+	"reflect"
+	"testing"
+
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+)
+
+func TestMatchesFile(t *testing.T) {
+	li := &ra.ListItem{PathStr: "/Show/S01E02.mkv", Index: 3}
+	path := "Show/S01E02.mkv"
+	other := "Show/S01E03.mkv"
+	idx, wrongIdx := 3, 4
+	for _, c := range []struct {
+		path *string
+		idx  *int
+		want bool
+	}{
+		{&path, nil, true},
+		// A stored path wins over a matching index.
+		{&other, &idx, false},
+		{nil, &idx, true},
+		{nil, &wrongIdx, false},
+		{nil, nil, false},
+	} {
+		if got := matchesFile(li, c.path, c.idx); got != c.want {
+			t.Errorf("matchesFile(%v, %v) = %v, want %v", c.path, c.idx, got, c.want)
+		}
+	}
+}
+
+func TestAddEpisodeMetadata(t *testing.T) {
+	parsedYear, year := int16(2009), int16(2008)
+	season, episode := int16(1), int16(2)
+	sr := &models.Series{
+		VideoContent: &models.VideoContent{Title: "breaking bad", Year: &parsedYear},
+		SeriesMetadata: &models.SeriesMetadata{VideoMetadata: &models.VideoMetadata{
+			VideoID: "tt0903747",
+			Title:   "Breaking Bad",
+			Year:    &year,
+		}},
+	}
+	e := &models.Episode{
+		Season:   &season,
+		Episode:  &episode,
+		Metadata: map[string]any{"resolution": "720p", "codec": " x264 ", "year": 2009.0},
+	}
+	md := map[string]string{MetaInfohash: "abc"}
+	addEpisodeMetadata(md, sr, e)
+	want := map[string]string{
+		MetaInfohash:   "abc",
+		MetaIMDBID:     "tt0903747",
+		MetaTitle:      "Breaking Bad",
+		MetaYear:       "2008",
+		MetaSeason:     "1",
+		MetaEpisode:    "2",
+		MetaResolution: "720p",
+		MetaCodec:      "x264",
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("got %v, want %v", md, want)
+	}
+}
@@ -0,0 +1,226 @@
+package libfs
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+const unknownArtist = "Unknown Artist"
+
+// albumTree is the music tree's layout: artist folder, then album folder,
+// then the album itself.
+type albumTree map[string]map[string]*models.Album
+
+// albumFolders names the folders albums are shown under. Names come from
+// the metadata where enrichment found some, an album folder carries its
+// year, and two albums that would still share a folder (the same record
+// in two torrents) are told apart with a counter.
+func albumFolders(albums []*models.Album) albumTree {
+	type named struct {
+		artist string
+		folder string
+		a      *models.Album
+	}
+	ns := make([]named, 0, len(albums))
+	for _, a := range albums {
+		artist := groupName(a.GetArtist())
+		if artist == "" {
+			artist = unknownArtist
+		}
+		folder := groupName(a.GetTitle())
+		if folder == "" {
+			continue
+		}
+		if y := a.GetIntYear(); y > 0 {
+			folder += " (" + strconv.Itoa(y) + ")"
+		}
+		ns = append(ns, named{artist: artist, folder: folder, a: a})
+	}
+	// The counter must not depend on the order the database returned rows
+	// in, or a folder could swap albums between two listings.
+	sort.SliceStable(ns, func(i, j int) bool {
+		if ns[i].artist != ns[j].artist {
+			return ns[i].artist < ns[j].artist
+		}
+		if ns[i].folder != ns[j].folder {
+			return ns[i].folder < ns[j].folder
+		}
+		if ns[i].a.ResourceID != ns[j].a.ResourceID {
+			return ns[i].a.ResourceID < ns[j].a.ResourceID
+		}
+		return ns[i].a.Path < ns[j].a.Path
+	})
+	res := albumTree{}
+	for _, n := range ns {
+		fs, ok := res[n.artist]
+		if !ok {
+			fs = map[string]*models.Album{}
+			res[n.artist] = fs
+		}
+		folder := n.folder
+		for i := 2; ; i++ {
+			if _, ok := fs[folder]; !ok {
+				break
+			}
+			folder = n.folder + " (" + strconv.Itoa(i) + ")"
+		}
+		fs[folder] = n.a
+	}
+	return res
+}
+
+func (t albumTree) artists() []string {
+	res := make([]string, 0, len(t))
+	for a := range t {
+		res = append(res, a)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (t albumTree) albums(artist string) []string {
+	res := make([]string, 0, len(t[artist]))
+	for f := range t[artist] {
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func getAlbumTree(ctx context.Context, db *pg.DB, uID uuid.UUID) (albumTree, error) {
+	as, err := models.GetLibraryAlbumList(ctx, db, uID, models.SortTypeName)
+	if err != nil {
+		return nil, err
+	}
+	return albumFolders(as), nil
+}
+
+// musicTree builds music/<artist>/<album>/, each album folder opening onto
+// its folder in the torrent.
+func musicTree(p *cs.PG, td *TorrentDirectory) vfs.FileSystem {
+	return &GroupDirectory{
+		pg: p,
+		Groups: func(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]string, error) {
+			t, err := getAlbumTree(ctx, db, uID)
+			if err != nil {
+				return nil, err
+			}
+			return t.artists(), nil
+		},
+		Child: func(artist string) vfs.FileSystem {
+			return &GroupDirectory{
+				pg: p,
+				Groups: func(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]string, error) {
+					t, err := getAlbumTree(ctx, db, uID)
+					if err != nil {
+						return nil, err
+					}
+					return t.albums(artist), nil
+				},
+				Child: func(album string) vfs.FileSystem {
+					return &AlbumDirectory{
+						pg:     p,
+						td:     td,
+						artist: artist,
+						album:  album,
+					}
+				},
+			}
+		},
+	}
+}
+
+// AlbumDirectory is one album's folder of the torrent it came from. It is
+// read-only: audio has no sidecars to manage.
+type AlbumDirectory struct {
+	BaseDirectory
+	pg     *cs.PG
+	td     *TorrentDirectory
+	artist string
+	album  string
+}
+
+// getAlbum resolves the album behind the folder and its folder's path
+// relative to the torrent's root, as TorrentDirectory addresses it.
+func (s *AlbumDirectory) getAlbum(ctx context.Context) (*models.Album, string, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, "", errors.New("db is nil")
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	t, err := getAlbumTree(ctx, db, wcc.User.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	a := t[s.artist][s.album]
+	if a == nil || a.Torrent == nil {
+		return nil, "", vfs.NewHTTPError(404, errors.New("file not found"))
+	}
+	prefix, err := s.td.getPrefix(ctx, a.ResourceID)
+	if err != nil {
+		return nil, "", err
+	}
+	return a, strings.TrimSuffix(strings.TrimPrefix(a.Path, prefix), "/"), nil
+}
+
+func (s *AlbumDirectory) Stat(ctx context.Context, path string) (*vfs.FileInfo, error) {
+	a, rel, err := s.getAlbum(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if isRoot(path) {
+		return &vfs.FileInfo{
+			Path:    "/",
+			ModTime: a.Torrent.CreatedAt,
+			IsDir:   true,
+		}, nil
+	}
+	fi, err := s.td.Stat(ctx, a.Torrent, rel+path)
+	if err != nil {
+		return nil, err
+	}
+	fi.Path = strings.TrimPrefix(fi.Path, rel)
+	return fi, nil
+}
+
+func (s *AlbumDirectory) ReadDir(ctx context.Context, path string, recursive bool) ([]vfs.FileInfo, error) {
+	a, rel, err := s.getAlbum(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fis, err := s.td.ReadDir(ctx, a.Torrent, rel+path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fis {
+		fis[i].Path = strings.TrimPrefix(fis[i].Path, rel)
+	}
+	return fis, nil
+}
+
+func (s *AlbumDirectory) Open(ctx context.Context, path string) (io.ReadCloser, *url.URL, error) {
+	if isRoot(path) {
+		return nil, nil, vfs.NewHTTPError(403, errors.New("operation not permitted"))
+	}
+	a, rel, err := s.getAlbum(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.td.Open(ctx, a.Torrent, rel+path)
+}
+
+var _ vfs.FileSystem = (*AlbumDirectory)(nil)
@@ -0,0 +1,39 @@
+package libfs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+func TestAlbumFolders(t *testing.T) {
+	y := func(v int16) *int16 { return &v }
+	wall := &models.Album{ResourceID: "b", Artist: "Pink Floyd", Title: "The Wall", Year: y(1979), Path: "/PF/The Wall"}
+	wallAgain := &models.Album{ResourceID: "a", Artist: "Pink Floyd", Title: "The Wall", Year: y(1979), Path: "/The Wall [FLAC]"}
+	dsotm := &models.Album{ResourceID: "c", Artist: "pink floyd", Title: "Dark Side", Path: "/DSOTM",
+		AlbumMetadata: &models.AlbumMetadata{Artist: "Pink Floyd", Title: "The Dark Side of the Moon", Year: y(1973)}}
+	acdc := &models.Album{ResourceID: "d", Artist: "AC/DC", Title: "Back in Black", Path: "/AC DC/Back in Black"}
+	untitled := &models.Album{ResourceID: "e", Title: "Tape 3", Path: "/Tape 3"}
+	root := &models.Album{ResourceID: "f", Path: "/"}
+
+	got := albumFolders([]*models.Album{wall, dsotm, acdc, untitled, wallAgain, root})
+	want := albumTree{
+		"Pink Floyd": {
+			"The Wall (1979)":                  wallAgain,
+			"The Wall (1979) (2)":              wall,
+			"The Dark Side of the Moon (1973)": dsotm,
+		},
+		"AC-DC":       {"Back in Black": acdc},
+		unknownArtist: {"Tape 3": untitled},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("albumFolders = %v, want %v", got, want)
+	}
+	if as := got.artists(); !reflect.DeepEqual(as, []string{"AC-DC", "Pink Floyd", unknownArtist}) {
+		t.Errorf("artists = %v", as)
+	}
+	if fs := got.albums("Pink Floyd"); !reflect.DeepEqual(fs, []string{"The Dark Side of the Moon (1973)", "The Wall (1979)", "The Wall (1979) (2)"}) {
+		t.Errorf("albums = %v", fs)
+	}
+}
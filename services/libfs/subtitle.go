@@ -0,0 +1,271 @@
+package libfs
+
+import (
+	"context"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+// subtitleStore is the slice of *user_subtitle.Service the content
+// directories need. Declared as an interface for the same reason as
+// torrentAPI: tests swap in a fake instead of Postgres and S3.
+type subtitleStore interface {
+	ListForResource(ctx context.Context, userID uuid.UUID, resourceID string) ([]*models.UserSubtitle, error)
+	Upload(ctx context.Context, userID uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error)
+	Replace(ctx context.Context, userID, replaces uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	GetFile(ctx context.Context, hash string) (io.ReadCloser, int64, error)
+}
+
+var subtitleExts = map[string]struct{}{
+	".srt": {},
+	".vtt": {},
+	".ass": {},
+}
+
+func isSubtitleName(name string) bool {
+	_, ok := subtitleExts[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// sidecar is a user subtitle as it appears in the tree: a file next to the
+// video it belongs to.
+type sidecar struct {
+	// Path is relative to the torrent root, like the torrent's own files.
+	Path string
+	Sub  *models.UserSubtitle
+}
+
+func (s *sidecar) fileInfo() *vfs.FileInfo {
+	return &vfs.FileInfo{
+		Path:     s.Path,
+		Size:     s.Sub.Size,
+		ModTime:  s.Sub.CreatedAt,
+		MIMEType: us.ContentTypeFor(s.Sub.Format),
+		ETag:     s.Sub.Hash,
+	}
+}
+
+// sidecarNames names the subtitles of one video the way Kodi, Infuse and VLC
+// look for them: the video's name without its extension, an optional tag,
+// and the subtitle's format — "Movie.mkv" gets "Movie.srt", "Movie.en.srt".
+// An upload already named after the video keeps its name, so a file PUT as
+// "Movie.en.srt" lists back as "Movie.en.srt"; anything else is tagged with
+// its own name. taken holds the directory's other names, which a sidecar
+// never shadows.
+func sidecarNames(video string, subs []*models.UserSubtitle, taken map[string]struct{}) []string {
+	base := strings.TrimSuffix(video, path.Ext(video))
+	names := make([]string, len(subs))
+	for i, sub := range subs {
+		orig := path.Base(sub.OriginalName)
+		orig = strings.TrimSuffix(orig, path.Ext(orig))
+		tag := ""
+		if orig != base {
+			tag = strings.TrimPrefix(orig, base+".")
+		}
+		name := sidecarName(base, tag, sub.Format)
+		for n := 2; ; n++ {
+			if _, ok := taken[name]; !ok {
+				break
+			}
+			name = sidecarName(base, joinTag(tag, strconv.Itoa(n)), sub.Format)
+		}
+		taken[name] = struct{}{}
+		names[i] = name
+	}
+	return names
+}
+
+func sidecarName(base, tag, format string) string {
+	if tag == "" {
+		return base + "." + format
+	}
+	return base + "." + tag + "." + format
+}
+
+func joinTag(tag, n string) string {
+	if tag == "" {
+		return n
+	}
+	return tag + "-" + n
+}
+
+func itemName(it *ra.ListItem) string {
+	return path.Base(strings.TrimSuffix(it.PathStr, "/"))
+}
+
+// videoFor picks the video in items a subtitle file name belongs to: the one
+// whose name without extension is the longest prefix of it.
+func videoFor(items []ra.ListItem, file string) *ra.ListItem {
+	var best *ra.ListItem
+	bestLen := -1
+	for i := range items {
+		it := &items[i]
+		if it.Type == ra.ListTypeDirectory || it.MediaFormat != ra.Video {
+			continue
+		}
+		name := itemName(it)
+		base := strings.TrimSuffix(name, path.Ext(name))
+		if !strings.HasPrefix(file, base+".") || len(base) <= bestLen {
+			continue
+		}
+		best, bestLen = it, len(base)
+	}
+	return best
+}
+
+// sidecars lists the subtitles of the videos directly in dir, a directory
+// of the torrent. User subtitles are keyed by the video's path as rest-api
+// reports it, single top-level folder included, hence the prefix.
+func (s *ContentDirectory) sidecars(ctx context.Context, lr *ContentItemResponse, dir string) ([]sidecar, error) {
+	if s.subtitles == nil {
+		return nil, nil
+	}
+	rID := lr.Item.Torrent.ResourceID
+	items, err := s.TorrentDirectory.retrieveTorrentItemsWithoutPrefix(ctx, rID, dir)
+	if err != nil {
+		return nil, err
+	}
+	hasVideo := false
+	taken := make(map[string]struct{}, len(items))
+	for _, it := range items {
+		taken[itemName(&it)] = struct{}{}
+		hasVideo = hasVideo || it.MediaFormat == ra.Video
+	}
+	if !hasVideo {
+		return nil, nil
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subtitles.ListForResource(ctx, wcc.User.ID, rID)
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return nil, nil
+	}
+	prefix, err := s.TorrentDirectory.getPrefix(ctx, rID)
+	if err != nil {
+		return nil, err
+	}
+	byVideo := map[string][]*models.UserSubtitle{}
+	for _, sub := range subs {
+		byVideo[sub.Path] = append(byVideo[sub.Path], sub)
+	}
+	var res []sidecar
+	for _, it := range items {
+		if it.MediaFormat != ra.Video {
+			continue
+		}
+		vs := byVideo[prefix+it.PathStr]
+		for i, name := range sidecarNames(itemName(&it), vs, taken) {
+			res = append(res, sidecar{Path: path.Join(path.Dir(it.PathStr), name), Sub: vs[i]})
+		}
+	}
+	return res, nil
+}
+
+// sidecar finds the subtitle file at p, a path inside the torrent.
+func (s *ContentDirectory) sidecar(ctx context.Context, lr *ContentItemResponse, p string) (*sidecar, error) {
+	if s.subtitles == nil || !isSubtitleName(p) {
+		return nil, nil
+	}
+	scs, err := s.sidecars(ctx, lr, parentPath(p))
+	if err != nil {
+		return nil, err
+	}
+	for i := range scs {
+		if scs[i].Path == p {
+			return &scs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// createSidecar uploads a subtitle PUT next to a video. Putting a name that
+// is already listed replaces that subtitle.
+func (s *ContentDirectory) createSidecar(ctx context.Context, lr *ContentItemResponse, p string, body io.Reader) (*vfs.FileInfo, bool, error) {
+	rID := lr.Item.Torrent.ResourceID
+	dir, name := parentPath(p), path.Base(p)
+	items, err := s.TorrentDirectory.retrieveTorrentItemsWithoutPrefix(ctx, rID, dir)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, it := range items {
+		if itemName(&it) == name {
+			return nil, false, vfs.NewHTTPError(403, errors.New("torrent files are read-only"))
+		}
+	}
+	video := videoFor(items, name)
+	if video == nil {
+		return nil, false, vfs.NewHTTPError(403, errors.New("subtitle name does not match any video"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, us.MaxUploadSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	// Finder and the Windows redirector create an empty file before they
+	// write the real one. There is nothing to store yet, and failing would
+	// abort the copy.
+	if len(data) == 0 {
+		return &vfs.FileInfo{Path: p, MIMEType: us.ContentTypeFor(strings.TrimPrefix(path.Ext(name), "."))}, true, nil
+	}
+	if int64(len(data)) > us.MaxUploadSize {
+		return nil, false, vfs.NewHTTPError(413, us.ErrTooLarge)
+	}
+
+	old, err := s.sidecar(ctx, lr, p)
+	if err != nil {
+		return nil, false, err
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	prefix, err := s.TorrentDirectory.getPrefix(ctx, rID)
+	if err != nil {
+		return nil, false, err
+	}
+	// Overwriting a sidecar replaces its subtitle in one go, so a file
+	// already at the per-file limit can still have one rewritten.
+	var sub *models.UserSubtitle
+	if old != nil {
+		sub, err = s.subtitles.Replace(ctx, wcc.User.ID, old.Sub.UserSubtitleID, rID, prefix+video.PathStr, name, data)
+	} else {
+		sub, err = s.subtitles.Upload(ctx, wcc.User.ID, rID, prefix+video.PathStr, name, data)
+	}
+	switch {
+	case errors.Is(err, us.ErrUnsupportedFormat):
+		return nil, false, vfs.NewHTTPError(400, err)
+	case errors.Is(err, us.ErrLimitReached):
+		return nil, false, vfs.NewHTTPError(403, err)
+	case errors.Is(err, us.ErrNotFound):
+		// The replaced subtitle went away in the meantime.
+		return nil, false, vfs.NewHTTPError(409, err)
+	case err != nil:
+		return nil, false, err
+	}
+	return (&sidecar{Path: p, Sub: sub}).fileInfo(), old == nil, nil
+}
+
+func (s *ContentDirectory) openSidecar(ctx context.Context, sc *sidecar) (io.ReadCloser, error) {
+	rc, _, err := s.subtitles.GetFile(ctx, sc.Sub.Hash)
+	if errors.Is(err, us.ErrNotFound) {
+		return nil, vfs.NewHTTPError(404, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
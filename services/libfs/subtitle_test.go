@@ -0,0 +1,263 @@
+package libfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	ra "github.com/webtor-io/rest-api/services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/auth"
+	us "github.com/webtor-io/web-ui/services/user_subtitle"
+	"github.com/webtor-io/web-ui/services/vfs"
+	"github.com/webtor-io/web-ui/services/web"
+)
+
+// fakeSubtitles keeps user subtitles in memory, keyed the way
+// user_subtitle.Service keys them: (resource, path inside the torrent), and
+// holds each path to us.MaxPerFile the same way.
+type fakeSubtitles struct {
+	subs    []*models.UserSubtitle
+	deleted []uuid.UUID
+}
+
+func (f *fakeSubtitles) count(resourceID, path string, except uuid.UUID) int {
+	n := 0
+	for _, s := range f.subs {
+		if s.ResourceID == resourceID && s.Path == path && s.UserSubtitleID != except {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeSubtitles) ListForResource(_ context.Context, _ uuid.UUID, resourceID string) ([]*models.UserSubtitle, error) {
+	var res []*models.UserSubtitle
+	for _, s := range f.subs {
+		if s.ResourceID == resourceID {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeSubtitles) Upload(ctx context.Context, userID uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error) {
+	return f.Replace(ctx, userID, uuid.Nil, resourceID, path, filename, data)
+}
+
+func (f *fakeSubtitles) Replace(ctx context.Context, userID, replaces uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error) {
+	if f.count(resourceID, path, replaces) >= us.MaxPerFile {
+		return nil, us.ErrLimitReached
+	}
+	if replaces != uuid.Nil {
+		if err := f.Delete(ctx, userID, replaces); err != nil {
+			return nil, err
+		}
+	}
+	format := strings.TrimPrefix(filename[strings.LastIndex(filename, "."):], ".")
+	s := &models.UserSubtitle{
+		UserSubtitleID: uuid.NewV4(),
+		UserID:         userID,
+		ResourceID:     resourceID,
+		Path:           path,
+		Hash:           string(data),
+		OriginalName:   filename,
+		Format:         format,
+		Size:           int64(len(data)),
+	}
+	f.subs = append(f.subs, s)
+	return s, nil
+}
+
+func (f *fakeSubtitles) Delete(_ context.Context, _ uuid.UUID, id uuid.UUID) error {
+	for i, s := range f.subs {
+		if s.UserSubtitleID == id {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			f.deleted = append(f.deleted, id)
+			return nil
+		}
+	}
+	return us.ErrNotFound
+}
+
+func (f *fakeSubtitles) GetFile(_ context.Context, hash string) (io.ReadCloser, int64, error) {
+	return io.NopCloser(strings.NewReader(hash)), int64(len(hash)), nil
+}
+
+func video(path string) ra.ListItem {
+	return ra.ListItem{PathStr: path, Type: ra.ListTypeFile, Size: 100, MimeType: "video/x-matroska", MediaFormat: ra.Video}
+}
+
+func userCtx() context.Context {
+	wc := &web.Context{ApiClaims: &api.Claims{}, User: &auth.User{ID: uuid.NewV4()}}
+	return context.WithValue(context.Background(), web.Context{}, wc)
+}
+
+func newSubtitleContent(files ...ra.ListItem) (*ContentDirectory, *fakeSubtitles, *ContentItemResponse) {
+	subs := &fakeSubtitles{}
+	cd := &ContentDirectory{
+		TorrentDirectory: &TorrentDirectory{api: &fakeTorrentAPI{files: files}},
+		subtitles:        subs,
+	}
+	lr := &ContentItemResponse{
+		Item:    &models.Library{Torrent: &models.TorrentResource{ResourceID: "hash", Name: "Top"}},
+		NewPath: "/",
+		Root:    "/Top/",
+	}
+	return cd, subs, lr
+}
+
+func TestSidecarNames(t *testing.T) {
+	sub := func(orig, format string) *models.UserSubtitle {
+		return &models.UserSubtitle{OriginalName: orig, Format: format}
+	}
+	taken := map[string]struct{}{"Movie.mkv": {}, "Movie.srt": {}}
+	got := sidecarNames("Movie.mkv", []*models.UserSubtitle{
+		sub("Movie.srt", "srt"),          // the torrent ships Movie.srt already
+		sub("Movie.en.srt", "srt"),       // named after the video: kept
+		sub("Movie.en.srt", "srt"),       // a second upload of the same name
+		sub("english-forced.vtt", "vtt"), // tagged with its own name
+		sub("Movie.txt", "vtt"),          // sniffed as VTT, extension fixed
+	}, taken)
+	want := []string{"Movie.2.srt", "Movie.en.srt", "Movie.en-2.srt", "Movie.english-forced.vtt", "Movie.vtt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sidecarNames = %v, want %v", got, want)
+	}
+}
+
+func TestVideoFor_LongestBase(t *testing.T) {
+	items := []ra.ListItem{
+		video("/Show.S01E01.mkv"),
+		video("/Show.S01E01.Extended.mkv"),
+		file("/Show.S01E01.Extended.nfo", 1, "text/plain"),
+	}
+	if v := videoFor(items, "Show.S01E01.Extended.en.srt"); v == nil || v.PathStr != "/Show.S01E01.Extended.mkv" {
+		t.Fatalf("expected the Extended cut, got %+v", v)
+	}
+	if v := videoFor(items, "Show.S01E01.srt"); v == nil || v.PathStr != "/Show.S01E01.mkv" {
+		t.Fatalf("expected the plain cut, got %+v", v)
+	}
+	if v := videoFor(items, "Other.srt"); v != nil {
+		t.Fatalf("expected no match, got %+v", v)
+	}
+}
+
+// A PUT next to a video uploads under the video's torrent path — prefix
+// included, the key the player looks subtitles up by — and then lists, stats
+// and deletes as a file beside it.
+func TestSidecar_PutListDelete(t *testing.T) {
+	cd, subs, lr := newSubtitleContent(
+		video("/Top/Movie.mkv"),
+		file("/Top/Movie.nfo", 1, "text/plain"),
+	)
+	ctx := userCtx()
+
+	fi, created, err := cd.createSidecar(ctx, lr, "/Movie.en.srt", strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nHi\n"))
+	if err != nil {
+		t.Fatalf("createSidecar: %v", err)
+	}
+	if !created || fi.Path != "/Movie.en.srt" {
+		t.Fatalf("unexpected result: created=%v fi=%+v", created, fi)
+	}
+	if len(subs.subs) != 1 || subs.subs[0].Path != "/Top/Movie.mkv" || subs.subs[0].OriginalName != "Movie.en.srt" {
+		t.Fatalf("unexpected upload: %+v", subs.subs)
+	}
+
+	scs, err := cd.sidecars(ctx, lr, "/")
+	if err != nil {
+		t.Fatalf("sidecars: %v", err)
+	}
+	if len(scs) != 1 || scs[0].Path != "/Movie.en.srt" {
+		t.Fatalf("expected /Movie.en.srt beside the video, got %+v", scs)
+	}
+	if fi := scs[0].fileInfo(); fi.MIMEType != us.ContentTypeFor("srt") || fi.Size == 0 {
+		t.Fatalf("unexpected sidecar file info: %+v", fi)
+	}
+
+	// Putting the same name again replaces the subtitle instead of adding one.
+	if _, created, err := cd.createSidecar(ctx, lr, "/Movie.en.srt", strings.NewReader("WEBVTT\n")); err != nil || created {
+		t.Fatalf("replace: created=%v err=%v", created, err)
+	}
+	if len(subs.subs) != 1 || len(subs.deleted) != 1 {
+		t.Fatalf("replace must delete the previous subtitle: subs=%+v deleted=%v", subs.subs, subs.deleted)
+	}
+
+	sc, err := cd.sidecar(ctx, lr, "/Movie.en.srt")
+	if err != nil || sc == nil {
+		t.Fatalf("sidecar lookup: %+v, %v", sc, err)
+	}
+	rc, err := cd.openSidecar(ctx, sc)
+	if err != nil {
+		t.Fatalf("openSidecar: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	if string(b) != "WEBVTT\n" {
+		t.Fatalf("unexpected content %q", b)
+	}
+}
+
+func TestSidecar_PutRejected(t *testing.T) {
+	cd, subs, lr := newSubtitleContent(
+		video("/Top/Movie.mkv"),
+		file("/Top/Movie.srt", 10, "text/plain"),
+	)
+	ctx := userCtx()
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"Movie.srt", "x", http.StatusForbidden},   // a file of the torrent
+		{"Trailer.srt", "x", http.StatusForbidden}, // no video by that name
+		{"Movie.en.srt", strings.Repeat("x", int(us.MaxUploadSize)+1), http.StatusRequestEntityTooLarge},
+	} {
+		_, _, err := cd.createSidecar(ctx, lr, "/"+tt.name, strings.NewReader(tt.body))
+		if got := vfs.HTTPErrorFromError(err); err == nil || got.Code != tt.code {
+			t.Errorf("PUT %s: expected %d, got %v", tt.name, tt.code, err)
+		}
+	}
+	// The empty file clients create before writing is accepted, not stored.
+	if _, created, err := cd.createSidecar(ctx, lr, "/Movie.en.srt", strings.NewReader("")); err != nil || !created {
+		t.Fatalf("empty PUT: created=%v err=%v", created, err)
+	}
+	if len(subs.subs) != 0 {
+		t.Fatalf("nothing must be stored, got %+v", subs.subs)
+	}
+}
+
+// Rewriting a sidecar on a video that already has us.MaxPerFile subtitles
+// replaces it rather than counting as one more.
+func TestSidecar_ReplaceAtLimit(t *testing.T) {
+	cd, subs, lr := newSubtitleContent(video("/Top/Movie.mkv"))
+	ctx := userCtx()
+	for i := 0; i < us.MaxPerFile; i++ {
+		name := "/Movie." + strconv.Itoa(i) + ".srt"
+		if _, _, err := cd.createSidecar(ctx, lr, name, strings.NewReader("sub "+name)); err != nil {
+			t.Fatalf("createSidecar %s: %v", name, err)
+		}
+	}
+	if _, _, err := cd.createSidecar(ctx, lr, "/Movie.new.srt", strings.NewReader("x")); vfs.HTTPErrorFromError(err).Code != http.StatusForbidden {
+		t.Fatalf("a new sidecar past the limit: expected 403, got %v", err)
+	}
+	if _, created, err := cd.createSidecar(ctx, lr, "/Movie.3.srt", strings.NewReader("WEBVTT\n")); err != nil || created {
+		t.Fatalf("replace at the limit: created=%v err=%v", created, err)
+	}
+	if len(subs.subs) != us.MaxPerFile || len(subs.deleted) != 1 {
+		t.Fatalf("expected one subtitle replaced: subs=%d deleted=%v", len(subs.subs), subs.deleted)
+	}
+}
+
+func TestSidecar_NotConfigured(t *testing.T) {
+	cd, _, lr := newSubtitleContent(video("/Top/Movie.mkv"))
+	cd.subtitles = nil
+	scs, err := cd.sidecars(userCtx(), lr, "/")
+	if err != nil || scs != nil {
+		t.Fatalf("expected no sidecars without a store, got %+v, %v", scs, err)
+	}
+}
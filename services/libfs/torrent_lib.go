@@ -7,25 +7,39 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/anacrolix/torrent/metainfo"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 	services "github.com/webtor-io/common-services"
 	ra "github.com/webtor-io/rest-api/services"
 	j "github.com/webtor-io/web-ui/jobs"
 	"github.com/webtor-io/web-ui/models"
 	"github.com/webtor-io/web-ui/services/api"
+	le "github.com/webtor-io/web-ui/services/library_event"
 	"github.com/webtor-io/web-ui/services/vfs"
 )
 
 type TorrentLibraryDirectory struct {
 	*BaseDirectory
-	pg   *services.PG
-	api  *api.Api
-	jobs *j.Jobs
+	pg     *services.PG
+	api    *api.Api
+	jobs   *j.Jobs
+	events *le.Bus
 }
 
 func (s *TorrentLibraryDirectory) Open(ctx context.Context, name string) (io.ReadCloser, *url.URL, error) {
+	if isMagnetName(name) {
+		m, err := s.getMagnet(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if m == nil {
+			return nil, nil, vfs.NewHTTPError(404, errors.New("file not found"))
+		}
+		return io.NopCloser(strings.NewReader(m.Body)), nil, nil
+	}
 	l, err := s.getLibraryByName(ctx, torrentToName(name))
 	if err != nil {
 		return nil, nil, err
@@ -38,6 +52,17 @@ func (s *TorrentLibraryDirectory) Open(ctx context.Context, name string) (io.Rea
 }
 
 func (s *TorrentLibraryDirectory) Stat(ctx context.Context, name string) (*vfs.FileInfo, error) {
+	if isMagnetName(name) {
+		m, err := s.getMagnet(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, vfs.NewHTTPError(404, errors.New("file not found"))
+		}
+		fi := magnetToFileInfo(m, strings.TrimPrefix(name, "/"))
+		return &fi, nil
+	}
 	l, err := s.getLibraryByName(ctx, torrentToName(name))
 	if err != nil {
 		return nil, err
@@ -54,14 +79,25 @@ func (s *TorrentLibraryDirectory) ReadDir(ctx context.Context, name string, recu
 	if err != nil {
 		return nil, err
 	}
-	fi := make([]vfs.FileInfo, len(ls))
-	for i, v := range ls {
-		fi[i] = s.libraryToFileInfo(v)
+	ms, err := s.getMagnets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fi := make([]vfs.FileInfo, 0, len(ls)+len(ms))
+	for _, v := range ls {
+		fi = append(fi, s.libraryToFileInfo(v))
+	}
+	now := time.Now()
+	for _, m := range ms {
+		fi = append(fi, magnetToFileInfo(m, placeholderName(m, now)))
 	}
 	return fi, nil
 }
 
 func (s *TorrentLibraryDirectory) Create(ctx context.Context, name string, body io.ReadCloser, opts *vfs.CreateOptions) (*vfs.FileInfo, bool, error) {
+	if isMagnetName(name) {
+		return s.createMagnet(ctx, name, body)
+	}
 	if !strings.HasSuffix(name, ".torrent") {
 		return nil, false, vfs.NewHTTPError(400, errors.New("bad request"))
 	}
@@ -85,6 +121,9 @@ func (s *TorrentLibraryDirectory) Create(ctx context.Context, name string, body
 }
 
 func (s *TorrentLibraryDirectory) RemoveAll(ctx context.Context, name string, opts *vfs.RemoveAllOptions) error {
+	if isMagnetName(name) {
+		return s.removeMagnet(ctx, name)
+	}
 	l, err := s.getLibraryByName(ctx, torrentToName(name))
 	if err != nil {
 		return err
@@ -124,29 +163,41 @@ func (s *TorrentLibraryDirectory) storeToLibrary(ctx context.Context, resourceID
 	if err != nil {
 		return nil, err
 	}
+	s.publish(le.Added, wcc.User.ID, resourceID)
 	_, _ = s.jobs.Enrich(wcc, resourceID)
 	return l, nil
 }
 
+func (s *TorrentLibraryDirectory) publish(kind le.Kind, userID uuid.UUID, resourceID string) {
+	s.events.Publish(le.Event{Kind: kind, UserID: userID.String(), ResourceID: resourceID})
+}
+
 func (s *TorrentLibraryDirectory) storeTorrentToAPI(ctx context.Context, t []byte) (*ra.ResourceResponse, *metainfo.Info, error) {
 	wcc, err := getWebContext(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	b := io.NopCloser(bytes.NewReader(t))
-	mi, err := metainfo.Load(b)
+	info, err := loadTorrentInfo(t)
 	if err != nil {
 		return nil, nil, err
 	}
-	info, err := mi.UnmarshalInfo()
+	resp, err := s.api.StoreResource(ctx, wcc.ApiClaims, t)
 	if err != nil {
 		return nil, nil, err
 	}
-	resp, err := s.api.StoreResource(ctx, wcc.ApiClaims, t)
+	return resp, info, nil
+}
+
+func loadTorrentInfo(t []byte) (*metainfo.Info, error) {
+	mi, err := metainfo.Load(io.NopCloser(bytes.NewReader(t)))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil, err
 	}
-	return resp, &info, nil
+	return &info, nil
 }
 
 func (s *TorrentLibraryDirectory) readTorrent(ctx context.Context, l *models.Library) (io.ReadCloser, *url.URL, error) {
@@ -170,7 +221,11 @@ func (s *TorrentLibraryDirectory) removeFromLibrary(ctx context.Context, l *mode
 	if err != nil {
 		return err
 	}
-	return models.RemoveFromLibrary(ctx, db, wcc.User.ID, l.Torrent.ResourceID)
+	if err := models.RemoveFromLibrary(ctx, db, wcc.User.ID, l.Torrent.ResourceID); err != nil {
+		return err
+	}
+	s.publish(le.Removed, wcc.User.ID, l.Torrent.ResourceID)
+	return nil
 }
 
 func (s *TorrentLibraryDirectory) updateLibraryName(ctx context.Context, l *models.Library) error {
@@ -178,7 +233,11 @@ func (s *TorrentLibraryDirectory) updateLibraryName(ctx context.Context, l *mode
 	if db == nil {
 		return errors.New("db is nil")
 	}
-	return models.UpdateLibraryName(ctx, db, l)
+	if err := models.UpdateLibraryName(ctx, db, l); err != nil {
+		return err
+	}
+	s.publish(le.Renamed, l.UserID, l.ResourceID)
+	return nil
 }
 
 func (s *TorrentLibraryDirectory) getLibraryList(ctx context.Context) ([]*models.Library, error) {
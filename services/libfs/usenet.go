@@ -0,0 +1,275 @@
+package libfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+// shortcutMIMEType is the type of the .url files standing in for the
+// folders self-hosted backends save downloads to.
+const shortcutMIMEType = "application/internet-shortcut"
+
+// usenetLinker hands out links to the files of finished Usenet downloads.
+// LinkResolver is the production one.
+type usenetLinker interface {
+	NZBLink(ctx context.Context, backend *models.StreamingBackend, id, fileID string) (string, error)
+}
+
+// usenetTree is the usenet tree's layout: a folder per finished download.
+type usenetTree map[string]*models.UsenetDownload
+
+// usenetFolders names the folders downloads are shown under: the release
+// name, with a counter when two downloads would share one. Downloads come
+// oldest first, so a folder keeps its name when a newer namesake arrives.
+func usenetFolders(ds []*models.UsenetDownload) usenetTree {
+	res := usenetTree{}
+	for _, d := range ds {
+		name := groupName(d.Name)
+		if name == "" {
+			name = d.ID.String()
+		}
+		folder := name
+		for i := 2; ; i++ {
+			if _, ok := res[folder]; !ok {
+				break
+			}
+			folder = name + " (" + strconv.Itoa(i) + ")"
+		}
+		res[folder] = d
+	}
+	return res
+}
+
+func (t usenetTree) folders() []string {
+	res := make([]string, 0, len(t))
+	for f := range t {
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func getUsenetTree(ctx context.Context, db *pg.DB, uID uuid.UUID) (usenetTree, error) {
+	ds, err := models.GetUserReadyUsenetDownloads(ctx, db, uID)
+	if err != nil {
+		return nil, err
+	}
+	return usenetFolders(ds), nil
+}
+
+// usenetEntry is a file of a download as its folder lists it.
+type usenetEntry struct {
+	name string
+	file models.UsenetDownloadFile
+}
+
+// shortcut reports whether the entry is the folder a self-hosted backend
+// saved the download to rather than a file. It is listed as an internet
+// shortcut to the link stored with the download: the address is the user's
+// own, so the server must not fetch it on their behalf.
+func (e *usenetEntry) shortcut() bool {
+	return e.file.ID == ""
+}
+
+// usenetEntries lists the files of a download under flat names, told apart
+// with a counter before the extension when two would clash. A folder with
+// no stored link, because the backend has no files URL, is left out.
+func usenetEntries(d *models.UsenetDownload) []usenetEntry {
+	res := make([]usenetEntry, 0, len(d.Files))
+	seen := map[string]bool{}
+	for _, f := range d.Files {
+		name := groupName(path.Base(strings.ReplaceAll(f.Name, "\\", "/")))
+		if name == "" || name == "." || name == "/" {
+			continue
+		}
+		if f.ID == "" && f.Link == "" {
+			continue
+		}
+		if f.ID == "" {
+			name += ".url"
+		}
+		ext := path.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for i := 2; seen[name]; i++ {
+			name = base + " (" + strconv.Itoa(i) + ")" + ext
+		}
+		seen[name] = true
+		res = append(res, usenetEntry{name: name, file: f})
+	}
+	return res
+}
+
+// shortcutBody is an internet shortcut to the link, which file managers
+// and browsers open as the link itself.
+func shortcutBody(link string) []byte {
+	return []byte("[InternetShortcut]\r\nURL=" + link + "\r\n")
+}
+
+// usenetRoot builds usenet/<download>/, each folder holding the files of a
+// finished Usenet download.
+func usenetRoot(p *cs.PG, un usenetLinker) vfs.FileSystem {
+	return &GroupDirectory{
+		pg: p,
+		Groups: func(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]string, error) {
+			t, err := getUsenetTree(ctx, db, uID)
+			if err != nil {
+				return nil, err
+			}
+			return t.folders(), nil
+		},
+		Child: func(folder string) vfs.FileSystem {
+			return &UsenetDownloadDirectory{
+				pg:     p,
+				un:     un,
+				folder: folder,
+			}
+		},
+	}
+}
+
+// UsenetDownloadDirectory is one finished Usenet download's folder. It is
+// listed from the stored download; only opening a file asks the backend for
+// its link. It is read-only.
+type UsenetDownloadDirectory struct {
+	BaseDirectory
+	pg     *cs.PG
+	un     usenetLinker
+	folder string
+}
+
+func (s *UsenetDownloadDirectory) getDownload(ctx context.Context) (*models.UsenetDownload, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := getUsenetTree(ctx, db, wcc.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	d := t[s.folder]
+	if d == nil {
+		return nil, vfs.NewHTTPError(404, errors.New("file not found"))
+	}
+	return d, nil
+}
+
+func (s *UsenetDownloadDirectory) getEntry(ctx context.Context, p string) (*models.UsenetDownload, *usenetEntry, error) {
+	d, err := s.getDownload(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := strings.TrimPrefix(p, "/")
+	for _, e := range usenetEntries(d) {
+		if e.name == name {
+			return d, &e, nil
+		}
+	}
+	return nil, nil, vfs.NewHTTPError(404, errors.New("file not found"))
+}
+
+func (s *UsenetDownloadDirectory) getLink(ctx context.Context, d *models.UsenetDownload, e *usenetEntry) (string, error) {
+	if d.StreamingBackend == nil {
+		return "", vfs.NewHTTPError(404, errors.New("usenet download has no backend"))
+	}
+	return s.un.NZBLink(ctx, d.StreamingBackend, d.RemoteID, e.file.ID)
+}
+
+func downloadFileInfo(d *models.UsenetDownload) vfs.FileInfo {
+	fi := vfs.FileInfo{ModTime: d.CreatedAt}
+	if d.FinishedAt != nil {
+		fi.ModTime = *d.FinishedAt
+	}
+	return fi
+}
+
+// usenetFileInfo describes an entry from the stored download alone, so
+// listings never wait on the user's backend.
+func usenetFileInfo(d *models.UsenetDownload, e *usenetEntry) vfs.FileInfo {
+	fi := downloadFileInfo(d)
+	fi.Path = "/" + e.name
+	if e.shortcut() {
+		fi.Size = int64(len(shortcutBody(e.file.Link)))
+		fi.MIMEType = shortcutMIMEType
+		return fi
+	}
+	fi.Size = e.file.Size
+	fi.MIMEType = mime.TypeByExtension(path.Ext(e.name))
+	return fi
+}
+
+func (s *UsenetDownloadDirectory) Stat(ctx context.Context, p string) (*vfs.FileInfo, error) {
+	if isRoot(p) {
+		d, err := s.getDownload(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fi := downloadFileInfo(d)
+		fi.Path = "/"
+		fi.IsDir = true
+		return &fi, nil
+	}
+	d, e, err := s.getEntry(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	fi := usenetFileInfo(d, e)
+	return &fi, nil
+}
+
+func (s *UsenetDownloadDirectory) ReadDir(ctx context.Context, p string, recursive bool) ([]vfs.FileInfo, error) {
+	if !isRoot(p) {
+		return nil, vfs.NewHTTPError(404, errors.New("file not found"))
+	}
+	d, err := s.getDownload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	es := usenetEntries(d)
+	fis := make([]vfs.FileInfo, 0, len(es))
+	for i := range es {
+		fis = append(fis, usenetFileInfo(d, &es[i]))
+	}
+	return fis, nil
+}
+
+func (s *UsenetDownloadDirectory) Open(ctx context.Context, p string) (io.ReadCloser, *url.URL, error) {
+	if isRoot(p) {
+		return nil, nil, vfs.NewHTTPError(403, errors.New("operation not permitted"))
+	}
+	d, e, err := s.getEntry(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.shortcut() {
+		return io.NopCloser(bytes.NewReader(shortcutBody(e.file.Link))), nil, nil
+	}
+	link, err := s.getLink(ctx, d, e)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse usenet link")
+	}
+	return nil, u, nil
+}
+
+var _ vfs.FileSystem = (*UsenetDownloadDirectory)(nil)
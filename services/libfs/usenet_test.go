@@ -0,0 +1,64 @@
+package libfs
+
+import (
+	"reflect"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/webtor-io/web-ui/models"
+)
+
+func TestUsenetFolders(t *testing.T) {
+	movie := &models.UsenetDownload{ID: uuid.NewV4(), Name: "Movie (2024)"}
+	again := &models.UsenetDownload{ID: uuid.NewV4(), Name: " Movie (2024) "}
+	acdc := &models.UsenetDownload{ID: uuid.NewV4(), Name: "AC/DC Live"}
+	unnamed := &models.UsenetDownload{ID: uuid.FromStringOrNil("f47ac10b-58cc-4372-a567-0e02b2c3d479")}
+
+	got := usenetFolders([]*models.UsenetDownload{movie, again, acdc, unnamed})
+	want := usenetTree{
+		"Movie (2024)":      movie,
+		"Movie (2024) (2)":  again,
+		"AC-DC Live":        acdc,
+		unnamed.ID.String(): unnamed,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("usenetFolders = %v, want %v", got, want)
+	}
+	if fs := got.folders(); !reflect.DeepEqual(fs, []string{"AC-DC Live", "Movie (2024)", "Movie (2024) (2)", unnamed.ID.String()}) {
+		t.Errorf("folders = %v", fs)
+	}
+}
+
+func TestUsenetEntries(t *testing.T) {
+	names := func(d *models.UsenetDownload) []string {
+		var res []string
+		for _, e := range usenetEntries(d) {
+			res = append(res, e.name)
+		}
+		return res
+	}
+	torbox := &models.UsenetDownload{Files: []models.UsenetDownloadFile{
+		{ID: "0", Name: "Show/S01E01.mkv"},
+		{ID: "1", Name: "Show/Extras/S01E01.mkv"},
+		{ID: "2", Name: `Show\Sample\sample.mkv`},
+		{ID: "3", Name: ""},
+	}}
+	if got := names(torbox); !reflect.DeepEqual(got, []string{"S01E01.mkv", "S01E01 (2).mkv", "sample.mkv"}) {
+		t.Errorf("entries = %v", got)
+	}
+	link := "https://files.example.com/Movie"
+	sab := &models.UsenetDownload{Files: []models.UsenetDownloadFile{{Name: "Movie (2024)", Link: link}}}
+	es := usenetEntries(sab)
+	if len(es) != 1 || es[0].name != "Movie (2024).url" || !es[0].shortcut() {
+		t.Errorf("self-hosted entries = %+v", es)
+	}
+	if string(shortcutBody(link)) != "[InternetShortcut]\r\nURL=https://files.example.com/Movie\r\n" {
+		t.Errorf("shortcutBody = %q", shortcutBody(link))
+	}
+	if fi := usenetFileInfo(sab, &es[0]); fi.Size != int64(len(shortcutBody(link))) || fi.MIMEType != shortcutMIMEType {
+		t.Errorf("shortcut info = %+v", fi)
+	}
+	if es := usenetEntries(&models.UsenetDownload{Files: []models.UsenetDownloadFile{{Name: "Movie (2024)"}}}); len(es) != 0 {
+		t.Errorf("entries without a files URL = %+v", es)
+	}
+}
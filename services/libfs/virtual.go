@@ -0,0 +1,305 @@
+package libfs
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	cs "github.com/webtor-io/common-services"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+// GroupDirectory is one generated level of a browsable tree: its folders are
+// computed per user from the library (a genre, a decade, a collection) and
+// each opens into another directory — a deeper GroupDirectory or, at the
+// leaves, a ContentDirectory over the matching part of the library.
+type GroupDirectory struct {
+	BaseDirectory
+	pg *cs.PG
+	// Groups lists the folder names of this level.
+	Groups func(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]string, error)
+	// Child is the directory behind a folder name.
+	Child func(group string) vfs.FileSystem
+}
+
+// splitGroup cuts "/Drama/Movie/a.mkv" into "Drama" and "/Movie/a.mkv".
+func splitGroup(path string) (group string, rest string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], "/"
+	}
+	return parts[0], "/" + parts[1]
+}
+
+func (s *GroupDirectory) getGroups(ctx context.Context) ([]string, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	wcc, err := getWebContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Groups(ctx, db, wcc.User.ID)
+}
+
+func (s *GroupDirectory) hasGroup(ctx context.Context, group string) (bool, error) {
+	gs, err := s.getGroups(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, g := range gs {
+		if g == group {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *GroupDirectory) ReadDir(ctx context.Context, path string, recursive bool) ([]vfs.FileInfo, error) {
+	if isRoot(path) {
+		gs, err := s.getGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fis := make([]vfs.FileInfo, len(gs))
+		for i, g := range gs {
+			fis[i] = newDirectoryFileInfo(g)
+		}
+		return fis, nil
+	}
+	group, rest := splitGroup(path)
+	if isRoot(rest) {
+		ok, err := s.hasGroup(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, vfs.NewHTTPError(404, errors.New("file not found"))
+		}
+	}
+	fis, err := s.Child(group).ReadDir(ctx, rest, recursive)
+	if err != nil {
+		return nil, err
+	}
+	return AddPrefixes(fis, "/"+group+"/"), nil
+}
+
+func (s *GroupDirectory) Stat(ctx context.Context, path string) (*vfs.FileInfo, error) {
+	if isRoot(path) {
+		fi := newDirectoryFileInfo("/")
+		return &fi, nil
+	}
+	group, rest := splitGroup(path)
+	if isRoot(rest) {
+		ok, err := s.hasGroup(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, vfs.NewHTTPError(404, errors.New("file not found"))
+		}
+		fi := newDirectoryFileInfo(group)
+		return &fi, nil
+	}
+	fi, err := s.Child(group).Stat(ctx, rest)
+	if err != nil {
+		return nil, err
+	}
+	return AddPrefix(fi, "/"+group+"/"), nil
+}
+
+func (s *GroupDirectory) Open(ctx context.Context, path string) (io.ReadCloser, *url.URL, error) {
+	group, rest := splitGroup(path)
+	if isRoot(path) || isRoot(rest) {
+		return nil, nil, vfs.NewHTTPError(403, errors.New("operation not permitted"))
+	}
+	return s.Child(group).Open(ctx, rest)
+}
+
+// Create and RemoveAll pass through, so subtitle sidecars can be managed from
+// any tree a video shows up in.
+func (s *GroupDirectory) Create(ctx context.Context, path string, body io.ReadCloser, opts *vfs.CreateOptions) (*vfs.FileInfo, bool, error) {
+	group, rest := splitGroup(path)
+	if isRoot(path) || isRoot(rest) {
+		return nil, false, vfs.NewHTTPError(403, errors.New("operation not permitted"))
+	}
+	fi, ok, err := s.Child(group).Create(ctx, rest, body, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	return AddPrefix(fi, "/"+group+"/"), ok, nil
+}
+
+func (s *GroupDirectory) RemoveAll(ctx context.Context, path string, opts *vfs.RemoveAllOptions) error {
+	group, rest := splitGroup(path)
+	if isRoot(path) || isRoot(rest) {
+		return vfs.NewHTTPError(403, errors.New("operation not permitted"))
+	}
+	return s.Child(group).RemoveAll(ctx, rest, opts)
+}
+
+var _ vfs.FileSystem = (*GroupDirectory)(nil)
+
+// facetKey reads the folder names a library entry belongs under at one level
+// of a tree. Names are already safe as path segments.
+type facetKey func(f *models.LibraryFacet) []string
+
+func groupName(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "/", "-"))
+}
+
+func genreKey(f *models.LibraryFacet) []string {
+	res := make([]string, 0, len(f.Genres))
+	for _, g := range f.Genres {
+		if g = groupName(g); g != "" {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func collectionKey(f *models.LibraryFacet) []string {
+	if c := groupName(f.Collection); c != "" {
+		return []string{c}
+	}
+	return nil
+}
+
+func decadeKey(f *models.LibraryFacet) []string {
+	if f.Year == nil || *f.Year <= 0 {
+		return nil
+	}
+	return []string{strconv.Itoa(int(*f.Year)/10*10) + "s"}
+}
+
+func yearKey(f *models.LibraryFacet) []string {
+	if f.Year == nil || *f.Year <= 0 {
+		return nil
+	}
+	return []string{strconv.Itoa(int(*f.Year))}
+}
+
+func hasKey(key facetKey, f *models.LibraryFacet, group string) bool {
+	for _, k := range key(f) {
+		if k == group {
+			return true
+		}
+	}
+	return false
+}
+
+type facetStep struct {
+	key   facetKey
+	group string
+}
+
+// facetFilter narrows facets down to those under a path of folders, one step
+// per level above.
+type facetFilter []facetStep
+
+func (ff facetFilter) with(key facetKey, group string) facetFilter {
+	return append(ff[:len(ff):len(ff)], facetStep{key: key, group: group})
+}
+
+func (ff facetFilter) match(f *models.LibraryFacet) bool {
+	for _, st := range ff {
+		if !hasKey(st.key, f, st.group) {
+			return false
+		}
+	}
+	return true
+}
+
+// facetGroups is the sorted set of folder names key yields across the facets
+// ff lets through.
+func facetGroups(fs []*models.LibraryFacet, ff facetFilter, key facetKey) []string {
+	seen := map[string]struct{}{}
+	var res []string
+	for _, f := range fs {
+		if !ff.match(f) {
+			continue
+		}
+		for _, k := range key(f) {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// FacetLibrary is the part of the library under a path of generated folders.
+type FacetLibrary struct {
+	Filter facetFilter
+}
+
+func (s *FacetLibrary) GetContent(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]*models.Library, error) {
+	fs, err := models.GetLibraryFacets(ctx, db, uID)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]struct{}{}
+	for _, f := range fs {
+		if s.Filter.match(f) {
+			ids[f.ResourceID] = struct{}{}
+		}
+	}
+	return getLibraryByIDs(ctx, db, uID, ids)
+}
+
+var _ Library = (*FacetLibrary)(nil)
+
+// getLibraryByIDs is the user's library, by name, narrowed to ids.
+func getLibraryByIDs(ctx context.Context, db *pg.DB, uID uuid.UUID, ids map[string]struct{}) ([]*models.Library, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	ls, err := models.GetLibraryTorrentsList(ctx, db, uID, models.SortTypeName)
+	if err != nil {
+		return nil, err
+	}
+	res := ls[:0]
+	for _, l := range ls {
+		if _, ok := ids[l.ResourceID]; ok {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+// facetTree builds a browsable tree with one generated level per key; the
+// last level opens into the matching library entries.
+func facetTree(pg *cs.PG, content func(Library) vfs.FileSystem, keys ...facetKey) vfs.FileSystem {
+	return facetLevel(pg, content, nil, keys)
+}
+
+func facetLevel(p *cs.PG, content func(Library) vfs.FileSystem, ff facetFilter, keys []facetKey) vfs.FileSystem {
+	if len(keys) == 0 {
+		return content(&FacetLibrary{Filter: ff})
+	}
+	key := keys[0]
+	return &GroupDirectory{
+		pg: p,
+		Groups: func(ctx context.Context, db *pg.DB, uID uuid.UUID) ([]string, error) {
+			fs, err := models.GetLibraryFacets(ctx, db, uID)
+			if err != nil {
+				return nil, err
+			}
+			return facetGroups(fs, ff, key), nil
+		},
+		Child: func(group string) vfs.FileSystem {
+			return facetLevel(p, content, ff.with(key, group), keys[1:])
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package libfs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+func facet(id string, year int16, collection string, genres ...string) *models.LibraryFacet {
+	f := &models.LibraryFacet{ResourceID: id, Collection: collection, Genres: genres}
+	if year != 0 {
+		f.Year = &year
+	}
+	return f
+}
+
+var testFacets = []*models.LibraryFacet{
+	facet("a", 1994, "", "Drama", "Crime"),
+	facet("b", 1999, "The Matrix Collection", "Action", "Science Fiction"),
+	facet("c", 2003, "The Matrix Collection", "Action", "Science Fiction"),
+	facet("d", 0, "", "Action & Adventure", "Sci-Fi / Fantasy"),
+	facet("e", 0, ""), // not enriched: in no tree at all
+}
+
+func TestFacetGroups(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		ff   facetFilter
+		key  facetKey
+		want []string
+	}{
+		{"genres", nil, genreKey, []string{"Action", "Action & Adventure", "Crime", "Drama", "Sci-Fi - Fantasy", "Science Fiction"}},
+		{"decades", nil, decadeKey, []string{"1990s", "2000s"}},
+		{"years of a decade", facetFilter{{decadeKey, "1990s"}}, yearKey, []string{"1994", "1999"}},
+		{"collections", nil, collectionKey, []string{"The Matrix Collection"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := facetGroups(testFacets, tt.ff, tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("facetGroups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFacetFilter(t *testing.T) {
+	ff := facetFilter(nil).with(decadeKey, "1990s").with(yearKey, "1999")
+	var got []string
+	for _, f := range testFacets {
+		if ff.match(f) {
+			got = append(got, f.ResourceID)
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("years/1990s/1999 = %v, want [b]", got)
+	}
+	// with must not share the parent's backing array between siblings.
+	parent := facetFilter(nil).with(decadeKey, "1990s")
+	a, b := parent.with(yearKey, "1994"), parent.with(yearKey, "1999")
+	if a[1].group != "1994" || b[1].group != "1999" {
+		t.Fatalf("siblings overwrote each other: %v %v", a[1].group, b[1].group)
+	}
+}
+
+func TestSplitGroup(t *testing.T) {
+	for path, want := range map[string][2]string{
+		"/Drama":             {"Drama", "/"},
+		"/Drama/":            {"Drama", "/"},
+		"/Drama/Movie/a.mkv": {"Drama", "/Movie/a.mkv"},
+		"/1990s/1994/Movie/": {"1990s", "/1994/Movie/"},
+	} {
+		if g, r := splitGroup(path); g != want[0] || r != want[1] {
+			t.Errorf("splitGroup(%q) = %q, %q; want %q, %q", path, g, r, want[0], want[1])
+		}
+	}
+}
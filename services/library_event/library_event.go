@@ -0,0 +1,144 @@
+// Package library_event tells every replica that a user's library changed, so
+// caches of its listings can be dropped instead of waiting out their TTL.
+package library_event
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	cs "github.com/webtor-io/common-services"
+)
+
+// subject is core NATS, not JetStream: every replica has to see every event,
+// which a shared durable consumer would prevent, and an event missed while a
+// replica restarts does not matter — its caches restarted empty.
+const subject = "web-ui.library.changed"
+
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Renamed Kind = "renamed"
+	// Enriched is sent when enrichment of a torrent finishes, which is when
+	// it moves into the movies/series trees, some time after Added.
+	Enriched Kind = "enriched"
+)
+
+type Event struct {
+	Kind Kind `json:"kind"`
+	// UserID is empty when the change concerns everyone who has the resource
+	// (enrichment is per torrent, not per user).
+	UserID     string `json:"user_id,omitempty"`
+	ResourceID string `json:"resource_id"`
+}
+
+type message struct {
+	Event
+	// Origin is the sending replica, which has already handled the event.
+	Origin string `json:"origin"`
+}
+
+// Bus publishes library changes and hands them to subscribers on every
+// replica. A nil *Bus is valid and does nothing; without NATS, events only
+// reach subscribers of the replica that published them.
+type Bus struct {
+	nats     *cs.NATS
+	origin   string
+	mux      sync.RWMutex
+	handlers []func(Event)
+	sub      *nats.Subscription
+	done     chan struct{}
+}
+
+func New(nats *cs.NATS) *Bus {
+	return &Bus{
+		nats:   nats,
+		origin: uuid.NewV4().String(),
+		done:   make(chan struct{}),
+	}
+}
+
+// Subscribe registers h for every event, local or remote. h runs on the
+// publishing goroutine for local events, so it must be quick.
+func (s *Bus) Subscribe(h func(Event)) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.handlers = append(s.handlers, h)
+}
+
+// Publish handles e locally right away — so the request that made the change
+// sees it on its next listing — and broadcasts it to the other replicas.
+func (s *Bus) Publish(e Event) {
+	if s == nil {
+		return
+	}
+	s.dispatch(e)
+	if s.nats == nil {
+		return
+	}
+	nc := s.nats.Get()
+	if nc == nil {
+		return
+	}
+	b, err := json.Marshal(&message{Event: e, Origin: s.origin})
+	if err != nil {
+		log.WithError(err).Error("failed to marshal library event")
+		return
+	}
+	if err := nc.Publish(subject, b); err != nil {
+		log.WithError(err).WithField("kind", e.Kind).Warn("failed to publish library event")
+	}
+}
+
+func (s *Bus) dispatch(e Event) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, h := range s.handlers {
+		h(e)
+	}
+}
+
+func (s *Bus) handle(data []byte) {
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		log.WithError(err).Warn("failed to unmarshal library event")
+		return
+	}
+	if m.Origin == s.origin {
+		return
+	}
+	s.dispatch(m.Event)
+}
+
+// Serve receives other replicas' events until Close.
+func (s *Bus) Serve() error {
+	nc := s.nats.Get()
+	if nc == nil {
+		log.Warn("nats connection is nil, library events stay local")
+		return nil
+	}
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		s.handle(msg.Data)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to subscribe to library events")
+	}
+	s.sub = sub
+	<-s.done
+	return nil
+}
+
+func (s *Bus) Close() {
+	if s.sub != nil {
+		_ = s.sub.Unsubscribe()
+	}
+	close(s.done)
+}
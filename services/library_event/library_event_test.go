@@ -0,0 +1,39 @@
+package library_event
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPublishWithoutNATS(t *testing.T) {
+	b := New(nil)
+	var got []Event
+	b.Subscribe(func(e Event) { got = append(got, e) })
+	b.Publish(Event{Kind: Added, UserID: "u", ResourceID: "r"})
+	if len(got) != 1 || got[0].Kind != Added || got[0].UserID != "u" {
+		t.Fatalf("got %+v", got)
+	}
+
+	var nb *Bus
+	nb.Subscribe(func(Event) { t.Fatal("nil bus dispatched") })
+	nb.Publish(Event{Kind: Removed})
+}
+
+func TestHandleSkipsOwnEvents(t *testing.T) {
+	b := New(nil)
+	var got []Event
+	b.Subscribe(func(e Event) { got = append(got, e) })
+
+	own, _ := json.Marshal(&message{Event: Event{Kind: Renamed, UserID: "u"}, Origin: b.origin})
+	b.handle(own)
+	if len(got) != 0 {
+		t.Fatalf("own event handled twice: %+v", got)
+	}
+
+	other, _ := json.Marshal(&message{Event: Event{Kind: Enriched, ResourceID: "r"}, Origin: "other"})
+	b.handle(other)
+	b.handle([]byte("not json"))
+	if len(got) != 1 || got[0].Kind != Enriched || got[0].UserID != "" || got[0].ResourceID != "r" {
+		t.Fatalf("got %+v", got)
+	}
+}
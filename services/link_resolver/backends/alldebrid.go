@@ -0,0 +1,210 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/lazymap"
+	ad "github.com/webtor-io/web-ui/services/alldebrid"
+	"github.com/webtor-io/web-ui/services/link_resolver/common"
+)
+
+// resolveLinkResultAllDebrid holds the result of a ResolveLink call
+type resolveLinkResultAllDebrid struct {
+	url    string
+	cached bool
+}
+
+// AllDebrid implements Backend interface for AllDebrid
+type AllDebrid struct {
+	linkCache lazymap.LazyMap[*resolveLinkResultAllDebrid]
+	cl        *http.Client
+	baseURL   string
+}
+
+// Compile-time check to ensure AllDebrid implements Backend interface
+var _ common.Backend = (*AllDebrid)(nil)
+var _ common.AccountChecker = (*AllDebrid)(nil)
+var _ common.LinkForgetter = (*AllDebrid)(nil)
+
+// NewAllDebrid creates a new AllDebrid backend
+func NewAllDebrid(cl *http.Client) *AllDebrid {
+	return &AllDebrid{
+		linkCache: *lazymap.New[*resolveLinkResultAllDebrid](&lazymap.Config{
+			Expire:      15 * time.Minute,
+			ErrorExpire: 30 * time.Second,
+			Concurrency: 5,
+		}),
+		cl:      cl,
+		baseURL: "https://api.alldebrid.com",
+	}
+}
+
+func (s *AllDebrid) Validate(ctx context.Context, token string) error {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return err
+	}
+	_, err = cl.GetUser(ctx)
+	return err
+}
+
+// ContentKinds reports that AllDebrid fetches torrents only
+func (s *AllDebrid) ContentKinds() []common.ContentKind {
+	return []common.ContentKind{common.ContentKindTorrent}
+}
+
+// Account describes the AllDebrid account behind the token
+func (s *AllDebrid) Account(ctx context.Context, token string) (*common.Account, error) {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	u, err := cl.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a := &common.Account{Points: &u.FidelityPoints}
+	if u.IsPremium && u.PremiumUntil > 0 {
+		t := time.Unix(u.PremiumUntil, 0)
+		a.PremiumUntil = &t
+	}
+	return a, nil
+}
+
+// getClient creates an AllDebrid API client with the provided API key
+func (s *AllDebrid) getClient(token string) (*ad.Client, error) {
+	if token == "" {
+		return nil, errors.New("no access token for alldebrid backend")
+	}
+	return ad.New(s.cl, s.baseURL, token), nil
+}
+
+// fileAtIdx returns the file at the given torrent-natural-order index.
+// AllDebrid's file tree, walked depth-first, lists files in the order the
+// torrent does.
+func (s *AllDebrid) fileAtIdx(files []ad.File, fileIdx int) (*ad.File, bool) {
+	if fileIdx < 0 || fileIdx >= len(files) {
+		return nil, false
+	}
+	return &files[fileIdx], true
+}
+
+// ResolveLink generates a direct link using AllDebrid with caching
+// Returns the direct download URL and cached status
+func (s *AllDebrid) ResolveLink(ctx context.Context, token, hash string, fileIdx int) (string, bool, error) {
+	cacheKey := fmt.Sprintf("%s:%s:%d", token, hash, fileIdx)
+
+	result, err := s.linkCache.Get(cacheKey, func() (*resolveLinkResultAllDebrid, error) {
+		log.WithFields(log.Fields{
+			"hash":     hash,
+			"file_idx": fileIdx,
+		}).Debug("cache miss, performing actual link resolution")
+
+		client, err := s.getClient(token)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create alldebrid client")
+		}
+
+		url, cached, err := s.resolveLink(ctx, client, hash, fileIdx)
+		if err != nil {
+			return nil, err
+		}
+		return &resolveLinkResultAllDebrid{
+			url:    url,
+			cached: cached,
+		}, nil
+	})
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return result.url, result.cached, nil
+}
+
+// resolveLink performs the actual link resolution logic. A magnet the user
+// already has is used as is; otherwise it is uploaded, used if AllDebrid has
+// it cached and deleted again either way, so resolving never leaves a
+// download running on the user's account.
+func (s *AllDebrid) resolveLink(ctx context.Context, client *ad.Client, hash string, fileIdx int) (string, bool, error) {
+	magnets, err := client.ListMagnets(ctx)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to get user magnets")
+	}
+
+	var id int64
+	for i := range magnets {
+		if strings.EqualFold(magnets[i].Hash, hash) {
+			if !magnets[i].Ready() {
+				log.WithFields(log.Fields{
+					"hash":   hash,
+					"status": magnets[i].Status,
+				}).Debug("magnet in list but not ready")
+				return "", false, nil
+			}
+			id = magnets[i].ID
+			break
+		}
+	}
+
+	if id == 0 {
+		magnetURL := fmt.Sprintf("magnet:?xt=urn:btih:%s", hash)
+		up, err := client.UploadMagnet(ctx, magnetURL)
+		if err != nil {
+			return "", false, errors.Wrap(err, "failed to upload magnet")
+		}
+
+		defer func(mid int64) {
+			err := client.DeleteMagnet(ctx, mid)
+			if err != nil {
+				log.WithError(err).
+					WithField("magnet_id", mid).
+					Warn("failed to delete temporary magnet")
+			}
+		}(up.ID)
+
+		if !up.Ready {
+			log.WithField("hash", hash).Debug("torrent not cached")
+			return "", false, nil
+		}
+		id = up.ID
+	}
+
+	files, err := client.GetMagnetFiles(ctx, id)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to get magnet files")
+	}
+	file, found := s.fileAtIdx(files, fileIdx)
+	if !found || file.Link == "" {
+		return "", false, nil
+	}
+
+	unlocked, err := client.UnlockLink(ctx, file.Link)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to unlock link")
+	}
+	if unlocked.Link == "" {
+		log.WithField("magnet_id", id).Debug("no download link available for file")
+		return "", false, nil
+	}
+
+	log.WithFields(log.Fields{
+		"hash":     hash,
+		"file_idx": fileIdx,
+		"url":      unlocked.Link,
+		"cached":   true,
+	}).Info("generated alldebrid link")
+
+	return unlocked.Link, true, nil
+}
+
+// ForgetLink drops the cached link for the file
+func (s *AllDebrid) ForgetLink(token, hash string, fileIdx int) {
+	s.linkCache.Drop(fmt.Sprintf("%s:%s:%d", token, hash, fileIdx))
+}
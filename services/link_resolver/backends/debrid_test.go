@@ -0,0 +1,235 @@
+package backends
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/link_resolver/common"
+)
+
+const testHash = "08ada5a7a6183aae1e09d831df6748d566095a10"
+
+func TestAllDebridResolveLink(t *testing.T) {
+	deleted := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.1/magnet/status", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","data":{"magnets":[]}}`))
+	})
+	mux.HandleFunc("/v4/magnet/upload", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","data":{"magnets":[{"hash":"` + testHash + `","id":7,"ready":true}]}}`))
+	})
+	mux.HandleFunc("/v4/magnet/files", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","data":{"magnets":[{"id":"7","files":[{"n":"Sintel","e":[
+			{"n":"Sintel.en.srt","s":12,"l":"https://alldebrid.com/f/srt"},
+			{"n":"Sintel.mkv","s":300,"l":"https://alldebrid.com/f/mkv"}
+		]}]}]}}`))
+	})
+	mux.HandleFunc("/v4/link/unlock", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("link") != "https://alldebrid.com/f/mkv" {
+			t.Errorf("unlocked %q", r.URL.Query().Get("link"))
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"link":"https://dl.alldebrid.com/Sintel.mkv"}}`))
+	})
+	mux.HandleFunc("/v4/magnet/delete", func(w http.ResponseWriter, r *http.Request) {
+		deleted = r.URL.Query().Get("id") == "7"
+		_, _ = w.Write([]byte(`{"status":"success","data":{"message":"Magnet was successfully deleted"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	b := NewAllDebrid(srv.Client())
+	b.baseURL = srv.URL
+	url, cached, err := b.ResolveLink(context.Background(), "key", testHash, 1)
+	if err != nil || !cached || url != "https://dl.alldebrid.com/Sintel.mkv" {
+		t.Fatalf("resolved %q %v: %v", url, cached, err)
+	}
+	if !deleted {
+		t.Error("the uploaded magnet was not cleaned up")
+	}
+	if _, cached, _ := b.ResolveLink(context.Background(), "key", testHash, 5); cached {
+		t.Error("an index past the last file resolved")
+	}
+}
+
+func TestPremiumizeResolveLink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cache/check", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","response":[true]}`))
+	})
+	mux.HandleFunc("/transfer/directdl", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","content":[
+			{"path":"Sintel/Sintel.en.srt","size":12,"link":"https://cdn.premiumize.me/srt"},
+			{"path":"Sintel/Sintel.mkv","size":300,"link":"https://cdn.premiumize.me/mkv"}
+		]}`))
+	})
+	mux.HandleFunc("/account/info", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"error","message":"Not logged in."}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	b := NewPremiumize(srv.Client())
+	b.baseURL = srv.URL
+	url, cached, err := b.ResolveLink(context.Background(), "key", testHash, 1)
+	if err != nil || !cached || url != "https://cdn.premiumize.me/mkv" {
+		t.Fatalf("resolved %q %v: %v", url, cached, err)
+	}
+	err = b.Validate(context.Background(), "key")
+	if st := common.StatusFromError(err); st != models.StreamingBackendStatusInvalidCredentials {
+		t.Errorf("status %q for %v", st, err)
+	}
+}
+
+func TestStatusFromError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	b := NewAllDebrid(srv.Client())
+	b.baseURL = srv.URL
+	_, _, err := b.ResolveLink(context.Background(), "key", testHash, 0)
+	if st := common.StatusFromError(err); st != models.StreamingBackendStatusRateLimited {
+		t.Errorf("status %q for %v", st, err)
+	}
+	if st := common.StatusFromError(nil); st != models.StreamingBackendStatusOK {
+		t.Errorf("status %q for no error", st)
+	}
+	if _, _, err := NewAllDebrid(srv.Client()).ResolveLink(context.Background(), "", testHash, 0); common.StatusFromError(err) != models.StreamingBackendStatusError {
+		t.Errorf("an unclassified error: %v", err)
+	}
+}
+
+func TestCheckAvailability(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache/check":
+			_, _ = w.Write([]byte(`{"status":"success","response":[false,true]}`))
+		case "/v1/api/torrents/checkcached":
+			_, _ = w.Write([]byte(`{"success":true,"data":{"BBBB":{"hash":"BBBB","name":"Sintel"}}}`))
+		}
+	}))
+	defer srv.Close()
+
+	pm := NewPremiumize(srv.Client())
+	pm.baseURL = srv.URL
+	tb := NewTorbox(srv.Client())
+	tb.baseURL = srv.URL
+	for name, b := range map[string]common.AvailabilityChecker{"premiumize": pm, "torbox": tb} {
+		cached, err := b.CheckAvailability(context.Background(), "key", []string{"AAAA", "BBBB"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(cached) != 1 || !cached["bbbb"] {
+			t.Errorf("%s: cached %v, want only bbbb", name, cached)
+		}
+	}
+}
+
+func TestRealDebridTorrents(t *testing.T) {
+	var selected, deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/torrents":
+			if r.URL.Query().Get("filter") != "" {
+				t.Errorf("listed with filter %q", r.URL.Query().Get("filter"))
+			}
+			_, _ = w.Write([]byte(`[{"id":"OLD","filename":"Old","hash":"AAAA","bytes":10,"progress":100,"status":"downloaded"}]`))
+		case "/torrents/availableHosts":
+			_, _ = w.Write([]byte(`[{"id":"real-debrid.com"}]`))
+		case "/torrents/addMagnet":
+			_, _ = w.Write([]byte(`{"id":"NEW","uri":"https://api.real-debrid.com/rest/1.0/torrents/info/NEW"}`))
+		case "/torrents/info/NEW":
+			_, _ = w.Write([]byte(`{"id":"NEW","hash":"` + testHash + `","status":"waiting_files_selection","files":[
+				{"id":1,"path":"/Sintel.en.srt","bytes":12},
+				{"id":2,"path":"/Sintel.mkv","bytes":300}
+			]}`))
+		case "/torrents/selectFiles/NEW":
+			_ = r.ParseForm()
+			selected = r.PostForm.Get("files")
+			w.WriteHeader(http.StatusNoContent)
+		case "/torrents/delete/OLD":
+			deleted = "OLD"
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	b := NewRealDebrid(srv.Client())
+	b.baseURL = srv.URL
+	ctx := context.Background()
+	if id, err := b.AddTorrent(ctx, "key", "aaaa", nil); err != nil || id != "OLD" {
+		t.Errorf("a torrent already in the account was added again: %q %v", id, err)
+	}
+	id, err := b.AddTorrent(ctx, "key", testHash, []int{1})
+	if err != nil || id != "NEW" {
+		t.Fatalf("added %q: %v", id, err)
+	}
+	if selected != "2" {
+		t.Errorf("selected files %q, want 2", selected)
+	}
+	if _, err := b.AddTorrent(ctx, "key", testHash, []int{5}); err == nil {
+		t.Error("an index past the last file was accepted")
+	}
+	list, err := b.ListTorrents(ctx, "key")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("listed %v: %v", list, err)
+	}
+	if tr := list[0]; tr.Hash != "aaaa" || !tr.Ready || tr.Progress != 100 {
+		t.Errorf("listed %+v", tr)
+	}
+	if err := b.DeleteTorrent(ctx, "key", "OLD"); err != nil || deleted != "OLD" {
+		t.Errorf("deleted %q: %v", deleted, err)
+	}
+}
+
+func TestTorboxTorrents(t *testing.T) {
+	var created url.Values
+	var control map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/api/torrents/mylist":
+			_, _ = w.Write([]byte(`{"success":true,"data":[{"id":3,"hash":"AAAA","name":"Old","size":10,"progress":0.5,"status":"downloading"}]}`))
+		case "/v1/api/torrents/createtorrent":
+			_ = r.ParseMultipartForm(1 << 20)
+			created = r.Form
+			_, _ = w.Write([]byte(`{"success":true,"data":{"torrent_id":9,"hash":"` + testHash + `"}}`))
+		case "/v1/api/torrents/controltorrent":
+			_ = json.NewDecoder(r.Body).Decode(&control)
+			_, _ = w.Write([]byte(`{"success":true}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	b := NewTorbox(srv.Client())
+	b.baseURL = srv.URL
+	ctx := context.Background()
+	id, err := b.AddTorrent(ctx, "key", testHash, []int{1})
+	if err != nil || id != "9" {
+		t.Fatalf("added %q: %v", id, err)
+	}
+	if created == nil || created.Get("magnet") == "" || created.Get("add_only_if_cached") != "" {
+		t.Error("sending a torrent must not require it to be cached")
+	}
+	list, err := b.ListTorrents(ctx, "key")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("listed %v: %v", list, err)
+	}
+	if tr := list[0]; tr.ID != "3" || tr.Hash != "aaaa" || tr.Progress != 50 || tr.Ready {
+		t.Errorf("listed %+v", tr)
+	}
+	if err := b.DeleteTorrent(ctx, "key", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if control["operation"] != "delete" || control["torrent_id"] != float64(3) {
+		t.Errorf("control request %v", control)
+	}
+}
@@ -0,0 +1,163 @@
+package backends
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/link_resolver/common"
+	ng "github.com/webtor-io/web-ui/services/nzbget"
+)
+
+// NZBGet implements Backend for a self-hosted NZBGet. It fetches Usenet
+// releases only, so it never resolves a torrent link.
+//
+// The token is NZBGet's address with the control username and password in
+// it. Like SABnzbd, NZBGet only writes finished jobs to disk; links to them
+// point into the files URL from the backend config.
+type NZBGet struct {
+	cl *http.Client
+}
+
+var _ common.Backend = (*NZBGet)(nil)
+var _ common.UsenetManager = (*NZBGet)(nil)
+
+// NewNZBGet creates a new NZBGet backend. The client reaches an address the
+// user typed in, so it should refuse private networks wherever the
+// deployment is shared.
+func NewNZBGet(cl *http.Client) *NZBGet {
+	return &NZBGet{cl: cl}
+}
+
+func (s *NZBGet) getClient(token string) (*ng.Client, error) {
+	if token == "" {
+		return nil, errors.New("no api url for nzbget backend")
+	}
+	apiURL, user, pass, err := ng.ParseAPIURL(token)
+	if err != nil {
+		return nil, err
+	}
+	return ng.New(s.cl, apiURL, user, pass), nil
+}
+
+func (s *NZBGet) Validate(ctx context.Context, token string) error {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return err
+	}
+	_, err = cl.Version(ctx)
+	return err
+}
+
+// ContentKinds reports that NZBGet fetches Usenet releases only
+func (s *NZBGet) ContentKinds() []common.ContentKind {
+	return []common.ContentKind{common.ContentKindNZB}
+}
+
+// ResolveLink never finds a torrent: NZBGet does not fetch them
+func (s *NZBGet) ResolveLink(ctx context.Context, token, hash string, fileIdx int) (string, bool, error) {
+	return "", false, nil
+}
+
+// AddNZB uploads the NZB to NZBGet
+func (s *NZBGet) AddNZB(ctx context.Context, backend *models.StreamingBackend, name string, nzb []byte) (string, error) {
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	return cl.Append(ctx, name, nzb)
+}
+
+// GetNZB describes the job, looking in the queue first and the history
+// after. As with SABnzbd, a finished job has one file: its folder, with an
+// empty id.
+func (s *NZBGet) GetNZB(ctx context.Context, backend *models.StreamingBackend, id string) (*common.UsenetJob, error) {
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	g, err := cl.Group(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if g != nil {
+		return &common.UsenetJob{
+			ID:       id,
+			Name:     g.NZBName,
+			Size:     g.Size(),
+			Progress: g.Progress(),
+			Status:   g.Status,
+		}, nil
+	}
+	h, err := cl.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, errors.Errorf("nzbget has no job %s", id)
+	}
+	job := &common.UsenetJob{
+		ID:     id,
+		Name:   h.Name,
+		Size:   h.Size(),
+		Status: h.Status,
+	}
+	switch {
+	case h.Completed():
+		job.Progress = 100
+		job.Ready = true
+		link, err := s.folderLink(ctx, cl, backend, h.Storage())
+		if err != nil {
+			return nil, err
+		}
+		job.Files = []common.UsenetFile{{Name: h.Name, Size: job.Size, Link: link}}
+	case h.Failed():
+		job.Failed = true
+		job.Error = h.Status
+	}
+	return job, nil
+}
+
+// NZBLink maps the folder NZBGet saved the job to onto the files URL
+func (s *NZBGet) NZBLink(ctx context.Context, backend *models.StreamingBackend, id, fileID string) (string, error) {
+	base := backend.Config.GetString(models.StreamingBackendConfigFilesURL)
+	if base == "" {
+		return "", errors.New("nzbget backend has no files url")
+	}
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	h, err := cl.History(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if h == nil || !h.Completed() {
+		return "", errors.Errorf("nzbget job %s is not finished", id)
+	}
+	return s.folderLink(ctx, cl, backend, h.Storage())
+}
+
+// folderLink maps the storage of a finished job onto the files URL. It is
+// empty when the backend has no files URL.
+func (s *NZBGet) folderLink(ctx context.Context, cl *ng.Client, backend *models.StreamingBackend, storage string) (string, error) {
+	base := backend.Config.GetString(models.StreamingBackendConfigFilesURL)
+	if base == "" {
+		return "", nil
+	}
+	dir, err := cl.DestDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filesURL(base, filesPath(storage, dir)), nil
+}
+
+// DeleteNZB removes the job from NZBGet. Finished files stay on disk.
+func (s *NZBGet) DeleteNZB(ctx context.Context, backend *models.StreamingBackend, id string) error {
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return err
+	}
+	return cl.Delete(ctx, id)
+}
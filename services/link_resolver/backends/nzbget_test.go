@@ -0,0 +1,54 @@
+package backends
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+func TestNZBGetJob(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "listgroups":
+			_, _ = w.Write([]byte(`{"result":[]}`))
+		case "history":
+			_, _ = w.Write([]byte(`{"result":[{"NZBID":8,"Name":"Movie (2024)","Status":"SUCCESS/ALL","FileSizeLo":2048,"DestDir":"/data/dst/movies/Movie (2024)"},{"NZBID":9,"Name":"Broken","Status":"FAILURE/PAR"}]}`))
+		case "config":
+			_, _ = w.Write([]byte(`{"result":[{"Name":"DestDir","Value":"/data/dst"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	b := NewNZBGet(srv.Client())
+	backend := &models.StreamingBackend{
+		Type:        models.StreamingBackendTypeNZBGet,
+		AccessToken: "http://nzbget:secret@" + srv.Listener.Addr().String(),
+		Config:      models.StreamingBackendConfig{models.StreamingBackendConfigFilesURL: "https://files.example.com/nzb/"},
+	}
+	ctx := context.Background()
+	job, err := b.GetNZB(ctx, backend, "8")
+	if err != nil || !job.Ready || len(job.Files) != 1 || job.Files[0].ID != "" || job.Size != 2048 {
+		t.Fatalf("GetNZB() = %+v, %v", job, err)
+	}
+	link, err := b.NZBLink(ctx, backend, "8", job.Files[0].ID)
+	if err != nil || link != "https://files.example.com/nzb/movies/Movie%20%282024%29" || job.Files[0].Link != link {
+		t.Errorf("NZBLink() = %q, %v; job link %q", link, err, job.Files[0].Link)
+	}
+	if job, err := b.GetNZB(ctx, backend, "9"); err != nil || !job.Failed || job.Ready {
+		t.Errorf("GetNZB() of a failed job = %+v, %v", job, err)
+	}
+	if _, err := b.NZBLink(ctx, backend, "9", ""); err == nil {
+		t.Error("NZBLink() linked a failed job")
+	}
+	if _, cached, err := b.ResolveLink(ctx, backend.AccessToken, testHash, 0); cached || err != nil {
+		t.Errorf("NZBGet resolved a torrent: %v, %v", cached, err)
+	}
+}
@@ -0,0 +1,193 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/lazymap"
+	"github.com/webtor-io/web-ui/services/link_resolver/common"
+	pm "github.com/webtor-io/web-ui/services/premiumize"
+)
+
+// resolveLinkResultPremiumize holds the result of a ResolveLink call
+type resolveLinkResultPremiumize struct {
+	url    string
+	cached bool
+}
+
+// Premiumize implements Backend interface for Premiumize
+type Premiumize struct {
+	linkCache lazymap.LazyMap[*resolveLinkResultPremiumize]
+	cl        *http.Client
+	baseURL   string
+}
+
+// Compile-time check to ensure Premiumize implements Backend interface
+var _ common.Backend = (*Premiumize)(nil)
+var _ common.AvailabilityChecker = (*Premiumize)(nil)
+var _ common.AccountChecker = (*Premiumize)(nil)
+var _ common.LinkForgetter = (*Premiumize)(nil)
+
+// premiumizeCacheCheckBatch bounds the hashes sent in one cache check
+// request; they travel in the query string.
+const premiumizeCacheCheckBatch = 100
+
+// NewPremiumize creates a new Premiumize backend
+func NewPremiumize(cl *http.Client) *Premiumize {
+	return &Premiumize{
+		linkCache: *lazymap.New[*resolveLinkResultPremiumize](&lazymap.Config{
+			Expire:      15 * time.Minute,
+			ErrorExpire: 30 * time.Second,
+			Concurrency: 5,
+		}),
+		cl:      cl,
+		baseURL: "https://www.premiumize.me/api",
+	}
+}
+
+func (s *Premiumize) Validate(ctx context.Context, token string) error {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return err
+	}
+	_, err = cl.GetAccountInfo(ctx)
+	return err
+}
+
+// ContentKinds reports that Premiumize fetches torrents only
+func (s *Premiumize) ContentKinds() []common.ContentKind {
+	return []common.ContentKind{common.ContentKindTorrent}
+}
+
+// getClient creates a Premiumize API client with the provided API key
+func (s *Premiumize) getClient(token string) (*pm.Client, error) {
+	if token == "" {
+		return nil, errors.New("no access token for premiumize backend")
+	}
+	return pm.New(s.cl, s.baseURL, token), nil
+}
+
+// Account describes the Premiumize account behind the token
+func (s *Premiumize) Account(ctx context.Context, token string) (*common.Account, error) {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	info, err := cl.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a := &common.Account{Usage: &info.LimitUsed}
+	if info.PremiumUntil > 0 {
+		t := time.Unix(int64(info.PremiumUntil), 0)
+		a.PremiumUntil = &t
+	}
+	return a, nil
+}
+
+// CheckAvailability reports which of the hashes Premiumize has cached
+func (s *Premiumize) CheckAvailability(ctx context.Context, token string, hashes []string) (map[string]bool, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]bool{}
+	for start := 0; start < len(hashes); start += premiumizeCacheCheckBatch {
+		end := min(start+premiumizeCacheCheckBatch, len(hashes))
+		cached, err := client.CheckCache(ctx, hashes[start:end])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check cached status")
+		}
+		for i, c := range cached {
+			if c {
+				res[strings.ToLower(hashes[start+i])] = true
+			}
+		}
+	}
+	return res, nil
+}
+
+// fileAtIdx returns the file at the given torrent-natural-order index.
+// Premiumize lists a cached torrent's content in the order the torrent does.
+func (s *Premiumize) fileAtIdx(files []pm.File, fileIdx int) (*pm.File, bool) {
+	if fileIdx < 0 || fileIdx >= len(files) {
+		return nil, false
+	}
+	return &files[fileIdx], true
+}
+
+// ResolveLink generates a direct link using Premiumize with caching
+// Returns the direct download URL and cached status
+func (s *Premiumize) ResolveLink(ctx context.Context, token, hash string, fileIdx int) (string, bool, error) {
+	cacheKey := fmt.Sprintf("%s:%s:%d", token, hash, fileIdx)
+
+	result, err := s.linkCache.Get(cacheKey, func() (*resolveLinkResultPremiumize, error) {
+		log.WithFields(log.Fields{
+			"hash":     hash,
+			"file_idx": fileIdx,
+		}).Debug("cache miss, performing actual link resolution")
+
+		client, err := s.getClient(token)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create premiumize client")
+		}
+
+		url, cached, err := s.resolveLink(ctx, client, hash, fileIdx)
+		if err != nil {
+			return nil, err
+		}
+		return &resolveLinkResultPremiumize{
+			url:    url,
+			cached: cached,
+		}, nil
+	})
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return result.url, result.cached, nil
+}
+
+// resolveLink performs the actual link resolution logic. Premiumize serves
+// cached content straight from the magnet, so there is no transfer to create
+// or clean up, and its links are direct already.
+func (s *Premiumize) resolveLink(ctx context.Context, client *pm.Client, hash string, fileIdx int) (string, bool, error) {
+	cached, err := client.CheckCache(ctx, []string{hash})
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to check cached status")
+	}
+	if !cached[0] {
+		log.WithField("hash", hash).Debug("torrent not cached")
+		return "", false, nil
+	}
+
+	magnetURL := fmt.Sprintf("magnet:?xt=urn:btih:%s", hash)
+	files, err := client.DirectDL(ctx, magnetURL)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to get direct download links")
+	}
+	file, found := s.fileAtIdx(files, fileIdx)
+	if !found || file.Link == "" {
+		return "", false, nil
+	}
+
+	log.WithFields(log.Fields{
+		"hash":     hash,
+		"file_idx": fileIdx,
+		"url":      file.Link,
+		"cached":   true,
+	}).Info("generated premiumize link")
+
+	return file.Link, true, nil
+}
+
+// ForgetLink drops the cached link for the file
+func (s *Premiumize) ForgetLink(token, hash string, fileIdx int) {
+	s.linkCache.Drop(fmt.Sprintf("%s:%s:%d", token, hash, fileIdx))
+}
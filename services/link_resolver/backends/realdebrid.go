@@ -14,6 +14,17 @@ import (
 	rd "github.com/webtor-io/web-ui/services/realdebrid"
 )
 
+// parseAccountTime reads the RFC 3339 timestamps Real-Debrid and Torbox
+// report a plan's end in. Anything else — an empty string for a free
+// account — is no expiry.
+func parseAccountTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
 // resolveLinkResult holds the result of a ResolveLink call
 type resolveLinkResult struct {
 	url    string
@@ -24,10 +35,14 @@ type resolveLinkResult struct {
 type RealDebrid struct {
 	linkCache *lazymap.LazyMap[*resolveLinkResult]
 	cl        *http.Client
+	baseURL   string
 }
 
 // Compile-time check to ensure RealDebrid implements Backend interface
 var _ common.Backend = (*RealDebrid)(nil)
+var _ common.AccountChecker = (*RealDebrid)(nil)
+var _ common.LinkForgetter = (*RealDebrid)(nil)
+var _ common.TorrentManager = (*RealDebrid)(nil)
 
 // NewRealDebrid creates a new RealDebrid backend
 func NewRealDebrid(cl *http.Client) *RealDebrid {
@@ -37,7 +52,8 @@ func NewRealDebrid(cl *http.Client) *RealDebrid {
 			ErrorExpire: 30 * time.Second,
 			Concurrency: 5,
 		}),
-		cl: cl,
+		cl:      cl,
+		baseURL: "https://api.real-debrid.com/rest/1.0",
 	}
 }
 
@@ -53,6 +69,29 @@ func (s *RealDebrid) Validate(ctx context.Context, token string) error {
 	return nil
 }
 
+// ContentKinds reports that Real-Debrid fetches torrents only
+func (s *RealDebrid) ContentKinds() []common.ContentKind {
+	return []common.ContentKind{common.ContentKindTorrent}
+}
+
+// Account describes the Real-Debrid account behind the token. A free account
+// has no expiration.
+func (s *RealDebrid) Account(ctx context.Context, token string) (*common.Account, error) {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	u, err := cl.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a := &common.Account{Points: &u.Points}
+	if u.Type == "premium" {
+		a.PremiumUntil = parseAccountTime(u.Expiration)
+	}
+	return a, nil
+}
+
 // getClient creates a RealDebrid API client with the provided access token
 func (s *RealDebrid) getClient(token string) (*rd.Client, error) {
 	if token == "" {
@@ -60,7 +99,7 @@ func (s *RealDebrid) getClient(token string) (*rd.Client, error) {
 	}
 
 	// Create RealDebrid client
-	return rd.New(s.cl, "https://api.real-debrid.com/rest/1.0", token), nil
+	return rd.New(s.cl, s.baseURL, token), nil
 }
 
 // getAvailableHost fetches available hosts for torrents and returns the first one
@@ -385,3 +424,101 @@ func (s *RealDebrid) resolveLink(ctx context.Context, client *rd.Client, hash st
 
 	return download.Download, true, nil
 }
+
+// AddTorrent adds the torrent to the Real-Debrid account with the given
+// files selected. A torrent the account already has is left as it is.
+// Selection needs the file list, which Real-Debrid only has once it has
+// fetched the metadata; a torrent that is still converting stays waiting
+// for a selection, which the user makes on Real-Debrid.
+func (s *RealDebrid) AddTorrent(ctx context.Context, token, hash string, fileIdx []int) (string, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return "", err
+	}
+	torrents, err := client.GetAllTorrents(ctx, false)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user torrents")
+	}
+	for i := range torrents {
+		if strings.EqualFold(torrents[i].Hash, hash) {
+			return torrents[i].ID, nil
+		}
+	}
+	host, err := s.getAvailableHost(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	addResp, err := client.AddMagnet(ctx, fmt.Sprintf("magnet:?xt=urn:btih:%s", hash), host)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to add magnet")
+	}
+	torrent, err := client.GetTorrentInfo(ctx, addResp.ID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get torrent info")
+	}
+	if len(torrent.Files) == 0 {
+		log.WithFields(log.Fields{
+			"hash":   hash,
+			"status": torrent.Status,
+		}).Debug("torrent has no file list yet, leaving selection to the user")
+		return addResp.ID, nil
+	}
+	var selection []int
+	if len(fileIdx) == 0 {
+		for _, f := range torrent.Files {
+			selection = append(selection, f.ID)
+		}
+	}
+	for _, idx := range fileIdx {
+		if f, ok := s.fileAtIdx(torrent.Files, idx, false); ok {
+			selection = append(selection, f.ID)
+		}
+	}
+	if len(selection) == 0 {
+		return "", errors.Errorf("no files of torrent %s at indexes %v", hash, fileIdx)
+	}
+	err = client.SelectTorrentFiles(ctx, addResp.ID, selection)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to select files")
+	}
+	return addResp.ID, nil
+}
+
+// ListTorrents returns the torrents in the Real-Debrid account
+func (s *RealDebrid) ListTorrents(ctx context.Context, token string) ([]common.Torrent, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	torrents, err := client.GetAllTorrents(ctx, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user torrents")
+	}
+	res := make([]common.Torrent, 0, len(torrents))
+	for _, t := range torrents {
+		res = append(res, common.Torrent{
+			ID:       t.ID,
+			Hash:     strings.ToLower(t.Hash),
+			Name:     t.Filename,
+			Size:     t.Bytes,
+			Progress: t.Progress,
+			Status:   t.Status,
+			Ready:    t.Status == "downloaded",
+		})
+	}
+	return res, nil
+}
+
+// DeleteTorrent removes the torrent from the Real-Debrid account
+func (s *RealDebrid) DeleteTorrent(ctx context.Context, token, id string) error {
+	client, err := s.getClient(token)
+	if err != nil {
+		return err
+	}
+	return client.DeleteTorrent(ctx, id)
+}
+
+// ForgetLink drops the cached link for the file
+func (s *RealDebrid) ForgetLink(token, hash string, fileIdx int) {
+	s.linkCache.Drop(fmt.Sprintf("%s:%s:%d", token, hash, fileIdx))
+}
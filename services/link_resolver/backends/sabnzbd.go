@@ -0,0 +1,191 @@
+package backends
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/link_resolver/common"
+	sab "github.com/webtor-io/web-ui/services/sabnzbd"
+)
+
+// SABnzbd implements Backend for a self-hosted SABnzbd. It fetches Usenet
+// releases only, so it never resolves a torrent link.
+//
+// The token is SABnzbd's address with the API key in it, the way the
+// SABnzbd config page shows it. SABnzbd only writes finished jobs to disk;
+// links to them point into the files URL from the backend config, which the
+// user serves with a web server of their own.
+type SABnzbd struct {
+	cl *http.Client
+}
+
+var _ common.Backend = (*SABnzbd)(nil)
+var _ common.UsenetManager = (*SABnzbd)(nil)
+
+// NewSABnzbd creates a new SABnzbd backend. The client reaches an address
+// the user typed in, so it should refuse private networks wherever the
+// deployment is shared.
+func NewSABnzbd(cl *http.Client) *SABnzbd {
+	return &SABnzbd{cl: cl}
+}
+
+func (s *SABnzbd) getClient(token string) (*sab.Client, error) {
+	if token == "" {
+		return nil, errors.New("no api url for sabnzbd backend")
+	}
+	apiURL, key, err := sab.ParseAPIURL(token)
+	if err != nil {
+		return nil, err
+	}
+	return sab.New(s.cl, apiURL, key), nil
+}
+
+func (s *SABnzbd) Validate(ctx context.Context, token string) error {
+	cl, err := s.getClient(token)
+	if err != nil {
+		return err
+	}
+	_, err = cl.Queue(ctx, "")
+	return err
+}
+
+// ContentKinds reports that SABnzbd fetches Usenet releases only
+func (s *SABnzbd) ContentKinds() []common.ContentKind {
+	return []common.ContentKind{common.ContentKindNZB}
+}
+
+// ResolveLink never finds a torrent: SABnzbd does not fetch them
+func (s *SABnzbd) ResolveLink(ctx context.Context, token, hash string, fileIdx int) (string, bool, error) {
+	return "", false, nil
+}
+
+// AddNZB uploads the NZB to SABnzbd
+func (s *SABnzbd) AddNZB(ctx context.Context, backend *models.StreamingBackend, name string, nzb []byte) (string, error) {
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	return cl.AddFile(ctx, name, nzb)
+}
+
+// GetNZB describes the job, looking in the queue first and the history
+// after. SABnzbd reports where a finished job was saved but not what is in
+// it, so a finished job has one file: its folder, with an empty id.
+func (s *SABnzbd) GetNZB(ctx context.Context, backend *models.StreamingBackend, id string) (*common.UsenetJob, error) {
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	q, err := cl.Queue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q != nil {
+		return &common.UsenetJob{
+			ID:       id,
+			Name:     q.Filename,
+			Size:     q.Size(),
+			Progress: float64(q.Percentage),
+			Status:   q.Status,
+		}, nil
+	}
+	h, err := cl.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, errors.Errorf("sabnzbd has no job %s", id)
+	}
+	job := &common.UsenetJob{
+		ID:     id,
+		Name:   h.Name,
+		Size:   int64(h.Bytes),
+		Status: h.Status,
+	}
+	switch {
+	case h.Completed():
+		job.Progress = 100
+		job.Ready = true
+		link, err := s.folderLink(ctx, cl, backend, h.Storage)
+		if err != nil {
+			return nil, err
+		}
+		job.Files = []common.UsenetFile{{Name: h.Name, Size: job.Size, Link: link}}
+	case h.Failed():
+		job.Failed = true
+		job.Error = h.FailMessage
+	}
+	return job, nil
+}
+
+// NZBLink maps the folder SABnzbd saved the job to onto the files URL
+func (s *SABnzbd) NZBLink(ctx context.Context, backend *models.StreamingBackend, id, fileID string) (string, error) {
+	base := backend.Config.GetString(models.StreamingBackendConfigFilesURL)
+	if base == "" {
+		return "", errors.New("sabnzbd backend has no files url")
+	}
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	h, err := cl.History(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if h == nil || !h.Completed() {
+		return "", errors.Errorf("sabnzbd job %s is not finished", id)
+	}
+	return s.folderLink(ctx, cl, backend, h.Storage)
+}
+
+// folderLink maps the storage of a finished job onto the files URL. It is
+// empty when the backend has no files URL.
+func (s *SABnzbd) folderLink(ctx context.Context, cl *sab.Client, backend *models.StreamingBackend, storage string) (string, error) {
+	base := backend.Config.GetString(models.StreamingBackendConfigFilesURL)
+	if base == "" {
+		return "", nil
+	}
+	dir, err := cl.CompleteDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filesURL(base, filesPath(storage, dir)), nil
+}
+
+// DeleteNZB removes the job from SABnzbd. Finished files stay on disk.
+func (s *SABnzbd) DeleteNZB(ctx context.Context, backend *models.StreamingBackend, id string) error {
+	cl, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return err
+	}
+	return cl.Delete(ctx, id)
+}
+
+// filesPath is where the job's storage lies under the completed folder,
+// which is what the files URL serves. The completed folder may be set
+// relative to SABnzbd's home while storage is always absolute, and either
+// may be a Windows path. A storage outside it keeps its last element only.
+func filesPath(storage, completeDir string) string {
+	storage = path.Clean(strings.ReplaceAll(storage, `\`, "/"))
+	completeDir = strings.Trim(path.Clean(strings.ReplaceAll(completeDir, `\`, "/")), "/")
+	if completeDir != "" && completeDir != "." {
+		if i := strings.Index(storage, completeDir+"/"); i >= 0 && (i == 0 || storage[i-1] == '/') {
+			return storage[i+len(completeDir)+1:]
+		}
+	}
+	return path.Base(storage)
+}
+
+// filesURL joins the relative path to the files URL, escaping each element
+func filesURL(base, rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,60 @@
+package backends
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+func TestSABnzbdFilesPath(t *testing.T) {
+	for _, tt := range []struct{ storage, dir, want string }{
+		{"/data/complete/movies/Movie", "/data/complete", "movies/Movie"},
+		{"/home/sab/Downloads/complete/Movie", "Downloads/complete", "Movie"},
+		{`C:\Users\sab\Downloads\complete\Movie (2024)`, `Downloads\complete`, "Movie (2024)"},
+		{"/mnt/elsewhere/Movie", "/data/complete", "Movie"},
+		{"/data/completed/Movie", "/data/complete", "Movie"},
+	} {
+		if got := filesPath(tt.storage, tt.dir); got != tt.want {
+			t.Errorf("filesPath(%q, %q) = %q, want %q", tt.storage, tt.dir, got, tt.want)
+		}
+	}
+	if got := filesURL("https://files.example.com/sab/", "movies/Movie (2024)"); got != "https://files.example.com/sab/movies/Movie%20%282024%29" {
+		t.Errorf("filesURL() = %q", got)
+	}
+}
+
+func TestSABnzbdJob(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("mode") {
+		case "queue":
+			_, _ = w.Write([]byte(`{"queue":{"slots":[]}}`))
+		case "history":
+			_, _ = w.Write([]byte(`{"history":{"slots":[{"nzo_id":"SABnzbd_nzo_2","name":"Movie","status":"Completed","bytes":"2048","storage":"/data/complete/Movie"}]}}`))
+		case "get_config":
+			_, _ = w.Write([]byte(`{"config":{"misc":{"complete_dir":"/data/complete"}}}`))
+		}
+	}))
+	defer srv.Close()
+
+	b := NewSABnzbd(srv.Client())
+	backend := &models.StreamingBackend{
+		Type:        models.StreamingBackendTypeSABnzbd,
+		AccessToken: srv.URL + "/sabnzbd/?apikey=key",
+		Config:      models.StreamingBackendConfig{models.StreamingBackendConfigFilesURL: "https://files.example.com"},
+	}
+	ctx := context.Background()
+	job, err := b.GetNZB(ctx, backend, "SABnzbd_nzo_2")
+	if err != nil || !job.Ready || len(job.Files) != 1 || job.Size != 2048 {
+		t.Fatalf("GetNZB() = %+v, %v", job, err)
+	}
+	link, err := b.NZBLink(ctx, backend, "SABnzbd_nzo_2", job.Files[0].ID)
+	if err != nil || link != "https://files.example.com/Movie" || job.Files[0].Link != link {
+		t.Errorf("NZBLink() = %q, %v; job link %q", link, err, job.Files[0].Link)
+	}
+	if _, cached, err := b.ResolveLink(ctx, backend.AccessToken, testHash, 0); cached || err != nil {
+		t.Errorf("SABnzbd resolved a torrent: %v, %v", cached, err)
+	}
+}
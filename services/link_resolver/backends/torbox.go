@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/webtor-io/lazymap"
+	"github.com/webtor-io/web-ui/models"
 	"github.com/webtor-io/web-ui/services/link_resolver/common"
 	tb "github.com/webtor-io/web-ui/services/torbox"
 )
@@ -24,6 +26,7 @@ type resolveLinkResultTorbox struct {
 type Torbox struct {
 	linkCache lazymap.LazyMap[*resolveLinkResultTorbox]
 	cl        *http.Client
+	baseURL   string
 }
 
 func (s *Torbox) Validate(ctx context.Context, token string) error {
@@ -38,8 +41,21 @@ func (s *Torbox) Validate(ctx context.Context, token string) error {
 	return nil
 }
 
+// ContentKinds reports that Torbox fetches both torrents and Usenet releases
+func (s *Torbox) ContentKinds() []common.ContentKind {
+	return []common.ContentKind{common.ContentKindTorrent, common.ContentKindNZB}
+}
+
 // Compile-time check to ensure Torbox implements Backend interface
 var _ common.Backend = (*Torbox)(nil)
+var _ common.AvailabilityChecker = (*Torbox)(nil)
+var _ common.AccountChecker = (*Torbox)(nil)
+var _ common.LinkForgetter = (*Torbox)(nil)
+var _ common.TorrentManager = (*Torbox)(nil)
+var _ common.UsenetManager = (*Torbox)(nil)
+
+// torboxCheckCachedBatch bounds the hashes sent in one checkcached request
+const torboxCheckCachedBatch = 100
 
 // NewTorbox creates a new Torbox backend
 func NewTorbox(cl *http.Client) *Torbox {
@@ -49,7 +65,8 @@ func NewTorbox(cl *http.Client) *Torbox {
 			ErrorExpire: 30 * time.Second,
 			Concurrency: 5,
 		}),
-		cl: cl,
+		cl:      cl,
+		baseURL: "https://api.torbox.app",
 	}
 }
 
@@ -60,7 +77,43 @@ func (s *Torbox) getClient(token string) (*tb.Client, error) {
 	}
 
 	// Create Torbox client
-	return tb.NewClient(s.cl, "https://api.torbox.app", token), nil
+	return tb.NewClient(s.cl, s.baseURL, token), nil
+}
+
+// Account describes the Torbox account behind the token
+func (s *Torbox) Account(ctx context.Context, token string) (*common.Account, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	u, err := client.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &common.Account{
+		PremiumUntil: parseAccountTime(u.PremiumExpiresAt),
+		Downloaded:   &u.TotalDownloaded,
+	}, nil
+}
+
+// CheckAvailability reports which of the hashes Torbox has cached
+func (s *Torbox) CheckAvailability(ctx context.Context, token string, hashes []string) (map[string]bool, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]bool{}
+	for start := 0; start < len(hashes); start += torboxCheckCachedBatch {
+		end := min(start+torboxCheckCachedBatch, len(hashes))
+		cached, err := client.CheckCached(ctx, hashes[start:end], "", false)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check cached status")
+		}
+		for _, c := range cached {
+			res[strings.ToLower(c.Hash)] = true
+		}
+	}
+	return res, nil
 }
 
 // fileAtIdx returns the file at the given torrent-natural-order index.
@@ -180,7 +233,7 @@ func (s *Torbox) resolveLink(ctx context.Context, client *tb.Client, hash string
 
 		// Create torrent from magnet
 		magnetURL := fmt.Sprintf("magnet:?xt=urn:btih:%s", hash)
-		createResp, err := client.CreateTorrent(ctx, magnetURL)
+		createResp, err := client.CreateTorrent(ctx, magnetURL, true)
 		if err != nil {
 			return "", false, errors.Wrap(err, "failed to create torrent")
 		}
@@ -254,3 +307,158 @@ func (s *Torbox) resolveLink(ctx context.Context, client *tb.Client, hash string
 
 	return downloadURL, true, nil
 }
+
+// AddTorrent adds the torrent to the TorBox account. Unlike stream
+// resolution it also takes torrents TorBox has not cached, which it then
+// downloads. TorBox always downloads whole torrents, so fileIdx is not
+// used. A torrent the account already has is left as it is.
+func (s *Torbox) AddTorrent(ctx context.Context, token, hash string, fileIdx []int) (string, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return "", err
+	}
+	torrents, err := client.ListTorrents(ctx, 0)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user torrents")
+	}
+	for i := range torrents {
+		if strings.EqualFold(torrents[i].Hash, hash) {
+			return strconv.Itoa(torrents[i].ID), nil
+		}
+	}
+	created, err := client.CreateTorrent(ctx, fmt.Sprintf("magnet:?xt=urn:btih:%s", hash), false)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create torrent")
+	}
+	return strconv.Itoa(created.TorrentID), nil
+}
+
+// ListTorrents returns the torrents in the TorBox account
+func (s *Torbox) ListTorrents(ctx context.Context, token string) ([]common.Torrent, error) {
+	client, err := s.getClient(token)
+	if err != nil {
+		return nil, err
+	}
+	torrents, err := client.ListTorrents(ctx, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user torrents")
+	}
+	res := make([]common.Torrent, 0, len(torrents))
+	for _, t := range torrents {
+		res = append(res, common.Torrent{
+			ID:   strconv.Itoa(t.ID),
+			Hash: strings.ToLower(t.Hash),
+			Name: t.Name,
+			Size: t.Size,
+			// TorBox reports progress as a fraction
+			Progress: t.Progress * 100,
+			Status:   t.Status,
+			Ready:    t.DownloadFinished && t.DownloadPresent,
+		})
+	}
+	return res, nil
+}
+
+// DeleteTorrent removes the torrent from the TorBox account
+func (s *Torbox) DeleteTorrent(ctx context.Context, token, id string) error {
+	tid, err := strconv.Atoi(id)
+	if err != nil {
+		return errors.Wrapf(err, "wrong torbox torrent id %q", id)
+	}
+	client, err := s.getClient(token)
+	if err != nil {
+		return err
+	}
+	return client.ControlTorrent(ctx, tid, "delete")
+}
+
+// ForgetLink drops the cached link for the file
+func (s *Torbox) ForgetLink(token, hash string, fileIdx int) {
+	s.linkCache.Drop(fmt.Sprintf("%s:%s:%d", token, hash, fileIdx))
+}
+
+// AddNZB uploads the NZB to the TorBox account
+func (s *Torbox) AddNZB(ctx context.Context, backend *models.StreamingBackend, name string, nzb []byte) (string, error) {
+	client, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	created, err := client.CreateUsenetDownload(ctx, name, nzb)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(created.UsenetDownloadID), nil
+}
+
+// GetNZB describes the Usenet download in the TorBox account
+func (s *Torbox) GetNZB(ctx context.Context, backend *models.StreamingBackend, id string) (*common.UsenetJob, error) {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "wrong torbox usenet id %q", id)
+	}
+	client, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	d, err := client.GetUsenetDownload(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	job := &common.UsenetJob{
+		ID:   id,
+		Name: d.Name,
+		Size: d.Size,
+		// TorBox reports progress as a fraction
+		Progress: d.Progress * 100,
+		Status:   d.DownloadState,
+		Ready:    d.DownloadFinished && d.DownloadPresent,
+	}
+	if strings.Contains(strings.ToLower(d.DownloadState), "fail") {
+		job.Failed = true
+		job.Error = d.DownloadState
+	}
+	if job.Ready {
+		for _, f := range d.Files {
+			name := f.ShortName
+			if name == "" {
+				name = f.Name
+			}
+			job.Files = append(job.Files, common.UsenetFile{
+				ID:   strconv.Itoa(f.ID),
+				Name: name,
+				Size: f.Size,
+			})
+		}
+	}
+	return job, nil
+}
+
+// NZBLink requests a download link for a file of the Usenet download
+func (s *Torbox) NZBLink(ctx context.Context, backend *models.StreamingBackend, id, fileID string) (string, error) {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return "", errors.Wrapf(err, "wrong torbox usenet id %q", id)
+	}
+	fid, err := strconv.Atoi(fileID)
+	if err != nil {
+		return "", errors.Wrapf(err, "wrong torbox usenet file id %q", fileID)
+	}
+	client, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	return client.RequestUsenetDownloadLink(ctx, uid, fid)
+}
+
+// DeleteNZB removes the Usenet download from the TorBox account
+func (s *Torbox) DeleteNZB(ctx context.Context, backend *models.StreamingBackend, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return errors.Wrapf(err, "wrong torbox usenet id %q", id)
+	}
+	client, err := s.getClient(backend.AccessToken)
+	if err != nil {
+		return err
+	}
+	return client.ControlUsenetDownload(ctx, uid, "delete")
+}
@@ -2,6 +2,18 @@ package common
 
 import (
 	"context"
+	"time"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// ContentKind is what a backend can fetch: torrents by infohash, or Usenet
+// releases from an NZB.
+type ContentKind string
+
+const (
+	ContentKindTorrent ContentKind = "torrent"
+	ContentKindNZB     ContentKind = "nzb"
 )
 
 // Backend defines the interface for streaming backends.
@@ -9,10 +21,143 @@ import (
 // fileIdx is the file's index in the torrent's natural file order (the
 // same convention Stremio addons use). Backends look up the file in
 // their own torrent metadata at the matching index — RealDebrid and
-// Torbox return Files[] in bencode-decode order, which matches.
+// Torbox return Files[] in bencode-decode order, which matches; AllDebrid's
+// file tree and Premiumize's content list follow the same order.
+//
+// Errors that mean the token was rejected or the account throttled should
+// say so through InvalidCredentials() / RateLimited() methods; see
+// StatusFromError.
+//
+// A backend that does not fetch torrents (see ContentKinds) answers
+// ResolveLink with "not cached"; the resolver does not ask it anyway.
 type Backend interface {
 	// ResolveLink generates a direct link for the content
 	ResolveLink(ctx context.Context, token, hash string, fileIdx int) (string, bool, error)
 	// Validate validates backend
 	Validate(ctx context.Context, token string) error
+	// ContentKinds lists what the backend can fetch
+	ContentKinds() []ContentKind
+}
+
+// Serves reports whether the backend fetches content of the kind
+func Serves(b Backend, kind ContentKind) bool {
+	for _, k := range b.ContentKinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailabilityChecker is implemented by backends whose service can report
+// instant availability for many torrents in one call. Backends without such
+// an endpoint learn it one resolved link at a time.
+type AvailabilityChecker interface {
+	// CheckAvailability returns the set of hashes, lowercased, the service
+	// has cached
+	CheckAvailability(ctx context.Context, token string, hashes []string) (map[string]bool, error)
+}
+
+// Account is what a debrid service tells about the account behind a token.
+// Every field is optional: services report different subsets, and nil means
+// "not reported", not zero.
+type Account struct {
+	// PremiumUntil is when the paid plan runs out
+	PremiumUntil *time.Time
+	// Points is the service's loyalty balance (Real-Debrid points,
+	// AllDebrid fidelity points)
+	Points *int
+	// Usage is the share of the fair-use allowance spent, 0 to 1
+	Usage *float64
+	// Downloaded is the traffic the account has used, in bytes
+	Downloaded *int64
+}
+
+// AccountChecker is implemented by backends that can describe the account
+// behind a token — what the periodic account check stores and the profile
+// shows.
+type AccountChecker interface {
+	Account(ctx context.Context, token string) (*Account, error)
+}
+
+// Torrent is a torrent in the user's debrid account
+type Torrent struct {
+	// ID is the service's own id for the torrent
+	ID string
+	// Hash is the infohash, lowercased
+	Hash string
+	Name string
+	Size int64
+	// Progress is the download progress in percent, 0 to 100
+	Progress float64
+	// Status is the service's own status word, e.g. "downloading"
+	Status string
+	// Ready is set once the files can be streamed
+	Ready bool
+}
+
+// TorrentManager is implemented by backends whose account holds a torrent
+// list the user can manage from Webtor: send a torrent there, see what is
+// in it and remove it.
+type TorrentManager interface {
+	// AddTorrent adds the torrent to the account and returns the service's
+	// id for it. fileIdx selects files by their index in the torrent's
+	// natural order; empty means every file. A service that always takes
+	// whole torrents ignores it.
+	AddTorrent(ctx context.Context, token, hash string, fileIdx []int) (string, error)
+	ListTorrents(ctx context.Context, token string) ([]Torrent, error)
+	DeleteTorrent(ctx context.Context, token, id string) error
+}
+
+// LinkForgetter is implemented by backends that cache resolved links. A
+// link the failover probe found dead is forgotten, so the next resolve asks
+// the service for a fresh one instead of handing out the dead link again.
+type LinkForgetter interface {
+	ForgetLink(token, hash string, fileIdx int)
+}
+
+// UsenetFile is a file of a finished Usenet download
+type UsenetFile struct {
+	// ID is the service's own id for the file, passed back to NZBLink
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Link is set by self-hosted backends, which have no file ids: it is
+	// the folder the job was saved to under the backend's files URL
+	Link string `json:"link,omitempty"`
+}
+
+// UsenetJob is a Usenet download in the user's account
+type UsenetJob struct {
+	ID   string
+	Name string
+	Size int64
+	// Progress is the download progress in percent, 0 to 100
+	Progress float64
+	// Status is the service's own status word, e.g. "Downloading"
+	Status string
+	// Ready is set once the files can be streamed
+	Ready bool
+	// Failed is set when the service gave up on the download, with its
+	// reason in Error
+	Failed bool
+	Error  string
+	// Files are known once the job is ready
+	Files []UsenetFile
+}
+
+// UsenetManager is implemented by backends that download Usenet releases:
+// hand them an NZB, follow the job, and link to its files once it is done.
+//
+// Unlike the torrent interfaces it takes the whole backend row rather than
+// the token: a self-hosted downloader also needs to know where its finished
+// files are served from, which is configuration, not a credential.
+type UsenetManager interface {
+	// AddNZB starts downloading the release described by the NZB and
+	// returns the service's id for the job
+	AddNZB(ctx context.Context, backend *models.StreamingBackend, name string, nzb []byte) (string, error)
+	GetNZB(ctx context.Context, backend *models.StreamingBackend, id string) (*UsenetJob, error)
+	// NZBLink returns a direct link to a file of a finished job
+	NZBLink(ctx context.Context, backend *models.StreamingBackend, id, fileID string) (string, error)
+	DeleteNZB(ctx context.Context, backend *models.StreamingBackend, id string) error
 }
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// credentialsError is implemented by client errors that can tell a rejected
+// token from any other failure.
+type credentialsError interface {
+	InvalidCredentials() bool
+}
+
+// rateLimitError is implemented by client errors that can tell a throttled
+// request from any other failure.
+type rateLimitError interface {
+	RateLimited() bool
+}
+
+// StatusFromError maps the outcome of a backend call to the status shown
+// next to the backend in the profile. Errors are matched through the
+// optional interfaces above anywhere in the wrap chain, so backends can
+// wrap client errors freely.
+func StatusFromError(err error) models.StreamingBackendStatus {
+	if err == nil {
+		return models.StreamingBackendStatusOK
+	}
+	var ce credentialsError
+	if errors.As(err, &ce) && ce.InvalidCredentials() {
+		return models.StreamingBackendStatusInvalidCredentials
+	}
+	var re rateLimitError
+	if errors.As(err, &re) && re.RateLimited() {
+		return models.StreamingBackendStatusRateLimited
+	}
+	return models.StreamingBackendStatusError
+}
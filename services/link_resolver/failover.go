@@ -0,0 +1,160 @@
+package link_resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/api"
+	"github.com/webtor-io/web-ui/services/claims"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+)
+
+// probeTimeout bounds the check that a debrid link still serves bytes. The
+// player is waiting on it, so a link that cannot answer the first byte in
+// this long counts as dead.
+const probeTimeout = 5 * time.Second
+
+// ProbeError is a link that answered the probe with an error status
+type ProbeError struct {
+	StatusCode int
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("link answered %d", e.StatusCode)
+}
+
+// RateLimited lets common.StatusFromError file a throttled link under
+// rate_limited rather than failed_over
+func (e *ProbeError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// probeLink asks for the first byte of the link. Any 4xx/5xx answer, a
+// network error or a timeout means the link is no good for playback.
+func probeLink(ctx context.Context, cl *http.Client, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to build probe request")
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	res, err := cl.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to probe link")
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(res.Body)
+	if res.StatusCode >= http.StatusBadRequest {
+		return &ProbeError{StatusCode: res.StatusCode}
+	}
+	return nil
+}
+
+// failoverOrder returns the backends a play URL tries, in order: the one it
+// was minted for, then every enabled backend after it. A backend that has
+// been disabled or removed since starts the list from the top.
+func failoverOrder(enabled []*models.StreamingBackend, from models.StreamingBackendType) []*models.StreamingBackend {
+	for i, b := range enabled {
+		if b.Type == from {
+			return enabled[i:]
+		}
+	}
+	return enabled
+}
+
+// Failover resolves the file for a play URL. The backend that served the
+// stream when it was first resolved is re-checked: its link is resolved
+// again (usually from cache) and probed. On a 4xx/5xx answer, a timeout or
+// a resolve error the next enabled backend takes over, and Webtor last.
+// Every backend passed over is recorded in its status. A cancelled request —
+// the player hung up mid-probe — stops the walk and blames no one.
+func (s *LinkResolver) Failover(ctx context.Context, userID uuid.UUID, apiClaims *api.Claims, userClaims *claims.Data, hash string, fileIdx int, from models.StreamingBackendType, requiresPayment bool) (*co.LinkResult, error) {
+	if from != models.StreamingBackendTypeWebtor {
+		enabledBackends, err := s.GetUserEnabledBackends(ctx, userID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load user enabled backends")
+		}
+		for _, userBackend := range failoverOrder(enabledBackends, from) {
+			url, ok, err := s.tryBackend(ctx, userBackend, hash, fileIdx)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			return &co.LinkResult{
+				URL:         url,
+				ServiceType: userBackend.Type,
+				Cached:      true,
+			}, nil
+		}
+	}
+	return s.resolveWebtor(ctx, apiClaims, userClaims, hash, fileIdx, requiresPayment)
+}
+
+// tryBackend resolves and probes the file on one backend. A backend that
+// does not have the file cached is skipped quietly; one that fails is
+// recorded as failed over. The error is ctx's own, when the caller gave up
+// while the backend was being tried: the backend is not at fault then.
+func (s *LinkResolver) tryBackend(ctx context.Context, userBackend *models.StreamingBackend, hash string, fileIdx int) (string, bool, error) {
+	backend, ok := s.userBackends[userBackend.Type]
+	if !ok {
+		return "", false, nil
+	}
+	url, cached, err := backend.ResolveLink(ctx, userBackend.AccessToken, hash, fileIdx)
+	if ctx.Err() != nil {
+		return "", false, ctx.Err()
+	}
+	if err != nil {
+		s.recordFailover(ctx, userBackend, hash, err)
+		return "", false, nil
+	}
+	if !cached {
+		return "", false, nil
+	}
+	if err := probeLink(ctx, s.cl, url); err != nil {
+		// probeLink's own timeout is the link's fault; the caller's is not
+		if ctx.Err() != nil {
+			return "", false, ctx.Err()
+		}
+		if f, ok := backend.(co.LinkForgetter); ok {
+			f.ForgetLink(userBackend.AccessToken, hash, fileIdx)
+		}
+		s.recordFailover(ctx, userBackend, hash, err)
+		return "", false, nil
+	}
+	s.recordStatus(ctx, userBackend, nil)
+	return url, true, nil
+}
+
+// recordFailover stamps the backend with why the stream left it. Unlike
+// recordStatus it always writes: a dead link is worth showing even when it
+// says nothing about the token. A rejected token or a throttled account
+// keeps its own status; anything else reads failed_over.
+func (s *LinkResolver) recordFailover(ctx context.Context, backend *models.StreamingBackend, hash string, err error) {
+	st := co.StatusFromError(err)
+	if st == models.StreamingBackendStatusError {
+		st = models.StreamingBackendStatusFailedOver
+	}
+	log.WithError(err).
+		WithField("backend_type", backend.Type).
+		WithField("hash", hash).
+		WithField("status", st).
+		Warn("streaming backend failed, failing over")
+	db := s.pg.Get()
+	if db == nil {
+		return
+	}
+	if serr := models.SetStreamingBackendStatus(ctx, db, backend.ID, st); serr != nil {
+		log.WithError(serr).WithField("backend_id", backend.ID).Warn("failed to record streaming backend failover")
+	}
+}
@@ -0,0 +1,161 @@
+package link_resolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webtor-io/web-ui/models"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+)
+
+// TestProbeLink checks which answers keep a debrid link in play and how the
+// rejected ones end up in the backend status.
+func TestProbeLink(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		code   int
+		ok     bool
+		status models.StreamingBackendStatus
+	}{
+		{name: "full body", code: http.StatusOK, ok: true},
+		{name: "range", code: http.StatusPartialContent, ok: true},
+		{name: "gone", code: http.StatusNotFound, status: models.StreamingBackendStatusError},
+		{name: "unavailable", code: http.StatusServiceUnavailable, status: models.StreamingBackendStatusError},
+		{name: "throttled", code: http.StatusTooManyRequests, status: models.StreamingBackendStatusRateLimited},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "bytes=0-0" {
+					t.Errorf("probe sent Range %q", r.Header.Get("Range"))
+				}
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+			err := probeLink(context.Background(), srv.Client(), srv.URL)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("probe failed: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("probe passed a failing link")
+			}
+			if st := co.StatusFromError(err); st != tt.status {
+				t.Errorf("status = %s, want %s", st, tt.status)
+			}
+		})
+	}
+}
+
+func TestProbeLinkTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := probeLink(ctx, srv.Client(), srv.URL); err == nil {
+		t.Fatal("probe passed a link that never answered")
+	}
+}
+
+func TestFailoverOrder(t *testing.T) {
+	rd := &models.StreamingBackend{Type: models.StreamingBackendTypeRealDebrid}
+	tb := &models.StreamingBackend{Type: models.StreamingBackendTypeTorbox}
+	enabled := []*models.StreamingBackend{rd, tb}
+
+	if got := failoverOrder(enabled, models.StreamingBackendTypeTorbox); len(got) != 1 || got[0] != tb {
+		t.Errorf("from torbox = %v, want only torbox", got)
+	}
+	if got := failoverOrder(enabled, models.StreamingBackendTypeRealDebrid); len(got) != 2 || got[0] != rd {
+		t.Errorf("from real-debrid = %v, want both in order", got)
+	}
+	if got := failoverOrder(enabled, models.StreamingBackendTypeAllDebrid); len(got) != 2 {
+		t.Errorf("from a disabled backend = %v, want all enabled", got)
+	}
+}
+
+// A backend that stalls the bulk check must not cost the others their
+// answer: asked one after another, the fast one would only get the context
+// the slow one ran out.
+func TestCheckInParallelSlowBackend(t *testing.T) {
+	slow := &models.StreamingBackend{Type: models.StreamingBackendTypeRealDebrid}
+	fast := &models.StreamingBackend{Type: models.StreamingBackendTypeTorbox}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var answered []models.StreamingBackendType
+	var mu sync.Mutex
+	err := checkInParallel(ctx, []*models.StreamingBackend{slow, fast}, func(ctx context.Context, b *models.StreamingBackend) error {
+		if b == slow {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		answered = append(answered, b.Type)
+		return nil
+	})
+	if err == nil {
+		t.Error("the slow backend's failure was not reported")
+	}
+	if len(answered) != 1 || answered[0] != models.StreamingBackendTypeTorbox {
+		t.Errorf("answered = %v, want the fast backend", answered)
+	}
+}
+
+// fakeBackend hands out one link and counts the links it was told to forget
+type fakeBackend struct {
+	url       string
+	forgotten int
+}
+
+func (b *fakeBackend) ResolveLink(context.Context, string, string, int) (string, bool, error) {
+	return b.url, true, nil
+}
+
+func (b *fakeBackend) Validate(context.Context, string) error { return nil }
+
+func (b *fakeBackend) ContentKinds() []co.ContentKind {
+	return []co.ContentKind{co.ContentKindTorrent}
+}
+
+func (b *fakeBackend) ForgetLink(string, string, int) { b.forgotten++ }
+
+// A player that hangs up during the probe says nothing about the backend:
+// the walk stops with the caller's error, the link is kept and no failover
+// is recorded (the resolver has no database here, so recording one would
+// panic).
+func TestTryBackendCancelled(t *testing.T) {
+	probing := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(probing)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	fb := &fakeBackend{url: srv.URL}
+	s := &LinkResolver{
+		cl:           srv.Client(),
+		userBackends: map[models.StreamingBackendType]co.Backend{models.StreamingBackendTypeTorbox: fb},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-probing
+		cancel()
+	}()
+	_, ok, err := s.tryBackend(ctx, &models.StreamingBackend{Type: models.StreamingBackendTypeTorbox}, "hash", 0)
+	if ok || !errors.Is(err, context.Canceled) {
+		t.Fatalf("ok = %v, err = %v; want the caller's cancellation", ok, err)
+	}
+	if fb.forgotten != 0 {
+		t.Error("a link was forgotten over the caller's cancellation")
+	}
+}
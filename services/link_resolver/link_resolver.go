@@ -2,10 +2,13 @@ package link_resolver
 
 import (
 	"context"
+	"crypto/sha1"
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -23,39 +26,84 @@ import (
 	co "github.com/webtor-io/web-ui/services/link_resolver/common"
 )
 
-// LinkResolver resolves streaming links across multiple backends (RealDebrid, Torbox, Webtor)
+// LinkResolver resolves streaming links across multiple backends (RealDebrid, Torbox,
+// AllDebrid, Premiumize, Webtor)
 // by checking content availability and generating direct download URLs
 type LinkResolver struct {
+	cl                   *http.Client
 	pg                   *cs.PG
 	api                  *api.Api
 	cacheIndex           *ci.CacheIndex
 	userBackends         map[models.StreamingBackendType]co.Backend
 	webtorBackend        *backends.Webtor
 	enabledBackendsCache *lazymap.LazyMap[[]*models.StreamingBackend]
+	batchCheckCache      *lazymap.LazyMap[bool]
 }
 
 // New creates a new LinkResolver with configured backends
 func New(cl *http.Client, pg *cs.PG, apiService *api.Api, cacheIndex *ci.CacheIndex) *LinkResolver {
 	return &LinkResolver{
+		cl:         cl,
 		pg:         pg,
 		api:        apiService,
 		cacheIndex: cacheIndex,
 		userBackends: map[models.StreamingBackendType]co.Backend{
 			models.StreamingBackendTypeRealDebrid: backends.NewRealDebrid(cl),
 			models.StreamingBackendTypeTorbox:     backends.NewTorbox(cl),
+			models.StreamingBackendTypeAllDebrid:  backends.NewAllDebrid(cl),
+			models.StreamingBackendTypePremiumize: backends.NewPremiumize(cl),
 		},
 		webtorBackend: backends.NewWebtor(apiService),
 		enabledBackendsCache: lazymap.New[[]*models.StreamingBackend](&lazymap.Config{
 			Expire:      1 * time.Minute,
 			ErrorExpire: 30 * time.Second,
 		}),
+		// Stremio asks for the same stream list again on every visit to a
+		// title; answering a repeat from memory keeps us clear of the
+		// services' rate limits.
+		batchCheckCache: lazymap.New[bool](&lazymap.Config{
+			Expire:      5 * time.Minute,
+			ErrorExpire: 30 * time.Second,
+		}),
 	}
 }
 
+// RegisterSelfHosted adds the backends that run on the user's own machine
+// (SABnzbd, NZBGet). They are reached at an address the user typed in, so cl
+// should carry the same egress policy as Torznab indexers: on shared
+// infrastructure an arbitrary address is an SSRF hole. Call it before
+// serving.
+func (s *LinkResolver) RegisterSelfHosted(cl *http.Client) {
+	s.userBackends[models.StreamingBackendTypeSABnzbd] = backends.NewSABnzbd(cl)
+	s.userBackends[models.StreamingBackendTypeNZBGet] = backends.NewNZBGet(cl)
+}
+
+// GetUserEnabledBackends returns the user's enabled backends that fetch
+// torrents, highest priority first
 func (s *LinkResolver) GetUserEnabledBackends(ctx context.Context, userID uuid.UUID) ([]*models.StreamingBackend, error) {
-	return s.enabledBackendsCache.Get(userID.String(), func() ([]*models.StreamingBackend, error) {
+	return s.getUserBackendsServing(ctx, userID, co.ContentKindTorrent)
+}
+
+// GetUserUsenetBackends returns the user's enabled backends that fetch
+// Usenet releases, highest priority first
+func (s *LinkResolver) GetUserUsenetBackends(ctx context.Context, userID uuid.UUID) ([]*models.StreamingBackend, error) {
+	return s.getUserBackendsServing(ctx, userID, co.ContentKindNZB)
+}
+
+func (s *LinkResolver) getUserBackendsServing(ctx context.Context, userID uuid.UUID, kind co.ContentKind) ([]*models.StreamingBackend, error) {
+	all, err := s.enabledBackendsCache.Get(userID.String(), func() ([]*models.StreamingBackend, error) {
 		return s.getUserEnabledBackends(ctx, userID)
 	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.StreamingBackend, 0, len(all))
+	for _, b := range all {
+		if co.Serves(s.userBackends[b.Type], kind) {
+			res = append(res, b)
+		}
+	}
+	return res, nil
 }
 
 func (s *LinkResolver) getUserEnabledBackends(ctx context.Context, userID uuid.UUID) ([]*models.StreamingBackend, error) {
@@ -107,6 +155,7 @@ func (s *LinkResolver) ResolveLink(ctx context.Context, userID uuid.UUID, apiCla
 			continue
 		}
 		url, cached, berr := backend.ResolveLink(ctx, userBackend.AccessToken, hash, fileIdx)
+		s.recordStatus(ctx, userBackend, berr)
 		if berr != nil {
 			log.WithError(berr).WithField("backend_type", userBackend.Type).Warn("failed to generate link from backend")
 			continue
@@ -130,7 +179,12 @@ func (s *LinkResolver) ResolveLink(ctx context.Context, userID uuid.UUID, apiCla
 		}, nil
 	}
 
-	// Fallback to webtor. Free users hit the paywall here.
+	return s.resolveWebtor(ctx, apiClaims, userClaims, hash, fileIdx, requiresPayment)
+}
+
+// resolveWebtor resolves the file through Webtor itself, the fallback after
+// every user backend. Free users hit the paywall here.
+func (s *LinkResolver) resolveWebtor(ctx context.Context, apiClaims *api.Claims, userClaims *claims.Data, hash string, fileIdx int, requiresPayment bool) (*co.LinkResult, error) {
 	if requiresPayment && !s.isPaidUser(userClaims) {
 		return nil, nil
 	}
@@ -156,6 +210,134 @@ func (s *LinkResolver) ResolveLink(ctx context.Context, userID uuid.UUID, apiCla
 	}, nil
 }
 
+// CheckAvailabilityBatch asks each of the user's enabled backends that can
+// answer in bulk (co.AvailabilityChecker) which of the files are cached, and
+// writes the answers to the cache index — so the per-file CheckAvailability
+// that follows labels every stream of a list, not only the ones played
+// before. One call per backend per stream list; backends that cannot answer
+// in bulk are skipped. The backends are asked at once, so a slow one costs
+// only its own answer. A failure leaves that backend's part of the index as
+// it was and is returned after the others are done.
+func (s *LinkResolver) CheckAvailabilityBatch(ctx context.Context, userID uuid.UUID, keys []models.CacheIndexKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	enabledBackends, err := s.GetUserEnabledBackends(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load user enabled backends")
+	}
+	var hashes []string
+	seen := map[string]bool{}
+	for _, k := range keys {
+		h := strings.ToLower(k.ResourceID)
+		if !seen[h] {
+			seen[h] = true
+			hashes = append(hashes, h)
+		}
+	}
+	sort.Strings(hashes)
+	sum := sha1.Sum([]byte(strings.Join(hashes, ",")))
+	var checkers []*models.StreamingBackend
+	for _, b := range enabledBackends {
+		if _, ok := s.userBackends[b.Type].(co.AvailabilityChecker); ok {
+			checkers = append(checkers, b)
+		}
+	}
+	return checkInParallel(ctx, checkers, func(ctx context.Context, userBackend *models.StreamingBackend) error {
+		checker := s.userBackends[userBackend.Type].(co.AvailabilityChecker)
+		key := fmt.Sprintf("%s:%x", userBackend.ID, sum)
+		_, err := s.batchCheckCache.Get(key, func() (bool, error) {
+			cached, err := checker.CheckAvailability(ctx, userBackend.AccessToken, hashes)
+			s.recordStatus(ctx, userBackend, err)
+			if err != nil {
+				return false, err
+			}
+			var hit, miss []models.CacheIndexKey
+			for _, k := range keys {
+				if cached[strings.ToLower(k.ResourceID)] {
+					hit = append(hit, k)
+				} else {
+					miss = append(miss, k)
+				}
+			}
+			return true, s.cacheIndex.MarkAsChecked(ctx, userBackend.Type, hit, miss)
+		})
+		if err != nil {
+			log.WithError(err).WithField("backend_type", userBackend.Type).Warn("failed to check availability in bulk")
+			return errors.Wrapf(err, "failed to check availability in bulk on %s", userBackend.Type)
+		}
+		return nil
+	})
+}
+
+// checkInParallel runs check for every backend at once and returns the
+// first error once all of them are done.
+func checkInParallel(ctx context.Context, backends []*models.StreamingBackend, check func(ctx context.Context, b *models.StreamingBackend) error) error {
+	errs := make([]error, len(backends))
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		wg.Add(1)
+		go func(i int, b *models.StreamingBackend) {
+			defer wg.Done()
+			errs[i] = check(ctx, b)
+		}(i, b)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChecksInBulk reports whether backends of this type can say in one call
+// which of many torrents they have cached
+func (s *LinkResolver) ChecksInBulk(t models.StreamingBackendType) bool {
+	_, ok := s.userBackends[t].(co.AvailabilityChecker)
+	return ok
+}
+
+// WarmCache checks the hashes against the backend in bulk and writes the
+// answer to the cache index as whole-torrent entries, without anyone asking
+// for a stream. It returns how many of them the backend has cached.
+func (s *LinkResolver) WarmCache(ctx context.Context, backend *models.StreamingBackend, hashes []string) (int, error) {
+	checker, ok := s.userBackends[backend.Type].(co.AvailabilityChecker)
+	if !ok {
+		return 0, errors.Errorf("backend %s cannot check availability in bulk", backend.Type)
+	}
+	cached, err := checker.CheckAvailability(ctx, backend.AccessToken, hashes)
+	s.recordStatus(ctx, backend, err)
+	if err != nil {
+		return 0, err
+	}
+	var hit, miss []models.CacheIndexKey
+	for _, h := range hashes {
+		k := models.CacheIndexKey{ResourceID: h, FileIdx: models.CacheIndexTorrentFileIdx}
+		if cached[h] {
+			hit = append(hit, k)
+		} else {
+			miss = append(miss, k)
+		}
+	}
+	return len(hit), s.cacheIndex.MarkAsChecked(ctx, backend.Type, hit, miss)
+}
+
+// CachedTorrents returns which of the torrents the cache index knows to be
+// cached on the user's enabled backends, and on which. It only reads the
+// index, so it is cheap enough to rank a whole stream list by.
+func (s *LinkResolver) CachedTorrents(ctx context.Context, userID uuid.UUID, hashes []string) (map[string][]models.StreamingBackendType, error) {
+	enabledBackends, err := s.GetUserEnabledBackends(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load user enabled backends")
+	}
+	var types []models.StreamingBackendType
+	for _, b := range enabledBackends {
+		types = append(types, b.Type)
+	}
+	return s.cacheIndex.CachedTorrents(ctx, hashes, types)
+}
+
 // listPageSize bounds a single rest-api listing page while looking for the
 // file a stream means. rest-api caps a page at 1000 and serves listings from
 // the lightweight torrent-store manifest, so one page covers all but the
@@ -413,9 +595,169 @@ func (s *LinkResolver) CheckAvailability(ctx context.Context, id uuid.UUID, cla
 	}, nil
 }
 
+// Validate checks the backend's token against its service. On success the
+// backend is stamped with an ok status, so a freshly added backend shows as
+// checked before its first play.
 func (s *LinkResolver) Validate(ctx context.Context, backend *models.StreamingBackend) error {
 	if _, ok := s.userBackends[backend.Type]; !ok {
 		return errors.New("backend implementation not found")
 	}
-	return s.userBackends[backend.Type].Validate(ctx, backend.AccessToken)
+	if err := s.userBackends[backend.Type].Validate(ctx, backend.AccessToken); err != nil {
+		return err
+	}
+	st := models.StreamingBackendStatusOK
+	now := time.Now()
+	backend.LastStatus = &st
+	backend.LastCheckedAt = &now
+	return nil
+}
+
+// Account asks the backend's service about the account behind its token.
+// It returns nil, nil for a backend that cannot tell.
+func (s *LinkResolver) Account(ctx context.Context, backend *models.StreamingBackend) (*co.Account, error) {
+	checker, ok := s.userBackends[backend.Type].(co.AccountChecker)
+	if !ok {
+		return nil, nil
+	}
+	return checker.Account(ctx, backend.AccessToken)
+}
+
+// ChecksAccount reports whether a backend of this type can tell anything
+// about the account behind its token
+func (s *LinkResolver) ChecksAccount(t models.StreamingBackendType) bool {
+	_, ok := s.userBackends[t].(co.AccountChecker)
+	return ok
+}
+
+// ManagesTorrents reports whether the user can manage the torrent list of a
+// backend of this type from Webtor
+func (s *LinkResolver) ManagesTorrents(t models.StreamingBackendType) bool {
+	_, ok := s.userBackends[t].(co.TorrentManager)
+	return ok
+}
+
+func (s *LinkResolver) torrentManager(backend *models.StreamingBackend) (co.TorrentManager, error) {
+	m, ok := s.userBackends[backend.Type].(co.TorrentManager)
+	if !ok {
+		return nil, errors.Errorf("backend %s has no torrent list", backend.Type)
+	}
+	return m, nil
+}
+
+// AddTorrent sends the torrent to the backend's account, with the files at
+// fileIdx selected where the service supports it
+func (s *LinkResolver) AddTorrent(ctx context.Context, backend *models.StreamingBackend, hash string, fileIdx []int) (string, error) {
+	m, err := s.torrentManager(backend)
+	if err != nil {
+		return "", err
+	}
+	id, err := m.AddTorrent(ctx, backend.AccessToken, hash, fileIdx)
+	s.recordStatus(ctx, backend, err)
+	return id, err
+}
+
+// ListTorrents returns the torrents in the backend's account
+func (s *LinkResolver) ListTorrents(ctx context.Context, backend *models.StreamingBackend) ([]co.Torrent, error) {
+	m, err := s.torrentManager(backend)
+	if err != nil {
+		return nil, err
+	}
+	list, err := m.ListTorrents(ctx, backend.AccessToken)
+	s.recordStatus(ctx, backend, err)
+	return list, err
+}
+
+// DeleteTorrent removes a torrent from the backend's account
+func (s *LinkResolver) DeleteTorrent(ctx context.Context, backend *models.StreamingBackend, id string) error {
+	m, err := s.torrentManager(backend)
+	if err != nil {
+		return err
+	}
+	err = m.DeleteTorrent(ctx, backend.AccessToken, id)
+	s.recordStatus(ctx, backend, err)
+	return err
+}
+
+// ServesUsenet reports whether backends of this type download Usenet
+// releases
+func (s *LinkResolver) ServesUsenet(t models.StreamingBackendType) bool {
+	b, ok := s.userBackends[t]
+	if !ok {
+		return false
+	}
+	_, ok = b.(co.UsenetManager)
+	return ok && co.Serves(b, co.ContentKindNZB)
+}
+
+func (s *LinkResolver) usenetManager(backend *models.StreamingBackend) (co.UsenetManager, error) {
+	if !s.ServesUsenet(backend.Type) {
+		return nil, errors.Errorf("backend %s does not download usenet releases", backend.Type)
+	}
+	return s.userBackends[backend.Type].(co.UsenetManager), nil
+}
+
+// AddNZB starts a Usenet download of the NZB on the backend
+func (s *LinkResolver) AddNZB(ctx context.Context, backend *models.StreamingBackend, name string, nzb []byte) (string, error) {
+	m, err := s.usenetManager(backend)
+	if err != nil {
+		return "", err
+	}
+	id, err := m.AddNZB(ctx, backend, name, nzb)
+	s.recordStatus(ctx, backend, err)
+	return id, err
+}
+
+// GetNZB describes a Usenet download on the backend
+func (s *LinkResolver) GetNZB(ctx context.Context, backend *models.StreamingBackend, id string) (*co.UsenetJob, error) {
+	m, err := s.usenetManager(backend)
+	if err != nil {
+		return nil, err
+	}
+	job, err := m.GetNZB(ctx, backend, id)
+	s.recordStatus(ctx, backend, err)
+	return job, err
+}
+
+// NZBLink returns a direct link to a file of a finished Usenet download
+func (s *LinkResolver) NZBLink(ctx context.Context, backend *models.StreamingBackend, id, fileID string) (string, error) {
+	m, err := s.usenetManager(backend)
+	if err != nil {
+		return "", err
+	}
+	link, err := m.NZBLink(ctx, backend, id, fileID)
+	s.recordStatus(ctx, backend, err)
+	return link, err
+}
+
+// DeleteNZB removes a Usenet download from the backend
+func (s *LinkResolver) DeleteNZB(ctx context.Context, backend *models.StreamingBackend, id string) error {
+	m, err := s.usenetManager(backend)
+	if err != nil {
+		return err
+	}
+	err = m.DeleteNZB(ctx, backend, id)
+	s.recordStatus(ctx, backend, err)
+	return err
+}
+
+// recordStatus persists what a resolve said about the backend's account.
+// Failures that are not about the account — a torrent the service cannot
+// add, a network blip — leave the status alone: they say nothing about
+// whether the token works. The row is only written when the status changes
+// from what the (briefly cached) backend list last saw.
+func (s *LinkResolver) recordStatus(ctx context.Context, backend *models.StreamingBackend, err error) {
+	st := co.StatusFromError(err)
+	if st == models.StreamingBackendStatusError {
+		return
+	}
+	if backend.LastStatus != nil && *backend.LastStatus == st {
+		return
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return
+	}
+	if serr := models.SetStreamingBackendStatus(ctx, db, backend.ID, st); serr != nil {
+		log.WithError(serr).WithField("backend_id", backend.ID).Warn("failed to record streaming backend status")
+	}
 }
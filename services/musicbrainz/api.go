@@ -0,0 +1,264 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	enabledFlag   = "musicbrainz-enabled"
+	urlFlag       = "musicbrainz-api-url"
+	coverURLFlag  = "coverartarchive-api-url"
+	userAgentFlag = "musicbrainz-user-agent"
+)
+
+func RegisterFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.BoolFlag{
+			Name:   enabledFlag,
+			Usage:  "identify music albums through musicbrainz",
+			EnvVar: "MUSICBRAINZ_ENABLED",
+		},
+		cli.StringFlag{
+			Name:   urlFlag,
+			Usage:  "musicbrainz api url",
+			EnvVar: "MUSICBRAINZ_API_URL",
+			Value:  "https://musicbrainz.org/ws/2",
+		},
+		cli.StringFlag{
+			Name:   coverURLFlag,
+			Usage:  "cover art archive api url",
+			EnvVar: "COVERARTARCHIVE_API_URL",
+			Value:  "https://coverartarchive.org",
+		},
+		cli.StringFlag{
+			Name:   userAgentFlag,
+			Usage:  "user agent musicbrainz requests identify with, as its terms require",
+			EnvVar: "MUSICBRAINZ_USER_AGENT",
+			Value:  "webtor-web-ui/1.0 ( https://webtor.io )",
+		},
+	)
+}
+
+// minInterval is MusicBrainz's rate limit: one request a second per
+// client, answered with 503 above it.
+const minInterval = time.Second
+
+// ReleaseGroup is one MusicBrainz release group — an album as a work,
+// all of its editions and formats together.
+type ReleaseGroup struct {
+	ID               string         `json:"id"`
+	Score            int            `json:"score"`
+	Title            string         `json:"title"`
+	PrimaryType      string         `json:"primary-type"`
+	SecondaryTypes   []string       `json:"secondary-types"`
+	FirstReleaseDate string         `json:"first-release-date"`
+	ArtistCredit     []ArtistCredit `json:"artist-credit"`
+	Raw              map[string]any `json:"-"`
+}
+
+// ArtistCredit is one artist of a credit, as named on the release
+type ArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     Artist `json:"artist"`
+}
+
+type Artist struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Artist is the credited artist as printed on the cover, "Simon &
+// Garfunkel" rather than two names
+func (g *ReleaseGroup) Artist() string {
+	var sb strings.Builder
+	for _, ac := range g.ArtistCredit {
+		sb.WriteString(ac.Name)
+		sb.WriteString(ac.JoinPhrase)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Year is the year of the group's first release, 0 when unknown
+func (g *ReleaseGroup) Year() int {
+	if len(g.FirstReleaseDate) < 4 {
+		return 0
+	}
+	y, err := strconv.Atoi(g.FirstReleaseDate[:4])
+	if err != nil {
+		return 0
+	}
+	return y
+}
+
+type Api struct {
+	url      string
+	coverURL string
+	ua       string
+	cl       *http.Client
+	mux      sync.Mutex
+	last     time.Time
+}
+
+func New(c *cli.Context, cl *http.Client) *Api {
+	if !c.Bool(enabledFlag) {
+		return nil
+	}
+	u := c.String(urlFlag)
+	log.Infof("musicbrainz api endpoint %v", u)
+	return newApi(cl, u, c.String(coverURLFlag), c.String(userAgentFlag))
+}
+
+func newApi(cl *http.Client, u, coverURL, ua string) *Api {
+	return &Api{
+		url:      strings.TrimSuffix(u, "/"),
+		coverURL: strings.TrimSuffix(coverURL, "/"),
+		ua:       ua,
+		cl:       cl,
+	}
+}
+
+// SearchReleaseGroups returns the release groups titled album by artist,
+// best match first
+func (api *Api) SearchReleaseGroups(ctx context.Context, artist, album string) ([]*ReleaseGroup, error) {
+	q := url.Values{}
+	q.Set("query", "releasegroup:"+phrase(album)+" AND artist:"+phrase(artist))
+	q.Set("limit", "10")
+	q.Set("fmt", "json")
+	var data struct {
+		ReleaseGroups []json.RawMessage `json:"release-groups"`
+	}
+	if err := api.doRequest(ctx, api.url+"/release-group?"+q.Encode(), true, &data); err != nil {
+		return nil, errors.Wrap(err, "musicbrainz search request")
+	}
+	out := make([]*ReleaseGroup, 0, len(data.ReleaseGroups))
+	for _, raw := range data.ReleaseGroups {
+		var g ReleaseGroup
+		if err := json.Unmarshal(raw, &g); err != nil {
+			return nil, errors.Wrap(err, "unmarshal release group")
+		}
+		if err := json.Unmarshal(raw, &g.Raw); err != nil {
+			return nil, errors.Wrap(err, "unmarshal release group")
+		}
+		out = append(out, &g)
+	}
+	return out, nil
+}
+
+// phrase quotes s as a Lucene phrase
+func phrase(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+type coverImage struct {
+	Front      bool              `json:"front"`
+	Image      string            `json:"image"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// FrontCover returns the URL of a release group's front cover from the
+// Cover Art Archive, "" when it has none
+func (api *Api) FrontCover(ctx context.Context, mbid string) (string, error) {
+	var data struct {
+		Images []coverImage `json:"images"`
+	}
+	err := api.doRequest(ctx, api.coverURL+"/release-group/"+url.PathEscape(mbid), false, &data)
+	if errors.Is(err, errNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "cover art archive request")
+	}
+	for _, im := range data.Images {
+		if !im.Front {
+			continue
+		}
+		// 500px is plenty for a card and spares the poster proxy a
+		// multi-megabyte original
+		for _, k := range []string{"500", "large", "1200"} {
+			if u := im.Thumbnails[k]; u != "" {
+				return httpsURL(u), nil
+			}
+		}
+		return httpsURL(im.Image), nil
+	}
+	return "", nil
+}
+
+// httpsURL upgrades the archive's http links, which it still hands out
+func httpsURL(u string) string {
+	if strings.HasPrefix(u, "http://") {
+		return "https://" + strings.TrimPrefix(u, "http://")
+	}
+	return u
+}
+
+var errNotFound = errors.New("not found")
+
+// wait holds a MusicBrainz request back until a second has passed since
+// the previous one. The Cover Art Archive has no such limit.
+func (api *Api) wait(ctx context.Context) error {
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	if d := minInterval - time.Since(api.last); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	api.last = time.Now()
+	return nil
+}
+
+func (api *Api) doRequest(ctx context.Context, u string, limited bool, out any) error {
+	if limited {
+		if err := api.wait(ctx); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", api.ua)
+
+	resp, err := api.cl.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(b))
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package musicbrainz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replay serves recorded responses by path and hands back the last
+// request it was asked.
+func replay(t *testing.T, fixtures map[string]string) (*Api, **http.Request) {
+	t.Helper()
+	var last *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		fixture, ok := fixtures[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return newApi(srv.Client(), srv.URL+"/ws/2", srv.URL, "webtor-test/1.0"), &last
+}
+
+func TestSearchReleaseGroups(t *testing.T) {
+	api, last := replay(t, map[string]string{
+		"/ws/2/release-group": "testdata/search_dark_side_of_the_moon.json",
+	})
+	res, err := api.SearchReleaseGroups(context.Background(), "Pink Floyd", `The Dark Side of the "Moon"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := *last
+	if q := r.URL.Query().Get("query"); q != `releasegroup:"The Dark Side of the \"Moon\"" AND artist:"Pink Floyd"` {
+		t.Errorf("query %s", q)
+	}
+	if r.URL.Query().Get("fmt") != "json" || r.Header.Get("User-Agent") != "webtor-test/1.0" {
+		t.Errorf("request %v", r.URL)
+	}
+	if len(res) != 3 {
+		t.Fatalf("got %d release groups", len(res))
+	}
+	g := res[0]
+	if g.ID != "f5093c06-23e3-404f-aeaa-40f72885ee3a" || g.Year() != 1973 || g.Artist() != "Pink Floyd" || g.PrimaryType != "Album" {
+		t.Errorf("first release group %+v", g)
+	}
+	if g.Raw["tags"] == nil {
+		t.Errorf("raw payload %v", g.Raw)
+	}
+	if a := res[2].Artist(); a != "Easy Star All-Stars & Pink Floyd" {
+		t.Errorf("joined credit %q", a)
+	}
+	if y := res[2].Year(); y != 2003 {
+		t.Errorf("year-only date %d", y)
+	}
+}
+
+func TestFrontCover(t *testing.T) {
+	api, _ := replay(t, map[string]string{
+		"/release-group/f5093c06-23e3-404f-aeaa-40f72885ee3a": "testdata/cover_dark_side_of_the_moon.json",
+	})
+	u, err := api.FrontCover(context.Background(), "f5093c06-23e3-404f-aeaa-40f72885ee3a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "https://") || !strings.HasSuffix(u, "/1609440020-500.jpg") {
+		t.Errorf("front cover %s", u)
+	}
+}
+
+func TestFrontCoverMissing(t *testing.T) {
+	api, _ := replay(t, map[string]string{})
+	u, err := api.FrontCover(context.Background(), "7d7b2c3a-4a1b-4a8e-9b55-2f5f6f3b1c02")
+	if err != nil || u != "" {
+		t.Errorf("got %q, %v; want no cover and no error", u, err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	api, _ := replay(t, map[string]string{
+		"/ws/2/release-group": "testdata/search_dark_side_of_the_moon.json",
+	})
+	api.last = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := api.SearchReleaseGroups(ctx, "Pink Floyd", "Animals"); err == nil {
+		t.Error("request went out inside the rate limit window")
+	}
+}
@@ -3,6 +3,7 @@ package notification
 import (
 	"strings"
 	"testing"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 )
@@ -77,6 +78,15 @@ func TestSubscriptionTemplatesRender(t *testing.T) {
 			}(),
 			want: "The.Boys.S03E05.1080p",
 		},
+		{
+			name:     "digest across subscriptions",
+			template: "subscription-digest.html",
+			data: s.digestData([]SubscriptionUpdate{
+				{Sub: sub, Releases: []ReleaseView{{Name: "The.Boys.S03E06.1080p", InfoHash: "cc", URL: "https://webtor.io/x", Source: "RuTracker.org"}}},
+				{Sub: movie, Releases: []ReleaseView{{Name: "The.Shawshank.Redemption.1994.2160p", InfoHash: "dd", URL: "https://webtor.io/y"}}},
+			}),
+			want: "The.Shawshank.Redemption.1994.2160p",
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			body, err := s.render(tt.template, "ru", tt.data)
@@ -108,3 +118,50 @@ func TestSubscriptionUpdateOmitsEmptySource(t *testing.T) {
 		t.Error("a release with no source rendered the source line anyway")
 	}
 }
+
+// TestSubscriptionDigestSections: every subscription in a digest gets its
+// own heading and its own unsubscribe link — one letter must not make it
+// harder to leave any one of them.
+func TestSubscriptionDigestSections(t *testing.T) {
+	s := &Service{templateDir: "../../templates/notification", domain: "https://webtor.io"}
+	d := s.digestData([]SubscriptionUpdate{
+		{Sub: SubscriptionView{ID: uuid.NewV4(), Title: "Dune", UnsubscribeURL: "https://webtor.io/u/dune"}, Releases: []ReleaseView{{Name: "Dune.2021.1080p", InfoHash: "aa"}}},
+		{Sub: SubscriptionView{ID: uuid.NewV4(), Title: "Severance", Season: 2, UnsubscribeURL: "https://webtor.io/u/sev"}, Releases: []ReleaseView{{Name: "Severance.S02E01", InfoHash: "bb"}}},
+	})
+
+	body, err := s.render("subscription-digest.html", "en", d)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for _, want := range []string{"Dune", "Severance", "https://webtor.io/u/dune", "https://webtor.io/u/sev", "/profile#subscription-mail"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("digest is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+// TestBackendTemplatesRender executes the streaming backend emails against
+// the data their senders pass.
+func TestBackendTemplatesRender(t *testing.T) {
+	s := &Service{templateDir: "../../templates/notification", domain: "https://webtor.io"}
+	until := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	d := s.backendData(BackendView{ID: uuid.NewV4(), Name: "Real-Debrid", PremiumUntil: &until})
+
+	for tmpl, want := range map[string]string{
+		"backend-expiring.html": "email.backend.expiring.text",
+		"backend-invalid.html":  "email.backend.invalid.text",
+	} {
+		body, err := s.render(tmpl, "en", d)
+		if err != nil {
+			t.Fatalf("%s: render: %v", tmpl, err)
+		}
+		for _, w := range []string{want, "/profile#streaming-backends"} {
+			if !strings.Contains(body, w) {
+				t.Errorf("%s is missing %q:\n%s", tmpl, w, body)
+			}
+		}
+		if strings.Contains(body, "<no value>") {
+			t.Errorf("%s has an unresolved field:\n%s", tmpl, body)
+		}
+	}
+}
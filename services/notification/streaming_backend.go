@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// BackendView is what a streaming backend email needs to know about the
+// account: which service, and when its premium runs out.
+type BackendView struct {
+	ID uuid.UUID
+	// Name is the service's display name, e.g. "Real-Debrid"
+	Name string
+	// Lang is the language to render in, resolved by the caller from the
+	// account settings.
+	Lang         string
+	PremiumUntil *time.Time
+}
+
+type backendMailData struct {
+	Name         string
+	PremiumUntil string
+	ManageURL    string
+	Domain       string
+}
+
+func (s *Service) backendData(b BackendView) backendMailData {
+	d := backendMailData{
+		Name:      b.Name,
+		ManageURL: s.domain + "/profile#streaming-backends",
+		Domain:    s.domain,
+	}
+	if b.PremiumUntil != nil {
+		d.PremiumUntil = b.PremiumUntil.Format("2006-01-02")
+	}
+	return d
+}
+
+// SendBackendExpiring warns that the premium plan behind a backend is about
+// to run out. The letter names the date rather than a day count, which reads
+// the same in every language. The key carries that date too, so a renewed
+// plan that nears its new end is announced again.
+func (s *Service) SendBackendExpiring(to string, b BackendView) error {
+	data := s.backendData(b)
+	return s.Send(SendOptions{
+		To:       to,
+		Lang:     b.Lang,
+		Key:      fmt.Sprintf("backend-expiring-%s-%s", b.ID, data.PremiumUntil),
+		Title:    s.T(b.Lang, "email.backend.expiring.subject", "Name", b.Name, "Date", data.PremiumUntil),
+		Template: "backend-expiring.html",
+		Data:     data,
+	})
+}
+
+// SendBackendInvalid reports that the service no longer accepts the stored
+// token, so links stopped resolving through it.
+func (s *Service) SendBackendInvalid(to string, b BackendView) error {
+	return s.Send(SendOptions{
+		To:       to,
+		Lang:     b.Lang,
+		Key:      fmt.Sprintf("backend-invalid-%s", b.ID),
+		Title:    s.T(b.Lang, "email.backend.invalid.subject", "Name", b.Name),
+		Template: "backend-invalid.html",
+		Data:     s.backendData(b),
+	})
+}
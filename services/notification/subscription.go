@@ -90,26 +90,81 @@ func (s *Service) SendSubscriptionOff(to string, sub SubscriptionView, completed
 	})
 }
 
-// SendSubscriptionUpdate reports releases the subscription had not seen
+// SubscriptionUpdate is what one subscription has to report: the
+// subscription and the releases it had not seen before.
+type SubscriptionUpdate struct {
+	Sub      SubscriptionView
+	Releases []ReleaseView
+}
+
+type subscriptionDigestData struct {
+	Updates   []subscriptionMailData
+	ManageURL string
+	// SettingsURL points at the delivery settings: a letter that batches
+	// on the account's behalf says where that is decided.
+	SettingsURL string
+	Domain      string
+}
+
+// SendSubscriptionUpdate reports releases the subscriptions had not seen
 // before. Everything found since the last letter goes out in one message —
 // four new rips are four lines here, not four emails.
-func (s *Service) SendSubscriptionUpdate(to string, sub SubscriptionView, releases []ReleaseView) error {
-	if len(releases) == 0 {
+//
+// All updates belong to one account and go out as one letter. A single
+// subscription keeps the plain update layout; several — a digest, or an
+// immediate-mode account whose finds were held by quiet hours — render as
+// one combined letter, a section per subscription, each with its own
+// unsubscribe link.
+func (s *Service) SendSubscriptionUpdate(to string, updates []SubscriptionUpdate) error {
+	nonEmpty := make([]SubscriptionUpdate, 0, len(updates))
+	for _, u := range updates {
+		if len(u.Releases) > 0 {
+			nonEmpty = append(nonEmpty, u)
+		}
+	}
+	if len(nonEmpty) == 0 {
 		return nil
 	}
-	data := s.subscriptionData(sub)
-	data.Releases = releases
+	first := nonEmpty[0]
+	lang := first.Sub.Lang
+	// Unlike the on/off letters this one recurs, so the key has to change
+	// between sends or the 24-hour dedupe would swallow the second batch —
+	// and its hashes, already recorded as seen, would never be mentioned
+	// again. How often a batch may go out is the poller's decision, not
+	// the deduper's.
+	key := fmt.Sprintf("sub-upd-%s-%s", first.Sub.ID, first.Releases[0].InfoHash)
+	if len(nonEmpty) == 1 {
+		data := s.subscriptionData(first.Sub)
+		data.Releases = first.Releases
+		return s.Send(SendOptions{
+			To:       to,
+			Lang:     lang,
+			Key:      key,
+			Title:    s.T(lang, "email.subscription.update.subject", "Title", first.Sub.Title),
+			Template: "subscription-update.html",
+			Data:     data,
+		})
+	}
 	return s.Send(SendOptions{
-		To:   to,
-		Lang: sub.Lang,
-		// Unlike the on/off letters this one recurs, so the key has to
-		// change between sends or the 24-hour dedupe would swallow the
-		// second batch — and its hashes, already recorded as seen, would
-		// never be mentioned again. How often a batch may go out is the
-		// poller's decision, not the deduper's.
-		Key:      fmt.Sprintf("sub-upd-%s-%s", sub.ID, releases[0].InfoHash),
-		Title:    s.T(sub.Lang, "email.subscription.update.subject", "Title", sub.Title),
-		Template: "subscription-update.html",
-		Data:     data,
+		To:       to,
+		Lang:     lang,
+		Key:      key,
+		Title:    s.T(lang, "email.subscription.digest.subject", "Count", len(nonEmpty)),
+		Template: "subscription-digest.html",
+		Data:     s.digestData(nonEmpty),
 	})
 }
+
+func (s *Service) digestData(updates []SubscriptionUpdate) subscriptionDigestData {
+	d := subscriptionDigestData{
+		ManageURL:   s.domain + "/profile#subscriptions",
+		SettingsURL: s.domain + "/profile#subscription-mail",
+		Domain:      s.domain,
+	}
+	for _, u := range updates {
+		sd := s.subscriptionData(u.Sub)
+		sd.Releases = u.Releases
+		d.Updates = append(d.Updates, sd)
+	}
+	return d
+}
@@ -0,0 +1,145 @@
+package nzbget
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testUser = "nzbget"
+	testPass = "tegbzn6789"
+)
+
+// mockNZBGet answers the JSON-RPC methods the client uses, the way NZBGet
+// 24 does: everything posted to /jsonrpc, a plain 401 for wrong
+// credentials and a 200 with an error object for a failed call.
+func mockNZBGet(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jsonrpc" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if u, p, ok := r.BasicAuth(); !ok || u != testUser || p != testPass {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+			return
+		}
+		switch req.Method {
+		case "version":
+			_, _ = w.Write([]byte(`{"result":"24.3"}`))
+		case "append":
+			var name, content string
+			if len(req.Params) != 10 {
+				t.Errorf("append got %d params", len(req.Params))
+			} else {
+				_ = json.Unmarshal(req.Params[0], &name)
+				_ = json.Unmarshal(req.Params[1], &content)
+			}
+			b, _ := base64.StdEncoding.DecodeString(content)
+			if name != "Show.S01E01.nzb" || !strings.Contains(string(b), "<nzb") {
+				t.Errorf("upload %q named %q", b, name)
+			}
+			_, _ = w.Write([]byte(`{"result":7}`))
+		case "listgroups":
+			_, _ = w.Write([]byte(`{"result":[{"NZBID":7,"NZBName":"Show.S01E01","Status":"DOWNLOADING","FileSizeLo":104857600,"FileSizeHi":0,"RemainingSizeLo":62914560,"RemainingSizeHi":0}]}`))
+		case "history":
+			_, _ = w.Write([]byte(`{"result":[{"NZBID":8,"Name":"Movie","Status":"SUCCESS/UNPACK","FileSizeLo":0,"FileSizeHi":1,"DestDir":"/data/dst/Movie.#8","FinalDir":"/data/dst/movies/Movie"},{"NZBID":9,"Name":"Broken","Status":"FAILURE/PAR","DestDir":"/data/dst/Broken"}]}`))
+		case "config":
+			_, _ = w.Write([]byte(`{"result":[{"Name":"MainDir","Value":"/data"},{"Name":"DestDir","Value":"/data/dst"}]}`))
+		case "editqueue":
+			_, _ = w.Write([]byte(`{"result":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"result":null,"error":{"name":"JsonRpcError","code":1,"message":"Invalid procedure"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParseAPIURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"http://u:p@nas:6789":                  "http://nas:6789/jsonrpc",
+		"http://u:p@nas:6789/":                 "http://nas:6789/jsonrpc",
+		"https://u:p@nzb.example.com/nzbget/":  "https://nzb.example.com/nzbget/jsonrpc",
+		"http://u:p@nas:6789/jsonrpc?x=1#frag": "http://nas:6789/jsonrpc",
+	} {
+		got, user, pass, err := ParseAPIURL(raw)
+		if err != nil || got != want || user != "u" || pass != "p" {
+			t.Errorf("ParseAPIURL(%q) = %q, %q, %q, %v; want %q", raw, got, user, pass, err, want)
+		}
+	}
+	for _, raw := range []string{"http://nas:6789", "ftp://u:p@nas", "nas:6789"} {
+		if _, _, _, err := ParseAPIURL(raw); err == nil {
+			t.Errorf("ParseAPIURL(%q) accepted", raw)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := mockNZBGet(t)
+	ctx := context.Background()
+	cl := New(srv.Client(), srv.URL+"/jsonrpc", testUser, testPass)
+
+	if v, err := cl.Version(ctx); err != nil || v != "24.3" {
+		t.Errorf("Version() = %q, %v", v, err)
+	}
+	id, err := cl.Append(ctx, "Show.S01E01", []byte(`<?xml version="1.0"?><nzb></nzb>`))
+	if err != nil || id != "7" {
+		t.Fatalf("Append() = %q, %v", id, err)
+	}
+	g, err := cl.Group(ctx, id)
+	if err != nil || g == nil || g.Size() != 100<<20 || g.Progress() != 40 {
+		t.Errorf("Group() = %+v, %v", g, err)
+	}
+	if h, err := cl.History(ctx, id); err != nil || h != nil {
+		t.Errorf("History() of a queued job = %+v, %v; want nil", h, err)
+	}
+	h, err := cl.History(ctx, "8")
+	if err != nil || h == nil || !h.Completed() || h.Size() != 1<<32 || h.Storage() != "/data/dst/movies/Movie" {
+		t.Errorf("History() = %+v, %v", h, err)
+	}
+	if h, err := cl.History(ctx, "9"); err != nil || h == nil || !h.Failed() || h.Storage() != "/data/dst/Broken" {
+		t.Errorf("History() of a failed job = %+v, %v", h, err)
+	}
+	if dir, err := cl.DestDir(ctx); err != nil || dir != "/data/dst" {
+		t.Errorf("DestDir() = %q, %v", dir, err)
+	}
+	if err := cl.Delete(ctx, id); err != nil {
+		t.Errorf("Delete() = %v", err)
+	}
+	if err := cl.Delete(ctx, "SABnzbd_nzo_1"); err == nil {
+		t.Error("Delete() accepted a non-numeric id")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := mockNZBGet(t)
+	ctx := context.Background()
+
+	_, err := New(srv.Client(), srv.URL+"/jsonrpc", testUser, "bad").Version(ctx)
+	var ae *APIError
+	if !errors.As(err, &ae) || !ae.InvalidCredentials() || ae.RateLimited() {
+		t.Errorf("wrong password: %v", err)
+	}
+
+	cl := New(srv.Client(), srv.URL+"/jsonrpc", testUser, testPass)
+	var v any
+	err = cl.call(ctx, "nope", nil, &v)
+	if !errors.As(err, &ae) || ae.InvalidCredentials() || ae.Message != "Invalid procedure" {
+		t.Errorf("failed call: %v", err)
+	}
+}
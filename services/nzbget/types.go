@@ -0,0 +1,90 @@
+package nzbget
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Group is a job still downloading, waiting to, or being unpacked
+type Group struct {
+	NZBID   int64  `json:"NZBID"`
+	NZBName string `json:"NZBName"`
+	// Status is NZBGet's own word for the stage, e.g. "DOWNLOADING"
+	Status      string `json:"Status"`
+	FileSizeLo  uint32 `json:"FileSizeLo"`
+	FileSizeHi  uint32 `json:"FileSizeHi"`
+	RemainingLo uint32 `json:"RemainingSizeLo"`
+	RemainingHi uint32 `json:"RemainingSizeHi"`
+}
+
+// Size is the job's size in bytes
+func (s *Group) Size() int64 {
+	return int64(s.FileSizeHi)<<32 | int64(s.FileSizeLo)
+}
+
+// Progress is the share of the job downloaded, in percent
+func (s *Group) Progress() float64 {
+	size := s.Size()
+	if size == 0 {
+		return 0
+	}
+	remaining := int64(s.RemainingHi)<<32 | int64(s.RemainingLo)
+	return float64(size-remaining) / float64(size) * 100
+}
+
+// HistoryItem is a job NZBGet is done with, successfully or not
+type HistoryItem struct {
+	NZBID int64  `json:"NZBID"`
+	Name  string `json:"Name"`
+	// Status is the outcome and its detail, e.g. "SUCCESS/UNPACK" or
+	// "FAILURE/PAR"
+	Status     string `json:"Status"`
+	FileSizeLo uint32 `json:"FileSizeLo"`
+	FileSizeHi uint32 `json:"FileSizeHi"`
+	// DestDir is where the finished job was saved, on the NZBGet host;
+	// FinalDir is where a post-processing script moved it, if one did
+	DestDir  string `json:"DestDir"`
+	FinalDir string `json:"FinalDir"`
+}
+
+// Size is the job's size in bytes
+func (s *HistoryItem) Size() int64 {
+	return int64(s.FileSizeHi)<<32 | int64(s.FileSizeLo)
+}
+
+// Completed reports whether the job finished and its files are in place. A
+// failed post-processing script leaves the files alone.
+func (s *HistoryItem) Completed() bool {
+	return strings.HasPrefix(s.Status, "SUCCESS/") || s.Status == "WARNING/SCRIPT"
+}
+
+// Failed reports whether NZBGet gave up on the job or it was deleted
+func (s *HistoryItem) Failed() bool {
+	return !s.Completed()
+}
+
+// Storage is where the job's files are now
+func (s *HistoryItem) Storage() string {
+	if s.FinalDir != "" {
+		return s.FinalDir
+	}
+	return s.DestDir
+}
+
+type configOption struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type rpcRequest struct {
+	Method string `json:"method"`
+	Params []any  `json:"params"`
+	ID     int    `json:"id"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
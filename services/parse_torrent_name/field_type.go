@@ -42,5 +42,6 @@ const (
 	FieldTypeSport       FieldType = "sport"
 	FieldTypeCourse      FieldType = "course"
 	FieldTypePPV         FieldType = "ppv"
+	FieldTypeChecksum    FieldType = "checksum"
 	FieldTypeUnknown     FieldType = "unknown"
 )
@@ -234,10 +234,16 @@ var fieldParsers = FieldParsers{
 	// "BD1080p" leaks. Inner capture stays "1080p" so Resolution.Content
 	// is consistent regardless of source prefix; the outer span eats
 	// the "BD"/"UHD" prefix too, keeping it out of Title/Extra.
+	//
+	// Last alternative is the "WIDTHxHEIGHT" form raw-broadcast fansub
+	// groups use ("[Ohys-Raws] Show - 05 (BS11 1280x720 x264 AAC)").
+	// Left unclaimed, Season's `NNx` pattern read "1280x720" as season
+	// 80. Only the height is kept, as "720p".
 	{FieldTypeResolution, NewRegexpMatcher(
 		`(?i)\b((?:BD|UHD|HD)([0-9]{3,4}p|[248][Kk]))\b`,
 		`\b(([0-9]{3,4}p|[248][Kk]))\b`,
-	), NewLowercaseTransformer()},
+		`\b([0-9]{3,4}x([0-9]{3,4}))\b`,
+	), NewResolutionTransformer()},
 	{FieldTypeBitrate, NewRegexpMatcher(`(?i)\b(([0-9]+[KMGT]bps))\b`), nil},
 	// ColorDepth covers SDR/HDR variants plus the "N-bit" / "Nbit"
 	// suffix common on anime encodes ("10bit", "10-bit", "8-bit").
@@ -260,6 +266,11 @@ var fieldParsers = FieldParsers{
 	// alternations first so a "DTS-HD MA" match doesn't get cut
 	// short by the bare "DTS" alternative.
 	{FieldTypeAudio, NewRegexpMatcher(`(?i)\b((DTS[\s.-]?HD(?:[\s.-]?MA)?|TrueHD|Atmos|E[\s.-]?AC3|FLAC|MP3|DDP[\s.]?[57]\.[01]|DDP|DD\+?5\.?1|DD5\.?1|Dual[\- ]Audio|LiNE|DTS|AAC[.-]LC|AAC(?:\.?2\.0)?|AC3(?:(?:[\s-]+)?\.?5\.1)?|[5-9]\.1|[5-9]ch|2CH))\b`), nil},
+	// Anime fansub releases close the name with a CRC32 of the file
+	// ("[SubsPlease] Show - 01 (1080p) [5F1C9A2B].mkv"). Runs before
+	// Studio, whose "[Name YYYY]" form read "[ABCD1234]" as studio
+	// "ABCD". The leading "[Group]" tag is sorted out in Parse.
+	{FieldTypeChecksum, NewRegexpMatcher(`(\[([0-9A-Fa-f]{8})\])`), NewUppercaseTransformer()},
 	{FieldTypeWebsite, NewRegexpMatcher(`^((www\.[a-zA-Z0-9][a-zA-Z0-9-]{1,61}[a-zA-Z0-9]\.[a-zA-Z]{2,}))`, `^(\[ ?([^\]]+?) ?\])`), nil},
 	// Scene-release date. Runs BEFORE Year + Episode so the year-shaped
 	// trailing group in "DD.MM.YYYY" doesn't get split between Year and
@@ -344,9 +355,9 @@ var fieldParsers = FieldParsers{
 	// (ONA/OVA/OAD/NCOP/NCED) so movie titles containing "Special" /
 	// "Movie" / "Trailer" don't false-fire.
 	{FieldTypeKind, NewRegexpMatcher(`(?i)\b((` + kindAlternation + `))\b`), nil},
-	// Episode digit count capped at 3. Anything longer (4+ digits) is
-	// always a year, size, codec tag, or some other false-positive — there
-	// are no real shows with 1000+ episodes packaged into a single torrent.
+	// Episode digit count capped at 3 outside fansub names (see the first
+	// alternative). Anything longer (4+ digits) is otherwise a year, size,
+	// codec tag, or some other false-positive.
 	// Without the cap, "- 1997" / "- 1046" / "- 1080p" off a movie filename
 	// would write into Episode and flip the whole torrent into series.
 	//
@@ -372,7 +383,16 @@ var fieldParsers = FieldParsers{
 	// Allows both space- and dot-separated filenames; requires a Unicode
 	// letter (\p{L}) after the dash so any digit-NN-dash-NN combos (e.g.
 	// "11.10.WS") can't false-fire.
+	//
+	// The first alternative is the fansub form: a name that opens with a
+	// "[Group]" tag numbers episodes across the whole run after a dash
+	// ("[SubsPlease] One Piece - 1071 (1080p)"), so it is the one place
+	// four digits are an episode. A re-release suffix ("- 28v2") is
+	// consumed with it rather than leaking "2" into Extra. Four-digit
+	// years are claimed by Year above, so "[Group] Movie - 2019" stays a
+	// year.
 	{FieldTypeEpisode, NewRegexpMatcher(
+		`^\[[^\]]+\][^\[\]()]*?(-\s+([0-9]{1,4})(?:v[0-9])?)(?:[\s\[(.]|$)`,
 		`(-\s+([0-9]{1,3})(?:[^0-9]|$))`,
 		`(?i)([ex]([0-9]{2,3})(?:[^0-9]|$))`,
 		`(\.([0-9]{2,3})\.(?:19|20)[0-9]{2}\b)`,
@@ -741,6 +761,16 @@ func Parse(tor *TorrentInfo, filename string) (*TorrentInfo, error) {
 		}
 	}
 
+	// Demote a fansub `[Group]` from Website to Group. Anime releases
+	// lead with the group in brackets ("[SubsPlease] Show - 01"), which
+	// the same permissive Website parser claims. A tag with a dot is a
+	// site ("[720pMkv.Com]"); the rest are groups, unless the name
+	// already ends in a scene group or is adult ("[FC2PPV-1311003]").
+	if tor.Group == "" && !tor.Adult && isFansubGroup(tor.Website) {
+		tor.Group = tor.Website
+		tor.Website = ""
+	}
+
 	// Year-from-Date back-fill. When a scene date is extracted (almost
 	// always from adult/dated releases — see FieldTypeDate above) and
 	// no explicit 4-digit year survives in the filename, copy the
@@ -760,6 +790,17 @@ func Parse(tor *TorrentInfo, filename string) (*TorrentInfo, error) {
 	return tor, nil
 }
 
+var fansubGroupRe = regexp.MustCompile(`^[A-Za-z][^.\s]*(?: [^.\s]+)?$`)
+var longNumberRe = regexp.MustCompile(`[0-9]{4}`)
+
+// isFansubGroup reports whether a leading bracket tag reads as a release
+// group: it starts with a letter, has no dot, at most two words and no
+// run of four digits. Digit-led tags ("[1000] Onigashima 20") are episode
+// ranges; long numbers are catalogue codes.
+func isFansubGroup(tag string) bool {
+	return fansubGroupRe.MatchString(tag) && !longNumberRe.MatchString(tag)
+}
+
 func GetFieldParser(fielType FieldType) *FieldParser {
 	for _, p := range fieldParsers {
 		if p.FieldType == fielType {
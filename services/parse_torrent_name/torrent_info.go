@@ -54,6 +54,9 @@ type TorrentInfo struct {
 	//   - "bex_stormy_daniels_kl040518_480p"  → 2018-05-04 (YYMMDD glued)
 	// Two-digit years assume 20YY (adult-scene convention since ~2000).
 	Date string `json:"date,omitempty"`
+	// Checksum is the CRC32 fansub releases put at the end of the file
+	// name ("[SubsPlease] Show - 01 (1080p) [5F1C9A2B].mkv"), upper-cased.
+	Checksum string `json:"checksum,omitempty"`
 	// Extra collects any input bytes that no field parser (nor Title)
 	// claimed — typically content inside `(...)` parens, language tags,
 	// fansub annotations, etc. Bracket characters and pure-separator
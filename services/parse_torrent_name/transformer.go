@@ -66,6 +66,33 @@ func NewLowercaseTransformer() *LowercaseTransformer {
 	return &LowercaseTransformer{}
 }
 
+type UppercaseTransformer struct{}
+
+func (t *UppercaseTransformer) Transform(val string) (string, error) {
+	return strings.ToUpper(val), nil
+}
+
+func NewUppercaseTransformer() *UppercaseTransformer {
+	return &UppercaseTransformer{}
+}
+
+// ResolutionTransformer lowercases a resolution tag ("1080P" → "1080p",
+// "4K" → "4k") and reads a bare frame height — all the "1280x720" form
+// leaves once the width is dropped — as "720p".
+type ResolutionTransformer struct{}
+
+func (t *ResolutionTransformer) Transform(val string) (string, error) {
+	val = strings.ToLower(val)
+	if _, err := strconv.Atoi(val); err == nil {
+		return val + "p", nil
+	}
+	return val, nil
+}
+
+func NewResolutionTransformer() *ResolutionTransformer {
+	return &ResolutionTransformer{}
+}
+
 // MapTransformer rewrites an exact-match input value through a lookup
 // table; values not in the table pass through unchanged. Used for
 // alias / abbreviation normalisation — e.g. the Quality field accepts
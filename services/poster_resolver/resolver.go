@@ -5,8 +5,8 @@
 //   - Library / continue-watching cards (resized JPEG per width)
 //   - Resource share previews (1200x630 OG canvas)
 //
-// Both share resolution (IMDb poster > per-resource thumbnail >
-// brand-default), S3 cache (keyed by source-content), and lazymap dedup
+// Both share resolution (IMDb poster > album cover > per-resource
+// thumbnail > brand-default), S3 cache (keyed by source-content), and lazymap dedup
 // (keyed by request). Cross-resource sharing happens at the S3 layer
 // — two resources matched to the same IMDb work serve the same cached
 // object even though their request URLs differ.
@@ -34,6 +34,7 @@ type SourceKind string
 const (
 	SourceIMDbMovie  SourceKind = "imdb_movie"
 	SourceIMDbSeries SourceKind = "imdb_series"
+	SourceAlbumCover SourceKind = "album_cover"
 	SourceThumbnail  SourceKind = "thumbnail"
 	sourceDefault    SourceKind = "default"
 )
@@ -53,7 +54,8 @@ type Source struct {
 //
 //  1. movie metadata → IMDb poster URL
 //  2. series metadata → IMDb poster URL
-//  3. per-resource thumbnail (image_file / ffmpeg_frame / audio_art)
+//  3. album metadata → Cover Art Archive front cover
+//  4. per-resource thumbnail (image_file / ffmpeg_frame / audio_art)
 //
 // Returns (nil, nil) when nothing usable exists — caller decides what
 // to do (404 for resize, brand-default for OG canvas).
@@ -74,6 +76,13 @@ func Resolve(ctx context.Context, db *pg.DB, thumb *thumbnail.Service, cl *http.
 			return posterSource(SourceIMDbSeries, md.VideoID, md.PosterURL, cl), nil
 		}
 	}
+	if albums, err := models.GetAlbumsByResourceID(ctx, db, resourceID); err == nil {
+		for _, a := range albums {
+			if md := a.AlbumMetadata; md != nil && md.CoverURL != "" && md.MBID != "" {
+				return posterSource(SourceAlbumCover, md.MBID, md.CoverURL, cl), nil
+			}
+		}
+	}
 	if thumb != nil && thumb.Enabled() {
 		t, err := thumb.Get(ctx, resourceID)
 		if err != nil {
@@ -0,0 +1,161 @@
+package premiumize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// APIError is an error reported by the Premiumize API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (code %d): %s", e.StatusCode, e.Message)
+}
+
+// InvalidCredentials reports whether the API key was rejected. Premiumize
+// answers a bad key with a 200 and "Not logged in.", so the message is all
+// there is to go by.
+func (e *APIError) InvalidCredentials() bool {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return true
+	}
+	m := strings.ToLower(e.Message)
+	return strings.Contains(m, "not logged in") || strings.Contains(m, "api key") || strings.Contains(m, "apikey")
+}
+
+// RateLimited reports whether the request was throttled
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		strings.Contains(strings.ToLower(e.Message), "too many requests")
+}
+
+// Client is a Premiumize API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// New creates a new Premiumize API client
+func New(httpClient *http.Client, baseURL, apiKey string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+	}
+}
+
+// GetAccountInfo retrieves the current account's information
+func (s *Client) GetAccountInfo(ctx context.Context) (*AccountInfo, error) {
+	var resp AccountInfo
+	if err := s.get(ctx, "/account/info", nil, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to get account info")
+	}
+	if resp.Status != "success" {
+		return nil, &APIError{StatusCode: http.StatusOK, Message: resp.Message}
+	}
+	return &resp, nil
+}
+
+// CheckCache reports, for each hash, whether Premiumize has it cached
+func (s *Client) CheckCache(ctx context.Context, hashes []string) ([]bool, error) {
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("at least one hash is required")
+	}
+	params := url.Values{}
+	for _, h := range hashes {
+		params.Add("items[]", h)
+	}
+	var resp CacheCheckResponse
+	if err := s.get(ctx, "/cache/check", params, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to check cache")
+	}
+	if resp.Status != "success" {
+		return nil, &APIError{StatusCode: http.StatusOK, Message: resp.Message}
+	}
+	if len(resp.Response) != len(hashes) {
+		return nil, errors.Errorf("cache check answered %d items for %d hashes", len(resp.Response), len(hashes))
+	}
+	return resp.Response, nil
+}
+
+// DirectDL lists the files of cached content with their direct links. The
+// magnet is the upload: nothing is added to the user's transfers.
+func (s *Client) DirectDL(ctx context.Context, magnet string) ([]File, error) {
+	params := url.Values{}
+	params.Set("src", magnet)
+	var resp DirectDLResponse
+	if err := s.post(ctx, "/transfer/directdl", params, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to get direct download links")
+	}
+	if resp.Status != "success" {
+		return nil, &APIError{StatusCode: http.StatusOK, Message: resp.Message}
+	}
+	return resp.Content, nil
+}
+
+// get performs a GET request
+func (s *Client) get(ctx context.Context, path string, params url.Values, v any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("apikey", s.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return s.doRequest(req, v)
+}
+
+// post performs a POST request with a form body
+func (s *Client) post(ctx context.Context, path string, params url.Values, v any) error {
+	q := url.Values{}
+	q.Set("apikey", s.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+path+"?"+q.Encode(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.doRequest(req, v)
+}
+
+// doRequest executes an HTTP request and decodes the response into v
+func (s *Client) doRequest(req *http.Request, v any) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(body, &apiError); err == nil && apiError.Message != "" {
+			return &APIError{StatusCode: resp.StatusCode, Message: apiError.Message}
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package premiumize
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testKey = "good-key"
+
+func mockAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/account/info", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","customer_id":1234,"premium_until":1893456000}`))
+	})
+	mux.HandleFunc("/api/cache/check", func(w http.ResponseWriter, r *http.Request) {
+		items := r.URL.Query()["items[]"]
+		if len(items) != 2 {
+			t.Errorf("items %v", items)
+		}
+		_, _ = w.Write([]byte(`{"status":"success","response":[true,false],"transcoded":[false,false],"filename":["Show",""],"filesize":["210",null]}`))
+	})
+	mux.HandleFunc("/api/transfer/directdl", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("src") != "magnet:?xt=urn:btih:abc" {
+			t.Errorf("src %q", r.PostFormValue("src"))
+		}
+		_, _ = w.Write([]byte(`{"status":"success","content":[
+			{"path":"Show/S01E01.mkv","size":100,"link":"https://cdn.premiumize.me/1"},
+			{"path":"Show/S01E02.mkv","size":110,"link":"https://cdn.premiumize.me/2"}
+		]}`))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != testKey {
+			_, _ = w.Write([]byte(`{"status":"error","message":"Not logged in."}`))
+			return
+		}
+		if r.Header.Get("X-Throttle") != "" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetAccountInfo(t *testing.T) {
+	srv := mockAPI(t)
+	if _, err := New(srv.Client(), srv.URL+"/api", testKey).GetAccountInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := New(srv.Client(), srv.URL+"/api", "bad").GetAccountInfo(context.Background())
+	var ae *APIError
+	if !errors.As(err, &ae) || !ae.InvalidCredentials() || ae.RateLimited() {
+		t.Errorf("bad key: %v", err)
+	}
+}
+
+func TestCheckCacheAndDirectDL(t *testing.T) {
+	srv := mockAPI(t)
+	cl := New(srv.Client(), srv.URL+"/api/", testKey)
+	cached, err := cl.CheckCache(context.Background(), []string{"abc", "def"})
+	if err != nil || len(cached) != 2 || !cached[0] || cached[1] {
+		t.Fatalf("cached %v: %v", cached, err)
+	}
+	files, err := cl.DirectDL(context.Background(), "magnet:?xt=urn:btih:abc")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("files %+v: %v", files, err)
+	}
+	if files[1].Path != "Show/S01E02.mkv" || files[1].Link != "https://cdn.premiumize.me/2" || files[1].Size != 110 {
+		t.Errorf("file %+v", files[1])
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	srv := mockAPI(t)
+	cl := New(&http.Client{Transport: throttle{}}, srv.URL+"/api", testKey)
+	_, err := cl.GetAccountInfo(context.Background())
+	var ae *APIError
+	if !errors.As(err, &ae) || !ae.RateLimited() || ae.InvalidCredentials() {
+		t.Errorf("throttled: %v", err)
+	}
+}
+
+// throttle marks every request so the mock answers it with a 429
+type throttle struct{}
+
+func (throttle) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("X-Throttle", "1")
+	return http.DefaultTransport.RoundTrip(r)
+}
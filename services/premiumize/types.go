@@ -0,0 +1,54 @@
+package premiumize
+
+import "encoding/json"
+
+// AccountInfo represents a Premiumize account
+type AccountInfo struct {
+	Status       string    `json:"status"`
+	Message      string    `json:"message,omitempty"`
+	CustomerID   any       `json:"customer_id"`
+	PremiumUntil Timestamp `json:"premium_until"`
+	// LimitUsed is the share of the fair-use allowance spent, 0 to 1
+	LimitUsed float64 `json:"limit_used"`
+	SpaceUsed float64 `json:"space_used"`
+}
+
+// Timestamp is a Unix time Premiumize sends as a number, or as false when
+// there is none — a free account's premium_until.
+type Timestamp int64
+
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		*t = 0
+		return nil
+	}
+	*t = Timestamp(n)
+	return nil
+}
+
+// CacheCheckResponse represents the response of the cache check endpoint.
+// Every slice holds one entry per requested item, in request order.
+type CacheCheckResponse struct {
+	Status     string   `json:"status"`
+	Message    string   `json:"message,omitempty"`
+	Response   []bool   `json:"response"`
+	Transcoded []bool   `json:"transcoded"`
+	Filename   []string `json:"filename"`
+	Filesize   []any    `json:"filesize"`
+}
+
+// File represents a file of a cached transfer
+type File struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	Link       string `json:"link"`
+	StreamLink string `json:"stream_link,omitempty"`
+}
+
+// DirectDLResponse represents the response of the direct download endpoint
+type DirectDLResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Content []File `json:"content"`
+}
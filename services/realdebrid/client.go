@@ -11,6 +11,32 @@ import (
 	"strings"
 )
 
+// APIError is an error response of the Real-Debrid API
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+	ErrorCode  int
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP error: %d %s", e.StatusCode, e.Status)
+	}
+	return fmt.Sprintf("API error (code %d): %s (error_code: %d)", e.StatusCode, e.Message, e.ErrorCode)
+}
+
+// InvalidCredentials reports whether the token was rejected: 401 is a bad or
+// expired token, 403 a locked account.
+func (e *APIError) InvalidCredentials() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// RateLimited reports whether the request was throttled
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
 // Client represents a Real-Debrid API client
 type Client struct {
 	httpClient *http.Client
@@ -77,6 +103,10 @@ func (c *Client) GetTorrents(ctx context.Context, offset, limit int, activeFirst
 	if err != nil {
 		return nil, err
 	}
+	// An empty list comes back as 204 with no body
+	if len(data) == 0 {
+		return nil, nil
+	}
 	var torrents []TorrentInfo
 	if err := json.Unmarshal(data, &torrents); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal torrents: %w", err)
@@ -250,10 +280,11 @@ func (c *Client) doRequest(req *http.Request) ([]byte, error) {
 			Error     string `json:"error"`
 			ErrorCode int    `json:"error_code,omitempty"`
 		}
+		e := &APIError{StatusCode: resp.StatusCode, Status: resp.Status}
 		if err := json.Unmarshal(body, &apiError); err == nil && apiError.Error != "" {
-			return nil, fmt.Errorf("API error (code %d): %s (error_code: %d)", resp.StatusCode, apiError.Error, apiError.ErrorCode)
+			e.Message, e.ErrorCode = apiError.Error, apiError.ErrorCode
 		}
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+		return nil, e
 	}
 
 	return body, nil
@@ -0,0 +1,115 @@
+package release_subscription
+
+import (
+	"context"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// The delivery pass. pollOne mails an immediate-mode subscription the
+// moment it finds something; everything else — digest accounts, and finds
+// that came in during an account's quiet hours — is left pending and sent
+// from here, one letter per account across all its subscriptions.
+//
+// It reads pending hits rather than this run's batch, because what it owes
+// is not tied to what was polled: a weekly digest collects a week of
+// polls, and a season that completed on Tuesday still has its last finds
+// in Sunday's letter.
+
+// deliver sends every account's held mail that has come due.
+func (p *Poller) deliver(ctx context.Context) {
+	subs, err := p.store.ListPending(ctx)
+	if err != nil {
+		log.WithError(err).Error("failed to list subscriptions with pending releases")
+		return
+	}
+
+	byUser := map[uuid.UUID][]models.ReleaseSubscription{}
+	order := make([]uuid.UUID, 0)
+	for _, sub := range subs {
+		if _, ok := byUser[sub.UserID]; !ok {
+			order = append(order, sub.UserID)
+		}
+		byUser[sub.UserID] = append(byUser[sub.UserID], sub)
+	}
+
+	sent := 0
+	for _, userID := range order {
+		if ctx.Err() != nil {
+			return
+		}
+		ok, err := p.deliverAccount(ctx, userID, byUser[userID], time.Now())
+		if err != nil {
+			log.WithError(err).
+				WithField("user_id", userID).
+				Error("failed to deliver held subscription mail")
+		}
+		if ok {
+			sent++
+		}
+	}
+	if sent > 0 {
+		log.WithField("letters", sent).Info("delivered held subscription mail")
+	}
+}
+
+// deliverAccount sends one account's letter if it is due, and reports
+// whether it sent one.
+func (p *Poller) deliverAccount(ctx context.Context, userID uuid.UUID, subs []models.ReleaseSubscription, now time.Time) (bool, error) {
+	if len(subs) == 0 || subs[0].User == nil || subs[0].User.Email == "" {
+		return false, nil
+	}
+	to := subs[0].User.Email
+
+	mail := p.store.MailSettings(ctx, userID)
+	if mail.InQuietHours(now) {
+		return false, nil
+	}
+
+	if mail.GetSubscriptionDigest() == models.SubscriptionDigestImmediate {
+		// What is pending here was held by quiet hours, or is waiting out
+		// NotifyInterval. The first kind goes out now, in one letter rather
+		// than one per subscription; the second keeps waiting.
+		ready := make([]models.ReleaseSubscription, 0, len(subs))
+		for _, sub := range subs {
+			if sub.IsCompleted() || sub.LastNotifiedAt == nil || now.Sub(*sub.LastNotifiedAt) >= p.cfg.NotifyInterval {
+				ready = append(ready, sub)
+			}
+		}
+		return p.send(ctx, to, ready)
+	}
+
+	if !digestDue(mail, now) {
+		return false, nil
+	}
+	sent, err := p.send(ctx, to, subs)
+	if !sent {
+		return false, err
+	}
+	// Stamped even when marking the hits failed: the letter went out, and
+	// a second one within the period is the thing the user opted out of.
+	if markErr := p.store.MarkDigestSent(ctx, userID, now); markErr != nil && err == nil {
+		err = markErr
+	}
+	return true, err
+}
+
+// digestDue reports whether an account's digest may go out now: at most one
+// per local calendar day for daily, one per seven for weekly. Counting in
+// days rather than hours keeps the letter from drifting later with every
+// run — the first run of the day, outside quiet hours, sends it.
+func digestDue(mail *models.UserSettings, now time.Time) bool {
+	if mail == nil || mail.DigestSentAt == nil {
+		return true
+	}
+	local := now.In(mail.Location())
+	threshold := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	if mail.GetSubscriptionDigest() == models.SubscriptionDigestWeekly {
+		threshold = threshold.AddDate(0, 0, -6)
+	}
+	return mail.DigestSentAt.Before(threshold)
+}
@@ -0,0 +1,216 @@
+package release_subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/stremio"
+)
+
+func digestSettings(mode string) *models.UserSettings {
+	return &models.UserSettings{SubscriptionDigest: &mode}
+}
+
+// quietAround returns settings whose quiet window, in UTC, contains now
+// (inside=true) or lies well clear of it.
+func quietAround(now time.Time, inside bool) *models.UserSettings {
+	h := int16(now.UTC().Hour())
+	start, end := h, (h+2)%24
+	if !inside {
+		start, end = (h+3)%24, (h+5)%24
+	}
+	return &models.UserSettings{QuietHoursStart: &start, QuietHoursEnd: &end}
+}
+
+// TestDigestAccountPollDoesNotMail: on a digest the poll records what it
+// finds and sends nothing — the hits wait, pending, for the account's
+// letter.
+func TestDigestAccountPollDoesNotMail(t *testing.T) {
+	sub := seasonSub()
+	store := &fakeStore{
+		due:          []models.ReleaseSubscription{*sub},
+		episodes:     []models.EpisodeMetadata{episode(5, 2*24*time.Hour)},
+		pending:      []models.ReleaseSubscriptionHit{{SubscriptionID: sub.ID, InfoHash: "aa"}},
+		mailSettings: digestSettings(models.SubscriptionDigestDaily),
+	}
+	search := &fakeSearch{byContentID: map[string][]stremio.StreamItem{
+		"tt1190634:3:5": {{InfoHash: "aa", Title: "The.Boys.S03E05", Name: "Torrentio"}},
+	}}
+	mail := &fakeMailer{}
+	p := NewPoller(store, search, mail, fakeTier{}, fakeAiring{airing: true}, testConfig())
+
+	if err := p.pollOne(context.Background(), sub, false); err != nil {
+		t.Fatalf("pollOne: %v", err)
+	}
+	if len(store.inserted) != 1 {
+		t.Errorf("inserted %d hits, want 1", len(store.inserted))
+	}
+	if len(mail.updates) != 0 || len(store.notifiedHashes) != 0 {
+		t.Errorf("a digest account was mailed from the poll: %d letters, %v marked", len(mail.updates), store.notifiedHashes)
+	}
+}
+
+// TestQuietHoursHoldImmediateMail: an immediate account inside its quiet
+// hours is not mailed either, and a season that ends meanwhile is not
+// closed — its completion notice would be a letter in the quiet window.
+func TestQuietHoursHoldImmediateMail(t *testing.T) {
+	sub := seasonSub()
+	now := time.Now()
+	store := &fakeStore{
+		episodes:     []models.EpisodeMetadata{episode(5, 2*24*time.Hour)},
+		pending:      []models.ReleaseSubscriptionHit{{SubscriptionID: sub.ID, InfoHash: "aa"}},
+		mailSettings: quietAround(now, true),
+	}
+	mail := &fakeMailer{}
+	p := NewPoller(store, &fakeSearch{}, mail, fakeTier{}, fakeAiring{airing: false}, testConfig())
+
+	if err := p.pollOne(context.Background(), sub, false); err != nil {
+		t.Fatalf("pollOne: %v", err)
+	}
+	if len(mail.updates) != 0 || len(mail.offs) != 0 {
+		t.Errorf("quiet hours let mail through: %d updates, %d notices", len(mail.updates), len(mail.offs))
+	}
+	if store.checkedState == models.ReleaseSubscriptionStateCompleted {
+		t.Error("the season was closed inside quiet hours")
+	}
+	if until := store.mailSettings.QuietUntil(now); store.checkedNext.Before(until.Add(-time.Hour)) {
+		t.Errorf("next check %s, want around the end of quiet hours %s", store.checkedNext, until)
+	}
+}
+
+// TestDeliverCombinesSubscriptions is the point of the feature: an account
+// with finds pending on several subscriptions gets one letter with a
+// section for each, and only a second day brings a second letter.
+func TestDeliverCombinesSubscriptions(t *testing.T) {
+	a, b := seasonSub(), seasonSub()
+	b.UserID = a.UserID
+	movie := "Dune"
+	b.Kind, b.Season, b.Title = models.ReleaseSubscriptionKindMovie, nil, &movie
+
+	store := &fakeStore{
+		pendingSubs: []models.ReleaseSubscription{*a, *b},
+		pendingBySub: map[uuid.UUID][]models.ReleaseSubscriptionHit{
+			a.ID: {{SubscriptionID: a.ID, InfoHash: "aa"}, {SubscriptionID: a.ID, InfoHash: "bb"}},
+			b.ID: {{SubscriptionID: b.ID, InfoHash: "cc"}},
+		},
+		mailSettings: digestSettings(models.SubscriptionDigestDaily),
+	}
+	mail := &fakeMailer{}
+	p := NewPoller(store, &fakeSearch{}, mail, fakeTier{}, fakeAiring{}, testConfig())
+
+	p.deliver(context.Background())
+
+	if len(mail.letters) != 1 {
+		t.Fatalf("letters: got %d, want 1", len(mail.letters))
+	}
+	if got := mail.letters[0]; len(got) != 2 || len(got[0].Releases) != 2 || len(got[1].Releases) != 1 {
+		t.Errorf("letter sections: %+v, want two subscriptions with 2 and 1 releases", got)
+	}
+	if len(store.notifiedHashes) != 3 {
+		t.Errorf("marked delivered: %v, want all three", store.notifiedHashes)
+	}
+	if store.digestSentAt == nil {
+		t.Fatal("the digest was not stamped")
+	}
+
+	// Same day, more finds: they wait for tomorrow.
+	store.mailSettings.DigestSentAt = store.digestSentAt
+	p.deliver(context.Background())
+	if len(mail.letters) != 1 {
+		t.Errorf("a daily digest went out twice in one day")
+	}
+}
+
+// TestRunDeliversWhenListingFails: the delivery pass does not depend on the
+// poll, so a run that cannot list due subscriptions still sends what is
+// pending.
+func TestRunDeliversWhenListingFails(t *testing.T) {
+	sub := seasonSub()
+	store := &fakeStore{
+		dueErr:       errors.New("db down"),
+		pendingSubs:  []models.ReleaseSubscription{*sub},
+		pendingBySub: map[uuid.UUID][]models.ReleaseSubscriptionHit{sub.ID: {{SubscriptionID: sub.ID, InfoHash: "aa"}}},
+		mailSettings: digestSettings(models.SubscriptionDigestDaily),
+	}
+	mail := &fakeMailer{}
+	p := NewPoller(store, &fakeSearch{}, mail, fakeTier{}, fakeAiring{}, testConfig())
+
+	if _, err := p.Run(context.Background()); err == nil {
+		t.Fatal("Run hid the listing error")
+	}
+	if len(mail.letters) != 1 || store.digestSentAt == nil {
+		t.Errorf("letters: got %d, want the pending digest sent", len(mail.letters))
+	}
+}
+
+// TestDeliverFlushesQuietHours: for an immediate account the pass sends
+// what quiet hours held once they are over — in one letter — but leaves a
+// subscription that is only waiting out NotifyInterval alone.
+func TestDeliverFlushesQuietHours(t *testing.T) {
+	held, recent := seasonSub(), seasonSub()
+	recent.UserID = held.UserID
+	justNow := time.Now().Add(-time.Hour)
+	recent.LastNotifiedAt = &justNow
+
+	store := &fakeStore{
+		pendingSubs: []models.ReleaseSubscription{*held, *recent},
+		pendingBySub: map[uuid.UUID][]models.ReleaseSubscriptionHit{
+			held.ID:   {{SubscriptionID: held.ID, InfoHash: "aa"}},
+			recent.ID: {{SubscriptionID: recent.ID, InfoHash: "bb"}},
+		},
+		mailSettings: quietAround(time.Now(), true),
+	}
+	mail := &fakeMailer{}
+	p := NewPoller(store, &fakeSearch{}, mail, fakeTier{}, fakeAiring{}, testConfig())
+
+	p.deliver(context.Background())
+	if len(mail.letters) != 0 {
+		t.Fatalf("the pass mailed inside quiet hours")
+	}
+
+	store.mailSettings = quietAround(time.Now(), false)
+	p.deliver(context.Background())
+	if len(mail.letters) != 1 || len(mail.letters[0]) != 1 || mail.letters[0][0].Sub.ID != held.ID {
+		t.Errorf("letters: %+v, want one covering only the held subscription", mail.letters)
+	}
+	if store.digestSentAt != nil {
+		t.Error("an immediate account had a digest stamped")
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo") // UTC+9, no DST
+	if err != nil {
+		t.Skipf("no zone database: %v", err)
+	}
+	zone := "Asia/Tokyo"
+	// 10:00 on a Wednesday, Tokyo time.
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, loc)
+	at := func(d time.Time) *time.Time { return &d }
+
+	for _, tt := range []struct {
+		name string
+		mode string
+		sent *time.Time
+		want bool
+	}{
+		{"never sent", models.SubscriptionDigestDaily, nil, true},
+		{"daily, sent earlier today", models.SubscriptionDigestDaily, at(time.Date(2026, 3, 4, 0, 30, 0, 0, loc)), false},
+		// Less than 24 hours ago, but yesterday: a daily letter must not
+		// drift later by a run every day.
+		{"daily, sent yesterday evening", models.SubscriptionDigestDaily, at(time.Date(2026, 3, 3, 23, 0, 0, 0, loc)), true},
+		{"weekly, sent six days ago", models.SubscriptionDigestWeekly, at(time.Date(2026, 2, 26, 9, 0, 0, 0, loc)), false},
+		{"weekly, sent seven days ago", models.SubscriptionDigestWeekly, at(time.Date(2026, 2, 25, 9, 0, 0, 0, loc)), true},
+	} {
+		mode := tt.mode
+		us := &models.UserSettings{SubscriptionDigest: &mode, TimeZone: &zone, DigestSentAt: tt.sent}
+		if got := digestDue(us, now); got != tt.want {
+			t.Errorf("%s: digestDue = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -49,6 +49,8 @@ type memStore struct {
 	prefResolutions map[uuid.UUID][]string
 	prefLang        map[uuid.UUID]string
 	eps             []models.EpisodeMetadata
+	// settings is user_settings: digest mode, quiet hours, last digest.
+	settings map[uuid.UUID]*models.UserSettings
 }
 
 func newMemStore() *memStore {
@@ -59,6 +61,7 @@ func newMemStore() *memStore {
 		users:           map[uuid.UUID]*models.User{},
 		prefResolutions: map[uuid.UUID][]string{},
 		prefLang:        map[uuid.UUID]string{},
+		settings:        map[uuid.UUID]*models.UserSettings{},
 	}
 }
 
@@ -312,6 +315,43 @@ func (m *memStore) AccountLang(_ context.Context, userID uuid.UUID) string {
 	return m.lang[userID]
 }
 
+func (m *memStore) MailSettings(_ context.Context, userID uuid.UUID) *models.UserSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.settings[userID]
+}
+
+// ListPending is the EXISTS over the hit table: enabled rows with anything
+// not yet notified, completed ones included.
+func (m *memStore) ListPending(context.Context) ([]models.ReleaseSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.ReleaseSubscription
+	for _, s := range m.subs {
+		if !s.Enabled {
+			continue
+		}
+		for _, h := range m.hits[s.ID] {
+			if h.NotifiedAt == nil {
+				row := *s
+				row.User = m.users[s.UserID]
+				out = append(out, row)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) MarkDigestSent(_ context.Context, userID uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if us, ok := m.settings[userID]; ok {
+		us.DigestSentAt = &at
+	}
+	return nil
+}
+
 // due drags a subscription's next check into the past, the way an hour of
 // wall-clock would.
 func (m *memStore) due(id uuid.UUID) {
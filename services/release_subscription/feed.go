@@ -0,0 +1,437 @@
+package release_subscription
+
+// Feed mode. In the default mode every due subscription runs its own
+// targeted searches — up to MaxEpisodes queries against every source of the
+// account — so the requests sent to an indexer grow with the number of
+// subscriptions. Feed mode reads each indexer's latest-releases feed once
+// per run instead, and matches what it finds against all of the account's
+// active subscriptions at once. What an indexer is asked then grows with
+// the number of indexers.
+//
+// A feed is a window, not a history. Reading it covers the time since its
+// oldest item; whatever scrolled out before that is gone. So every
+// indexer keeps where its current unbroken run of reads began
+// (feed_covered_since), and a subscription last checked before that point
+// falls back to the targeted search — as does a subscription whose feed
+// could not be read this run, or one still waiting for its baseline. The
+// targeted path is the same one search mode always runs, so the fallback
+// can only ever cost what search mode costs.
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/enrich"
+	ptn "github.com/webtor-io/web-ui/services/parse_torrent_name"
+	"github.com/webtor-io/web-ui/services/stremio"
+	tn "github.com/webtor-io/web-ui/services/torznab"
+)
+
+// Poll sources. search is the original per-subscription mode and stays the
+// default; rss is feed mode.
+const (
+	PollSourceSearch = "search"
+	PollSourceRSS    = "rss"
+)
+
+// maxFeedDownloads bounds the .torrent downloads one feed read may trigger
+// to learn the infohash of a matching item — the same budget a search has
+// (stremio's maxHashDownloads). Only matching items are ever resolved.
+const maxFeedDownloads = 8
+
+// feedSource is everything feed mode needs from the outside world.
+type feedSource interface {
+	// ListActive returns every enabled, post-baseline subscription with its
+	// owner joined — due or not, because a release is in the feed now.
+	ListActive(ctx context.Context) ([]models.ReleaseSubscription, error)
+	// Indexers returns an account's enabled indexers.
+	Indexers(ctx context.Context, userID uuid.UUID) ([]models.TorznabIndexer, error)
+	// Latest reads an indexer's latest-releases feed, newest first.
+	Latest(ctx context.Context, ix *models.TorznabIndexer) ([]tn.Result, error)
+	// Item resolves a matching result's infohash and labels it as a search
+	// through this indexer would.
+	Item(ctx context.Context, ix *models.TorznabIndexer, r *tn.Result) (stremio.StreamItem, error)
+	// Titles returns the names a subscription's releases may go by: the
+	// title snapshot and the canonical English one.
+	Titles(ctx context.Context, sub *models.ReleaseSubscription) []string
+	// MarkRead records a successful read and where its coverage begins.
+	MarkRead(ctx context.Context, indexerID uuid.UUID, readAt, coveredSince time.Time) error
+}
+
+// addonSearch is the search a covered subscription still runs: addons have
+// no feed and can only be asked. An optional extension of streamSearch — a
+// search without it makes every subscription run the full pipeline.
+type addonSearch interface {
+	SearchAddons(ctx context.Context, u *auth.User, contentType, contentID string) ([]stremio.StreamItem, error)
+}
+
+// WithFeeds switches the poller to feed mode. Without it, or with the
+// search source configured, the poller runs targeted searches only.
+func (p *Poller) WithFeeds(f feedSource) *Poller {
+	p.feeds = f
+	return p
+}
+
+// absoluteNumbering places an episode numbered across a whole anime run
+// ("[SubsPlease] One Piece - 1071") in the series' seasons. The enricher
+// implements it when an AbsoluteEpisodeMapper is configured.
+type absoluteNumbering interface {
+	PlaceAbsoluteEpisode(ctx context.Context, videoID string, title string, episode int) (enrich.EpisodeNumber, bool)
+}
+
+// WithAbsoluteNumbering lets season subscriptions match feed items that
+// name no season but an absolute episode number. Without it such items
+// match nothing, as feed items naming no season never do.
+func (p *Poller) WithAbsoluteNumbering(a absoluteNumbering) *Poller {
+	p.absolute = a
+	return p
+}
+
+// feedMode reports whether this run reads feeds.
+func (p *Poller) feedMode() bool {
+	return p.feeds != nil && p.cfg.Source == PollSourceRSS
+}
+
+// readFeeds reads every indexer of every account with an active
+// subscription once, records what matches, and returns per account the
+// time since which all of its indexers are covered. An account missing from
+// the map is not covered this run.
+func (p *Poller) readFeeds(ctx context.Context) map[uuid.UUID]time.Time {
+	out := map[uuid.UUID]time.Time{}
+	subs, err := p.feeds.ListActive(ctx)
+	if err != nil {
+		log.WithError(err).Error("failed to list active subscriptions for feed mode")
+		return out
+	}
+
+	byUser := map[uuid.UUID][]models.ReleaseSubscription{}
+	for _, sub := range subs {
+		byUser[sub.UserID] = append(byUser[sub.UserID], sub)
+	}
+
+	var mu sync.Mutex
+	sem := make(chan struct{}, p.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for userID, list := range byUser {
+		wg.Add(1)
+		go func(userID uuid.UUID, list []models.ReleaseSubscription) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			since, ok := p.readAccountFeeds(ctx, userID, list)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			out[userID] = since
+			mu.Unlock()
+		}(userID, list)
+	}
+	wg.Wait()
+
+	log.WithField("accounts", len(byUser)).
+		WithField("covered", len(out)).
+		Info("read release feeds")
+	return out
+}
+
+// readAccountFeeds reads one account's indexers in sequence — they may
+// share one Jackett, for the same reason Run keeps an account's searches
+// sequential. The account is covered since the latest coverage start of
+// any of its indexers, and not at all if any one of them could not be read.
+func (p *Poller) readAccountFeeds(ctx context.Context, userID uuid.UUID, subs []models.ReleaseSubscription) (time.Time, bool) {
+	indexers, err := p.feeds.Indexers(ctx, userID)
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Warn("failed to list indexers for feed mode")
+		return time.Time{}, false
+	}
+
+	matchers := make([]*feedMatcher, 0, len(subs))
+	for i := range subs {
+		matchers = append(matchers, newFeedMatcher(&subs[i], p.feeds.Titles(ctx, &subs[i])))
+	}
+
+	var covered time.Time
+	ok := true
+	for i := range indexers {
+		since, read := p.readFeed(ctx, &indexers[i], matchers)
+		if !read {
+			ok = false
+			continue
+		}
+		if since.After(covered) {
+			covered = since
+		}
+	}
+	return covered, ok
+}
+
+// readFeed reads one indexer's feed and records its matches. The bool is
+// false when the read cannot be trusted to have seen everything — a failed
+// read, an indexer with no plain search mode, or a match whose infohash was
+// out of budget — and the indexer's bookkeeping is left alone then, so the
+// next read is measured against the last good one.
+func (p *Poller) readFeed(ctx context.Context, ix *models.TorznabIndexer, matchers []*feedMatcher) (time.Time, bool) {
+	logger := log.WithField("indexer_id", ix.ID).WithField("indexer", ix.GetName())
+	if ix.Caps != nil && len(ix.Caps.SearchParams) == 0 {
+		// No t=search, so no feed to read. Caps from an indexer that
+		// never answered a probe are nil, and those get a try.
+		logger.Debug("indexer has no search mode to read a feed from")
+		return time.Time{}, false
+	}
+
+	now := time.Now()
+	results, err := p.feeds.Latest(ctx, ix)
+	if err != nil {
+		logger.WithError(err).Warn("failed to read indexer feed")
+		return time.Time{}, false
+	}
+
+	coveredSince := ix.FeedCoveredSince
+	if ix.FeedReadAt == nil || coveredSince == nil || feedHasGap(results, *ix.FeedReadAt) {
+		// First read, or the window no longer reaches back to the last one:
+		// coverage starts over at the oldest release this page holds.
+		start := oldestPublished(results, now)
+		coveredSince = &start
+	}
+
+	hits, complete := p.matchFeed(ctx, ix, results, matchers)
+	if len(hits) > 0 {
+		if _, err := p.store.InsertHits(ctx, hits, false); err != nil {
+			logger.WithError(err).Warn("failed to record feed hits")
+			return time.Time{}, false
+		}
+	}
+	if !complete {
+		logger.Info("feed matches exceeded the download budget; leaving the read unconfirmed")
+		return time.Time{}, false
+	}
+	if err := p.feeds.MarkRead(ctx, ix.ID, now, *coveredSince); err != nil {
+		logger.WithError(err).Warn("failed to record feed read")
+		return time.Time{}, false
+	}
+	return *coveredSince, true
+}
+
+// matchFeed turns the feed items that match a subscription into hit rows.
+// The bool is false when a matching item could not be resolved for lack of
+// download budget — a release that exists but was not recorded.
+func (p *Poller) matchFeed(ctx context.Context, ix *models.TorznabIndexer, results []tn.Result, matchers []*feedMatcher) ([]models.ReleaseSubscriptionHit, bool) {
+	var out []models.ReleaseSubscriptionHit
+	downloads := 0
+	complete := true
+	placed := map[absoluteKey]*enrich.EpisodeNumber{}
+	for i := range results {
+		r := &results[i]
+		ti := parseRelease(r.Title)
+		var (
+			item     stremio.StreamItem
+			resolved bool
+		)
+		for _, m := range matchers {
+			mti := p.placeAbsolute(ctx, m, r, ti, placed)
+			if !m.matches(r, mti) {
+				continue
+			}
+			if !resolved {
+				if tn.NeedsDownload(r) {
+					if downloads >= maxFeedDownloads {
+						complete = false
+						break
+					}
+					downloads++
+				}
+				it, err := p.feeds.Item(ctx, ix, r)
+				if err != nil {
+					// Same as a search: a result nothing can address is
+					// dropped rather than recorded as a dead row.
+					log.WithError(err).
+						WithField("indexer", ix.GetName()).
+						WithField("title", r.Title).
+						Debug("failed to resolve infohash for feed item")
+					break
+				}
+				item, resolved = it, true
+			}
+			if hit, ok := feedHit(m.sub, item, mti); ok {
+				out = append(out, hit)
+			}
+		}
+	}
+	return out, complete
+}
+
+// absoluteKey is one absolute episode of one series
+type absoluteKey struct {
+	videoID string
+	episode int
+}
+
+// placeAbsolute reads a release that names no season but an episode as the
+// season and episode it is in the subscription's series, when the release
+// is of that series at all. Anything else is returned as parsed. Answers,
+// misses included, are kept in placed for the rest of the feed read.
+func (p *Poller) placeAbsolute(ctx context.Context, m *feedMatcher, r *tn.Result, ti *ptn.TorrentInfo, placed map[absoluteKey]*enrich.EpisodeNumber) *ptn.TorrentInfo {
+	if p.absolute == nil || !m.sub.IsSeason() || r.Season > 0 || ti.Season > 0 || ti.Episode <= 0 || !m.identifies(r, ti) {
+		return ti
+	}
+	k := absoluteKey{videoID: m.sub.VideoID, episode: ti.Episode}
+	n, ok := placed[k]
+	if !ok {
+		if en, found := p.absolute.PlaceAbsoluteEpisode(ctx, m.sub.VideoID, ti.Title, ti.Episode); found {
+			n = &en
+		}
+		placed[k] = n
+	}
+	if n == nil {
+		return ti
+	}
+	out := *ti
+	out.Season, out.Episode = n.Season, n.Episode
+	return &out
+}
+
+// feedHit builds the hit row for a matched item, applying the same checks
+// collect does on search results.
+func feedHit(sub *models.ReleaseSubscription, item stremio.StreamItem, ti *ptn.TorrentInfo) (models.ReleaseSubscriptionHit, bool) {
+	hash := strings.ToLower(strings.TrimSpace(item.InfoHash))
+	if hash == "" || len(hash) > 40 || !matchesPreferences(item, sub) {
+		return models.ReleaseSubscriptionHit{}, false
+	}
+	hit := models.ReleaseSubscriptionHit{
+		SubscriptionID: sub.ID,
+		InfoHash:       hash,
+		Season:         sub.Season,
+	}
+	if name := releaseName(item); name != "" {
+		hit.Name = &name
+	}
+	if src := sourceName(item); src != "" {
+		hit.SourceName = &src
+	}
+	if sub.IsSeason() && ti.Episode > 0 {
+		ep := int16(ti.Episode)
+		hit.Episode = &ep
+	}
+	return hit, true
+}
+
+// feedHasGap reports whether a feed page fails to reach back to the last
+// read. A page with no dates at all cannot show that it does, and is read
+// as a gap; an empty page has nothing to miss.
+func feedHasGap(results []tn.Result, lastRead time.Time) bool {
+	if len(results) == 0 {
+		return false
+	}
+	oldest := oldestPublished(results, time.Time{})
+	if oldest.IsZero() {
+		return true
+	}
+	return oldest.After(lastRead)
+}
+
+// oldestPublished returns the earliest publish date in a page, or def when
+// no item carries one.
+func oldestPublished(results []tn.Result, def time.Time) time.Time {
+	var oldest time.Time
+	for _, r := range results {
+		if r.PublishDate.IsZero() {
+			continue
+		}
+		if oldest.IsZero() || r.PublishDate.Before(oldest) {
+			oldest = r.PublishDate
+		}
+	}
+	if oldest.IsZero() {
+		return def
+	}
+	return oldest
+}
+
+// parseRelease reads a release title with the shared name parser. A parse
+// failure is an empty reading, not an error: the IMDB id may still match.
+func parseRelease(title string) *ptn.TorrentInfo {
+	ti, err := ptn.Parse(&ptn.TorrentInfo{}, title)
+	if err != nil || ti == nil {
+		return &ptn.TorrentInfo{}
+	}
+	return ti
+}
+
+// feedMatcher decides whether a feed item is a release of one subscription.
+type feedMatcher struct {
+	sub    *models.ReleaseSubscription
+	titles map[string]bool
+}
+
+func newFeedMatcher(sub *models.ReleaseSubscription, titles []string) *feedMatcher {
+	m := &feedMatcher{sub: sub, titles: map[string]bool{}}
+	for _, t := range titles {
+		if k := tn.TitleKey(t); k != "" {
+			m.titles[k] = true
+		}
+	}
+	return m
+}
+
+// matches checks identity first and season second.
+//
+// Identity is the IMDB id when the feed carries one — exact, and it holds
+// across languages — and the parsed title otherwise. An id that disagrees
+// is final: a title match cannot overrule it.
+//
+// A feed item was not asked about this show, so unlike a search result it
+// earns nothing by naming no season: a season subscription needs the
+// release to name its season, and a movie subscription rejects anything
+// that names an episode.
+func (m *feedMatcher) matches(r *tn.Result, ti *ptn.TorrentInfo) bool {
+	if !m.identifies(r, ti) {
+		return false
+	}
+
+	if !m.sub.IsSeason() {
+		return r.Season == 0 && r.Episode == 0 && ti.Season == 0 && ti.Episode == 0
+	}
+	season := m.sub.GetSeason()
+	switch {
+	case r.Season > 0:
+		return int(r.Season) == season
+	case ti.Season > 0:
+		return ti.Season == season
+	}
+	return stremio.NamesSeason(r.Title, season)
+}
+
+// identifies checks the identity half of matches.
+func (m *feedMatcher) identifies(r *tn.Result, ti *ptn.TorrentInfo) bool {
+	if !r.PublishDate.IsZero() && r.PublishDate.Before(m.sub.CreatedAt) {
+		// Older than the subscription, so the baseline could have seen it.
+		// Recording it now would mail it as new.
+		return false
+	}
+	if id := strings.TrimSpace(r.IMDBID); id != "" {
+		return sameIMDBID(id, m.sub.VideoID)
+	}
+	return tn.NamesOneOf(ti.Title, m.titles)
+}
+
+// sameIMDBID compares two IMDB ids however each side spells them: feeds send
+// the bare number, sometimes without leading zeros; subscriptions store tt…
+func sameIMDBID(a, b string) bool {
+	norm := func(s string) string {
+		s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "tt")
+		return strings.TrimLeft(s, "0")
+	}
+	na, nb := norm(a), norm(b)
+	return na != "" && na == nb
+}
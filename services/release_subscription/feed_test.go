@@ -0,0 +1,385 @@
+package release_subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/enrich"
+	"github.com/webtor-io/web-ui/services/stremio"
+	tn "github.com/webtor-io/web-ui/services/torznab"
+)
+
+// --- fakes ---
+
+type fakeFeeds struct {
+	active   []models.ReleaseSubscription
+	indexers []models.TorznabIndexer
+	results  []tn.Result
+	err      error
+	titles   []string
+
+	reads    int
+	resolved int
+	marked   map[uuid.UUID][2]time.Time
+}
+
+func (f *fakeFeeds) ListActive(context.Context) ([]models.ReleaseSubscription, error) {
+	return f.active, nil
+}
+
+func (f *fakeFeeds) Indexers(context.Context, uuid.UUID) ([]models.TorznabIndexer, error) {
+	return f.indexers, nil
+}
+
+func (f *fakeFeeds) Latest(context.Context, *models.TorznabIndexer) ([]tn.Result, error) {
+	f.reads++
+	return f.results, f.err
+}
+
+func (f *fakeFeeds) Item(_ context.Context, _ *models.TorznabIndexer, r *tn.Result) (stremio.StreamItem, error) {
+	f.resolved++
+	return stremio.StreamItem{Name: "Tracker\n1080p", Title: r.Title, InfoHash: r.InfoHash}, nil
+}
+
+func (f *fakeFeeds) Titles(_ context.Context, sub *models.ReleaseSubscription) []string {
+	return append([]string{sub.GetTitle()}, f.titles...)
+}
+
+func (f *fakeFeeds) MarkRead(_ context.Context, id uuid.UUID, readAt, coveredSince time.Time) error {
+	if f.marked == nil {
+		f.marked = map[uuid.UUID][2]time.Time{}
+	}
+	f.marked[id] = [2]time.Time{readAt, coveredSince}
+	return nil
+}
+
+// fakeAbsolute places absolute episodes the way Attack on Titan's seasons
+// run: 25, 12 and 22 episodes, then season 4.
+type fakeAbsolute struct {
+	asked []int
+}
+
+func (f *fakeAbsolute) PlaceAbsoluteEpisode(_ context.Context, _ string, _ string, episode int) (enrich.EpisodeNumber, bool) {
+	f.asked = append(f.asked, episode)
+	for i, n := range []int{25, 12, 22} {
+		if episode <= n {
+			return enrich.EpisodeNumber{Season: i + 1, Episode: episode}, true
+		}
+		episode -= n
+	}
+	return enrich.EpisodeNumber{Season: 4, Episode: episode}, true
+}
+
+// addonOnlySearch records which half of the pipeline each query went to.
+type addonOnlySearch struct {
+	fakeSearch
+	addonAsked []string
+	addonErr   error
+}
+
+func (s *addonOnlySearch) SearchAddons(_ context.Context, _ *auth.User, _, contentID string) ([]stremio.StreamItem, error) {
+	s.addonAsked = append(s.addonAsked, contentID)
+	return nil, s.addonErr
+}
+
+// --- helpers ---
+
+func feedConfig() PollConfig {
+	cfg := testConfig()
+	cfg.Source = PollSourceRSS
+	return cfg
+}
+
+func feedIndexer(readAgo, coveredAgo time.Duration) models.TorznabIndexer {
+	ix := models.TorznabIndexer{ID: uuid.NewV4()}
+	if readAgo > 0 {
+		read := time.Now().Add(-readAgo)
+		covered := time.Now().Add(-coveredAgo)
+		ix.FeedReadAt = &read
+		ix.FeedCoveredSince = &covered
+	}
+	return ix
+}
+
+func feedResult(title, hash string, ago time.Duration) tn.Result {
+	return tn.Result{Title: title, InfoHash: hash, PublishDate: time.Now().Add(-ago)}
+}
+
+func checkedAgo(sub *models.ReleaseSubscription, d time.Duration) *models.ReleaseSubscription {
+	at := time.Now().Add(-d)
+	sub.LastCheckedAt = &at
+	return sub
+}
+
+// --- tests ---
+
+// TestFeedMatchesBySeason pins what a feed item has to say to count for a
+// season subscription: the show, by id or by name, and that season by
+// name. Unlike a search result, an item that names no season is not given
+// the benefit of the doubt — nobody asked the indexer about this show.
+func TestFeedMatchesBySeason(t *testing.T) {
+	sub := seasonSub()
+	m := newFeedMatcher(sub, []string{"The Boys"})
+
+	cases := []struct {
+		name string
+		r    tn.Result
+		want bool
+	}{
+		{"episode by title", tn.Result{Title: "The.Boys.S03E04.1080p.WEB.h264"}, true},
+		{"season pack by title", tn.Result{Title: "The Boys S03 Complete 2160p"}, true},
+		{"other season", tn.Result{Title: "The.Boys.S02E04.1080p.WEB.h264"}, false},
+		{"no season named", tn.Result{Title: "The Boys 1080p"}, false},
+		{"other show", tn.Result{Title: "The.Boys.Presents.Diabolical.S01E01.1080p"}, false},
+		{"imdb id beats a foreign title", tn.Result{Title: "Пацаны S03E01", IMDBID: "1190634"}, true},
+		{"imdb id overrules the title", tn.Result{Title: "The.Boys.S03E01.1080p", IMDBID: "0903747"}, false},
+		{"feed season field", tn.Result{Title: "Пацаны 3 сезон", IMDBID: "tt1190634", Season: 3}, true},
+		{"older than the subscription", tn.Result{Title: "The.Boys.S03E04.1080p", PublishDate: sub.CreatedAt.Add(-time.Hour)}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := m.matches(&c.r, parseRelease(c.r.Title)); got != c.want {
+				t.Fatalf("matches(%q) = %v, want %v", c.r.Title, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFeedMatchesMovie(t *testing.T) {
+	sub := seasonSub()
+	sub.Kind = models.ReleaseSubscriptionKindMovie
+	sub.Season = nil
+	m := newFeedMatcher(sub, []string{"Oppenheimer"})
+
+	if !m.matches(&tn.Result{Title: "Oppenheimer.2023.2160p.UHD"}, parseRelease("Oppenheimer.2023.2160p.UHD")) {
+		t.Fatal("the film itself must match")
+	}
+	if m.matches(&tn.Result{Title: "Oppenheimer.S01E01.720p"}, parseRelease("Oppenheimer.S01E01.720p")) {
+		t.Fatal("an episode of a namesake series must not match a film")
+	}
+}
+
+// TestFeedAbsoluteEpisodes: a fansub release numbered across the whole run
+// names no season, and still counts for the season its number falls in.
+func TestFeedAbsoluteEpisodes(t *testing.T) {
+	sub := seasonSub()
+	season := int16(4)
+	title := "Attack on Titan"
+	sub.VideoID, sub.Season, sub.Title = "tt2560140", &season, &title
+	abs := &fakeAbsolute{}
+	p := NewPoller(&fakeStore{}, &fakeSearch{}, &fakeMailer{}, fakeTier{}, fakeAiring{}, feedConfig()).
+		WithFeeds(&fakeFeeds{}).
+		WithAbsoluteNumbering(abs)
+	m := newFeedMatcher(sub, []string{"Attack on Titan", "Shingeki no Kyojin"})
+
+	results := []tn.Result{
+		feedResult("[SubsPlease] Shingeki no Kyojin - 65 (1080p) [4E1F2A3B].mkv", "aaaa", time.Minute),
+		feedResult("[SubsPlease] Shingeki no Kyojin - 65 (720p) [9C0D1E2F].mkv", "bbbb", time.Minute),
+		feedResult("[SubsPlease] Shingeki no Kyojin - 12 (1080p) [5A6B7C8D].mkv", "cccc", time.Minute),
+		feedResult("[SubsPlease] Kimetsu no Yaiba - 65 (1080p) [1A2B3C4D].mkv", "dddd", time.Minute),
+	}
+	ix := feedIndexer(0, 0)
+	hits, _ := p.matchFeed(context.Background(), &ix, results, []*feedMatcher{m})
+	if len(hits) != 2 {
+		t.Fatalf("got %d hits, want both releases of episode 65", len(hits))
+	}
+	for _, h := range hits {
+		if h.Episode == nil || *h.Episode != 6 {
+			t.Fatalf("hit %s: episode %v, want S04E06", h.InfoHash, h.Episode)
+		}
+	}
+	if len(abs.asked) != 2 {
+		t.Fatalf("asked to place %v, want 65 once and 12 — never another show", abs.asked)
+	}
+}
+
+// TestFeedGap pins the coverage rule: a page whose oldest item is newer than
+// the last read has lost whatever fell between them.
+func TestFeedGap(t *testing.T) {
+	lastRead := time.Now().Add(-2 * time.Hour)
+	reaches := []tn.Result{feedResult("a", "", time.Hour), feedResult("b", "", 3*time.Hour)}
+	if feedHasGap(reaches, lastRead) {
+		t.Fatal("a page reaching back past the last read has no gap")
+	}
+	short := []tn.Result{feedResult("a", "", time.Hour)}
+	if !feedHasGap(short, lastRead) {
+		t.Fatal("a page that stops after the last read has a gap")
+	}
+	if !feedHasGap([]tn.Result{{Title: "undated"}}, lastRead) {
+		t.Fatal("an undated page cannot prove it has no gap")
+	}
+	if feedHasGap(nil, lastRead) {
+		t.Fatal("an empty page has nothing to miss")
+	}
+}
+
+// TestRunFeedModeCoveredSkipsIndexerSearch is the point of feed mode: a
+// subscription whose indexers were read as feeds since its last check asks
+// only the addons, and what the feed held is recorded as new.
+func TestRunFeedModeCoveredSkipsIndexerSearch(t *testing.T) {
+	sub := checkedAgo(seasonSub(), time.Hour)
+	store := &fakeStore{due: []models.ReleaseSubscription{*sub}}
+	search := &addonOnlySearch{addonErr: ErrNoSources}
+	ix := feedIndexer(2*time.Hour, 48*time.Hour)
+	feeds := &fakeFeeds{
+		active:   []models.ReleaseSubscription{*sub},
+		indexers: []models.TorznabIndexer{ix},
+		results: []tn.Result{
+			feedResult("The.Boys.S03E05.1080p.WEB.h264", "aaaa", 30*time.Minute),
+			feedResult("Some.Other.Show.S01E01.720p", "bbbb", 90*time.Minute),
+			feedResult("The.Boys.S02E01.1080p", "cccc", 3*time.Hour),
+		},
+	}
+	p := NewPoller(store, search, &fakeMailer{}, fakeTier{}, fakeAiring{airing: true}, feedConfig()).WithFeeds(feeds)
+
+	if _, err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(search.asked) != 0 {
+		t.Fatalf("a covered subscription must not search its indexers, asked %v", search.asked)
+	}
+	if len(search.addonAsked) == 0 {
+		t.Fatal("addons have no feed and must still be asked")
+	}
+	if len(store.inserted) != 1 || store.inserted[0].InfoHash != "aaaa" {
+		t.Fatalf("inserted %+v, want only the S03 episode", store.inserted)
+	}
+	if store.insertBaseline {
+		t.Fatal("feed hits are news, not baseline")
+	}
+	if e := store.inserted[0].Episode; e == nil || *e != 5 {
+		t.Fatalf("episode = %v, want 5", e)
+	}
+	mark, ok := feeds.marked[ix.ID]
+	if !ok {
+		t.Fatal("a good read must be recorded")
+	}
+	if !mark[1].Equal(*ix.FeedCoveredSince) {
+		t.Fatal("a read without a gap must keep the coverage start")
+	}
+}
+
+// TestRunFeedModeGapFallsBackToSearch: when the window no longer reaches the
+// last read, coverage starts over, and a subscription checked before the new
+// start runs the full search it would have run without feeds.
+func TestRunFeedModeGapFallsBackToSearch(t *testing.T) {
+	sub := checkedAgo(seasonSub(), 6*time.Hour)
+	store := &fakeStore{due: []models.ReleaseSubscription{*sub}}
+	search := &addonOnlySearch{}
+	ix := feedIndexer(5*time.Hour, 48*time.Hour)
+	feeds := &fakeFeeds{
+		active:   []models.ReleaseSubscription{*sub},
+		indexers: []models.TorznabIndexer{ix},
+		results:  []tn.Result{feedResult("Some.Other.Show.S01E01.720p", "bbbb", time.Hour)},
+	}
+	p := NewPoller(store, search, &fakeMailer{}, fakeTier{}, fakeAiring{airing: true}, feedConfig()).WithFeeds(feeds)
+
+	if _, err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(search.asked) == 0 {
+		t.Fatal("a gap must fall back to the targeted search")
+	}
+	if len(search.addonAsked) != 0 {
+		t.Fatal("the fallback is the full pipeline, not addons only")
+	}
+	if mark := feeds.marked[ix.ID]; !mark[1].After(*ix.FeedCoveredSince) {
+		t.Fatal("a gap must restart coverage at the page's oldest item")
+	}
+}
+
+// TestRunFeedModeFailedReadFallsBackToSearch: an indexer that could not be
+// read leaves its account uncovered and its bookkeeping untouched.
+func TestRunFeedModeFailedReadFallsBackToSearch(t *testing.T) {
+	sub := checkedAgo(seasonSub(), time.Hour)
+	store := &fakeStore{due: []models.ReleaseSubscription{*sub}}
+	search := &addonOnlySearch{}
+	feeds := &fakeFeeds{
+		active:   []models.ReleaseSubscription{*sub},
+		indexers: []models.TorznabIndexer{feedIndexer(2*time.Hour, 48*time.Hour)},
+		err:      context.DeadlineExceeded,
+	}
+	p := NewPoller(store, search, &fakeMailer{}, fakeTier{}, fakeAiring{airing: true}, feedConfig()).WithFeeds(feeds)
+
+	if _, err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(search.asked) == 0 {
+		t.Fatal("an unread feed must fall back to the targeted search")
+	}
+	if len(feeds.marked) != 0 {
+		t.Fatal("a failed read must not be recorded")
+	}
+}
+
+// TestRunFeedModeBaselineStillSearches: a baseline needs what exists, which
+// no feed shows.
+func TestRunFeedModeBaselineStillSearches(t *testing.T) {
+	sub := seasonSub()
+	sub.State = models.ReleaseSubscriptionStatePendingBaseline
+	store := &fakeStore{due: []models.ReleaseSubscription{*sub}}
+	search := &addonOnlySearch{}
+	feeds := &fakeFeeds{indexers: []models.TorznabIndexer{feedIndexer(0, 0)}}
+	p := NewPoller(store, search, &fakeMailer{}, fakeTier{}, fakeAiring{airing: true}, feedConfig()).WithFeeds(feeds)
+
+	if _, err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(search.asked) == 0 {
+		t.Fatal("a pending baseline must run the full search")
+	}
+}
+
+// TestRunSearchModeIgnoresFeeds: the flag, not the wiring, picks the mode.
+func TestRunSearchModeIgnoresFeeds(t *testing.T) {
+	sub := checkedAgo(seasonSub(), time.Hour)
+	store := &fakeStore{due: []models.ReleaseSubscription{*sub}}
+	feeds := &fakeFeeds{active: []models.ReleaseSubscription{*sub}}
+	p := NewPoller(store, &fakeSearch{}, &fakeMailer{}, fakeTier{}, fakeAiring{airing: true}, testConfig()).WithFeeds(feeds)
+
+	if _, err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if feeds.reads != 0 {
+		t.Fatal("search mode must not read feeds")
+	}
+}
+
+// TestFeedDownloadBudget: matches that need a .torrent download to learn
+// their infohash are capped per read, and a read that hit the cap is not
+// trusted to have recorded everything.
+func TestFeedDownloadBudget(t *testing.T) {
+	sub := checkedAgo(seasonSub(), time.Hour)
+	var results []tn.Result
+	for i := 0; i < maxFeedDownloads+2; i++ {
+		results = append(results, tn.Result{
+			Title:       "The.Boys.S03E01.1080p",
+			Link:        "https://tracker.example/dl/" + uuid.NewV4().String(),
+			PublishDate: time.Now().Add(-time.Duration(i+1) * time.Minute),
+		})
+	}
+	feeds := &fakeFeeds{results: results}
+	p := NewPoller(&fakeStore{}, &fakeSearch{}, &fakeMailer{}, fakeTier{}, fakeAiring{}, feedConfig()).WithFeeds(feeds)
+
+	ix := feedIndexer(2*time.Hour, 48*time.Hour)
+	if _, ok := p.readFeed(context.Background(), &ix, []*feedMatcher{newFeedMatcher(sub, []string{"The Boys"})}); ok {
+		t.Fatal("a read over the download budget must not count as covered")
+	}
+	if feeds.resolved != maxFeedDownloads {
+		t.Fatalf("resolved %d items, want the budget of %d", feeds.resolved, maxFeedDownloads)
+	}
+	if len(feeds.marked) != 0 {
+		t.Fatal("an incomplete read must not be recorded")
+	}
+}
+
+func TestSameIMDBID(t *testing.T) {
+	if !sameIMDBID("tt0903747", "903747") || sameIMDBID("tt0903747", "tt1190634") || sameIMDBID("", "") {
+		t.Fatal("imdb ids must compare by number")
+	}
+}
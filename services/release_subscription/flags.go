@@ -18,6 +18,7 @@ const (
 	IntervalFreeFlag    = "subscription-interval-free"
 	IntervalMaxFlag     = "subscription-interval-max"
 	HotWindowFlag       = "subscription-hot-window"
+	PollSourceFlag      = "subscription-poll-source"
 )
 
 // RegisterPollFlags declares the poller's scheduling policy.
@@ -82,6 +83,12 @@ func RegisterPollFlags(f []cli.Flag) []cli.Flag {
 			Value:  72 * time.Hour,
 			EnvVar: "SUBSCRIPTION_HOT_WINDOW",
 		},
+		cli.StringFlag{
+			Name:   PollSourceFlag,
+			Usage:  "how indexers are asked: search (targeted searches per subscription) or rss (each indexer's latest-releases feed once per run, searching only to fill gaps)",
+			Value:  PollSourceSearch,
+			EnvVar: "SUBSCRIPTION_POLL_SOURCE",
+		},
 	)
 }
 
@@ -99,6 +106,7 @@ func NewPollConfig(c *cli.Context) PollConfig {
 		IntervalFree:   c.Duration(IntervalFreeFlag),
 		IntervalMax:    c.Duration(IntervalMaxFlag),
 		HotWindow:      c.Duration(HotWindowFlag),
+		Source:         c.String(PollSourceFlag),
 		Domain:         c.String(common.DomainFlag),
 		Secret:         c.String(common.SessionSecretFlag),
 	}
@@ -6,13 +6,17 @@ package release_subscription
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
+	cs "github.com/webtor-io/common-services"
 
+	"github.com/webtor-io/web-ui/models"
 	"github.com/webtor-io/web-ui/services/auth"
 	"github.com/webtor-io/web-ui/services/claims"
 	"github.com/webtor-io/web-ui/services/stremio"
+	tn "github.com/webtor-io/web-ui/services/torznab"
 )
 
 // BuilderSearch runs the user's own stream pipeline.
@@ -21,13 +25,18 @@ import (
 // subscriptions all query the same addons and indexers, and rebuilding the
 // pipeline for each one would re-read the same two tables every time.
 type BuilderSearch struct {
-	b     *stremio.Builder
-	mu    sync.Mutex
-	cache map[uuid.UUID]stremio.StreamsService
+	b      *stremio.Builder
+	mu     sync.Mutex
+	cache  map[uuid.UUID]stremio.StreamsService
+	addons map[uuid.UUID]stremio.StreamsService
 }
 
 func NewBuilderSearch(b *stremio.Builder) *BuilderSearch {
-	return &BuilderSearch{b: b, cache: map[uuid.UUID]stremio.StreamsService{}}
+	return &BuilderSearch{
+		b:      b,
+		cache:  map[uuid.UUID]stremio.StreamsService{},
+		addons: map[uuid.UUID]stremio.StreamsService{},
+	}
 }
 
 // ErrNoSources means the account has no addons and no indexers to ask. The
@@ -41,6 +50,21 @@ func (s *BuilderSearch) Search(ctx context.Context, u *auth.User, contentType, c
 	if err != nil {
 		return nil, err
 	}
+	return streams(ctx, svc, contentType, contentID)
+}
+
+// SearchAddons runs the addon half of the pipeline only — what a
+// subscription whose indexers are covered by feed mode still has to ask.
+// An account without addons answers ErrNoSources, same as Search.
+func (s *BuilderSearch) SearchAddons(ctx context.Context, u *auth.User, contentType, contentID string) ([]stremio.StreamItem, error) {
+	svc, err := s.addonServiceFor(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return streams(ctx, svc, contentType, contentID)
+}
+
+func streams(ctx context.Context, svc stremio.StreamsService, contentType, contentID string) ([]stremio.StreamItem, error) {
 	if svc == nil {
 		return nil, nil
 	}
@@ -80,6 +104,103 @@ func (s *BuilderSearch) serviceFor(ctx context.Context, u *auth.User) (stremio.S
 	return svc, nil
 }
 
+func (s *BuilderSearch) addonServiceFor(ctx context.Context, u *auth.User) (stremio.StreamsService, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if svc, ok := s.addons[u.ID]; ok {
+		return svc, nil
+	}
+	svc, err := s.b.BuildPollAddonStreamsService(ctx, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build poll addon streams service")
+	}
+	s.addons[u.ID] = svc
+	return svc, nil
+}
+
+// TorznabFeeds reads indexer feeds for feed mode, through the same client
+// and with the same labels as the stream pipeline's indexer search.
+type TorznabFeeds struct {
+	pg     *cs.PG
+	cl     *tn.Client
+	titles tn.TitleResolver
+}
+
+func NewTorznabFeeds(pg *cs.PG, cl *tn.Client, titles tn.TitleResolver) *TorznabFeeds {
+	return &TorznabFeeds{pg: pg, cl: cl, titles: titles}
+}
+
+func (s *TorznabFeeds) ListActive(ctx context.Context) ([]models.ReleaseSubscription, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	return models.ListActiveReleaseSubscriptions(ctx, db)
+}
+
+func (s *TorznabFeeds) Indexers(ctx context.Context, userID uuid.UUID) ([]models.TorznabIndexer, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	return models.GetUserTorznabIndexers(ctx, db, userID)
+}
+
+// Latest asks for films and series together, narrowed to the two video
+// categories when the indexer advertises them — one request per indexer,
+// whatever its subscriptions follow.
+func (s *TorznabFeeds) Latest(ctx context.Context, ix *models.TorznabIndexer) ([]tn.Result, error) {
+	ep := tn.Endpoint{
+		URL:    ix.Url,
+		APIKey: ix.GetApiKey(),
+		Name:   ix.GetName(),
+	}
+	var cats []int
+	cats = append(cats, tn.CategoriesFor(ix.Caps, "movie")...)
+	cats = append(cats, tn.CategoriesFor(ix.Caps, "series")...)
+	return s.cl.Latest(ctx, ep, cats)
+}
+
+func (s *TorznabFeeds) Item(ctx context.Context, ix *models.TorznabIndexer, r *tn.Result) (stremio.StreamItem, error) {
+	hash, err := s.cl.ResolveInfoHash(ctx, r)
+	if err != nil {
+		return stremio.StreamItem{}, err
+	}
+	return stremio.NewTorznabStream(s.cl, *ix, nil, nil, nil).StreamItem(*r, hash), nil
+}
+
+// Titles adds the canonical English title to the snapshot, and the
+// localized ones when the resolver has aliases: the snapshot is in whatever
+// language the user subscribed in, and release names may be in any of them.
+func (s *TorznabFeeds) Titles(ctx context.Context, sub *models.ReleaseSubscription) []string {
+	out := []string{}
+	if sub.Title != nil && *sub.Title != "" {
+		out = append(out, *sub.Title)
+	}
+	if s.titles == nil {
+		return out
+	}
+	contentType := string(sub.ContentType())
+	t, err := s.titles.Resolve(ctx, contentType, sub.VideoID)
+	if err == nil && t != nil && t.Name != "" {
+		out = append(out, t.Name)
+	}
+	if ar, ok := s.titles.(tn.AliasResolver); ok {
+		if a, err := ar.Aliases(ctx, contentType, sub.VideoID); err == nil && a != nil {
+			out = append(out, a.Titles...)
+		}
+	}
+	return out
+}
+
+func (s *TorznabFeeds) MarkRead(ctx context.Context, indexerID uuid.UUID, readAt, coveredSince time.Time) error {
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	return models.MarkTorznabIndexerFeedRead(ctx, db, indexerID, readAt, coveredSince)
+}
+
 // ClaimsTier answers the tier question through the claims provider.
 type ClaimsTier struct {
 	cl *claims.Claims
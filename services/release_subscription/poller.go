@@ -38,6 +38,11 @@ type pollStore interface {
 	MarkNotified(ctx context.Context, id uuid.UUID) error
 	SeasonEpisodes(ctx context.Context, videoID string, season int16) ([]models.EpisodeMetadata, error)
 	AccountLang(ctx context.Context, userID uuid.UUID) string
+	// MailSettings, ListPending and MarkDigestSent are the delivery pass's
+	// (see digest.go). A nil settings row is the defaults.
+	MailSettings(ctx context.Context, userID uuid.UUID) *models.UserSettings
+	ListPending(ctx context.Context) ([]models.ReleaseSubscription, error)
+	MarkDigestSent(ctx context.Context, userID uuid.UUID, at time.Time) error
 }
 
 // streamSearch is the user's own stream pipeline — addons and indexers,
@@ -47,7 +52,7 @@ type streamSearch interface {
 }
 
 type pollMailer interface {
-	SendSubscriptionUpdate(to string, sub notification.SubscriptionView, releases []notification.ReleaseView) error
+	SendSubscriptionUpdate(to string, updates []notification.SubscriptionUpdate) error
 	SendSubscriptionOff(to string, sub notification.SubscriptionView, completed bool) error
 }
 
@@ -86,6 +91,10 @@ type PollConfig struct {
 	IntervalFree time.Duration
 	IntervalMax  time.Duration
 	HotWindow    time.Duration
+	// Source picks how indexers are asked: PollSourceSearch runs targeted
+	// searches per subscription, PollSourceRSS reads each indexer's feed
+	// once per run (see feed.go).
+	Source string
 
 	Domain string
 	Secret string
@@ -98,6 +107,10 @@ type Poller struct {
 	tiers  tierResolver
 	airing AiringChecker
 	cfg    PollConfig
+	// feeds is nil outside feed mode.
+	feeds feedSource
+	// absolute is nil without an anime mapper.
+	absolute absoluteNumbering
 	// rnd spreads next_check_at so a batch that came due together does not
 	// come due together again. Seeded per poller; no cryptographic use.
 	rnd   *rand.Rand
@@ -124,7 +137,19 @@ func NewPoller(store pollStore, search streamSearch, mail pollMailer, tiers tier
 // Work is grouped by account and the groups run in parallel: one account's
 // subscriptions hit the same addons and the same indexers, and running them
 // side by side is how you get a private tracker to rate-limit you.
+//
+// In feed mode the feeds are read first, so that a subscription whose
+// account they cover can skip its indexer searches below. The delivery
+// pass comes last, so what this run found can make this run's digests.
 func (p *Poller) Run(ctx context.Context) (int, error) {
+	var coverage map[uuid.UUID]time.Time
+	if p.feedMode() {
+		coverage = p.readFeeds(ctx)
+	}
+
+	// Deferred before the listing, so digests already pending still go out
+	// on a run that cannot list what is due.
+	defer p.deliver(ctx)
 	subs, err := p.store.ListDue(ctx, time.Now(), p.cfg.Batch)
 	if err != nil {
 		return 0, err
@@ -158,7 +183,7 @@ func (p *Poller) Run(ctx context.Context) (int, error) {
 				if ctx.Err() != nil {
 					return
 				}
-				if err := p.pollOne(ctx, &list[i]); err != nil {
+				if err := p.pollOne(ctx, &list[i], p.covered(&list[i], coverage)); err != nil {
 					log.WithError(err).
 						WithField("subscription_id", list[i].ID).
 						Error("failed to poll release subscription")
@@ -171,12 +196,27 @@ func (p *Poller) Run(ctx context.Context) (int, error) {
 	return len(subs), nil
 }
 
+// covered reports whether this run's feed reads stand in for a
+// subscription's indexer searches: its account's feeds were all read, and
+// their unbroken coverage reaches back to the subscription's last check.
+// A subscription still waiting for its baseline is never covered — a feed
+// shows what is new, and a baseline needs what exists.
+func (p *Poller) covered(sub *models.ReleaseSubscription, coverage map[uuid.UUID]time.Time) bool {
+	if sub.State != models.ReleaseSubscriptionStateActive || sub.LastCheckedAt == nil {
+		return false
+	}
+	since, ok := coverage[sub.UserID]
+	return ok && !since.After(*sub.LastCheckedAt)
+}
+
 // pollOne is one subscription's turn: search, record, maybe mail, reschedule.
 //
 // The order matters. Hits are recorded before anything is sent, and marked
 // as delivered only after a letter actually goes out, so a failed send
 // leaves them pending for the next run rather than losing them.
-func (p *Poller) pollOne(ctx context.Context, sub *models.ReleaseSubscription) error {
+//
+// covered narrows the search to addons; the indexers were read as feeds.
+func (p *Poller) pollOne(ctx context.Context, sub *models.ReleaseSubscription, covered bool) error {
 	if sub.User == nil || sub.User.Email == "" {
 		// Nothing to mail. Push the row far out rather than leaving it due,
 		// or it comes back every run.
@@ -194,7 +234,7 @@ func (p *Poller) pollOne(ctx context.Context, sub *models.ReleaseSubscription) e
 		return p.rescheduleAfter(ctx, sub, err)
 	}
 
-	hits, searched, err := p.collect(ctx, u, sub, episodes)
+	hits, searched, err := p.collect(ctx, u, sub, episodes, covered)
 	if err != nil {
 		return p.rescheduleAfter(ctx, sub, err)
 	}
@@ -206,6 +246,14 @@ func (p *Poller) pollOne(ctx context.Context, sub *models.ReleaseSubscription) e
 		}
 	}
 
+	// Digest accounts and accounts inside their quiet hours get nothing
+	// from here: the hits stay pending, and the delivery pass at the end
+	// of the run sends them when the account's letter is due.
+	mail := p.store.MailSettings(ctx, sub.UserID)
+	now := time.Now()
+	quiet := mail.InQuietHours(now)
+	held := quiet || mail.GetSubscriptionDigest() != models.SubscriptionDigestImmediate
+
 	state := sub.State
 	notified := false
 	if baseline {
@@ -217,7 +265,7 @@ func (p *Poller) pollOne(ctx context.Context, sub *models.ReleaseSubscription) e
 		if searched {
 			state = models.ReleaseSubscriptionStateActive
 		}
-	} else {
+	} else if !held {
 		sent, err := p.notify(ctx, sub, false)
 		notified = sent
 		if err != nil {
@@ -231,13 +279,22 @@ func (p *Poller) pollOne(ctx context.Context, sub *models.ReleaseSubscription) e
 	}
 
 	if p.seasonIsOver(ctx, sub, episodes) {
+		if quiet {
+			// The completion notice is a letter too. The season will still
+			// be over when the window closes; look again then.
+			return p.store.MarkChecked(ctx, sub.ID, state, p.jitter(mail.QuietUntil(now)))
+		}
 		// Last call. Whatever is still pending goes out now, interval or
 		// not: a completed row is never polled again, so anything left
-		// behind here is never mentioned to anyone.
-		if _, err := p.notify(ctx, sub, true); err != nil {
-			log.WithError(err).
-				WithField("subscription_id", sub.ID).
-				Error("failed to send the final subscription update")
+		// behind here is never mentioned to anyone. A digest account is
+		// the exception — the delivery pass reads completed rows too, so
+		// the final finds wait for the digest like every other.
+		if !held {
+			if _, err := p.notify(ctx, sub, true); err != nil {
+				log.WithError(err).
+					WithField("subscription_id", sub.ID).
+					Error("failed to send the final subscription update")
+			}
 		}
 		p.announceCompletion(ctx, sub)
 		return p.store.MarkChecked(ctx, sub.ID, models.ReleaseSubscriptionStateCompleted, p.retryAt())
@@ -295,14 +352,25 @@ func (p *Poller) seasonEpisodes(ctx context.Context, sub *models.ReleaseSubscrip
 // and the baseline decision turns on the difference — per query, not per
 // run: a five-episode pass where one episode's search failed has not seen
 // that episode's releases, and promoting on it would mail them later as new.
-func (p *Poller) collect(ctx context.Context, u *auth.User, sub *models.ReleaseSubscription, episodes []models.EpisodeMetadata) ([]models.ReleaseSubscriptionHit, bool, error) {
+func (p *Poller) collect(ctx context.Context, u *auth.User, sub *models.ReleaseSubscription, episodes []models.EpisodeMetadata, covered bool) ([]models.ReleaseSubscriptionHit, bool, error) {
 	var out []models.ReleaseSubscriptionHit
 	seen := map[string]bool{}
 	answered := 0
 
+	search := p.search.Search
+	if as, ok := p.search.(addonSearch); ok && covered {
+		search = as.SearchAddons
+	}
+
 	queries := p.queries(sub, episodes)
 	for _, q := range queries {
-		items, err := p.search.Search(ctx, u, q.contentType, q.contentID)
+		items, err := search(ctx, u, q.contentType, q.contentID)
+		if covered && errors.Is(err, ErrNoSources) {
+			// No addons to ask, and the indexers were read as feeds:
+			// nothing is left unasked.
+			answered++
+			continue
+		}
 		if err != nil {
 			// One dead source must not cost the whole poll: the composite
 			// stream already swallows per-source failures, so an error here
@@ -422,34 +490,57 @@ func (p *Poller) notify(ctx context.Context, sub *models.ReleaseSubscription, fo
 		// one send that has no "next time": a subscription about to close.
 		return false, nil
 	}
-	pending, err := p.store.ListPendingHits(ctx, sub.ID)
-	if err != nil {
-		return false, err
+	return p.send(ctx, sub.User.Email, []models.ReleaseSubscription{*sub})
+}
+
+// send mails one letter covering whatever the given subscriptions — all of
+// one account — still have pending, and records it as delivered. Nothing
+// pending across all of them means no letter.
+func (p *Poller) send(ctx context.Context, to string, subs []models.ReleaseSubscription) (bool, error) {
+	updates := make([]notification.SubscriptionUpdate, 0, len(subs))
+	hashes := make([][]string, 0, len(subs))
+	included := make([]*models.ReleaseSubscription, 0, len(subs))
+	for i := range subs {
+		sub := &subs[i]
+		pending, err := p.store.ListPendingHits(ctx, sub.ID)
+		if err != nil {
+			return false, err
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		releases := make([]notification.ReleaseView, 0, len(pending))
+		subHashes := make([]string, 0, len(pending))
+		for j := range pending {
+			h := &pending[j]
+			releases = append(releases, notification.ReleaseView{
+				Name:     h.GetName(),
+				InfoHash: h.InfoHash,
+				URL:      p.magnetURL(h),
+				Source:   strOr(h.SourceName, ""),
+			})
+			subHashes = append(subHashes, h.InfoHash)
+		}
+		updates = append(updates, notification.SubscriptionUpdate{Sub: p.view(ctx, sub), Releases: releases})
+		hashes = append(hashes, subHashes)
+		included = append(included, sub)
 	}
-	if len(pending) == 0 {
+	if len(updates) == 0 {
 		return false, nil
 	}
 
-	releases := make([]notification.ReleaseView, 0, len(pending))
-	hashes := make([]string, 0, len(pending))
-	for i := range pending {
-		h := &pending[i]
-		releases = append(releases, notification.ReleaseView{
-			Name:     h.GetName(),
-			InfoHash: h.InfoHash,
-			URL:      p.magnetURL(h),
-			Source:   strOr(h.SourceName, ""),
-		})
-		hashes = append(hashes, h.InfoHash)
-	}
-
-	if err := p.mail.SendSubscriptionUpdate(sub.User.Email, p.view(ctx, sub), releases); err != nil {
+	if err := p.mail.SendSubscriptionUpdate(to, updates); err != nil {
 		return false, err
 	}
-	if err := p.store.MarkHitsNotified(ctx, sub.ID, hashes); err != nil {
-		return true, err
+	for i, sub := range included {
+		if err := p.store.MarkHitsNotified(ctx, sub.ID, hashes[i]); err != nil {
+			return true, err
+		}
+		if err := p.store.MarkNotified(ctx, sub.ID); err != nil {
+			return true, err
+		}
 	}
-	return true, p.store.MarkNotified(ctx, sub.ID)
+	return true, nil
 }
 
 // announceCompletion tells the user their season is done. The state itself
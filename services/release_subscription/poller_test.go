@@ -19,6 +19,7 @@ import (
 
 type fakeStore struct {
 	due      []models.ReleaseSubscription
+	dueErr   error
 	episodes []models.EpisodeMetadata
 	pending  []models.ReleaseSubscriptionHit
 
@@ -31,10 +32,16 @@ type fakeStore struct {
 	checkedNext    time.Time
 	markedNotified bool
 	lang           string
+
+	// the delivery pass's half
+	mailSettings *models.UserSettings
+	pendingSubs  []models.ReleaseSubscription
+	pendingBySub map[uuid.UUID][]models.ReleaseSubscriptionHit
+	digestSentAt *time.Time
 }
 
 func (s *fakeStore) ListDue(context.Context, time.Time, int) ([]models.ReleaseSubscription, error) {
-	return s.due, nil
+	return s.due, s.dueErr
 }
 
 func (s *fakeStore) InsertHits(_ context.Context, hits []models.ReleaseSubscriptionHit, baseline bool) (int, error) {
@@ -46,7 +53,10 @@ func (s *fakeStore) InsertHits(_ context.Context, hits []models.ReleaseSubscript
 	return len(hits), nil
 }
 
-func (s *fakeStore) ListPendingHits(context.Context, uuid.UUID) ([]models.ReleaseSubscriptionHit, error) {
+func (s *fakeStore) ListPendingHits(_ context.Context, id uuid.UUID) ([]models.ReleaseSubscriptionHit, error) {
+	if s.pendingBySub != nil {
+		return s.pendingBySub[id], nil
+	}
 	return s.pending, nil
 }
 
@@ -72,6 +82,19 @@ func (s *fakeStore) SeasonEpisodes(context.Context, string, int16) ([]models.Epi
 
 func (s *fakeStore) AccountLang(context.Context, uuid.UUID) string { return s.lang }
 
+func (s *fakeStore) MailSettings(context.Context, uuid.UUID) *models.UserSettings {
+	return s.mailSettings
+}
+
+func (s *fakeStore) ListPending(context.Context) ([]models.ReleaseSubscription, error) {
+	return s.pendingSubs, nil
+}
+
+func (s *fakeStore) MarkDigestSent(_ context.Context, _ uuid.UUID, at time.Time) error {
+	s.digestSentAt = &at
+	return nil
+}
+
 type fakeSearch struct {
 	byContentID  map[string][]stremio.StreamItem
 	asked        []string
@@ -91,16 +114,24 @@ func (s *fakeSearch) Search(_ context.Context, _ *auth.User, _, contentID string
 }
 
 type fakeMailer struct {
+	// updates is every letter's releases, flattened across subscriptions;
+	// letters keeps the per-subscription split.
 	updates  [][]notification.ReleaseView
+	letters  [][]notification.SubscriptionUpdate
 	offs     []bool
 	failWith error
 }
 
-func (m *fakeMailer) SendSubscriptionUpdate(_ string, _ notification.SubscriptionView, releases []notification.ReleaseView) error {
+func (m *fakeMailer) SendSubscriptionUpdate(_ string, updates []notification.SubscriptionUpdate) error {
 	if m.failWith != nil {
 		return m.failWith
 	}
+	var releases []notification.ReleaseView
+	for _, u := range updates {
+		releases = append(releases, u.Releases...)
+	}
 	m.updates = append(m.updates, releases)
+	m.letters = append(m.letters, updates)
 	return nil
 }
 
@@ -427,7 +458,7 @@ func TestCollectDropsOverlongInfohash(t *testing.T) {
 	}}
 	p := NewPoller(&fakeStore{}, search, &fakeMailer{}, fakeTier{}, fakeAiring{}, testConfig())
 
-	hits, searched, err := p.collect(context.Background(), &auth.User{}, seasonSub(), []models.EpisodeMetadata{episode(5, time.Hour)})
+	hits, searched, err := p.collect(context.Background(), &auth.User{}, seasonSub(), []models.EpisodeMetadata{episode(5, time.Hour)}, false)
 	if err != nil {
 		t.Fatalf("collect: %v", err)
 	}
@@ -520,7 +551,7 @@ func TestCollectReadsNamesFromTheRightLines(t *testing.T) {
 	}}
 	p := NewPoller(&fakeStore{}, search, &fakeMailer{}, fakeTier{}, fakeAiring{}, testConfig())
 
-	hits, _, err := p.collect(context.Background(), &auth.User{}, sub, []models.EpisodeMetadata{episode(5, time.Hour)})
+	hits, _, err := p.collect(context.Background(), &auth.User{}, sub, []models.EpisodeMetadata{episode(5, time.Hour)}, false)
 	if err != nil {
 		t.Fatalf("collect: %v", err)
 	}
@@ -555,7 +586,7 @@ func TestCollectDedupesAcrossEpisodeQueries(t *testing.T) {
 	p := NewPoller(&fakeStore{}, search, &fakeMailer{}, fakeTier{}, fakeAiring{}, testConfig())
 
 	episodes := []models.EpisodeMetadata{episode(1, 21*24*time.Hour), episode(2, 14*24*time.Hour), episode(3, 7*24*time.Hour)}
-	hits, _, err := p.collect(context.Background(), &auth.User{}, seasonSub(), episodes)
+	hits, _, err := p.collect(context.Background(), &auth.User{}, seasonSub(), episodes, false)
 	if err != nil {
 		t.Fatalf("collect: %v", err)
 	}
@@ -933,7 +964,7 @@ func TestPreferencesFilterWhatIsRecorded(t *testing.T) {
 			}}
 			p := NewPoller(&fakeStore{}, search, &fakeMailer{}, fakeTier{}, fakeAiring{}, testConfig())
 
-			hits, _, err := p.collect(context.Background(), &auth.User{}, sub, []models.EpisodeMetadata{episode(5, time.Hour)})
+			hits, _, err := p.collect(context.Background(), &auth.User{}, sub, []models.EpisodeMetadata{episode(5, time.Hour)}, false)
 			if err != nil {
 				t.Fatalf("collect: %v", err)
 			}
@@ -200,6 +200,38 @@ func (s pgStore) SeasonEpisodes(ctx context.Context, videoID string, season int1
 	return models.ListEpisodeMetadataBySeason(ctx, db, videoID, season)
 }
 
+func (s pgStore) ListPending(ctx context.Context) ([]models.ReleaseSubscription, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.ListReleaseSubscriptionsWithPendingHits(ctx, db)
+}
+
+// MailSettings returns how the account wants its subscription mail, or nil
+// for the defaults. A read failure is the defaults too: immediate, no quiet
+// hours — exactly how every letter went out before the settings existed.
+func (s pgStore) MailSettings(ctx context.Context, userID uuid.UUID) *models.UserSettings {
+	db, err := s.db()
+	if err != nil {
+		return nil
+	}
+	us, err := models.GetUserSettings(ctx, db, userID)
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Warn("failed to read subscription mail settings")
+		return nil
+	}
+	return us
+}
+
+func (s pgStore) MarkDigestSent(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+	return models.MarkUserSettingsDigestSent(ctx, db, userID, at)
+}
+
 // AccountLang returns the language the account browses in, or "" when it has
 // never been observed. Errors are swallowed: every caller has a fallback —
 // the language the subscription was created in — and a lookup failure must
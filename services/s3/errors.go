@@ -23,6 +23,7 @@ const (
 	ErrCodeSignatureMismatch    = "SignatureDoesNotMatch"
 	ErrCodeMissingSecurity      = "MissingSecurityHeader"
 	ErrCodeRequestTimeTooSkewed = "RequestTimeTooSkewed"
+	ErrCodeEntityTooLarge       = "EntityTooLarge"
 )
 
 // Error is an S3 protocol error. It carries both the wire code and the HTTP
@@ -106,6 +107,8 @@ func errorFromVFS(err error, bucketOnly bool) *Error {
 		return newError(http.StatusForbidden, ErrCodeAccessDenied, "Access Denied", err)
 	case http.StatusBadRequest:
 		return newError(http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid Request", err)
+	case http.StatusRequestEntityTooLarge:
+		return newError(http.StatusBadRequest, ErrCodeEntityTooLarge, "Your proposed upload exceeds the maximum allowed object size", err)
 	case http.StatusMethodNotAllowed:
 		return newError(http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "The specified method is not allowed against this resource", err)
 	default:
@@ -145,7 +145,7 @@ func (h *Handler) readDir(ctx context.Context, bucket string, dir string) ([]ent
 	return entries, nil
 }
 
-// walkCached is walk plus a short-lived per-user cache.
+// walkCached is walk plus a per-user cache, dropped by Invalidate.
 //
 // One recursive listing costs one ReadDir per directory, i.e. one rest-api call
 // per torrent, so a `rclone sync` of a large library would otherwise re-issue
@@ -156,7 +156,11 @@ func (h *Handler) walkCached(ctx context.Context, bucket string, dir string) ([]
 		return nil, err
 	}
 	key := strings.Join([]string{userID, bucket, dir}, "|")
-	return h.walks.Get(key, func() ([]entry, error) {
+	m := h.walks
+	if h.VolatileBuckets[bucket] {
+		m = h.volatileWalks
+	}
+	return m.Get(key, func() ([]entry, error) {
 		return h.walk(ctx, bucket, dir)
 	})
 }
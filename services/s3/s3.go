@@ -18,8 +18,10 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +29,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/webtor-io/lazymap"
+	cp "github.com/webtor-io/web-ui/services/content_proxy"
 	"github.com/webtor-io/web-ui/services/vfs"
 )
 
@@ -57,8 +60,14 @@ type Handler struct {
 	MaxWalkKeys int
 	// Now is overridable in tests; signature verification needs a clock.
 	Now func() time.Time
-
-	walks *lazymap.LazyMap[[]entry]
+	// VolatileBuckets change without a library event — watch progress, vault
+	// pledges — so Invalidate cannot be relied on for them and their walks are
+	// only cached briefly.
+	VolatileBuckets map[string]bool
+
+	walks         *lazymap.LazyMap[[]entry]
+	volatileWalks *lazymap.LazyMap[[]entry]
+	metadata      *lazymap.LazyMap[map[string]string]
 }
 
 func New(fs vfs.FileSystem, signingSecret string, mountPath string) *Handler {
@@ -71,13 +80,46 @@ func New(fs vfs.FileSystem, signingSecret string, mountPath string) *Handler {
 		Now:           time.Now,
 		// Each cached walk holds every key under a prefix, so the cache is sized
 		// against memory, not hit rate: worst case is Capacity × MaxWalkKeys
-		// entries resident. web-ui has been OOM-killed by unbounded caches
-		// before — keep this deliberately small and short-lived.
+		// entries resident across both maps. web-ui has been OOM-killed by
+		// unbounded caches before — keep this deliberately small.
+		//
+		// Library changes drop a user's walks as they happen (Invalidate), so
+		// the TTL only bounds what a missed event can cost.
 		walks: lazymap.New[[]entry](&lazymap.Config{
+			Expire:      15 * time.Minute,
+			ErrorExpire: 10 * time.Second,
+			Capacity:    24,
+		}),
+		volatileWalks: lazymap.New[[]entry](&lazymap.Config{
 			Expire:      1 * time.Minute,
 			ErrorExpire: 10 * time.Second,
-			Capacity:    32,
+			Capacity:    8,
 		}),
+		// Object metadata is a few short strings per key, looked up on every
+		// HEAD — and rclone HEADs each object before transferring it.
+		metadata: lazymap.New[map[string]string](&lazymap.Config{
+			Expire:      15 * time.Minute,
+			ErrorExpire: 10 * time.Second,
+			Capacity:    1000,
+		}),
+	}
+}
+
+// Invalidate drops the cached listings and metadata of one user, or of every
+// user when userID is empty. A change to one library entry can move it in and
+// out of any bucket — renamed everywhere, classified into movies/, genres/
+// and years/ by enrichment — so the whole of the user's cache goes.
+func (h *Handler) Invalidate(userID string) {
+	dropUser(h.walks, userID)
+	dropUser(h.volatileWalks, userID)
+	dropUser(h.metadata, userID)
+}
+
+func dropUser[T any](m *lazymap.LazyMap[T], userID string) {
+	for _, k := range m.Keys() {
+		if userID == "" || strings.HasPrefix(k, userID+"|") {
+			m.Drop(k)
+		}
 	}
 }
 
@@ -268,6 +310,12 @@ func (h *Handler) serveObject(w http.ResponseWriter, r *http.Request, bucketName
 	if hasDotSegment(key) {
 		return newError(http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid key", nil)
 	}
+	if _, ok := query["tagging"]; ok {
+		if r.Method != http.MethodGet {
+			return newError(http.StatusNotImplemented, ErrCodeNotImplemented, "Object tags are derived from the library and cannot be changed", nil)
+		}
+		return h.getObjectTagging(w, r, bucketName, key)
+	}
 	switch r.Method {
 	case http.MethodGet, http.MethodHead:
 		return h.getObject(w, r, bucketName, key)
@@ -297,6 +345,7 @@ func (h *Handler) getObject(w http.ResponseWriter, r *http.Request, bucketName s
 	}
 
 	e := toEntry(fi, bucketName)
+	setMetadataHeaders(w, h.objectMetadata(r.Context(), bucketName, key))
 	if r.Method == http.MethodHead {
 		// HEAD is answered from metadata alone. Opening would ask the streaming
 		// chain to mint a content URL, and clients HEAD constantly (rclone
@@ -324,6 +373,15 @@ func (h *Handler) getObject(w http.ResponseWriter, r *http.Request, bucketName s
 		if redirect.String() == "" {
 			return newError(http.StatusInternalServerError, ErrCodeInternalError, "Failed to resolve the content location", nil)
 		}
+		// Proxy mode, for clients that do not follow the redirect. The ETag is
+		// ours, as on HEAD, so the client sees one object either way.
+		if st := cp.FromContext(r.Context()); st != nil {
+			w.Header().Set("ETag", etagFor(&e))
+			if err := st.Serve(w, r, redirect); err != nil {
+				return newError(http.StatusBadGateway, ErrCodeInternalError, "Failed to fetch the content", err)
+			}
+			return nil
+		}
 		http.Redirect(w, r, redirect.String(), http.StatusTemporaryRedirect)
 		return nil
 	}
@@ -352,6 +410,68 @@ func (h *Handler) getObject(w http.ResponseWriter, r *http.Request, bucketName s
 	return nil
 }
 
+func (h *Handler) getObjectTagging(w http.ResponseWriter, r *http.Request, bucketName string, key string) *Error {
+	fi, err := h.FileSystem.Stat(r.Context(), vfsPath(bucketName, key))
+	if err != nil {
+		return errorFromVFS(err, false)
+	}
+	if fi.IsDir {
+		return newError(http.StatusNotFound, ErrCodeNoSuchKey, "The specified key does not exist", nil)
+	}
+	md := h.objectMetadata(r.Context(), bucketName, key)
+	res := &tagging{XMLNS: s3NS}
+	res.TagSet.Tag = []tag{}
+	for _, k := range sortedKeys(md) {
+		res.TagSet.Tag = append(res.TagSet.Tag, tag{Key: k, Value: md[k]})
+	}
+	return h.writeXML(w, http.StatusOK, res)
+}
+
+// objectMetadata is what the filesystem knows about an object beyond its
+// size: for library files, what enrichment identified them as. It is an
+// extra, so a failure to look it up is logged and the object served without.
+func (h *Handler) objectMetadata(ctx context.Context, bucketName string, key string) map[string]string {
+	read := func() (map[string]string, error) {
+		return vfs.ReadMetadata(ctx, h.FileSystem, vfsPath(bucketName, key))
+	}
+	var md map[string]string
+	var err error
+	// Cached like the walks and dropped with them by Invalidate; without a
+	// user there is nothing to key the cache by.
+	if userID, uerr := userIDFromContext(ctx); uerr == nil {
+		md, err = h.metadata.Get(strings.Join([]string{userID, bucketName, key}, "|"), read)
+	} else {
+		md, err = read()
+	}
+	if err != nil {
+		log.WithError(err).WithField("bucket", bucketName).WithField("key", key).Warn("failed to read s3 object metadata")
+		return nil
+	}
+	return md
+}
+
+// setMetadataHeaders publishes md as user metadata, plus the tag count the
+// SDKs surface on HEAD/GET. Header values have to be ASCII; anything else —
+// a title in Cyrillic — goes out RFC 2047-encoded, as S3 itself does.
+func setMetadataHeaders(w http.ResponseWriter, md map[string]string) {
+	if len(md) == 0 {
+		return
+	}
+	for k, v := range md {
+		w.Header().Set("x-amz-meta-"+k, mime.BEncoding.Encode("UTF-8", v))
+	}
+	w.Header().Set("x-amz-tagging-count", strconv.Itoa(len(md)))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (h *Handler) putObject(w http.ResponseWriter, r *http.Request, bucketName string, key string) *Error {
 	body, cerr := decodeBody(r)
 	if cerr != nil {
@@ -16,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	cp "github.com/webtor-io/web-ui/services/content_proxy"
 	"github.com/webtor-io/web-ui/services/vfs"
 )
 
@@ -37,6 +38,7 @@ type fakeFS struct {
 	files    map[string]int64
 	redirect string
 	body     string
+	meta     map[string]map[string]string
 }
 
 func newFakeFS() *fakeFS {
@@ -137,7 +139,14 @@ func (f *fakeFS) Move(context.Context, string, string, *vfs.MoveOptions) (bool,
 	return false, vfs.NewHTTPError(http.StatusForbidden, nil)
 }
 
-var _ vfs.FileSystem = (*fakeFS)(nil)
+func (f *fakeFS) Metadata(_ context.Context, name string) (map[string]string, error) {
+	return f.meta[name], nil
+}
+
+var (
+	_ vfs.FileSystem     = (*fakeFS)(nil)
+	_ vfs.MetadataReader = (*fakeFS)(nil)
+)
 
 func newTestServer(t *testing.T, fs vfs.FileSystem) *httptest.Server {
 	t.Helper()
@@ -605,6 +614,55 @@ func TestPlusInNamesSurvivesListing(t *testing.T) {
 	}
 }
 
+// In proxy mode the content comes from us, ranges included, for clients that
+// would not follow the redirect.
+func TestGetObjectProxied(t *testing.T) {
+	content := "the movie bytes"
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mkv", testModTime, strings.NewReader(content))
+	}))
+	defer origin.Close()
+
+	fs := newFakeFS()
+	fs.redirect = origin.URL + "/video.mkv"
+	h := New(fs, testSecret, "/s3")
+	proxy := cp.NewWith(nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithUser(r.Context(), testUser)
+		ctx = cp.WithStream(ctx, proxy.For(r, true, testUser, ""))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	defer srv.Close()
+
+	req, _ := newSignedClient(t, srv.URL).GetObjectRequest(&awss3.GetObjectInput{
+		Bucket: aws.String("all"),
+		Key:    aws.String(movieDir + "/video.mkv"),
+	})
+	signed, err := req.Presign(5 * time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	get, _ := http.NewRequest(http.MethodGet, signed, nil)
+	get.Header.Set("Range", "bytes=4-8")
+	resp, err := noRedirect.Do(get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "movie" {
+		t.Errorf("got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Error("proxied object has no ETag")
+	}
+}
+
 // Query-string (presigned) signatures share the verification path with header
 // ones, and they are what a shareable link would be built from.
 func TestPresignedGet(t *testing.T) {
@@ -708,3 +766,141 @@ func errorsAs(err error, target *awserr.RequestFailure) bool {
 	}
 	return false
 }
+
+// A library change drops the user's cached walks, so the next recursive
+// listing sees it instead of waiting out the TTL — and only that user's.
+func TestInvalidateDropsCachedWalks(t *testing.T) {
+	fs := newFakeFS()
+	h := New(fs, testSecret, "/s3")
+	ctx := WithUser(context.Background(), testUser)
+	count := func() int {
+		t.Helper()
+		es, err := h.walkCached(ctx, "torrents", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(es)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("got %d keys, want 1", n)
+	}
+	fs.files["/torrents/Added.torrent"] = 64
+
+	h.Invalidate("someone-else")
+	if n := count(); n != 1 {
+		t.Fatalf("another user's change dropped the walk: %d keys", n)
+	}
+	h.Invalidate(testUser)
+	if n := count(); n != 2 {
+		t.Fatalf("got %d keys after invalidation, want 2", n)
+	}
+	delete(fs.files, "/torrents/Added.torrent")
+	h.Invalidate("")
+	if n := count(); n != 1 {
+		t.Fatalf("got %d keys after invalidating everyone, want 1", n)
+	}
+}
+
+// Metadata is looked up on every HEAD, so it is cached with the walks and
+// dropped with them.
+func TestInvalidateDropsCachedMetadata(t *testing.T) {
+	fs := newFakeFS()
+	name := "/all/" + movieDir + "/video.mkv"
+	fs.meta = map[string]map[string]string{name: {"year": "2020"}}
+	h := New(fs, testSecret, "/s3")
+	ctx := WithUser(context.Background(), testUser)
+	year := func() string {
+		return h.objectMetadata(ctx, "all", movieDir+"/video.mkv")["year"]
+	}
+	if y := year(); y != "2020" {
+		t.Fatalf("year %q, want 2020", y)
+	}
+	fs.meta[name] = map[string]string{"year": "2021"}
+	if y := year(); y != "2020" {
+		t.Fatalf("metadata was looked up again: year %q", y)
+	}
+	h.Invalidate(testUser)
+	if y := year(); y != "2021" {
+		t.Fatalf("year %q after invalidation, want 2021", y)
+	}
+}
+
+func TestObjectMetadataAndTags(t *testing.T) {
+	fs := newFakeFS()
+	fs.meta = map[string]map[string]string{
+		"/all/" + movieDir + "/video.mkv": {
+			"infohash":   "08ada5a7a6183aae1e09d831df6748d566095a10",
+			"imdb-id":    "tt1727587",
+			"title":      "Movie One",
+			"year":       "2020",
+			"resolution": "1080p",
+		},
+		"/all/Другой Фильм/video.mkv": {"title": "Другой Фильм"},
+	}
+	srv := newTestServer(t, fs)
+	defer srv.Close()
+	cl := newSignedClient(t, srv.URL)
+
+	head, err := cl.HeadObject(&awss3.HeadObjectInput{
+		Bucket: aws.String("all"),
+		Key:    aws.String(movieDir + "/video.mkv"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := aws.StringValue(head.Metadata["Imdb-Id"]); v != "tt1727587" {
+		t.Errorf("imdb-id metadata %q, all: %v", v, aws.StringValueMap(head.Metadata))
+	}
+	if v := aws.StringValue(head.Metadata["Resolution"]); v != "1080p" {
+		t.Errorf("resolution metadata %q", v)
+	}
+
+	tags, err := cl.GetObjectTagging(&awss3.GetObjectTaggingInput{
+		Bucket: aws.String("all"),
+		Key:    aws.String(movieDir + "/video.mkv"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, tg := range tags.TagSet {
+		keys = append(keys, aws.StringValue(tg.Key)+"="+aws.StringValue(tg.Value))
+	}
+	want := "imdb-id=tt1727587,infohash=08ada5a7a6183aae1e09d831df6748d566095a10,resolution=1080p,title=Movie One,year=2020"
+	if got := strings.Join(keys, ","); got != want {
+		t.Errorf("tags %s, want %s", got, want)
+	}
+
+	// Non-ASCII values cannot go into a header as they are.
+	head, err = cl.HeadObject(&awss3.HeadObjectInput{
+		Bucket: aws.String("all"),
+		Key:    aws.String("Другой Фильм/video.mkv"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := aws.StringValue(head.Metadata["Title"]); !strings.HasPrefix(v, "=?UTF-8?b?") {
+		t.Errorf("title metadata %q is not RFC 2047 encoded", v)
+	}
+
+	// Files without metadata still answer with an empty tag set.
+	tags, err = cl.GetObjectTagging(&awss3.GetObjectTaggingInput{
+		Bucket: aws.String("all"),
+		Key:    aws.String(movieDir + "/subs/eng.srt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags.TagSet) != 0 {
+		t.Errorf("unexpected tags %v", tags.TagSet)
+	}
+
+	_, err = cl.PutObjectTagging(&awss3.PutObjectTaggingInput{
+		Bucket:  aws.String("all"),
+		Key:     aws.String(movieDir + "/video.mkv"),
+		Tagging: &awss3.Tagging{TagSet: []*awss3.Tag{{Key: aws.String("a"), Value: aws.String("b")}}},
+	})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != ErrCodeNotImplemented {
+		t.Errorf("put tagging: got %v, want NotImplemented", err)
+	}
+}
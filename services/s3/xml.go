@@ -89,6 +89,21 @@ type copyObjectResult struct {
 	ETag         string   `xml:"ETag"`
 }
 
+// tagging is the GetObjectTagging response. Tags are read-only here: they
+// are what enrichment found out about the file (see objectMetadata).
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	TagSet  struct {
+		Tag []tag `xml:"Tag"`
+	} `xml:"TagSet"`
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
 func formatListTime(t time.Time) string {
 	if t.IsZero() {
 		return time.Unix(0, 0).UTC().Format(iso8601Millis)
@@ -0,0 +1,250 @@
+package sabnzbd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// APIError is an error reported by the SABnzbd API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (code %d): %s", e.StatusCode, e.Message)
+}
+
+// InvalidCredentials reports whether the API key was rejected. SABnzbd
+// answers a bad key with a 200 and "API Key Incorrect", so the message is
+// all there is to go by.
+func (e *APIError) InvalidCredentials() bool {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return true
+	}
+	return strings.Contains(strings.ToLower(e.Message), "api key")
+}
+
+// RateLimited reports whether the request was throttled. A self-hosted
+// SABnzbd does not throttle.
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// ParseAPIURL splits what the user pasted — SABnzbd's address with the API
+// key as a query parameter, as the SABnzbd config page shows it — into the
+// API endpoint and the key. An address without the /api path gets it.
+func ParseAPIURL(raw string) (string, string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", "", errors.New("invalid SABnzbd URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", errors.New("SABnzbd URL must be http or https")
+	}
+	key := u.Query().Get("apikey")
+	if key == "" {
+		return "", "", errors.New("SABnzbd URL carries no apikey")
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	if path.Base(p) != "api" {
+		p += "/api"
+	}
+	u.Path = p
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), key, nil
+}
+
+// Client is a SABnzbd API client
+type Client struct {
+	httpClient *http.Client
+	apiURL     string
+	apiKey     string
+}
+
+// New creates a new SABnzbd API client for the API endpoint, as returned by
+// ParseAPIURL
+func New(httpClient *http.Client, apiURL, apiKey string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		apiURL:     apiURL,
+		apiKey:     apiKey,
+	}
+}
+
+// AddFile uploads an NZB and returns the id SABnzbd gave the job
+func (s *Client) AddFile(ctx context.Context, name string, nzb []byte) (string, error) {
+	params := url.Values{}
+	params.Set("mode", "addfile")
+	if name != "" {
+		params.Set("nzbname", name)
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	fw, err := w.CreateFormFile("name", name+".nzb")
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := fw.Write(nzb); err != nil {
+		return "", fmt.Errorf("failed to write form file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to close multipart body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url(params), &buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	var resp addResponse
+	if err := s.doRequest(req, &resp); err != nil {
+		return "", errors.Wrap(err, "failed to add nzb")
+	}
+	if !resp.Status || len(resp.NzoIDs) == 0 {
+		return "", &APIError{StatusCode: http.StatusOK, Message: "nzb was not accepted"}
+	}
+	return resp.NzoIDs[0], nil
+}
+
+// Queue returns the queued job with the id, or nil when it is not in the
+// queue. An empty id asks for nothing in particular, which is how the
+// key is checked.
+func (s *Client) Queue(ctx context.Context, nzoID string) (*QueueSlot, error) {
+	params := url.Values{}
+	params.Set("mode", "queue")
+	if nzoID != "" {
+		params.Set("nzo_ids", nzoID)
+	} else {
+		params.Set("limit", "1")
+	}
+	var resp queueResponse
+	if err := s.get(ctx, params, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to get queue")
+	}
+	for i := range resp.Queue.Slots {
+		if resp.Queue.Slots[i].NzoID == nzoID {
+			return &resp.Queue.Slots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// History returns the finished job with the id, or nil when it is not in
+// the history
+func (s *Client) History(ctx context.Context, nzoID string) (*HistorySlot, error) {
+	params := url.Values{}
+	params.Set("mode", "history")
+	params.Set("nzo_ids", nzoID)
+	var resp historyResponse
+	if err := s.get(ctx, params, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to get history")
+	}
+	for i := range resp.History.Slots {
+		if resp.History.Slots[i].NzoID == nzoID {
+			return &resp.History.Slots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete removes the job from the queue or the history. Finished files are
+// left on disk.
+func (s *Client) Delete(ctx context.Context, nzoID string) error {
+	for _, mode := range []string{"queue", "history"} {
+		params := url.Values{}
+		params.Set("mode", mode)
+		params.Set("name", "delete")
+		params.Set("value", nzoID)
+		var resp statusResponse
+		if err := s.get(ctx, params, &resp); err != nil {
+			return errors.Wrapf(err, "failed to delete from %s", mode)
+		}
+	}
+	return nil
+}
+
+// CompleteDir returns the folder SABnzbd moves finished jobs to, as it is
+// configured — relative to SABnzbd's own home unless set absolute
+func (s *Client) CompleteDir(ctx context.Context) (string, error) {
+	params := url.Values{}
+	params.Set("mode", "get_config")
+	params.Set("section", "misc")
+	params.Set("keyword", "complete_dir")
+	var resp configResponse
+	if err := s.get(ctx, params, &resp); err != nil {
+		return "", errors.Wrap(err, "failed to get config")
+	}
+	return resp.Config.Misc.CompleteDir, nil
+}
+
+func (s *Client) url(params url.Values) string {
+	params.Set("apikey", s.apiKey)
+	params.Set("output", "json")
+	return s.apiURL + "?" + params.Encode()
+}
+
+// get performs a GET request
+func (s *Client) get(ctx context.Context, params url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url(params), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return s.doRequest(req, v)
+}
+
+// doRequest executes an HTTP request and decodes the response into v.
+// SABnzbd reports most failures as a 200 with {"status": false}, so that is
+// checked before v is decoded.
+func (s *Client) doRequest(req *http.Request, v any) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		// The request URL carries the key
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+
+	var st statusResponse
+	if err := json.Unmarshal(body, &st); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if st.Status != nil && !*st.Status {
+		msg := st.Error
+		if msg == "" {
+			msg = "request refused"
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: msg}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sabnzbd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testKey = "good-key"
+
+// mockSAB answers the handful of API modes the client uses, the way
+// SABnzbd 4 does: everything under /api, the mode in the query, and a 200
+// with {"status": false} for a wrong key.
+func mockSAB(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/sabnzbd/api" || q.Get("output") != "json" {
+			http.NotFound(w, r)
+			return
+		}
+		if q.Get("apikey") != testKey {
+			_, _ = w.Write([]byte(`{"status":false,"error":"API Key Incorrect"}`))
+			return
+		}
+		switch q.Get("mode") {
+		case "addfile":
+			f, _, err := r.FormFile("name")
+			if err != nil {
+				t.Errorf("no nzb uploaded: %v", err)
+				return
+			}
+			b, _ := io.ReadAll(f)
+			if !strings.Contains(string(b), "<nzb") || q.Get("nzbname") != "Show.S01E01" {
+				t.Errorf("upload %q named %q", b, q.Get("nzbname"))
+			}
+			_, _ = w.Write([]byte(`{"status":true,"nzo_ids":["SABnzbd_nzo_1"]}`))
+		case "queue":
+			if q.Get("name") == "delete" {
+				_, _ = w.Write([]byte(`{"status":true,"nzo_ids":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"queue":{"slots":[{"nzo_id":"SABnzbd_nzo_1","filename":"Show.S01E01","status":"Downloading","percentage":"42","mb":"100"}]}}`))
+		case "history":
+			if q.Get("name") == "delete" {
+				_, _ = w.Write([]byte(`{"status":true}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"history":{"slots":[{"nzo_id":"SABnzbd_nzo_2","name":"Movie","status":"Completed","bytes":1048576,"storage":"/data/complete/movies/Movie"}]}}`))
+		case "get_config":
+			_, _ = w.Write([]byte(`{"config":{"misc":{"complete_dir":"/data/complete"}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":false,"error":"not implemented"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParseAPIURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"http://nas:8080/sabnzbd/api?apikey=k": "http://nas:8080/sabnzbd/api",
+		"http://nas:8080/sabnzbd/?apikey=k":    "http://nas:8080/sabnzbd/api",
+		"https://sab.example.com?apikey=k":     "https://sab.example.com/api",
+	} {
+		got, key, err := ParseAPIURL(raw)
+		if err != nil || got != want || key != "k" {
+			t.Errorf("ParseAPIURL(%q) = %q, %q, %v; want %q", raw, got, key, err, want)
+		}
+	}
+	for _, raw := range []string{"http://nas:8080/sabnzbd/", "ftp://nas/?apikey=k", "nas:8080"} {
+		if _, _, err := ParseAPIURL(raw); err == nil {
+			t.Errorf("ParseAPIURL(%q) accepted", raw)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := mockSAB(t)
+	ctx := context.Background()
+	cl := New(srv.Client(), srv.URL+"/sabnzbd/api", testKey)
+
+	id, err := cl.AddFile(ctx, "Show.S01E01", []byte(`<?xml version="1.0"?><nzb></nzb>`))
+	if err != nil || id != "SABnzbd_nzo_1" {
+		t.Fatalf("AddFile() = %q, %v", id, err)
+	}
+	q, err := cl.Queue(ctx, id)
+	if err != nil || q == nil || q.Percentage != 42 || q.Size() != 100<<20 {
+		t.Errorf("Queue() = %+v, %v", q, err)
+	}
+	if h, err := cl.History(ctx, id); err != nil || h != nil {
+		t.Errorf("History() of a queued job = %+v, %v; want nil", h, err)
+	}
+	h, err := cl.History(ctx, "SABnzbd_nzo_2")
+	if err != nil || h == nil || !h.Completed() || h.Storage != "/data/complete/movies/Movie" {
+		t.Errorf("History() = %+v, %v", h, err)
+	}
+	if dir, err := cl.CompleteDir(ctx); err != nil || dir != "/data/complete" {
+		t.Errorf("CompleteDir() = %q, %v", dir, err)
+	}
+	if err := cl.Delete(ctx, id); err != nil {
+		t.Errorf("Delete() = %v", err)
+	}
+}
+
+func TestClientWrongKey(t *testing.T) {
+	srv := mockSAB(t)
+	_, err := New(srv.Client(), srv.URL+"/sabnzbd/api", "bad").Queue(context.Background(), "")
+	var ae *APIError
+	if !errors.As(err, &ae) || !ae.InvalidCredentials() || ae.RateLimited() {
+		t.Errorf("wrong key: %v", err)
+	}
+}
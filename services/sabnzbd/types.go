@@ -0,0 +1,89 @@
+package sabnzbd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// number reads SABnzbd's numbers, which the API sends as strings in some
+// places ("percentage": "42") and as numbers in others
+type number float64
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*n = number(f)
+	return nil
+}
+
+// QueueSlot is a job still downloading or waiting to
+type QueueSlot struct {
+	NzoID      string `json:"nzo_id"`
+	Filename   string `json:"filename"`
+	Status     string `json:"status"`
+	Percentage number `json:"percentage"`
+	MB         number `json:"mb"`
+}
+
+// Size is the job's size in bytes
+func (s *QueueSlot) Size() int64 {
+	return int64(float64(s.MB) * 1024 * 1024)
+}
+
+// HistorySlot is a job SABnzbd is done with, successfully or not
+type HistorySlot struct {
+	NzoID       string `json:"nzo_id"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	FailMessage string `json:"fail_message"`
+	Bytes       number `json:"bytes"`
+	// Storage is where the finished job was saved, on the SABnzbd host
+	Storage string `json:"storage"`
+}
+
+// Completed reports whether the job finished and its files are in place
+func (s *HistorySlot) Completed() bool {
+	return s.Status == "Completed"
+}
+
+// Failed reports whether SABnzbd gave up on the job
+func (s *HistorySlot) Failed() bool {
+	return s.Status == "Failed"
+}
+
+type statusResponse struct {
+	Status *bool  `json:"status"`
+	Error  string `json:"error"`
+}
+
+type queueResponse struct {
+	Queue struct {
+		Slots []QueueSlot `json:"slots"`
+	} `json:"queue"`
+}
+
+type historyResponse struct {
+	History struct {
+		Slots []HistorySlot `json:"slots"`
+	} `json:"history"`
+}
+
+type addResponse struct {
+	Status bool     `json:"status"`
+	NzoIDs []string `json:"nzo_ids"`
+}
+
+type configResponse struct {
+	Config struct {
+		Misc struct {
+			CompleteDir string `json:"complete_dir"`
+		} `json:"misc"`
+	} `json:"config"`
+}
@@ -0,0 +1,245 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/web-ui/services/vfs"
+)
+
+// maxInlineFile bounds what is buffered in memory: a file served from its body
+// (a .torrent, a subtitle) or an upload, which reaches the filesystem in one
+// Create once the client closes it.
+const maxInlineFile = 32 << 20
+
+// handlers adapts a vfs.FileSystem to the pkg/sftp request server. Every call
+// runs in the session's context: the request server's own one knows nothing
+// about who is logged in.
+type handlers struct {
+	ctx      context.Context
+	fs       vfs.FileSystem
+	fetch    Fetcher
+	readOnly bool
+}
+
+// NewHandlers serves fs to one session.
+func NewHandlers(ctx context.Context, fs vfs.FileSystem, fetch Fetcher, readOnly bool) sftp.Handlers {
+	h := &handlers{
+		ctx:      ctx,
+		fs:       fs,
+		fetch:    fetch,
+		readOnly: readOnly,
+	}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// fxError maps filesystem errors onto SFTP status codes. Anything without a
+// code of its own goes back as a generic failure carrying the message.
+func fxError(err error) error {
+	if err == nil {
+		return nil
+	}
+	he := vfs.HTTPErrorFromError(err)
+	switch he.Code {
+	case http.StatusNotFound:
+		return sftp.ErrSSHFxNoSuchFile
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return sftp.ErrSSHFxPermissionDenied
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	if he.Code >= http.StatusInternalServerError {
+		log.WithError(err).Warn("sftp request failed")
+	}
+	return err
+}
+
+func (s *handlers) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	fi, err := s.fs.Stat(s.ctx, r.Filepath)
+	if err != nil {
+		return nil, fxError(err)
+	}
+	if fi.IsDir {
+		return nil, sftp.ErrSSHFxFailure
+	}
+	body, u, err := s.fs.Open(s.ctx, r.Filepath)
+	if err != nil {
+		return nil, fxError(err)
+	}
+	if body != nil {
+		defer func() {
+			_ = body.Close()
+		}()
+		b, err := io.ReadAll(io.LimitReader(body, maxInlineFile+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > maxInlineFile {
+			return nil, errors.New("file is too large to serve inline")
+		}
+		return bytes.NewReader(b), nil
+	}
+	if u == nil || u.String() == "" {
+		return nil, errors.New("failed to resolve the content location")
+	}
+	// Torrent content is proxied from the streaming chain: unlike WebDAV and
+	// S3, SFTP has no way to send the client elsewhere.
+	return newURLReader(s.ctx, s.fetch, u, fi.Size), nil
+}
+
+func (s *handlers) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	if s.readOnly {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	return &fileWriter{h: s, path: r.Filepath}, nil
+}
+
+func (s *handlers) Filecmd(r *sftp.Request) error {
+	if r.Method == "Setstat" {
+		// Clients set times and modes after an upload; there is nothing to
+		// keep them in, and failing would abort the transfer.
+		return nil
+	}
+	if s.readOnly {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	switch r.Method {
+	case "Rename":
+		_, err := s.fs.Move(s.ctx, r.Filepath, r.Target, &vfs.MoveOptions{NoOverwrite: true})
+		return fxError(err)
+	case "Remove", "Rmdir":
+		return fxError(s.fs.RemoveAll(s.ctx, r.Filepath, nil))
+	case "Mkdir":
+		return fxError(s.fs.Mkdir(s.ctx, r.Filepath))
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename is rename with overwrite, which is what sshfs and rclone use.
+func (s *handlers) PosixRename(r *sftp.Request) error {
+	if s.readOnly {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	_, err := s.fs.Move(s.ctx, r.Filepath, r.Target, &vfs.MoveOptions{})
+	return fxError(err)
+}
+
+func (s *handlers) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		fis, err := s.fs.ReadDir(s.ctx, r.Filepath, false)
+		if err != nil {
+			return nil, fxError(err)
+		}
+		res := make(listerAt, 0, len(fis))
+		for _, fi := range fis {
+			res = append(res, &fileInfo{fi: fi, readOnly: s.readOnly})
+		}
+		return res, nil
+	case "Stat":
+		fi, err := s.fs.Stat(s.ctx, r.Filepath)
+		if err != nil {
+			return nil, fxError(err)
+		}
+		return listerAt{&fileInfo{fi: *fi, readOnly: s.readOnly}}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(fis []os.FileInfo, off int64) (int, error) {
+	if off >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(fis, l[off:])
+	if n+int(off) >= len(l) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fileInfo presents a vfs.FileInfo as what the request server lists.
+type fileInfo struct {
+	fi       vfs.FileInfo
+	readOnly bool
+}
+
+func (s *fileInfo) Name() string {
+	name := path.Base(strings.TrimSuffix(s.fi.Path, "/"))
+	if name == "." || name == "" {
+		return "/"
+	}
+	return name
+}
+
+func (s *fileInfo) Size() int64 {
+	return s.fi.Size
+}
+
+func (s *fileInfo) Mode() os.FileMode {
+	var m os.FileMode = 0644
+	if s.fi.IsDir {
+		m = os.ModeDir | 0755
+	}
+	if s.readOnly {
+		m &^= 0222
+	}
+	return m
+}
+
+func (s *fileInfo) ModTime() time.Time {
+	return s.fi.ModTime
+}
+
+func (s *fileInfo) IsDir() bool {
+	return s.fi.IsDir
+}
+
+func (s *fileInfo) Sys() any {
+	return nil
+}
+
+// fileWriter collects an upload. Clients pipeline writes, so they may arrive
+// out of order; the file only reaches the filesystem on Close, in one piece.
+type fileWriter struct {
+	mu   sync.Mutex
+	h    *handlers
+	path string
+	buf  []byte
+}
+
+func (s *fileWriter) WriteAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	end := off + int64(len(p))
+	if end > maxInlineFile {
+		return 0, errors.New("file is too large")
+	}
+	if end > int64(len(s.buf)) {
+		s.buf = append(s.buf, make([]byte, end-int64(len(s.buf)))...)
+	}
+	return copy(s.buf[off:], p), nil
+}
+
+func (s *fileWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, err := s.h.fs.Create(s.h.ctx, s.path, io.NopCloser(bytes.NewReader(s.buf)), nil)
+	return fxError(err)
+}
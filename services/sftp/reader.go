@@ -0,0 +1,125 @@
+package sftp
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sync"
+)
+
+// readWindow is how far a read may land from the current stream position and
+// still be served without opening a new one. Clients keep many reads in
+// flight and the request server answers them concurrently, so offsets arrive
+// slightly shuffled; the window absorbs that in both directions.
+const readWindow = 4 << 20
+
+// urlReader turns sequential ranged GETs into an io.ReaderAt. It keeps one
+// stream open and only reopens it at a new offset when a read lands outside
+// the window — a seek, not a reordering.
+type urlReader struct {
+	mu    sync.Mutex
+	ctx   context.Context
+	fetch Fetcher
+	u     *url.URL
+	size  int64
+
+	rc  io.ReadCloser
+	pos int64
+	// tail holds the bytes just before pos, for reads that arrive late.
+	tail []byte
+}
+
+func newURLReader(ctx context.Context, fetch Fetcher, u *url.URL, size int64) *urlReader {
+	return &urlReader{
+		ctx:   ctx,
+		fetch: fetch,
+		u:     u,
+		size:  size,
+	}
+}
+
+func (s *urlReader) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	if rest := s.size - off; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n := 0
+	// Late: whatever is still in the tail.
+	if start := s.pos - int64(len(s.tail)); off >= start && off < s.pos {
+		n = copy(p, s.tail[off-start:])
+		off += int64(n)
+	}
+	if n == len(p) {
+		return s.result(n, off)
+	}
+	// Early: skip ahead on the open stream instead of reopening.
+	if s.rc != nil && off > s.pos && off-s.pos <= readWindow {
+		if err := s.read(make([]byte, off-s.pos)); err != nil {
+			s.reset()
+		}
+	}
+	if s.rc == nil || off != s.pos {
+		if err := s.open(off); err != nil {
+			return n, err
+		}
+	}
+	if err := s.read(p[n:]); err != nil {
+		s.reset()
+		return n, err
+	}
+	return s.result(len(p), s.pos)
+}
+
+// result reports io.EOF along with the last bytes of the file, which is what
+// io.ReaderAt allows and saves the client a round trip.
+func (s *urlReader) result(n int, end int64) (int, error) {
+	if end >= s.size {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (s *urlReader) open(off int64) error {
+	s.reset()
+	rc, err := s.fetch(s.ctx, s.u, off)
+	if err != nil {
+		return err
+	}
+	s.rc = rc
+	s.pos = off
+	return nil
+}
+
+// read fills p from the stream and remembers it in the tail.
+func (s *urlReader) read(p []byte) error {
+	n, err := io.ReadFull(s.rc, p)
+	s.pos += int64(n)
+	s.tail = append(s.tail, p[:n]...)
+	// Trimmed in bulk, not per read: the tail is a few megabytes.
+	if len(s.tail) > 2*readWindow {
+		s.tail = append(s.tail[:0], s.tail[len(s.tail)-readWindow:]...)
+	}
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return err
+}
+
+func (s *urlReader) reset() {
+	if s.rc != nil {
+		_ = s.rc.Close()
+	}
+	s.rc = nil
+	s.tail = nil
+}
+
+func (s *urlReader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	return nil
+}
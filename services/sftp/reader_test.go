@@ -0,0 +1,60 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"testing"
+)
+
+func TestURLReaderReusesStream(t *testing.T) {
+	data := make([]byte, 10<<20)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	opens := 0
+	fetch := func(_ context.Context, _ *url.URL, off int64) (io.ReadCloser, error) {
+		opens++
+		return io.NopCloser(bytes.NewReader(data[off:])), nil
+	}
+	r := newURLReader(context.Background(), fetch, &url.URL{}, int64(len(data)))
+	defer func() {
+		_ = r.Close()
+	}()
+
+	const chunk = 32 << 10
+	// In-flight reads answered out of order: after the first one, pairs
+	// swapped.
+	offs := []int64{0}
+	for off := int64(chunk); off < int64(len(data)); off += 2 * chunk {
+		if off+chunk < int64(len(data)) {
+			offs = append(offs, off+chunk)
+		}
+		offs = append(offs, off)
+	}
+	got := make([]byte, len(data))
+	for _, off := range offs {
+		n, err := r.ReadAt(got[off:off+chunk], off)
+		if n != chunk || (err != nil && err != io.EOF) {
+			t.Fatalf("ReadAt(%d) = %d, %v", off, n, err)
+		}
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("content mismatch")
+	}
+	if opens != 1 {
+		t.Fatalf("expected one stream, opened %d", opens)
+	}
+
+	// A real seek reopens.
+	if _, err := r.ReadAt(got[:chunk], 0); err != nil {
+		t.Fatalf("ReadAt(0): %v", err)
+	}
+	if opens != 2 {
+		t.Fatalf("expected a second stream after seeking back, opened %d", opens)
+	}
+	if n, err := r.ReadAt(got[:chunk], int64(len(data))); n != 0 || err != io.EOF {
+		t.Fatalf("ReadAt(end) = %d, %v", n, err)
+	}
+}
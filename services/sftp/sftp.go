@@ -0,0 +1,320 @@
+// Package sftp serves a vfs.FileSystem over SSH, as an SFTP subsystem.
+//
+// Like services/webdav and services/s3 it is protocol-only: who may log in and
+// what a session sees are decided by an Auth supplied by handlers/sftp. There
+// is no shell, no exec and no port forwarding — a session can only open the
+// "sftp" subsystem.
+package sftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	co "github.com/webtor-io/web-ui/services/common"
+	"github.com/webtor-io/web-ui/services/vfs"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// TokenName is the access_token row whose token is the SFTP password;
+	// Scope* are what it is issued with.
+	TokenName  = "sftp"
+	ScopeRead  = "sftp:read"
+	ScopeWrite = "sftp:write"
+)
+
+const (
+	hostFlag          = "sftp-host"
+	portFlag          = "sftp-port"
+	hostKeyFlag       = "sftp-host-key"
+	publicAddressFlag = "sftp-public-address"
+)
+
+// handshakeTimeout bounds the SSH handshake, authentication included, so a
+// connection that never gets past it does not hold a goroutine forever.
+const handshakeTimeout = 30 * time.Second
+
+func RegisterFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   hostFlag,
+			Usage:  "sftp listening host",
+			Value:  "",
+			EnvVar: "SFTP_HOST",
+		},
+		cli.IntFlag{
+			Name:   portFlag,
+			Usage:  "sftp listening port (0 disables sftp)",
+			Value:  0,
+			EnvVar: "SFTP_PORT",
+		},
+		cli.StringFlag{
+			Name: hostKeyFlag,
+			// Every replica must present the same key, or clients refuse to
+			// reconnect once the load balancer picks another one.
+			Usage:  "sftp host private key, PEM or OpenSSH format",
+			EnvVar: "SFTP_HOST_KEY",
+		},
+		cli.StringFlag{
+			Name:   publicAddressFlag,
+			Usage:  "host:port users connect to, shown on the profile page (defaults to the domain and sftp port)",
+			EnvVar: "SFTP_PUBLIC_ADDRESS",
+		},
+	)
+}
+
+// Enabled tells whether the SFTP server is configured to run.
+func Enabled(c *cli.Context) bool {
+	return c.Int(portFlag) != 0
+}
+
+// PublicAddress is the host:port users point their clients at, or "" when
+// SFTP is disabled.
+func PublicAddress(c *cli.Context) string {
+	if !Enabled(c) {
+		return ""
+	}
+	if a := c.String(publicAddressFlag); a != "" {
+		return a
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(c.String(co.DomainFlag), "https://"), "http://")
+	host = strings.TrimSuffix(host, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return net.JoinHostPort(host, strconv.Itoa(c.Int(portFlag)))
+}
+
+// Identity is who a session belongs to, as settled by Auth at login.
+type Identity struct {
+	UserID string
+	// ReadOnly sessions may list and download but not change anything.
+	ReadOnly bool
+	// KeyID is the uploaded key a key login offered, for Context to record
+	// the login with; empty for password logins.
+	KeyID string
+}
+
+// Auth decides who may log in and builds what their session runs with.
+type Auth interface {
+	// Password checks a password login. Errors reject the login.
+	Password(ctx context.Context, user string, password string) (*Identity, error)
+	// PublicKey checks a key login. Errors reject the login. It runs for
+	// every key a client offers, before the client proves it holds the
+	// private half, so it must only look the key up: a public key is no
+	// secret.
+	PublicKey(ctx context.Context, user string, key ssh.PublicKey) (*Identity, error)
+	// Context returns the context every filesystem call of the session runs
+	// in — it carries whatever the filesystem needs to know about the user.
+	// It runs once the handshake is done, so it is where a login is
+	// recorded; errors end the session.
+	Context(ctx context.Context, id *Identity, remote net.Addr) (context.Context, error)
+}
+
+// Fetcher opens a content URL returned by vfs.FileSystem.Open, starting at
+// offset.
+type Fetcher func(ctx context.Context, u *url.URL, offset int64) (io.ReadCloser, error)
+
+// Extensions under which the Identity travels from the auth callbacks to the
+// session, in ssh.Permissions.
+const (
+	extUserID   = "webtor-user-id"
+	extReadOnly = "webtor-read-only"
+	extKeyID    = "webtor-key-id"
+)
+
+type Server struct {
+	host   string
+	port   int
+	fs     vfs.FileSystem
+	auth   Auth
+	fetch  Fetcher
+	config *ssh.ServerConfig
+	ln     net.Listener
+}
+
+// New returns nil when SFTP is disabled.
+func New(c *cli.Context, fs vfs.FileSystem, auth Auth, fetch Fetcher) (*Server, error) {
+	if !Enabled(c) {
+		return nil, nil
+	}
+	key := c.String(hostKeyFlag)
+	if key == "" {
+		return nil, errors.Errorf("%s is required when %s is set", hostKeyFlag, portFlag)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse sftp host key")
+	}
+	s := &Server{
+		host:  c.String(hostFlag),
+		port:  c.Int(portFlag),
+		fs:    fs,
+		auth:  auth,
+		fetch: fetch,
+	}
+	s.config = s.serverConfig(signer)
+	return s, nil
+}
+
+func (s *Server) serverConfig(signer ssh.Signer) *ssh.ServerConfig {
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+			defer cancel()
+			return permissions(s.auth.Password(ctx, meta.User(), string(password)))
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+			defer cancel()
+			return permissions(s.auth.PublicKey(ctx, meta.User(), key))
+		},
+		ServerVersion: "SSH-2.0-webtor",
+	}
+	config.AddHostKey(signer)
+	return config
+}
+
+func permissions(id *Identity, err error) (*ssh.Permissions, error) {
+	if err != nil {
+		return nil, err
+	}
+	if id == nil {
+		return nil, errors.New("access denied")
+	}
+	return &ssh.Permissions{
+		Extensions: map[string]string{
+			extUserID:   id.UserID,
+			extReadOnly: strconv.FormatBool(id.ReadOnly),
+			extKeyID:    id.KeyID,
+		},
+	}, nil
+}
+
+func identity(p *ssh.Permissions) *Identity {
+	ro, _ := strconv.ParseBool(p.Extensions[extReadOnly])
+	return &Identity{
+		UserID:   p.Extensions[extUserID],
+		ReadOnly: ro,
+		KeyID:    p.Extensions[extKeyID],
+	}
+}
+
+func (s *Server) Serve() error {
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to sftp listen to tcp connection")
+	}
+	s.ln = ln
+	log.Infof("serving sftp at %v", addr)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return errors.Wrap(err, "failed to accept sftp connection")
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) Close() {
+	log.Info("closing sftp")
+	defer func() {
+		log.Info("sftp closed")
+	}()
+	if s.ln != nil {
+		_ = s.ln.Close()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	sc, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		log.WithError(err).WithField("remote", conn.RemoteAddr().String()).Debug("sftp handshake failed")
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	defer func() {
+		_ = sc.Close()
+	}()
+	go ssh.DiscardRequests(reqs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	id := identity(sc.Permissions)
+	sctx, err := s.auth.Context(ctx, id, sc.RemoteAddr())
+	if err != nil {
+		log.WithError(err).WithField("user_id", id.UserID).Warn("failed to start sftp session")
+		return
+	}
+	h := NewHandlers(sctx, s.fs, s.fetch, id.ReadOnly)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			log.WithError(err).Warn("failed to accept sftp channel")
+			continue
+		}
+		go serveSession(ch, creqs, h)
+	}
+}
+
+// serveSession waits for the client to ask for the sftp subsystem and serves
+// it. Anything else — a shell, a command — gets told this is SFTP only.
+func serveSession(ch ssh.Channel, reqs <-chan *ssh.Request, h sftp.Handlers) {
+	defer func() {
+		_ = ch.Close()
+	}()
+	for req := range reqs {
+		switch {
+		case req.Type == "subsystem" && subsystem(req.Payload) == "sftp":
+			_ = req.Reply(true, nil)
+			rs := sftp.NewRequestServer(ch, h)
+			if err := rs.Serve(); err != nil && !errors.Is(err, io.EOF) {
+				log.WithError(err).Debug("sftp session ended")
+			}
+			_ = rs.Close()
+			return
+		case req.Type == "shell" || req.Type == "exec":
+			_ = req.Reply(true, nil)
+			_, _ = io.WriteString(ch.Stderr(), "This service only speaks SFTP.\r\n")
+			_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{1}))
+			return
+		default:
+			// env, pty-req and the like: refuse, but keep waiting for the
+			// subsystem request that usually follows.
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// subsystem decodes the name out of a "subsystem" request payload.
+func subsystem(payload []byte) string {
+	var p struct{ Name string }
+	if err := ssh.Unmarshal(payload, &p); err != nil {
+		return ""
+	}
+	return p.Name
+}
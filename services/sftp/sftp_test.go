@@ -0,0 +1,393 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"github.com/webtor-io/web-ui/services/vfs"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUser     = "11111111-2222-3333-4444-555555555555"
+	testPassword = "99999999-8888-7777-6666-555555555555"
+	movieDir     = "Movie One (2020)"
+)
+
+var testModTime = time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeFS is a small tree shaped like the library: content under /all served
+// by URL, .torrent files under /torrents served inline and writable.
+type fakeFS struct {
+	mu      sync.Mutex
+	dirs    map[string]bool
+	files   map[string][]byte
+	created map[string][]byte
+}
+
+func newFakeFS() *fakeFS {
+	video := make([]byte, 3<<20+123)
+	_, _ = rand.Read(video)
+	return &fakeFS{
+		dirs: map[string]bool{
+			"/":                      true,
+			"/all/":                  true,
+			"/torrents/":             true,
+			"/all/" + movieDir + "/": true,
+		},
+		files: map[string][]byte{
+			"/all/" + movieDir + "/video.mkv":    video,
+			"/torrents/" + movieDir + ".torrent": []byte("d4:infoe"),
+		},
+		created: map[string][]byte{},
+	}
+}
+
+func (f *fakeFS) Stat(_ context.Context, name string) (*vfs.FileInfo, error) {
+	if f.dirs[strings.TrimSuffix(name, "/")+"/"] {
+		return &vfs.FileInfo{Path: strings.TrimSuffix(name, "/") + "/", IsDir: true, ModTime: testModTime}, nil
+	}
+	if b, ok := f.files[name]; ok {
+		return &vfs.FileInfo{Path: name, Size: int64(len(b)), ModTime: testModTime}, nil
+	}
+	return nil, vfs.NewHTTPError(http.StatusNotFound, nil)
+}
+
+func (f *fakeFS) ReadDir(_ context.Context, name string, _ bool) ([]vfs.FileInfo, error) {
+	dir := strings.TrimSuffix(name, "/") + "/"
+	if !f.dirs[dir] {
+		return nil, vfs.NewHTTPError(http.StatusNotFound, nil)
+	}
+	var out []vfs.FileInfo
+	for d := range f.dirs {
+		if d != dir && strings.HasPrefix(d, dir) && strings.Count(strings.TrimPrefix(d, dir), "/") == 1 {
+			out = append(out, vfs.FileInfo{Path: d, IsDir: true, ModTime: testModTime})
+		}
+	}
+	for p, b := range f.files {
+		if strings.HasPrefix(p, dir) && !strings.Contains(strings.TrimPrefix(p, dir), "/") {
+			out = append(out, vfs.FileInfo{Path: p, Size: int64(len(b)), ModTime: testModTime})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeFS) Open(_ context.Context, name string) (io.ReadCloser, *url.URL, error) {
+	b, ok := f.files[name]
+	if !ok {
+		return nil, nil, vfs.NewHTTPError(http.StatusNotFound, nil)
+	}
+	if strings.HasPrefix(name, "/torrents/") {
+		return io.NopCloser(bytes.NewReader(b)), nil, nil
+	}
+	return nil, &url.URL{Scheme: "http", Host: "stream", Path: name}, nil
+}
+
+func (f *fakeFS) Create(_ context.Context, name string, body io.ReadCloser, _ *vfs.CreateOptions) (*vfs.FileInfo, bool, error) {
+	if !strings.HasPrefix(name, "/torrents/") {
+		return nil, false, vfs.NewHTTPError(http.StatusForbidden, nil)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created[name] = b
+	return &vfs.FileInfo{Path: name, Size: int64(len(b))}, true, nil
+}
+
+func (f *fakeFS) RemoveAll(_ context.Context, name string, _ *vfs.RemoveAllOptions) error {
+	if !strings.HasPrefix(name, "/torrents/") {
+		return vfs.NewHTTPError(http.StatusForbidden, nil)
+	}
+	if _, ok := f.files[name]; !ok {
+		return vfs.NewHTTPError(http.StatusNotFound, nil)
+	}
+	delete(f.files, name)
+	return nil
+}
+
+func (f *fakeFS) Mkdir(context.Context, string) error {
+	return vfs.NewHTTPError(http.StatusForbidden, nil)
+}
+
+func (f *fakeFS) Copy(context.Context, string, string, *vfs.CopyOptions) (bool, error) {
+	return false, vfs.NewHTTPError(http.StatusForbidden, nil)
+}
+
+func (f *fakeFS) Move(context.Context, string, string, *vfs.MoveOptions) (bool, error) {
+	return false, vfs.NewHTTPError(http.StatusForbidden, nil)
+}
+
+var _ vfs.FileSystem = (*fakeFS)(nil)
+
+// fetch serves the fake tree's content the way the streaming chain would.
+func (f *fakeFS) fetch(_ context.Context, u *url.URL, off int64) (io.ReadCloser, error) {
+	b, ok := f.files[u.Path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(b[off:])), nil
+}
+
+type fakeAuth struct {
+	readOnly bool
+	// key is the one uploaded key; Context stamps it in lastUsed the way
+	// handlers/sftp records a key login.
+	key      ssh.PublicKey
+	mu       sync.Mutex
+	offered  int
+	lastUsed map[string]time.Time
+}
+
+func (a *fakeAuth) Password(_ context.Context, _ string, password string) (*Identity, error) {
+	if password != testPassword {
+		return nil, errors.New("wrong password")
+	}
+	return &Identity{UserID: testUser, ReadOnly: a.readOnly}, nil
+}
+
+func (a *fakeAuth) PublicKey(_ context.Context, _ string, key ssh.PublicKey) (*Identity, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.offered++
+	if a.key == nil || ssh.FingerprintSHA256(key) != ssh.FingerprintSHA256(a.key) {
+		return nil, errors.New("no keys")
+	}
+	return &Identity{UserID: testUser, KeyID: "key-1"}, nil
+}
+
+func (a *fakeAuth) Context(ctx context.Context, id *Identity, _ net.Addr) (context.Context, error) {
+	if id.KeyID != "" {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.lastUsed == nil {
+			a.lastUsed = map[string]time.Time{}
+		}
+		a.lastUsed[id.KeyID] = time.Now()
+	}
+	return ctx, nil
+}
+
+// dial runs one server connection on a loopback port and logs in to it.
+func dial(t *testing.T, fs *fakeFS, auth Auth, password string) (*sftp.Client, error) {
+	t.Helper()
+	return dialWith(t, fs, auth, ssh.Password(password))
+}
+
+func dialWith(t *testing.T, fs *fakeFS, auth Auth, method ssh.AuthMethod) (*sftp.Client, error) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{fs: fs, auth: auth, fetch: fs.fetch}
+	s.config = s.serverConfig(signer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+	go func() {
+		if sc, err := ln.Accept(); err == nil {
+			s.serveConn(sc)
+		}
+	}()
+	cc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(cc, ln.Addr().String(), &ssh.ClientConfig{
+		User:            "anyone",
+		Auth:            []ssh.AuthMethod{method},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		_ = cc.Close()
+		return nil, err
+	}
+	client, err := sftp.NewClient(ssh.NewClient(conn, chans, reqs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client, nil
+}
+
+func TestWrongPasswordIsRejected(t *testing.T) {
+	if _, err := dial(t, newFakeFS(), &fakeAuth{}, "nope"); err == nil {
+		t.Fatal("expected the login to fail")
+	}
+}
+
+func TestListAndStat(t *testing.T) {
+	client, err := dial(t, newFakeFS(), &fakeAuth{}, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fis, err := client.ReadDir("/all/" + movieDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(fis) != 1 || fis[0].Name() != "video.mkv" || fis[0].Size() != 3<<20+123 {
+		t.Fatalf("unexpected listing: %+v", fis)
+	}
+	root, err := client.ReadDir("/")
+	if err != nil {
+		t.Fatalf("ReadDir(/): %v", err)
+	}
+	var names []string
+	for _, fi := range root {
+		if !fi.IsDir() {
+			t.Errorf("%s: expected a directory", fi.Name())
+		}
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "all,torrents" {
+		t.Fatalf("unexpected root: %v", names)
+	}
+	if _, err := client.Stat("/all/missing.mkv"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not-exist, got %v", err)
+	}
+}
+
+func TestReadProxiesContent(t *testing.T) {
+	fs := newFakeFS()
+	client, err := dial(t, fs, &fakeAuth{}, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]byte{
+		"/all/" + movieDir + "/video.mkv":    fs.files["/all/"+movieDir+"/video.mkv"],
+		"/torrents/" + movieDir + ".torrent": fs.files["/torrents/"+movieDir+".torrent"],
+	} {
+		f, err := client.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%s): %v", name, err)
+		}
+		var buf bytes.Buffer
+		// WriteTo keeps many reads in flight, like real clients do.
+		if _, err := f.WriteTo(&buf); err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		_ = f.Close()
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatalf("%s: got %d bytes, want %d", name, buf.Len(), len(want))
+		}
+	}
+}
+
+func TestUploadReachesFilesystemOnClose(t *testing.T) {
+	fs := newFakeFS()
+	client, err := dial(t, fs, &fakeAuth{}, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := bytes.Repeat([]byte("torrent!"), 50000)
+	f, err := client.Create("/torrents/New.torrent")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := f.ReadFrom(bytes.NewReader(body)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !bytes.Equal(fs.created["/torrents/New.torrent"], body) {
+		t.Fatalf("filesystem got %d bytes, want %d", len(fs.created["/torrents/New.torrent"]), len(body))
+	}
+	// Elsewhere the tree refuses, and the client must hear about it.
+	f, err = client.Create("/all/x.mkv")
+	if err == nil {
+		_, _ = f.Write([]byte("x"))
+		err = f.Close()
+	}
+	if err == nil {
+		t.Fatal("expected the upload outside torrents/ to fail")
+	}
+}
+
+func TestReadOnlySessionCannotWrite(t *testing.T) {
+	client, err := dial(t, newFakeFS(), &fakeAuth{readOnly: true}, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create("/torrents/New.torrent"); err == nil {
+		t.Fatal("expected Create to be refused")
+	}
+	if err := client.Remove("/torrents/" + movieDir + ".torrent"); err == nil {
+		t.Fatal("expected Remove to be refused")
+	}
+}
+
+// unsignedSigner offers a public key it cannot sign for, as anyone who only
+// knows a user's public key would.
+type unsignedSigner struct {
+	ssh.Signer
+}
+
+func (unsignedSigner) Sign(io.Reader, []byte) (*ssh.Signature, error) {
+	return nil, errors.New("no private key")
+}
+
+func TestOfferedKeyIsNotALogin(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := &fakeAuth{key: signer.PublicKey()}
+	if _, err := dialWith(t, newFakeFS(), auth, ssh.PublicKeys(unsignedSigner{signer})); err == nil {
+		t.Fatal("expected the login to fail")
+	}
+	auth.mu.Lock()
+	offered, used := auth.offered, len(auth.lastUsed)
+	auth.mu.Unlock()
+	if offered == 0 {
+		t.Fatal("the key was never offered")
+	}
+	if used != 0 {
+		t.Fatal("an unsigned key was recorded as used")
+	}
+
+	client, err := dialWith(t, newFakeFS(), auth, ssh.PublicKeys(signer))
+	if err != nil {
+		t.Fatalf("signed login: %v", err)
+	}
+	if _, err := client.ReadDir("/"); err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if _, ok := auth.lastUsed["key-1"]; !ok {
+		t.Fatal("a signed login was not recorded")
+	}
+}
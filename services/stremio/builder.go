@@ -103,6 +103,23 @@ func (s *Builder) BuildTorznabStreamsService(ctx context.Context, u *auth.User)
 	return NewTorznabCompositeStreamsByUserID(ctx, db, s.tn, u.ID, s.titles, s.tnCache)
 }
 
+// BuildUsenetStreamsService builds the Usenet half of Discover: the user's
+// Newznab indexers, whose streams carry NZB releases rather than
+// infohashes. Nothing past the indexers applies — dedup, availability and
+// playback tokens are all keyed by infohash.
+//
+// Returns nil when Torznab is not configured.
+func (s *Builder) BuildUsenetStreamsService(ctx context.Context, u *auth.User) (StreamsService, error) {
+	if s.tn == nil {
+		return nil, nil
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return NewUsenetCompositeStreamsByUserID(ctx, db, s.tn, u.ID, s.titles, s.tnCache)
+}
+
 // BuildPollStreamsService builds the pipeline the release-subscription
 // poller runs: the user's own addons and indexers, deduplicated by infohash.
 //
@@ -152,6 +169,23 @@ func (s *Builder) BuildPollStreamsService(ctx context.Context, u *auth.User) (St
 	return NewDedupStream(cs), nil
 }
 
+// BuildPollAddonStreamsService is BuildPollStreamsService without the
+// indexers. The poller runs it in feed mode for subscriptions whose indexers
+// it has already read through their latest-releases feeds this run: asking
+// those indexers again per subscription is exactly the cost the feed exists
+// to avoid, while addons have no feed and can only be asked.
+func (s *Builder) BuildPollAddonStreamsService(ctx context.Context, u *auth.User) (StreamsService, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	acs, err := NewAddonCompositeStreamsByUserID(ctx, db, s.cl, u.ID, s.cache, s.userAgent, s.requestURLMapper)
+	if err != nil {
+		return nil, err
+	}
+	return NewDedupStream(acs), nil
+}
+
 func (s *Builder) BuildStreamsService(ctx context.Context, u *auth.User, lr *lr.LinkResolver, apiClaims *api.Claims, cla *claims.Data, token string) (StreamsService, error) {
 	db := s.pg.Get()
 	if db == nil {
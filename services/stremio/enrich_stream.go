@@ -20,8 +20,9 @@ import (
 
 // EnrichStream wraps another StreamsService to enrich streams with URLs.
 //
-// Enrichment is intentionally lightweight: per stream we issue a single
-// hash-only availability check (Postgres) and emit a redirect URL whose
+// Enrichment is intentionally lightweight: one bulk availability check per
+// stream list against the debrid services that offer one, then per stream
+// a single hash-only availability check (Postgres), and we emit a redirect URL whose
 // JWT carries the file's filename (or fileIdx). The expensive bits — making
 // sure rest-api knows the magnet, listing its contents, picking the path —
 // are deferred to /stremio/resolve, which only runs when the user actually
@@ -33,8 +34,14 @@ import (
 type availabilityChecker interface {
 	CheckAvailability(ctx context.Context, id uuid.UUID, cla *claims.Data, hash string, fileIdx int, requiresPayment bool) (*common.CheckAvailabilityResult, error)
 	CheckTorrentAvailability(ctx context.Context, cla *claims.Data, hash string, requiresPayment bool) (*common.CheckAvailabilityResult, error)
+	CheckAvailabilityBatch(ctx context.Context, userID uuid.UUID, keys []models.CacheIndexKey) error
 }
 
+// batchCheckTimeout bounds the bulk availability check. It is shorter than
+// the per-stream deadline and runs first: a debrid API that stalls costs
+// the list its bulk answer, never the per-stream labels.
+const batchCheckTimeout = 2 * time.Second
+
 type EnrichStream struct {
 	inner        StreamsService
 	linkResolver availabilityChecker
@@ -77,6 +84,23 @@ func (s *EnrichStream) GetStreams(ctx context.Context, contentType, contentID st
 	enrichedStreams := make([]*StreamItem, len(response.Streams))
 	var wg sync.WaitGroup
 
+	// Refresh the cache index for the whole list up front, so the per-stream
+	// checks below see every file the user's debrid services hold — not only
+	// the ones someone happened to play. Streams whose file is unknown have
+	// no cache index entry to refresh.
+	var keys []models.CacheIndexKey
+	for _, stream := range response.Streams {
+		if stream.Url == "" && stream.InfoHash != "" && !stream.FileIdxUnknown {
+			keys = append(keys, models.CacheIndexKey{ResourceID: stream.InfoHash, FileIdx: stream.FileIdx})
+		}
+	}
+	bCtx, bCancel := context.WithTimeout(ctx, batchCheckTimeout)
+	if err := s.linkResolver.CheckAvailabilityBatch(bCtx, s.u.ID, keys); err != nil {
+		log.WithError(err).Warn("bulk availability check failed, labelling streams one by one")
+	}
+	bCancel()
+
+	// A fresh deadline, whatever the bulk check used up.
 	eCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -262,4 +286,6 @@ var servicesNames = map[models.StreamingBackendType]string{
 	models.StreamingBackendTypeWebtor:     "WT",
 	models.StreamingBackendTypeRealDebrid: "RD",
 	models.StreamingBackendTypeTorbox:     "TB",
+	models.StreamingBackendTypeAllDebrid:  "AD",
+	models.StreamingBackendTypePremiumize: "PM",
 }
@@ -1,6 +1,13 @@
 package stremio
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/auth"
+	"github.com/webtor-io/web-ui/services/link_resolver/common"
+)
 
 func lib(name string, cached bool) StreamItem {
 	return StreamItem{
@@ -64,3 +71,63 @@ func TestSortVaultFirstByResolution_NoCrossBucketMove(t *testing.T) {
 	// Different resolutions → each is its own singleton bucket → no movement.
 	assertOrder(t, streams, []string{"addon 1080p", "lib 720p"})
 }
+
+// The whole list goes to the debrid services in one bulk check before any
+// stream is labelled — that is what lets a stream nobody has played yet show
+// ⚡. Streams without a known file, or with a URL already, are left out.
+func TestGetStreamsChecksAvailabilityOnce(t *testing.T) {
+	inner := &mockStreamService{response: &StreamsResponse{Streams: []StreamItem{
+		{InfoHash: "aaaa", FileIdx: 2, Name: "Torrentio\n1080p"},
+		{InfoHash: "bbbb", FileIdxUnknown: true, Name: "Jackett\n1080p"},
+		{Url: "https://example.com/direct.mkv", Name: "Direct"},
+		{InfoHash: "cccc", Name: "Torrentio\n720p"},
+	}}}
+	fake := &fakeAvailability{
+		perFile: &common.CheckAvailabilityResult{Cached: true, ServiceType: models.StreamingBackendTypeTorbox},
+		torrent: &common.CheckAvailabilityResult{ServiceType: models.StreamingBackendTypeWebtor},
+	}
+	es := NewEnrichStream(inner, fake, &auth.User{}, nil, "https://webtor.io", "tok", "secret")
+	resp, err := es.GetStreams(context.Background(), "movie", "tt0133093")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Streams) != 4 {
+		t.Fatalf("got %d streams", len(resp.Streams))
+	}
+	want := []models.CacheIndexKey{{ResourceID: "aaaa", FileIdx: 2}, {ResourceID: "cccc", FileIdx: 0}}
+	if len(fake.batches) != 1 || len(fake.batches[0]) != len(want) {
+		t.Fatalf("batches = %v, want one with %v", fake.batches, want)
+	}
+	for i, k := range want {
+		if fake.batches[0][i] != k {
+			t.Errorf("batch[%d] = %v, want %v", i, fake.batches[0][i], k)
+		}
+	}
+}
+
+// A debrid API that stalls the bulk check must not take the per-stream
+// labels down with it: the bulk check gives up on its own, shorter deadline
+// and the streams are then checked one by one with a fresh one.
+func TestGetStreamsSurvivesStalledBatch(t *testing.T) {
+	inner := &mockStreamService{response: &StreamsResponse{Streams: []StreamItem{
+		{InfoHash: "aaaa", FileIdx: 2, Name: "Torrentio\n1080p"},
+	}}}
+	fake := &fakeAvailability{
+		perFile: &common.CheckAvailabilityResult{Cached: true, ServiceType: models.StreamingBackendTypeTorbox},
+		batch: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	es := NewEnrichStream(inner, fake, &auth.User{}, nil, "https://webtor.io", "tok", "secret")
+	resp, err := es.GetStreams(context.Background(), "movie", "tt0133093")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Streams) != 1 || !resp.Streams[0].Cached {
+		t.Fatalf("streams = %+v, want the one stream cached", resp.Streams)
+	}
+	if len(fake.deadlines) != 2 || !fake.deadlines[0].Before(fake.deadlines[1]) {
+		t.Errorf("deadlines = %v, want the bulk one first and earlier", fake.deadlines)
+	}
+}
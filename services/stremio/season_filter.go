@@ -127,14 +127,35 @@ func matchesRequestedSeason(title string, season int) bool {
 	if season <= 0 || strings.TrimSpace(title) == "" {
 		return true
 	}
-	named := false
+	named, covers := readSeason(title, season)
+	return covers || !named
+}
+
+// NamesSeason is the strict reading: the title names a season, and that
+// season — or a range of them — covers the one asked for.
+//
+// matchesRequestedSeason keeps a title that names nothing because the
+// indexer was asked about this show and season to begin with. A release
+// read off a latest-releases feed was asked about nothing, and there "names
+// no season" says nothing about which one it is.
+func NamesSeason(title string, season int) bool {
+	if season <= 0 || strings.TrimSpace(title) == "" {
+		return false
+	}
+	_, covers := readSeason(title, season)
+	return covers
+}
+
+// readSeason reports whether a title names any season at all, and whether
+// one of the seasons it names covers the requested one.
+func readSeason(title string, season int) (named, covers bool) {
 	for _, r := range seasonsFromWords(title) {
 		if r.from <= 0 {
 			continue
 		}
 		named = true
 		if r.covers(season) {
-			return true
+			return true, true
 		}
 	}
 	for _, re := range seasonPatterns {
@@ -145,9 +166,9 @@ func matchesRequestedSeason(title string, season int) bool {
 			}
 			named = true
 			if r.covers(season) {
-				return true
+				return true, true
 			}
 		}
 	}
-	return !named
+	return named, false
 }
@@ -85,3 +85,25 @@ func TestMatchesRequestedSeasonOnRealTitles(t *testing.T) {
 		}
 	}
 }
+
+// TestNamesSeason: the strict reading used on latest-releases feeds, where a
+// title that names no season is not evidence of anything.
+func TestNamesSeason(t *testing.T) {
+	for _, tt := range []struct {
+		title  string
+		season int
+		want   bool
+	}{
+		{"Укрытие / Silo / Сезон: 3 / Серии: 1-4 из 10 [2026 WEB-DL]", 3, true},
+		{"Silo S01-S03 COMPLETE 1080p", 3, true},
+		{"Silo.S01E01.2160p.WEB-DL", 3, false},
+		// The permissive guard keeps these; the strict one must not.
+		{"Silo 2160p ATVP WEB-DL DDP5 1 Atmos DoVi HDR", 3, false},
+		{"", 3, false},
+		{"Anything at all S01", 0, false},
+	} {
+		if got := NamesSeason(tt.title, tt.season); got != tt.want {
+			t.Errorf("NamesSeason(%q, %d) = %v, want %v", tt.title, tt.season, got, tt.want)
+		}
+	}
+}
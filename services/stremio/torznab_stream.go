@@ -111,37 +111,21 @@ func (s *TorznabStream) fetchStreams(ctx context.Context, contentType, contentID
 		APIKey: s.indexer.GetApiKey(),
 		Name:   s.indexer.GetName(),
 	}
-	cats := tn.CategoriesFor(s.indexer.Caps, contentType)
-
-	// Queries are attempts, not a fan-out. The title query is only built
-	// when the id query came back empty — building it eagerly would put a
-	// metadata lookup on the hot path of every single stream request, even
-	// for indexers that answer by id perfectly well.
-	var results []tn.Result
-	var lastErr error
-
-	if q := s.buildIDQuery(contentType, imdbID, season, episode); q != nil {
-		q.Cats = cats
-		r, err := s.cl.Search(ctx, ep, *q)
-		if err != nil {
-			lastErr = err
-		}
-		results = r
-	}
-
+	target := tn.Target{
+		ContentType: contentType,
+		IMDBID:      imdbID,
+		Season:      season,
+		Episode:     episode,
+	}
+	// What to ask, in which order, and whether a name query's answers are
+	// really this title is the planner's call — see tn.Planner.
+	results, err := tn.NewPlanner(s.indexer.Caps, contentType, s.titles).
+		Run(ctx, target, func(ctx context.Context, q tn.Query) ([]tn.Result, error) {
+			return s.cl.Search(ctx, ep, q)
+		})
 	if len(results) == 0 {
-		if q := s.buildTitleQuery(ctx, contentType, imdbID, season, episode); q != nil {
-			q.Cats = cats
-			r, err := s.cl.Search(ctx, ep, *q)
-			if err != nil {
-				lastErr = err
-			}
-			results = r
-		}
-	}
-	if len(results) == 0 {
-		if lastErr != nil {
-			return nil, lastErr
+		if err != nil {
+			return nil, err
 		}
 		return &StreamsResponse{Streams: []StreamItem{}}, nil
 	}
@@ -224,93 +208,13 @@ func filterBySeason(results []tn.Result, season int, indexer string) []tn.Result
 	return out
 }
 
-// buildIDQuery returns the query-by-imdb-id attempt, or nil when the
-// indexer cannot serve one. It is preferred whenever available: it is exact
-// and needs no metadata lookup. A nil caps snapshot means the probe never
-// succeeded, in which case trying the id query costs one request and can
-// save the fallback entirely.
-func (s *TorznabStream) buildIDQuery(contentType, imdbID string, season, episode *int) *tn.Query {
-	caps := s.indexer.Caps
-	if isSeriesQuery(contentType, season, episode) {
-		if caps == nil || (caps.SupportsTVIMDB() && caps.SupportsSeasonEpisode()) {
-			return &tn.Query{
-				Type:    tn.SearchTypeTV,
-				IMDBID:  imdbID,
-				Season:  season,
-				Episode: episode,
-			}
-		}
-		return nil
-	}
-	if caps == nil || caps.SupportsMovieIMDB() {
-		return &tn.Query{Type: tn.SearchTypeMovie, IMDBID: imdbID}
-	}
-	return nil
-}
-
-// buildTitleQuery returns the query-by-name attempt. This is the only mode
-// most bare tracker feeds support, and the fallback when an id query finds
-// nothing. Returns nil when the title cannot be resolved — an indexer is
-// better left empty than searched for a raw IMDb id.
-func (s *TorznabStream) buildTitleQuery(ctx context.Context, contentType, imdbID string, season, episode *int) *tn.Query {
-	caps := s.indexer.Caps
-	isSeries := isSeriesQuery(contentType, season, episode)
-
-	title, err := s.titles.Resolve(ctx, contentType, imdbID)
-	if err != nil || title == nil {
-		log.WithError(err).
-			WithField("content_id", imdbID).
-			Debug("failed to resolve title for torznab query")
-		return nil
-	}
-
-	if isSeries {
-		searchType := tn.SearchTypeTV
-		if caps != nil && !caps.SupportsTVSearch() {
-			searchType = tn.SearchTypeSearch
-		}
-		// Season and episode go as parameters whenever the indexer takes
-		// them, with the title alone in q. Baking "S05E14" into the query
-		// text instead assumes releases are named the Anglo per-episode
-		// way, and on a tracker that names them "Сезон 5" it matches
-		// nothing: measured against a live Jackett, the structured form
-		// returned 8 results for an episode where the S01E05 form
-		// returned zero.
-		if searchType == tn.SearchTypeTV && (caps == nil || caps.SupportsSeasonEpisode()) {
-			return &tn.Query{
-				Type:    searchType,
-				Q:       title.Name,
-				Season:  season,
-				Episode: episode,
-			}
-		}
-		// No structured support: the episode marker has to ride in the
-		// text, which is all such an indexer understands.
-		return &tn.Query{
-			Type: searchType,
-			Q:    fmt.Sprintf("%s S%02dE%02d", title.Name, *season, *episode),
-		}
-	}
-
-	q := title.Name
-	if title.Year != "" {
-		q = fmt.Sprintf("%s %s", title.Name, title.Year)
-	}
-	searchType := tn.SearchTypeMovie
-	if caps != nil && !caps.SupportsMovieSearch() {
-		searchType = tn.SearchTypeSearch
-	}
-	return &tn.Query{Type: searchType, Q: q}
-}
-
-func isSeriesQuery(contentType string, season, episode *int) bool {
-	return contentType == "series" && season != nil && episode != nil
-}
-
 // toStreamItems resolves infohashes in parallel and drops results we cannot
 // address. A result without a hash is not playable by any Webtor backend, so
 // dropping it here is better than surfacing a dead entry.
 func (s *TorznabStream) toStreamItems(ctx context.Context, results []tn.Result) []StreamItem {
+	if s.indexer.Usenet {
+		return s.nzbItems(results)
+	}
 	items := make([]*StreamItem, len(results))
 	sem := make(chan struct{}, hashResolveConcurrency)
 	downloads := make(chan struct{}, maxHashDownloads)
@@ -340,13 +244,8 @@ func (s *TorznabStream) toStreamItems(ctx context.Context, results []tn.Result)
 					Debug("failed to resolve infohash for torznab result")
 				return
 			}
-			items[index] = &StreamItem{
-				Name:     s.makeStreamName(res),
-				Title:    s.makeStreamTitle(res),
-				InfoHash: hash,
-				// A Torznab result names a torrent, not a file in it.
-				FileIdxUnknown: true,
-			}
+			item := s.StreamItem(res, hash)
+			items[index] = &item
 		}(i, r)
 	}
 	wg.Wait()
@@ -360,6 +259,48 @@ func (s *TorznabStream) toStreamItems(ctx context.Context, results []tn.Result)
 	return out
 }
 
+// nzbItems maps the results of a Usenet indexer. There is no infohash to
+// resolve and the download link is not fetched here: it counts against the
+// indexer's grab limit, so it is only fetched for the release the user
+// sends to a backend.
+func (s *TorznabStream) nzbItems(results []tn.Result) []StreamItem {
+	out := make([]StreamItem, 0, len(results))
+	for _, r := range results {
+		if r.Link == "" {
+			continue
+		}
+		var size int64
+		if r.Size <= math.MaxInt64 {
+			size = int64(r.Size)
+		}
+		out = append(out, StreamItem{
+			Name:  s.makeStreamName(r),
+			Title: s.makeStreamTitle(r),
+			NZB: &NZBRelease{
+				IndexerID: s.indexer.ID,
+				Link:      r.Link,
+				Name:      r.Title,
+				Size:      size,
+			},
+		})
+	}
+	return out
+}
+
+// StreamItem maps one result of this indexer, whose infohash is already
+// resolved, onto the row the rest of the pipeline reads. Exported for the
+// release-subscription feed reader, which reads results outside GetStreams
+// and has to label them exactly as a search would.
+func (s *TorznabStream) StreamItem(r tn.Result, hash string) StreamItem {
+	return StreamItem{
+		Name:     s.makeStreamName(r),
+		Title:    s.makeStreamTitle(r),
+		InfoHash: hash,
+		// A Torznab result names a torrent, not a file in it.
+		FileIdxUnknown: true,
+	}
+}
+
 // makeStreamName follows the addon convention: first line names the source,
 // the rest are labels. PreferredStream parses the resolution out of this
 // field, and Discover renders the extra lines as chips.
@@ -448,16 +389,32 @@ func parseContentID(contentID string) (string, *int, *int) {
 }
 
 // NewTorznabCompositeStreamsByUserID builds a CompositeStream over every
-// enabled indexer of a user, mirroring NewAddonCompositeStreamsByUserID.
+// enabled torrent indexer of a user, mirroring
+// NewAddonCompositeStreamsByUserID.
 func NewTorznabCompositeStreamsByUserID(ctx context.Context, db *pg.DB, cl *tn.Client, userID uuid.UUID, titles tn.TitleResolver, cache *lazymap.LazyMap[*StreamsResponse]) (*CompositeStream, error) {
 	indexers, err := models.GetUserTorznabIndexers(ctx, db, userID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get user torznab indexers")
 	}
+	return newIndexerCompositeStream(db, cl, indexers, titles, cache), nil
+}
+
+// NewUsenetCompositeStreamsByUserID builds a CompositeStream over every
+// enabled Usenet indexer of a user. Its streams carry NZB releases, not
+// infohashes, so it is kept out of every torrent pipeline.
+func NewUsenetCompositeStreamsByUserID(ctx context.Context, db *pg.DB, cl *tn.Client, userID uuid.UUID, titles tn.TitleResolver, cache *lazymap.LazyMap[*StreamsResponse]) (*CompositeStream, error) {
+	indexers, err := models.GetUserUsenetIndexers(ctx, db, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user usenet indexers")
+	}
+	return newIndexerCompositeStream(db, cl, indexers, titles, cache), nil
+}
+
+func newIndexerCompositeStream(db *pg.DB, cl *tn.Client, indexers []models.TorznabIndexer, titles tn.TitleResolver, cache *lazymap.LazyMap[*StreamsResponse]) *CompositeStream {
 	names := pgTrackerNames{db: db}
 	services := make([]StreamsService, 0, len(indexers))
 	for _, indexer := range indexers {
 		services = append(services, NewTorznabStream(cl, indexer, titles, cache, names))
 	}
-	return NewCompositeStream(services), nil
+	return NewCompositeStream(services)
 }
@@ -209,142 +209,6 @@ func TestTorznabStreamFallsBackToTitleQuery(t *testing.T) {
 	}
 }
 
-func TestTorznabStreamBuildQueries(t *testing.T) {
-	titles := &fakeTitles{title: &tn.Title{Name: "The Matrix", Year: "1999"}}
-
-	for _, tt := range []struct {
-		name           string
-		caps           *models.TorznabCaps
-		contentType    string
-		contentID      string
-		wantIDType     tn.SearchType // "" = no id query
-		wantIDQuery    bool
-		wantTitleT     tn.SearchType
-		wantTitleQ     string
-		wantStructured bool
-	}{
-		{
-			name:        "movie with imdbid support queries by id first",
-			caps:        &models.TorznabCaps{MovieParams: []string{"q", "imdbid"}},
-			contentType: "movie",
-			contentID:   "tt0133093",
-			wantIDQuery: true,
-			wantIDType:  tn.SearchTypeMovie,
-			wantTitleT:  tn.SearchTypeMovie,
-			wantTitleQ:  "The Matrix 1999",
-		},
-		{
-			name:        "movie without imdbid support has no id query",
-			caps:        &models.TorznabCaps{MovieParams: []string{"q"}},
-			contentType: "movie",
-			contentID:   "tt0133093",
-			wantTitleT:  tn.SearchTypeMovie,
-			wantTitleQ:  "The Matrix 1999",
-		},
-		{
-			name:        "indexer with only a plain search mode uses t=search",
-			caps:        &models.TorznabCaps{SearchParams: []string{"q"}},
-			contentType: "movie",
-			contentID:   "tt0133093",
-			wantTitleT:  tn.SearchTypeSearch,
-			wantTitleQ:  "The Matrix 1999",
-		},
-		{
-			name:        "unknown caps tries the id query anyway",
-			caps:        nil,
-			contentType: "movie",
-			contentID:   "tt0133093",
-			wantIDQuery: true,
-			wantIDType:  tn.SearchTypeMovie,
-			wantTitleT:  tn.SearchTypeMovie,
-			wantTitleQ:  "The Matrix 1999",
-		},
-		{
-			// No structured season/ep: the episode marker has to ride in
-			// the query text, because that is all the indexer understands.
-			name:        "series without season/ep support falls back to SxxEyy in the text",
-			caps:        &models.TorznabCaps{TVParams: []string{"q", "imdbid"}},
-			contentType: "series",
-			contentID:   "tt1839578:5:14",
-			wantTitleT:  tn.SearchTypeTV,
-			wantTitleQ:  "The Matrix S05E14",
-		},
-		{
-			name:           "series with season/ep support sends them as parameters",
-			caps:           &models.TorznabCaps{TVParams: []string{"q", "imdbid", "season", "ep"}},
-			contentType:    "series",
-			contentID:      "tt1839578:5:14",
-			wantIDQuery:    true,
-			wantIDType:     tn.SearchTypeTV,
-			wantTitleT:     tn.SearchTypeTV,
-			wantTitleQ:     "The Matrix",
-			wantStructured: true,
-		},
-		{
-			// The real-world shape that started this: rutracker advertises
-			// tv-search with q,season,ep and no imdbid, so everything runs
-			// through the title query — which must still carry the episode.
-			name:           "no imdbid but season/ep: title query carries the episode",
-			caps:           &models.TorznabCaps{TVParams: []string{"q", "season", "ep"}},
-			contentType:    "series",
-			contentID:      "tt1839578:5:14",
-			wantTitleT:     tn.SearchTypeTV,
-			wantTitleQ:     "The Matrix",
-			wantStructured: true,
-		},
-	} {
-		t.Run(tt.name, func(t *testing.T) {
-			s := newTestStream(t, "https://example.com/torznab", tt.caps, titles)
-			imdbID, season, ep := parseContentID(tt.contentID)
-
-			idQuery := s.buildIDQuery(tt.contentType, imdbID, season, ep)
-			if tt.wantIDQuery {
-				if idQuery == nil {
-					t.Fatalf("buildIDQuery() = nil, want a %q query", tt.wantIDType)
-				}
-				if idQuery.Type != tt.wantIDType {
-					t.Errorf("id query type = %q, want %q", idQuery.Type, tt.wantIDType)
-				}
-				if idQuery.IMDBID != imdbID {
-					t.Errorf("id query imdbid = %q, want %q", idQuery.IMDBID, imdbID)
-				}
-			} else if idQuery != nil {
-				t.Errorf("buildIDQuery() = %+v, want nil", idQuery)
-			}
-
-			titleQuery := s.buildTitleQuery(context.Background(), tt.contentType, imdbID, season, ep)
-			if titleQuery == nil {
-				t.Fatalf("buildTitleQuery() = nil, want a %q query", tt.wantTitleT)
-			}
-			if titleQuery.Type != tt.wantTitleT {
-				t.Errorf("title query type = %q, want %q", titleQuery.Type, tt.wantTitleT)
-			}
-			if titleQuery.Q != tt.wantTitleQ {
-				t.Errorf("title query q = %q, want %q", titleQuery.Q, tt.wantTitleQ)
-			}
-			hasStructured := titleQuery.Season != nil && titleQuery.Episode != nil
-			if hasStructured != tt.wantStructured {
-				t.Errorf("title query structured season/ep = %v, want %v", hasStructured, tt.wantStructured)
-			}
-		})
-	}
-}
-
-func TestTorznabStreamBuildQueriesWithoutTitle(t *testing.T) {
-	// When the metadata lookup fails there is no title query to fall back
-	// to; the id query must still be attempted rather than the whole
-	// indexer dropping out.
-	titles := &fakeTitles{err: context.DeadlineExceeded}
-	s := newTestStream(t, "https://example.com/torznab", &models.TorznabCaps{MovieParams: []string{"q", "imdbid"}}, titles)
-
-	if q := s.buildIDQuery("movie", "tt0133093", nil, nil); q == nil || q.IMDBID != "tt0133093" {
-		t.Errorf("buildIDQuery() = %+v, want the id query", q)
-	}
-	if q := s.buildTitleQuery(context.Background(), "movie", "tt0133093", nil, nil); q != nil {
-		t.Errorf("buildTitleQuery() = %+v, want nil when the title cannot be resolved", q)
-	}
-}
-
 func TestParseContentID(t *testing.T) {
 	for _, tt := range []struct {
 		in         string
@@ -745,19 +609,49 @@ func TestTorznabStreamDropsOtherSeasons(t *testing.T) {
 type fakeAvailability struct {
 	perFile *common.CheckAvailabilityResult
 	torrent *common.CheckAvailabilityResult
+	mu      sync.Mutex
 	calls   []string
+	batches [][]models.CacheIndexKey
+	// batch stands in for the bulk check when set; deadlines records the
+	// deadline of every call, bulk first.
+	batch     func(ctx context.Context) error
+	deadlines []time.Time
+}
+
+func (f *fakeAvailability) recordDeadline(ctx context.Context) {
+	d, _ := ctx.Deadline()
+	f.deadlines = append(f.deadlines, d)
 }
 
-func (f *fakeAvailability) CheckAvailability(_ context.Context, _ uuid.UUID, _ *claims.Data, _ string, _ int, _ bool) (*common.CheckAvailabilityResult, error) {
+func (f *fakeAvailability) CheckAvailability(ctx context.Context, _ uuid.UUID, _ *claims.Data, _ string, _ int, _ bool) (*common.CheckAvailabilityResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.calls = append(f.calls, "per-file")
+	f.recordDeadline(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return f.perFile, nil
 }
 
 func (f *fakeAvailability) CheckTorrentAvailability(_ context.Context, _ *claims.Data, _ string, _ bool) (*common.CheckAvailabilityResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.calls = append(f.calls, "torrent")
 	return f.torrent, nil
 }
 
+func (f *fakeAvailability) CheckAvailabilityBatch(ctx context.Context, _ uuid.UUID, keys []models.CacheIndexKey) error {
+	f.mu.Lock()
+	f.batches = append(f.batches, keys)
+	f.recordDeadline(ctx)
+	f.mu.Unlock()
+	if f.batch != nil {
+		return f.batch(ctx)
+	}
+	return nil
+}
+
 // TestIndexerStreamsAreLabelledWT: P2P means "no backend will serve this",
 // which is what a free user hitting the paywall sees. Indexer streams play
 // through Webtor exactly like addon streams do, so labelling them P2P — as
@@ -1,5 +1,7 @@
 package stremio
 
+import uuid "github.com/satori/go.uuid"
+
 // StreamBehaviorHints represents behavior hints specific to stream items
 type StreamBehaviorHints struct {
 	BingeGroup string `json:"bingeGroup,omitempty"`
@@ -29,6 +31,21 @@ type StreamItem struct {
 	// post-enrichment sort to surface "⚡" entries first within each
 	// resolution bucket. Not serialised to Stremio.
 	Cached bool `json:"-"`
+	// NZB is set instead of InfoHash on a release from a Usenet indexer. Its
+	// download link carries the indexer's API key, so it is never
+	// serialised: Discover hands the browser a sealed reference to it
+	// instead (see services/usenet).
+	NZB *NZBRelease `json:"-"`
+}
+
+// NZBRelease is a Usenet release a Newznab indexer returned
+type NZBRelease struct {
+	IndexerID uuid.UUID
+	// Link is the NZB download link, API key included
+	Link string
+	// Name is the release name as the indexer gives it
+	Name string
+	Size int64
 }
 
 type StreamsResponse struct {
@@ -0,0 +1,78 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// TestAlbumListRenders executes the library's music section: album cards
+// with and without MusicBrainz metadata, and the menu that only lists
+// Music once the library has some.
+func TestAlbumListRenders(t *testing.T) {
+	type menuItem struct {
+		Title     string
+		TargetURL string
+		Active    bool
+	}
+	funcs := debridFuncs(t)
+	funcs["withContext"] = func(ctx, data any) any { return map[string]any{"Ctx": ctx, "Data": data} }
+	funcs["getAlbumCover240"] = func(a *models.Album, ctx any) string { return "/cover/" + a.ResourceID }
+	funcs["makeMenu"] = func(args any) []menuItem {
+		return []menuItem{{"torrents", "/lib/", false}, {"music", "/lib/music", args == "music"}}
+	}
+	tpl, err := template.New("library").Funcs(funcs).ParseFiles(
+		"../../templates/partials/library/album_list.html",
+		"../../templates/partials/library/menu.html",
+	)
+	if err != nil {
+		t.Fatalf("failed to parse partials: %v", err)
+	}
+
+	year := int16(1972)
+	albums := []*models.Album{
+		{ResourceID: "r1", Artist: "Pink Floyd", Title: "Dark Side", Year: &year, AlbumMetadata: &models.AlbumMetadata{
+			MBID: "f5093c06", Artist: "Pink Floyd", Title: "The Dark Side of the Moon", Year: func() *int16 { y := int16(1973); return &y }(),
+		}},
+		{ResourceID: "r2", Artist: "", Title: "Untitled Tape"},
+	}
+	type ctx struct{ Lang string }
+	var buf bytes.Buffer
+	if err := tpl.ExecuteTemplate(&buf, "library/album_list", map[string]any{"Ctx": &ctx{Lang: "en"}, "Data": albums}); err != nil {
+		t.Fatalf("execute album list: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"The Dark Side of the Moon", "1973", "/cover/r1", "/r2", "Untitled Tape", "aspect-square"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("album list: missing %q", want)
+		}
+	}
+	if strings.Contains(out, "Dark Side<") || strings.Contains(out, "1972") {
+		t.Error("album list: parsed name shown over the metadata")
+	}
+
+	for _, tt := range []struct {
+		name    string
+		section string
+		count   int
+		want    bool
+	}{
+		{"no music", "torrents", 0, false},
+		{"some music", "torrents", 3, true},
+		{"on the music page", "music", 0, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]any{"Args": tt.section, "MusicCount": tt.count, "TorrentCount": 5}
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "library/menu", map[string]any{"Lang": "en", "Data": data}); err != nil {
+				t.Fatalf("execute menu: %v", err)
+			}
+			if got := strings.Contains(buf.String(), "/lib/music"); got != tt.want {
+				t.Errorf("music entry shown = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
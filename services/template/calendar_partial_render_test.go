@@ -0,0 +1,72 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/webtor-io/web-ui/services/i18n"
+)
+
+// TestCalendarPartialRenders executes the profile calendar section before
+// and after a link is issued. The webcal:// button is the part worth
+// guarding: html/template replaces an href in a scheme it does not know
+// with "#ZgotmplZ", and only a trusted URL value gets through.
+func TestCalendarPartialRenders(t *testing.T) {
+	locales, err := os.OpenRoot("../../locales")
+	if err != nil {
+		t.Fatalf("locales: %v", err)
+	}
+	defer locales.Close()
+	helper := i18n.NewHelper(i18n.New(locales.FS()))
+
+	funcs := template.FuncMap{
+		"t":        helper.T,
+		"langPath": func(lang, p string) string { return p },
+		"domain":   func() string { return "https://webtor.io" },
+		"json":     func(s string) template.JS { return template.JS(`"` + s + `"`) },
+	}
+	tpl, err := template.New("calendar.html").Funcs(funcs).
+		ParseFiles("../../templates/partials/profile/calendar.html")
+	if err != nil {
+		t.Fatalf("failed to parse partial: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		data map[string]interface{}
+		want []string
+	}{
+		{
+			name: "not issued",
+			data: map[string]interface{}{"CalendarURL": "", "CalendarWebcalURL": template.URL("")},
+			want: []string{`action="/calendar/url/generate"`},
+		},
+		{
+			name: "issued",
+			data: map[string]interface{}{
+				"CalendarURL":       "/s/abc/episodes.ics",
+				"CalendarWebcalURL": template.URL("webcal://webtor.io/s/abc/episodes.ics"),
+			},
+			want: []string{`value="https://webtor.io/s/abc/episodes.ics"`, `href="webcal://webtor.io/s/abc/episodes.ics"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "profile/calendar", map[string]interface{}{"Lang": "en", "Data": tt.data}); err != nil {
+				t.Fatalf("failed to render partial: %v", err)
+			}
+			out := buf.String()
+			if strings.Contains(out, "ZgotmplZ") || strings.Contains(out, "profile.calendar.") {
+				t.Errorf("an unsafe value or a missing translation:\n%s", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("rendered output is missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
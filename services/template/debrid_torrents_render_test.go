@@ -0,0 +1,174 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/i18n"
+)
+
+func debridFuncs(t *testing.T) template.FuncMap {
+	locales, err := os.OpenRoot("../../locales")
+	if err != nil {
+		t.Fatalf("locales: %v", err)
+	}
+	t.Cleanup(func() { _ = locales.Close() })
+	helper := i18n.NewHelper(i18n.New(locales.FS()))
+	return template.FuncMap{
+		"t":             helper.T,
+		"tp":            helper.Tp,
+		"langPath":      func(lang, p string) string { return p },
+		"bitsForHumans": func(b int64) string { return "1.2 GB" },
+	}
+}
+
+// TestDebridTorrentsRenders executes the debrid torrent list with a ready
+// torrent already in the library, one still downloading, an empty account
+// and a backend that could not be loaded at all.
+func TestDebridTorrentsRenders(t *testing.T) {
+	tpl, err := template.New("torrents.html").Funcs(debridFuncs(t)).
+		ParseFiles("../../templates/views/streaming/torrents.html")
+	if err != nil {
+		t.Fatalf("failed to parse view: %v", err)
+	}
+
+	type torrent struct {
+		ID        string
+		Hash      string
+		Name      string
+		Size      int64
+		Progress  float64
+		Status    string
+		Ready     bool
+		InLibrary bool
+	}
+	type data struct {
+		Backend  *models.StreamingBackend
+		Torrents []torrent
+		Message  string
+		ErrKey   string
+	}
+	type ctx struct {
+		Lang string
+		CSRF string
+		Data *data
+	}
+	backend := &models.StreamingBackend{ID: uuid.NewV4(), Type: models.StreamingBackendTypeRealDebrid}
+
+	for _, tt := range []struct {
+		name    string
+		data    *data
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "torrents",
+			data: &data{Backend: backend, Message: "toast.torrentDeleted", Torrents: []torrent{
+				{ID: "A", Hash: "aaaa", Name: "Sintel", Size: 300, Progress: 100, Status: "downloaded", Ready: true, InLibrary: true},
+				{ID: "B", Hash: "bbbb", Name: "Tears of Steel", Progress: 42.4, Status: "downloading"},
+			}},
+			want: []string{"Torrents in Real-Debrid", "Torrent deleted", "Sintel", "In library", "downloading · 42%",
+				"/streaming/backends/" + backend.ID.String() + "/torrents/import", `value="bbbb"`, `name="_csrf"`},
+		},
+		{
+			name:    "empty account",
+			data:    &data{Backend: backend},
+			want:    []string{"This account has no torrents."},
+			notWant: []string{"torrents/delete"},
+		},
+		{
+			name:    "unknown backend",
+			data:    &data{ErrKey: "error.not_found"},
+			want:    []string{"Resource not found."},
+			notWant: []string{"This account has no torrents."},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "main", &ctx{Lang: "en", CSRF: "csrf-token", Data: tt.data}); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			out := buf.String()
+			if strings.Contains(out, "<no value>") {
+				t.Error("the page rendered a missing parameter")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("rendered output is missing %q", w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("rendered output has %q", w)
+				}
+			}
+		})
+	}
+}
+
+// TestSendToDebridPartialRenders executes the resource page's send dialog
+// for a multi-file torrent and checks it stays hidden without an account.
+func TestSendToDebridPartialRenders(t *testing.T) {
+	tpl, err := template.New("send_to_debrid.html").Funcs(debridFuncs(t)).
+		ParseFiles("../../templates/partials/resource/send_to_debrid.html")
+	if err != nil {
+		t.Fatalf("failed to parse partial: %v", err)
+	}
+
+	type item struct {
+		Type  string
+		Index int
+		Name  string
+		Size  int64
+	}
+	type list struct{ Items []item }
+	type resource struct{ ID string }
+	type data struct {
+		Resource       *resource
+		List           *list
+		DebridBackends []*models.StreamingBackend
+	}
+	type withCtx struct {
+		Ctx  map[string]any
+		Data *data
+	}
+
+	backends := []*models.StreamingBackend{
+		{ID: uuid.NewV4(), Type: models.StreamingBackendTypeRealDebrid},
+		{ID: uuid.NewV4(), Type: models.StreamingBackendTypeTorbox},
+	}
+	d := &data{
+		Resource: &resource{ID: "08ada5a7a6183aae1e09d831df6748d566095a10"},
+		List: &list{Items: []item{
+			{Type: "directory", Name: "Extras"},
+			{Type: "file", Index: 3, Name: "Sintel.mkv", Size: 300},
+		}},
+		DebridBackends: backends,
+	}
+	var buf bytes.Buffer
+	if err := tpl.ExecuteTemplate(&buf, "resource/send_to_debrid", &withCtx{Ctx: map[string]any{"Lang": "en"}, Data: d}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out := buf.String()
+	for _, w := range []string{"Send to debrid", "TorBox", `value="` + backends[0].ID.String() + `"`, `name="file_idx" value="3"`, "/streaming/backends/send"} {
+		if !strings.Contains(out, w) {
+			t.Errorf("rendered output is missing %q", w)
+		}
+	}
+	if strings.Contains(out, "Extras") {
+		t.Error("directories must not be offered for selection")
+	}
+
+	buf.Reset()
+	if err := tpl.ExecuteTemplate(&buf, "resource/send_to_debrid", &withCtx{Ctx: map[string]any{"Lang": "en"}, Data: &data{Resource: d.Resource}}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "" {
+		t.Errorf("rendered without debrid accounts: %q", buf.String())
+	}
+}
@@ -0,0 +1,129 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+// TestMatchRenders executes the "wrong match?" page with search results
+// and the user's own correction, with an empty search, with a correction
+// other library owners outvoted, with a free one, for a resource outside
+// the user's library
+// and for a resource that cannot be corrected.
+func TestMatchRenders(t *testing.T) {
+	tpl, err := template.New("match.html").Funcs(debridFuncs(t)).
+		ParseFiles("../../templates/views/resource/match.html")
+	if err != nil {
+		t.Fatalf("failed to parse view: %v", err)
+	}
+
+	type data struct {
+		ResourceID  string
+		ContentType models.ContentType
+		ParsedTitle string
+		Current     *models.VideoMetadata
+		Override    *models.MatchOverride
+		Pending     bool
+		CanPick     bool
+		Paid        bool
+		Query       string
+		Year        string
+		Searched    bool
+		Candidates  []*models.VideoMetadata
+		Message     string
+		ErrKey      string
+	}
+	type ctx struct {
+		Lang string
+		CSRF string
+		Data *data
+	}
+	const rid = "08ada5a7a6183aae1e09d831df6748d566095a10"
+	year := int16(1999)
+	videoID := "tt0133093"
+	wrong := &models.VideoMetadata{VideoID: "tt0000001", Title: "Matrix Reloaded Fan Cut"}
+
+	for _, tt := range []struct {
+		name    string
+		data    *data
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "results",
+			data: &data{
+				ResourceID: rid, ContentType: models.ContentTypeMovie, ParsedTitle: "The Matrix", Current: wrong,
+				Override: &models.MatchOverride{VideoID: &videoID}, CanPick: true, Query: "matrix", Year: "1999", Searched: true,
+				Candidates: []*models.VideoMetadata{{VideoID: videoID, Title: "The Matrix", Year: &year}},
+				Message:    "toast.matchSaved",
+			},
+			want: []string{"Match saved", "Matrix Reloaded Fan Cut", "You picked", `name="reset"`,
+				"The Matrix (1999)", `name="video_id" value="tt0133093"`, `name="not_media"`, `name="_csrf"`,
+				"/" + rid + "/match"},
+			notWant: []string{"Nothing found"},
+		},
+		{
+			name:    "no results",
+			data:    &data{ResourceID: rid, ContentType: models.ContentTypeSeries, ParsedTitle: "Psych", CanPick: true, Query: "psyh", Searched: true},
+			want:    []string{"Nothing found", "Recognised as", "Nothing"},
+			notWant: []string{`name="reset"`, `name="video_id"`},
+		},
+		{
+			name: "pending",
+			data: &data{
+				ResourceID: rid, ContentType: models.ContentTypeMovie, ParsedTitle: "The Matrix", Current: wrong,
+				Override: &models.MatchOverride{VideoID: &videoID}, Pending: true, CanPick: true, Paid: true,
+				Query: "The Matrix", Message: "toast.matchPending",
+			},
+			want:    []string{"others picked another title", "so theirs is used", `name="reset"`},
+			notWant: []string{"Only picks made on a paid plan"},
+		},
+		{
+			name: "free",
+			data: &data{
+				ResourceID: rid, ContentType: models.ContentTypeMovie, ParsedTitle: "The Matrix",
+				Current:  &models.VideoMetadata{VideoID: videoID, Title: "The Matrix"},
+				Override: &models.MatchOverride{VideoID: &videoID}, CanPick: true, Query: "The Matrix",
+			},
+			want:    []string{"Used for this torrent", "Only picks made on a paid plan"},
+			notWant: []string{"so theirs is used"},
+		},
+		{
+			name:    "not in library",
+			data:    &data{ResourceID: rid, ContentType: models.ContentTypeMovie, ParsedTitle: "The Matrix", Current: wrong, Query: "The Matrix"},
+			want:    []string{"Add this torrent to your library", "Recognised as"},
+			notWant: []string{`name="q"`, `name="not_media"`, `name="video_id"`},
+		},
+		{
+			name:    "no target",
+			data:    &data{ResourceID: rid, ErrKey: "error.matchNoTarget"},
+			want:    []string{"holds several titles", "Back to the torrent"},
+			notWant: []string{`name="q"`, `name="not_media"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "main", &ctx{Lang: "en", CSRF: "csrf-token", Data: tt.data}); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			out := buf.String()
+			if strings.Contains(out, "<no value>") {
+				t.Error("the page rendered a missing parameter")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("rendered output is missing %q", w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("rendered output has %q", w)
+				}
+			}
+		})
+	}
+}
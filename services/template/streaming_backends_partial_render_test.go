@@ -0,0 +1,130 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/webtor-io/web-ui/models"
+)
+
+// TestStreamingBackendsPartialRenders executes the profile streaming backends
+// section with and without account details. Each service reports a different
+// subset of them, so every combination of nil pointers has to render.
+func TestStreamingBackendsPartialRenders(t *testing.T) {
+	funcs := template.FuncMap{
+		"t":             func(lang, key string, args ...interface{}) string { return key },
+		"langPath":      func(lang, p string) string { return p },
+		"asset":         func(p string) template.HTML { return template.HTML("<script src=\"" + p + "\"></script>") },
+		"isPaid":        func(_ interface{}) bool { return false },
+		"bitsForHumans": func(b int64) string { return "1.2 TB" },
+	}
+	tpl, err := template.New("streaming_backends.html").Funcs(funcs).
+		ParseFiles("../../templates/partials/profile/streaming_backends.html")
+	if err != nil {
+		t.Fatalf("failed to parse partial: %v", err)
+	}
+
+	ok := models.StreamingBackendStatusOK
+	now := time.Now()
+	soon := now.Add(3 * 24 * time.Hour)
+	past := now.Add(-24 * time.Hour)
+	points := 1200
+	usage := 0.42
+	downloaded := int64(1 << 40)
+	torrentsID := uuid.NewV4()
+
+	for _, tt := range []struct {
+		name    string
+		data    []*models.StreamingBackend
+		types   []map[string]any
+		want    []string
+		notWant []string
+	}{
+		{name: "empty list", want: []string{"profile.backends.noBackends"}},
+		{
+			// A backend added before the first account check.
+			name:    "unchecked backend",
+			data:    []*models.StreamingBackend{{ID: uuid.NewV4(), Type: models.StreamingBackendTypeRealDebrid}},
+			notWant: []string{"profile.backends.points", "profile.backends.premiumUntil"},
+		},
+		{
+			name: "premium ending soon",
+			data: []*models.StreamingBackend{{
+				ID: uuid.NewV4(), Type: models.StreamingBackendTypeRealDebrid,
+				LastStatus: &ok, LastCheckedAt: &now, PremiumUntil: &soon, Points: &points,
+			}},
+			want: []string{"profile.backends.premiumUntil", soon.Format("2006-01-02"), "text-warning", "1200"},
+		},
+		{
+			name: "usage and traffic",
+			data: []*models.StreamingBackend{{
+				ID: uuid.NewV4(), Type: models.StreamingBackendTypePremiumize,
+				Usage: &usage, Downloaded: &downloaded,
+			}},
+			want: []string{"42%", "1.2 TB"},
+		},
+		{
+			// Only types whose torrent list Webtor manages link to it.
+			name: "torrent list link",
+			data: []*models.StreamingBackend{
+				{ID: torrentsID, Type: models.StreamingBackendTypeRealDebrid},
+				{ID: uuid.NewV4(), Type: models.StreamingBackendTypePremiumize},
+			},
+			types: []map[string]any{
+				{"Type": "real_debrid", "DisplayName": "Real-Debrid", "ManagesTorrents": true},
+				{"Type": "premiumize", "DisplayName": "Premiumize", "ManagesTorrents": false},
+			},
+			want:    []string{"/streaming/backends/" + torrentsID.String() + "/torrents", "Real-Debrid"},
+			notWant: []string{`href="/usenet"`, `name="files_url"`},
+		},
+		{
+			// Usenet backends link to the download list and bring the files
+			// URL field to the add form.
+			name: "usenet backend",
+			data: []*models.StreamingBackend{{ID: uuid.NewV4(), Type: models.StreamingBackendTypeSABnzbd}},
+			types: []map[string]any{
+				{"Type": "torbox", "DisplayName": "TorBox", "ManagesTorrents": true, "ServesUsenet": true},
+				{"Type": "sabnzbd", "DisplayName": "SABnzbd", "ServesUsenet": true},
+			},
+			want: []string{`href="/usenet"`, `name="files_url"`, "SABnzbd"},
+		},
+		{
+			name: "expired plan",
+			data: []*models.StreamingBackend{{
+				ID: uuid.NewV4(), Type: models.StreamingBackendTypeTorbox, PremiumUntil: &past,
+			}},
+			want: []string{"profile.backends.premiumExpired"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := map[string]interface{}{
+				"Lang":   "en",
+				"Claims": nil,
+				"Data": map[string]interface{}{
+					"StreamingBackends":     tt.data,
+					"AvailableBackendTypes": tt.types,
+					"ErrKey":                "",
+				},
+			}
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "profile/streaming_backends", ctx); err != nil {
+				t.Fatalf("failed to render partial: %v", err)
+			}
+			out := buf.String()
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("rendered output is missing %q", w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("rendered output has %q", w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+	"github.com/webtor-io/web-ui/services/i18n"
+)
+
+// TestSubscriptionMailPartialRenders executes the subscription mail settings
+// section for an account on the defaults and for one with everything set:
+// the stored digest mode, both quiet-hour edges and the zone must come back
+// selected, or saving the form unchanged would quietly reset them.
+func TestSubscriptionMailPartialRenders(t *testing.T) {
+	locales, err := os.OpenRoot("../../locales")
+	if err != nil {
+		t.Fatalf("locales: %v", err)
+	}
+	defer locales.Close()
+	helper := i18n.NewHelper(i18n.New(locales.FS()))
+
+	funcs := template.FuncMap{
+		"t":           helper.T,
+		"tp":          helper.Tp,
+		"langPath":    func(lang, p string) string { return p },
+		"withContext": func(ctx, data interface{}) interface{} { return map[string]interface{}{"Ctx": ctx, "Data": data} },
+		"seq": func(from, to int) []int {
+			var out []int
+			for i := from; i <= to; i++ {
+				out = append(out, i)
+			}
+			return out
+		},
+	}
+	tpl, err := template.New("subscription_mail.html").Funcs(funcs).
+		ParseFiles("../../templates/partials/profile/subscription_mail.html")
+	if err != nil {
+		t.Fatalf("failed to parse partial: %v", err)
+	}
+
+	weekly, zone := models.SubscriptionDigestWeekly, "Europe/Berlin"
+	start, end := int16(22), int16(7)
+
+	for _, tt := range []struct {
+		name string
+		data *models.UserSettings
+		want []string
+	}{
+		{
+			name: "defaults",
+			data: &models.UserSettings{},
+			want: []string{`value="immediate" selected`, `value="" selected`},
+		},
+		{
+			name: "everything set",
+			data: &models.UserSettings{SubscriptionDigest: &weekly, QuietHoursStart: &start, QuietHoursEnd: &end, TimeZone: &zone},
+			want: []string{`value="weekly" selected`, `value="22" selected`, `value="7" selected`, `value="Europe/Berlin"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := map[string]interface{}{"Lang": "en", "CSRF": "token"}
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "profile/subscription_mail", map[string]interface{}{"Ctx": ctx, "Data": tt.data}); err != nil {
+				t.Fatalf("failed to render partial: %v", err)
+			}
+			out := buf.String()
+			if strings.Contains(out, "<no value>") || strings.Contains(out, "profile.subscriptionMail.") {
+				t.Errorf("a parameter or translation did not arrive:\n%s", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("rendered output is missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
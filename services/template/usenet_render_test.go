@@ -0,0 +1,81 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/webtor-io/web-ui/models"
+)
+
+// TestUsenetDownloadsRenders executes the Usenet download list with a ready,
+// a running and a failed download, and with nothing sent yet.
+func TestUsenetDownloadsRenders(t *testing.T) {
+	tpl, err := template.New("usenet.html").Funcs(debridFuncs(t)).
+		ParseFiles("../../templates/views/streaming/usenet.html")
+	if err != nil {
+		t.Fatalf("failed to parse view: %v", err)
+	}
+
+	type data struct {
+		Downloads []*models.UsenetDownload
+		Message   string
+		ErrKey    string
+	}
+	type ctx struct {
+		Lang string
+		CSRF string
+		Data *data
+	}
+	sab := &models.StreamingBackend{ID: uuid.NewV4(), Type: models.StreamingBackendTypeSABnzbd}
+	reason := "Out of retention"
+	ready := &models.UsenetDownload{
+		ID: uuid.NewV4(), Name: "Sintel.2010.1080p", Size: 300, Status: models.UsenetDownloadStatusReady, Progress: 100,
+		Files: []models.UsenetDownloadFile{{ID: "7", Name: "Sintel.mkv", Size: 290}}, StreamingBackend: sab,
+	}
+	running := &models.UsenetDownload{ID: uuid.NewV4(), Name: "Tears.of.Steel", Status: models.UsenetDownloadStatusDownloading, Progress: 42.4}
+	failed := &models.UsenetDownload{ID: uuid.NewV4(), Name: "Cosmos.Laundromat", Status: models.UsenetDownloadStatusFailed, Error: &reason}
+
+	for _, tt := range []struct {
+		name    string
+		data    *data
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "downloads",
+			data: &data{Message: "toast.usenetDeleted", Downloads: []*models.UsenetDownload{ready, running, failed}},
+			want: []string{"Download deleted", "SABnzbd", "/usenet/" + ready.ID.String() + "/play?file=7", "Sintel.mkv",
+				"Downloading · 42%", "Failed: Out of retention", "/usenet/" + failed.ID.String() + "/delete", `name="_csrf"`},
+		},
+		{
+			name:    "nothing sent",
+			data:    &data{},
+			want:    []string{"Nothing sent yet."},
+			notWant: []string{"/delete"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buf, "main", &ctx{Lang: "en", CSRF: "csrf-token", Data: tt.data}); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			out := buf.String()
+			if strings.Contains(out, "<no value>") {
+				t.Error("the page rendered a missing parameter")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("rendered output is missing %q", w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("rendered output has %q", w)
+				}
+			}
+		})
+	}
+}
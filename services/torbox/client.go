@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
@@ -13,6 +14,30 @@ import (
 	"github.com/pkg/errors"
 )
 
+// APIError is an error response of the TorBox API
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP error: %d %s", e.StatusCode, e.Status)
+	}
+	return fmt.Sprintf("API error (code %d): %s", e.StatusCode, e.Message)
+}
+
+// InvalidCredentials reports whether the token was rejected
+func (e *APIError) InvalidCredentials() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// RateLimited reports whether the request was throttled
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
 // Client is a TorBox API client
 type Client struct {
 	httpClient *http.Client
@@ -48,11 +73,14 @@ func (s *Client) GetUser(ctx context.Context) (*User, error) {
 	return &resp.Data, nil
 }
 
-// CreateTorrent creates a new torrent from a magnet link or torrent file
-func (s *Client) CreateTorrent(ctx context.Context, magnet string) (*CreateTorrentData, error) {
+// CreateTorrent creates a new torrent from a magnet link or torrent file.
+// With onlyIfCached TorBox refuses torrents it has not cached yet.
+func (s *Client) CreateTorrent(ctx context.Context, magnet string, onlyIfCached bool) (*CreateTorrentData, error) {
 	params := url.Values{}
 	params.Set("magnet", magnet)
-	params.Set("add_only_if_cached", "true")
+	if onlyIfCached {
+		params.Set("add_only_if_cached", "true")
+	}
 
 	body, err := s.post(ctx, "/v1/api/torrents/createtorrent", params)
 	if err != nil {
@@ -204,6 +232,117 @@ func (s *Client) CheckCached(ctx context.Context, hashes []string, format string
 	return result, nil
 }
 
+// CreateUsenetDownload uploads an NZB and starts downloading the release
+// it describes
+func (s *Client) CreateUsenetDownload(ctx context.Context, name string, nzb []byte) (*CreateUsenetData, error) {
+	fields := map[string]string{}
+	if name != "" {
+		fields["name"] = name
+	}
+	body, err := s.postMultipart(ctx, "/v1/api/usenet/createusenetdownload", fields, "file", name+".nzb", nzb)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create usenet download")
+	}
+
+	var resp struct {
+		Success bool             `json:"success"`
+		Detail  string           `json:"detail"`
+		Data    CreateUsenetData `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to parse create usenet download response")
+	}
+
+	if !resp.Success {
+		return nil, fmt.Errorf("API error: %s", resp.Detail)
+	}
+
+	return &resp.Data, nil
+}
+
+// GetUsenetDownload retrieves one of the user's Usenet downloads
+func (s *Client) GetUsenetDownload(ctx context.Context, id int) (*UsenetDownload, error) {
+	params := url.Values{}
+	params.Set("bypass_cache", "true")
+	params.Set("id", fmt.Sprintf("%d", id))
+	body, err := s.get(ctx, "/v1/api/usenet/mylist", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get usenet download")
+	}
+
+	var resp struct {
+		Success bool           `json:"success"`
+		Detail  string         `json:"detail"`
+		Data    UsenetDownload `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to parse usenet download response")
+	}
+
+	if !resp.Success {
+		return nil, fmt.Errorf("API error: %s", resp.Detail)
+	}
+
+	return &resp.Data, nil
+}
+
+// RequestUsenetDownloadLink requests a download link for a file of a
+// finished Usenet download
+func (s *Client) RequestUsenetDownloadLink(ctx context.Context, usenetID, fileID int) (string, error) {
+	params := url.Values{}
+	params.Set("usenet_id", fmt.Sprintf("%d", usenetID))
+	params.Set("file_id", fmt.Sprintf("%d", fileID))
+	params.Set("token", s.apiToken)
+
+	body, err := s.get(ctx, "/v1/api/usenet/requestdl", params)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to request usenet download link")
+	}
+
+	var resp struct {
+		Success bool   `json:"success"`
+		Detail  string `json:"detail"`
+		Data    string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", errors.Wrap(err, "failed to parse request usenet download link response")
+	}
+
+	if !resp.Success {
+		return "", fmt.Errorf("API error: %s", resp.Detail)
+	}
+
+	return resp.Data, nil
+}
+
+// ControlUsenetDownload controls a Usenet download (pause, resume, delete, etc.)
+func (s *Client) ControlUsenetDownload(ctx context.Context, usenetID int, operation string) error {
+	payload := map[string]any{
+		"operation": operation,
+		"usenet_id": usenetID,
+		"all":       false,
+	}
+
+	body, err := s.postJSON(ctx, "/v1/api/usenet/controlusenetdownload", payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to control usenet download")
+	}
+
+	var resp struct {
+		Success bool   `json:"success"`
+		Detail  string `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return errors.Wrap(err, "failed to parse control usenet download response")
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("API error: %s", resp.Detail)
+	}
+
+	return nil
+}
+
 // get performs a GET request
 func (s *Client) get(ctx context.Context, path string, params url.Values) ([]byte, error) {
 	urlStr := s.baseURL + path
@@ -259,6 +398,39 @@ func (s *Client) postJSON(ctx context.Context, path string, payload any) ([]byte
 	return s.doRequest(req)
 }
 
+// postMultipart performs a POST request with a multipart body carrying the
+// fields and one file
+func (s *Client) postMultipart(ctx context.Context, path string, fields map[string]string, fileField, fileName string, file []byte) ([]byte, error) {
+	urlStr := s.baseURL + path
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, fmt.Errorf("failed to write form field: %w", err)
+		}
+	}
+	fw, err := w.CreateFormFile(fileField, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := fw.Write(file); err != nil {
+		return nil, fmt.Errorf("failed to write form file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", urlStr, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return s.doRequest(req)
+}
+
 // put performs a PUT request
 func (s *Client) put(ctx context.Context, path string, params url.Values) ([]byte, error) {
 	urlStr := s.baseURL + path
@@ -322,15 +494,14 @@ func (s *Client) doRequest(req *http.Request) ([]byte, error) {
 			Detail  string `json:"detail"`
 			Error   string `json:"error"`
 		}
+		e := &APIError{StatusCode: resp.StatusCode, Status: resp.Status}
 		if err := json.Unmarshal(body, &apiError); err == nil {
-			if apiError.Detail != "" {
-				return nil, fmt.Errorf("API error (code %d): %s", resp.StatusCode, apiError.Detail)
-			}
-			if apiError.Error != "" {
-				return nil, fmt.Errorf("API error (code %d): %s", resp.StatusCode, apiError.Error)
+			e.Message = apiError.Detail
+			if e.Message == "" {
+				e.Message = apiError.Error
 			}
 		}
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+		return nil, e
 	}
 
 	return body, nil
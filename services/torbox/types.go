@@ -8,8 +8,10 @@ type User struct {
 	TotalDownloaded int64  `json:"total_downloaded"`
 	Customer        string `json:"customer"`
 	ExpiresAt       string `json:"expires_at"`
-	CreatedAt       string `json:"created_at"`
-	UpdatedAt       string `json:"updated_at"`
+	// PremiumExpiresAt is when the paid plan runs out
+	PremiumExpiresAt string `json:"premium_expires_at"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
 }
 
 // Torrent represents a torrent in TorBox
@@ -106,3 +108,26 @@ type CheckCachedResponse struct {
 	Detail  string                   `json:"detail"`
 	Data    map[string]CachedTorrent `json:"data"`
 }
+
+// UsenetDownload represents a Usenet download in TorBox
+type UsenetDownload struct {
+	ID               int     `json:"id"`
+	Hash             string  `json:"hash"`
+	Name             string  `json:"name"`
+	Size             int64   `json:"size"`
+	Progress         float64 `json:"progress"`
+	DownloadState    string  `json:"download_state"`
+	DownloadPresent  bool    `json:"download_present"`
+	DownloadFinished bool    `json:"download_finished"`
+	Active           bool    `json:"active"`
+	Files            []File  `json:"files"`
+	CreatedAt        string  `json:"created_at"`
+	UpdatedAt        string  `json:"updated_at"`
+}
+
+// CreateUsenetData represents the data returned when creating a Usenet download
+type CreateUsenetData struct {
+	UsenetDownloadID int    `json:"usenetdownload_id"`
+	Hash             string `json:"hash"`
+	AuthID           string `json:"auth_id"`
+}
@@ -284,14 +284,55 @@ func (c *Client) Search(ctx context.Context, ep Endpoint, q Query) ([]Result, er
 	if err != nil {
 		return nil, errors.Wrap(RedactError(err), "indexer search failed")
 	}
+	sanitizeSwarm(results)
+	sort.SliceStable(results, func(i, k int) bool {
+		return results[i].Seeders > results[k].Seeders
+	})
+	if len(results) > c.maxResults {
+		results = results[:c.maxResults]
+	}
+	return results, nil
+}
+
+// Latest reads the indexer's latest-releases feed: a t=search with no query,
+// which every Torznab implementation answers with its newest items — the
+// same document an RSS reader subscribes to.
+//
+// Unlike Search it neither ranks by seeders nor applies MaxResults. A feed
+// is read for coverage, not for the best few results: the caller matches
+// every item against what it is waiting for, and needs the oldest item's
+// date to know whether the page reached back far enough. Results come
+// newest first.
+func (c *Client) Latest(ctx context.Context, ep Endpoint, cats []int) ([]Result, error) {
+	j, err := c.jackettClient(ep)
+	if err != nil {
+		return nil, err
+	}
+	fr, err := Query{Type: SearchTypeSearch, Cats: cats}.fetchRequest()
+	if err != nil {
+		return nil, err
+	}
+	results, err := j.Fetch(ctx, fr, jackett.WithoutCapsValidation())
+	if err != nil {
+		return nil, errors.Wrap(RedactError(err), "indexer feed read failed")
+	}
+	sanitizeSwarm(results)
+	sort.SliceStable(results, func(i, k int) bool {
+		return results[i].PublishDate.After(results[k].PublishDate)
+	})
+	return results, nil
+}
+
+// sanitizeSwarm zeroes swarm counts no real torrent has.
+//
+// Tracker is deliberately left as the feed set it. It used to be backfilled
+// from the item's URLs, which put an id we invented ("rutracker", Prowlarr's
+// "#3") in the same field as a name the feed gave itself ("RuTracker.org") —
+// and the label layer, which now stores that name, cannot tell the two
+// apart. Untagged feeds are labelled by their indexer instead; see
+// stremio.TorznabStream.makeStreamName.
+func sanitizeSwarm(results []Result) {
 	for i := range results {
-		// Tracker is deliberately left as the feed set it. It used to be
-		// backfilled from the item's URLs, which put an id we invented
-		// ("rutracker", Prowlarr's "#3") in the same field as a name the
-		// feed gave itself ("RuTracker.org") — and the label layer, which
-		// now stores that name, cannot tell the two apart. Untagged feeds
-		// are labelled by their indexer instead; see
-		// stremio.TorznabStream.makeStreamName.
 		// Feeds that report "unknown" as seeders="-1" parse into a huge
 		// uint. Left alone those items sort above everything real and, at
 		// 30 of them, evict every genuine result before the cap.
@@ -302,13 +343,6 @@ func (c *Client) Search(ctx context.Context, ep Endpoint, q Query) ([]Result, er
 			results[i].Peers = 0
 		}
 	}
-	sort.SliceStable(results, func(i, k int) bool {
-		return results[i].Seeders > results[k].Seeders
-	})
-	if len(results) > c.maxResults {
-		results = results[:c.maxResults]
-	}
-	return results, nil
 }
 
 // IsUnreachable reports whether an error means "our servers cannot reach
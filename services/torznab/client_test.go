@@ -215,6 +215,49 @@ func TestSearchCapsResultsAtMaxResults(t *testing.T) {
 	}
 }
 
+// TestLatestReadsTheWholeFeed: the feed is read for coverage, so neither the
+// seeder ranking nor the MaxResults cap of a search may apply — a dropped
+// item is a release no subscription hears about. The query must carry no q,
+// which is what makes t=search the latest-releases feed.
+func TestLatestReadsTheWholeFeed(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString(`<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed"><channel>`)
+	for i := 0; i < 10; i++ {
+		day := 10 + i
+		sb.WriteString(`<item><title>rel</title><link>magnet:?xt=urn:btih:aaaabbbbccccddddeeeeffff0000111122223333</link>`)
+		sb.WriteString(`<pubDate>` + time.Date(2026, 1, day, 0, 0, 0, 0, time.UTC).Format(time.RFC1123Z) + `</pubDate>`)
+		sb.WriteString(`<torznab:attr name="seeders" value="` + string(rune('0'+9-i)) + `"/></item>`)
+	}
+	sb.WriteString(`</channel></rss>`)
+
+	var gotQuery map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(sb.String()))
+	}))
+	defer srv.Close()
+
+	c := NewWithOptions(Options{AllowPrivateNetwork: true, MaxResults: 3})
+	results, err := c.Latest(context.Background(), Endpoint{URL: srv.URL}, []int{CategoryMovies, CategoryTV})
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if len(results) != 10 {
+		t.Fatalf("got %d results, want all 10 — MaxResults is a search cap", len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].PublishDate.After(results[i-1].PublishDate) {
+			t.Fatalf("result %d is newer than result %d; want newest first", i, i-1)
+		}
+	}
+	if q := gotQuery["q"]; len(q) > 0 && q[0] != "" {
+		t.Errorf("q = %q, want none: a query turns the feed into a search", q)
+	}
+	if tt := gotQuery["t"]; len(tt) == 0 || tt[0] != "search" {
+		t.Errorf("t = %v, want search", tt)
+	}
+}
+
 func TestPrivateNetworkGuard(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte(jackettFeed))
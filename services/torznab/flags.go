@@ -1,6 +1,7 @@
 package torznab
 
 import (
+	"strings"
 	"time"
 
 	"github.com/urfave/cli"
@@ -12,6 +13,7 @@ const (
 	UserAgentFlag           = "torznab-user-agent"
 	AllowPrivateNetworkFlag = "torznab-allow-private-network"
 	ProxyFlag               = "torznab-proxy"
+	AliasLangsFlag          = "torznab-alias-langs"
 )
 
 // DefaultTimeout is deliberately well above the 5s CompositeStream budget
@@ -53,5 +55,23 @@ func RegisterFlags(f []cli.Flag) []cli.Flag {
 				"(self-hosted deployments only — on shared infrastructure this is an SSRF hole)",
 			EnvVar: "TORZNAB_ALLOW_PRIVATE_NETWORK",
 		},
+		cli.StringFlag{
+			Name: AliasLangsFlag,
+			Usage: "comma-separated languages whose localized titles are tried as name queries " +
+				"when an indexer finds nothing by id or by the English title",
+			Value:  "ru,uk",
+			EnvVar: "TORZNAB_ALIAS_LANGS",
+		},
 	)
 }
+
+// AliasLangs reads the alias languages flag.
+func AliasLangs(c *cli.Context) []string {
+	var out []string
+	for _, l := range strings.Split(c.String(AliasLangsFlag), ",") {
+		if l = strings.TrimSpace(l); l != "" && l != "en" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
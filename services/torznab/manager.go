@@ -0,0 +1,247 @@
+package torznab
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Indexer managers an import can read. Both sit in front of many trackers
+// and expose one Torznab feed per tracker; what they add over a bare feed is
+// a list of which trackers are configured.
+const (
+	ManagerJackett  = "jackett"
+	ManagerProwlarr = "prowlarr"
+)
+
+// errNotManager means the address answered, but not as the manager kind
+// asked about — the cue to try the other one.
+var errNotManager = errors.New("not an indexer manager API")
+
+// Manager is one Jackett or Prowlarr instance: the base URL the UI lives
+// under and the API key it hands out. One key covers every feed it serves.
+type Manager struct {
+	Kind   string
+	URL    string
+	APIKey string
+}
+
+// ManagedIndexer is one tracker configured in a manager.
+type ManagedIndexer struct {
+	// ID is the manager's own id: Jackett's slug ("rutracker"), Prowlarr's
+	// number. It is what a later sync matches rows on — the name can be
+	// renamed upstream, the id cannot.
+	ID string
+	// Name is the tracker's display name, "RuTracker.org".
+	Name string
+	// FeedURL is the tracker's Torznab feed, without the key.
+	FeedURL string
+}
+
+// ParseManagerURL reduces whatever the user pasted — the UI address, the
+// API root, or one of the manager's own feed URLs — to the base URL the
+// management API hangs off, lifting an inline key the way ParseFeedURL does.
+// A base path is kept: both managers can run under a reverse-proxy prefix.
+func ParseManagerURL(raw, apiKey string) (string, string, error) {
+	feedURL, key, err := ParseFeedURL(raw, apiKey)
+	if err != nil {
+		return "", "", err
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return "", "", errors.New("invalid URL format")
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, p := range parts {
+		// "UI" is where Jackett's dashboard lives, the address users copy
+		// from the browser bar.
+		if p != "api" && p != "UI" {
+			continue
+		}
+		// Prowlarr's per-indexer feed is /{id}/api, so the id goes too.
+		if p == "api" && i > 0 && isDigits(parts[i-1]) {
+			i--
+		}
+		parts = parts[:i]
+		break
+	}
+	u.Path = ""
+	if p := strings.Join(parts, "/"); p != "" {
+		u.Path = "/" + p
+	}
+	// The UI fragment (#/indexers) and every query parameter belong to the
+	// page the URL was copied from, not to the API.
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), key, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// DetectManager finds out which manager answers at a base URL and lists its
+// indexers. Prowlarr is asked first: its API answers a wrong path with a
+// 404, while Jackett's all-feed would have to be asked with the key in the
+// URL.
+func (c *Client) DetectManager(ctx context.Context, baseURL, apiKey string) (*Manager, []ManagedIndexer, error) {
+	var lastErr error
+	for _, kind := range []string{ManagerProwlarr, ManagerJackett} {
+		m := &Manager{Kind: kind, URL: baseURL, APIKey: apiKey}
+		list, err := c.ListManaged(ctx, *m)
+		if err == nil {
+			return m, list, nil
+		}
+		if !errors.Is(err, errNotManager) {
+			return nil, nil, err
+		}
+		lastErr = err
+	}
+	return nil, nil, errors.Wrap(lastErr, "no Jackett or Prowlarr API found at this address")
+}
+
+// ListManaged lists the torrent indexers a manager has configured and
+// enabled. Usenet indexers are left out: their feeds answer Newznab, which
+// the stream pipeline cannot play.
+func (c *Client) ListManaged(ctx context.Context, m Manager) ([]ManagedIndexer, error) {
+	if err := c.validateEndpointURL(m.URL); err != nil {
+		return nil, err
+	}
+	switch m.Kind {
+	case ManagerJackett:
+		return c.listJackett(ctx, m)
+	case ManagerProwlarr:
+		return c.listProwlarr(ctx, m)
+	}
+	return nil, errors.Errorf("unknown indexer manager %q", m.Kind)
+}
+
+// jackettIndexers is the answer to t=indexers on Jackett's all-feed, the one
+// listing its Torznab API offers. A bad key comes back as the usual
+// in-band <error> document instead.
+type jackettIndexers struct {
+	XMLName     xml.Name
+	Description string `xml:"description,attr"`
+	Indexers    []struct {
+		ID         string `xml:"id,attr"`
+		Configured string `xml:"configured,attr"`
+		Title      string `xml:"title"`
+	} `xml:"indexer"`
+}
+
+func (c *Client) listJackett(ctx context.Context, m Manager) ([]ManagedIndexer, error) {
+	base := strings.TrimRight(m.URL, "/")
+	v := url.Values{}
+	v.Set("t", "indexers")
+	v.Set("configured", "true")
+	if m.APIKey != "" {
+		v.Set("apikey", m.APIKey)
+	}
+	body, status, err := c.getManager(ctx, base+"/api/v2.0/indexers/all/results/torznab/api?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The error document is read whatever the status: Jackett sends a bad
+	// key with a 4xx, other Torznab servers with a 200.
+	var doc jackettIndexers
+	if err := xml.Unmarshal(body, &doc); err == nil && doc.XMLName.Local == "error" {
+		return nil, errors.Errorf("jackett refused the request: %s", Redact(doc.Description))
+	}
+	if status != http.StatusOK || doc.XMLName.Local != "indexers" {
+		return nil, errors.Wrapf(errNotManager, "jackett answered HTTP %d without an indexer list", status)
+	}
+	out := make([]ManagedIndexer, 0, len(doc.Indexers))
+	for _, ix := range doc.Indexers {
+		id := strings.TrimSpace(ix.ID)
+		if id == "" || strings.EqualFold(ix.Configured, "false") {
+			continue
+		}
+		out = append(out, ManagedIndexer{
+			ID:      id,
+			Name:    strings.TrimSpace(ix.Title),
+			FeedURL: base + "/api/v2.0/indexers/" + url.PathEscape(id) + "/results/torznab",
+		})
+	}
+	return out, nil
+}
+
+// prowlarrIndexer is the subset of Prowlarr's /api/v1/indexer entries an
+// import needs.
+type prowlarrIndexer struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Enable   bool   `json:"enable"`
+	Protocol string `json:"protocol"`
+}
+
+func (c *Client) listProwlarr(ctx context.Context, m Manager) ([]ManagedIndexer, error) {
+	base := strings.TrimRight(m.URL, "/")
+	// The key goes in a header: Prowlarr accepts it there, and it keeps the
+	// credential out of every URL an error could quote.
+	body, status, err := c.getManager(ctx, base+"/api/v1/indexer", map[string]string{"X-Api-Key": m.APIKey})
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case status == http.StatusUnauthorized:
+		return nil, errors.New("prowlarr refused the API key")
+	case status != http.StatusOK:
+		return nil, errors.Wrapf(errNotManager, "prowlarr answered HTTP %d", status)
+	}
+	var list []prowlarrIndexer
+	// Prowlarr's UI answers unknown paths with its single-page app, so a
+	// 200 that is not a JSON array is "not Prowlarr", not a broken one.
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, errors.Wrap(errNotManager, "prowlarr answer is not an indexer list")
+	}
+	out := make([]ManagedIndexer, 0, len(list))
+	for _, ix := range list {
+		if !ix.Enable || (ix.Protocol != "" && ix.Protocol != "torrent") {
+			continue
+		}
+		id := strconv.Itoa(ix.ID)
+		out = append(out, ManagedIndexer{
+			ID:      id,
+			Name:    strings.TrimSpace(ix.Name),
+			FeedURL: base + "/api/v1/indexer/" + id + "/newznab",
+		})
+	}
+	return out, nil
+}
+
+// getManager reads one management API response through the search client,
+// so the egress guard and the proxy apply exactly as they do to searches.
+func (c *Client) getManager(ctx context.Context, u string, headers map[string]string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, errors.Wrap(RedactError(err), "invalid indexer manager URL")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if c.ua != "" {
+		req.Header.Set("User-Agent", c.ua)
+	}
+	res, err := c.cl.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrap(RedactError(err), "indexer manager request failed")
+	}
+	defer func() { _ = res.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodyBytes))
+	if err != nil {
+		return nil, 0, errors.Wrap(RedactError(err), "failed to read indexer manager response")
+	}
+	return body, res.StatusCode, nil
+}
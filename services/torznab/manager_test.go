@@ -0,0 +1,158 @@
+package torznab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseManagerURL(t *testing.T) {
+	for _, tt := range []struct {
+		raw     string
+		wantURL string
+		wantKey string
+	}{
+		{"https://jackett.example.com/UI/Dashboard", "https://jackett.example.com", ""},
+		{"https://jackett.example.com", "https://jackett.example.com", ""},
+		{"https://jackett.example.com/api/v2.0/indexers/rutracker/results/torznab/?apikey=k1", "https://jackett.example.com", "k1"},
+		{"https://example.com/prowlarr/api/v1/indexer/3/newznab", "https://example.com/prowlarr", ""},
+		{"http://10.0.0.5:9696/3/api?apikey=k2&t=caps", "http://10.0.0.5:9696", "k2"},
+		{"http://10.0.0.5:9696/#/indexers", "http://10.0.0.5:9696", ""},
+	} {
+		gotURL, gotKey, err := ParseManagerURL(tt.raw, "")
+		if err != nil {
+			t.Errorf("ParseManagerURL(%q) error = %v", tt.raw, err)
+			continue
+		}
+		if gotURL != tt.wantURL || gotKey != tt.wantKey {
+			t.Errorf("ParseManagerURL(%q) = %q, %q; want %q, %q", tt.raw, gotURL, gotKey, tt.wantURL, tt.wantKey)
+		}
+	}
+	if _, _, err := ParseManagerURL("ftp://example.com", ""); err == nil {
+		t.Error("ParseManagerURL accepted a non-http scheme")
+	}
+}
+
+const jackettIndexerList = `<?xml version="1.0" encoding="UTF-8"?>
+<indexers>
+  <indexer id="rutracker" configured="true">
+    <title>RuTracker.org</title>
+    <type>semi-private</type>
+  </indexer>
+  <indexer id="1337x" configured="true">
+    <title>1337x</title>
+    <type>public</type>
+  </indexer>
+</indexers>`
+
+func TestDetectManagerJackett(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jackett/api/v2.0/indexers/all/results/torznab/api" &&
+			r.URL.Query().Get("t") == "indexers" && r.URL.Query().Get("apikey") == "secret" {
+			_, _ = w.Write([]byte(jackettIndexerList))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	m, list, err := testClient().DetectManager(context.Background(), srv.URL+"/jackett", "secret")
+	if err != nil {
+		t.Fatalf("DetectManager() error = %v", err)
+	}
+	if m.Kind != ManagerJackett {
+		t.Errorf("kind = %q, want %q", m.Kind, ManagerJackett)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d indexers, want 2", len(list))
+	}
+	want := ManagedIndexer{
+		ID:      "rutracker",
+		Name:    "RuTracker.org",
+		FeedURL: srv.URL + "/jackett/api/v2.0/indexers/rutracker/results/torznab",
+	}
+	if list[0] != want {
+		t.Errorf("first indexer = %+v, want %+v", list[0], want)
+	}
+}
+
+func TestDetectManagerProwlarr(t *testing.T) {
+	var sawKeyInURL bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "" {
+			sawKeyInURL = true
+		}
+		if r.URL.Path != "/api/v1/indexer" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"id": 3, "name": "RuTracker.org", "enable": true, "protocol": "torrent"},
+			{"id": 4, "name": "NZBgeek", "enable": true, "protocol": "usenet"},
+			{"id": 5, "name": "Disabled", "enable": false, "protocol": "torrent"}
+		]`))
+	}))
+	defer srv.Close()
+
+	m, list, err := testClient().DetectManager(context.Background(), srv.URL, "secret")
+	if err != nil {
+		t.Fatalf("DetectManager() error = %v", err)
+	}
+	if m.Kind != ManagerProwlarr {
+		t.Errorf("kind = %q, want %q", m.Kind, ManagerProwlarr)
+	}
+	// Usenet and disabled indexers are not offered: neither can answer a
+	// torrent search.
+	if len(list) != 1 || list[0].ID != "3" || list[0].FeedURL != srv.URL+"/api/v1/indexer/3/newznab" {
+		t.Errorf("list = %+v, want only indexer 3", list)
+	}
+	if sawKeyInURL {
+		t.Error("the Prowlarr key travelled in the URL, want the X-Api-Key header")
+	}
+
+	if _, _, err := testClient().DetectManager(context.Background(), srv.URL, "wrong"); err == nil || !strings.Contains(err.Error(), "API key") {
+		t.Errorf("wrong key error = %v, want a key refusal rather than a fallback to Jackett", err)
+	}
+}
+
+func TestDetectManagerJackettErrorIsRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/torznab/api") {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`<error code="100" description="Invalid API Key: apikey=topsecret"/>`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	_, _, err := testClient().DetectManager(context.Background(), srv.URL, "topsecret")
+	if err == nil {
+		t.Fatal("DetectManager() succeeded against a refusing Jackett")
+	}
+	if strings.Contains(err.Error(), "topsecret") {
+		t.Errorf("error leaks the key: %v", err)
+	}
+	if !strings.Contains(err.Error(), "jackett refused") {
+		t.Errorf("error = %v, want the refusal, not \"no manager found\"", err)
+	}
+}
+
+func TestDetectManagerNeither(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// What a single-page app answers every path with.
+		_, _ = w.Write([]byte("<!doctype html><html><body></body></html>"))
+	}))
+	defer srv.Close()
+
+	if _, _, err := testClient().DetectManager(context.Background(), srv.URL, "k"); err == nil {
+		t.Error("DetectManager() accepted a server that is neither manager")
+	}
+}
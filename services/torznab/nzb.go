@@ -0,0 +1,71 @@
+package torznab
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// maxNZBBytes bounds an NZB download. An NZB lists every article of the
+// release, so a remux of tens of gigabytes runs to several megabytes — well
+// past what a .torrent or a search answer is allowed.
+const maxNZBBytes = 32 << 20
+
+// DownloadNZB fetches the NZB behind a Newznab result's download link. The
+// link carries the indexer's API key and counts against its grab limit, so
+// it is only fetched when the user sends the release somewhere.
+func (c *Client) DownloadNZB(ctx context.Context, link string) ([]byte, error) {
+	if !isHTTPURL(link) {
+		return nil, errors.New("nzb link is not an http(s) URL")
+	}
+	current := link
+	for i := 0; i < maxRedirects; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current, nil)
+		if err != nil {
+			return nil, errors.Wrap(RedactError(err), "failed to create nzb request")
+		}
+		req.Header.Set("Accept", "application/x-nzb, */*")
+		if c.ua != "" {
+			req.Header.Set("User-Agent", c.ua)
+		}
+		resp, err := c.dl.Do(req)
+		if err != nil {
+			return nil, errors.Wrap(RedactError(err), "failed to fetch nzb")
+		}
+
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+			loc := resp.Header.Get("Location")
+			_ = resp.Body.Close()
+			next, err := resolveReference(current, loc)
+			if loc == "" || err != nil || !isHTTPURL(next) {
+				return nil, errors.Errorf("nzb link redirected to an unusable location")
+			}
+			current = next
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			_ = resp.Body.Close()
+			return nil, errors.Errorf("nzb link returned HTTP %d", resp.StatusCode)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxNZBBytes+1))
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read nzb")
+		}
+		if len(body) > maxNZBBytes {
+			return nil, errors.New("nzb is too large")
+		}
+		// Indexers answer an exhausted grab limit or a bad key with a 200
+		// and an error document, which no downloader would take.
+		if !bytes.Contains(body, []byte("<nzb")) {
+			return nil, errors.New("download link did not return an nzb")
+		}
+		return body, nil
+	}
+	return nil, errors.New("too many redirects on nzb link")
+}
@@ -0,0 +1,31 @@
+package torznab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDownloadNZB(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/getnzb":
+			http.Redirect(w, r, "/files/release.nzb", http.StatusFound)
+		case "/files/release.nzb":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><nzb xmlns="http://www.newzbin.com/DTD/2003/nzb"></nzb>`))
+		case "/limit":
+			_, _ = w.Write([]byte(`<error code="429" description="Request limit reached"/>`))
+		}
+	}))
+	defer srv.Close()
+
+	body, err := testClient().DownloadNZB(context.Background(), srv.URL+"/getnzb?apikey=secret")
+	if err != nil || !strings.Contains(string(body), "<nzb") {
+		t.Fatalf("DownloadNZB() = %q, %v", body, err)
+	}
+	if _, err := testClient().DownloadNZB(context.Background(), srv.URL+"/limit?apikey=secret"); err == nil {
+		t.Error("an error document was taken for an nzb")
+	}
+}
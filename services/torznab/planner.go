@@ -0,0 +1,352 @@
+package torznab
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/webtor-io/web-ui/models"
+	ptn "github.com/webtor-io/web-ui/services/parse_torrent_name"
+)
+
+// maxTextQueries bounds the by-name attempts one request may make. The
+// canonical title is first, so the cap only ever costs aliases — and every
+// attempt is a full indexer round-trip on a private tracker's rate limit.
+const maxTextQueries = 3
+
+// Target is what one stream request is for.
+type Target struct {
+	ContentType string
+	IMDBID      string
+	Season      *int
+	Episode     *int
+}
+
+// IsSeries reports whether the target is one episode of a series — the only
+// shape a series is ever asked for.
+func (t Target) IsSeries() bool {
+	return t.ContentType == "series" && t.Season != nil && t.Episode != nil
+}
+
+// SearchFunc runs one query against the indexer the plan is for.
+type SearchFunc func(ctx context.Context, q Query) ([]Result, error)
+
+// Planner decides what one indexer is asked for a target, and in which
+// order, from its caps snapshot.
+//
+// The order is exactness first. An id query cannot return another title; a
+// name query can, so it goes last and its results are checked against the
+// name before they are kept. The planner stops at the first attempt that
+// leaves anything, and resolves nothing it does not need: the title is
+// looked up only when the id queries came back empty, the aliases only when
+// the caps or the name queries call for them. Building either eagerly would
+// put a metadata lookup on the hot path of every stream request, even for
+// indexers that answer by id perfectly well.
+type Planner struct {
+	caps    *models.TorznabCaps
+	cats    []int
+	titles  TitleResolver
+	aliases AliasResolver
+}
+
+// NewPlanner plans for one indexer. titles may also be an AliasResolver
+// (see WithAliases); categories come from CategoriesFor.
+func NewPlanner(caps *models.TorznabCaps, contentType string, titles TitleResolver) *Planner {
+	p := &Planner{
+		caps:   caps,
+		cats:   CategoriesFor(caps, contentType),
+		titles: titles,
+	}
+	if ar, ok := titles.(AliasResolver); ok {
+		p.aliases = ar
+	}
+	return p
+}
+
+// Run walks the plan. The error is returned only when nothing was found
+// and some attempt failed: one indexer failing an id query and answering
+// the name query is a success.
+func (p *Planner) Run(ctx context.Context, t Target, search SearchFunc) ([]Result, error) {
+	var lastErr error
+	attempt := func(q Query) []Result {
+		q.Cats = p.cats
+		r, err := search(ctx, q)
+		if err != nil {
+			lastErr = err
+		}
+		return r
+	}
+
+	if q := p.IDQuery(t); q != nil {
+		if r := attempt(*q); len(r) > 0 {
+			return r, nil
+		}
+	}
+
+	var aliases *Aliases
+	loadAliases := func() *Aliases {
+		if aliases == nil {
+			aliases = p.resolveAliases(ctx, t)
+		}
+		return aliases
+	}
+
+	if p.wantsAliasIDs(t) {
+		for _, q := range p.AliasIDQueries(t, loadAliases()) {
+			if r := attempt(q); len(r) > 0 {
+				return r, nil
+			}
+		}
+	}
+
+	title := p.resolveTitle(ctx, t)
+	if title == nil {
+		return nil, lastErr
+	}
+	names := []string{title.Name}
+	names = append(names, loadAliases().Titles...)
+	for _, q := range p.TextQueries(t, title, names) {
+		r := FilterResults(attempt(q), t, title, names)
+		if len(r) > 0 {
+			return r, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// IDQuery returns the query-by-imdb-id attempt, or nil when the indexer
+// cannot serve one. A nil caps snapshot means the probe never succeeded, in
+// which case trying the id query costs one request and can save the
+// fallback entirely.
+func (p *Planner) IDQuery(t Target) *Query {
+	caps := p.caps
+	if t.IsSeries() {
+		if caps == nil || (caps.SupportsTVIMDB() && caps.SupportsSeasonEpisode()) {
+			return &Query{
+				Type:    SearchTypeTV,
+				IMDBID:  t.IMDBID,
+				Season:  t.Season,
+				Episode: t.Episode,
+			}
+		}
+		return nil
+	}
+	if caps == nil || caps.SupportsMovieIMDB() {
+		return &Query{Type: SearchTypeMovie, IMDBID: t.IMDBID}
+	}
+	return nil
+}
+
+// wantsAliasIDs reports whether the caps advertise a series id other than
+// imdbid. An unknown caps snapshot does not: the imdb attempt has already
+// covered that guess.
+func (p *Planner) wantsAliasIDs(t Target) bool {
+	return t.IsSeries() && p.caps.SupportsSeasonEpisode() &&
+		(p.caps.SupportsTVTVDB() || p.caps.SupportsTVTMDB())
+}
+
+// AliasIDQueries returns the by-tvdbid and by-tmdbid attempts the caps
+// allow, in that order — tvdb is the id Sonarr-era indexers were built
+// around, tmdb the one newer definitions add.
+func (p *Planner) AliasIDQueries(t Target, a *Aliases) []Query {
+	if a == nil || !p.wantsAliasIDs(t) {
+		return nil
+	}
+	var out []Query
+	if a.TVDBID > 0 && p.caps.SupportsTVTVDB() {
+		out = append(out, Query{Type: SearchTypeTV, TVDBID: a.TVDBID, Season: t.Season, Episode: t.Episode})
+	}
+	if a.TMDBID > 0 && p.caps.SupportsTVTMDB() {
+		out = append(out, Query{Type: SearchTypeTV, TMDBID: a.TMDBID, Season: t.Season, Episode: t.Episode})
+	}
+	return out
+}
+
+// TextQueries returns the by-name attempts: the canonical title first, then
+// each distinct alias, capped at maxTextQueries. This is the only mode most
+// bare tracker feeds support, and a tracker that files "The Boys" as
+// "Пацаны" only answers to the second.
+func (p *Planner) TextQueries(t Target, title *Title, names []string) []Query {
+	seen := map[string]bool{}
+	var out []Query
+	for i, name := range names {
+		k := TitleKey(name)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		year := ""
+		if i == 0 && title != nil {
+			// The year disambiguates remakes, but only the canonical
+			// title is known to go with it.
+			year = title.Year
+		}
+		out = append(out, p.textQuery(t, strings.TrimSpace(name), year))
+		if len(out) == maxTextQueries {
+			break
+		}
+	}
+	return out
+}
+
+func (p *Planner) textQuery(t Target, name, year string) Query {
+	caps := p.caps
+	if t.IsSeries() {
+		searchType := SearchTypeTV
+		if caps != nil && !caps.SupportsTVSearch() {
+			searchType = SearchTypeSearch
+		}
+		// Season and episode go as parameters whenever the indexer takes
+		// them, with the title alone in q. Baking "S05E14" into the query
+		// text instead assumes releases are named the Anglo per-episode
+		// way, and on a tracker that names them "Сезон 5" it matches
+		// nothing: measured against a live Jackett, the structured form
+		// returned 8 results for an episode where the S01E05 form
+		// returned zero.
+		if searchType == SearchTypeTV && (caps == nil || caps.SupportsSeasonEpisode()) {
+			return Query{
+				Type:    searchType,
+				Q:       name,
+				Season:  t.Season,
+				Episode: t.Episode,
+			}
+		}
+		// No structured support: the episode marker has to ride in the
+		// text, which is all such an indexer understands.
+		return Query{
+			Type: searchType,
+			Q:    fmt.Sprintf("%s S%02dE%02d", name, *t.Season, *t.Episode),
+		}
+	}
+
+	q := name
+	if year != "" {
+		q = fmt.Sprintf("%s %s", name, year)
+	}
+	searchType := SearchTypeMovie
+	if caps != nil && !caps.SupportsMovieSearch() {
+		searchType = SearchTypeSearch
+	}
+	return Query{Type: searchType, Q: q}
+}
+
+func (p *Planner) resolveTitle(ctx context.Context, t Target) *Title {
+	if p.titles == nil {
+		return nil
+	}
+	title, err := p.titles.Resolve(ctx, t.ContentType, t.IMDBID)
+	if err != nil || title == nil {
+		log.WithError(err).
+			WithField("content_id", t.IMDBID).
+			Debug("failed to resolve title for torznab query")
+		return nil
+	}
+	return title
+}
+
+// resolveAliases never fails the plan: without aliases it is the imdb id and
+// the canonical title, which is what every indexer got before aliases.
+func (p *Planner) resolveAliases(ctx context.Context, t Target) *Aliases {
+	if p.aliases == nil {
+		return &Aliases{}
+	}
+	a, err := p.aliases.Aliases(ctx, t.ContentType, t.IMDBID)
+	if err != nil || a == nil {
+		log.WithError(err).
+			WithField("content_id", t.IMDBID).
+			Debug("failed to resolve aliases for torznab query")
+		return &Aliases{}
+	}
+	return a
+}
+
+// FilterResults drops name-query results that are not the target. A query
+// by name is a keyword match: "The Boys" also finds "The Boys Presents:
+// Diabolical", and "The Matrix 1999" finds "The Matrix Resurrections".
+//
+// Each result is read with the shared name parser and kept when one of its
+// names — trackers list several, "Пацаны / The Boys" — is one of the
+// target's, and nothing it states contradicts the target: another year (a
+// remake), another episode of the same season. What the parser cannot read
+// is kept — an unreadable name is not evidence of a mismatch.
+func FilterResults(results []Result, t Target, title *Title, names []string) []Result {
+	if len(results) == 0 {
+		return results
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		if k := TitleKey(n); k != "" {
+			want[k] = true
+		}
+	}
+	year := 0
+	if title != nil {
+		year, _ = strconv.Atoi(title.Year)
+	}
+
+	out := make([]Result, 0, len(results))
+	for _, r := range results {
+		ti, err := ptn.Parse(&ptn.TorrentInfo{}, r.Title)
+		if err != nil || ti == nil || strings.TrimSpace(ti.Title) == "" {
+			out = append(out, r)
+			continue
+		}
+		if len(want) > 0 && !NamesOneOf(ti.Title, want) {
+			continue
+		}
+		if !t.IsSeries() && year > 0 && ti.Year > 0 && (ti.Year < year-1 || ti.Year > year+1) {
+			// A year either side: release dates and festival premieres
+			// disagree by one often enough.
+			continue
+		}
+		if t.IsSeries() && ti.Season == *t.Season && ti.Episode > 0 &&
+			ti.Episode != *t.Episode && !episodeRangeRe.MatchString(r.Title) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// episodeRangeRe spots "E01-E08" and "1-8": the parser reads only the first
+// number of a range, and a range is a pack that may hold the episode asked
+// for.
+var episodeRangeRe = regexp.MustCompile(`(?i)(?:^|[^0-9])e?\d{1,3}\s*[-–]\s*e?\d{1,3}(?:[^0-9]|$)`)
+
+// NamesOneOf reports whether any "/"-separated part of a parsed release
+// title is one of the wanted TitleKey keys.
+func NamesOneOf(parsed string, want map[string]bool) bool {
+	for _, part := range strings.Split(parsed, "/") {
+		if want[TitleKey(part)] {
+			return true
+		}
+	}
+	return false
+}
+
+var bracketedRe = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+
+// TitleKey folds a title for comparison: bracketed asides dropped, lowercase
+// letters and digits with single spaces, a leading English article dropped.
+// "The Boys", "the.boys" and "Boys (2019)" meet in one key.
+func TitleKey(s string) string {
+	s = bracketedRe.ReplaceAllString(s, " ")
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	fields := strings.Fields(b.String())
+	if len(fields) > 1 && (fields[0] == "the" || fields[0] == "a" || fields[0] == "an") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,334 @@
+package torznab
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/webtor-io/web-ui/models"
+)
+
+type fakeTitles struct {
+	title   *Title
+	err     error
+	aliases *Aliases
+
+	resolved     int
+	aliasLookups int
+}
+
+func (f *fakeTitles) Resolve(context.Context, string, string) (*Title, error) {
+	f.resolved++
+	return f.title, f.err
+}
+
+type fakeAliases struct{ *fakeTitles }
+
+func (f fakeAliases) Aliases(context.Context, string, string) (*Aliases, error) {
+	f.aliasLookups++
+	return f.aliases, nil
+}
+
+func intp(i int) *int { return &i }
+
+func episodeTarget() Target {
+	return Target{ContentType: "series", IMDBID: "tt1190634", Season: intp(3), Episode: intp(4)}
+}
+
+// recorder answers each query from a table keyed by a label the test picks,
+// and remembers what it was asked.
+type recorder struct {
+	asked  []Query
+	answer func(q Query) []Result
+}
+
+func (r *recorder) search(_ context.Context, q Query) ([]Result, error) {
+	r.asked = append(r.asked, q)
+	if r.answer == nil {
+		return nil, nil
+	}
+	return r.answer(q), nil
+}
+
+func TestPlannerQueries(t *testing.T) {
+	title := &Title{Name: "The Matrix", Year: "1999"}
+
+	for _, tt := range []struct {
+		name           string
+		caps           *models.TorznabCaps
+		target         Target
+		wantIDType     SearchType // "" = no id query
+		wantTitleT     SearchType
+		wantTitleQ     string
+		wantStructured bool
+	}{
+		{
+			name:       "movie with imdbid support queries by id first",
+			caps:       &models.TorznabCaps{MovieParams: []string{"q", "imdbid"}},
+			target:     Target{ContentType: "movie", IMDBID: "tt0133093"},
+			wantIDType: SearchTypeMovie,
+			wantTitleT: SearchTypeMovie,
+			wantTitleQ: "The Matrix 1999",
+		},
+		{
+			name:       "movie without imdbid support has no id query",
+			caps:       &models.TorznabCaps{MovieParams: []string{"q"}},
+			target:     Target{ContentType: "movie", IMDBID: "tt0133093"},
+			wantTitleT: SearchTypeMovie,
+			wantTitleQ: "The Matrix 1999",
+		},
+		{
+			name:       "indexer with only a plain search mode uses t=search",
+			caps:       &models.TorznabCaps{SearchParams: []string{"q"}},
+			target:     Target{ContentType: "movie", IMDBID: "tt0133093"},
+			wantTitleT: SearchTypeSearch,
+			wantTitleQ: "The Matrix 1999",
+		},
+		{
+			name:       "unknown caps tries the id query anyway",
+			caps:       nil,
+			target:     Target{ContentType: "movie", IMDBID: "tt0133093"},
+			wantIDType: SearchTypeMovie,
+			wantTitleT: SearchTypeMovie,
+			wantTitleQ: "The Matrix 1999",
+		},
+		{
+			// No structured season/ep: the episode marker has to ride in
+			// the query text, because that is all the indexer understands.
+			name:       "series without season/ep support falls back to SxxEyy in the text",
+			caps:       &models.TorznabCaps{TVParams: []string{"q", "imdbid"}},
+			target:     Target{ContentType: "series", IMDBID: "tt1839578", Season: intp(5), Episode: intp(14)},
+			wantTitleT: SearchTypeTV,
+			wantTitleQ: "The Matrix S05E14",
+		},
+		{
+			name:           "series with season/ep support sends them as parameters",
+			caps:           &models.TorznabCaps{TVParams: []string{"q", "imdbid", "season", "ep"}},
+			target:         Target{ContentType: "series", IMDBID: "tt1839578", Season: intp(5), Episode: intp(14)},
+			wantIDType:     SearchTypeTV,
+			wantTitleT:     SearchTypeTV,
+			wantTitleQ:     "The Matrix",
+			wantStructured: true,
+		},
+		{
+			// The real-world shape that started this: rutracker advertises
+			// tv-search with q,season,ep and no imdbid, so everything runs
+			// through the title query — which must still carry the episode.
+			name:           "no imdbid but season/ep: title query carries the episode",
+			caps:           &models.TorznabCaps{TVParams: []string{"q", "season", "ep"}},
+			target:         Target{ContentType: "series", IMDBID: "tt1839578", Season: intp(5), Episode: intp(14)},
+			wantTitleT:     SearchTypeTV,
+			wantTitleQ:     "The Matrix",
+			wantStructured: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlanner(tt.caps, tt.target.ContentType, &fakeTitles{title: title})
+
+			idQuery := p.IDQuery(tt.target)
+			if tt.wantIDType != "" {
+				if idQuery == nil {
+					t.Fatalf("IDQuery() = nil, want a %q query", tt.wantIDType)
+				}
+				if idQuery.Type != tt.wantIDType {
+					t.Errorf("id query type = %q, want %q", idQuery.Type, tt.wantIDType)
+				}
+				if idQuery.IMDBID != tt.target.IMDBID {
+					t.Errorf("id query imdbid = %q, want %q", idQuery.IMDBID, tt.target.IMDBID)
+				}
+			} else if idQuery != nil {
+				t.Errorf("IDQuery() = %+v, want nil", idQuery)
+			}
+
+			qs := p.TextQueries(tt.target, title, []string{title.Name})
+			if len(qs) != 1 {
+				t.Fatalf("TextQueries() = %+v, want one query", qs)
+			}
+			q := qs[0]
+			if q.Type != tt.wantTitleT {
+				t.Errorf("title query type = %q, want %q", q.Type, tt.wantTitleT)
+			}
+			if q.Q != tt.wantTitleQ {
+				t.Errorf("title query q = %q, want %q", q.Q, tt.wantTitleQ)
+			}
+			hasStructured := q.Season != nil && q.Episode != nil
+			if hasStructured != tt.wantStructured {
+				t.Errorf("title query structured season/ep = %v, want %v", hasStructured, tt.wantStructured)
+			}
+		})
+	}
+}
+
+func TestPlannerWithoutTitle(t *testing.T) {
+	// When the metadata lookup fails there is no title query to fall back
+	// to; the id query must still be attempted rather than the whole
+	// indexer dropping out.
+	titles := &fakeTitles{err: context.DeadlineExceeded}
+	p := NewPlanner(&models.TorznabCaps{MovieParams: []string{"q", "imdbid"}}, "movie", titles)
+	rec := &recorder{}
+
+	if _, err := p.Run(context.Background(), Target{ContentType: "movie", IMDBID: "tt0133093"}, rec.search); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.asked) != 1 || rec.asked[0].IMDBID != "tt0133093" {
+		t.Fatalf("asked %+v, want the id query alone", rec.asked)
+	}
+}
+
+// TestPlannerAliasIDs: an indexer that keys series on tvdb/tmdb and not imdb
+// is asked by those ids before it is asked by name, and the title is never
+// looked up when they answer.
+func TestPlannerAliasIDs(t *testing.T) {
+	titles := &fakeTitles{
+		title:   &Title{Name: "The Boys"},
+		aliases: &Aliases{TVDBID: 355567, TMDBID: 76479},
+	}
+	caps := &models.TorznabCaps{
+		TVParams:   []string{"q", "season", "ep", "tvdbid", "tmdbid"},
+		Categories: []int{5000},
+	}
+	rec := &recorder{answer: func(q Query) []Result {
+		if q.TMDBID > 0 {
+			return []Result{{Title: "The.Boys.S03E04.1080p"}}
+		}
+		return nil
+	}}
+
+	r, err := NewPlanner(caps, "series", WithAliases(titles, fakeAliases{titles})).
+		Run(context.Background(), episodeTarget(), rec.search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("got %d results, want the tmdb answer", len(r))
+	}
+	if len(rec.asked) != 2 || rec.asked[0].TVDBID != 355567 || rec.asked[1].TMDBID != 76479 {
+		t.Fatalf("asked %+v, want tvdbid then tmdbid", rec.asked)
+	}
+	if rec.asked[0].Type != SearchTypeTV || *rec.asked[0].Season != 3 || *rec.asked[0].Episode != 4 {
+		t.Errorf("tvdb query = %+v, want a structured tvsearch", rec.asked[0])
+	}
+	if len(rec.asked[0].Cats) != 1 || rec.asked[0].Cats[0] != CategoryTV {
+		t.Errorf("cats = %v, want the TV category from CategoriesFor", rec.asked[0].Cats)
+	}
+	if titles.resolved != 0 {
+		t.Errorf("resolved the title %d times, want 0 — an id answered", titles.resolved)
+	}
+}
+
+// TestPlannerLocalizedTitle: a tracker that files a show under its local
+// name is asked by that name once the canonical one finds nothing.
+func TestPlannerLocalizedTitle(t *testing.T) {
+	titles := &fakeTitles{
+		title:   &Title{Name: "The Boys", Year: "2019"},
+		aliases: &Aliases{Titles: []string{"Пацаны", "The Boys"}},
+	}
+	caps := &models.TorznabCaps{TVParams: []string{"q", "season", "ep"}}
+	rec := &recorder{answer: func(q Query) []Result {
+		if q.Q == "Пацаны" {
+			return []Result{
+				{Title: "Пацаны / The Boys / Сезон: 3 / Серии: 1-8 из 8 [2022, WEB-DL 1080p]"},
+				{Title: "Пацаны. Презентация / The Boys Presents: Diabolical [2022]"},
+			}
+		}
+		return nil
+	}}
+
+	r, err := NewPlanner(caps, "series", WithAliases(titles, fakeAliases{titles})).
+		Run(context.Background(), episodeTarget(), rec.search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.asked) != 2 || rec.asked[0].Q != "The Boys" || rec.asked[1].Q != "Пацаны" {
+		t.Fatalf("asked %+v, want the canonical title, then the localized one, and no duplicate", rec.asked)
+	}
+	if len(r) != 1 {
+		t.Fatalf("kept %+v, want only the season pack — the spin-off is another show", r)
+	}
+}
+
+func TestPlannerCapsTextQueries(t *testing.T) {
+	titles := &fakeTitles{
+		title:   &Title{Name: "The Boys"},
+		aliases: &Aliases{Titles: []string{"Пацаны", "Хлопаки", "Chłopaki", "Los chicos"}},
+	}
+	rec := &recorder{}
+	_, _ = NewPlanner(&models.TorznabCaps{TVParams: []string{"q", "season", "ep"}}, "series", WithAliases(titles, fakeAliases{titles})).
+		Run(context.Background(), episodeTarget(), rec.search)
+	if len(rec.asked) != maxTextQueries {
+		t.Fatalf("asked %d name queries, want the cap of %d", len(rec.asked), maxTextQueries)
+	}
+}
+
+func TestPlannerReportsErrorOnlyWhenEmpty(t *testing.T) {
+	titles := &fakeTitles{title: &Title{Name: "The Matrix", Year: "1999"}}
+	caps := &models.TorznabCaps{MovieParams: []string{"q", "imdbid"}}
+	boom := errors.New("indexer down")
+
+	calls := 0
+	r, err := NewPlanner(caps, "movie", titles).Run(context.Background(), Target{ContentType: "movie", IMDBID: "tt0133093"},
+		func(_ context.Context, q Query) ([]Result, error) {
+			calls++
+			if q.IMDBID != "" {
+				return nil, boom
+			}
+			return []Result{{Title: "The.Matrix.1999.1080p"}}, nil
+		})
+	if err != nil || len(r) != 1 {
+		t.Fatalf("Run() = %v, %v; a failed id query answered by name is a success", r, err)
+	}
+
+	_, err = NewPlanner(caps, "movie", titles).Run(context.Background(), Target{ContentType: "movie", IMDBID: "tt0133093"},
+		func(context.Context, Query) ([]Result, error) { return nil, boom })
+	if !errors.Is(err, boom) {
+		t.Fatalf("Run() error = %v, want the search failure when nothing was found", err)
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	movie := Target{ContentType: "movie", IMDBID: "tt0133093"}
+	matrix := &Title{Name: "The Matrix", Year: "1999"}
+
+	for _, tt := range []struct {
+		name   string
+		target Target
+		title  *Title
+		names  []string
+		result string
+		keep   bool
+	}{
+		{"same film", movie, matrix, []string{"The Matrix"}, "The.Matrix.1999.1080p.BluRay", true},
+		{"sequel sharing the prefix", movie, matrix, []string{"The Matrix"}, "The Matrix Resurrections 2021 1080p", false},
+		{"remake in another year", movie, matrix, []string{"The Matrix"}, "The.Matrix.2031.2160p", false},
+		{"year off by one", movie, matrix, []string{"The Matrix"}, "The.Matrix.2000.DVDRip", true},
+		{"localized name among several", movie, matrix, []string{"The Matrix", "Матрица"}, "Матрица / The Matrix (1999) BDRip 1080p", true},
+		{"unparseable name is kept", movie, matrix, []string{"The Matrix"}, "", true},
+		{"same episode", episodeTarget(), &Title{Name: "The Boys"}, []string{"The Boys"}, "The.Boys.S03E04.1080p", true},
+		{"other episode of the season", episodeTarget(), &Title{Name: "The Boys"}, []string{"The Boys"}, "The.Boys.S03E05.1080p", false},
+		{"episode range is a pack", episodeTarget(), &Title{Name: "The Boys"}, []string{"The Boys"}, "The Boys S03E01-E08 1080p", true},
+		{"season pack", episodeTarget(), &Title{Name: "The Boys"}, []string{"The Boys"}, "The Boys S03 Complete", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterResults([]Result{{Title: tt.result}}, tt.target, tt.title, tt.names)
+			if (len(got) == 1) != tt.keep {
+				t.Fatalf("FilterResults(%q) kept = %v, want %v", tt.result, len(got) == 1, tt.keep)
+			}
+		})
+	}
+}
+
+func TestTitleKey(t *testing.T) {
+	for _, tt := range []struct{ a, b string }{
+		{"The Boys", "the.boys"},
+		{"The Boys", "Boys"},
+		{"Boys (2019)", "The Boys"},
+		{"Пацаны", "пацаны"},
+	} {
+		if TitleKey(tt.a) != TitleKey(tt.b) {
+			t.Errorf("TitleKey(%q) = %q, TitleKey(%q) = %q, want equal", tt.a, TitleKey(tt.a), tt.b, TitleKey(tt.b))
+		}
+	}
+	if TitleKey("The Boys Presents Diabolical") == TitleKey("The Boys") {
+		t.Error("a longer title must not fold into a shorter one")
+	}
+}
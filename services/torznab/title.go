@@ -33,6 +33,42 @@ type TitleResolver interface {
 	Resolve(ctx context.Context, contentType, imdbID string) (*Title, error)
 }
 
+// Aliases are the other handles a title can be searched by: the names it
+// is released under in other languages, and the series ids indexers built
+// for Sonarr key on. Any field may be empty.
+type Aliases struct {
+	Titles []string
+	TVDBID int
+	TMDBID int
+}
+
+// AliasResolver looks aliases up. An optional extension of TitleResolver,
+// found by type assertion: a resolver without it leaves the planner to the
+// IMDb id and the canonical title.
+type AliasResolver interface {
+	Aliases(ctx context.Context, contentType, imdbID string) (*Aliases, error)
+}
+
+// AliasedTitles pairs a TitleResolver with an AliasResolver, so the pair can
+// travel where a TitleResolver is expected.
+type AliasedTitles struct {
+	TitleResolver
+	aliases AliasResolver
+}
+
+// WithAliases returns titles unchanged when there is nothing to pair it
+// with — a deployment without metadata mappers, say.
+func WithAliases(titles TitleResolver, aliases AliasResolver) TitleResolver {
+	if aliases == nil {
+		return titles
+	}
+	return &AliasedTitles{TitleResolver: titles, aliases: aliases}
+}
+
+func (s *AliasedTitles) Aliases(ctx context.Context, contentType, imdbID string) (*Aliases, error) {
+	return s.aliases.Aliases(ctx, contentType, imdbID)
+}
+
 // CinemetaTitles is the default TitleResolver. Lookups are cached for a day —
 // a title's name does not change, and the cache is what keeps an indexer
 // without imdbid support from adding a Cinemeta round-trip to every stream
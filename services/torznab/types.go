@@ -38,9 +38,13 @@ const (
 // layer only ever searches for a movie or an episode, and keeping the
 // translation in one place means a library change lands in one file.
 type Query struct {
-	Type    SearchType
-	Q       string
-	IMDBID  string
+	Type   SearchType
+	Q      string
+	IMDBID string
+	// TVDBID and TMDBID are series-only: go-jackett's movie search has no
+	// builder for either, and indexers that key films on them are rare.
+	TVDBID  int
+	TMDBID  int
 	Season  *int
 	Episode *int
 	Cats    []int
@@ -73,6 +77,12 @@ func (q Query) fetchRequest() (*jackett.FetchRequest, error) {
 		if id := normalizeIMDBID(q.IMDBID); id != "" {
 			s = s.WithIMDBID(id)
 		}
+		if q.TVDBID > 0 {
+			s = s.WithTVDBID(uint(q.TVDBID))
+		}
+		if q.TMDBID > 0 {
+			s = s.WithTMDBID(uint(q.TMDBID))
+		}
 		if q.Season != nil && *q.Season > 0 {
 			s = s.WithSeason(uint(*q.Season))
 		}
@@ -0,0 +1,90 @@
+package usenet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/services/stremio"
+)
+
+// sealTTL is how long a sealed release can be sent: long enough for a
+// Discover modal left open, short of making old page loads replayable.
+const sealTTL = 24 * time.Hour
+
+// ErrBadToken means a sealed release could not be opened: tampered with,
+// sealed for someone else, sealed with another secret, or expired.
+var ErrBadToken = errors.New("bad usenet release token")
+
+// Sealer hands releases to the browser without their download links. An
+// NZB link carries the indexer's API key, which never reaches a page, so
+// Discover gets the release encrypted and authenticated with a key derived
+// from the session secret, bound to the user it was sealed for.
+type Sealer struct {
+	aead cipher.AEAD
+	now  func() time.Time
+}
+
+func NewSealer(secret string) (*Sealer, error) {
+	key := sha256.Sum256([]byte("usenet-release:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gcm")
+	}
+	return &Sealer{aead: aead, now: time.Now}, nil
+}
+
+type sealed struct {
+	UserID    uuid.UUID          `json:"u"`
+	ExpiresAt int64              `json:"e"`
+	Release   stremio.NZBRelease `json:"r"`
+}
+
+// Seal encrypts the release for the user
+func (s *Sealer) Seal(userID uuid.UUID, r *stremio.NZBRelease) (string, error) {
+	plain, err := json.Marshal(sealed{
+		UserID:    userID,
+		ExpiresAt: s.now().Add(sealTTL).Unix(),
+		Release:   *r,
+	})
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "failed to read nonce")
+	}
+	return base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+// Open decrypts a release sealed for the user
+func (s *Sealer) Open(userID uuid.UUID, token string) (*stremio.NZBRelease, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) < s.aead.NonceSize() {
+		return nil, ErrBadToken
+	}
+	n := s.aead.NonceSize()
+	plain, err := s.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return nil, ErrBadToken
+	}
+	var v sealed
+	if err := json.Unmarshal(plain, &v); err != nil {
+		return nil, ErrBadToken
+	}
+	if v.UserID != userID || s.now().Unix() > v.ExpiresAt {
+		return nil, ErrBadToken
+	}
+	return &v.Release, nil
+}
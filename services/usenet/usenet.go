@@ -0,0 +1,257 @@
+package usenet
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	cs "github.com/webtor-io/common-services"
+
+	"github.com/webtor-io/web-ui/models"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+	"github.com/webtor-io/web-ui/services/stremio"
+)
+
+const (
+	// pollBatch bounds the downloads one poll run asks about
+	pollBatch = 500
+	// staleAfter is how long a download may go unanswered before the poll
+	// gives up on it: a job deleted on the backend's side would otherwise
+	// stay "downloading" forever
+	staleAfter = 7 * 24 * time.Hour
+)
+
+// ErrNoBackend means the user has no enabled backend that downloads Usenet
+// releases
+var ErrNoBackend = errors.New("no usenet backend")
+
+// store is the database behind Usenet downloads, an interface so sending
+// and polling can be tested without Postgres.
+type store interface {
+	Indexer(ctx context.Context, id uuid.UUID) (*models.TorznabIndexer, error)
+	CreateDownload(ctx context.Context, d *models.UsenetDownload) error
+	PendingDownloads(ctx context.Context, limit int) ([]*models.UsenetDownload, error)
+	UpdateDownload(ctx context.Context, d *models.UsenetDownload) error
+}
+
+// manager talks to the user's Usenet backends. LinkResolver is the
+// production one.
+type manager interface {
+	GetUserUsenetBackends(ctx context.Context, userID uuid.UUID) ([]*models.StreamingBackend, error)
+	AddNZB(ctx context.Context, backend *models.StreamingBackend, name string, nzb []byte) (string, error)
+	GetNZB(ctx context.Context, backend *models.StreamingBackend, id string) (*co.UsenetJob, error)
+}
+
+// fetcher downloads NZBs from indexers. The Torznab client is the
+// production one: it applies the same egress policy and proxy as searches.
+type fetcher interface {
+	DownloadNZB(ctx context.Context, link string) ([]byte, error)
+}
+
+// PollStats is what one poll run did
+type PollStats struct {
+	Checked int
+	Ready   int
+	Failed  int
+}
+
+// Usenet sends Usenet releases found in Discover to the user's backends
+// and follows the downloads until their files can be played.
+type Usenet struct {
+	store   store
+	manager manager
+	fetcher fetcher
+	sealer  *Sealer
+	now     func() time.Time
+}
+
+func New(store store, manager manager, fetcher fetcher, sealer *Sealer) *Usenet {
+	return &Usenet{
+		store:   store,
+		manager: manager,
+		fetcher: fetcher,
+		sealer:  sealer,
+		now:     time.Now,
+	}
+}
+
+// Seal hands out a reference to the release the user can send later, see
+// Sealer
+func (s *Usenet) Seal(userID uuid.UUID, r *stremio.NZBRelease) (string, error) {
+	return s.sealer.Seal(userID, r)
+}
+
+// Send downloads the NZB of a sealed release and hands it to the user's
+// Usenet backends in priority order, stopping at the first that takes it.
+func (s *Usenet) Send(ctx context.Context, userID uuid.UUID, token string) (*models.UsenetDownload, error) {
+	r, err := s.sealer.Open(userID, token)
+	if err != nil {
+		return nil, err
+	}
+	// The release was sealed from the user's own indexer; one deleted or
+	// disabled since is not fetched from.
+	ix, err := s.store.Indexer(ctx, r.IndexerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get indexer")
+	}
+	if ix == nil || ix.UserID != userID || !ix.Usenet || !ix.Enabled {
+		return nil, ErrBadToken
+	}
+	backends, err := s.manager.GetUserUsenetBackends(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get usenet backends")
+	}
+	if len(backends) == 0 {
+		return nil, ErrNoBackend
+	}
+	nzb, err := s.fetcher.DownloadNZB(ctx, r.Link)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download nzb")
+	}
+	var lastErr error
+	for _, b := range backends {
+		id, err := s.manager.AddNZB(ctx, b, r.Name, nzb)
+		if err != nil {
+			log.WithError(err).
+				WithField("streaming_backend_id", b.ID).
+				Warn("failed to send nzb to usenet backend")
+			lastErr = err
+			continue
+		}
+		d := &models.UsenetDownload{
+			UserID:             userID,
+			StreamingBackendID: b.ID,
+			RemoteID:           id,
+			Name:               r.Name,
+			Size:               r.Size,
+			Status:             models.UsenetDownloadStatusDownloading,
+		}
+		if err := s.store.CreateDownload(ctx, d); err != nil {
+			return nil, errors.Wrap(err, "failed to store usenet download")
+		}
+		return d, nil
+	}
+	return nil, lastErr
+}
+
+// Poll asks the backends about every download still in flight and records
+// the answers. One download failing to answer does not stop the others.
+func (s *Usenet) Poll(ctx context.Context) (*PollStats, error) {
+	pending, err := s.store.PendingDownloads(ctx, pollBatch)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pending usenet downloads")
+	}
+	st := &PollStats{}
+	for _, d := range pending {
+		if ctx.Err() != nil {
+			return st, ctx.Err()
+		}
+		st.Checked++
+		if err := s.poll(ctx, d); err != nil {
+			log.WithError(err).
+				WithField("usenet_download_id", d.ID).
+				Warn("failed to poll usenet download")
+			continue
+		}
+		switch d.Status {
+		case models.UsenetDownloadStatusReady:
+			st.Ready++
+		case models.UsenetDownloadStatusFailed:
+			st.Failed++
+		}
+	}
+	return st, nil
+}
+
+func (s *Usenet) poll(ctx context.Context, d *models.UsenetDownload) error {
+	if d.StreamingBackend == nil {
+		return errors.New("usenet download has no backend")
+	}
+	job, err := s.manager.GetNZB(ctx, d.StreamingBackend, d.RemoteID)
+	if err != nil {
+		if s.now().Sub(d.CreatedAt) < staleAfter {
+			return err
+		}
+		msg := "the backend stopped answering about this download"
+		job = &co.UsenetJob{Failed: true, Error: msg, Progress: d.Progress}
+		log.WithError(err).WithField("usenet_download_id", d.ID).Info("giving up on stale usenet download")
+	}
+	if job.Name != "" {
+		d.Name = job.Name
+	}
+	if job.Size > 0 {
+		d.Size = job.Size
+	}
+	d.Progress = job.Progress
+	now := s.now()
+	switch {
+	case job.Failed:
+		d.Status = models.UsenetDownloadStatusFailed
+		msg := job.Error
+		if msg == "" {
+			msg = job.Status
+		}
+		d.Error = &msg
+		d.FinishedAt = &now
+	case job.Ready:
+		d.Status = models.UsenetDownloadStatusReady
+		d.Files = make([]models.UsenetDownloadFile, 0, len(job.Files))
+		for _, f := range job.Files {
+			d.Files = append(d.Files, models.UsenetDownloadFile{ID: f.ID, Name: f.Name, Size: f.Size, Link: f.Link})
+		}
+		d.FinishedAt = &now
+	default:
+		d.Status = models.UsenetDownloadStatusDownloading
+	}
+	return s.store.UpdateDownload(ctx, d)
+}
+
+// pgStore is the production store.
+type pgStore struct{ pg *cs.PG }
+
+func NewStore(pg *cs.PG) pgStore {
+	return pgStore{pg: pg}
+}
+
+func (s pgStore) db() (*pg.DB, error) {
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	return db, nil
+}
+
+func (s pgStore) Indexer(ctx context.Context, id uuid.UUID) (*models.TorznabIndexer, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.GetTorznabIndexerByID(ctx, db, id)
+}
+
+func (s pgStore) CreateDownload(ctx context.Context, d *models.UsenetDownload) error {
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+	return models.CreateUsenetDownload(ctx, db, d)
+}
+
+func (s pgStore) PendingDownloads(ctx context.Context, limit int) ([]*models.UsenetDownload, error) {
+	db, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	return models.GetPendingUsenetDownloads(ctx, db, limit)
+}
+
+func (s pgStore) UpdateDownload(ctx context.Context, d *models.UsenetDownload) error {
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+	return models.UpdateUsenetDownload(ctx, db, d)
+}
@@ -0,0 +1,171 @@
+package usenet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/webtor-io/web-ui/models"
+	co "github.com/webtor-io/web-ui/services/link_resolver/common"
+	"github.com/webtor-io/web-ui/services/stremio"
+)
+
+type fakeStore struct {
+	indexers map[uuid.UUID]*models.TorznabIndexer
+	created  []*models.UsenetDownload
+	pending  []*models.UsenetDownload
+	updated  []*models.UsenetDownload
+}
+
+func (s *fakeStore) Indexer(_ context.Context, id uuid.UUID) (*models.TorznabIndexer, error) {
+	return s.indexers[id], nil
+}
+
+func (s *fakeStore) CreateDownload(_ context.Context, d *models.UsenetDownload) error {
+	s.created = append(s.created, d)
+	return nil
+}
+
+func (s *fakeStore) PendingDownloads(context.Context, int) ([]*models.UsenetDownload, error) {
+	return s.pending, nil
+}
+
+func (s *fakeStore) UpdateDownload(_ context.Context, d *models.UsenetDownload) error {
+	s.updated = append(s.updated, d)
+	return nil
+}
+
+type fakeManager struct {
+	backends []*models.StreamingBackend
+	refuse   map[uuid.UUID]bool
+	jobs     map[string]*co.UsenetJob
+}
+
+func (m *fakeManager) GetUserUsenetBackends(context.Context, uuid.UUID) ([]*models.StreamingBackend, error) {
+	return m.backends, nil
+}
+
+func (m *fakeManager) AddNZB(_ context.Context, b *models.StreamingBackend, _ string, _ []byte) (string, error) {
+	if m.refuse[b.ID] {
+		return "", errors.New("account full")
+	}
+	return "job-1", nil
+}
+
+func (m *fakeManager) GetNZB(_ context.Context, _ *models.StreamingBackend, id string) (*co.UsenetJob, error) {
+	if j, ok := m.jobs[id]; ok {
+		return j, nil
+	}
+	return nil, errors.New("no such job")
+}
+
+type fakeFetcher struct{ links []string }
+
+func (f *fakeFetcher) DownloadNZB(_ context.Context, link string) ([]byte, error) {
+	f.links = append(f.links, link)
+	return []byte("<nzb></nzb>"), nil
+}
+
+func TestSealer(t *testing.T) {
+	s, err := NewSealer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+	r := &stremio.NZBRelease{IndexerID: uuid.NewV4(), Link: "https://indexer/getnzb?apikey=k", Name: "Movie", Size: 7}
+	token, err := s.Seal(alice, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Open(alice, token)
+	if err != nil || *got != *r {
+		t.Fatalf("Open() = %+v, %v", got, err)
+	}
+	if _, err := s.Open(bob, token); err != ErrBadToken {
+		t.Errorf("opened for another user: %v", err)
+	}
+	if _, err := s.Open(alice, token[:len(token)-2]+"AA"); err != ErrBadToken {
+		t.Errorf("opened a tampered token: %v", err)
+	}
+	other, _ := NewSealer("other")
+	if _, err := other.Open(alice, token); err != ErrBadToken {
+		t.Errorf("opened with another secret: %v", err)
+	}
+	s.now = func() time.Time { return time.Now().Add(sealTTL + time.Minute) }
+	if _, err := s.Open(alice, token); err != ErrBadToken {
+		t.Errorf("opened an expired token: %v", err)
+	}
+}
+
+// TestSendFallsThroughBackends checks that a release goes to the first
+// backend that takes it, and that a release from an indexer that is not
+// the user's is refused before anything is downloaded.
+func TestSendFallsThroughBackends(t *testing.T) {
+	alice := uuid.NewV4()
+	ix := &models.TorznabIndexer{ID: uuid.NewV4(), UserID: alice, Usenet: true, Enabled: true}
+	foreign := &models.TorznabIndexer{ID: uuid.NewV4(), UserID: uuid.NewV4(), Usenet: true, Enabled: true}
+	full := &models.StreamingBackend{ID: uuid.NewV4()}
+	sab := &models.StreamingBackend{ID: uuid.NewV4()}
+	st := &fakeStore{indexers: map[uuid.UUID]*models.TorznabIndexer{ix.ID: ix, foreign.ID: foreign}}
+	m := &fakeManager{backends: []*models.StreamingBackend{full, sab}, refuse: map[uuid.UUID]bool{full.ID: true}}
+	f := &fakeFetcher{}
+	sealer, _ := NewSealer("secret")
+	u := New(st, m, f, sealer)
+
+	token, _ := u.Seal(alice, &stremio.NZBRelease{IndexerID: ix.ID, Link: "https://indexer/getnzb", Name: "Movie", Size: 7})
+	d, err := u.Send(context.Background(), alice, token)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if d.StreamingBackendID != sab.ID || d.RemoteID != "job-1" || d.Status != models.UsenetDownloadStatusDownloading || len(st.created) != 1 {
+		t.Errorf("download = %+v", d)
+	}
+
+	token, _ = u.Seal(alice, &stremio.NZBRelease{IndexerID: foreign.ID, Link: "https://elsewhere/getnzb"})
+	if _, err := u.Send(context.Background(), alice, token); err != ErrBadToken {
+		t.Errorf("Send() from a foreign indexer = %v", err)
+	}
+	if len(f.links) != 1 {
+		t.Errorf("fetched %v, want only the first release", f.links)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	b := &models.StreamingBackend{ID: uuid.NewV4()}
+	now := time.Now()
+	ready := &models.UsenetDownload{ID: uuid.NewV4(), RemoteID: "ready", StreamingBackend: b, CreatedAt: now}
+	running := &models.UsenetDownload{ID: uuid.NewV4(), RemoteID: "running", StreamingBackend: b, CreatedAt: now}
+	lost := &models.UsenetDownload{ID: uuid.NewV4(), RemoteID: "lost", StreamingBackend: b, CreatedAt: now.Add(-staleAfter - time.Hour)}
+	missing := &models.UsenetDownload{ID: uuid.NewV4(), RemoteID: "missing", StreamingBackend: b, CreatedAt: now}
+	st := &fakeStore{pending: []*models.UsenetDownload{ready, running, lost, missing}}
+	m := &fakeManager{jobs: map[string]*co.UsenetJob{
+		"ready":   {Ready: true, Progress: 100, Name: "Movie", Files: []co.UsenetFile{{ID: "3", Name: "Movie.mkv", Size: 9}}},
+		"running": {Progress: 40, Status: "Downloading"},
+	}}
+	u := New(st, m, &fakeFetcher{}, nil)
+
+	stats, err := u.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Checked != 4 || stats.Ready != 1 || stats.Failed != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if !ready.Ready() || ready.FinishedAt == nil || len(ready.Files) != 1 || ready.Files[0].ID != "3" || ready.Name != "Movie" {
+		t.Errorf("ready download = %+v", ready)
+	}
+	if running.Status != models.UsenetDownloadStatusDownloading || running.Progress != 40 || running.FinishedAt != nil {
+		t.Errorf("running download = %+v", running)
+	}
+	if !lost.Failed() || lost.Error == nil {
+		t.Errorf("stale download = %+v", lost)
+	}
+	// A recent download the backend did not answer about is left for the
+	// next run
+	if len(st.updated) != 3 {
+		t.Errorf("updated %d downloads, want 3", len(st.updated))
+	}
+}
@@ -115,3 +115,21 @@ func (s *Service) SetLang(ctx context.Context, userID uuid.UUID, lang string) er
 	s.LazyMap.Drop(userID.String())
 	return nil
 }
+
+// SetMail stores the release-subscription mail settings: digest mode,
+// quiet hours and the time zone both are read in. Column-scoped, like
+// SetLang, so the profile form that saves it cannot reset the others.
+func (s *Service) SetMail(ctx context.Context, userID uuid.UUID, digest string, start, end *int16, timeZone string) error {
+	if userID == uuid.Nil {
+		return errors.New("user_settings: empty user_id")
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return errors.New("user_settings: no db")
+	}
+	if err := models.SetUserSettingsMail(ctx, db, userID, digest, start, end, timeZone); err != nil {
+		return errors.Wrap(err, "failed to store subscription mail settings")
+	}
+	s.LazyMap.Drop(userID.String())
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -119,6 +120,17 @@ func (s *Service) Enabled() bool {
 // serialises the transaction against Delete so a concurrent DeleteObject
 // cannot race the PutObject.
 func (s *Service) Upload(ctx context.Context, userID uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error) {
+	return s.upload(ctx, userID, uuid.Nil, resourceID, path, filename, data)
+}
+
+// Replace uploads a subtitle in place of the user's subtitle replaces. The
+// replaced one does not count towards MaxPerFile, and it is deleted in the
+// transaction that inserts the new one, so a failed upload keeps it.
+func (s *Service) Replace(ctx context.Context, userID, replaces uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error) {
+	return s.upload(ctx, userID, replaces, resourceID, path, filename, data)
+}
+
+func (s *Service) upload(ctx context.Context, userID, replaces uuid.UUID, resourceID, path, filename string, data []byte) (*models.UserSubtitle, error) {
 	if s == nil {
 		return nil, ErrNotConfigured
 	}
@@ -139,7 +151,18 @@ func (s *Service) Upload(ctx context.Context, userID uuid.UUID, resourceID, path
 		return nil, errors.New("no db")
 	}
 
-	count, err := models.CountUserSubtitlesForFile(ctx, db, userID, resourceID, path)
+	var old *models.UserSubtitle
+	if replaces != uuid.Nil {
+		var err error
+		old, err = models.GetUserSubtitle(ctx, db, userID, replaces)
+		if err != nil {
+			return nil, err
+		}
+		if old == nil {
+			return nil, ErrNotFound
+		}
+	}
+	count, err := models.CountUserSubtitlesForFile(ctx, db, userID, resourceID, path, replaces)
 	if err != nil {
 		return nil, err
 	}
@@ -161,9 +184,18 @@ func (s *Service) Upload(ctx context.Context, userID uuid.UUID, resourceID, path
 		Size:           int64(len(data)),
 	}
 
+	// Both blobs are locked, in a fixed order so two replaces cannot wait
+	// on each other.
+	locks := []string{hash}
+	if old != nil && old.Hash != hash {
+		locks = append(locks, old.Hash)
+		sort.Strings(locks)
+	}
 	err = db.RunInTransaction(ctx, func(tx *pg.Tx) error {
-		if err := advisoryLock(ctx, tx, hash); err != nil {
-			return err
+		for _, h := range locks {
+			if err := advisoryLock(ctx, tx, h); err != nil {
+				return err
+			}
 		}
 		if err := s.putObject(ctx, hash, data); err != nil {
 			return err
@@ -183,11 +215,14 @@ func (s *Service) Upload(ctx context.Context, userID uuid.UUID, resourceID, path
 					return errors.Wrap(lookupErr, "failed to load existing user subtitle")
 				}
 				*us = existing
-				return nil
+			} else {
+				return errors.Wrap(err, "failed to insert user subtitle")
 			}
-			return errors.Wrap(err, "failed to insert user subtitle")
 		}
-		return nil
+		if old == nil || old.UserSubtitleID == us.UserSubtitleID {
+			return nil
+		}
+		return s.deleteTx(ctx, tx, userID, old.UserSubtitleID)
 	})
 	if err != nil {
 		return nil, err
@@ -232,26 +267,30 @@ func (s *Service) Delete(ctx context.Context, userID, id uuid.UUID) error {
 		if err := advisoryLock(ctx, tx, hash); err != nil {
 			return err
 		}
-		deletedHash, err := models.DeleteUserSubtitleTx(ctx, tx, userID, id)
-		if err != nil {
-			return err
-		}
-		if deletedHash == "" {
-			return nil
-		}
-		count, err := models.CountUserSubtitlesByHash(ctx, tx, deletedHash)
-		if err != nil {
-			return err
-		}
-		if count == 0 {
-			if err := s.deleteObject(ctx, deletedHash); err != nil {
-				return err
-			}
-		}
-		return nil
+		return s.deleteTx(ctx, tx, userID, id)
 	})
 }
 
+// deleteTx removes a binding inside a transaction that holds the advisory
+// lock on its hash, and the S3 object with it when it was the last one.
+func (s *Service) deleteTx(ctx context.Context, tx *pg.Tx, userID, id uuid.UUID) error {
+	deletedHash, err := models.DeleteUserSubtitleTx(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if deletedHash == "" {
+		return nil
+	}
+	count, err := models.CountUserSubtitlesByHash(ctx, tx, deletedHash)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return s.deleteObject(ctx, deletedHash)
+	}
+	return nil
+}
+
 // GetFile streams the raw blob from S3. The endpoint that wraps this call
 // is public on purpose: torrent-http-proxy reaches it through /ext/ when
 // converting SRT → VTT on the fly, so it must not require a user session.
@@ -290,6 +329,18 @@ func (s *Service) List(ctx context.Context, userID uuid.UUID, resourceID, path s
 	return models.ListUserSubtitlesForFile(ctx, db, userID, resourceID, path)
 }
 
+// ListForResource returns a user's subtitles for every file of one torrent.
+func (s *Service) ListForResource(ctx context.Context, userID uuid.UUID, resourceID string) ([]*models.UserSubtitle, error) {
+	if s == nil {
+		return nil, nil
+	}
+	db := s.pg.Get()
+	if db == nil {
+		return nil, errors.New("no db")
+	}
+	return models.ListUserSubtitlesForResource(ctx, db, userID, resourceID)
+}
+
 // Get returns the binding for a user-owned id, or nil if it does not exist.
 // Useful for the delete flow, which needs (resource_id, path) before the row
 // disappears so the async re-render can target the same file context.
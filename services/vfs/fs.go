@@ -3,7 +3,8 @@
 //
 // It is deliberately protocol-neutral: the tree in handlers/vfs implements this
 // interface once (library folders, torrent contents, .torrent files) and each
-// protocol layer — services/webdav (RFC 4918) and services/s3 (S3 REST) —
+// protocol layer — services/webdav (RFC 4918), services/s3 (S3 REST),
+// services/sftp (SFTP over SSH) and services/autoindex (HTML for browsers) —
 // adapts it to its own wire format. Nothing here may depend on a protocol
 // package.
 package vfs
@@ -46,6 +47,24 @@ type FileSystem interface {
 	Move(ctx context.Context, name, dest string, options *MoveOptions) (created bool, err error)
 }
 
+// MetadataReader is implemented by a FileSystem that can say what a file is,
+// beyond what FileInfo carries. Protocol layers probe for it (ReadMetadata)
+// and publish the pairs in their own idiom — S3 as user metadata and object
+// tags. Keys are lowercase words joined by dashes.
+type MetadataReader interface {
+	Metadata(ctx context.Context, name string) (map[string]string, error)
+}
+
+// ReadMetadata returns fs's metadata for name, or nil when fs does not
+// implement MetadataReader.
+func ReadMetadata(ctx context.Context, fs FileSystem, name string) (map[string]string, error) {
+	mr, ok := fs.(MetadataReader)
+	if !ok {
+		return nil, nil
+	}
+	return mr.Metadata(ctx, name)
+}
+
 type CreateOptions struct {
 	IfMatch     ConditionalMatch
 	IfNoneMatch ConditionalMatch
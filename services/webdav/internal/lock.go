@@ -0,0 +1,691 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locking (RFC 4918 §6-7, class 2). Nothing here stops a client that does
+// not lock from writing; what locks buy is the handshake Finder and the
+// Windows Mini-Redirector insist on before they will treat a share as
+// writable, and protection of one client's edit from another's.
+
+var (
+	// ErrLocked is returned by LockSystem.Create when the new lock would
+	// conflict with an existing one.
+	ErrLocked = errors.New("webdav: locked")
+	// ErrNoSuchLock is returned when a token names no active lock.
+	ErrNoSuchLock = errors.New("webdav: no such lock")
+)
+
+const (
+	// MaxLockTimeout caps what a client may ask for. Windows asks for
+	// Infinite, Finder for ten minutes and refreshes; a lock a crashed
+	// client left behind should not outlive the hour.
+	MaxLockTimeout = time.Hour
+)
+
+// LockDetails is a lock as the client asked for it.
+type LockDetails struct {
+	// Root is the path the lock was taken on.
+	Root string `json:"root"`
+	// Exclusive is an exclusive write lock; otherwise it is shared.
+	Exclusive bool `json:"exclusive"`
+	// ZeroDepth locks only Root, not its members.
+	ZeroDepth bool `json:"zero_depth"`
+	// Owner is the DAV:owner element the client sent, as XML, or "".
+	Owner   string        `json:"owner,omitempty"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+// Lock is an active lock.
+type Lock struct {
+	LockDetails
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Covers reports whether the lock applies to name.
+func (l *Lock) Covers(name string) bool {
+	root := cleanLockPath(l.Root)
+	name = cleanLockPath(name)
+	if l.ZeroDepth {
+		return root == name
+	}
+	return isUnder(name, root)
+}
+
+// LockSystem keeps lock state. Implementations must make Create atomic:
+// two conflicting requests racing must not both get a lock.
+type LockSystem interface {
+	// Create takes a new lock, or fails with ErrLocked.
+	Create(ctx context.Context, now time.Time, details LockDetails) (*Lock, error)
+	// Refresh extends a lock's timeout, or fails with ErrNoSuchLock.
+	Refresh(ctx context.Context, now time.Time, token string, timeout time.Duration) (*Lock, error)
+	// Unlock removes a lock, or fails with ErrNoSuchLock.
+	Unlock(ctx context.Context, now time.Time, token string) error
+	// List returns every active lock.
+	List(ctx context.Context, now time.Time) ([]Lock, error)
+	// Release removes the locks on root and everything under it — what a
+	// successful DELETE or MOVE does to the locks on its source.
+	Release(ctx context.Context, now time.Time, root string) error
+}
+
+// ConflictingLock returns an active lock that keeps d from being granted,
+// or nil. Two locks conflict when their scopes share a resource and either
+// is exclusive.
+func ConflictingLock(locks []Lock, d LockDetails) *Lock {
+	root := cleanLockPath(d.Root)
+	for i := range locks {
+		l := &locks[i]
+		if !l.Exclusive && !d.Exclusive {
+			continue
+		}
+		if l.Covers(root) {
+			return l
+		}
+		if !d.ZeroDepth && isUnder(cleanLockPath(l.Root), root) {
+			return l
+		}
+	}
+	return nil
+}
+
+// IsUnderLockRoot reports whether name is root or lies inside it.
+func IsUnderLockRoot(name, root string) bool {
+	return isUnder(cleanLockPath(name), cleanLockPath(root))
+}
+
+func cleanLockPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return path.Clean("/" + p)
+}
+
+func isUnder(name, root string) bool {
+	if root == "/" {
+		return true
+	}
+	return name == root || strings.HasPrefix(name, root+"/")
+}
+
+// ParseTimeout reads a Timeout header (RFC 4918 §10.7): the first value
+// the server understands wins, capped at MaxLockTimeout. An absent or
+// unparseable header gets the cap.
+func ParseTimeout(s string) time.Duration {
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "Infinite" {
+			return MaxLockTimeout
+		}
+		if n, ok := strings.CutPrefix(v, "Second-"); ok {
+			sec, err := strconv.ParseInt(n, 10, 64)
+			if err != nil || sec <= 0 {
+				continue
+			}
+			if sec >= int64(MaxLockTimeout/time.Second) {
+				return MaxLockTimeout
+			}
+			return time.Duration(sec) * time.Second
+		}
+	}
+	return MaxLockTimeout
+}
+
+// ParseLockToken reads a Lock-Token header: a Coded-URL, "<token>".
+func ParseLockToken(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 3 || s[0] != '<' || s[len(s)-1] != '>' {
+		return "", fmt.Errorf("webdav: malformed Lock-Token header")
+	}
+	return s[1 : len(s)-1], nil
+}
+
+// IfCondition is one condition of an If header list: a state token or an
+// entity tag, possibly negated.
+type IfCondition struct {
+	Not   bool
+	Token string
+	ETag  string
+}
+
+// IfList is one parenthesised list; it holds when all its conditions do.
+// Resource is the tagged resource's path, or "" for the request URI.
+type IfList struct {
+	Resource   string
+	Conditions []IfCondition
+}
+
+// ParseIf parses an If header (RFC 4918 §10.4): untagged lists apply to
+// the request URI, tagged ones to the resource whose <href> precedes them.
+func ParseIf(s string) ([]IfList, error) {
+	var lists []IfList
+	resource := ""
+	s = strings.TrimSpace(s)
+	for s != "" {
+		switch s[0] {
+		case '<':
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return nil, fmt.Errorf("webdav: malformed If header")
+			}
+			u, err := url.Parse(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("webdav: malformed If header resource: %v", err)
+			}
+			resource = u.Path
+			if resource == "" {
+				resource = "/"
+			}
+			s = s[end+1:]
+		case '(':
+			end := strings.IndexByte(s, ')')
+			if end < 0 {
+				return nil, fmt.Errorf("webdav: malformed If header")
+			}
+			conds, err := parseIfConditions(s[1:end])
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, IfList{Resource: resource, Conditions: conds})
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("webdav: malformed If header")
+		}
+		s = strings.TrimSpace(s)
+	}
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("webdav: empty If header")
+	}
+	return lists, nil
+}
+
+func parseIfConditions(s string) ([]IfCondition, error) {
+	var conds []IfCondition
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var c IfCondition
+		if rest, ok := strings.CutPrefix(s, "Not"); ok {
+			c.Not = true
+			s = strings.TrimSpace(rest)
+		}
+		if s == "" {
+			return nil, fmt.Errorf("webdav: malformed If header condition")
+		}
+		var closing byte
+		switch s[0] {
+		case '<':
+			closing = '>'
+		case '[':
+			closing = ']'
+		default:
+			return nil, fmt.Errorf("webdav: malformed If header condition")
+		}
+		end := strings.IndexByte(s, closing)
+		if end < 0 {
+			return nil, fmt.Errorf("webdav: malformed If header condition")
+		}
+		if closing == '>' {
+			c.Token = s[1:end]
+		} else {
+			c.ETag = s[1:end]
+		}
+		conds = append(conds, c)
+		s = strings.TrimSpace(s[end+1:])
+	}
+	if len(conds) == 0 {
+		return nil, fmt.Errorf("webdav: empty If header list")
+	}
+	return conds, nil
+}
+
+var (
+	LockDiscoveryName = xml.Name{Namespace, "lockdiscovery"}
+	SupportedLockName = xml.Name{Namespace, "supportedlock"}
+)
+
+// https://tools.ietf.org/html/rfc4918#section-14.11
+type LockInfo struct {
+	XMLName   xml.Name     `xml:"DAV: lockinfo"`
+	LockScope LockScope    `xml:"lockscope"`
+	LockType  LockType     `xml:"locktype"`
+	Owner     *RawXMLValue `xml:"owner,omitempty"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.13
+type LockScope struct {
+	Exclusive *struct{} `xml:"exclusive,omitempty"`
+	Shared    *struct{} `xml:"shared,omitempty"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.15
+type LockType struct {
+	Write *struct{} `xml:"write,omitempty"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.1
+type ActiveLock struct {
+	XMLName   xml.Name     `xml:"DAV: activelock"`
+	LockScope LockScope    `xml:"lockscope"`
+	LockType  LockType     `xml:"locktype"`
+	Depth     string       `xml:"depth"`
+	Owner     *RawXMLValue `xml:"owner,omitempty"`
+	Timeout   string       `xml:"timeout"`
+	LockToken Href         `xml:"locktoken>href"`
+	LockRoot  Href         `xml:"lockroot>href"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-15.8
+type LockDiscovery struct {
+	XMLName     xml.Name     `xml:"DAV: lockdiscovery"`
+	ActiveLocks []ActiveLock `xml:"activelock"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-15.10
+type SupportedLock struct {
+	XMLName     xml.Name    `xml:"DAV: supportedlock"`
+	LockEntries []LockEntry `xml:"lockentry"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.10
+type LockEntry struct {
+	LockScope LockScope `xml:"lockscope"`
+	LockType  LockType  `xml:"locktype"`
+}
+
+// NewSupportedLock advertises exclusive and shared write locks.
+func NewSupportedLock() *SupportedLock {
+	return &SupportedLock{LockEntries: []LockEntry{
+		{LockScope: LockScope{Exclusive: &struct{}{}}, LockType: LockType{Write: &struct{}{}}},
+		{LockScope: LockScope{Shared: &struct{}{}}, LockType: LockType{Write: &struct{}{}}},
+	}}
+}
+
+// NewLockDiscovery renders the locks covering a resource.
+func NewLockDiscovery(now time.Time, locks ...Lock) *LockDiscovery {
+	ld := &LockDiscovery{ActiveLocks: make([]ActiveLock, 0, len(locks))}
+	for _, l := range locks {
+		ld.ActiveLocks = append(ld.ActiveLocks, newActiveLock(now, &l))
+	}
+	return ld
+}
+
+func newActiveLock(now time.Time, l *Lock) ActiveLock {
+	al := ActiveLock{
+		LockType: LockType{Write: &struct{}{}},
+		Depth:    DepthInfinity.String(),
+		LockRoot: Href{Path: l.Root},
+	}
+	// Tokens are URNs; as a Path they would be printed "./urn:uuid:…".
+	if u, err := url.Parse(l.Token); err == nil {
+		al.LockToken = Href(*u)
+	}
+	if l.Exclusive {
+		al.LockScope.Exclusive = &struct{}{}
+	} else {
+		al.LockScope.Shared = &struct{}{}
+	}
+	if l.ZeroDepth {
+		al.Depth = DepthZero.String()
+	}
+	left := l.Expires.Sub(now) / time.Second
+	if left < 1 {
+		left = 1
+	}
+	al.Timeout = fmt.Sprintf("Second-%d", left)
+	if l.Owner != "" {
+		var owner RawXMLValue
+		if err := xml.Unmarshal([]byte(l.Owner), &owner); err == nil {
+			al.Owner = &owner
+		}
+	}
+	return al
+}
+
+// encodeOwner turns the DAV:owner element of a LOCK body into the
+// self-contained XML a LockSystem stores. Namespace declarations are
+// dropped: the decoder has already resolved every name, and encoding/xml
+// would write the declarations back as attributes of their own.
+func encodeOwner(owner *RawXMLValue) (string, error) {
+	if owner == nil {
+		return "", nil
+	}
+	stripNamespaceAttrs(owner)
+	b, err := xml.Marshal(owner)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func stripNamespaceAttrs(v *RawXMLValue) {
+	if start, ok := v.tok.(xml.StartElement); ok {
+		attrs := start.Attr[:0:0]
+		for _, a := range start.Attr {
+			if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		start.Attr = attrs
+		v.tok = start
+	}
+	for i := range v.children {
+		stripNamespaceAttrs(&v.children[i])
+	}
+}
+
+type lockTokenSubmitted struct {
+	XMLName xml.Name `xml:"DAV: lock-token-submitted"`
+	Hrefs   []Href   `xml:"href"`
+}
+
+type noConflictingLock struct {
+	XMLName xml.Name `xml:"DAV: no-conflicting-lock"`
+	Hrefs   []Href   `xml:"href"`
+}
+
+type lockTokenMatchesRequestURI struct {
+	XMLName xml.Name `xml:"DAV: lock-token-matches-request-uri"`
+}
+
+// lockError is a status with the precondition element (RFC 4918 §16) the
+// client reads to tell why it was refused.
+func lockError(code int, cond interface{}) error {
+	raw, err := EncodeRawXMLElement(cond)
+	if err != nil {
+		return err
+	}
+	return &HTTPError{Code: code, Err: &Error{Raw: []RawXMLValue{*raw}}}
+}
+
+// lockTarget is a resource a write method modifies. deep is set when the
+// method also modifies everything under it (DELETE, MOVE, COPY onto an
+// existing tree); member when it may add or remove the resource itself,
+// which a depth-0 lock on the parent collection protects.
+type lockTarget struct {
+	name   string
+	deep   bool
+	member bool
+}
+
+func (t lockTarget) lockedBy(l *Lock) bool {
+	if l.Covers(t.name) {
+		return true
+	}
+	if t.deep && IsUnderLockRoot(l.Root, t.name) {
+		return true
+	}
+	return t.member && l.ZeroDepth && cleanLockPath(l.Root) == path.Dir(cleanLockPath(t.name))
+}
+
+// lockTargets lists what a request writes to; nil for methods that write
+// nothing. LOCK and UNLOCK check their tokens themselves.
+func lockTargets(r *http.Request) ([]lockTarget, error) {
+	switch r.Method {
+	case http.MethodPut, "MKCOL":
+		return []lockTarget{{name: r.URL.Path, member: true}}, nil
+	case "PROPPATCH":
+		return []lockTarget{{name: r.URL.Path}}, nil
+	case http.MethodDelete:
+		return []lockTarget{{name: r.URL.Path, deep: true, member: true}}, nil
+	case "COPY", "MOVE":
+		dest, err := parseDestination(r.Header)
+		if err != nil {
+			return nil, err
+		}
+		targets := []lockTarget{{name: dest.Path, deep: true, member: true}}
+		if r.Method == "MOVE" {
+			targets = append(targets, lockTarget{name: r.URL.Path, deep: true, member: true})
+		}
+		return targets, nil
+	}
+	return nil, nil
+}
+
+// confirmLocks refuses a write that touches a locked resource without the
+// lock's token in the If header, and any request whose If header does not
+// hold.
+func (h *Handler) confirmLocks(r *http.Request) error {
+	if h.LockSystem == nil {
+		return nil
+	}
+	targets, err := lockTargets(r)
+	if err != nil || targets == nil {
+		return err
+	}
+	locks, err := h.LockSystem.List(r.Context(), time.Now())
+	if err != nil {
+		return err
+	}
+	submitted, err := evalIf(r, locks)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		for i := range locks {
+			l := &locks[i]
+			if !t.lockedBy(l) || lockSubmitted(l, locks, submitted) {
+				continue
+			}
+			return lockError(http.StatusLocked, &lockTokenSubmitted{Hrefs: []Href{{Path: l.Root}}})
+		}
+	}
+	return nil
+}
+
+// evalIf evaluates the If header and returns the tokens of the lists that
+// hold. A state token holds when it names an active lock on or under the
+// resource; entity tags are not tracked and always hold.
+func evalIf(r *http.Request, locks []Lock) (map[string]bool, error) {
+	s := r.Header.Get("If")
+	if s == "" {
+		return nil, nil
+	}
+	lists, err := ParseIf(s)
+	if err != nil {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: err}
+	}
+	submitted := map[string]bool{}
+	held := false
+	for _, l := range lists {
+		res := l.Resource
+		if res == "" {
+			res = r.URL.Path
+		}
+		if !ifListHolds(l, res, locks) {
+			continue
+		}
+		held = true
+		for _, c := range l.Conditions {
+			if c.Token != "" && !c.Not {
+				submitted[c.Token] = true
+			}
+		}
+	}
+	if !held {
+		return nil, HTTPErrorf(http.StatusPreconditionFailed, "webdav: If header precondition failed")
+	}
+	return submitted, nil
+}
+
+func ifListHolds(l IfList, res string, locks []Lock) bool {
+	for _, c := range l.Conditions {
+		ok := true
+		if c.Token != "" {
+			ok = false
+			for i := range locks {
+				if locks[i].Token == c.Token && (locks[i].Covers(res) || IsUnderLockRoot(locks[i].Root, res)) {
+					ok = true
+					break
+				}
+			}
+		}
+		if ok == c.Not {
+			return false
+		}
+	}
+	return true
+}
+
+// lockSubmitted reports whether the request holds l. Any one of the shared
+// locks on a resource is enough: they are what lets several clients write.
+func lockSubmitted(l *Lock, locks []Lock, submitted map[string]bool) bool {
+	if submitted[l.Token] {
+		return true
+	}
+	if l.Exclusive {
+		return false
+	}
+	for i := range locks {
+		o := &locks[i]
+		if !o.Exclusive && cleanLockPath(o.Root) == cleanLockPath(l.Root) && submitted[o.Token] {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request) error {
+	ctx, now := r.Context(), time.Now()
+	timeout := ParseTimeout(r.Header.Get("Timeout"))
+
+	// An empty body refreshes the lock named in the If header (RFC 4918
+	// §9.10.2).
+	body := bufio.NewReader(r.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		return h.refreshLock(w, r, now, timeout)
+	}
+	var info LockInfo
+	if err := xml.NewDecoder(body).Decode(&info); err != nil {
+		return &HTTPError{Code: http.StatusBadRequest, Err: err}
+	}
+	if info.LockType.Write == nil {
+		return HTTPErrorf(http.StatusBadRequest, "webdav: only write locks are supported")
+	}
+	if (info.LockScope.Exclusive == nil) == (info.LockScope.Shared == nil) {
+		return HTTPErrorf(http.StatusBadRequest, "webdav: lock scope must be exclusive or shared")
+	}
+	depth := DepthInfinity
+	if s := r.Header.Get("Depth"); s != "" {
+		var err error
+		depth, err = ParseDepth(s)
+		if err != nil {
+			return &HTTPError{Code: http.StatusBadRequest, Err: err}
+		}
+	}
+	if depth == DepthOne {
+		return HTTPErrorf(http.StatusBadRequest, `webdav: "Depth: 1" is not allowed in LOCK request`)
+	}
+	owner, err := encodeOwner(info.Owner)
+	if err != nil {
+		return &HTTPError{Code: http.StatusBadRequest, Err: err}
+	}
+
+	// An unmapped URL is locked without being created: the clients that do
+	// this (Windows, Office) PUT the content right after.
+	l, err := h.LockSystem.Create(ctx, now, LockDetails{
+		Root:      r.URL.Path,
+		Exclusive: info.LockScope.Exclusive != nil,
+		ZeroDepth: depth == DepthZero,
+		Owner:     owner,
+		Timeout:   timeout,
+	})
+	if errors.Is(err, ErrLocked) {
+		return lockError(http.StatusLocked, &noConflictingLock{Hrefs: []Href{{Path: r.URL.Path}}})
+	} else if err != nil {
+		return err
+	}
+	w.Header().Set("Lock-Token", "<"+l.Token+">")
+	return serveLockDiscovery(w, now, l)
+}
+
+func (h *Handler) refreshLock(w http.ResponseWriter, r *http.Request, now time.Time, timeout time.Duration) error {
+	lists, err := ParseIf(r.Header.Get("If"))
+	if err != nil {
+		return HTTPErrorf(http.StatusBadRequest, "webdav: LOCK refresh needs a lock token in the If header")
+	}
+	locks, err := h.LockSystem.List(r.Context(), now)
+	if err != nil {
+		return err
+	}
+	for _, list := range lists {
+		for _, c := range list.Conditions {
+			if c.Token == "" || c.Not {
+				continue
+			}
+			for i := range locks {
+				if locks[i].Token != c.Token || !locks[i].Covers(r.URL.Path) {
+					continue
+				}
+				l, err := h.LockSystem.Refresh(r.Context(), now, c.Token, timeout)
+				if errors.Is(err, ErrNoSuchLock) {
+					break
+				} else if err != nil {
+					return err
+				}
+				return serveLockDiscovery(w, now, l)
+			}
+		}
+	}
+	return HTTPErrorf(http.StatusPreconditionFailed, "webdav: no lock to refresh")
+}
+
+func serveLockDiscovery(w http.ResponseWriter, now time.Time, l *Lock) error {
+	raw, err := EncodeRawXMLElement(NewLockDiscovery(now, *l))
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return ServeXML(w).Encode(&Prop{Raw: []RawXMLValue{*raw}})
+}
+
+func (h *Handler) handleUnlock(w http.ResponseWriter, r *http.Request) error {
+	token, err := ParseLockToken(r.Header.Get("Lock-Token"))
+	if err != nil {
+		return &HTTPError{Code: http.StatusBadRequest, Err: err}
+	}
+	ctx, now := r.Context(), time.Now()
+	locks, err := h.LockSystem.List(ctx, now)
+	if err != nil {
+		return err
+	}
+	var found *Lock
+	for i := range locks {
+		if locks[i].Token == token {
+			found = &locks[i]
+			break
+		}
+	}
+	if found == nil || !found.Covers(r.URL.Path) {
+		return lockError(http.StatusConflict, &lockTokenMatchesRequestURI{})
+	}
+	err = h.LockSystem.Unlock(ctx, now, token)
+	if errors.Is(err, ErrNoSuchLock) {
+		return lockError(http.StatusConflict, &lockTokenMatchesRequestURI{})
+	} else if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// releaseLocks drops the locks on a resource that is gone. The write has
+// already happened, so a failure here only leaves locks to time out.
+func (h *Handler) releaseLocks(r *http.Request, name string) {
+	if h.LockSystem == nil {
+		return
+	}
+	_ = h.LockSystem.Release(r.Context(), time.Now(), name)
+}
@@ -0,0 +1,281 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLocks is an in-memory LockSystem for handler tests.
+type memLocks struct {
+	mu    sync.Mutex
+	locks []Lock
+	next  int
+}
+
+func (m *memLocks) Create(_ context.Context, now time.Time, d LockDetails) (*Lock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ConflictingLock(m.locks, d) != nil {
+		return nil, ErrLocked
+	}
+	m.next++
+	l := Lock{LockDetails: d, Token: fmt.Sprintf("urn:uuid:%d", m.next), Expires: now.Add(d.Timeout)}
+	m.locks = append(m.locks, l)
+	return &l, nil
+}
+
+func (m *memLocks) Refresh(_ context.Context, now time.Time, token string, timeout time.Duration) (*Lock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.locks {
+		if m.locks[i].Token == token {
+			m.locks[i].Expires = now.Add(timeout)
+			l := m.locks[i]
+			return &l, nil
+		}
+	}
+	return nil, ErrNoSuchLock
+}
+
+func (m *memLocks) Unlock(_ context.Context, _ time.Time, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.locks {
+		if m.locks[i].Token == token {
+			m.locks = append(m.locks[:i], m.locks[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNoSuchLock
+}
+
+func (m *memLocks) List(context.Context, time.Time) ([]Lock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Lock(nil), m.locks...), nil
+}
+
+func (m *memLocks) Release(_ context.Context, _ time.Time, root string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.locks[:0]
+	for _, l := range m.locks {
+		if !IsUnderLockRoot(l.Root, root) {
+			kept = append(kept, l)
+		}
+	}
+	m.locks = kept
+	return nil
+}
+
+const exclusiveLockBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+ <D:lockscope><D:exclusive/></D:lockscope>
+ <D:locktype><D:write/></D:locktype>
+ <D:owner><D:href>http://example.org/~finder</D:href></D:owner>
+</D:lockinfo>`
+
+const sharedLockBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+ <D:lockscope><D:shared/></D:lockscope>
+ <D:locktype><D:write/></D:locktype>
+</D:lockinfo>`
+
+func serve(t *testing.T, h *Handler, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, http.NoBody)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func lock(t *testing.T, h *Handler, target, body string) string {
+	t.Helper()
+	w := serve(t, h, "LOCK", target, body, map[string]string{"Timeout": "Second-600"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("LOCK %s: expected 200, got %d: %s", target, w.Code, w.Body.String())
+	}
+	token, err := ParseLockToken(w.Header().Get("Lock-Token"))
+	if err != nil {
+		t.Fatalf("LOCK %s: %v", target, err)
+	}
+	return token
+}
+
+func TestLock_WriteNeedsToken(t *testing.T) {
+	h := &Handler{Backend: &fakeBackend{}, LockSystem: &memLocks{}}
+	token := lock(t, h, "/torrents/a.torrent", exclusiveLockBody)
+
+	w := serve(t, h, http.MethodPut, "/torrents/a.torrent", "x", nil)
+	if w.Code != http.StatusLocked {
+		t.Fatalf("PUT without token: expected 423, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "lock-token-submitted") {
+		t.Fatalf("423 must name the lock-token-submitted precondition: %s", w.Body.String())
+	}
+
+	w = serve(t, h, http.MethodPut, "/torrents/a.torrent", "x", map[string]string{"If": "(<" + token + ">)"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT with token: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(t, h, http.MethodPut, "/torrents/a.torrent", "x", map[string]string{"If": "(<urn:uuid:nope>)"})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT with unknown token: expected 412, got %d", w.Code)
+	}
+
+	// Other resources are not affected.
+	w = serve(t, h, http.MethodPut, "/torrents/b.torrent", "x", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT elsewhere: expected 200, got %d", w.Code)
+	}
+}
+
+func TestLock_ZeroDepthParentGuardsMembership(t *testing.T) {
+	h := &Handler{Backend: &fakeBackend{}, LockSystem: &memLocks{}}
+	w := serve(t, h, "LOCK", "/torrents", exclusiveLockBody, map[string]string{"Depth": "0"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("LOCK: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(t, h, http.MethodPut, "/torrents/new.torrent", "x", nil); w.Code != http.StatusLocked {
+		t.Fatalf("adding a member: expected 423, got %d", w.Code)
+	}
+	if w := serve(t, h, "MOVE", "/movies/a.mkv", "", map[string]string{"Destination": "http://example.com/torrents/a.mkv"}); w.Code != http.StatusLocked {
+		t.Fatalf("moving into the collection: expected 423, got %d", w.Code)
+	}
+}
+
+func TestLock_DeleteCountsLockedDescendants(t *testing.T) {
+	h := &Handler{Backend: &fakeBackend{}, LockSystem: &memLocks{}}
+	token := lock(t, h, "/torrents/a.torrent", exclusiveLockBody)
+
+	if w := serve(t, h, http.MethodDelete, "/torrents", "", nil); w.Code != http.StatusLocked {
+		t.Fatalf("DELETE of the parent: expected 423, got %d", w.Code)
+	}
+	w := serve(t, h, http.MethodDelete, "/torrents", "", map[string]string{"If": "</torrents/a.torrent> (<" + token + ">)"})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE with tagged token: expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	locks, _ := h.LockSystem.List(context.Background(), time.Now())
+	if len(locks) != 0 {
+		t.Fatalf("DELETE must release the locks under it, got %v", locks)
+	}
+}
+
+func TestLock_Conflicts(t *testing.T) {
+	h := &Handler{Backend: &fakeBackend{}, LockSystem: &memLocks{}}
+	lock(t, h, "/torrents/a.torrent", sharedLockBody)
+	lock(t, h, "/torrents/a.torrent", sharedLockBody)
+
+	w := serve(t, h, "LOCK", "/torrents/a.torrent", exclusiveLockBody, nil)
+	if w.Code != http.StatusLocked || !strings.Contains(w.Body.String(), "no-conflicting-lock") {
+		t.Fatalf("exclusive over shared: expected 423 no-conflicting-lock, got %d: %s", w.Code, w.Body.String())
+	}
+	w = serve(t, h, "LOCK", "/torrents", exclusiveLockBody, nil)
+	if w.Code != http.StatusLocked {
+		t.Fatalf("infinity lock over a locked member: expected 423, got %d", w.Code)
+	}
+	w = serve(t, h, "LOCK", "/torrents", exclusiveLockBody, map[string]string{"Depth": "0"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("depth-0 lock beside a locked member: expected 200, got %d", w.Code)
+	}
+}
+
+func TestLock_RefreshAndUnlock(t *testing.T) {
+	h := &Handler{Backend: &fakeBackend{}, LockSystem: &memLocks{}}
+	token := lock(t, h, "/torrents/a.torrent", exclusiveLockBody)
+
+	w := serve(t, h, "LOCK", "/torrents/a.torrent", "", map[string]string{"If": "(<" + token + ">)", "Timeout": "Second-60"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"<lockdiscovery", "<exclusive>", "Second-60", token, "http://example.org/~finder"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("refresh response is missing %q: %s", want, body)
+		}
+	}
+
+	w = serve(t, h, "UNLOCK", "/torrents/b.torrent", "", map[string]string{"Lock-Token": "<" + token + ">"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("UNLOCK of another resource: expected 409, got %d", w.Code)
+	}
+	w = serve(t, h, "UNLOCK", "/torrents/a.torrent", "", map[string]string{"Lock-Token": "<" + token + ">"})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("UNLOCK: expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(t, h, http.MethodPut, "/torrents/a.torrent", "x", nil); w.Code != http.StatusOK {
+		t.Fatalf("PUT after UNLOCK: expected 200, got %d", w.Code)
+	}
+}
+
+func TestLock_Options(t *testing.T) {
+	w := serve(t, &Handler{Backend: &fakeBackend{}, LockSystem: &memLocks{}}, http.MethodOptions, "/", "", nil)
+	if got := w.Header().Get("DAV"); got != "1, 2, 3" {
+		t.Fatalf("DAV: expected class 2, got %q", got)
+	}
+	if got := w.Header().Get("Allow"); !strings.Contains(got, "LOCK") {
+		t.Fatalf("Allow must list LOCK, got %q", got)
+	}
+
+	w = serve(t, &Handler{Backend: &fakeBackend{}}, http.MethodOptions, "/", "", nil)
+	if got := w.Header().Get("DAV"); got != "1, 3" {
+		t.Fatalf("DAV without a lock system: expected %q, got %q", "1, 3", got)
+	}
+	if w := serve(t, &Handler{Backend: &fakeBackend{}}, "LOCK", "/", exclusiveLockBody, nil); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("LOCK without a lock system: expected 405, got %d", w.Code)
+	}
+}
+
+func TestParseIf(t *testing.T) {
+	lists, err := ParseIf(`</a/b> (<urn:uuid:1> ["etag"]) (Not <DAV:no-lock>)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Fatalf("expected 2 lists, got %d", len(lists))
+	}
+	if lists[0].Resource != "/a/b" || lists[1].Resource != "/a/b" {
+		t.Fatalf("tagged lists must carry their resource: %+v", lists)
+	}
+	if c := lists[0].Conditions; len(c) != 2 || c[0].Token != "urn:uuid:1" || c[1].ETag != `"etag"` {
+		t.Fatalf("unexpected conditions: %+v", c)
+	}
+	if c := lists[1].Conditions[0]; !c.Not || c.Token != "DAV:no-lock" {
+		t.Fatalf("unexpected negated condition: %+v", c)
+	}
+	for _, bad := range []string{"", "urn:uuid:1", "(<urn:uuid:1>", "()"} {
+		if _, err := ParseIf(bad); err == nil {
+			t.Errorf("ParseIf(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"":                            MaxLockTimeout,
+		"Infinite":                    MaxLockTimeout,
+		"Second-600":                  10 * time.Minute,
+		"Second-99999999999":          MaxLockTimeout,
+		"Second-x, Second-30":         30 * time.Second,
+		"Infinite, Second-4100000000": MaxLockTimeout,
+	} {
+		if got := ParseTimeout(in); got != want {
+			t.Errorf("ParseTimeout(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -70,13 +70,16 @@ type Backend interface {
 
 type Handler struct {
 	Backend Backend
+	// LockSystem enables class 2 (LOCK/UNLOCK). With nil the handler is
+	// class 1 and 3 only, as before.
+	LockSystem LockSystem
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var err error
 	if h.Backend == nil {
 		err = fmt.Errorf("webdav: no backend available")
-	} else {
+	} else if err = h.confirmLocks(r); err == nil {
 		switch r.Method {
 		case http.MethodOptions:
 			err = h.handleOptions(w, r)
@@ -88,6 +91,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			// TODO: send a multistatus in case of partial failure
 			err = h.Backend.Delete(r)
 			if err == nil {
+				h.releaseLocks(r, r.URL.Path)
 				w.WriteHeader(http.StatusNoContent)
 			}
 		case "PROPFIND":
@@ -101,6 +105,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		case "COPY", "MOVE":
 			err = h.handleCopyMove(w, r)
+		case "LOCK", "UNLOCK":
+			if h.LockSystem == nil {
+				err = HTTPErrorf(http.StatusMethodNotAllowed, "webdav: unsupported method")
+			} else if r.Method == "LOCK" {
+				err = h.handleLock(w, r)
+			} else {
+				err = h.handleUnlock(w, r)
+			}
 		default:
 			err = HTTPErrorf(http.StatusMethodNotAllowed, "webdav: unsupported method")
 		}
@@ -116,7 +128,12 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	caps = append([]string{"1", "3"}, caps...)
+	if h.LockSystem != nil {
+		caps = append([]string{"1", "2", "3"}, caps...)
+		allow = append(allow, "LOCK", "UNLOCK")
+	} else {
+		caps = append([]string{"1", "3"}, caps...)
+	}
 
 	w.Header().Add("DAV", strings.Join(caps, ", "))
 	w.Header().Add("Allow", strings.Join(allow, ", "))
@@ -321,6 +338,9 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	if r.Method == "MOVE" {
+		h.releaseLocks(r, r.URL.Path)
+	}
 
 	if created {
 		w.WriteHeader(http.StatusCreated)
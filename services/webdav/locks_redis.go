@@ -0,0 +1,199 @@
+package webdav
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	uuid "github.com/satori/go.uuid"
+	"github.com/webtor-io/web-ui/services/webdav/internal"
+)
+
+const (
+	lockKeyPrefix = "webdav:locks:"
+	// lockTxRetries bounds the optimistic retries of one lock update. Two
+	// clients of the same user racing for a lock is the only contention.
+	lockTxRetries = 10
+)
+
+// RedisLocks keeps WebDAV locks in Redis, so that a LOCK taken through one
+// replica is honoured by the next request landing on another.
+//
+// Key layout: webdav:locks:{scope}
+// Value:      hash, lock token → JSON-encoded Lock
+// TTL:        until the latest expiry among the locks in it
+//
+// Each update is a WATCH/MULTI read-modify-write of the whole hash: a
+// scope holds a handful of locks at most, and the conflict check needs all
+// of them anyway.
+type RedisLocks struct {
+	cl redis.UniversalClient
+}
+
+// NewRedisLocks constructs a RedisLocks backed by the given Redis client.
+func NewRedisLocks(cl redis.UniversalClient) *RedisLocks {
+	return &RedisLocks{cl: cl}
+}
+
+// Scope returns the LockSystem of one namespace — a user: every user sees
+// their own tree under the same paths, so locks must not cross accounts.
+func (l *RedisLocks) Scope(ns string) LockSystem {
+	return &redisLockScope{cl: l.cl, key: lockKeyPrefix + ns}
+}
+
+type redisLockScope struct {
+	cl  redis.UniversalClient
+	key string
+}
+
+var _ LockSystem = (*redisLockScope)(nil)
+
+func (s *redisLockScope) Create(ctx context.Context, now time.Time, d internal.LockDetails) (*internal.Lock, error) {
+	var created *internal.Lock
+	err := s.update(ctx, now, func(locks map[string]internal.Lock) error {
+		if internal.ConflictingLock(sortedLocks(locks), d) != nil {
+			return internal.ErrLocked
+		}
+		l := internal.Lock{
+			LockDetails: d,
+			Token:       "urn:uuid:" + uuid.NewV4().String(),
+			Expires:     now.Add(d.Timeout),
+		}
+		locks[l.Token] = l
+		created = &l
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *redisLockScope) Refresh(ctx context.Context, now time.Time, token string, timeout time.Duration) (*internal.Lock, error) {
+	var refreshed *internal.Lock
+	err := s.update(ctx, now, func(locks map[string]internal.Lock) error {
+		l, ok := locks[token]
+		if !ok {
+			return internal.ErrNoSuchLock
+		}
+		l.Timeout = timeout
+		l.Expires = now.Add(timeout)
+		locks[token] = l
+		refreshed = &l
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+func (s *redisLockScope) Unlock(ctx context.Context, now time.Time, token string) error {
+	return s.update(ctx, now, func(locks map[string]internal.Lock) error {
+		if _, ok := locks[token]; !ok {
+			return internal.ErrNoSuchLock
+		}
+		delete(locks, token)
+		return nil
+	})
+}
+
+func (s *redisLockScope) List(ctx context.Context, now time.Time) ([]internal.Lock, error) {
+	locks, err := s.load(ctx, s.cl, now)
+	if err != nil {
+		return nil, err
+	}
+	return sortedLocks(locks), nil
+}
+
+func (s *redisLockScope) Release(ctx context.Context, now time.Time, root string) error {
+	return s.update(ctx, now, func(locks map[string]internal.Lock) error {
+		for token, l := range locks {
+			if internal.IsUnderLockRoot(l.Root, root) {
+				delete(locks, token)
+			}
+		}
+		return nil
+	})
+}
+
+// load reads the scope's unexpired locks.
+func (s *redisLockScope) load(ctx context.Context, cl redis.Cmdable, now time.Time) (map[string]internal.Lock, error) {
+	raw, err := cl.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load webdav locks")
+	}
+	locks := make(map[string]internal.Lock, len(raw))
+	for token, v := range raw {
+		var l internal.Lock
+		if err := json.Unmarshal([]byte(v), &l); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode webdav lock %s", token)
+		}
+		if l.Expires.After(now) {
+			locks[token] = l
+		}
+	}
+	return locks, nil
+}
+
+// update applies fn to the scope's locks and writes the result back, unless
+// another writer got there first, in which case it starts over. Expired
+// locks are dropped on the way.
+func (s *redisLockScope) update(ctx context.Context, now time.Time, fn func(locks map[string]internal.Lock) error) error {
+	for i := 0; i < lockTxRetries; i++ {
+		err := s.cl.Watch(ctx, func(tx *redis.Tx) error {
+			locks, err := s.load(ctx, tx, now)
+			if err != nil {
+				return err
+			}
+			if err := fn(locks); err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+				p.Del(ctx, s.key)
+				if len(locks) == 0 {
+					return nil
+				}
+				var expires time.Time
+				fields := make([]interface{}, 0, 2*len(locks))
+				for token, l := range locks {
+					b, err := json.Marshal(l)
+					if err != nil {
+						return err
+					}
+					fields = append(fields, token, string(b))
+					if l.Expires.After(expires) {
+						expires = l.Expires
+					}
+				}
+				p.HSet(ctx, s.key, fields...)
+				p.PExpire(ctx, s.key, expires.Sub(now))
+				return nil
+			})
+			return err
+		}, s.key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.Errorf("failed to update webdav locks: %d concurrent attempts", lockTxRetries)
+}
+
+// sortedLocks returns the locks ordered by root, so that PROPFIND answers and
+// conflict reports do not depend on map order.
+func sortedLocks(locks map[string]internal.Lock) []internal.Lock {
+	res := make([]internal.Lock, 0, len(locks))
+	for _, l := range locks {
+		res = append(res, l)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Root != res[j].Root {
+			return res[i].Root < res[j].Root
+		}
+		return res[i].Token < res[j].Token
+	})
+	return res
+}
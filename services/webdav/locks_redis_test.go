@@ -0,0 +1,131 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/webtor-io/web-ui/services/webdav/internal"
+)
+
+func newTestLocks(t *testing.T) (*RedisLocks, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cl := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return NewRedisLocks(cl), mr
+}
+
+func TestRedisLocks_Lifecycle(t *testing.T) {
+	rl, mr := newTestLocks(t)
+	ctx := context.Background()
+	now := time.Date(2026, 4, 10, 12, 0, 0, 0, time.UTC)
+	ls := rl.Scope("user-1")
+
+	l, err := ls.Create(ctx, now, internal.LockDetails{Root: "/torrents/a.torrent", Exclusive: true, Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(l.Token, "urn:uuid:") {
+		t.Fatalf("token must be a urn:uuid, got %q", l.Token)
+	}
+	if _, err := ls.Create(ctx, now, internal.LockDetails{Root: "/torrents", Timeout: time.Minute}); !errors.Is(err, internal.ErrLocked) {
+		t.Fatalf("conflicting Create: expected ErrLocked, got %v", err)
+	}
+	// Scopes are separate: another user locks the same path freely.
+	if _, err := rl.Scope("user-2").Create(ctx, now, internal.LockDetails{Root: "/torrents/a.torrent", Exclusive: true, Timeout: time.Minute}); err != nil {
+		t.Fatalf("Create in another scope: %v", err)
+	}
+	if ttl := mr.TTL(lockKeyPrefix + "user-1"); ttl <= 0 {
+		t.Fatalf("lock key must expire, got TTL %v", ttl)
+	}
+
+	r, err := ls.Refresh(ctx, now.Add(50*time.Second), l.Token, time.Minute)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if want := now.Add(110 * time.Second); !r.Expires.Equal(want) {
+		t.Fatalf("Refresh: expected expiry %v, got %v", want, r.Expires)
+	}
+	locks, err := ls.List(ctx, now.Add(90*time.Second))
+	if err != nil || len(locks) != 1 {
+		t.Fatalf("List after refresh: expected 1 lock, got %v (%v)", locks, err)
+	}
+
+	if err := ls.Unlock(ctx, now, l.Token); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := ls.Unlock(ctx, now, l.Token); !errors.Is(err, internal.ErrNoSuchLock) {
+		t.Fatalf("second Unlock: expected ErrNoSuchLock, got %v", err)
+	}
+	if mr.Exists(lockKeyPrefix + "user-1") {
+		t.Fatal("the key must go with the last lock")
+	}
+}
+
+func TestRedisLocks_ExpiryAndRelease(t *testing.T) {
+	rl, _ := newTestLocks(t)
+	ctx := context.Background()
+	now := time.Date(2026, 4, 10, 12, 0, 0, 0, time.UTC)
+	ls := rl.Scope("user-1")
+
+	old, err := ls.Create(ctx, now, internal.LockDetails{Root: "/a", Exclusive: true, Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Once expired the lock neither shows up nor conflicts.
+	later := now.Add(2 * time.Minute)
+	if locks, _ := ls.List(ctx, later); len(locks) != 0 {
+		t.Fatalf("expired lock is still listed: %v", locks)
+	}
+	if _, err := ls.Refresh(ctx, later, old.Token, time.Minute); !errors.Is(err, internal.ErrNoSuchLock) {
+		t.Fatalf("Refresh of an expired lock: expected ErrNoSuchLock, got %v", err)
+	}
+	for _, root := range []string{"/a", "/a/b", "/ab"} {
+		if _, err := ls.Create(ctx, later, internal.LockDetails{Root: root, ZeroDepth: true, Exclusive: true, Timeout: time.Minute}); err != nil {
+			t.Fatalf("Create %s: %v", root, err)
+		}
+	}
+
+	if err := ls.Release(ctx, later, "/a"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	locks, _ := ls.List(ctx, later)
+	if len(locks) != 1 || locks[0].Root != "/ab" {
+		t.Fatalf("Release must drop /a and /a/b only, got %v", locks)
+	}
+}
+
+func TestPropFind_LockDiscovery(t *testing.T) {
+	rl, _ := newTestLocks(t)
+	h := &Handler{FileSystem: fakeFS{}, LockSystem: rl.Scope("user-1")}
+
+	r := httptest.NewRequest("LOCK", "/all/", strings.NewReader(`<?xml version="1.0"?>
+<D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockinfo>`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("LOCK: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	token := strings.Trim(w.Header().Get("Lock-Token"), "<>")
+
+	r = httptest.NewRequest("PROPFIND", "/", strings.NewReader(`<?xml version="1.0"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:lockdiscovery/><D:supportedlock/></D:prop></D:propfind>`))
+	r.Header.Set("Depth", "1")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND: expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"<lockentry>", "<activelock", token} {
+		if !strings.Contains(body, want) {
+			t.Errorf("PROPFIND is missing %q:\n%s", want, body)
+		}
+	}
+}
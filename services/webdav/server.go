@@ -8,7 +8,9 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
+	cp "github.com/webtor-io/web-ui/services/content_proxy"
 	"github.com/webtor-io/web-ui/services/vfs"
 	"github.com/webtor-io/web-ui/services/webdav/internal"
 )
@@ -20,6 +22,9 @@ type FileSystem = vfs.FileSystem
 // server.
 type Handler struct {
 	FileSystem FileSystem
+	// LockSystem turns on LOCK/UNLOCK (class 2). Finder mounts a share
+	// without it read-only.
+	LockSystem LockSystem
 }
 
 // ServeHTTP implements http.Handler.
@@ -29,8 +34,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b := backend{h.FileSystem}
-	hh := internal.Handler{Backend: &b}
+	b := backend{FileSystem: h.FileSystem, LockSystem: h.LockSystem}
+	hh := internal.Handler{Backend: &b, LockSystem: h.LockSystem}
 	hh.ServeHTTP(w, r)
 }
 
@@ -45,6 +50,21 @@ func NewHTTPError(statusCode int, cause error) error {
 
 type backend struct {
 	FileSystem FileSystem
+	LockSystem LockSystem
+
+	// locks is the lock list, read once per request: a Depth: 1 PROPFIND
+	// asks for lockdiscovery on every child.
+	locks    []internal.Lock
+	locksErr error
+	listed   bool
+}
+
+func (b *backend) activeLocks(r *http.Request) ([]internal.Lock, error) {
+	if !b.listed {
+		b.locks, b.locksErr = b.LockSystem.List(r.Context(), time.Now())
+		b.listed = true
+	}
+	return b.locks, b.locksErr
 }
 
 func (b *backend) Options(r *http.Request) (caps []string, allow []string, err error) {
@@ -87,6 +107,13 @@ func (b *backend) HeadGet(w http.ResponseWriter, r *http.Request) error {
 		defer f.Close()
 	}
 	if red != nil {
+		// Clients that cannot follow the redirect get the content through us.
+		if st := cp.FromContext(r.Context()); st != nil {
+			if fi.ETag != "" {
+				w.Header().Set("ETag", internal.ETag(fi.ETag).String())
+			}
+			return st.Serve(w, r, red)
+		}
 		http.Redirect(w, r, red.String(), http.StatusTemporaryRedirect)
 		return nil
 	}
@@ -130,14 +157,14 @@ func (b *backend) PropFind(r *http.Request, propfind *internal.PropFind, depth i
 
 		resps = make([]internal.Response, len(children))
 		for i, child := range children {
-			resp, err := b.propFindFile(propfind, &child)
+			resp, err := b.propFindFile(r, propfind, &child)
 			if err != nil {
 				return nil, err
 			}
 			resps[i] = *resp
 		}
 	} else {
-		resp, err := b.propFindFile(propfind, fi)
+		resp, err := b.propFindFile(r, propfind, fi)
 		if err != nil {
 			return nil, err
 		}
@@ -148,7 +175,7 @@ func (b *backend) PropFind(r *http.Request, propfind *internal.PropFind, depth i
 	return internal.NewMultiStatus(resps...), nil
 }
 
-func (b *backend) propFindFile(propfind *internal.PropFind, fi *FileInfo) (*internal.Response, error) {
+func (b *backend) propFindFile(r *http.Request, propfind *internal.PropFind, fi *FileInfo) (*internal.Response, error) {
 	props := make(map[xml.Name]internal.PropFindFunc)
 
 	props[internal.ResourceTypeName] = func(*internal.RawXMLValue) (interface{}, error) {
@@ -195,6 +222,23 @@ func (b *backend) propFindFile(propfind *internal.PropFind, fi *FileInfo) (*inte
 		}
 	}
 
+	if b.LockSystem != nil {
+		props[internal.SupportedLockName] = internal.PropFindValue(internal.NewSupportedLock())
+		props[internal.LockDiscoveryName] = func(*internal.RawXMLValue) (interface{}, error) {
+			locks, err := b.activeLocks(r)
+			if err != nil {
+				return nil, err
+			}
+			var covering []internal.Lock
+			for i := range locks {
+				if locks[i].Covers(fi.Path) {
+					covering = append(covering, locks[i])
+				}
+			}
+			return internal.NewLockDiscovery(time.Now(), covering...), nil
+		}
+	}
+
 	return internal.NewPropFindResponse(fi.Path, propfind, props)
 }
 
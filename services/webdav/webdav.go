@@ -9,6 +9,7 @@ package webdav
 
 import (
 	"github.com/webtor-io/web-ui/services/vfs"
+	"github.com/webtor-io/web-ui/services/webdav/internal"
 )
 
 // FileInfo holds information about a WebDAV file.
@@ -22,6 +23,9 @@ type CopyOptions = vfs.CopyOptions
 
 type MoveOptions = vfs.MoveOptions
 
+// LockSystem keeps the state of WebDAV locks; see RedisLocks.
+type LockSystem = internal.LockSystem
+
 // ConditionalMatch represents the value of a conditional header
 // according to RFC 2068 section 14.25 and RFC 2068 section 14.26
 // The (optional) value can either be a wildcard or an ETag.
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	cs "github.com/webtor-io/common-services"
+
+	ba "github.com/webtor-io/web-ui/services/backend_account"
+	"github.com/webtor-io/web-ui/services/common"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	"github.com/webtor-io/web-ui/services/notification"
+)
+
+// accountCheckTimeout bounds each request to a backend's service
+const accountCheckTimeout = 30 * time.Second
+
+func makeStreamingBackendCMD() cli.Command {
+	streamingBackendCMD := cli.Command{
+		Name:    "streaming-backend",
+		Aliases: []string{"sb"},
+		Usage:   "Streaming backend commands",
+	}
+	configureStreamingBackend(&streamingBackendCMD)
+	return streamingBackendCMD
+}
+
+func configureStreamingBackend(c *cli.Command) {
+	checkCmd := cli.Command{
+		Name:    "check",
+		Aliases: []string{"c"},
+		Usage:   "Refreshes debrid account details and mails owners about expiring plans and invalid tokens",
+		Action:  checkStreamingBackends,
+	}
+	configureStreamingBackendCheck(&checkCmd)
+	c.Subcommands = []cli.Command{checkCmd}
+}
+
+func configureStreamingBackendCheck(c *cli.Command) {
+	c.Flags = cs.RegisterPGFlags(c.Flags)
+	c.Flags = common.RegisterFlags(c.Flags)
+}
+
+func checkStreamingBackends(c *cli.Context) error {
+	ctx := context.Background()
+
+	pg := cs.NewPG(c)
+	defer pg.Close()
+
+	m := cs.NewPGMigration(pg)
+	if err := m.Run(); err != nil {
+		return errors.Wrap(err, "failed to run migrations")
+	}
+
+	db := pg.Get()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	// Only the debrid backends are asked anything here, so the resolver
+	// needs neither the Webtor API nor the cache index. A service that
+	// hangs must not hold up the whole run.
+	resolver := lr.New(&http.Client{Timeout: accountCheckTimeout}, pg, nil, nil)
+	ns := notification.New(c, db, newI18n())
+
+	n, err := ba.New(ba.NewStore(pg), resolver, ns).Run(ctx)
+	if err != nil {
+		return err
+	}
+	log.WithField("checked", n).Info("streaming backend accounts checked")
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/webtor-io/web-ui/services/api"
 	"github.com/webtor-io/web-ui/services/claims"
 	"github.com/webtor-io/web-ui/services/common"
+	enr "github.com/webtor-io/web-ui/services/enrich"
 	"github.com/webtor-io/web-ui/services/notification"
 	rss "github.com/webtor-io/web-ui/services/release_subscription"
 	rum "github.com/webtor-io/web-ui/services/request_url_mapper"
@@ -85,12 +86,6 @@ func pollSubscriptions(c *cli.Context) error {
 		return err
 	}
 
-	// The same builder the web process uses, minus the halves a cron job
-	// cannot supply — see Builder.BuildPollStreamsService.
-	torznabCl := torznab.New(c)
-	torznabTitles := torznab.NewCinemetaTitles(torznabCl.HTTP(), c.String(torznab.UserAgentFlag))
-	sb := stremios.NewBuilder(c, pg, stremios.NewClient(c), sapi, requestURLMapper, torznabCl, torznabTitles)
-
 	// The enricher answers one question here: is this series still in
 	// production. That is what decides whether a season subscription has a
 	// future or is finished. A mapper-less enricher (a deployment without
@@ -105,16 +100,34 @@ func pollSubscriptions(c *cli.Context) error {
 		airing = en
 	}
 
+	// The same builder the web process uses, minus the halves a cron job
+	// cannot supply — see Builder.BuildPollStreamsService.
+	torznabCl := torznab.New(c)
+	torznabTitles := torznab.TitleResolver(torznab.NewCinemetaTitles(torznabCl.HTTP(), c.String(torznab.UserAgentFlag)))
+	if aliases := enr.NewTorznabAliases(en, torznab.AliasLangs(c)); aliases != nil {
+		torznabTitles = torznab.WithAliases(torznabTitles, aliases)
+	}
+	sb := stremios.NewBuilder(c, pg, stremios.NewClient(c), sapi, requestURLMapper, torznabCl, torznabTitles)
+
 	ns := notification.New(c, db, newI18n())
 
+	cfg := rss.NewPollConfig(c)
 	poller := rss.NewPoller(
 		rss.NewStore(pg),
 		rss.NewBuilderSearch(sb),
 		ns,
 		rss.NewClaimsTier(claimsSvc),
 		airing,
-		rss.NewPollConfig(c),
+		cfg,
 	)
+	if cfg.Source == rss.PollSourceRSS {
+		poller.WithFeeds(rss.NewTorznabFeeds(pg, torznabCl, torznabTitles))
+		// Anime feeds number episodes across the whole run; the enricher
+		// places them in seasons when an AniList mapper is configured.
+		if en != nil && en.HasMappers() {
+			poller.WithAbsoluteNumbering(en)
+		}
+	}
 
 	n, err := poller.Run(ctx)
 	if err != nil {
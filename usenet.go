@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	cs "github.com/webtor-io/common-services"
+
+	"github.com/webtor-io/web-ui/services/common"
+	lr "github.com/webtor-io/web-ui/services/link_resolver"
+	"github.com/webtor-io/web-ui/services/torznab"
+	"github.com/webtor-io/web-ui/services/usenet"
+)
+
+func makeUsenetCMD() cli.Command {
+	usenetCMD := cli.Command{
+		Name:  "usenet",
+		Usage: "Usenet download commands",
+	}
+	configureUsenet(&usenetCMD)
+	return usenetCMD
+}
+
+func configureUsenet(c *cli.Command) {
+	pollCmd := cli.Command{
+		Name:    "poll",
+		Aliases: []string{"p"},
+		Usage:   "Follows Usenet downloads on their backends until they are ready or failed",
+		Action:  pollUsenet,
+	}
+	configureUsenetPoll(&pollCmd)
+	c.Subcommands = []cli.Command{pollCmd}
+}
+
+func configureUsenetPoll(c *cli.Command) {
+	c.Flags = cs.RegisterPGFlags(c.Flags)
+	c.Flags = common.RegisterFlags(c.Flags)
+	c.Flags = torznab.RegisterFlags(c.Flags)
+}
+
+func pollUsenet(c *cli.Context) error {
+	ctx := context.Background()
+
+	pg := cs.NewPG(c)
+	defer pg.Close()
+
+	m := cs.NewPGMigration(pg)
+	if err := m.Run(); err != nil {
+		return errors.Wrap(err, "failed to run migrations")
+	}
+
+	// Only the Usenet backends are asked anything here, so the resolver
+	// needs neither the Webtor API nor the cache index. SABnzbd goes
+	// through the indexers' egress policy, as it does when serving.
+	torznabCl := torznab.New(c)
+	resolver := lr.New(http.DefaultClient, pg, nil, nil)
+	resolver.RegisterSelfHosted(torznabCl.HTTP())
+
+	// Polling sends nothing, so it needs no sealer
+	stats, err := usenet.New(usenet.NewStore(pg), resolver, torznabCl, nil).Poll(ctx)
+	if err != nil {
+		return err
+	}
+	log.WithField("checked", stats.Checked).
+		WithField("ready", stats.Ready).
+		WithField("failed", stats.Failed).
+		Info("usenet downloads polled")
+	return nil
+}